@@ -0,0 +1,18 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"main/internal/productctl"
+)
+
+func main() {
+	// 收到中斷信號時取消進行中的資料庫操作
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := productctl.NewApp().Run(ctx, os.Args[1:])
+	stop()
+	os.Exit(code)
+}
@@ -1,28 +1,59 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 
+	"main/internal/auth"
 	"main/internal/config"
 	"main/internal/controller"
+	"main/internal/jobs"
 	"main/internal/logger"
+	"main/internal/middleware"
+	"main/internal/notify"
+	"main/internal/pagination"
 	"main/internal/repository"
 	"main/internal/service"
 	"main/pkg/database"
 )
 
-func SetupApplication() (*gin.Engine, *config.AppConfig, error) {
+// Application 應用程序及其需要在關閉時釋放的資源
+type Application struct {
+	Config *config.AppConfig
+	Logger *zap.Logger
+	DB     *sqlx.DB
+	Router *gin.Engine
+	Server *http.Server
+
+	// 背景任務，關閉時取消並等待結束
+	trashRetention     *jobs.TrashRetention
+	reservationSweeper *jobs.ReservationSweeper
+	expirySweeper      *jobs.ExpirySweeper
+	alertRelay         *jobs.AlertRelay
+	alertDispatcher    *notify.Dispatcher
+	cancelJobs         context.CancelFunc
+	jobs               sync.WaitGroup
+}
+
+func SetupApplication() (*Application, error) {
 
 	// 加載配置
 	appConfig, err := config.LoadConfig()
 	if err != nil {
-		panic("無法加載配置: " + err.Error())
+		return nil, fmt.Errorf("無法加載配置: %w", err)
 	}
 
 	// 設置 Gin 模式
@@ -32,9 +63,8 @@ func SetupApplication() (*gin.Engine, *config.AppConfig, error) {
 	loggerConfig := config.GetLoggerConfig(appConfig)
 	appLogger, err := logger.InitLogger(loggerConfig)
 	if err != nil {
-		panic("無法初始化日誌: " + err.Error())
+		return nil, fmt.Errorf("無法初始化日誌: %w", err)
 	}
-	defer appLogger.Sync()
 
 	appLogger.Info("應用程序啟動中",
 		zap.String("mode", appConfig.Server.Mode),
@@ -42,14 +72,77 @@ func SetupApplication() (*gin.Engine, *config.AppConfig, error) {
 
 	db, err := database.NewPostgresDB(&appConfig.Database)
 	if err != nil {
-		return nil, nil, err
+		appLogger.Sync()
+		return nil, err
 	}
 
-	productRepository := repository.NewProductRepository(db)
+	// 執行資料庫遷移
+	if appConfig.Database.AutoMigrate {
+		migrator, err := database.NewMigrator(db)
+		if err != nil {
+			db.Close()
+			appLogger.Sync()
+			return nil, err
+		}
+		applied, err := migrator.Up()
+		if err != nil {
+			db.Close()
+			appLogger.Sync()
+			return nil, err
+		}
+		appLogger.Info("資料庫遷移完成", zap.Int("applied", len(applied)))
+
+		// 遷移與資料列安全都需要表擁有者的權限，因此一併依配置啟用或停用
+		if err := database.SetRowLevelSecurity(context.Background(), db, appConfig.Database.RowLevelSecurity); err != nil {
+			db.Close()
+			appLogger.Sync()
+			return nil, err
+		}
+	}
+
+	if appConfig.Database.RowLevelSecurity {
+		if bypass, err := database.BypassesRowLevelSecurity(context.Background(), db); err == nil && bypass {
+			appLogger.Warn("資料庫用戶為超級用戶或擁有 BYPASSRLS 屬性，資料列安全策略不會生效")
+		}
+	}
+
+	// 所有查詢都限定在請求的租戶內，啟用資料列安全時由資料庫再檢查一次
+	rls := repository.WithRowLevelSecurity(appConfig.Database.RowLevelSecurity)
+	productRepository := repository.NewProductRepository(db, rls)
+	stockRepository := repository.NewStockRepository(db, rls)
+	warehouseRepository := repository.NewWarehouseRepository(db, rls)
+	reservationRepository := repository.NewReservationRepository(db, rls)
+	expiryRepository := repository.NewExpiryRepository(db, rls)
+	alertRepository := repository.NewAlertRepository(db, rls)
+
+	// 低庫存與過期事件一律寫入日誌，另依配置送到 Webhook 與電子郵件；過期事件由背景派送器非同步送出，
+	// 低庫存事件由寄件匣派送任務直接送出，送出後才從寄件匣刪除
+	notifiers := []notify.Notifier{notify.NewLogNotifier(appLogger)}
+	if appConfig.Alerts.WebhookURL != "" {
+		notifiers = append(notifiers, notify.NewWebhookNotifier(appConfig.Alerts.WebhookURL, nil))
+	}
+	if smtpConfig := appConfig.Alerts.SMTP; smtpConfig.Enabled() {
+		notifiers = append(notifiers, notify.NewSMTPNotifier(smtpConfig.Host, smtpConfig.Port,
+			smtpConfig.Username, smtpConfig.Password, smtpConfig.From, smtpConfig.To))
+	}
+	alertNotifier := notify.Multi(notifiers...)
+	alertDispatcher := notify.NewDispatcher(alertNotifier, appLogger,
+		appConfig.Alerts.QueueSize, appConfig.Alerts.TimeoutDuration())
 
 	productService := service.NewProductService(productRepository)
+	stockService := service.NewStockService(stockRepository)
+	warehouseService := service.NewWarehouseService(warehouseRepository)
+	reservationService := service.NewReservationService(reservationRepository)
+	expiryService := service.NewExpiryService(expiryRepository, alertDispatcher)
+	alertService := service.NewAlertService(alertRepository, alertNotifier, appConfig.Alerts.TimeoutDuration())
+	apiKeyService := service.NewAPIKeyService(repository.NewAPIKeyRepository(db))
+
+	if appConfig.Pagination.CursorSecret == "" {
+		appLogger.Warn("未設置游標簽名密鑰，使用隨機密鑰，重啟後分頁游標將失效")
+	}
+	cursorSigner := pagination.NewCursorSigner(appConfig.Pagination.CursorSecret)
 
-	productController := controller.NewProducController(productService, appLogger)
+	productController := controller.NewProducController(productService, appLogger, cursorSigner)
 
 	// 設置 Gin
 	router := gin.New() // 使用 New() 而不是 Default()，因為我們將使用自定義日誌中間件
@@ -60,27 +153,253 @@ func SetupApplication() (*gin.Engine, *config.AppConfig, error) {
 	// 添加自定義的日誌中間件
 	router.Use(logger.LoggerMiddleware(appLogger))
 
+	// 為每個請求設置處理超時，可依路由個別配置
+	router.Use(middleware.RequestTimeout(
+		time.Duration(appConfig.Server.RequestTimeout)*time.Second,
+		appConfig.Server.RouteTimeoutDurations(),
+	))
+
+	// 記錄客戶端聲明的操作者，寫入產品變更歷史
+	router.Use(middleware.Actor())
+
+	// 配置了 JWT 金鑰或啟用 API 金鑰時，除健康檢查外的所有路由都需要驗證
+	authEnabled := appConfig.Auth.Enabled()
+	if authEnabled {
+		var bearer, apiKeys middleware.TokenVerifier
+		if appConfig.Auth.JWTEnabled() {
+			verifier, err := newJWTVerifier(&appConfig.Auth)
+			if err != nil {
+				db.Close()
+				appLogger.Sync()
+				return nil, err
+			}
+			bearer = verifier
+		}
+		if appConfig.Auth.APIKeys {
+			apiKeys = apiKeyService
+		}
+		router.Use(middleware.Authenticate(bearer, apiKeys, "/health"))
+	} else {
+		appLogger.Warn("未配置 JWT 驗證金鑰也未啟用 API 金鑰，API 不需要驗證即可存取")
+	}
+
+	// 依憑證（未啟用驗證時依 X-Tenant-ID）決定請求的租戶，所有產品與庫存的查詢都限定在該租戶內
+	router.Use(middleware.Tenant())
+	if !authEnabled {
+		router.Use(middleware.Unauthenticated())
+	}
+
 	// 註冊路由
 	productController.RegisterRoutes(router)
+	controller.NewStockController(stockService, appLogger).RegisterRoutes(router)
+	controller.NewWarehouseController(warehouseService, appLogger).RegisterRoutes(router)
+	controller.NewReservationController(reservationService, appLogger).RegisterRoutes(router)
+	controller.NewExpiryController(expiryService, appLogger).RegisterRoutes(router)
+
+	// 管理端點需要 X-Admin-Token，未配置令牌時全部拒絕
+	if appConfig.Admin.Token == "" {
+		appLogger.Warn("未設置管理令牌，管理端點已停用")
+	}
+	admin := router.Group("/api/v1/admin", middleware.AdminToken(appConfig.Admin.Token))
+	productController.RegisterAdminRoutes(admin)
+	controller.NewAPIKeyController(apiKeyService, appLogger).RegisterAdminRoutes(admin)
+
+	var trashRetention *jobs.TrashRetention
+	if appConfig.Retention.TrashDays > 0 && appConfig.Retention.CheckInterval > 0 {
+		trashRetention = jobs.NewTrashRetention(productService, appLogger,
+			appConfig.Retention.TrashRetention(), appConfig.Retention.CheckIntervalDuration())
+	}
+
+	var reservationSweeper *jobs.ReservationSweeper
+	if appConfig.Reservations.SweepInterval > 0 {
+		reservationSweeper = jobs.NewReservationSweeper(reservationService, appLogger, appConfig.Reservations.SweepIntervalDuration())
+	}
+
+	// 低庫存事件由資料庫觸發器在寫入時記錄，任何寫入路徑都會產生；定期取出後交給派送器
+	var alertRelay *jobs.AlertRelay
+	if appConfig.Alerts.RelayInterval > 0 {
+		alertRelay = jobs.NewAlertRelay(alertService, appLogger, appConfig.Alerts.RelayIntervalDuration())
+	}
+
+	var expirySweeper *jobs.ExpirySweeper
+	if appConfig.Expiry.RunAt != "" {
+		runAt, err := appConfig.Expiry.RunAtOffset()
+		if err != nil {
+			db.Close()
+			appLogger.Sync()
+			return nil, err
+		}
+		leader := database.NewLeaderLock(db, jobs.ExpirySweeperLockID)
+		expirySweeper = jobs.NewExpirySweeper(expiryService, leader, appLogger, runAt, appConfig.Expiry.LeaderRetryDuration())
+	}
+
+	server := &http.Server{
+		Addr:         ":" + strconv.Itoa(appConfig.Server.Port),
+		Handler:      router,
+		ReadTimeout:  time.Duration(appConfig.Server.ReadTimeout) * time.Second,
+		WriteTimeout: time.Duration(appConfig.Server.WriteTimeout) * time.Second,
+		IdleTimeout:  time.Duration(appConfig.Server.IdleTimeout) * time.Second,
+	}
+
+	return &Application{
+		Config: appConfig,
+		Logger: appLogger,
+		DB:     db,
+		Router: router,
+		Server: server,
+
+		trashRetention:     trashRetention,
+		reservationSweeper: reservationSweeper,
+		expirySweeper:      expirySweeper,
+		alertRelay:         alertRelay,
+		alertDispatcher:    alertDispatcher,
+	}, nil
+}
 
-	// 啟動服務器
-	appLogger.Info("服務器啟動", zap.String("address", ":"+strconv.Itoa(appConfig.Server.Port)))
-	if err := router.Run(":" + strconv.Itoa(appConfig.Server.Port)); err != nil {
-		appLogger.Fatal("服務器啟動失敗", zap.Error(err))
+// newJWTVerifier 依配置創建 JWT 驗證器，JWKS 文件在此時讀取，URL 在第一次驗證令牌時獲取
+func newJWTVerifier(authConfig *config.AuthConfig) (*auth.JWTVerifier, error) {
+	opts := auth.JWTOptions{
+		HS256Secret: []byte(authConfig.HS256Secret),
+		Issuer:      authConfig.Issuer,
+		Audience:    authConfig.Audience,
+		ClockSkew:   authConfig.ClockSkewDuration(),
 	}
 
-	return router, appConfig, nil
+	switch {
+	case authConfig.JWKSFile != "" && authConfig.JWKSURL != "":
+		return nil, errors.New("JWKS 文件與 JWKS URL 只能配置其中一個")
+	case authConfig.JWKSFile != "":
+		keys, err := auth.NewJWKSFile(authConfig.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("無法載入 JWKS 文件: %w", err)
+		}
+		opts.Keys = keys
+	case authConfig.JWKSURL != "":
+		opts.Keys = auth.NewJWKSURL(authConfig.JWKSURL, nil, authConfig.JWKSRefreshDuration())
+	}
+
+	return auth.NewJWTVerifier(opts), nil
+}
+
+// Run 啟動服務器並阻塞，直到收到 SIGINT/SIGTERM 或服務器異常退出
+func (app *Application) Run() error {
+	app.startJobs()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		app.Logger.Info("服務器啟動", zap.String("address", app.Server.Addr))
+		if err := app.Server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	select {
+	case err := <-serverErr:
+		app.Logger.Error("服務器啟動失敗", zap.Error(err))
+		app.Shutdown()
+		return err
+	case sig := <-quit:
+		app.Logger.Info("收到關閉信號，開始優雅關閉", zap.String("signal", sig.String()))
+	}
+
+	return app.Shutdown()
+}
+
+// startJobs 啟動背景任務
+func (app *Application) startJobs() {
+	ctx, cancel := context.WithCancel(context.Background())
+	app.cancelJobs = cancel
+
+	if app.trashRetention != nil {
+		app.jobs.Add(1)
+		go func() {
+			defer app.jobs.Done()
+			app.trashRetention.Run(ctx)
+		}()
+	}
+
+	if app.reservationSweeper != nil {
+		app.jobs.Add(1)
+		go func() {
+			defer app.jobs.Done()
+			app.reservationSweeper.Run(ctx)
+		}()
+	}
+
+	// 停止時放棄領導權，其他實例可立即接手
+	if app.expirySweeper != nil {
+		app.jobs.Add(1)
+		go func() {
+			defer app.jobs.Done()
+			app.expirySweeper.Run(ctx)
+		}()
+	}
+
+	if app.alertRelay != nil {
+		app.jobs.Add(1)
+		go func() {
+			defer app.jobs.Done()
+			app.alertRelay.Run(ctx)
+		}()
+	}
+
+	// 停止時派送器會先送出佇列中剩餘的事件
+	app.jobs.Add(1)
+	go func() {
+		defer app.jobs.Done()
+		app.alertDispatcher.Run(ctx)
+	}()
+}
+
+// Shutdown 依序關閉：停止接收新請求並等待處理中的請求完成、停止背景任務、關閉資料庫連接池、刷新日誌
+func (app *Application) Shutdown() error {
+	timeout := time.Duration(app.Config.Server.ShutdownTimeout) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var shutdownErr error
+
+	if err := app.Server.Shutdown(ctx); err != nil {
+		app.Logger.Error("等待請求完成超時，強制關閉服務器", zap.Duration("timeout", timeout), zap.Error(err))
+		app.Server.Close()
+		shutdownErr = err
+	} else {
+		app.Logger.Info("所有處理中的請求已完成")
+	}
+
+	if app.cancelJobs != nil {
+		app.cancelJobs()
+		app.jobs.Wait()
+		app.Logger.Info("背景任務已停止")
+	}
+
+	// 請求全部結束後才關閉連接池，避免處理中的查詢失敗
+	if err := app.DB.Close(); err != nil {
+		app.Logger.Error("關閉資料庫連接失敗", zap.Error(err))
+		shutdownErr = errors.Join(shutdownErr, err)
+	} else {
+		app.Logger.Info("資料庫連接已關閉")
+	}
+
+	app.Logger.Info("服務器已關閉")
+
+	// 最後刷新日誌緩衝，確保上述關閉日誌都被寫出
+	app.Logger.Sync()
+
+	return shutdownErr
 }
 
 // NewServer 建立並啟動伺服器
 func NewServer() error {
-
-	router, cfg, err := SetupApplication()
-	gin.SetMode(cfg.Server.Mode)
+	app, err := SetupApplication()
 	if err != nil {
 		return err
 	}
-	return router.Run(fmt.Sprintf(":%d", cfg.Server.Port))
+	return app.Run()
 }
 
 // main 函数
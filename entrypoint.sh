@@ -2,6 +2,10 @@
 
 # 先在背景啟動應用程序
 /app/main &
+APP_PID=$!
+
+# 將停止信號轉發給應用程序，讓它完成處理中的請求後再退出
+trap 'kill -TERM $APP_PID 2>/dev/null' TERM INT
 
 # 等待應用程序啟動
 echo "等待應用程序啟動..."
@@ -15,10 +19,12 @@ echo "運行 API 測試..."
 TEST_RESULT=$?
 if [ $TEST_RESULT -ne 0 ]; then
     echo "API 測試失敗，退出代碼 $TEST_RESULT"
-    kill %1  # 終止背景應用程序
+    kill -TERM $APP_PID  # 終止背景應用程序
+    wait $APP_PID
     exit $TEST_RESULT
 fi
 
 # 如果測試成功，將前台進程切換到應用程序
 echo "API 測試通過，繼續運行應用程序..."
-wait %1  # 等待背景進程
\ No newline at end of file
+wait $APP_PID  # 等待背景進程（收到信號時會先返回）
+wait $APP_PID  # 等待應用程序完成優雅關閉
@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// API 金鑰的錯誤定義
+var (
+	ErrInvalidAPIKey = errors.New("無效的 API 金鑰")
+	ErrAPIKeyExpired = errors.New("API 金鑰已過期")
+)
+
+// APIKeyPrefix API 金鑰的固定開頭，便於在日誌或程式碼中辨識洩漏的金鑰
+const APIKeyPrefix = "pk_"
+
+// apiKeyDisplayLength 保存並顯示的金鑰開頭長度
+const apiKeyDisplayLength = len(APIKeyPrefix) + 8
+
+// GenerateAPIKey 生成新的 API 金鑰，返回明文金鑰與用於辨識的開頭
+func GenerateAPIKey() (key string, prefix string, err error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	key = APIKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	return key, key[:apiKeyDisplayLength], nil
+}
+
+// HashAPIKey 返回 API 金鑰的 SHA-256 雜湊（十六進位）；金鑰為高熵的隨機值，不需要加鹽或慢雜湊
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidAPIKeyFormat 檢查金鑰是否符合生成的格式，格式不符時不必查詢資料庫
+func ValidAPIKeyFormat(key string) bool {
+	return strings.HasPrefix(key, APIKeyPrefix) && len(key) == len(APIKeyPrefix)+43
+}
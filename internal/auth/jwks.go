@@ -0,0 +1,303 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// 錯誤定義
+var (
+	ErrUnknownKey         = errors.New("找不到存取令牌的簽名金鑰")
+	ErrKeySetUnavailable  = fmt.Errorf("%w: 無法獲取 JWKS", ErrUnavailable)
+	errUnsupportedJWKType = errors.New("不支援的 JWK 類型")
+)
+
+// jwksMinRefetch 遇到未知的 kid 時重新獲取 JWKS 的最短間隔，避免偽造的 kid 造成大量請求
+const jwksMinRefetch = 30 * time.Second
+
+// jwksDefaultRefresh 未指定時重新獲取 JWKS URL 的間隔
+const jwksDefaultRefresh = 5 * time.Minute
+
+// jwksMaxSize JWKS 響應的大小上限
+const jwksMaxSize = 1 << 20
+
+// JWKS 從本地文件或 URL 載入的公鑰集合，支援 RSA 與 P-256 EC 金鑰
+type JWKS struct {
+	load    func(ctx context.Context) ([]byte, error)
+	refresh time.Duration // 為 0 時載入後不再重新獲取
+
+	mu          sync.Mutex
+	keys        []jwk
+	fetchedAt   time.Time     // 上次成功獲取的時間
+	attemptedAt time.Time     // 上次嘗試獲取的時間
+	loadErr     error         // 上次獲取失敗的原因
+	refreshing  chan struct{} // 進行中的獲取完成時關閉，沒有進行中的獲取時為 nil
+}
+
+// jwk 解析後的單個公鑰
+type jwk struct {
+	kid string
+	alg string // 為空時適用於與金鑰類型相符的算法
+	key crypto.PublicKey
+}
+
+// rawJWK JWKS 中單個金鑰的 JSON 表示
+type rawJWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// NewJWKSFile 從本地文件載入 JWKS，文件在啟動時讀取一次，無效時返回錯誤
+func NewJWKSFile(path string) (*JWKS, error) {
+	set := &JWKS{load: func(context.Context) ([]byte, error) {
+		return os.ReadFile(path)
+	}}
+	if err := set.reload(context.Background()); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// NewJWKSURL 創建從 URL 獲取的 JWKS，第一次驗證令牌時獲取，之後每隔 refresh 重新獲取；
+// 遇到未知的 kid 時提前重新獲取，以便接受輪換後的金鑰。refresh 不大於 0 時為 5 分鐘，
+// client 為 nil 時使用 10 秒超時的預設客戶端
+func NewJWKSURL(url string, client *http.Client, refresh time.Duration) *JWKS {
+	if refresh <= 0 {
+		refresh = jwksDefaultRefresh
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &JWKS{
+		refresh: refresh,
+		load: func(ctx context.Context) ([]byte, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return nil, err
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				return nil, err
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				return nil, fmt.Errorf("JWKS 響應狀態碼 %d", resp.StatusCode)
+			}
+			return io.ReadAll(io.LimitReader(resp.Body, jwksMaxSize))
+		},
+	}
+}
+
+// Keys 返回 kid 相符且適用於 alg 的公鑰。快取過期或找不到 kid 時重新獲取，
+// 兩次獲取至少間隔 jwksMinRefetch（refresh 更短時為 refresh）；重新獲取失敗時沿用已載入的金鑰。
+// 獲取在背景進行且不持有鎖，快取過期時先以已載入的金鑰回應；只有尚未載入金鑰或找不到 kid 時
+// 才等待獲取完成，等待受 ctx 限制，但 ctx 取消不會中斷獲取
+func (s *JWKS) Keys(ctx context.Context, kid, alg string) ([]crypto.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	canFetch := s.refresh > 0 && time.Since(s.attemptedAt) >= min(s.refresh, jwksMinRefetch)
+	if canFetch && (s.keys == nil || time.Since(s.fetchedAt) >= s.refresh) {
+		s.startRefresh()
+		canFetch = false
+	}
+	if s.keys == nil && s.refreshing != nil {
+		if err := s.waitRefresh(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if s.keys == nil {
+		return nil, s.loadErr
+	}
+
+	keys := s.match(kid, alg)
+	if len(keys) == 0 && (canFetch || s.refreshing != nil) {
+		s.startRefresh()
+		if err := s.waitRefresh(ctx); err != nil {
+			return nil, err
+		}
+		keys = s.match(kid, alg)
+	}
+	if len(keys) == 0 {
+		return nil, ErrUnknownKey
+	}
+	return keys, nil
+}
+
+// startRefresh 在背景重新獲取 JWKS，已有進行中的獲取時不重複發起；呼叫者需持有鎖。
+// 獲取使用獨立的 context，不受觸發它的請求取消影響，HTTP 客戶端的超時限制其時間
+func (s *JWKS) startRefresh() {
+	if s.refreshing != nil {
+		return
+	}
+
+	done := make(chan struct{})
+	s.refreshing = done
+	s.attemptedAt = time.Now()
+	attemptedAt := s.attemptedAt
+
+	go func() {
+		keys, err := s.fetch(context.Background())
+
+		s.mu.Lock()
+		s.store(attemptedAt, keys, err)
+		s.refreshing = nil
+		s.mu.Unlock()
+		close(done)
+	}()
+}
+
+// waitRefresh 釋放鎖並等待進行中的獲取完成，返回時重新持有鎖；ctx 先結束時返回 ErrKeySetUnavailable
+func (s *JWKS) waitRefresh(ctx context.Context) error {
+	done := s.refreshing
+	s.mu.Unlock()
+	defer s.mu.Lock()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %v", ErrKeySetUnavailable, ctx.Err())
+	}
+}
+
+// match 返回 kid 相符且適用於 alg 的公鑰，kid 為空時不比較 kid
+func (s *JWKS) match(kid, alg string) []crypto.PublicKey {
+	var keys []crypto.PublicKey
+	for _, k := range s.keys {
+		if kid != "" && k.kid != kid {
+			continue
+		}
+		if k.alg != "" && k.alg != alg {
+			continue
+		}
+		keys = append(keys, k.key)
+	}
+	return keys
+}
+
+// reload 同步重新載入並解析 JWKS，只在建立時使用，尚無並發的存取
+func (s *JWKS) reload(ctx context.Context) error {
+	s.attemptedAt = time.Now()
+	keys, err := s.fetch(ctx)
+	return s.store(s.attemptedAt, keys, err)
+}
+
+// store 記錄一次獲取的結果，呼叫者需持有鎖；失敗時保留原有的金鑰
+func (s *JWKS) store(attemptedAt time.Time, keys []jwk, err error) error {
+	if err != nil {
+		s.loadErr = fmt.Errorf("%w: %v", ErrKeySetUnavailable, err)
+		return s.loadErr
+	}
+
+	s.keys = keys
+	s.fetchedAt = attemptedAt
+	s.loadErr = nil
+	return nil
+}
+
+// fetch 載入並解析 JWKS
+func (s *JWKS) fetch(ctx context.Context) ([]jwk, error) {
+	data, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return parseJWKS(data)
+}
+
+// parseJWKS 解析 JWKS，略過用於加密或類型不支援的金鑰
+func parseJWKS(data []byte) ([]jwk, error) {
+	var set struct {
+		Keys []rawJWK `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("解析 JWKS 失敗: %w", err)
+	}
+
+	keys := []jwk{}
+	for _, raw := range set.Keys {
+		if raw.Use != "" && raw.Use != "sig" {
+			continue
+		}
+		key, err := raw.publicKey()
+		if errors.Is(err, errUnsupportedJWKType) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析 JWK %q 失敗: %w", raw.Kid, err)
+		}
+		keys = append(keys, jwk{kid: raw.Kid, alg: raw.Alg, key: key})
+	}
+	return keys, nil
+}
+
+// publicKey 將 JWK 轉換為 RSA 或 P-256 ECDSA 公鑰
+func (k rawJWK) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("無效的 RSA 公開指數")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, errUnsupportedJWKType
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != 32 {
+			return nil, errors.New("無效的 EC 座標 x")
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil || len(y) != 32 {
+			return nil, errors.New("無效的 EC 座標 y")
+		}
+		// 確認座標位於曲線上
+		if _, err := ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, errors.New("EC 座標不在 P-256 曲線上")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+
+	default:
+		return nil, errUnsupportedJWKType
+	}
+}
+
+// decodeBigInt 解碼 base64url 編碼的大端序整數
+func decodeBigInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(data) == 0 {
+		return nil, errors.New("無效的 base64url 整數")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,228 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+// 支援的簽名算法
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+)
+
+// 錯誤定義
+var (
+	ErrInvalidToken         = errors.New("無效的存取令牌")
+	ErrUnsupportedAlgorithm = errors.New("不支援的令牌簽名算法")
+	ErrInvalidSignature     = errors.New("存取令牌的簽名無效")
+	ErrTokenExpired         = errors.New("存取令牌已過期")
+	ErrTokenNotYetValid     = errors.New("存取令牌尚未生效")
+	ErrInvalidIssuer        = errors.New("存取令牌的簽發者不符")
+	ErrInvalidAudience      = errors.New("存取令牌的受眾不符")
+	ErrMissingSubject       = errors.New("存取令牌缺少主體")
+)
+
+// KeySource 提供驗證 RS256 / ES256 簽名的公鑰
+type KeySource interface {
+	// Keys 返回可能簽發該令牌的公鑰，kid 為空時返回所有適用於 alg 的公鑰
+	Keys(ctx context.Context, kid, alg string) ([]crypto.PublicKey, error)
+}
+
+// JWTOptions JWT 驗證選項，HS256Secret 與 Keys 至少配置一個
+type JWTOptions struct {
+	HS256Secret []byte        // 為空時不接受 HS256 令牌
+	Keys        KeySource     // 為 nil 時不接受 RS256 / ES256 令牌
+	Issuer      string        // 為空時不檢查 iss
+	Audience    string        // 為空時不檢查 aud
+	ClockSkew   time.Duration // 檢查 exp 與 nbf 時容許的時鐘誤差
+}
+
+// JWTVerifier 驗證 JWT Bearer 令牌
+type JWTVerifier struct {
+	opts JWTOptions
+}
+
+// NewJWTVerifier 創建 JWT 驗證器
+func NewJWTVerifier(opts JWTOptions) *JWTVerifier {
+	return &JWTVerifier{opts: opts}
+}
+
+// Claims 驗證器使用的 JWT 聲明
+type Claims struct {
+	Subject   string      `json:"sub"`
+	Issuer    string      `json:"iss"`
+	Audience  StringList  `json:"aud"`
+	ExpiresAt NumericDate `json:"exp"`
+	NotBefore NumericDate `json:"nbf"`
+	Scope     string      `json:"scope"`     // 以空格分隔的範圍
+	Roles     StringList  `json:"roles"`     // 角色，見 RolePermissions
+	TenantID  string      `json:"tenant_id"` // 主體所屬的租戶
+}
+
+// StringList 可以是單個字串或字串陣列的聲明，例如 aud 與 roles
+type StringList []string
+
+// UnmarshalJSON 同時接受字串與字串陣列
+func (a *StringList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = StringList{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	*a = multiple
+	return nil
+}
+
+// NumericDate JWT 的時間聲明，自 Unix 紀元起的秒數，可帶小數；0 表示未提供
+type NumericDate float64
+
+// Time 轉換為 time.Time
+func (d NumericDate) Time() time.Time {
+	return time.UnixMilli(int64(float64(d) * 1000))
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Verify 驗證令牌的簽名、有效期、簽發者與受眾，返回令牌代表的身分；
+// 令牌必須帶有 exp 與 sub
+func (v *JWTVerifier) Verify(ctx context.Context, token string) (Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Principal{}, ErrInvalidToken
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Principal{}, ErrInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Principal{}, ErrInvalidToken
+	}
+
+	if err := v.verifySignature(ctx, header, parts[0]+"."+parts[1], signature); err != nil {
+		return Principal{}, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Principal{}, ErrInvalidToken
+	}
+	if err := v.validateClaims(claims); err != nil {
+		return Principal{}, err
+	}
+
+	return Principal{Subject: claims.Subject, Scopes: strings.Fields(claims.Scope), Roles: claims.Roles, Tenant: claims.TenantID}, nil
+}
+
+// verifySignature 依 header 的 alg 驗證簽名，只接受已配置金鑰的算法
+func (v *JWTVerifier) verifySignature(ctx context.Context, header jwtHeader, signingInput string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signingInput))
+
+	switch header.Alg {
+	case AlgHS256:
+		if len(v.opts.HS256Secret) == 0 {
+			return ErrUnsupportedAlgorithm
+		}
+		mac := hmac.New(sha256.New, v.opts.HS256Secret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return ErrInvalidSignature
+		}
+		return nil
+
+	case AlgRS256, AlgES256:
+		if v.opts.Keys == nil {
+			return ErrUnsupportedAlgorithm
+		}
+		keys, err := v.opts.Keys.Keys(ctx, header.Kid, header.Alg)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if verifyWithKey(key, header.Alg, digest[:], signature) {
+				return nil
+			}
+		}
+		return ErrInvalidSignature
+
+	default:
+		return ErrUnsupportedAlgorithm
+	}
+}
+
+// verifyWithKey 以公鑰驗證 RS256 或 ES256 簽名，公鑰類型與算法不符時驗證失敗
+func verifyWithKey(key crypto.PublicKey, alg string, digest, signature []byte) bool {
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		return alg == AlgRS256 && rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest, signature) == nil
+	case *ecdsa.PublicKey:
+		// ES256 的簽名為固定長度的 r || s，而不是 ASN.1 編碼
+		if alg != AlgES256 || len(signature) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(pub, digest, r, s)
+	default:
+		return false
+	}
+}
+
+// validateClaims 檢查有效期、簽發者、受眾與主體
+func (v *JWTVerifier) validateClaims(claims Claims) error {
+	now := time.Now()
+
+	if claims.ExpiresAt == 0 {
+		return fmt.Errorf("%w: 缺少 exp", ErrInvalidToken)
+	}
+	if now.After(claims.ExpiresAt.Time().Add(v.opts.ClockSkew)) {
+		return ErrTokenExpired
+	}
+	if claims.NotBefore != 0 && now.Add(v.opts.ClockSkew).Before(claims.NotBefore.Time()) {
+		return ErrTokenNotYetValid
+	}
+
+	if v.opts.Issuer != "" && claims.Issuer != v.opts.Issuer {
+		return ErrInvalidIssuer
+	}
+	if v.opts.Audience != "" && !slices.Contains(claims.Audience, v.opts.Audience) {
+		return ErrInvalidAudience
+	}
+	if claims.Subject == "" {
+		return ErrMissingSubject
+	}
+
+	return nil
+}
+
+// decodeSegment 解碼 base64url 編碼的 JSON 片段
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,39 @@
+// Package auth 驗證請求的身分，並在 context 中傳遞通過驗證的主體，供服務層與日誌使用
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrUnavailable 暫時無法驗證身分，例如無法獲取 JWKS 或查詢 API 金鑰失敗，與憑證無效不同
+var ErrUnavailable = errors.New("暫時無法驗證身分")
+
+type contextKey int
+
+const principalKey contextKey = iota
+
+// Principal 通過驗證的請求身分
+type Principal struct {
+	Subject string   // JWT 的 sub，API 金鑰為 api-key:<租戶>/<名稱>
+	Scopes  []string // JWT 的 scope 或 API 金鑰的範圍，與權限同名的範圍直接授予該權限
+	Roles   []string // JWT 的 roles
+	Tenant  string   // JWT 的 tenant_id 或 API 金鑰所屬的租戶，為空時屬於預設租戶
+}
+
+// WithPrincipal 返回帶有通過驗證身分的 context
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey, principal)
+}
+
+// FromContext 返回 context 中通過驗證的身分，未經驗證時 ok 為 false
+func FromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey).(Principal)
+	return principal, ok
+}
+
+// Subject 返回 context 中通過驗證的主體，未經驗證時為空字串
+func Subject(ctx context.Context) string {
+	principal, _ := FromContext(ctx)
+	return principal.Subject
+}
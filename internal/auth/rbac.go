@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrForbidden 已通過驗證但沒有執行操作所需的權限
+var ErrForbidden = errors.New("沒有執行此操作的權限")
+
+// Permission 操作所需的權限，格式為 <資源>:<動作>
+type Permission string
+
+const (
+	PermProductRead    Permission = "product:read"    // 查詢產品、庫存、預留、報表與倉庫
+	PermProductWrite   Permission = "product:write"   // 創建、修改與匯入產品
+	PermProductDelete  Permission = "product:delete"  // 將產品移到回收站或還原
+	PermProductPurge   Permission = "product:purge"   // 永久刪除回收站中的產品
+	PermStockAdjust    Permission = "stock:adjust"    // 調整庫存、登記異動與預留
+	PermWarehouseWrite Permission = "warehouse:write" // 創建、修改與刪除倉庫及儲位
+	PermAPIKeyManage   Permission = "apikey:manage"   // 創建、列出、輪換與撤銷 API 金鑰
+)
+
+// Role 角色，每個角色包含前一個角色的所有權限
+type Role string
+
+const (
+	RoleViewer  Role = "viewer"
+	RoleClerk   Role = "clerk"
+	RoleManager Role = "manager"
+	RoleAdmin   Role = "admin"
+)
+
+// rolePermissions 每個角色擁有的權限
+var rolePermissions = func() map[Role][]Permission {
+	viewer := []Permission{PermProductRead}
+	clerk := append(slices.Clone(viewer), PermProductWrite, PermStockAdjust)
+	manager := append(slices.Clone(clerk), PermProductDelete, PermWarehouseWrite)
+	admin := append(slices.Clone(manager), PermProductPurge, PermAPIKeyManage)
+
+	return map[Role][]Permission{
+		RoleViewer:  viewer,
+		RoleClerk:   clerk,
+		RoleManager: manager,
+		RoleAdmin:   admin,
+	}
+}()
+
+// RolePermissions 返回角色擁有的權限，未知的角色沒有任何權限
+func RolePermissions(role Role) []Permission {
+	return slices.Clone(rolePermissions[role])
+}
+
+// Can 返回主體是否擁有權限：任一角色包含該權限，或範圍中直接授予了該權限
+func (p Principal) Can(permission Permission) bool {
+	if slices.Contains(p.Scopes, string(permission)) {
+		return true
+	}
+	for _, role := range p.Roles {
+		if slices.Contains(rolePermissions[Role(role)], permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// System 受信任的內部呼叫者使用的主體，擁有所有權限：productctl、背景任務與未啟用驗證時的請求
+var System = Principal{Subject: "system", Roles: []string{string(RoleAdmin)}}
+
+// Authorize 檢查 context 中的主體是否擁有權限，沒有時返回包裝 ErrForbidden 的錯誤；
+// context 中沒有主體時同樣拒絕，內部呼叫者須明確以 WithPrincipal(ctx, System) 執行
+func Authorize(ctx context.Context, permission Permission) error {
+	principal, ok := FromContext(ctx)
+	if !ok {
+		return fmt.Errorf("%w: 沒有通過驗證的身分", ErrForbidden)
+	}
+	if principal.Can(permission) {
+		return nil
+	}
+	return fmt.Errorf("%w: 需要 %s", ErrForbidden, permission)
+}
@@ -6,29 +6,51 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // AppConfig 應用程序配置結構
 type AppConfig struct {
-	Server   ServerConfig   `json:"server"`
-	Database DatabaseConfig `json:"database"`
-	Logger   LoggerConfig   `json:"logger"`
+	Server     ServerConfig     `json:"server"`
+	Database   DatabaseConfig   `json:"database"`
+	Logger     LoggerConfig     `json:"logger"`
+	Pagination PaginationConfig `json:"pagination"`
+	Admin      AdminConfig      `json:"admin"`
+	Retention  RetentionConfig  `json:"retention"`
+
+	Reservations ReservationConfig `json:"reservations"`
+	Alerts       AlertConfig       `json:"alerts"`
+	Expiry       ExpiryConfig      `json:"expiry"`
+	Auth         AuthConfig        `json:"auth"`
 }
 
 // ServerConfig 服務器配置
 type ServerConfig struct {
-	Port int    `json:"port"`
-	Mode string `json:"mode"`
+	Port            int    `json:"port"`
+	Mode            string `json:"mode"`
+	ReadTimeout     int    `json:"read_timeout"`     // 讀取請求的超時時間，單位秒
+	WriteTimeout    int    `json:"write_timeout"`    // 寫入響應的超時時間，單位秒
+	IdleTimeout     int    `json:"idle_timeout"`     // keep-alive 連接的閒置超時，單位秒
+	ShutdownTimeout int    `json:"shutdown_timeout"` // 優雅關閉時等待請求完成的最長時間，單位秒
+
+	RequestTimeout int            `json:"request_timeout"` // 請求處理的默認超時，單位秒，0 表示不限制
+	RouteTimeouts  map[string]int `json:"route_timeouts"`  // 個別路由的超時，鍵為 "方法 路由"，例如 "GET /api/v1/products"
 }
 
 // DatabaseConfig 數據庫配置
 type DatabaseConfig struct {
-	Host     string `json:"host"`
-	Port     int    `json:"port"`
-	User     string `json:"user"`
-	Password string `json:"password"`
-	DBName   string `json:"dbname"`
-	SSLMode  string `json:"sslmode"`
+	Host        string `json:"host"`
+	Port        int    `json:"port"`
+	User        string `json:"user"`
+	Password    string `json:"password"`
+	DBName      string `json:"dbname"`
+	SSLMode     string `json:"sslmode"`
+	AutoMigrate bool   `json:"auto_migrate"` // 啟動時是否自動執行資料庫遷移
+
+	// RowLevelSecurity 是否以 PostgreSQL 資料列安全作為租戶隔離的第二層防護；
+	// 啟用後每個查詢都在設置了租戶的交易中執行，連線的用戶不能是超級用戶或擁有 BYPASSRLS 屬性
+	RowLevelSecurity bool `json:"row_level_security"`
 }
 
 // LoggerConfig 日誌配置
@@ -44,6 +66,137 @@ type LoggerConfig struct {
 	Compress     bool   `json:"compress"`      // 是否壓縮舊文件
 }
 
+// PaginationConfig 分頁配置
+type PaginationConfig struct {
+	CursorSecret string `json:"cursor_secret"` // 游標簽名密鑰，為空時每次啟動隨機生成
+}
+
+// AdminConfig 管理端點配置
+type AdminConfig struct {
+	Token string `json:"token"` // 管理端點的令牌，以 X-Admin-Token 標頭傳入，為空時停用管理端點
+}
+
+// RetentionConfig 回收站保留期配置
+type RetentionConfig struct {
+	TrashDays     int `json:"trash_days"`     // 軟刪除的產品保留天數，超過後永久刪除，0 表示不自動清理
+	CheckInterval int `json:"check_interval"` // 檢查過期產品的間隔，單位分鐘
+}
+
+// ReservationConfig 庫存預留配置
+type ReservationConfig struct {
+	SweepInterval int `json:"sweep_interval"` // 將到期預留標記為過期的間隔，單位秒，0 表示不執行清理
+}
+
+// AlertConfig 低庫存通知配置，事件一律寫入日誌，另可送到 Webhook 與電子郵件
+type AlertConfig struct {
+	QueueSize     int        `json:"queue_size"`     // 待派送事件的佇列容量，已滿時丟棄新事件
+	Timeout       int        `json:"timeout"`        // 單個事件的派送時限，單位秒
+	RelayInterval int        `json:"relay_interval"` // 從資料庫取出低庫存事件的間隔，單位秒，0 表示不派送
+	WebhookURL    string     `json:"webhook_url"`    // 為空時不送出 Webhook
+	SMTP          SMTPConfig `json:"smtp"`
+}
+
+// SMTPConfig 低庫存通知郵件的 SMTP 配置，host 或收件人為空時不寄送郵件
+type SMTPConfig struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port"`
+	Username string   `json:"username"` // 為空時不進行驗證
+	Password string   `json:"password"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+// ExpiryConfig 過期清理配置，多個實例以 advisory lock 選出一個執行
+type ExpiryConfig struct {
+	RunAt       string `json:"run_at"`       // 每天執行的時間，格式為 HH:MM（UTC），為空時不執行清理
+	LeaderRetry int    `json:"leader_retry"` // 競爭或確認領導權的間隔，單位秒
+}
+
+// RunAtOffset 將每天執行的時間轉換為自 UTC 零時起算的 time.Duration
+func (c *ExpiryConfig) RunAtOffset() (time.Duration, error) {
+	t, err := time.Parse("15:04", c.RunAt)
+	if err != nil {
+		return 0, fmt.Errorf("過期清理的執行時間必須為 HH:MM 格式: %s", c.RunAt)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// LeaderRetryDuration 將領導權的檢查間隔轉換為 time.Duration
+func (c *ExpiryConfig) LeaderRetryDuration() time.Duration {
+	return time.Duration(c.LeaderRetry) * time.Second
+}
+
+// AuthConfig 身分驗證配置，未配置 JWT 金鑰也未啟用 API 金鑰時不驗證請求
+type AuthConfig struct {
+	APIKeys     bool   `json:"api_keys"`     // 是否接受 X-API-Key 標頭的 API 金鑰
+	HS256Secret string `json:"hs256_secret"` // HS256 共享密鑰，為空時不接受 HS256 令牌
+	JWKSFile    string `json:"jwks_file"`    // RS256 / ES256 公鑰的本地 JWKS 文件，與 jwks_url 只能配置一個
+	JWKSURL     string `json:"jwks_url"`     // RS256 / ES256 公鑰的 JWKS URL
+	JWKSRefresh int    `json:"jwks_refresh"` // 重新獲取 JWKS URL 的間隔，單位秒
+	Issuer      string `json:"issuer"`       // 令牌必須的 iss，為空時不檢查
+	Audience    string `json:"audience"`     // 令牌必須包含的 aud，為空時不檢查
+	ClockSkew   int    `json:"clock_skew"`   // 檢查 exp 與 nbf 時容許的時鐘誤差，單位秒
+}
+
+// Enabled 檢查是否需要驗證請求
+func (c *AuthConfig) Enabled() bool {
+	return c.JWTEnabled() || c.APIKeys
+}
+
+// JWTEnabled 檢查是否配置了任何驗證 JWT 的金鑰
+func (c *AuthConfig) JWTEnabled() bool {
+	return c.HS256Secret != "" || c.JWKSFile != "" || c.JWKSURL != ""
+}
+
+// JWKSRefreshDuration 將 JWKS 的重新獲取間隔轉換為 time.Duration
+func (c *AuthConfig) JWKSRefreshDuration() time.Duration {
+	return time.Duration(c.JWKSRefresh) * time.Second
+}
+
+// ClockSkewDuration 將時鐘誤差轉換為 time.Duration
+func (c *AuthConfig) ClockSkewDuration() time.Duration {
+	return time.Duration(c.ClockSkew) * time.Second
+}
+
+// Enabled 檢查是否配置了 SMTP 伺服器與收件人
+func (c *SMTPConfig) Enabled() bool {
+	return c.Host != "" && len(c.To) > 0
+}
+
+// TimeoutDuration 將派送時限轉換為 time.Duration
+func (c *AlertConfig) TimeoutDuration() time.Duration {
+	return time.Duration(c.Timeout) * time.Second
+}
+
+// RelayIntervalDuration 將取出低庫存事件的間隔轉換為 time.Duration
+func (c *AlertConfig) RelayIntervalDuration() time.Duration {
+	return time.Duration(c.RelayInterval) * time.Second
+}
+
+// SweepIntervalDuration 將清理間隔轉換為 time.Duration
+func (c *ReservationConfig) SweepIntervalDuration() time.Duration {
+	return time.Duration(c.SweepInterval) * time.Second
+}
+
+// TrashRetention 將回收站保留天數轉換為 time.Duration
+func (c *RetentionConfig) TrashRetention() time.Duration {
+	return time.Duration(c.TrashDays) * 24 * time.Hour
+}
+
+// CheckIntervalDuration 將檢查間隔轉換為 time.Duration
+func (c *RetentionConfig) CheckIntervalDuration() time.Duration {
+	return time.Duration(c.CheckInterval) * time.Minute
+}
+
+// RouteTimeoutDurations 將個別路由的超時轉換為 time.Duration
+func (c *ServerConfig) RouteTimeoutDurations() map[string]time.Duration {
+	timeouts := make(map[string]time.Duration, len(c.RouteTimeouts))
+	for route, seconds := range c.RouteTimeouts {
+		timeouts[route] = time.Duration(seconds) * time.Second
+	}
+	return timeouts
+}
+
 // DSN 獲取數據庫連接字符串
 func (c *DatabaseConfig) DSN() string {
 	return fmt.Sprintf(
@@ -93,8 +246,13 @@ func LoadConfig() (*AppConfig, error) {
 func DefaultConfig() *AppConfig {
 	return &AppConfig{
 		Server: ServerConfig{
-			Port: 8080,
-			Mode: "debug",
+			Port:            8080,
+			Mode:            "debug",
+			ReadTimeout:     15,
+			WriteTimeout:    30,
+			IdleTimeout:     60,
+			ShutdownTimeout: 30,
+			RequestTimeout:  10,
 		},
 		Database: DatabaseConfig{
 			Host:     "localhost",
@@ -115,6 +273,29 @@ func DefaultConfig() *AppConfig {
 			MaxAge:       30,
 			Compress:     true,
 		},
+		Retention: RetentionConfig{
+			TrashDays:     30,
+			CheckInterval: 60,
+		},
+		Reservations: ReservationConfig{
+			SweepInterval: 60,
+		},
+		Alerts: AlertConfig{
+			QueueSize:     1000,
+			Timeout:       10,
+			RelayInterval: 5,
+			SMTP: SMTPConfig{
+				Port: 587,
+			},
+		},
+		Expiry: ExpiryConfig{
+			RunAt:       "00:05",
+			LeaderRetry: 60,
+		},
+		Auth: AuthConfig{
+			JWKSRefresh: 300,
+			ClockSkew:   30,
+		},
 	}
 }
 
@@ -143,6 +324,21 @@ func overrideWithEnv(config *AppConfig) {
 	if mode := os.Getenv("GIN_MODE"); mode != "" {
 		config.Server.Mode = mode
 	}
+	if timeout := getEnvAsInt("SERVER_READ_TIMEOUT", 0); timeout > 0 {
+		config.Server.ReadTimeout = timeout
+	}
+	if timeout := getEnvAsInt("SERVER_WRITE_TIMEOUT", 0); timeout > 0 {
+		config.Server.WriteTimeout = timeout
+	}
+	if timeout := getEnvAsInt("SERVER_IDLE_TIMEOUT", 0); timeout > 0 {
+		config.Server.IdleTimeout = timeout
+	}
+	if timeout := getEnvAsInt("SERVER_SHUTDOWN_TIMEOUT", 0); timeout > 0 {
+		config.Server.ShutdownTimeout = timeout
+	}
+	if timeout := getEnvAsInt("SERVER_REQUEST_TIMEOUT", -1); timeout >= 0 {
+		config.Server.RequestTimeout = timeout
+	}
 
 	// 數據庫配置
 	if host := os.Getenv("DB_HOST"); host != "" {
@@ -163,6 +359,8 @@ func overrideWithEnv(config *AppConfig) {
 	if sslMode := os.Getenv("DB_SSL_MODE"); sslMode != "" {
 		config.Database.SSLMode = sslMode
 	}
+	config.Database.AutoMigrate = getEnvAsBool("DB_AUTO_MIGRATE", config.Database.AutoMigrate)
+	config.Database.RowLevelSecurity = getEnvAsBool("DB_ROW_LEVEL_SECURITY", config.Database.RowLevelSecurity)
 
 	// 日誌配置
 	if level := os.Getenv("LOG_LEVEL"); level != "" {
@@ -192,22 +390,125 @@ func overrideWithEnv(config *AppConfig) {
 	if compress := getEnvAsBool("LOG_COMPRESS", config.Logger.Compress); compress != config.Logger.Compress {
 		config.Logger.Compress = compress
 	}
+
+	// 分頁配置
+	if secret := os.Getenv("CURSOR_SECRET"); secret != "" {
+		config.Pagination.CursorSecret = secret
+	}
+
+	// 管理與保留期配置
+	if token := os.Getenv("ADMIN_TOKEN"); token != "" {
+		config.Admin.Token = token
+	}
+	if days := getEnvAsInt("TRASH_RETENTION_DAYS", -1); days >= 0 {
+		config.Retention.TrashDays = days
+	}
+	if interval := getEnvAsInt("TRASH_CHECK_INTERVAL", 0); interval > 0 {
+		config.Retention.CheckInterval = interval
+	}
+	if interval := getEnvAsInt("RESERVATION_SWEEP_INTERVAL", -1); interval >= 0 {
+		config.Reservations.SweepInterval = interval
+	}
+
+	// 低庫存通知配置
+	if size := getEnvAsInt("ALERT_QUEUE_SIZE", 0); size > 0 {
+		config.Alerts.QueueSize = size
+	}
+	if timeout := getEnvAsInt("ALERT_TIMEOUT", 0); timeout > 0 {
+		config.Alerts.Timeout = timeout
+	}
+	if interval := getEnvAsInt("ALERT_RELAY_INTERVAL", -1); interval >= 0 {
+		config.Alerts.RelayInterval = interval
+	}
+	if url := os.Getenv("ALERT_WEBHOOK_URL"); url != "" {
+		config.Alerts.WebhookURL = url
+	}
+	if host := os.Getenv("ALERT_SMTP_HOST"); host != "" {
+		config.Alerts.SMTP.Host = host
+	}
+	if port := getEnvAsInt("ALERT_SMTP_PORT", 0); port > 0 {
+		config.Alerts.SMTP.Port = port
+	}
+	if username := os.Getenv("ALERT_SMTP_USERNAME"); username != "" {
+		config.Alerts.SMTP.Username = username
+	}
+	if password := os.Getenv("ALERT_SMTP_PASSWORD"); password != "" {
+		config.Alerts.SMTP.Password = password
+	}
+	if from := os.Getenv("ALERT_SMTP_FROM"); from != "" {
+		config.Alerts.SMTP.From = from
+	}
+	if to := os.Getenv("ALERT_SMTP_TO"); to != "" {
+		config.Alerts.SMTP.To = strings.Split(to, ",")
+	}
+
+	// 過期清理配置，EXPIRY_RUN_AT 設為 off 時停用
+	if runAt, exists := os.LookupEnv("EXPIRY_RUN_AT"); exists {
+		if runAt == "off" {
+			runAt = ""
+		}
+		config.Expiry.RunAt = runAt
+	}
+	if retry := getEnvAsInt("EXPIRY_LEADER_RETRY", 0); retry > 0 {
+		config.Expiry.LeaderRetry = retry
+	}
+
+	// 身分驗證配置
+	config.Auth.APIKeys = getEnvAsBool("AUTH_API_KEYS", config.Auth.APIKeys)
+	if secret := os.Getenv("AUTH_HS256_SECRET"); secret != "" {
+		config.Auth.HS256Secret = secret
+	}
+	if file := os.Getenv("AUTH_JWKS_FILE"); file != "" {
+		config.Auth.JWKSFile = file
+	}
+	if url := os.Getenv("AUTH_JWKS_URL"); url != "" {
+		config.Auth.JWKSURL = url
+	}
+	if refresh := getEnvAsInt("AUTH_JWKS_REFRESH", 0); refresh > 0 {
+		config.Auth.JWKSRefresh = refresh
+	}
+	if issuer := os.Getenv("AUTH_ISSUER"); issuer != "" {
+		config.Auth.Issuer = issuer
+	}
+	if audience := os.Getenv("AUTH_AUDIENCE"); audience != "" {
+		config.Auth.Audience = audience
+	}
+	if skew := getEnvAsInt("AUTH_CLOCK_SKEW", -1); skew >= 0 {
+		config.Auth.ClockSkew = skew
+	}
 }
 
 // logConfig 記錄配置信息（排除敏感信息）
 func logConfig(config *AppConfig) {
-	log.Printf("服務器配置: 端口=%d, 模式=%s",
-		config.Server.Port, config.Server.Mode)
+	log.Printf("服務器配置: 端口=%d, 模式=%s, 讀取超時=%ds, 寫入超時=%ds, 閒置超時=%ds, 關閉超時=%ds",
+		config.Server.Port, config.Server.Mode,
+		config.Server.ReadTimeout, config.Server.WriteTimeout,
+		config.Server.IdleTimeout, config.Server.ShutdownTimeout)
 
-	log.Printf("數據庫配置: 主機=%s, 端口=%d, 用戶=%s, 數據庫=%s, SSL模式=%s",
+	log.Printf("數據庫配置: 主機=%s, 端口=%d, 用戶=%s, 數據庫=%s, SSL模式=%s, 自動遷移=%v, 資料列安全=%v",
 		config.Database.Host, config.Database.Port,
 		config.Database.User, config.Database.DBName,
-		config.Database.SSLMode)
+		config.Database.SSLMode, config.Database.AutoMigrate, config.Database.RowLevelSecurity)
 
 	log.Printf("日誌配置: 級別=%s, 格式=%s, 輸出路徑=%s, 錯誤輸出=%s, 輪轉=%v",
 		config.Logger.Level, config.Logger.Format,
 		config.Logger.OutputPaths, config.Logger.ErrorOutputs,
 		config.Logger.EnableRotate)
+
+	log.Printf("回收站配置: 保留天數=%d, 檢查間隔=%d分鐘, 管理端點=%v",
+		config.Retention.TrashDays, config.Retention.CheckInterval, config.Admin.Token != "")
+
+	log.Printf("預留配置: 清理間隔=%d秒", config.Reservations.SweepInterval)
+
+	log.Printf("低庫存通知配置: 佇列容量=%d, 派送時限=%ds, 取出間隔=%ds, Webhook=%v, SMTP=%v",
+		config.Alerts.QueueSize, config.Alerts.Timeout, config.Alerts.RelayInterval,
+		config.Alerts.WebhookURL != "", config.Alerts.SMTP.Enabled())
+
+	log.Printf("過期清理配置: 執行時間=%q(UTC), 領導權檢查間隔=%d秒", config.Expiry.RunAt, config.Expiry.LeaderRetry)
+
+	log.Printf("身分驗證配置: JWT=%v, API金鑰=%v, HS256=%v, JWKS文件=%s, JWKS URL=%s, 簽發者=%s, 受眾=%s, 時鐘誤差=%d秒",
+		config.Auth.JWTEnabled(), config.Auth.APIKeys, config.Auth.HS256Secret != "", config.Auth.JWKSFile, config.Auth.JWKSURL,
+		config.Auth.Issuer, config.Auth.Audience, config.Auth.ClockSkew)
 }
 
 // 從環境變數獲取整數值
@@ -0,0 +1,156 @@
+package controller
+
+import (
+	"errors"
+	"main/internal/auth"
+	"main/internal/middleware"
+	model "main/internal/models"
+	"main/internal/repository"
+	"main/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type APIKeyController struct {
+	service service.APIKeyService
+	logger  *zap.Logger
+}
+
+func NewAPIKeyController(service service.APIKeyService, logger *zap.Logger) *APIKeyController {
+	return &APIKeyController{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// RegisterAdminRoutes 註冊 API 金鑰的管理端點，admin 應已掛上管理員驗證的中間件
+func (h *APIKeyController) RegisterAdminRoutes(admin *gin.RouterGroup) {
+	keys := admin.Group("/api-keys", middleware.RequirePermission(auth.PermAPIKeyManage))
+	{
+		keys.GET("", h.ListAPIKeys)
+		keys.POST("", h.CreateAPIKey)
+		keys.POST("/:id/rotate", h.RotateAPIKey)
+		keys.DELETE("/:id", h.RevokeAPIKey)
+	}
+}
+
+// ListAPIKeys 列出請求租戶的所有 API 金鑰，包括已撤銷的，不返回明文
+func (h *APIKeyController) ListAPIKeys(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	keys, err := h.service.ListAPIKeys(c.Request.Context())
+	if err != nil {
+		respondWithAPIKeyError(c, err, "API_KEY_FETCH_ERROR", "獲取 API 金鑰列表失敗", requestID)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": keys})
+}
+
+// CreateAPIKey 創建 API 金鑰，響應中的 key 為唯一一次返回的明文
+func (h *APIKeyController) CreateAPIKey(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	var req model.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithBindError(c, err, requestID)
+		return
+	}
+
+	created, err := h.service.CreateAPIKey(c.Request.Context(), req)
+	if err != nil {
+		respondWithAPIKeyError(c, err, "API_KEY_CREATE_ERROR", "創建 API 金鑰失敗", requestID)
+		return
+	}
+
+	h.logger.Info("API 金鑰已創建",
+		zap.Int64("api_key_id", created.ID),
+		zap.String("name", created.Name),
+		zap.String("prefix", created.Prefix),
+		zap.String("request_id", requestID),
+	)
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, created)
+}
+
+// RotateAPIKey 為金鑰生成新的明文，舊的明文立即失效
+func (h *APIKeyController) RotateAPIKey(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	id, ok := parseAPIKeyID(c, requestID)
+	if !ok {
+		return
+	}
+
+	rotated, err := h.service.RotateAPIKey(c.Request.Context(), id)
+	if err != nil {
+		respondWithAPIKeyError(c, err, "API_KEY_ROTATE_ERROR", "輪換 API 金鑰失敗", requestID)
+		return
+	}
+
+	h.logger.Info("API 金鑰已輪換",
+		zap.Int64("api_key_id", rotated.ID),
+		zap.String("prefix", rotated.Prefix),
+		zap.String("request_id", requestID),
+	)
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, rotated)
+}
+
+// RevokeAPIKey 撤銷 API 金鑰，重複撤銷不會出錯
+func (h *APIKeyController) RevokeAPIKey(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	id, ok := parseAPIKeyID(c, requestID)
+	if !ok {
+		return
+	}
+
+	if _, err := h.service.RevokeAPIKey(c.Request.Context(), id); err != nil {
+		respondWithAPIKeyError(c, err, "API_KEY_REVOKE_ERROR", "撤銷 API 金鑰失敗", requestID)
+		return
+	}
+
+	h.logger.Info("API 金鑰已撤銷",
+		zap.Int64("api_key_id", id),
+		zap.String("request_id", requestID),
+	)
+
+	c.Status(http.StatusNoContent)
+}
+
+// parseAPIKeyID 解析路徑中的 API 金鑰 ID，無效時回應 400
+func parseAPIKeyID(c *gin.Context, requestID string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, "INVALID_API_KEY_ID", "無效的 API 金鑰ID", requestID)
+		return 0, false
+	}
+	return id, true
+}
+
+// respondWithAPIKeyError 將 API 金鑰服務的錯誤對應為狀態碼與錯誤碼，無法識別的錯誤使用 fallback 並返回 500
+func respondWithAPIKeyError(c *gin.Context, err error, fallbackCode string, fallbackMessage string, requestID string) {
+	var validationErr *model.ValidationError
+
+	switch {
+	case errors.Is(err, repository.ErrAPIKeyNotFound):
+		respondWithError(c, http.StatusNotFound, "API_KEY_NOT_FOUND", err.Error(), requestID)
+	case errors.Is(err, repository.ErrAPIKeyRevoked):
+		respondWithError(c, http.StatusConflict, "API_KEY_REVOKED", err.Error(), requestID)
+	case errors.Is(err, repository.ErrDuplicateAPIKey):
+		respondWithError(c, http.StatusConflict, "DUPLICATE_API_KEY", err.Error(), requestID)
+	case errors.As(err, &validationErr):
+		respondWithError(c, http.StatusBadRequest, "API_KEY_VALIDATION_ERROR", validationErr.Message, requestID)
+	default:
+		if respondWithContextError(c, err, requestID) {
+			return
+		}
+		respondWithError(c, http.StatusInternalServerError, fallbackCode, fallbackMessage, requestID)
+	}
+}
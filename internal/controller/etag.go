@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"errors"
+	model "main/internal/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 條件請求錯誤
+var (
+	errMissingIfMatch = errors.New("缺少 If-Match 標頭")
+	errInvalidIfMatch = errors.New("無效的 If-Match 標頭")
+)
+
+// productETag 以版本號生成產品的強 ETag
+func productETag(product model.Product) string {
+	return `"` + strconv.Itoa(product.Version) + `"`
+}
+
+// setProductETag 在響應中設置產品的 ETag
+func setProductETag(c *gin.Context, product model.Product) {
+	c.Header("ETag", productETag(product))
+}
+
+// parseIfMatch 從 If-Match 標頭解析客戶端持有的版本號，"*" 表示不檢查版本並返回 0
+func parseIfMatch(c *gin.Context) (int, error) {
+	value := strings.TrimSpace(c.GetHeader("If-Match"))
+	if value == "" {
+		return 0, errMissingIfMatch
+	}
+	if value == "*" {
+		return 0, nil
+	}
+
+	// If-Match 使用強比較，弱 ETag 與多個 ETag 均不接受
+	if len(value) < 2 || !strings.HasPrefix(value, `"`) || !strings.HasSuffix(value, `"`) {
+		return 0, errInvalidIfMatch
+	}
+	version, err := strconv.Atoi(value[1 : len(value)-1])
+	if err != nil || version <= 0 {
+		return 0, errInvalidIfMatch
+	}
+
+	return version, nil
+}
+
+// respondWithIfMatchError 回應 If-Match 解析錯誤
+func respondWithIfMatchError(c *gin.Context, err error, requestID string) {
+	if errors.Is(err, errMissingIfMatch) {
+		respondWithError(c, http.StatusPreconditionRequired, "PRECONDITION_REQUIRED", "更新或刪除產品時必須提供 If-Match 標頭", requestID)
+		return
+	}
+	respondWithError(c, http.StatusBadRequest, "INVALID_IF_MATCH", "If-Match 必須為單個 ETag，例如 \"3\"", requestID)
+}
+
+// ifNoneMatch 檢查 If-None-Match 是否包含目前的 ETag（弱比較）
+func ifNoneMatch(c *gin.Context, etag string) bool {
+	header := c.GetHeader("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"main/internal/auth"
+	"main/internal/middleware"
+	model "main/internal/models"
+	"main/internal/service"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type ExpiryController struct {
+	service service.ExpiryService
+	logger  *zap.Logger
+}
+
+func NewExpiryController(service service.ExpiryService, logger *zap.Logger) *ExpiryController {
+	return &ExpiryController{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// RegisterRoutes 註冊即將到期報表路由
+func (h *ExpiryController) RegisterRoutes(router *gin.Engine) {
+	reports := router.Group("/api/v1/reports")
+	{
+		reports.GET("/expiring", middleware.RequirePermission(auth.PermProductRead), h.GetExpiringReport)
+	}
+}
+
+// GetExpiringReport 列出 within 天內到期的庫存，group_by 為 warehouse（預設）或 lot
+func (h *ExpiryController) GetExpiringReport(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	query := model.ExpiringQuery{WithinDays: model.DefaultExpiringWithinDays, GroupBy: c.Query("group_by")}
+	if within := c.Query("within"); within != "" {
+		days, err := parseWithinDays(within)
+		if err != nil {
+			respondWithError(c, http.StatusBadRequest, "INVALID_QUERY_PARAMS", err.Error(), requestID)
+			return
+		}
+		query.WithinDays = days
+	}
+
+	report, err := h.service.GetExpiringReport(c.Request.Context(), query)
+	if err != nil {
+		var validationErr *model.ValidationError
+		if errors.As(err, &validationErr) {
+			respondWithError(c, http.StatusBadRequest, "INVALID_QUERY_PARAMS", validationErr.Message, requestID)
+			return
+		}
+		if respondWithContextError(c, err, requestID) {
+			return
+		}
+		respondWithError(c, http.StatusInternalServerError, "EXPIRING_REPORT_FETCH_ERROR", "獲取即將到期報表失敗", requestID)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// parseWithinDays 解析查詢範圍的天數，接受 30d、4w 或不帶單位的天數
+func parseWithinDays(within string) (int, error) {
+	unit := 1
+	number := within
+	switch {
+	case strings.HasSuffix(within, "d"):
+		number = strings.TrimSuffix(within, "d")
+	case strings.HasSuffix(within, "w"):
+		unit = 7
+		number = strings.TrimSuffix(within, "w")
+	}
+
+	n, err := strconv.Atoi(number)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("無效的 within 參數: %s，格式為天數加上 d 或 w，例如 30d", within)
+	}
+	return n * unit, nil
+}
@@ -0,0 +1,34 @@
+package controller
+
+import (
+	model "main/internal/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetLowStockReport 列出庫存低於補貨點的產品，缺口最大的在前；支援偏移分頁與 sku_code、sku_name 過濾
+func (h *ProductController) GetLowStockReport(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	var query model.ProductQuery
+	if err := parseOffsetPage(c, &query); err != nil {
+		respondWithError(c, http.StatusBadRequest, "INVALID_QUERY_PARAMS", err.Error(), requestID)
+		return
+	}
+	query.SkuCode = c.Query("sku_code")
+	query.SkuName = c.Query("sku_name")
+
+	page, err := h.service.GetLowStockProducts(c.Request.Context(), query)
+	if err != nil {
+		if respondWithContextError(c, err, requestID) {
+			return
+		}
+		respondWithError(c, http.StatusInternalServerError, "LOW_STOCK_FETCH_ERROR", "獲取低庫存報表失敗", requestID)
+		return
+	}
+
+	page.Links = buildPageLinks(c.Request.URL, page, false)
+
+	c.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	model "main/internal/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BatchItemResult 批量操作中單項的結果
+type BatchItemResult struct {
+	Index   int            `json:"index"`
+	Status  int            `json:"status"`
+	Product *model.Product `json:"product,omitempty"`
+	Error   *ErrorResponse `json:"error,omitempty"`
+}
+
+// BatchResponse 批量操作的結果
+type BatchResponse struct {
+	Results   []BatchItemResult `json:"results"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+}
+
+// ProductAction 處理 POST /api/v1/products:<方法> 形式的自定義方法
+// gin 無法在同一路徑段中註冊帶冒號的靜態路由，因此以參數路由接收後再分派
+func (h *ProductController) ProductAction(c *gin.Context) {
+	switch c.Param("action") {
+	case "products:batchCreate":
+		h.BatchCreateProducts(c)
+	case "products:batchUpdate":
+		h.BatchUpdateProducts(c)
+	case "products:batchDelete":
+		h.BatchDeleteProducts(c)
+	default:
+		respondWithError(c, http.StatusNotFound, "NOT_FOUND", "找不到請求的資源", c.GetHeader("X-Request-ID"))
+	}
+}
+
+// BatchCreateProducts 批量創建產品
+func (h *ProductController) BatchCreateProducts(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	var req model.ProductBatchRequest
+	if !bindBatchRequest(c, &req, func() int { return len(req.Items) }, requestID) {
+		return
+	}
+
+	outcomes, err := h.service.BatchCreateProducts(c.Request.Context(), req.Items, req.Atomic)
+	h.respondWithBatch(c, outcomes, err, http.StatusCreated, "PRODUCT_CREATE_ERROR", "創建產品失敗", requestID)
+}
+
+// BatchUpdateProducts 批量以完整內容替換產品，每項需帶 id 與 version
+func (h *ProductController) BatchUpdateProducts(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	var req model.ProductBatchRequest
+	if !bindBatchRequest(c, &req, func() int { return len(req.Items) }, requestID) {
+		return
+	}
+
+	outcomes, err := h.service.BatchUpdateProducts(c.Request.Context(), req.Items, req.Atomic)
+	h.respondWithBatch(c, outcomes, err, http.StatusOK, "PRODUCT_UPDATE_ERROR", "更新產品失敗", requestID)
+}
+
+// BatchDeleteProducts 批量刪除產品，每項需帶 id 與 version
+func (h *ProductController) BatchDeleteProducts(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	var req model.ProductBatchDeleteRequest
+	if !bindBatchRequest(c, &req, func() int { return len(req.Items) }, requestID) {
+		return
+	}
+
+	outcomes, err := h.service.BatchDeleteProducts(c.Request.Context(), req.Items, req.Atomic)
+	h.respondWithBatch(c, outcomes, err, http.StatusOK, "PRODUCT_DELETE_ERROR", "刪除產品失敗", requestID)
+}
+
+// bindBatchRequest 解析批量請求並檢查項目數量，失敗時已回應
+func bindBatchRequest(c *gin.Context, req interface{}, count func() int, requestID string) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		respondWithBindError(c, err, requestID)
+		return false
+	}
+
+	if n := count(); n == 0 || n > model.MaxBatchSize {
+		respondWithError(c, http.StatusBadRequest, "INVALID_BATCH_SIZE",
+			fmt.Sprintf("items 必須包含 1 到 %d 項", model.MaxBatchSize), requestID)
+		return false
+	}
+
+	return true
+}
+
+// respondWithBatch 回應批量操作的結果
+// 全部成功時返回 200，部分失敗時返回 207；atomic 模式失敗時以失敗項目的狀態碼回應
+func (h *ProductController) respondWithBatch(c *gin.Context, outcomes []model.BatchOutcome, err error, successStatus int, fallbackCode string, fallbackMessage string, requestID string) {
+	if err != nil {
+		status, code, message := productErrorStatus(c, err, fallbackCode, fallbackMessage)
+
+		var itemErr *model.BatchItemError
+		if errors.As(err, &itemErr) {
+			message = fmt.Sprintf("第 %d 項: %s", itemErr.Index, message)
+		}
+		respondWithError(c, status, code, message, requestID)
+		return
+	}
+
+	response := BatchResponse{Results: make([]BatchItemResult, len(outcomes))}
+	for i, outcome := range outcomes {
+		result := BatchItemResult{Index: i, Status: successStatus}
+
+		if outcome.Err != nil {
+			status, code, message := productErrorStatus(c, outcome.Err, fallbackCode, fallbackMessage)
+			result.Status = status
+			result.Error = &ErrorResponse{ErrorCode: code, ErrorMessage: message, RequestID: requestID}
+			response.Failed++
+		} else {
+			product := outcome.Product
+			result.Product = &product
+			response.Succeeded++
+		}
+
+		response.Results[i] = result
+	}
+
+	status := http.StatusOK
+	if response.Failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	c.JSON(status, response)
+}
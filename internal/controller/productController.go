@@ -1,51 +1,97 @@
 package controller
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"main/internal/auth"
+	"main/internal/middleware"
 	model "main/internal/models"
+	"main/internal/pagination"
 	"main/internal/repository"
 	"main/internal/service"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
-type ErrorResponse struct {
-	ErrorCode    string `json:"error_code"`
-	ErrorMessage string `json:"error_message"`
-	RequestID    string `json:"request_id,omitempty"`
-}
+// StatusClientClosedRequest 客戶端在回應前斷開連接（沿用 nginx 的 499）
+const StatusClientClosedRequest = 499
+
+// PATCH 支援的修補格式
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	jsonPatchContentType  = "application/json-patch+json"
+)
+
+// ErrorResponse 錯誤回應的格式，與中介層共用 model.ErrorResponse
+type ErrorResponse = model.ErrorResponse
 
 type ProductController struct {
 	service service.ProductService
 	logger  *zap.Logger
+	cursors *pagination.CursorSigner
 }
 
-func NewProducController(service service.ProductService, logger *zap.Logger) *ProductController {
+func NewProducController(service service.ProductService, logger *zap.Logger, cursors *pagination.CursorSigner) *ProductController {
 	return &ProductController{
 		service: service,
 		logger:  logger,
+		cursors: cursors,
 	}
 }
 
+// 依動作分派的路由所需的權限
+var (
+	productItemActionPermissions = map[string]auth.Permission{
+		"stock:adjust": auth.PermStockAdjust,
+	}
+	productActionPermissions = map[string]auth.Permission{
+		"products:batchCreate": auth.PermProductWrite,
+		"products:batchUpdate": auth.PermProductWrite,
+		"products:batchDelete": auth.PermProductDelete,
+	}
+)
+
 // RegisterRoutes 註冊路由
 func (h *ProductController) RegisterRoutes(router *gin.Engine) {
 
 	router.GET("/health", h.HealthCheck)
 
+	read := middleware.RequirePermission(auth.PermProductRead)
+	write := middleware.RequirePermission(auth.PermProductWrite)
+	del := middleware.RequirePermission(auth.PermProductDelete)
+
 	api := router.Group("/api/v1")
 	{
 		products := api.Group("/products")
 		{
-			products.GET("", h.GetProducts)
-			products.GET("/:id", h.GetProduct)
-			products.POST("", h.CreateProduct)
-			products.PUT("/:id", h.UpdateProduct)
-			products.DELETE("/:id", h.DeleteProduct)
+			products.GET("", read, h.GetProducts)
+			products.GET("/export", read, h.ExportProducts)
+			products.POST("/import", write, h.ImportProducts)
+			products.GET("/by-sku/:sku_code", read, h.GetProductBySku)
+			products.PUT("/by-sku/:sku_code", write, h.UpsertProductBySku)
+			products.GET("/:id", read, h.GetProduct)
+			products.POST("", write, h.CreateProduct)
+			products.PUT("/:id", write, h.UpdateProduct)
+			products.PATCH("/:id", write, h.PatchProduct)
+			products.DELETE("/:id", del, h.DeleteProduct)
+			products.GET("/trash", read, h.GetTrash)
+			products.POST("/:id/restore", del, h.RestoreProduct)
+			products.GET("/:id/history", read, h.GetProductHistory)
+			// 單一產品的動作：POST /api/v1/products/:id/stock:adjust
+			products.POST("/:id/:action", middleware.RequireActionPermission("action", productItemActionPermissions), h.ProductItemAction)
 		}
+
+		api.GET("/alerts/low-stock", read, h.GetLowStockReport)
+
+		// 批量操作：POST /api/v1/products:batchCreate、:batchUpdate、:batchDelete
+		api.POST("/:action", middleware.RequireActionPermission("action", productActionPermissions), h.ProductAction)
 	}
 }
 
@@ -58,18 +104,271 @@ func (h *ProductController) HealthCheck(c *gin.Context) {
 	})
 }
 
-// GetProducts 獲取所有產品
+// GetProducts 獲取產品列表，支援偏移分頁、游標分頁、排序與過濾
 func (h *ProductController) GetProducts(c *gin.Context) {
-	products, err := h.service.GetProducts()
+	requestID := c.GetHeader("X-Request-ID")
+
+	query, err := h.parseProductQuery(c)
+	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			respondWithError(c, http.StatusBadRequest, "INVALID_CURSOR", err.Error(), requestID)
+			return
+		}
+		respondWithError(c, http.StatusBadRequest, "INVALID_QUERY_PARAMS", err.Error(), requestID)
+		return
+	}
+
+	page, err := h.service.GetProducts(c.Request.Context(), query)
 	if err != nil {
-		respondWithError(c, http.StatusInternalServerError, "PRODUCT_FETCH_ERROR", "獲取產品列表失敗", c.GetHeader("X-Request-ID"))
+		if respondWithContextError(c, err, requestID) {
+			return
+		}
+		respondWithError(c, http.StatusInternalServerError, "PRODUCT_FETCH_ERROR", "獲取產品列表失敗", requestID)
+		return
+	}
+
+	h.attachCursors(&page, query)
+	page.Links = buildPageLinks(c.Request.URL, page, query.Keyset != nil)
+
+	c.JSON(http.StatusOK, page)
+}
+
+// parseProductQuery 從查詢參數解析產品列表選項
+func (h *ProductController) parseProductQuery(c *gin.Context) (model.ProductQuery, error) {
+	var query model.ProductQuery
+
+	if err := parseOffsetPage(c, &query); err != nil {
+		return query, err
+	}
+
+	if v := c.Query("sort_by"); v != "" {
+		if !model.IsSortableProductField(v) {
+			return query, fmt.Errorf("不支援的排序欄位: %s", v)
+		}
+		query.SortBy = v
+	}
+
+	if v := c.Query("sort_dir"); v != "" {
+		v = strings.ToLower(v)
+		if v != model.SortAsc && v != model.SortDesc {
+			return query, errors.New("sort_dir 只能為 asc 或 desc")
+		}
+		query.SortDir = v
+	}
+
+	// 游標自帶排序方式，與明確指定的排序參數衝突時拒絕
+	if token := c.Query("cursor"); token != "" {
+		if c.Query("offset") != "" {
+			return query, errors.New("cursor 與 offset 不能同時使用")
+		}
+
+		cursor, err := h.cursors.Decode(token)
+		if err != nil {
+			return query, err
+		}
+
+		if (query.SortBy != "" && query.SortBy != cursor.SortBy) ||
+			(query.SortDir != "" && query.SortDir != cursor.SortDir) {
+			return query, errors.New("cursor 的排序方式與 sort_by/sort_dir 不一致")
+		}
+
+		query.SortBy = cursor.SortBy
+		query.SortDir = cursor.SortDir
+		query.Keyset = &model.ProductKeyset{
+			Value:    cursor.Value,
+			ID:       cursor.ID,
+			Backward: cursor.Backward,
+		}
+	}
+
+	query.SkuCode = c.Query("sku_code")
+	query.SkuName = c.Query("sku_name")
+
+	if v := c.Query("min_amount"); v != "" {
+		amount, err := strconv.Atoi(v)
+		if err != nil {
+			return query, errors.New("min_amount 必須為整數")
+		}
+		query.MinAmount = &amount
+	}
+
+	if v := c.Query("max_amount"); v != "" {
+		amount, err := strconv.Atoi(v)
+		if err != nil {
+			return query, errors.New("max_amount 必須為整數")
+		}
+		query.MaxAmount = &amount
+	}
+
+	if query.MinAmount != nil && query.MaxAmount != nil && *query.MinAmount > *query.MaxAmount {
+		return query, errors.New("min_amount 不能大於 max_amount")
+	}
+
+	if v := c.Query("warehouse_id"); v != "" {
+		warehouseID, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || warehouseID <= 0 {
+			return query, errors.New("warehouse_id 必須為正整數")
+		}
+		query.WarehouseID = warehouseID
+	}
+
+	if v := c.Query("expiration_from"); v != "" {
+		date, err := model.ParseDate(v)
+		if err != nil {
+			return query, errors.New("expiration_from 格式必須為 YYYY-MM-DD")
+		}
+		query.ExpirationFrom = date
+	}
+
+	if v := c.Query("expiration_to"); v != "" {
+		date, err := model.ParseDate(v)
+		if err != nil {
+			return query, errors.New("expiration_to 格式必須為 YYYY-MM-DD")
+		}
+		query.ExpirationTo = date
+	}
+
+	return query, nil
+}
+
+// parseOffsetPage 解析偏移分頁參數 page_size 與 offset
+func parseOffsetPage(c *gin.Context, query *model.ProductQuery) error {
+	if v := c.Query("page_size"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size <= 0 || size > model.MaxPageSize {
+			return fmt.Errorf("page_size 必須介於 1 到 %d 之間", model.MaxPageSize)
+		}
+		query.PageSize = size
+	}
+
+	if v := c.Query("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return errors.New("offset 必須為非負整數")
+		}
+		query.Offset = offset
+	}
+
+	return nil
+}
+
+// attachCursors 以當前頁首尾兩筆產生上一頁/下一頁的簽名游標
+func (h *ProductController) attachCursors(page *model.ProductPage, query model.ProductQuery) {
+	if len(page.Items) == 0 {
+		return
+	}
+
+	sortBy := query.SortBy
+	if sortBy == "" {
+		sortBy = "id"
+	}
+	sortDir := query.SortDir
+	if sortDir == "" {
+		sortDir = model.SortAsc
+	}
+
+	// 可為空的欄位無法作為鍵集
+	if !model.IsKeysetSortField(sortBy) {
 		return
 	}
 
-	c.JSON(http.StatusOK, products)
+	newCursor := func(p model.Product, backward bool) string {
+		return h.cursors.Encode(pagination.Cursor{
+			SortBy:   sortBy,
+			SortDir:  sortDir,
+			Value:    productSortValue(p, sortBy),
+			ID:       p.ID,
+			Backward: backward,
+		})
+	}
+
+	if page.HasNext {
+		page.NextCursor = newCursor(page.Items[len(page.Items)-1], false)
+	}
+	if page.HasPrev {
+		page.PrevCursor = newCursor(page.Items[0], true)
+	}
+}
+
+// productSortValue 取得產品在指定排序欄位上的值
+func productSortValue(p model.Product, field string) string {
+	switch field {
+	case "sku_code":
+		return p.SkuCode
+	case "sku_name":
+		return p.SkuName
+	case "sku_amount":
+		return strconv.Itoa(p.SkuAmount)
+	case "create_at":
+		// 鍵集比較需要完整精度，不能使用 JSON 中到秒的格式
+		return p.CreateAt.UTC().Format(time.RFC3339Nano)
+	case "update_at":
+		return p.UpdateAt.UTC().Format(time.RFC3339Nano)
+	default:
+		return ""
+	}
 }
 
-// GetProduct 獲取單個產品
+// buildPageLinks 根據當前請求與分頁結果構建上一頁/下一頁連結
+func buildPageLinks(requestURL *url.URL, page model.ProductPage, keyset bool) model.PageLinks {
+	link := func(set map[string]string, remove string) string {
+		u := *requestURL
+		q := u.Query()
+		q.Del(remove)
+		for k, v := range set {
+			q.Set(k, v)
+		}
+		u.RawQuery = q.Encode()
+		return u.RequestURI()
+	}
+
+	pageSize := strconv.Itoa(page.PageSize)
+
+	// 游標模式下連結沿用游標，不再使用 offset
+	if keyset {
+		links := model.PageLinks{Self: requestURL.RequestURI()}
+		if page.NextCursor != "" {
+			links.Next = link(map[string]string{"page_size": pageSize, "cursor": page.NextCursor}, "offset")
+		}
+		if page.PrevCursor != "" {
+			links.Prev = link(map[string]string{"page_size": pageSize, "cursor": page.PrevCursor}, "offset")
+		}
+		return links
+	}
+
+	return offsetPageLinks(requestURL, page.PageSize, page.Offset, page.HasNext, page.HasPrev)
+}
+
+// offsetPageLinks 生成偏移分頁的 self/next/prev 連結，保留其他查詢參數
+func offsetPageLinks(requestURL *url.URL, pageSize, offset int, hasNext, hasPrev bool) model.PageLinks {
+	withOffset := func(o int) string {
+		u := *requestURL
+		q := u.Query()
+		q.Del("cursor")
+		q.Set("page_size", strconv.Itoa(pageSize))
+		q.Set("offset", strconv.Itoa(o))
+		u.RawQuery = q.Encode()
+		return u.RequestURI()
+	}
+
+	links := model.PageLinks{Self: withOffset(offset)}
+
+	if hasNext {
+		links.Next = withOffset(offset + pageSize)
+	}
+
+	if hasPrev {
+		prev := offset - pageSize
+		if prev < 0 {
+			prev = 0
+		}
+		links.Prev = withOffset(prev)
+	}
+
+	return links
+}
+
+// GetProduct 獲取單個產品，帶 as_of 時返回產品在該時間點的內容
 func (h *ProductController) GetProduct(c *gin.Context) {
 	requestID := c.GetHeader("X-Request-ID")
 
@@ -80,17 +379,33 @@ func (h *ProductController) GetProduct(c *gin.Context) {
 		return
 	}
 
-	product, err := h.service.GetProduct(id)
+	if asOf := c.Query("as_of"); asOf != "" {
+		h.getProductAsOf(c, id, asOf, requestID)
+		return
+	}
+
+	product, err := h.service.GetProduct(c.Request.Context(), id)
 	if err != nil {
 		if errors.Is(err, repository.ErrProductNotFound) {
 			respondWithError(c, http.StatusNotFound, "PRODUCT_NOT_FOUND", "產品未找到", requestID)
 			return
 		}
+		if respondWithContextError(c, err, requestID) {
+			return
+		}
 
 		respondWithError(c, http.StatusInternalServerError, "PRODUCT_FETCH_ERROR", "獲取產品失敗", requestID)
 		return
 	}
 
+	setProductETag(c, product)
+
+	// 客戶端緩存的版本仍是最新時不返回內容
+	if ifNoneMatch(c, productETag(product)) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
 	c.JSON(http.StatusOK, product)
 }
 
@@ -100,37 +415,33 @@ func (h *ProductController) CreateProduct(c *gin.Context) {
 
 	var input model.Product
 	if err := c.ShouldBindJSON(&input); err != nil {
-		respondWithError(c, http.StatusBadRequest, "INVALID_REQUEST_DATA", "無效的請求數據", requestID)
+		respondWithBindError(c, err, requestID)
 		return
 	}
 
-	// 基本驗證
-	if err := validateProduct(input); err != nil {
+	// 基本驗證，新產品的到期日不能已經過去
+	if err := model.ValidateNewProduct(input, model.Today()); err != nil {
 		respondWithError(c, http.StatusBadRequest, "PRODUCT_VALIDATION_ERROR", err.Error(), requestID)
 		return
 	}
 
-	product, err := h.service.CreateProduct(input)
+	product, err := h.service.CreateProduct(c.Request.Context(), input)
 	if err != nil {
+		if errors.Is(err, repository.ErrDuplicateSku) {
+			respondWithError(c, http.StatusConflict, "DUPLICATE_SKU", "產品編號已存在", requestID)
+			return
+		}
+		if respondWithContextError(c, err, requestID) {
+			return
+		}
 		respondWithError(c, http.StatusInternalServerError, "PRODUCT_CREATE_ERROR", "創建產品失敗", requestID)
 		return
 	}
 
+	setProductETag(c, product)
 	c.JSON(http.StatusCreated, product)
 }
 
-func validateProduct(product model.Product) error {
-	if product.SkuCode == "" {
-		return errors.New("產品名稱不能為空")
-	}
-
-	if product.SkuAmount < 0 {
-		return errors.New("產品庫存不能為負數")
-	}
-
-	return nil
-}
-
 func (h *ProductController) UpdateProduct(c *gin.Context) {
 	requestID := c.GetHeader("X-Request-ID")
 
@@ -141,33 +452,142 @@ func (h *ProductController) UpdateProduct(c *gin.Context) {
 		return
 	}
 
+	version, err := parseIfMatch(c)
+	if err != nil {
+		respondWithIfMatchError(c, err, requestID)
+		return
+	}
+
 	var input model.Product
 	if err := c.ShouldBindJSON(&input); err != nil {
-		respondWithError(c, http.StatusBadRequest, "INVALID_REQUEST_DATA", "無效的請求數據", requestID)
+		respondWithBindError(c, err, requestID)
 		return
 	}
 
 	// 基本驗證
-	if err := validateProduct(input); err != nil {
+	if err := model.ValidateProduct(input); err != nil {
 		respondWithError(c, http.StatusBadRequest, "PRODUCT_VALIDATION_ERROR", err.Error(), requestID)
 		return
 	}
 
-	product, err := h.service.UpdateProduct(id, input)
+	product, err := h.service.UpdateProduct(c.Request.Context(), id, version, input)
 	if err != nil {
-		switch {
-		case errors.Is(err, repository.ErrProductNotFound):
-			respondWithError(c, http.StatusNotFound, "PRODUCT_NOT_FOUND", "產品未找到", requestID)
+		respondWithUpdateError(c, err, requestID)
+		return
+	}
+
+	setProductETag(c, product)
+	c.JSON(http.StatusOK, product)
+}
+
+// PatchProduct 部分更新產品，支援 JSON Merge Patch 與 JSON Patch
+func (h *ProductController) PatchProduct(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, "INVALID_PRODUCT_ID", "無效的產品ID", requestID)
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		respondWithIfMatchError(c, err, requestID)
+		return
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, "INVALID_REQUEST_DATA", "無效的請求數據", requestID)
+		return
+	}
+
+	var product model.Product
+	switch c.ContentType() {
+	case mergePatchContentType:
+		patch, err := model.DecodeMergePatch(body)
+		if err != nil {
+			respondWithError(c, http.StatusBadRequest, "INVALID_PATCH", err.Error(), requestID)
+			return
+		}
+		product, err = h.service.MergePatchProduct(c.Request.Context(), id, version, patch)
+		if err != nil {
+			respondWithUpdateError(c, err, requestID)
+			return
+		}
+	case jsonPatchContentType:
+		ops, err := model.DecodeJSONPatch(body)
+		if err != nil {
+			respondWithError(c, http.StatusBadRequest, "INVALID_PATCH", err.Error(), requestID)
 			return
-		default:
-			respondWithError(c, http.StatusInternalServerError, "PRODUCT_UPDATE_ERROR", "更新產品失敗", requestID)
+		}
+		product, err = h.service.JSONPatchProduct(c.Request.Context(), id, version, ops)
+		if err != nil {
+			respondWithUpdateError(c, err, requestID)
 			return
 		}
+	default:
+		c.Header("Accept-Patch", mergePatchContentType+", "+jsonPatchContentType)
+		respondWithError(c, http.StatusUnsupportedMediaType, "UNSUPPORTED_PATCH_TYPE",
+			"Content-Type 必須為 "+mergePatchContentType+" 或 "+jsonPatchContentType, requestID)
+		return
 	}
 
+	setProductETag(c, product)
 	c.JSON(http.StatusOK, product)
 }
 
+// respondWithBindError 回應請求內容解析失敗，欄位格式錯誤（例如日期）時返回具體原因
+func respondWithBindError(c *gin.Context, err error, requestID string) {
+	var validationErr *model.ValidationError
+	if errors.As(err, &validationErr) {
+		respondWithError(c, http.StatusBadRequest, "PRODUCT_VALIDATION_ERROR", validationErr.Message, requestID)
+		return
+	}
+	respondWithError(c, http.StatusBadRequest, "INVALID_REQUEST_DATA", "無效的請求數據", requestID)
+}
+
+// respondWithUpdateError 回應更新或修補產品時的錯誤
+func respondWithUpdateError(c *gin.Context, err error, requestID string) {
+	status, code, message := productErrorStatus(c, err, "PRODUCT_UPDATE_ERROR", "更新產品失敗")
+	respondWithError(c, status, code, message, requestID)
+}
+
+// productErrorStatus 將服務層的錯誤對應為狀態碼、錯誤碼與訊息，無法識別的錯誤使用 fallback 並返回 500
+func productErrorStatus(c *gin.Context, err error, fallbackCode string, fallbackMessage string) (int, string, string) {
+	var validationErr *model.ValidationError
+
+	switch {
+	case errors.Is(err, repository.ErrProductNotFound):
+		return http.StatusNotFound, "PRODUCT_NOT_FOUND", "產品未找到"
+	case errors.Is(err, repository.ErrVersionConflict):
+		return http.StatusPreconditionFailed, "VERSION_CONFLICT", "產品已被其他請求修改，請重新獲取後再更新"
+	case errors.Is(err, repository.ErrDuplicateSku):
+		return http.StatusConflict, "DUPLICATE_SKU", "產品編號已存在"
+	case errors.Is(err, repository.ErrInsufficientStock):
+		return http.StatusConflict, "INSUFFICIENT_STOCK", "庫存不足"
+	case errors.Is(err, repository.ErrLocationNotFound):
+		return http.StatusNotFound, "LOCATION_NOT_FOUND", "儲位未找到"
+	case errors.Is(err, repository.ErrMovementNotFound):
+		return http.StatusNotFound, "MOVEMENT_NOT_FOUND", "庫存異動未找到"
+	case errors.Is(err, repository.ErrLotExpirationMismatch):
+		return http.StatusConflict, "LOT_EXPIRATION_MISMATCH", "批號已存在且到期日不同"
+	case errors.As(err, &validationErr):
+		return http.StatusBadRequest, "PRODUCT_VALIDATION_ERROR", validationErr.Message
+	case errors.Is(err, model.ErrPatchTestFailed):
+		return http.StatusConflict, "PATCH_TEST_FAILED", err.Error()
+	case errors.Is(err, model.ErrInvalidPatch):
+		return http.StatusBadRequest, "INVALID_PATCH", err.Error()
+	}
+
+	if status, code, message, ok := contextErrorStatus(c, err); ok {
+		return status, code, message
+	}
+
+	return http.StatusInternalServerError, fallbackCode, fallbackMessage
+}
+
 func (h *ProductController) DeleteProduct(c *gin.Context) {
 	requestID := c.GetHeader("X-Request-ID")
 
@@ -178,12 +598,25 @@ func (h *ProductController) DeleteProduct(c *gin.Context) {
 		return
 	}
 
-	err = h.service.DeleteProduct(id)
+	version, err := parseIfMatch(c)
+	if err != nil {
+		respondWithIfMatchError(c, err, requestID)
+		return
+	}
+
+	err = h.service.DeleteProduct(c.Request.Context(), id, version)
 	if err != nil {
 		if errors.Is(err, repository.ErrProductNotFound) {
 			respondWithError(c, http.StatusNotFound, "PRODUCT_NOT_FOUND", "產品未找到", requestID)
 			return
 		}
+		if errors.Is(err, repository.ErrVersionConflict) {
+			respondWithError(c, http.StatusPreconditionFailed, "VERSION_CONFLICT", "產品已被其他請求修改，請重新獲取後再刪除", requestID)
+			return
+		}
+		if respondWithContextError(c, err, requestID) {
+			return
+		}
 
 		respondWithError(c, http.StatusInternalServerError, "PRODUCT_DELETE_ERROR", "刪除產品失敗", requestID)
 		return
@@ -194,6 +627,33 @@ func (h *ProductController) DeleteProduct(c *gin.Context) {
 	})
 }
 
+// respondWithContextError 處理請求超時或客戶端斷開導致的錯誤，返回是否已回應
+func respondWithContextError(c *gin.Context, err error, requestID string) bool {
+	status, code, message, ok := contextErrorStatus(c, err)
+	if ok {
+		respondWithError(c, status, code, message, requestID)
+	}
+	return ok
+}
+
+// contextErrorStatus 識別與具體操作無關的錯誤：權限不足、請求超時或客戶端斷開
+func contextErrorStatus(c *gin.Context, err error) (int, string, string, bool) {
+	// 資料庫驅動取消查詢時不一定返回 context 錯誤，因此同時檢查請求的 context
+	ctxErr := c.Request.Context().Err()
+
+	switch {
+	case errors.Is(err, auth.ErrForbidden):
+		return http.StatusForbidden, model.ErrCodePermissionDenied, err.Error(), true
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(ctxErr, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, model.ErrCodeRequestTimeout, "請求處理超時", true
+	case errors.Is(err, context.Canceled) || errors.Is(ctxErr, context.Canceled):
+		// 客戶端已斷開，回應不會被讀取，狀態碼僅供日誌使用
+		return StatusClientClosedRequest, model.ErrCodeRequestCanceled, "請求已取消", true
+	default:
+		return 0, "", "", false
+	}
+}
+
 func respondWithError(c *gin.Context, statusCode int, errorCode string, message string, requestID string) {
 	c.JSON(statusCode, ErrorResponse{
 		ErrorCode:    errorCode,
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"errors"
+	model "main/internal/models"
+	"main/internal/repository"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetProductHistory 列出產品的變更歷史，依時間由新到舊排序，已刪除的產品仍可查詢
+func (h *ProductController) GetProductHistory(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, "INVALID_PRODUCT_ID", "無效的產品ID", requestID)
+		return
+	}
+
+	var query model.ProductQuery
+	if err := parseOffsetPage(c, &query); err != nil {
+		respondWithError(c, http.StatusBadRequest, "INVALID_QUERY_PARAMS", err.Error(), requestID)
+		return
+	}
+
+	page, err := h.service.GetProductHistory(c.Request.Context(), id, query)
+	if err != nil {
+		if errors.Is(err, repository.ErrProductNotFound) {
+			respondWithError(c, http.StatusNotFound, "PRODUCT_NOT_FOUND", "產品未找到", requestID)
+			return
+		}
+		if respondWithContextError(c, err, requestID) {
+			return
+		}
+		respondWithError(c, http.StatusInternalServerError, "PRODUCT_HISTORY_ERROR", "獲取產品歷史失敗", requestID)
+		return
+	}
+
+	page.Links = offsetPageLinks(c.Request.URL, page.PageSize, page.Offset, page.HasNext, page.HasPrev)
+
+	c.JSON(http.StatusOK, page)
+}
+
+// getProductAsOf 返回產品在 as_of（RFC 3339）時的內容；歷史內容不可修改，因此不設置 ETag
+func (h *ProductController) getProductAsOf(c *gin.Context, id int64, asOf string, requestID string) {
+	at, err := time.Parse(time.RFC3339Nano, asOf)
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, "INVALID_QUERY_PARAMS", "as_of 必須為 RFC 3339 時間，例如 2024-01-02T15:04:05Z", requestID)
+		return
+	}
+
+	product, err := h.service.GetProductAsOf(c.Request.Context(), id, at)
+	if err != nil {
+		if errors.Is(err, repository.ErrProductNotFound) {
+			respondWithError(c, http.StatusNotFound, "PRODUCT_NOT_FOUND", "產品在該時間點不存在", requestID)
+			return
+		}
+		if respondWithContextError(c, err, requestID) {
+			return
+		}
+		respondWithError(c, http.StatusInternalServerError, "PRODUCT_FETCH_ERROR", "獲取產品失敗", requestID)
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	model "main/internal/models"
+	"main/internal/productio"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// maxImportFileSize 匯入檔案的大小上限
+const maxImportFileSize = 32 << 20
+
+// ExportProducts 依列表的過濾與排序條件匯出所有產品，逐筆寫出而不一次載入
+func (h *ProductController) ExportProducts(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	format, err := productio.ParseFormat(c.DefaultQuery("format", productio.FormatCSV))
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, "INVALID_EXPORT_FORMAT", err.Error(), requestID)
+		return
+	}
+
+	query, err := h.parseProductQuery(c)
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, "INVALID_QUERY_PARAMS", err.Error(), requestID)
+		return
+	}
+
+	// 匯出大量資料時可能超過伺服器的寫入超時，清除本次回應的寫入期限
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	// 寫入器在讀到第一筆後才創建，查詢失敗時仍可返回 JSON 錯誤
+	var writer productio.Writer
+	start := func() error {
+		c.Header("Content-Type", productio.ContentType(format))
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="products.%s"`, format))
+		writer, err = productio.NewWriter(c.Writer, format)
+		return err
+	}
+
+	err = h.service.ExportProducts(c.Request.Context(), query, func(product model.Product) error {
+		if writer == nil {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		return writer.Write(product)
+	})
+	if err == nil && writer == nil {
+		err = start()
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if err == nil {
+		return
+	}
+
+	// 已送出部分內容時無法再改變狀態碼，只能記錄錯誤並中止
+	if c.Writer.Written() {
+		h.logger.Error("匯出產品中斷", zap.String("request_id", requestID), zap.Error(err))
+		c.Abort()
+		return
+	}
+
+	c.Writer.Header().Del("Content-Disposition")
+	status, code, message, ok := contextErrorStatus(c, err)
+	if !ok {
+		status, code, message = http.StatusInternalServerError, "PRODUCT_EXPORT_ERROR", "匯出產品失敗"
+	}
+	respondWithError(c, status, code, message, requestID)
+}
+
+// ImportProducts 從上傳的 CSV 或 XLSX 檔案依 sku_code 更新或創建產品
+// 表單欄位：file 為檔案；format 可省略，依副檔名推斷；mapping 為表頭到產品欄位的 JSON 對應；
+// dry_run 為 true 時只驗證不寫入。有任一列失敗時返回 207 與逐列錯誤
+func (h *ProductController) ImportProducts(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportFileSize)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondWithError(c, http.StatusRequestEntityTooLarge, "IMPORT_FILE_TOO_LARGE",
+				fmt.Sprintf("匯入檔案不能超過 %d MB", maxImportFileSize>>20), requestID)
+			return
+		}
+		respondWithError(c, http.StatusBadRequest, "INVALID_IMPORT_FILE", "請以 multipart 表單的 file 欄位上傳檔案", requestID)
+		return
+	}
+
+	var format string
+	if v := c.PostForm("format"); v != "" {
+		format, err = productio.ParseFormat(v)
+	} else {
+		format, err = productio.FormatFromFilename(fileHeader.Filename)
+	}
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, "INVALID_IMPORT_FORMAT", err.Error(), requestID)
+		return
+	}
+
+	var mapping map[string]string
+	if v := c.PostForm("mapping"); v != "" {
+		if err := json.Unmarshal([]byte(v), &mapping); err != nil {
+			respondWithError(c, http.StatusBadRequest, "INVALID_IMPORT_MAPPING", "mapping 必須為表頭名稱到產品欄位的 JSON 物件", requestID)
+			return
+		}
+		if err := productio.ValidateMapping(mapping); err != nil {
+			respondWithError(c, http.StatusBadRequest, "INVALID_IMPORT_MAPPING", err.Error(), requestID)
+			return
+		}
+	}
+
+	dryRun := false
+	if v := c.DefaultPostForm("dry_run", c.Query("dry_run")); v != "" {
+		dryRun, err = strconv.ParseBool(v)
+		if err != nil {
+			respondWithError(c, http.StatusBadRequest, "INVALID_REQUEST_DATA", "dry_run 必須為布林值", requestID)
+			return
+		}
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, "INVALID_IMPORT_FILE", "無法讀取上傳的檔案", requestID)
+		return
+	}
+	defer file.Close()
+
+	rows, err := productio.ReadRows(file, format, mapping)
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, "INVALID_IMPORT_FILE", err.Error(), requestID)
+		return
+	}
+
+	report, err := h.service.ImportProducts(c.Request.Context(), rows, dryRun)
+	if err != nil {
+		if respondWithContextError(c, err, requestID) {
+			return
+		}
+		respondWithError(c, http.StatusInternalServerError, "PRODUCT_IMPORT_ERROR", "匯入產品失敗", requestID)
+		return
+	}
+
+	status := http.StatusOK
+	if report.Failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	c.JSON(status, report)
+}
@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"errors"
+	model "main/internal/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetProductBySku 依 sku_code 獲取產品
+func (h *ProductController) GetProductBySku(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	product, err := h.service.GetProductBySku(c.Request.Context(), c.Param("sku_code"))
+	if err != nil {
+		status, code, message := productErrorStatus(c, err, "PRODUCT_FETCH_ERROR", "獲取產品失敗")
+		respondWithError(c, status, code, message, requestID)
+		return
+	}
+
+	setProductETag(c, product)
+
+	// 客戶端緩存的版本仍是最新時不返回內容
+	if ifNoneMatch(c, productETag(product)) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
+
+// UpsertProductBySku 以請求內容完整替換 sku_code 對應的產品，不存在時創建
+// 重複發送相同內容不會改變產品；If-Match 可省略，提供時產品必須存在且版本一致
+func (h *ProductController) UpsertProductBySku(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+	skuCode := c.Param("sku_code")
+
+	version, err := parseIfMatch(c)
+	if err != nil && !errors.Is(err, errMissingIfMatch) {
+		respondWithIfMatchError(c, err, requestID)
+		return
+	}
+
+	var input model.Product
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondWithBindError(c, err, requestID)
+		return
+	}
+
+	// sku_code 以路徑為準，請求內容中可省略
+	if input.SkuCode != "" && input.SkuCode != skuCode {
+		respondWithError(c, http.StatusBadRequest, "PRODUCT_VALIDATION_ERROR", "請求內容的 sku_code 與路徑不一致", requestID)
+		return
+	}
+
+	product, created, err := h.service.UpsertProductBySku(c.Request.Context(), skuCode, version, input)
+	if err != nil {
+		respondWithUpdateError(c, err, requestID)
+		return
+	}
+
+	setProductETag(c, product)
+	if created {
+		c.JSON(http.StatusCreated, product)
+		return
+	}
+	c.JSON(http.StatusOK, product)
+}
@@ -0,0 +1,54 @@
+package controller
+
+import (
+	model "main/internal/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ProductItemAction 依路徑最後一段分派單一產品的動作，Gin 不支援在路由中直接寫 "stock:adjust"
+func (h *ProductController) ProductItemAction(c *gin.Context) {
+	switch c.Param("action") {
+	case "stock:adjust":
+		h.AdjustStock(c)
+	default:
+		respondWithError(c, http.StatusNotFound, "NOT_FOUND", "找不到請求的資源", c.GetHeader("X-Request-ID"))
+	}
+}
+
+// AdjustStock 以帶正負號的差額原子地調整庫存，取代「讀取、計算、PUT 寫回」，並發的調整不會遺失更新
+func (h *ProductController) AdjustStock(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, "INVALID_PRODUCT_ID", "無效的產品ID", requestID)
+		return
+	}
+
+	var input model.StockAdjustRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondWithBindError(c, err, requestID)
+		return
+	}
+
+	product, err := h.service.AdjustStock(c.Request.Context(), id, input.Delta)
+	if err != nil {
+		status, code, message := productErrorStatus(c, err, "STOCK_ADJUST_ERROR", "調整庫存失敗")
+		respondWithError(c, status, code, message, requestID)
+		return
+	}
+
+	h.logger.Info("庫存已調整",
+		zap.Int64("product_id", id),
+		zap.Int("delta", input.Delta),
+		zap.Int("sku_amount", product.SkuAmount),
+		zap.String("request_id", requestID),
+	)
+
+	setProductETag(c, product)
+	c.JSON(http.StatusOK, product)
+}
@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"errors"
+	"main/internal/auth"
+	"main/internal/middleware"
+	model "main/internal/models"
+	"main/internal/repository"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// RegisterAdminRoutes 註冊管理端點，admin 應已掛上管理員驗證的中間件
+func (h *ProductController) RegisterAdminRoutes(admin *gin.RouterGroup) {
+	admin.DELETE("/products/:id", middleware.RequirePermission(auth.PermProductPurge), h.PurgeProduct)
+}
+
+// GetTrash 列出回收站中的產品，依刪除時間由新到舊排序
+func (h *ProductController) GetTrash(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	var query model.ProductQuery
+	if err := parseOffsetPage(c, &query); err != nil {
+		respondWithError(c, http.StatusBadRequest, "INVALID_QUERY_PARAMS", err.Error(), requestID)
+		return
+	}
+	query.SkuCode = c.Query("sku_code")
+	query.SkuName = c.Query("sku_name")
+
+	page, err := h.service.GetDeletedProducts(c.Request.Context(), query)
+	if err != nil {
+		if respondWithContextError(c, err, requestID) {
+			return
+		}
+		respondWithError(c, http.StatusInternalServerError, "PRODUCT_FETCH_ERROR", "獲取回收站失敗", requestID)
+		return
+	}
+
+	page.Links = buildPageLinks(c.Request.URL, page, false)
+
+	c.JSON(http.StatusOK, page)
+}
+
+// RestoreProduct 還原回收站中的產品
+func (h *ProductController) RestoreProduct(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, "INVALID_PRODUCT_ID", "無效的產品ID", requestID)
+		return
+	}
+
+	product, err := h.service.RestoreProduct(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrProductNotFound) {
+			respondWithError(c, http.StatusNotFound, "PRODUCT_NOT_FOUND", "回收站中沒有該產品", requestID)
+			return
+		}
+		if errors.Is(err, repository.ErrDuplicateSku) {
+			respondWithError(c, http.StatusConflict, "DUPLICATE_SKU", "產品編號已被其他產品使用，無法還原", requestID)
+			return
+		}
+		if respondWithContextError(c, err, requestID) {
+			return
+		}
+		respondWithError(c, http.StatusInternalServerError, "PRODUCT_RESTORE_ERROR", "還原產品失敗", requestID)
+		return
+	}
+
+	setProductETag(c, product)
+	c.JSON(http.StatusOK, product)
+}
+
+// PurgeProduct 永久刪除回收站中的產品（僅限管理員）
+func (h *ProductController) PurgeProduct(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, "INVALID_PRODUCT_ID", "無效的產品ID", requestID)
+		return
+	}
+
+	if err := h.service.PurgeProduct(c.Request.Context(), id); err != nil {
+		if errors.Is(err, repository.ErrProductNotFound) {
+			respondWithError(c, http.StatusNotFound, "PRODUCT_NOT_FOUND", "回收站中沒有該產品", requestID)
+			return
+		}
+		if respondWithContextError(c, err, requestID) {
+			return
+		}
+		respondWithError(c, http.StatusInternalServerError, "PRODUCT_PURGE_ERROR", "永久刪除產品失敗", requestID)
+		return
+	}
+
+	h.logger.Info("產品已永久刪除", zap.Int64("id", id), zap.String("request_id", requestID))
+
+	c.Status(http.StatusNoContent)
+}
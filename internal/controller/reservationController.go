@@ -0,0 +1,225 @@
+package controller
+
+import (
+	"errors"
+	"main/internal/auth"
+	"main/internal/middleware"
+	model "main/internal/models"
+	"main/internal/repository"
+	"main/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type ReservationController struct {
+	service service.ReservationService
+	logger  *zap.Logger
+}
+
+func NewReservationController(service service.ReservationService, logger *zap.Logger) *ReservationController {
+	return &ReservationController{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// RegisterRoutes 註冊庫存預留路由
+func (h *ReservationController) RegisterRoutes(router *gin.Engine) {
+	read := middleware.RequirePermission(auth.PermProductRead)
+	adjust := middleware.RequirePermission(auth.PermStockAdjust)
+
+	products := router.Group("/api/v1/products")
+	{
+		products.POST("/:id/reservations", adjust, h.CreateReservation)
+		products.GET("/:id/reservations", read, h.ListReservations)
+		products.GET("/:id/availability", read, h.GetAvailability)
+	}
+
+	reservations := router.Group("/api/v1/reservations")
+	{
+		reservations.GET("/:id", read, h.GetReservation)
+		reservations.POST("/:id/commit", adjust, h.CommitReservation)
+		reservations.POST("/:id/release", adjust, h.ReleaseReservation)
+	}
+}
+
+// CreateReservation 為產品創建預留，新建時返回 201，以相同 reference 重送時返回既有的預留與 200
+func (h *ReservationController) CreateReservation(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, "INVALID_PRODUCT_ID", "無效的產品ID", requestID)
+		return
+	}
+
+	var input model.ReservationRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondWithBindError(c, err, requestID)
+		return
+	}
+
+	reservation, created, err := h.service.Reserve(c.Request.Context(), id, input)
+	if err != nil {
+		status, code, message := reservationErrorStatus(c, err, "RESERVATION_CREATE_ERROR", "創建預留失敗")
+		respondWithError(c, status, code, message, requestID)
+		return
+	}
+
+	if !created {
+		c.JSON(http.StatusOK, reservation)
+		return
+	}
+
+	h.logger.Info("庫存預留已創建",
+		zap.Int64("product_id", id),
+		zap.Int64("reservation_id", reservation.ID),
+		zap.Int("quantity", reservation.Quantity),
+		zap.String("request_id", requestID),
+	)
+
+	c.JSON(http.StatusCreated, reservation)
+}
+
+// ListReservations 列出產品未到期的預留
+func (h *ReservationController) ListReservations(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, "INVALID_PRODUCT_ID", "無效的產品ID", requestID)
+		return
+	}
+
+	reservations, err := h.service.ListReservations(c.Request.Context(), id)
+	if err != nil {
+		status, code, message := reservationErrorStatus(c, err, "RESERVATION_FETCH_ERROR", "獲取預留失敗")
+		respondWithError(c, status, code, message, requestID)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": reservations})
+}
+
+// GetAvailability 獲取產品的庫存、已預留數量與可承諾量
+func (h *ReservationController) GetAvailability(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, "INVALID_PRODUCT_ID", "無效的產品ID", requestID)
+		return
+	}
+
+	availability, err := h.service.GetAvailability(c.Request.Context(), id)
+	if err != nil {
+		status, code, message := reservationErrorStatus(c, err, "AVAILABILITY_FETCH_ERROR", "獲取可承諾量失敗")
+		respondWithError(c, status, code, message, requestID)
+		return
+	}
+
+	c.JSON(http.StatusOK, availability)
+}
+
+func (h *ReservationController) GetReservation(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	id, ok := parseReservationID(c, requestID)
+	if !ok {
+		return
+	}
+
+	reservation, err := h.service.GetReservation(c.Request.Context(), id)
+	if err != nil {
+		status, code, message := reservationErrorStatus(c, err, "RESERVATION_FETCH_ERROR", "獲取預留失敗")
+		respondWithError(c, status, code, message, requestID)
+		return
+	}
+
+	c.JSON(http.StatusOK, reservation)
+}
+
+// CommitReservation 提交預留並出庫，請求主體可省略，此時從預設儲位出庫
+func (h *ReservationController) CommitReservation(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	id, ok := parseReservationID(c, requestID)
+	if !ok {
+		return
+	}
+
+	var input model.CommitReservationRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondWithBindError(c, err, requestID)
+			return
+		}
+	}
+
+	reservation, err := h.service.Commit(c.Request.Context(), id, input)
+	if err != nil {
+		status, code, message := reservationErrorStatus(c, err, "RESERVATION_COMMIT_ERROR", "提交預留失敗")
+		respondWithError(c, status, code, message, requestID)
+		return
+	}
+
+	h.logger.Info("庫存預留已提交",
+		zap.Int64("reservation_id", id),
+		zap.Int64("movement_id", reservation.MovementID),
+		zap.String("request_id", requestID),
+	)
+
+	c.JSON(http.StatusOK, reservation)
+}
+
+// ReleaseReservation 釋放預留
+func (h *ReservationController) ReleaseReservation(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	id, ok := parseReservationID(c, requestID)
+	if !ok {
+		return
+	}
+
+	reservation, err := h.service.Release(c.Request.Context(), id)
+	if err != nil {
+		status, code, message := reservationErrorStatus(c, err, "RESERVATION_RELEASE_ERROR", "釋放預留失敗")
+		respondWithError(c, status, code, message, requestID)
+		return
+	}
+
+	c.JSON(http.StatusOK, reservation)
+}
+
+// parseReservationID 解析路徑中的預留 ID，無效時回應 400
+func parseReservationID(c *gin.Context, requestID string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, "INVALID_RESERVATION_ID", "無效的預留ID", requestID)
+		return 0, false
+	}
+	return id, true
+}
+
+// reservationErrorStatus 先對應預留特有的錯誤，其餘（產品不存在、庫存不足等）沿用產品的對應
+func reservationErrorStatus(c *gin.Context, err error, fallbackCode string, fallbackMessage string) (int, string, string) {
+	var validationErr *model.ValidationError
+
+	switch {
+	case errors.Is(err, repository.ErrReservationNotFound):
+		return http.StatusNotFound, "RESERVATION_NOT_FOUND", err.Error()
+	case errors.Is(err, repository.ErrReservationConflict):
+		return http.StatusConflict, "RESERVATION_CONFLICT", err.Error()
+	case errors.Is(err, repository.ErrReservationNotActive):
+		return http.StatusConflict, "RESERVATION_NOT_ACTIVE", err.Error()
+	case errors.Is(err, repository.ErrReservationExpired):
+		return http.StatusConflict, "RESERVATION_EXPIRED", err.Error()
+	case errors.As(err, &validationErr):
+		return http.StatusBadRequest, "RESERVATION_VALIDATION_ERROR", validationErr.Message
+	}
+
+	return productErrorStatus(c, err, fallbackCode, fallbackMessage)
+}
@@ -0,0 +1,170 @@
+package controller
+
+import (
+	"main/internal/auth"
+	"main/internal/middleware"
+	model "main/internal/models"
+	"main/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type StockController struct {
+	service service.StockService
+	logger  *zap.Logger
+}
+
+func NewStockController(service service.StockService, logger *zap.Logger) *StockController {
+	return &StockController{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// RegisterRoutes 註冊庫存異動路由
+func (h *StockController) RegisterRoutes(router *gin.Engine) {
+	read := middleware.RequirePermission(auth.PermProductRead)
+	adjust := middleware.RequirePermission(auth.PermStockAdjust)
+
+	products := router.Group("/api/v1/products")
+	{
+		products.GET("/:id/movements", read, h.GetMovements)
+		products.POST("/:id/movements", adjust, h.PostMovement)
+		products.GET("/:id/movements/:movement_id", read, h.GetMovement)
+		products.GET("/:id/lots", read, h.GetLots)
+	}
+}
+
+// PostMovement 登記產品的庫存異動，依 type 入庫、出庫、調整或轉移到其他儲位
+func (h *StockController) PostMovement(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, "INVALID_PRODUCT_ID", "無效的產品ID", requestID)
+		return
+	}
+
+	var input model.StockMovementRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondWithBindError(c, err, requestID)
+		return
+	}
+	if input.ToProductID != 0 && input.ToProductID != id {
+		respondWithError(c, http.StatusBadRequest, "PRODUCT_VALIDATION_ERROR", "轉移只能在同一產品的儲位間進行，不能轉到其他產品", requestID)
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	single := func(movement model.StockMovement, err error) ([]model.StockMovement, error) {
+		return []model.StockMovement{movement}, err
+	}
+
+	var movements []model.StockMovement
+	switch input.Type {
+	case model.MovementReceive:
+		movements, err = single(h.service.Receive(ctx, id, input.StockRequest))
+	case model.MovementIssue:
+		movements, err = single(h.service.Issue(ctx, id, input.StockRequest))
+	case model.MovementAdjust:
+		movements, err = single(h.service.Adjust(ctx, id, input.StockRequest))
+	case model.MovementTransfer:
+		movements, err = h.service.Transfer(ctx, id, input.TransferRequest)
+	default:
+		respondWithError(c, http.StatusBadRequest, "INVALID_MOVEMENT_TYPE", "type 必須為 receive、issue、adjust 或 transfer", requestID)
+		return
+	}
+
+	if err != nil {
+		status, code, message := productErrorStatus(c, err, "STOCK_MOVEMENT_ERROR", "登記庫存異動失敗")
+		respondWithError(c, status, code, message, requestID)
+		return
+	}
+
+	h.logger.Info("庫存異動已登記",
+		zap.Int64("product_id", id),
+		zap.String("type", input.Type),
+		zap.Int("quantity", input.Quantity),
+		zap.String("request_id", requestID),
+	)
+
+	c.JSON(http.StatusCreated, gin.H{"items": movements})
+}
+
+// GetMovements 依時間由新到舊列出產品的庫存異動
+func (h *StockController) GetMovements(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, "INVALID_PRODUCT_ID", "無效的產品ID", requestID)
+		return
+	}
+
+	var query model.ProductQuery
+	if err := parseOffsetPage(c, &query); err != nil {
+		respondWithError(c, http.StatusBadRequest, "INVALID_QUERY_PARAMS", err.Error(), requestID)
+		return
+	}
+
+	page, err := h.service.GetMovements(c.Request.Context(), id, query)
+	if err != nil {
+		status, code, message := productErrorStatus(c, err, "STOCK_MOVEMENT_FETCH_ERROR", "獲取庫存異動失敗")
+		respondWithError(c, status, code, message, requestID)
+		return
+	}
+
+	page.Links = offsetPageLinks(c.Request.URL, page.PageSize, page.Offset, page.HasNext, page.HasPrev)
+
+	c.JSON(http.StatusOK, page)
+}
+
+// GetMovement 獲取單筆庫存異動，包含其增減的批次（例如出庫消耗了哪些批次）
+func (h *StockController) GetMovement(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, "INVALID_PRODUCT_ID", "無效的產品ID", requestID)
+		return
+	}
+
+	movementID, err := strconv.ParseInt(c.Param("movement_id"), 10, 64)
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, "INVALID_MOVEMENT_ID", "無效的異動ID", requestID)
+		return
+	}
+
+	movement, err := h.service.GetMovement(c.Request.Context(), id, movementID)
+	if err != nil {
+		status, code, message := productErrorStatus(c, err, "STOCK_MOVEMENT_FETCH_ERROR", "獲取庫存異動失敗")
+		respondWithError(c, status, code, message, requestID)
+		return
+	}
+
+	c.JSON(http.StatusOK, movement)
+}
+
+// GetLots 依到期日先後列出產品仍有庫存的批次
+func (h *StockController) GetLots(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, "INVALID_PRODUCT_ID", "無效的產品ID", requestID)
+		return
+	}
+
+	lots, err := h.service.GetLots(c.Request.Context(), id)
+	if err != nil {
+		status, code, message := productErrorStatus(c, err, "PRODUCT_LOT_FETCH_ERROR", "獲取批次庫存失敗")
+		respondWithError(c, status, code, message, requestID)
+		return
+	}
+
+	c.JSON(http.StatusOK, lots)
+}
@@ -0,0 +1,314 @@
+package controller
+
+import (
+	"errors"
+	"main/internal/auth"
+	"main/internal/middleware"
+	model "main/internal/models"
+	"main/internal/repository"
+	"main/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type WarehouseController struct {
+	service service.WarehouseService
+	logger  *zap.Logger
+}
+
+func NewWarehouseController(service service.WarehouseService, logger *zap.Logger) *WarehouseController {
+	return &WarehouseController{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// RegisterRoutes 註冊倉庫與儲位路由
+func (h *WarehouseController) RegisterRoutes(router *gin.Engine) {
+	read := middleware.RequirePermission(auth.PermProductRead)
+	write := middleware.RequirePermission(auth.PermWarehouseWrite)
+
+	warehouses := router.Group("/api/v1/warehouses")
+	{
+		warehouses.GET("", read, h.ListWarehouses)
+		warehouses.POST("", write, h.CreateWarehouse)
+		warehouses.GET("/:id", read, h.GetWarehouse)
+		warehouses.PUT("/:id", write, h.UpdateWarehouse)
+		warehouses.DELETE("/:id", write, h.DeleteWarehouse)
+		warehouses.GET("/:id/locations", read, h.ListLocations)
+		warehouses.POST("/:id/locations", write, h.CreateLocation)
+	}
+
+	locations := router.Group("/api/v1/locations")
+	{
+		locations.GET("/:id", read, h.GetLocation)
+		locations.PUT("/:id", write, h.UpdateLocation)
+		locations.DELETE("/:id", write, h.DeleteLocation)
+	}
+}
+
+// ListWarehouses 依代碼排序列出所有倉庫
+func (h *WarehouseController) ListWarehouses(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	warehouses, err := h.service.ListWarehouses(c.Request.Context())
+	if err != nil {
+		respondWithWarehouseError(c, err, "WAREHOUSE_FETCH_ERROR", "獲取倉庫列表失敗", requestID)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": warehouses})
+}
+
+func (h *WarehouseController) GetWarehouse(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	id, ok := parseWarehouseID(c, requestID)
+	if !ok {
+		return
+	}
+
+	warehouse, err := h.service.GetWarehouse(c.Request.Context(), id)
+	if err != nil {
+		respondWithWarehouseError(c, err, "WAREHOUSE_FETCH_ERROR", "獲取倉庫失敗", requestID)
+		return
+	}
+
+	c.JSON(http.StatusOK, warehouse)
+}
+
+func (h *WarehouseController) CreateWarehouse(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	var input model.Warehouse
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondWithBindError(c, err, requestID)
+		return
+	}
+
+	warehouse, err := h.service.CreateWarehouse(c.Request.Context(), input)
+	if err != nil {
+		respondWithWarehouseError(c, err, "WAREHOUSE_CREATE_ERROR", "創建倉庫失敗", requestID)
+		return
+	}
+
+	h.logger.Info("倉庫已創建",
+		zap.Int64("warehouse_id", warehouse.ID),
+		zap.String("code", warehouse.Code),
+		zap.String("request_id", requestID),
+	)
+
+	c.JSON(http.StatusCreated, warehouse)
+}
+
+func (h *WarehouseController) UpdateWarehouse(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	id, ok := parseWarehouseID(c, requestID)
+	if !ok {
+		return
+	}
+
+	var input model.Warehouse
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondWithBindError(c, err, requestID)
+		return
+	}
+
+	warehouse, err := h.service.UpdateWarehouse(c.Request.Context(), id, input)
+	if err != nil {
+		respondWithWarehouseError(c, err, "WAREHOUSE_UPDATE_ERROR", "更新倉庫失敗", requestID)
+		return
+	}
+
+	c.JSON(http.StatusOK, warehouse)
+}
+
+// DeleteWarehouse 刪除沒有儲位的倉庫
+func (h *WarehouseController) DeleteWarehouse(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	id, ok := parseWarehouseID(c, requestID)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteWarehouse(c.Request.Context(), id); err != nil {
+		respondWithWarehouseError(c, err, "WAREHOUSE_DELETE_ERROR", "刪除倉庫失敗", requestID)
+		return
+	}
+
+	h.logger.Info("倉庫已刪除",
+		zap.Int64("warehouse_id", id),
+		zap.String("request_id", requestID),
+	)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "倉庫已成功刪除",
+	})
+}
+
+// ListLocations 依代碼排序列出倉庫內的儲位
+func (h *WarehouseController) ListLocations(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	id, ok := parseWarehouseID(c, requestID)
+	if !ok {
+		return
+	}
+
+	locations, err := h.service.ListLocations(c.Request.Context(), id)
+	if err != nil {
+		respondWithWarehouseError(c, err, "LOCATION_FETCH_ERROR", "獲取儲位列表失敗", requestID)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": locations})
+}
+
+// CreateLocation 在倉庫內創建儲位
+func (h *WarehouseController) CreateLocation(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	id, ok := parseWarehouseID(c, requestID)
+	if !ok {
+		return
+	}
+
+	var input model.StockLocation
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondWithBindError(c, err, requestID)
+		return
+	}
+
+	location, err := h.service.CreateLocation(c.Request.Context(), id, input)
+	if err != nil {
+		respondWithWarehouseError(c, err, "LOCATION_CREATE_ERROR", "創建儲位失敗", requestID)
+		return
+	}
+
+	h.logger.Info("儲位已創建",
+		zap.Int64("warehouse_id", id),
+		zap.Int64("location_id", location.ID),
+		zap.String("code", location.Code),
+		zap.String("request_id", requestID),
+	)
+
+	c.JSON(http.StatusCreated, location)
+}
+
+func (h *WarehouseController) GetLocation(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	id, ok := parseLocationID(c, requestID)
+	if !ok {
+		return
+	}
+
+	location, err := h.service.GetLocation(c.Request.Context(), id)
+	if err != nil {
+		respondWithWarehouseError(c, err, "LOCATION_FETCH_ERROR", "獲取儲位失敗", requestID)
+		return
+	}
+
+	c.JSON(http.StatusOK, location)
+}
+
+func (h *WarehouseController) UpdateLocation(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	id, ok := parseLocationID(c, requestID)
+	if !ok {
+		return
+	}
+
+	var input model.StockLocation
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondWithBindError(c, err, requestID)
+		return
+	}
+
+	location, err := h.service.UpdateLocation(c.Request.Context(), id, input)
+	if err != nil {
+		respondWithWarehouseError(c, err, "LOCATION_UPDATE_ERROR", "更新儲位失敗", requestID)
+		return
+	}
+
+	c.JSON(http.StatusOK, location)
+}
+
+// DeleteLocation 刪除沒有庫存的儲位
+func (h *WarehouseController) DeleteLocation(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+
+	id, ok := parseLocationID(c, requestID)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteLocation(c.Request.Context(), id); err != nil {
+		respondWithWarehouseError(c, err, "LOCATION_DELETE_ERROR", "刪除儲位失敗", requestID)
+		return
+	}
+
+	h.logger.Info("儲位已刪除",
+		zap.Int64("location_id", id),
+		zap.String("request_id", requestID),
+	)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "儲位已成功刪除",
+	})
+}
+
+// parseWarehouseID 解析路徑中的倉庫 ID，無效時回應 400
+func parseWarehouseID(c *gin.Context, requestID string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, "INVALID_WAREHOUSE_ID", "無效的倉庫ID", requestID)
+		return 0, false
+	}
+	return id, true
+}
+
+// parseLocationID 解析路徑中的儲位 ID，無效時回應 400
+func parseLocationID(c *gin.Context, requestID string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, "INVALID_LOCATION_ID", "無效的儲位ID", requestID)
+		return 0, false
+	}
+	return id, true
+}
+
+// respondWithWarehouseError 將倉庫服務的錯誤對應為狀態碼與錯誤碼，無法識別的錯誤使用 fallback 並返回 500
+func respondWithWarehouseError(c *gin.Context, err error, fallbackCode string, fallbackMessage string, requestID string) {
+	var validationErr *model.ValidationError
+
+	switch {
+	case errors.Is(err, repository.ErrWarehouseNotFound):
+		respondWithError(c, http.StatusNotFound, "WAREHOUSE_NOT_FOUND", err.Error(), requestID)
+	case errors.Is(err, repository.ErrLocationNotFound):
+		respondWithError(c, http.StatusNotFound, "LOCATION_NOT_FOUND", err.Error(), requestID)
+	case errors.Is(err, repository.ErrDuplicateWarehouse):
+		respondWithError(c, http.StatusConflict, "DUPLICATE_WAREHOUSE", err.Error(), requestID)
+	case errors.Is(err, repository.ErrDuplicateLocation):
+		respondWithError(c, http.StatusConflict, "DUPLICATE_LOCATION", err.Error(), requestID)
+	case errors.Is(err, repository.ErrWarehouseInUse):
+		respondWithError(c, http.StatusConflict, "WAREHOUSE_IN_USE", err.Error(), requestID)
+	case errors.Is(err, repository.ErrLocationInUse):
+		respondWithError(c, http.StatusConflict, "LOCATION_IN_USE", err.Error(), requestID)
+	case errors.Is(err, repository.ErrDefaultLocation):
+		respondWithError(c, http.StatusConflict, "DEFAULT_LOCATION", err.Error(), requestID)
+	case errors.As(err, &validationErr):
+		respondWithError(c, http.StatusBadRequest, "WAREHOUSE_VALIDATION_ERROR", validationErr.Message, requestID)
+	default:
+		if respondWithContextError(c, err, requestID) {
+			return
+		}
+		respondWithError(c, http.StatusInternalServerError, fallbackCode, fallbackMessage, requestID)
+	}
+}
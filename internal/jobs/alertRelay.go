@@ -0,0 +1,68 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"main/internal/auth"
+	"main/internal/service"
+
+	"go.uber.org/zap"
+)
+
+// alertRelayBatchSize 每次從寄件匣取出的事件數量
+const alertRelayBatchSize = 100
+
+// AlertRelay 定期把觸發器記錄的低庫存事件送到通知管道；
+// 事件以 SKIP LOCKED 鎖定，多個實例同時執行時各自派送不同的事件，因此不需要領導權
+type AlertRelay struct {
+	service  service.AlertService
+	logger   *zap.Logger
+	interval time.Duration
+}
+
+// NewAlertRelay 創建事件派送任務，interval 為檢查寄件匣的間隔
+func NewAlertRelay(service service.AlertService, logger *zap.Logger, interval time.Duration) *AlertRelay {
+	return &AlertRelay{
+		service:  service,
+		logger:   logger,
+		interval: interval,
+	}
+}
+
+// Run 啟動後立即派送一次，之後每隔 interval 派送，直到 ctx 被取消
+func (j *AlertRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		j.RunOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce 派送寄件匣中所有的事件，返回送出的數量；送出失敗時記錄日誌並停止，未送出的事件等待下次派送
+func (j *AlertRelay) RunOnce(ctx context.Context) int {
+	ctx = auth.WithPrincipal(ctx, auth.System)
+
+	total := 0
+	for ctx.Err() == nil {
+		relayed, err := j.service.RelayLowStock(ctx, alertRelayBatchSize)
+		total += relayed
+		if err != nil {
+			if ctx.Err() == nil {
+				j.logger.Error("派送低庫存事件失敗", zap.Int("relayed", total), zap.Error(err))
+			}
+			break
+		}
+		if relayed < alertRelayBatchSize {
+			break
+		}
+	}
+	return total
+}
@@ -0,0 +1,114 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"main/internal/auth"
+	model "main/internal/models"
+	"main/internal/service"
+
+	"go.uber.org/zap"
+)
+
+// ExpirySweeperLockID 過期清理選舉領導者使用的 advisory lock 鍵
+const ExpirySweeperLockID int64 = 7243190582
+
+// Leader 領導者選舉，多個實例中只有領導者執行排程的任務
+type Leader interface {
+	// TryAcquire 嘗試成為或確認仍是領導者，不等待其他實例
+	TryAcquire(ctx context.Context) (bool, error)
+	// Release 放棄領導權
+	Release()
+}
+
+// ExpirySweeper 每天把到期的批次與產品標記為過期；多個實例同時運行時只有取得領導權的實例執行
+type ExpirySweeper struct {
+	service service.ExpiryService
+	leader  Leader
+	logger  *zap.Logger
+	runAt   time.Duration
+	retry   time.Duration
+}
+
+// NewExpirySweeper 創建過期清理任務，runAt 為每天執行的時間（自 UTC 零時起算），
+// retry 為競爭或確認領導權的間隔
+func NewExpirySweeper(service service.ExpiryService, leader Leader, logger *zap.Logger, runAt, retry time.Duration) *ExpirySweeper {
+	return &ExpirySweeper{
+		service: service,
+		leader:  leader,
+		logger:  logger,
+		runAt:   runAt,
+		retry:   retry,
+	}
+}
+
+// Run 每隔 retry 競爭或確認領導權，直到 ctx 被取消；成為領導者時立即清理一次，
+// 補上前一個領導者可能錯過的執行，之後每天在 runAt 清理。返回前放棄領導權
+func (j *ExpirySweeper) Run(ctx context.Context) {
+	defer j.leader.Release()
+
+	leading := false
+	var next time.Time
+	for {
+		ok, err := j.leader.TryAcquire(ctx)
+		if err != nil && ctx.Err() == nil {
+			j.logger.Warn("過期清理的領導者選舉失敗", zap.Error(err))
+		}
+		if ok != leading {
+			leading = ok
+			next = time.Time{}
+			if leading {
+				j.logger.Info("取得過期清理的領導權")
+			} else {
+				j.logger.Warn("失去過期清理的領導權")
+			}
+		}
+
+		wait := j.retry
+		if leading {
+			if now := time.Now(); !now.Before(next) {
+				j.RunOnce(ctx)
+				next = nextDailyRun(time.Now(), j.runAt)
+			}
+			wait = min(wait, time.Until(next))
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// RunOnce 執行一次清理並記錄摘要；失敗時記錄日誌，等待下次執行
+func (j *ExpirySweeper) RunOnce(ctx context.Context) model.ExpirySweepResult {
+	start := time.Now()
+	result, err := j.service.SweepExpired(auth.WithPrincipal(ctx, auth.System), start)
+	if err != nil {
+		if ctx.Err() == nil {
+			j.logger.Error("過期清理失敗", zap.Error(err))
+		}
+		return result
+	}
+
+	j.logger.Info("過期清理完成",
+		zap.Int("lots", result.Lots),
+		zap.Int("products", result.Products),
+		zap.Int("quantity", result.Quantity),
+		zap.Duration("elapsed", time.Since(start)),
+	)
+	return result
+}
+
+// nextDailyRun 返回 now 之後第一個 UTC 零時加上 runAt 的時間
+func nextDailyRun(now time.Time, runAt time.Duration) time.Time {
+	next := now.UTC().Truncate(24 * time.Hour).Add(runAt)
+	if !next.After(now) {
+		next = next.Add(24 * time.Hour)
+	}
+	return next
+}
@@ -0,0 +1,57 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"main/internal/auth"
+	"main/internal/service"
+
+	"go.uber.org/zap"
+)
+
+// ReservationSweeper 定期把已到期的 active 預留標記為過期；
+// 到期的預留在清理前就不再計入可承諾量，清理只是讓狀態與實際一致
+type ReservationSweeper struct {
+	service  service.ReservationService
+	logger   *zap.Logger
+	interval time.Duration
+}
+
+// NewReservationSweeper 創建預留清理任務，interval 為檢查間隔
+func NewReservationSweeper(service service.ReservationService, logger *zap.Logger, interval time.Duration) *ReservationSweeper {
+	return &ReservationSweeper{
+		service:  service,
+		logger:   logger,
+		interval: interval,
+	}
+}
+
+// Run 啟動後立即清理一次，之後每隔 interval 清理，直到 ctx 被取消
+func (j *ReservationSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		j.RunOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce 執行一次清理，返回標記為過期的預留數量；失敗時記錄日誌，等待下次重試
+func (j *ReservationSweeper) RunOnce(ctx context.Context) int64 {
+	expired, err := j.service.ExpireReservations(auth.WithPrincipal(ctx, auth.System), time.Now())
+	if err != nil && ctx.Err() == nil {
+		j.logger.Error("清理過期預留失敗", zap.Int64("expired", expired), zap.Error(err))
+	}
+
+	if expired > 0 {
+		j.logger.Info("已將到期的預留標記為過期", zap.Int64("count", expired))
+	}
+	return expired
+}
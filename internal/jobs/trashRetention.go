@@ -0,0 +1,65 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"main/internal/audit"
+	"main/internal/auth"
+	"main/internal/service"
+	"main/internal/tenant"
+
+	"go.uber.org/zap"
+)
+
+// TrashRetention 定期永久刪除在回收站中超過保留期的產品
+type TrashRetention struct {
+	service   service.ProductService
+	logger    *zap.Logger
+	retention time.Duration
+	interval  time.Duration
+}
+
+// NewTrashRetention 創建回收站清理任務，retention 為產品在回收站中保留的時間，interval 為檢查間隔
+func NewTrashRetention(service service.ProductService, logger *zap.Logger, retention time.Duration, interval time.Duration) *TrashRetention {
+	return &TrashRetention{
+		service:   service,
+		logger:    logger,
+		retention: retention,
+		interval:  interval,
+	}
+}
+
+// Run 啟動後立即清理一次，之後每隔 interval 清理，直到 ctx 被取消
+func (j *TrashRetention) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		j.RunOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce 執行一次清理所有租戶的回收站，返回永久刪除的產品數量；失敗時記錄日誌，等待下次重試
+func (j *TrashRetention) RunOnce(ctx context.Context) int64 {
+	ctx = tenant.WithID(audit.WithActor(ctx, "trash-retention"), tenant.All)
+	ctx = auth.WithPrincipal(ctx, auth.System)
+	purged, err := j.service.PurgeDeletedProducts(ctx, j.retention)
+	if err != nil {
+		if ctx.Err() == nil {
+			j.logger.Error("清理回收站失敗", zap.Error(err))
+		}
+		return 0
+	}
+
+	if purged > 0 {
+		j.logger.Info("已永久刪除超過保留期的產品", zap.Int64("count", purged), zap.Duration("retention", j.retention))
+	}
+	return purged
+}
@@ -4,7 +4,10 @@ import (
 	"bytes"
 	"fmt"
 	"log"
+	"main/internal/audit"
+	"main/internal/auth"
 	"main/internal/config"
+	"main/internal/tenant"
 	"os"
 	"path/filepath"
 	"strings"
@@ -70,6 +73,7 @@ func LoggerMiddleware(logger *zap.Logger) gin.HandlerFunc {
 			requestID = uuid.New().String()
 			c.Header("X-Request-ID", requestID)
 		}
+		c.Request = c.Request.WithContext(audit.WithRequestID(c.Request.Context(), requestID))
 
 		// 創建自定義的響應寫入器來捕獲狀態碼
 		blw := &bodyLogWriter{
@@ -87,6 +91,10 @@ func LoggerMiddleware(logger *zap.Logger) gin.HandlerFunc {
 		// 獲取狀態碼
 		statusCode := c.Writer.Status()
 
+		// 通過驗證的主體，未驗證時為空
+		subject := auth.Subject(c.Request.Context())
+		tenantID := tenant.ID(c.Request.Context())
+
 		// 只記錄成功請求的執行時間
 		if statusCode < 400 {
 			// 記錄API執行時間
@@ -95,6 +103,8 @@ func LoggerMiddleware(logger *zap.Logger) gin.HandlerFunc {
 				zap.String("method", c.Request.Method),
 				zap.String("path", c.Request.URL.Path),
 				zap.String("client_ip", c.ClientIP()),
+				zap.String("subject", subject),
+				zap.String("tenant", tenantID),
 				zap.Int("status", statusCode),
 				zap.Duration("duration", duration),
 				zap.String("duration_ms", fmt.Sprintf("%.2fms", float64(duration.Microseconds())/1000.0)),
@@ -112,6 +122,8 @@ func LoggerMiddleware(logger *zap.Logger) gin.HandlerFunc {
 				zap.String("method", c.Request.Method),
 				zap.String("path", c.Request.URL.Path),
 				zap.String("client_ip", c.ClientIP()),
+				zap.String("subject", subject),
+				zap.String("tenant", tenantID),
 				zap.Int("status", statusCode),
 				zap.Duration("duration", duration),
 				zap.String("duration_ms", fmt.Sprintf("%.2fms", float64(duration.Microseconds())/1000.0)),
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"main/internal/audit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ActorHeader 客戶端聲明操作者使用的請求標頭
+const ActorHeader = "X-Actor"
+
+// Actor 將 X-Actor 標頭記錄為操作者，寫入產品變更歷史；
+// 標頭由客戶端自行聲明，未經驗證，只用於追蹤
+func Actor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if actor := c.GetHeader(ActorHeader); actor != "" {
+			c.Request = c.Request.WithContext(audit.WithActor(c.Request.Context(), actor))
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"main/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminTokenHeader 管理端點驗證使用的請求標頭
+const AdminTokenHeader = "X-Admin-Token"
+
+// AdminToken 要求請求帶上與 token 相同的 X-Admin-Token 標頭，token 為空時停用所有管理端點
+func AdminToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided := c.GetHeader(AdminTokenHeader)
+		if token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			abortWithError(c, http.StatusForbidden, models.ErrCodeAdminRequired, "需要管理員權限")
+			return
+		}
+
+		c.Next()
+	}
+}
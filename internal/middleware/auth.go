@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"main/internal/audit"
+	"main/internal/auth"
+	"main/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader 機器對機器客戶端傳入 API 金鑰使用的請求標頭
+const APIKeyHeader = "X-API-Key"
+
+// TokenVerifier 驗證 Bearer 令牌或 API 金鑰並返回代表的身分
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) (auth.Principal, error)
+}
+
+// Authenticate 要求請求帶上有效的 X-API-Key 標頭或 Authorization: Bearer 令牌，兩者都有時使用 API 金鑰；
+// bearer 或 apiKeys 為 nil 時不接受該種憑證。通過驗證的身分放入請求的 context，
+// 主體同時取代 X-Actor 作為變更歷史的操作者；publicPaths 中的路由不需要驗證
+func Authenticate(bearer, apiKeys TokenVerifier, publicPaths ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if slices.Contains(publicPaths, c.FullPath()) {
+			c.Next()
+			return
+		}
+
+		var verifier TokenVerifier
+		var credential string
+		usingBearer := false
+		if key := c.GetHeader(APIKeyHeader); key != "" && apiKeys != nil {
+			verifier, credential = apiKeys, key
+		} else if scheme, token, _ := strings.Cut(c.GetHeader("Authorization"), " "); bearer != nil &&
+			strings.EqualFold(scheme, "Bearer") && strings.TrimSpace(token) != "" {
+			verifier, credential, usingBearer = bearer, strings.TrimSpace(token), true
+		}
+
+		if verifier == nil {
+			if bearer != nil {
+				c.Header("WWW-Authenticate", "Bearer")
+			}
+			abortWithError(c, http.StatusUnauthorized, models.ErrCodeUnauthorized, missingCredentialMessage(bearer, apiKeys))
+			return
+		}
+
+		principal, err := verifier.Verify(c.Request.Context(), credential)
+		if err != nil {
+			if errors.Is(err, auth.ErrUnavailable) {
+				abortWithError(c, http.StatusServiceUnavailable, models.ErrCodeAuthUnavailable, "暫時無法驗證身分，請稍後重試")
+				return
+			}
+			if usingBearer {
+				c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			}
+			abortWithError(c, http.StatusUnauthorized, models.ErrCodeInvalidToken, err.Error())
+			return
+		}
+
+		ctx := auth.WithPrincipal(c.Request.Context(), principal)
+		c.Request = c.Request.WithContext(audit.WithActor(ctx, principal.Subject))
+		c.Next()
+	}
+}
+
+// Unauthenticated 未啟用驗證時以 auth.System 作為所有請求的主體，使服務層的權限檢查放行；
+// 須在 Tenant 之後執行，租戶仍由 X-Tenant-ID 決定
+func Unauthenticated() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), auth.System))
+		c.Next()
+	}
+}
+
+// missingCredentialMessage 依接受的憑證說明缺少的內容
+func missingCredentialMessage(bearer, apiKeys TokenVerifier) string {
+	switch {
+	case bearer != nil && apiKeys != nil:
+		return "缺少 Bearer 存取令牌或 " + APIKeyHeader + " 標頭"
+	case apiKeys != nil:
+		return "缺少 " + APIKeyHeader + " 標頭"
+	default:
+		return "缺少 Bearer 存取令牌"
+	}
+}
+
+// abortWithError 以與控制器相同的 models.ErrorResponse 格式中止請求
+func abortWithError(c *gin.Context, status int, code, message string) {
+	c.AbortWithStatusJSON(status, models.ErrorResponse{
+		ErrorCode:    code,
+		ErrorMessage: message,
+		RequestID:    c.GetHeader("X-Request-ID"),
+	})
+}
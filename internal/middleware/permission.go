@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+
+	"main/internal/auth"
+	"main/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission 要求通過驗證的主體擁有權限，沒有主體或權限不足時返回 403
+func RequirePermission(permission auth.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authorize(c, permission)
+	}
+}
+
+// RequireActionPermission 依路徑參數 param 選擇所需的權限，用於 POST /:action 這類分派多種操作的路由；
+// 不在 permissions 中的動作交由處理器回應 404
+func RequireActionPermission(param string, permissions map[string]auth.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		permission, ok := permissions[c.Param(param)]
+		if !ok {
+			c.Next()
+			return
+		}
+		authorize(c, permission)
+	}
+}
+
+// authorize 檢查權限並在不足時中止請求
+func authorize(c *gin.Context, permission auth.Permission) {
+	if err := auth.Authorize(c.Request.Context(), permission); err != nil {
+		abortWithError(c, http.StatusForbidden, models.ErrCodePermissionDenied, err.Error())
+		return
+	}
+
+	c.Next()
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+
+	"main/internal/auth"
+	"main/internal/models"
+	"main/internal/tenant"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TenantHeader 指定請求租戶使用的請求標頭
+const TenantHeader = "X-Tenant-ID"
+
+// Tenant 決定請求的租戶並放入 context，儲存庫以此限定所有查詢的範圍；須在 Authenticate 之後執行。
+// 有通過驗證的主體時租戶只取自憑證，沒有租戶的憑證屬於預設租戶，X-Tenant-ID 與之不同時返回 403；
+// 只有未啟用驗證時才使用 X-Tenant-ID，未指定時為預設租戶
+func Tenant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(TenantHeader)
+		if principal, ok := auth.FromContext(c.Request.Context()); ok {
+			owned := principal.Tenant
+			if owned == "" {
+				owned = tenant.Default
+			}
+			if id != "" && id != owned {
+				abortWithError(c, http.StatusForbidden, models.ErrCodeTenantMismatch, "X-Tenant-ID 與憑證所屬的租戶不同")
+				return
+			}
+			id = owned
+		}
+		if id == "" {
+			id = tenant.Default
+		}
+
+		if !tenant.Valid(id) {
+			abortWithError(c, http.StatusBadRequest, models.ErrCodeInvalidTenant, "租戶 ID 只能包含小寫字母、數字、底線與連字號，最長 63 個字元")
+			return
+		}
+
+		c.Request = c.Request.WithContext(tenant.WithID(c.Request.Context(), id))
+		c.Next()
+	}
+}
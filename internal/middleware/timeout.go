@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestTimeout 為請求的 context 設置截止時間，下游的資料庫查詢會隨之取消。
+// routeTimeouts 的鍵為 "方法 路由模板"，例如 "GET /api/v1/products/:id"，未列出的路由使用 defaultTimeout；
+// 超時為 0 表示不限制。
+func RequestTimeout(defaultTimeout time.Duration, routeTimeouts map[string]time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timeout := defaultTimeout
+		if t, ok := routeTimeouts[c.Request.Method+" "+c.FullPath()]; ok {
+			timeout = t
+		}
+
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// LowStockEvent 產品庫存在寫入後降到補貨點以下時發出的事件，由 products 的觸發器在寫入的同一個語句中記錄
+type LowStockEvent struct {
+	ID              int64     `json:"-" db:"id"`
+	ProductID       int       `json:"product_id" db:"product_id"`
+	SkuCode         string    `json:"sku_code" db:"sku_code"`
+	SkuName         string    `json:"sku_name" db:"sku_name"`
+	SkuAmount       int       `json:"sku_amount" db:"sku_amount"`
+	ReorderPoint    int       `json:"reorder_point" db:"reorder_point"`
+	ReorderQuantity int       `json:"reorder_quantity" db:"reorder_quantity"`
+	TenantID        string    `json:"tenant_id" db:"tenant_id"`
+	OccurredAt      time.Time `json:"occurred_at" db:"occurred_at"`
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"main/internal/tenant"
+
+	"github.com/lib/pq"
+)
+
+// MaxAPIKeyNameLength API 金鑰名稱的長度上限
+const MaxAPIKeyNameLength = 100
+
+// apiKeyScopePattern 範圍的格式，例如 product:read
+var apiKeyScopePattern = regexp.MustCompile(`^[a-z_]+:[a-z_]+$`)
+
+// APIKey 機器對機器客戶端使用的 API 金鑰；資料庫只保存金鑰的雜湊，明文只在創建或輪換時返回一次
+type APIKey struct {
+	ID         int64          `json:"id" db:"id"`
+	Name       string         `json:"name" db:"name"`
+	Prefix     string         `json:"prefix" db:"prefix"` // 金鑰的開頭，用於辨識
+	Scopes     pq.StringArray `json:"scopes" db:"scopes"`
+	Tenant     string         `json:"tenant" db:"tenant_id"`      // 金鑰只能存取此租戶的數據
+	ExpiresAt  Timestamp      `json:"expires_at" db:"expires_at"` // null 表示不過期
+	LastUsedAt Timestamp      `json:"last_used_at" db:"last_used_at"`
+	RevokedAt  Timestamp      `json:"revoked_at,omitzero" db:"revoked_at"`
+	CreateAt   Timestamp      `json:"create_at,omitzero" db:"create_at"`
+	UpdateAt   Timestamp      `json:"update_at,omitzero" db:"update_at"`
+}
+
+// Expired 檢查金鑰在 now 是否已過期
+func (k APIKey) Expired(now time.Time) bool {
+	return !k.ExpiresAt.IsZero() && !now.Before(k.ExpiresAt.Time)
+}
+
+// CreatedAPIKey 剛創建或輪換的 API 金鑰，Key 為只返回一次的明文
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// CreateAPIKeyRequest 創建 API 金鑰的請求，ExpiresAt 為 null 時不過期，Tenant 為空時屬於請求的租戶
+type CreateAPIKeyRequest struct {
+	Name      string    `json:"name"`
+	Scopes    []string  `json:"scopes"`
+	ExpiresAt Timestamp `json:"expires_at"`
+	Tenant    string    `json:"tenant"`
+}
+
+// Validate 驗證名稱、範圍、到期時間與租戶
+func (r CreateAPIKeyRequest) Validate(now time.Time) error {
+	if r.Name == "" {
+		return &ValidationError{Message: "API 金鑰名稱不能為空"}
+	}
+	if len([]rune(r.Name)) > MaxAPIKeyNameLength {
+		return &ValidationError{Message: fmt.Sprintf("API 金鑰名稱不能超過 %d 個字元", MaxAPIKeyNameLength)}
+	}
+	for _, scope := range r.Scopes {
+		if !apiKeyScopePattern.MatchString(scope) {
+			return &ValidationError{Message: fmt.Sprintf("無效的範圍: %q，格式為 資源:操作，例如 product:read", scope)}
+		}
+	}
+	if !r.ExpiresAt.IsZero() && !r.ExpiresAt.After(now) {
+		return &ValidationError{Message: "到期時間必須晚於現在"}
+	}
+	if r.Tenant != "" && !tenant.Valid(r.Tenant) {
+		return &ValidationError{Message: fmt.Sprintf("無效的租戶: %q，只能包含小寫字母、數字、底線與連字號", r.Tenant)}
+	}
+	return nil
+}
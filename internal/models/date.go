@@ -0,0 +1,180 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DateLayout 日期的 ISO 8601 格式
+const DateLayout = "2006-01-02"
+
+// Date 不含時間的日曆日期，JSON 與資料庫中均以 YYYY-MM-DD 表示，零值表示沒有日期
+type Date struct {
+	time.Time
+}
+
+// ParseDate 解析 YYYY-MM-DD 格式的日期，不接受不存在的日期（例如 2025-02-30）
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(DateLayout, s)
+	if err != nil {
+		return Date{}, &ValidationError{Message: fmt.Sprintf("日期格式必須為 YYYY-MM-DD: %s", s)}
+	}
+	return Date{Time: t}, nil
+}
+
+// MustParseDate 解析 YYYY-MM-DD 格式的日期，格式錯誤時 panic，僅用於常數日期
+func MustParseDate(s string) Date {
+	d, err := ParseDate(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// DateOf 返回時間在其所在時區的日期
+func DateOf(t time.Time) Date {
+	return Date{Time: time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)}
+}
+
+// String 以 YYYY-MM-DD 表示日期，零值為空字串
+func (d Date) String() string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.Format(DateLayout)
+}
+
+// MarshalJSON 以 YYYY-MM-DD 字串表示，零值為 null
+func (d Date) MarshalJSON() ([]byte, error) {
+	if d.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON 解析 YYYY-MM-DD 字串，null 表示沒有日期
+func (d *Date) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*d = Date{}
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return &ValidationError{Message: "日期必須為 YYYY-MM-DD 格式的字串"}
+	}
+
+	parsed, err := ParseDate(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// Scan 從資料庫讀取 DATE 欄位，NULL 對應零值
+func (d *Date) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*d = Date{}
+	case time.Time:
+		*d = DateOf(v)
+	case []byte:
+		return d.scanString(string(v))
+	case string:
+		return d.scanString(v)
+	default:
+		return fmt.Errorf("無法將 %T 轉換為日期", src)
+	}
+	return nil
+}
+
+func (d *Date) scanString(s string) error {
+	if len(s) > len(DateLayout) {
+		s = s[:len(DateLayout)]
+	}
+	parsed, err := ParseDate(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// Value 寫入資料庫時以 YYYY-MM-DD 表示，零值寫入 NULL
+func (d Date) Value() (driver.Value, error) {
+	if d.IsZero() {
+		return nil, nil
+	}
+	return d.String(), nil
+}
+
+// Timestamp 資料庫的時間戳，JSON 以 UTC 的 RFC 3339 表示
+type Timestamp struct {
+	time.Time
+}
+
+// String 以 UTC 的 RFC 3339 表示，零值為空字串
+func (t Timestamp) String() string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// MarshalJSON 以 UTC 的 RFC 3339 表示，零值為 null
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(t.String())
+}
+
+// UnmarshalJSON 解析 RFC 3339 時間
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*t = Timestamp{}
+		return nil
+	}
+	return json.Unmarshal(data, &t.Time)
+}
+
+// Scan 從資料庫讀取時間戳欄位，NULL 對應零值
+func (t *Timestamp) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*t = Timestamp{}
+	case time.Time:
+		*t = Timestamp{Time: v}
+	case []byte:
+		return t.scanString(string(v))
+	case string:
+		return t.scanString(v)
+	default:
+		return fmt.Errorf("無法將 %T 轉換為時間戳", src)
+	}
+	return nil
+}
+
+// timestampLayouts 以文字讀取時間戳時可接受的格式，無時區時視為 UTC
+var timestampLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999Z07", "2006-01-02 15:04:05.999999999"}
+
+func (t *Timestamp) scanString(s string) error {
+	for _, layout := range timestampLayouts {
+		if parsed, err := time.Parse(layout, s); err == nil {
+			*t = Timestamp{Time: parsed}
+			return nil
+		}
+	}
+	return fmt.Errorf("無法解析時間戳: %s", s)
+}
+
+// Value 寫入資料庫，零值寫入 NULL
+func (t Timestamp) Value() (driver.Value, error) {
+	if t.IsZero() {
+		return nil, nil
+	}
+	return t.Time, nil
+}
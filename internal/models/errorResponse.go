@@ -0,0 +1,21 @@
+package models
+
+// ErrorResponse 所有 API 錯誤回應的統一格式，控制器與中介層共用
+type ErrorResponse struct {
+	ErrorCode    string `json:"error_code"`
+	ErrorMessage string `json:"error_message"`
+	RequestID    string `json:"request_id,omitempty"`
+}
+
+// 與具體操作無關、由中介層或多個控制器共用的錯誤碼
+const (
+	ErrCodeUnauthorized     = "UNAUTHORIZED"
+	ErrCodeInvalidToken     = "INVALID_TOKEN"
+	ErrCodeAuthUnavailable  = "AUTH_UNAVAILABLE"
+	ErrCodeAdminRequired    = "ADMIN_REQUIRED"
+	ErrCodePermissionDenied = "PERMISSION_DENIED"
+	ErrCodeTenantMismatch   = "TENANT_MISMATCH"
+	ErrCodeInvalidTenant    = "INVALID_TENANT"
+	ErrCodeRequestTimeout   = "REQUEST_TIMEOUT"
+	ErrCodeRequestCanceled  = "REQUEST_CANCELED"
+)
@@ -0,0 +1,123 @@
+package models
+
+import "time"
+
+// 即將到期報表的分組方式
+const (
+	ExpiringByWarehouse = "warehouse" // 依倉庫分組，數量為產品在倉庫內各儲位的庫存，到期日取產品的到期日
+	ExpiringByLot       = "lot"       // 依批次分組，數量與到期日取批次本身
+)
+
+// 即將到期報表的查詢範圍
+const (
+	DefaultExpiringWithinDays = 30
+	MaxExpiringWithinDays     = 365
+)
+
+// ExpiringQuery 即將到期報表的查詢條件，列出今天起 WithinDays 天內到期且仍有庫存的項目
+type ExpiringQuery struct {
+	WithinDays int
+	GroupBy    string
+}
+
+// Validate 驗證查詢範圍與分組方式
+func (q ExpiringQuery) Validate() error {
+	if q.WithinDays < 0 || q.WithinDays > MaxExpiringWithinDays {
+		return &ValidationError{Message: "within 必須介於 0 到 365 天之間"}
+	}
+	if q.GroupBy != ExpiringByWarehouse && q.GroupBy != ExpiringByLot {
+		return &ValidationError{Message: "group_by 必須為 warehouse 或 lot"}
+	}
+	return nil
+}
+
+// ExpiringStock 即將到期報表的一行，依分組方式填入倉庫或批次
+type ExpiringStock struct {
+	WarehouseID   int64  `db:"warehouse_id"`
+	WarehouseCode string `db:"warehouse_code"`
+	WarehouseName string `db:"warehouse_name"`
+	LotID         int64  `db:"lot_id"`
+	LotNumber     string `db:"lot_number"`
+	ProductID     int64  `db:"product_id"`
+	SkuCode       string `db:"sku_code"`
+	SkuName       string `db:"sku_name"`
+	Expiration    Date   `db:"expiration"`
+	Quantity      int    `db:"quantity"`
+}
+
+// ExpiringItem 分組內即將到期的產品，DaysLeft 為距離到期日的天數，當天到期為 0
+type ExpiringItem struct {
+	ProductID  int64  `json:"product_id"`
+	SkuCode    string `json:"sku_code"`
+	SkuName    string `json:"sku_name"`
+	Expiration Date   `json:"expiration"`
+	DaysLeft   int    `json:"days_left"`
+	Quantity   int    `json:"quantity"`
+}
+
+// ExpiringGroup 報表的一個分組，依分組方式只填入倉庫或批次的欄位
+type ExpiringGroup struct {
+	WarehouseID   int64          `json:"warehouse_id,omitempty"`
+	WarehouseCode string         `json:"warehouse_code,omitempty"`
+	WarehouseName string         `json:"warehouse_name,omitempty"`
+	LotID         int64          `json:"lot_id,omitempty"`
+	LotNumber     string         `json:"lot_number,omitempty"`
+	Quantity      int            `json:"quantity"`
+	Items         []ExpiringItem `json:"items"`
+}
+
+// ExpiringReport 即將到期報表，Cutoff 為範圍的最後一天（含）
+type ExpiringReport struct {
+	WithinDays int             `json:"within_days"`
+	Cutoff     Date            `json:"cutoff"`
+	GroupBy    string          `json:"group_by"`
+	Quantity   int             `json:"quantity"`
+	Groups     []ExpiringGroup `json:"groups"`
+}
+
+// ExpiredStock 過期清理標記的批次或產品，LotID 為 0 表示產品本身的到期日已過
+type ExpiredStock struct {
+	ProductID  int64  `db:"product_id"`
+	SkuCode    string `db:"sku_code"`
+	SkuName    string `db:"sku_name"`
+	LotID      int64  `db:"lot_id"`
+	LotNumber  string `db:"lot_number"`
+	Expiration Date   `db:"expiration"`
+	Quantity   int    `db:"quantity"`
+	TenantID   string `db:"tenant_id"`
+}
+
+// ExpirySweepResult 一次過期清理的摘要
+type ExpirySweepResult struct {
+	Lots     int // 標記的批次數
+	Products int // 標記的產品數
+	Quantity int // 標記的批次與產品的庫存合計，產品的庫存可能已包含在其批次中
+}
+
+// ExpiredStockEvent 批次或產品被標記為過期時發出的事件
+type ExpiredStockEvent struct {
+	ProductID  int64     `json:"product_id"`
+	SkuCode    string    `json:"sku_code"`
+	SkuName    string    `json:"sku_name"`
+	LotID      int64     `json:"lot_id,omitempty"`
+	LotNumber  string    `json:"lot_number,omitempty"`
+	Expiration Date      `json:"expiration"`
+	Quantity   int       `json:"quantity"`
+	TenantID   string    `json:"tenant_id"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// NewExpiredStockEvent 以標記的批次或產品建立過期事件
+func NewExpiredStockEvent(stock ExpiredStock, now time.Time) ExpiredStockEvent {
+	return ExpiredStockEvent{
+		ProductID:  stock.ProductID,
+		SkuCode:    stock.SkuCode,
+		SkuName:    stock.SkuName,
+		LotID:      stock.LotID,
+		LotNumber:  stock.LotNumber,
+		Expiration: stock.Expiration,
+		Quantity:   stock.Quantity,
+		TenantID:   stock.TenantID,
+		OccurredAt: now,
+	}
+}
@@ -0,0 +1,30 @@
+package models
+
+// ProductLot 產品的一個批次；出庫依到期日由早到晚（FEFO）分配，沒有到期日的批次最後分配
+type ProductLot struct {
+	ID         int64     `json:"id" db:"id"`
+	ProductID  int64     `json:"product_id" db:"product_id"`
+	LotNumber  string    `json:"lot_number" db:"lot_number"`
+	Quantity   int       `json:"quantity" db:"quantity"`
+	Expiration Date      `json:"expiration" db:"expiration"`
+	ReceivedAt Date      `json:"received_at" db:"received_at"`
+	ExpiredAt  Timestamp `json:"expired_at,omitzero" db:"expired_at"` // 過期清理標記的時間，標記後只能以 expired 原因出庫
+	CreateAt   Timestamp `json:"create_at,omitzero" db:"create_at"`
+	UpdateAt   Timestamp `json:"update_at,omitzero" db:"update_at"`
+}
+
+// MovementLot 一筆異動在某個批次上的增減，Quantity 的正負號與異動相同
+type MovementLot struct {
+	MovementID int64  `json:"-" db:"movement_id"`
+	LotID      int64  `json:"lot_id" db:"lot_id"`
+	LotNumber  string `json:"lot_number" db:"lot_number"`
+	Expiration Date   `json:"expiration" db:"expiration"`
+	Quantity   int    `json:"quantity" db:"quantity"`
+}
+
+// ProductLotStock 產品的批次庫存，UntrackedQuantity 為不屬於任何批次的庫存
+type ProductLotStock struct {
+	ProductID         int64        `json:"product_id"`
+	Items             []ProductLot `json:"items"`
+	UntrackedQuantity int          `json:"untracked_quantity"`
+}
@@ -1,11 +1,75 @@
 package models
 
+import "time"
+
 type Product struct {
-	ID         int    `json:"id,omitempty" db:"id"`
-	SkuCode    string `json:"sku_code,omitempty" db:"sku_code"`
-	SkuName    string `json:"sku_name,omitempty" db:"sku_name"`
-	SkuAmount  int    `json:"sku_amount,omitempty" db:"sku_amount"`
-	Expiration string `json:"expiration,omitempty" db:"expiration"`
-	CreateAt   string `json:"create_at,omitempty" db:"create_at"`
-	UpdateAt   string `json:"update_at,omitempty" db:"update_at"`
+	ID         int       `json:"id,omitempty" db:"id"`
+	SkuCode    string    `json:"sku_code,omitempty" db:"sku_code"`
+	SkuName    string    `json:"sku_name,omitempty" db:"sku_name"`
+	SkuAmount  int       `json:"sku_amount,omitempty" db:"sku_amount"`
+	Expiration Date      `json:"expiration,omitzero" db:"expiration"`
+	CreateAt   Timestamp `json:"create_at,omitzero" db:"create_at"`
+	UpdateAt   Timestamp `json:"update_at,omitzero" db:"update_at"`
+	DeletedAt  Timestamp `json:"deleted_at,omitzero" db:"deleted_at"` // 軟刪除的時間，未刪除時為零值
+	Version    int       `json:"version,omitempty" db:"version"`
+	TenantID   string    `json:"-" db:"tenant_id"` // 所屬的租戶，由請求的租戶決定，不在 API 中出現
+
+	// ReorderPoint 補貨點，庫存低於此值時觸發低庫存事件，0 表示不監控；ReorderQuantity 建議的補貨數量
+	ReorderPoint    int `json:"reorder_point,omitempty" db:"reorder_point"`
+	ReorderQuantity int `json:"reorder_quantity,omitempty" db:"reorder_quantity"`
+
+	// ExpiredAt 過期清理把產品標記為過期的時間，標記後庫存不能以一般出庫動用；修改到期日時清除
+	ExpiredAt Timestamp `json:"expired_at,omitzero" db:"expired_at"`
+
+	// Locations 各儲位的庫存，合計為 SkuAmount；只在讀取產品時填入
+	Locations []LocationStock `json:"locations,omitempty" db:"-"`
+}
+
+// ValidationError 產品欄位驗證失敗，訊息可直接返回給客戶端
+type ValidationError struct {
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// ValidateProduct 驗證產品的基本欄位
+func ValidateProduct(product Product) error {
+	if product.SkuCode == "" {
+		return &ValidationError{Message: "產品名稱不能為空"}
+	}
+
+	if product.SkuAmount < 0 {
+		return &ValidationError{Message: "產品庫存不能為負數"}
+	}
+
+	if product.ReorderPoint < 0 || product.ReorderQuantity < 0 {
+		return &ValidationError{Message: "補貨點與補貨數量不能為負數"}
+	}
+
+	return nil
+}
+
+// IsLowStock 檢查產品是否設置了補貨點且庫存低於補貨點
+func (p Product) IsLowStock() bool {
+	return p.ReorderPoint > 0 && p.SkuAmount < p.ReorderPoint
+}
+
+// ValidateNewProduct 驗證新產品，除基本欄位外，到期日不能早於 today
+func ValidateNewProduct(product Product, today Date) error {
+	if err := ValidateProduct(product); err != nil {
+		return err
+	}
+
+	if !product.Expiration.IsZero() && product.Expiration.Before(today.Time) {
+		return &ValidationError{Message: "新產品的到期日不能早於今天"}
+	}
+
+	return nil
+}
+
+// Today 返回目前的日期（UTC）
+func Today() Date {
+	return DateOf(time.Now().UTC())
 }
@@ -0,0 +1,51 @@
+package models
+
+import "fmt"
+
+// MaxBatchSize 單次批量操作允許的最大項目數
+const MaxBatchSize = 5000
+
+// ProductBatchRequest 批量創建或更新產品的請求
+type ProductBatchRequest struct {
+	Atomic bool      `json:"atomic"` // true 時全部成功或全部不生效，false 時逐項返回結果
+	Items  []Product `json:"items"`  // 批量更新時每項需帶 id 與 version，並以完整內容替換
+}
+
+// ProductBatchDeleteRequest 批量刪除產品的請求
+type ProductBatchDeleteRequest struct {
+	Atomic bool         `json:"atomic"`
+	Items  []ProductRef `json:"items"`
+}
+
+// ProductRef 指向特定版本的產品
+type ProductRef struct {
+	ID      int64 `json:"id"`
+	Version int   `json:"version"`
+}
+
+// ProductBatchUpdate 批量更新中的單項
+type ProductBatchUpdate struct {
+	ID      int64
+	Version int
+	Patch   ProductPatch
+}
+
+// BatchOutcome 批量操作中單項的結果，Err 為 nil 表示成功
+type BatchOutcome struct {
+	Product Product
+	Err     error
+}
+
+// BatchItemError 批量操作中第 Index 項（從 0 開始）失敗
+type BatchItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchItemError) Error() string {
+	return fmt.Sprintf("第 %d 項: %v", e.Index, e.Err)
+}
+
+func (e *BatchItemError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,36 @@
+package models
+
+// 變更歷史的操作類型
+const (
+	HistoryCreate   = "create"
+	HistoryUpdate   = "update"
+	HistoryDelete   = "delete"
+	HistoryRestore  = "restore"
+	HistoryPurge    = "purge"
+	HistoryBaseline = "baseline" // 建立歷史記錄前已存在的產品，以當時的內容作為基準
+)
+
+// ProductHistory 產品的一次變更，Before/After 為變更前後的快照，創建時沒有 Before，永久刪除時沒有 After
+type ProductHistory struct {
+	ID        int64     `json:"id"`
+	ProductID int64     `json:"product_id"`
+	Operation string    `json:"operation"`
+	Version   int       `json:"version"`
+	Before    *Product  `json:"before"`
+	After     *Product  `json:"after"`
+	Actor     string    `json:"actor,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+	ChangedAt Timestamp `json:"changed_at"`
+}
+
+// ProductHistoryPage 分頁後的變更歷史，依時間由新到舊排序
+type ProductHistoryPage struct {
+	Items    []ProductHistory `json:"items"`
+	Total    int              `json:"total"`
+	PageSize int              `json:"page_size"`
+	Offset   int              `json:"offset,omitempty"`
+	Links    PageLinks        `json:"links"`
+
+	HasNext bool `json:"-"`
+	HasPrev bool `json:"-"`
+}
@@ -0,0 +1,25 @@
+package models
+
+// ImportRow 從匯入檔案讀取的一列
+type ImportRow struct {
+	Line    int // 檔案中的行號（表頭為第 1 行）
+	Product Product
+	Err     error // 解析失敗的原因
+}
+
+// ImportReport 匯入結果
+type ImportReport struct {
+	DryRun  bool             `json:"dry_run"`
+	Total   int              `json:"total"`
+	Created int              `json:"created"`
+	Updated int              `json:"updated"`
+	Failed  int              `json:"failed"`
+	Errors  []ImportRowError `json:"errors"`
+}
+
+// ImportRowError 匯入失敗的一列
+type ImportRowError struct {
+	Line    int    `json:"line"`
+	SkuCode string `json:"sku_code,omitempty"`
+	Error   string `json:"error"`
+}
@@ -0,0 +1,283 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// 修補文件錯誤
+var (
+	ErrInvalidPatch    = errors.New("無效的修補文件")
+	ErrPatchTestFailed = errors.New("修補文件的 test 操作不成立")
+)
+
+// Nullable 可區分「未提供」、「null」與具體值的欄位
+type Nullable[T any] struct {
+	Set   bool // JSON 中出現了該欄位
+	Null  bool // 欄位值為 null
+	Value T
+}
+
+// UnmarshalJSON 只有欄位出現在 JSON 中時才會被調用，因此可據此標記 Set
+func (n *Nullable[T]) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		n.Null = true
+		return nil
+	}
+	return json.Unmarshal(data, &n.Value)
+}
+
+// ProductPatch 產品的部分更新，未提供的欄位保持不變
+type ProductPatch struct {
+	SkuCode    Nullable[string] `json:"sku_code"`
+	SkuName    Nullable[string] `json:"sku_name"`
+	SkuAmount  Nullable[int]    `json:"sku_amount"`
+	Expiration Nullable[Date]   `json:"expiration"` // null 表示清除到期日
+
+	ReorderPoint    Nullable[int] `json:"reorder_point"`
+	ReorderQuantity Nullable[int] `json:"reorder_quantity"`
+}
+
+// productPatchFields 允許修補的欄位，其餘欄位（id、version、時間戳）由系統維護
+var productPatchFields = []string{"sku_code", "sku_name", "sku_amount", "expiration", "reorder_point", "reorder_quantity"}
+
+// ReplacePatch 以完整產品建立修補，所有欄位都會被覆蓋（PUT 的完整替換語義）
+func ReplacePatch(product Product) ProductPatch {
+	return ProductPatch{
+		SkuCode:    Nullable[string]{Set: true, Value: product.SkuCode},
+		SkuName:    Nullable[string]{Set: true, Value: product.SkuName},
+		SkuAmount:  Nullable[int]{Set: true, Value: product.SkuAmount},
+		Expiration: Nullable[Date]{Set: true, Null: product.Expiration.IsZero(), Value: product.Expiration},
+
+		ReorderPoint:    Nullable[int]{Set: true, Value: product.ReorderPoint},
+		ReorderQuantity: Nullable[int]{Set: true, Value: product.ReorderQuantity},
+	}
+}
+
+// IsEmpty 檢查修補是否不包含任何欄位
+func (p ProductPatch) IsEmpty() bool {
+	return !p.SkuCode.Set && !p.SkuName.Set && !p.SkuAmount.Set && !p.Expiration.Set &&
+		!p.ReorderPoint.Set && !p.ReorderQuantity.Set
+}
+
+// Apply 將修補套用到產品上並返回結果，必填欄位不能設為 null
+func (p ProductPatch) Apply(product Product) (Product, error) {
+	if p.SkuCode.Set {
+		if p.SkuCode.Null {
+			return Product{}, &ValidationError{Message: "sku_code 不能為 null"}
+		}
+		product.SkuCode = p.SkuCode.Value
+	}
+
+	if p.SkuName.Set {
+		if p.SkuName.Null {
+			return Product{}, &ValidationError{Message: "sku_name 不能為 null"}
+		}
+		product.SkuName = p.SkuName.Value
+	}
+
+	if p.SkuAmount.Set {
+		if p.SkuAmount.Null {
+			return Product{}, &ValidationError{Message: "sku_amount 不能為 null"}
+		}
+		product.SkuAmount = p.SkuAmount.Value
+	}
+
+	if p.Expiration.Set {
+		product.Expiration = Date{}
+		if !p.Expiration.Null {
+			product.Expiration = p.Expiration.Value
+		}
+	}
+
+	if p.ReorderPoint.Set {
+		if p.ReorderPoint.Null {
+			return Product{}, &ValidationError{Message: "reorder_point 不能為 null"}
+		}
+		product.ReorderPoint = p.ReorderPoint.Value
+	}
+
+	if p.ReorderQuantity.Set {
+		if p.ReorderQuantity.Null {
+			return Product{}, &ValidationError{Message: "reorder_quantity 不能為 null"}
+		}
+		product.ReorderQuantity = p.ReorderQuantity.Value
+	}
+
+	return product, nil
+}
+
+// DecodeMergePatch 解析 JSON Merge Patch（RFC 7396）文件
+func DecodeMergePatch(data []byte) (ProductPatch, error) {
+	var patch ProductPatch
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return patch, fmt.Errorf("%w: merge patch 必須為 JSON 物件", ErrInvalidPatch)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(trimmed))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patch); err != nil {
+		return ProductPatch{}, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	return patch, nil
+}
+
+// JSONPatchOperation JSON Patch（RFC 6902）中的單個操作
+type JSONPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// DecodeJSONPatch 解析 JSON Patch 文件
+func DecodeJSONPatch(data []byte) ([]JSONPatchOperation, error) {
+	var ops []JSONPatchOperation
+	if err := json.Unmarshal(data, &ops); err != nil {
+		return nil, fmt.Errorf("%w: json patch 必須為操作陣列: %v", ErrInvalidPatch, err)
+	}
+	return ops, nil
+}
+
+// ApplyJSONPatch 在產品的 JSON 表示上依序執行操作，並返回與原產品的差異；
+// 任一操作失敗時整份修補都不生效
+func ApplyJSONPatch(product Product, ops []JSONPatchOperation) (ProductPatch, error) {
+	original := productDocument(product)
+
+	doc := make(map[string]json.RawMessage, len(original))
+	for k, v := range original {
+		doc[k] = v
+	}
+
+	for i, op := range ops {
+		if err := applyJSONPatchOperation(doc, op); err != nil {
+			return ProductPatch{}, fmt.Errorf("第 %d 個操作 (%s %s): %w", i+1, op.Op, op.Path, err)
+		}
+	}
+
+	// 只保留有變化的欄位，被移除的欄位視為 null
+	changes := map[string]json.RawMessage{}
+	for _, field := range productPatchFields {
+		value, ok := doc[field]
+		if !ok {
+			value = json.RawMessage("null")
+		}
+		if !bytes.Equal(value, original[field]) {
+			changes[field] = value
+		}
+	}
+
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return ProductPatch{}, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return DecodeMergePatch(data)
+}
+
+// productDocument 產品可修補欄位的 JSON 表示
+func productDocument(product Product) map[string]json.RawMessage {
+	doc := map[string]json.RawMessage{}
+	doc["sku_code"], _ = json.Marshal(product.SkuCode)
+	doc["sku_name"], _ = json.Marshal(product.SkuName)
+	doc["sku_amount"], _ = json.Marshal(product.SkuAmount)
+	doc["expiration"], _ = json.Marshal(product.Expiration)
+	doc["reorder_point"], _ = json.Marshal(product.ReorderPoint)
+	doc["reorder_quantity"], _ = json.Marshal(product.ReorderQuantity)
+	return doc
+}
+
+// applyJSONPatchOperation 在文件上執行單個操作
+func applyJSONPatchOperation(doc map[string]json.RawMessage, op JSONPatchOperation) error {
+	field, err := patchField(op.Path)
+	if err != nil {
+		return err
+	}
+
+	switch op.Op {
+	case "add":
+		if op.Value == nil {
+			return fmt.Errorf("%w: 缺少 value", ErrInvalidPatch)
+		}
+		doc[field] = compactJSON(op.Value)
+	case "replace":
+		if _, ok := doc[field]; !ok {
+			return fmt.Errorf("%w: 路徑不存在", ErrInvalidPatch)
+		}
+		if op.Value == nil {
+			return fmt.Errorf("%w: 缺少 value", ErrInvalidPatch)
+		}
+		doc[field] = compactJSON(op.Value)
+	case "remove":
+		if _, ok := doc[field]; !ok {
+			return fmt.Errorf("%w: 路徑不存在", ErrInvalidPatch)
+		}
+		delete(doc, field)
+	case "move", "copy":
+		from, err := patchField(op.From)
+		if err != nil {
+			return err
+		}
+		value, ok := doc[from]
+		if !ok {
+			return fmt.Errorf("%w: from 路徑不存在", ErrInvalidPatch)
+		}
+		if op.Op == "move" {
+			delete(doc, from)
+		}
+		doc[field] = value
+	case "test":
+		if !jsonEqual(doc[field], op.Value) {
+			return ErrPatchTestFailed
+		}
+	default:
+		return fmt.Errorf("%w: 不支援的操作 %q", ErrInvalidPatch, op.Op)
+	}
+
+	return nil
+}
+
+// patchField 將 JSON Pointer 解析為可修補的頂層欄位
+func patchField(pointer string) (string, error) {
+	if !strings.HasPrefix(pointer, "/") || strings.Count(pointer, "/") != 1 {
+		return "", fmt.Errorf("%w: 不支援的路徑 %q", ErrInvalidPatch, pointer)
+	}
+
+	field := strings.NewReplacer("~1", "/", "~0", "~").Replace(pointer[1:])
+	for _, f := range productPatchFields {
+		if f == field {
+			return field, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: 欄位 %q 不可修改", ErrInvalidPatch, field)
+}
+
+// compactJSON 去除多餘空白，讓相同的值有相同的表示
+func compactJSON(value json.RawMessage) json.RawMessage {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, value); err != nil {
+		return value
+	}
+	return buf.Bytes()
+}
+
+// jsonEqual 依 JSON 語義比較兩個值，缺少的值與 null 不相等
+func jsonEqual(a, b json.RawMessage) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
@@ -0,0 +1,86 @@
+package models
+
+// 分頁預設值
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// 排序方向
+const (
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+// ProductSortFields 允許用於排序的產品欄位
+var ProductSortFields = []string{"id", "sku_code", "sku_name", "sku_amount", "expiration", "create_at", "update_at"}
+
+// KeysetSortFields 支援鍵集分頁的排序欄位（必須為非空欄位）
+var KeysetSortFields = []string{"id", "sku_code", "sku_name", "sku_amount", "create_at", "update_at"}
+
+// ProductQuery 產品列表的查詢選項（分頁、排序與過濾）
+type ProductQuery struct {
+	PageSize int
+	Offset   int
+	SortBy   string
+	SortDir  string
+
+	// Keyset 鍵集分頁定位點，設置時忽略 Offset
+	Keyset *ProductKeyset
+
+	SkuCode        string // 依 SKU 代碼前綴過濾
+	SkuName        string // 依名稱模糊過濾
+	MinAmount      *int   // 庫存下限（含）
+	MaxAmount      *int   // 庫存上限（含）
+	ExpirationFrom Date   // 到期日下限（含）
+	ExpirationTo   Date   // 到期日上限（含）
+	WarehouseID    int64  // 只包含在該倉庫有庫存的產品
+}
+
+// ProductKeyset 鍵集分頁定位點，即上一頁邊界那一筆的排序鍵
+type ProductKeyset struct {
+	Value    string // 排序欄位的值
+	ID       int    // 排序鍵相同時以 id 區分
+	Backward bool   // 往前翻頁
+}
+
+// ProductPage 分頁後的產品列表
+type ProductPage struct {
+	Items      []Product `json:"items"`
+	Total      int       `json:"total"`
+	PageSize   int       `json:"page_size"`
+	Offset     int       `json:"offset,omitempty"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+	PrevCursor string    `json:"prev_cursor,omitempty"`
+	Links      PageLinks `json:"links"`
+
+	HasNext bool `json:"-"`
+	HasPrev bool `json:"-"`
+}
+
+// PageLinks 分頁導覽連結
+type PageLinks struct {
+	Self string `json:"self"`
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// IsSortableProductField 檢查欄位是否允許排序
+func IsSortableProductField(field string) bool {
+	for _, f := range ProductSortFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// IsKeysetSortField 檢查欄位是否支援鍵集分頁
+func IsKeysetSortField(field string) bool {
+	for _, f := range KeysetSortFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package models
+
+// 預留的狀態：active 佔用可承諾量，其餘為終止狀態
+const (
+	ReservationActive    = "active"
+	ReservationCommitted = "committed"
+	ReservationReleased  = "released"
+	ReservationExpired   = "expired"
+)
+
+// StockReservation 產品庫存的預留；Reference 為同一產品內的冪等鍵，
+// 提交後 MovementID 為對應的出庫異動
+type StockReservation struct {
+	ID         int64     `json:"id" db:"id"`
+	ProductID  int64     `json:"product_id" db:"product_id"`
+	Quantity   int       `json:"quantity" db:"quantity"`
+	Reference  string    `json:"reference" db:"reference"`
+	Status     string    `json:"status" db:"status"`
+	ExpiresAt  Timestamp `json:"expires_at" db:"expires_at"`
+	MovementID int64     `json:"movement_id,omitempty" db:"movement_id"`
+	Actor      string    `json:"actor,omitempty" db:"actor"`
+	RequestID  string    `json:"request_id,omitempty" db:"request_id"`
+	CreateAt   Timestamp `json:"create_at,omitzero" db:"create_at"`
+	UpdateAt   Timestamp `json:"update_at,omitzero" db:"update_at"`
+}
+
+// ReservationRequest 創建預留的請求，TTLSeconds 為 0 時使用預設的有效期
+type ReservationRequest struct {
+	Quantity   int    `json:"quantity"`
+	Reference  string `json:"reference"`
+	TTLSeconds int    `json:"ttl_seconds,omitempty"`
+}
+
+// CommitReservationRequest 提交預留的請求，LocationID 為 0 時從預設儲位出庫
+type CommitReservationRequest struct {
+	LocationID int64 `json:"location_id,omitempty"`
+}
+
+// StockAvailability 產品的可承諾量：Available 為 OnHand 扣除未到期的 active 預留與已標記過期的庫存
+type StockAvailability struct {
+	ProductID int64 `json:"product_id" db:"product_id"`
+	OnHand    int   `json:"on_hand" db:"on_hand"`
+	Reserved  int   `json:"reserved" db:"reserved"`
+	Expired   int   `json:"expired" db:"expired"`
+	Available int   `json:"available" db:"available"`
+}
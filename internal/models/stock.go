@@ -0,0 +1,106 @@
+package models
+
+// 庫存異動類型
+const (
+	MovementReceive     = "receive"
+	MovementIssue       = "issue"
+	MovementAdjust      = "adjust"
+	MovementTransferOut = "transfer_out"
+	MovementTransferIn  = "transfer_in"
+
+	// MovementTransfer 只用於請求，產生 transfer_out 與 transfer_in 兩筆異動
+	MovementTransfer = "transfer"
+)
+
+// 庫存異動的原因代碼
+const (
+	ReasonPurchase       = "purchase"        // 採購入庫
+	ReasonCustomerReturn = "customer_return" // 客戶退貨
+	ReasonSale           = "sale"            // 銷售出庫
+	ReasonDamage         = "damage"          // 損壞
+	ReasonExpired        = "expired"         // 過期報廢
+	ReasonLoss           = "loss"            // 遺失
+	ReasonCount          = "count"           // 盤點差異
+	ReasonCorrection     = "correction"      // 更正先前的錯誤
+	ReasonTransfer       = "transfer"        // 儲位或產品間轉移
+	ReasonOpeningBalance = "opening_balance" // 期初餘額或創建產品時的庫存
+	ReasonProductEdit    = "product_edit"    // 經由產品更新直接修改庫存
+)
+
+// stockReasons 客戶端可以指定的原因代碼，opening_balance 與 product_edit 只由系統記錄
+var stockReasons = map[string]bool{
+	ReasonPurchase:       true,
+	ReasonCustomerReturn: true,
+	ReasonSale:           true,
+	ReasonDamage:         true,
+	ReasonExpired:        true,
+	ReasonLoss:           true,
+	ReasonCount:          true,
+	ReasonCorrection:     true,
+}
+
+// IsStockReason 判斷原因代碼是否可由客戶端指定
+func IsStockReason(reason string) bool {
+	return stockReasons[reason]
+}
+
+// StockMovement 庫存異動帳的一筆紀錄，Quantity 入庫為正、出庫為負，Balance 為異動後產品的總庫存；
+// 寫入時 LocationID 為 0 表示預設儲位，讀取時為 0 表示儲位已被刪除；
+// Lots 為異動增減的批次，寫入入庫時指定要放入的批次，出庫時由儲存庫依 FEFO 分配
+type StockMovement struct {
+	ID         int64     `json:"id" db:"id"`
+	ProductID  int64     `json:"product_id" db:"product_id"`
+	LocationID int64     `json:"location_id,omitempty" db:"location_id"`
+	Type       string    `json:"type" db:"movement_type"`
+	Quantity   int       `json:"quantity" db:"quantity"`
+	Reason     string    `json:"reason" db:"reason"`
+	Reference  string    `json:"reference,omitempty" db:"reference"`
+	Balance    int       `json:"balance" db:"balance"`
+	Actor      string    `json:"actor,omitempty" db:"actor"`
+	RequestID  string    `json:"request_id,omitempty" db:"request_id"`
+	CreatedAt  Timestamp `json:"created_at" db:"created_at"`
+
+	Lots []MovementLot `json:"lots,omitempty" db:"-"`
+}
+
+// StockRequest 入庫、出庫、調整與轉移的共同參數；Quantity 對入庫、出庫與轉移為正數，對調整為帶正負號的差額；
+// LocationID 為 0 時使用預設儲位；LotNumber 與 Expiration 只用於入庫，省略批號時入庫的庫存不屬於任何批次
+type StockRequest struct {
+	Quantity   int    `json:"quantity"`
+	Reason     string `json:"reason,omitempty"`
+	Reference  string `json:"reference,omitempty"`
+	LocationID int64  `json:"location_id,omitempty"`
+	LotNumber  string `json:"lot_number,omitempty"`
+	Expiration Date   `json:"expiration,omitzero"`
+}
+
+// TransferRequest 將同一產品的庫存從 LocationID 轉移到 ToLocationID
+type TransferRequest struct {
+	StockRequest
+	ToLocationID int64 `json:"to_location_id,omitempty"`
+}
+
+// StockAdjustRequest 原子調整產品庫存的請求，Delta 為帶正負號的差額
+type StockAdjustRequest struct {
+	Delta int `json:"delta"`
+}
+
+// StockMovementRequest 登記庫存異動的請求，Type 為 receive、issue、adjust 或 transfer；
+// ToProductID 只為拒絕跨產品轉移而綁定，只能省略或等於來源產品
+type StockMovementRequest struct {
+	Type        string `json:"type" binding:"required"`
+	ToProductID int64  `json:"to_product_id,omitempty"`
+	TransferRequest
+}
+
+// StockMovementPage 分頁後的庫存異動，依時間由新到舊排序
+type StockMovementPage struct {
+	Items    []StockMovement `json:"items"`
+	Total    int             `json:"total"`
+	PageSize int             `json:"page_size"`
+	Offset   int             `json:"offset,omitempty"`
+	Links    PageLinks       `json:"links"`
+
+	HasNext bool `json:"-"`
+	HasPrev bool `json:"-"`
+}
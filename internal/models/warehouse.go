@@ -0,0 +1,55 @@
+package models
+
+// Warehouse 倉庫
+type Warehouse struct {
+	ID       int64     `json:"id" db:"id"`
+	Code     string    `json:"code" db:"code"`
+	Name     string    `json:"name" db:"name"`
+	TenantID string    `json:"-" db:"tenant_id"`
+	CreateAt Timestamp `json:"create_at,omitzero" db:"create_at"`
+	UpdateAt Timestamp `json:"update_at,omitzero" db:"update_at"`
+}
+
+// StockLocation 倉庫內的儲位；IsDefault 的儲位接收租戶內未指定儲位的異動與直接修改的庫存，由系統維護
+type StockLocation struct {
+	ID          int64     `json:"id" db:"id"`
+	WarehouseID int64     `json:"warehouse_id" db:"warehouse_id"`
+	Code        string    `json:"code" db:"code"`
+	Name        string    `json:"name" db:"name"`
+	IsDefault   bool      `json:"is_default" db:"is_default"`
+	TenantID    string    `json:"-" db:"tenant_id"`
+	CreateAt    Timestamp `json:"create_at,omitzero" db:"create_at"`
+	UpdateAt    Timestamp `json:"update_at,omitzero" db:"update_at"`
+}
+
+// LocationStock 產品在單個儲位的庫存
+type LocationStock struct {
+	ProductID     int64  `json:"-" db:"product_id"`
+	LocationID    int64  `json:"location_id" db:"location_id"`
+	LocationCode  string `json:"location_code" db:"location_code"`
+	WarehouseID   int64  `json:"warehouse_id" db:"warehouse_id"`
+	WarehouseCode string `json:"warehouse_code" db:"warehouse_code"`
+	Quantity      int    `json:"quantity" db:"quantity"`
+}
+
+// ValidateWarehouse 驗證倉庫欄位
+func ValidateWarehouse(warehouse Warehouse) error {
+	if warehouse.Code == "" {
+		return &ValidationError{Message: "倉庫代碼不能為空"}
+	}
+	if warehouse.Name == "" {
+		return &ValidationError{Message: "倉庫名稱不能為空"}
+	}
+	return nil
+}
+
+// ValidateStockLocation 驗證儲位欄位
+func ValidateStockLocation(location StockLocation) error {
+	if location.Code == "" {
+		return &ValidationError{Message: "儲位代碼不能為空"}
+	}
+	if location.Name == "" {
+		return &ValidationError{Message: "儲位名稱不能為空"}
+	}
+	return nil
+}
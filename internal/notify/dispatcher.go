@@ -0,0 +1,110 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	model "main/internal/models"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrQueueFull 派送佇列已滿，事件被丟棄
+var ErrQueueFull = errors.New("通知佇列已滿，事件已丟棄")
+
+// Dispatcher 以背景 goroutine 非同步派送事件，寫入請求不必等待 Webhook 或 SMTP 完成；
+// 派送失敗只記錄日誌，不會重試
+type Dispatcher struct {
+	notifier Notifier
+	logger   *zap.Logger
+	queue    chan delivery
+	timeout  time.Duration
+}
+
+// delivery 佇列中的一個事件，send 以下游的管道送出事件，fields 用於記錄日誌
+type delivery struct {
+	kind   string
+	send   func(ctx context.Context, notifier Notifier) error
+	fields []zap.Field
+}
+
+// NewDispatcher 創建派送器，size 為佇列容量，timeout 為單個事件的派送時限
+func NewDispatcher(notifier Notifier, logger *zap.Logger, size int, timeout time.Duration) *Dispatcher {
+	return &Dispatcher{
+		notifier: notifier,
+		logger:   logger,
+		queue:    make(chan delivery, size),
+		timeout:  timeout,
+	}
+}
+
+// NotifyLowStock 將事件放入佇列後立即返回，佇列已滿時丟棄事件並返回 ErrQueueFull
+func (d *Dispatcher) NotifyLowStock(ctx context.Context, event model.LowStockEvent) error {
+	return d.enqueue(delivery{
+		kind: "低庫存",
+		send: func(ctx context.Context, notifier Notifier) error { return notifier.NotifyLowStock(ctx, event) },
+		fields: []zap.Field{
+			zap.Int("product_id", event.ProductID),
+			zap.String("sku_code", event.SkuCode),
+		},
+	})
+}
+
+// NotifyExpired 將事件放入佇列後立即返回，佇列已滿時丟棄事件並返回 ErrQueueFull
+func (d *Dispatcher) NotifyExpired(ctx context.Context, event model.ExpiredStockEvent) error {
+	return d.enqueue(delivery{
+		kind: "過期",
+		send: func(ctx context.Context, notifier Notifier) error { return notifier.NotifyExpired(ctx, event) },
+		fields: []zap.Field{
+			zap.Int64("product_id", event.ProductID),
+			zap.String("sku_code", event.SkuCode),
+			zap.String("lot_number", event.LotNumber),
+		},
+	})
+}
+
+func (d *Dispatcher) enqueue(item delivery) error {
+	select {
+	case d.queue <- item:
+		return nil
+	default:
+		d.logger.Warn("通知佇列已滿，丟棄"+item.kind+"事件", item.fields...)
+		return ErrQueueFull
+	}
+}
+
+// Run 逐一派送佇列中的事件，直到 ctx 被取消；取消後送出佇列中剩餘的事件再返回
+func (d *Dispatcher) Run(ctx context.Context) {
+	for {
+		select {
+		case item := <-d.queue:
+			d.deliver(item)
+		case <-ctx.Done():
+			d.drain()
+			return
+		}
+	}
+}
+
+// drain 送出佇列中剩餘的事件
+func (d *Dispatcher) drain() {
+	for {
+		select {
+		case item := <-d.queue:
+			d.deliver(item)
+		default:
+			return
+		}
+	}
+}
+
+// deliver 在時限內派送單個事件，失敗時記錄日誌；
+// 時限與 Run 的 ctx 無關，停止時剩餘的事件仍能送出
+func (d *Dispatcher) deliver(item delivery) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	if err := item.send(ctx, d.notifier); err != nil {
+		d.logger.Error("派送"+item.kind+"事件失敗", append(item.fields, zap.Error(err))...)
+	}
+}
@@ -0,0 +1,43 @@
+package notify
+
+import (
+	"context"
+	model "main/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// LogNotifier 將庫存事件寫入日誌
+type LogNotifier struct {
+	logger *zap.Logger
+}
+
+// NewLogNotifier 創建日誌通知管道
+func NewLogNotifier(logger *zap.Logger) *LogNotifier {
+	return &LogNotifier{logger: logger}
+}
+
+func (n *LogNotifier) NotifyLowStock(ctx context.Context, event model.LowStockEvent) error {
+	n.logger.Warn("產品庫存低於補貨點",
+		zap.Int("product_id", event.ProductID),
+		zap.String("sku_code", event.SkuCode),
+		zap.Int("sku_amount", event.SkuAmount),
+		zap.Int("reorder_point", event.ReorderPoint),
+		zap.Int("reorder_quantity", event.ReorderQuantity),
+		zap.String("tenant_id", event.TenantID),
+	)
+	return nil
+}
+
+func (n *LogNotifier) NotifyExpired(ctx context.Context, event model.ExpiredStockEvent) error {
+	n.logger.Warn("庫存已過期，標記為不可用",
+		zap.Int64("product_id", event.ProductID),
+		zap.String("sku_code", event.SkuCode),
+		zap.Int64("lot_id", event.LotID),
+		zap.String("lot_number", event.LotNumber),
+		zap.String("expiration", event.Expiration.String()),
+		zap.Int("quantity", event.Quantity),
+		zap.String("tenant_id", event.TenantID),
+	)
+	return nil
+}
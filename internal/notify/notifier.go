@@ -0,0 +1,41 @@
+// Package notify 負責將低庫存與過期等庫存事件送到日誌、Webhook 或電子郵件等通知管道
+package notify
+
+import (
+	"context"
+	"errors"
+	model "main/internal/models"
+)
+
+// Notifier 定義庫存事件的通知管道
+type Notifier interface {
+	NotifyLowStock(ctx context.Context, event model.LowStockEvent) error
+	NotifyExpired(ctx context.Context, event model.ExpiredStockEvent) error
+}
+
+// multiNotifier 依序通知多個管道
+type multiNotifier []Notifier
+
+// Multi 將多個管道組合為一個，每個管道都會被通知，返回所有管道的錯誤
+func Multi(notifiers ...Notifier) Notifier {
+	return multiNotifier(notifiers)
+}
+
+func (m multiNotifier) NotifyLowStock(ctx context.Context, event model.LowStockEvent) error {
+	return m.each(func(n Notifier) error { return n.NotifyLowStock(ctx, event) })
+}
+
+func (m multiNotifier) NotifyExpired(ctx context.Context, event model.ExpiredStockEvent) error {
+	return m.each(func(n Notifier) error { return n.NotifyExpired(ctx, event) })
+}
+
+// each 對每個管道執行 notify，合併所有錯誤
+func (m multiNotifier) each(notify func(n Notifier) error) error {
+	var errs []error
+	for _, n := range m {
+		if err := notify(n); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
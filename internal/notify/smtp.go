@@ -0,0 +1,142 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	model "main/internal/models"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTPNotifier 以電子郵件送出庫存事件
+type SMTPNotifier struct {
+	host string
+	addr string
+	auth smtp.Auth
+	from string
+	to   []string
+}
+
+// NewSMTPNotifier 創建電子郵件通知管道，username 為空時不進行驗證；
+// 伺服器支援 STARTTLS 時會先升級為加密連接
+func NewSMTPNotifier(host string, port int, username, password, from string, to []string) *SMTPNotifier {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &SMTPNotifier{
+		host: host,
+		addr: net.JoinHostPort(host, strconv.Itoa(port)),
+		auth: auth,
+		from: from,
+		to:   to,
+	}
+}
+
+// NotifyLowStock 寄出一封低庫存通知郵件
+func (n *SMTPNotifier) NotifyLowStock(ctx context.Context, event model.LowStockEvent) error {
+	subject := fmt.Sprintf("低庫存提醒：%s 庫存 %d，低於補貨點 %d", event.SkuCode, event.SkuAmount, event.ReorderPoint)
+
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "產品 ID：%d\r\n", event.ProductID)
+	fmt.Fprintf(&body, "產品編號：%s\r\n", event.SkuCode)
+	fmt.Fprintf(&body, "產品名稱：%s\r\n", event.SkuName)
+	fmt.Fprintf(&body, "目前庫存：%d\r\n", event.SkuAmount)
+	fmt.Fprintf(&body, "補貨點：%d\r\n", event.ReorderPoint)
+	fmt.Fprintf(&body, "建議補貨量：%d\r\n", event.ReorderQuantity)
+
+	return n.send(ctx, n.message(subject, event.OccurredAt, body.Bytes()))
+}
+
+// NotifyExpired 寄出一封過期通知郵件，產品本身過期時沒有批號
+func (n *SMTPNotifier) NotifyExpired(ctx context.Context, event model.ExpiredStockEvent) error {
+	subject := fmt.Sprintf("過期提醒：%s 已於 %s 到期", event.SkuCode, event.Expiration)
+
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "產品 ID：%d\r\n", event.ProductID)
+	fmt.Fprintf(&body, "產品編號：%s\r\n", event.SkuCode)
+	fmt.Fprintf(&body, "產品名稱：%s\r\n", event.SkuName)
+	if event.LotNumber != "" {
+		fmt.Fprintf(&body, "批號：%s\r\n", event.LotNumber)
+	}
+	fmt.Fprintf(&body, "到期日：%s\r\n", event.Expiration)
+	fmt.Fprintf(&body, "數量：%d\r\n", event.Quantity)
+
+	return n.send(ctx, n.message(subject, event.OccurredAt, body.Bytes()))
+}
+
+// send 寄出一封郵件，ctx 的截止時間同時限制整個 SMTP 對話
+func (n *SMTPNotifier) send(ctx context.Context, message []byte) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", n.addr)
+	if err != nil {
+		return fmt.Errorf("連接 SMTP 伺服器失敗: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("連接 SMTP 伺服器失敗: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: n.host}); err != nil {
+			return fmt.Errorf("SMTP STARTTLS 失敗: %w", err)
+		}
+	}
+	if n.auth != nil {
+		if err := client.Auth(n.auth); err != nil {
+			return fmt.Errorf("SMTP 驗證失敗: %w", err)
+		}
+	}
+
+	if err := client.Mail(n.from); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM 失敗: %w", err)
+	}
+	for _, to := range n.to {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("SMTP RCPT TO %s 失敗: %w", to, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA 失敗: %w", err)
+	}
+	if _, err := w.Write(message); err != nil {
+		w.Close()
+		return fmt.Errorf("寫入郵件內容失敗: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("寫入郵件內容失敗: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// message 組成郵件的標頭與內文，主旨以 RFC 2047 編碼以支援中文
+func (n *SMTPNotifier) message(subject string, date time.Time, body []byte) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", n.from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(n.to, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	buf.Write(body)
+
+	return buf.Bytes()
+}
@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	model "main/internal/models"
+	"net/http"
+)
+
+// WebhookNotifier 以 JSON POST 將庫存事件送到指定的 URL
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// webhookPayload Webhook 的請求主體，type 讓接收端區分事件種類
+type webhookPayload struct {
+	Type  string `json:"type"`
+	Event any    `json:"event"`
+}
+
+// NewWebhookNotifier 創建 Webhook 通知管道，client 為 nil 時使用 http.DefaultClient
+func NewWebhookNotifier(url string, client *http.Client) *WebhookNotifier {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &WebhookNotifier{url: url, client: client}
+}
+
+// NotifyLowStock 送出 low_stock 事件
+func (n *WebhookNotifier) NotifyLowStock(ctx context.Context, event model.LowStockEvent) error {
+	return n.post(ctx, webhookPayload{Type: "low_stock", Event: event})
+}
+
+// NotifyExpired 送出 expired_stock 事件
+func (n *WebhookNotifier) NotifyExpired(ctx context.Context, event model.ExpiredStockEvent) error {
+	return n.post(ctx, webhookPayload{Type: "expired_stock", Event: event})
+}
+
+// post 送出請求主體，接收端返回非 2xx 狀態碼時視為失敗
+func (n *WebhookNotifier) post(ctx context.Context, payload webhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("送出 webhook 失敗: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook 返回狀態碼 %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package pagination
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// 錯誤定義
+var (
+	ErrInvalidCursor = errors.New("無效的分頁游標")
+)
+
+// Cursor 鍵集分頁游標，記錄上一頁邊界那一筆的排序鍵
+type Cursor struct {
+	SortBy   string `json:"s"`
+	SortDir  string `json:"d"`
+	Value    string `json:"v,omitempty"` // 排序欄位的值，依 id 排序時為空
+	ID       int    `json:"i"`
+	Backward bool   `json:"b,omitempty"` // 是否往前翻頁
+}
+
+// CursorSigner 使用 HMAC-SHA256 簽名游標，防止客戶端竄改
+type CursorSigner struct {
+	secret []byte
+}
+
+// NewCursorSigner 創建游標簽名器，未提供密鑰時使用隨機密鑰（重啟後舊游標失效）
+func NewCursorSigner(secret string) *CursorSigner {
+	if secret == "" {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			panic("無法生成游標密鑰: " + err.Error())
+		}
+		return &CursorSigner{secret: key}
+	}
+	return &CursorSigner{secret: []byte(secret)}
+}
+
+// Encode 將游標編碼為不透明的字串：base64(payload).base64(signature)
+func (s *CursorSigner) Encode(cursor Cursor) string {
+	payload, _ := json.Marshal(cursor)
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(s.sign(encoded))
+}
+
+// Decode 驗證簽名並解碼游標
+func (s *CursorSigner) Decode(token string) (Cursor, error) {
+	encoded, signature, found := strings.Cut(token, ".")
+	if !found {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, s.sign(encoded)) {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(payload, &cursor); err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
+
+// sign 計算簽名
+func (s *CursorSigner) sign(data string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,194 @@
+package productctl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	model "main/internal/models"
+	"main/internal/tenant"
+)
+
+// runAPIKeys 執行 apikeys 子命令
+func (a *App) runAPIKeys(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("請指定 apikeys create、list、rotate 或 revoke")
+	}
+
+	switch args[0] {
+	case "create":
+		return a.createAPIKey(ctx, args[1:])
+	case "list":
+		return a.listAPIKeys(ctx, args[1:])
+	case "rotate":
+		return a.rotateAPIKey(ctx, args[1:])
+	case "revoke":
+		return a.revokeAPIKey(ctx, args[1:])
+	default:
+		return fmt.Errorf("未知的 apikeys 子命令: %s", args[0])
+	}
+}
+
+// createAPIKey 創建 API 金鑰並印出只顯示一次的明文
+func (a *App) createAPIKey(ctx context.Context, args []string) error {
+	fs := a.flagSet("apikeys create")
+	name := fs.String("name", "", "金鑰名稱")
+	scopes := fs.String("scopes", "", "範圍，多個以逗號分隔，例如 product:read,stock:adjust")
+	expiresIn := fs.Duration("expires-in", 0, "有效期，例如 720h，0 表示不過期")
+	keyTenant := fs.String("tenant", "", "金鑰所屬的租戶，為空時為 PRODUCTCTL_TENANT 的租戶")
+	asJSON := fs.Bool("json", false, "以 JSON 輸出")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	req := model.CreateAPIKeyRequest{Name: *name, Scopes: []string{}, Tenant: *keyTenant}
+	for _, scope := range strings.Split(*scopes, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			req.Scopes = append(req.Scopes, scope)
+		}
+	}
+	if *expiresIn > 0 {
+		req.ExpiresAt = model.Timestamp{Time: time.Now().Add(*expiresIn)}
+	}
+	// 命令列可以為任何租戶創建金鑰，-tenant 取代 PRODUCTCTL_TENANT
+	if *keyTenant != "" && tenant.Valid(*keyTenant) {
+		ctx = tenant.WithID(ctx, *keyTenant)
+	}
+
+	backend, _, err := a.open()
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	created, err := backend.APIKeys.CreateAPIKey(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	return a.printCreatedAPIKey(created, *asJSON)
+}
+
+// listAPIKeys 列出租戶的所有 API 金鑰
+func (a *App) listAPIKeys(ctx context.Context, args []string) error {
+	fs := a.flagSet("apikeys list")
+	asJSON := fs.Bool("json", false, "以 JSON 輸出")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	backend, _, err := a.open()
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	keys, err := backend.APIKeys.ListAPIKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return a.printJSON(keys)
+	}
+
+	now := time.Now()
+	w := tabwriter.NewWriter(a.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tPREFIX\tTENANT\tSCOPES\tSTATUS\tEXPIRES AT\tLAST USED AT")
+	for _, k := range keys {
+		status := "active"
+		switch {
+		case !k.RevokedAt.IsZero():
+			status = "revoked"
+		case k.Expired(now):
+			status = "expired"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			k.ID, k.Name, k.Prefix, k.Tenant, strings.Join(k.Scopes, ","), status, k.ExpiresAt, k.LastUsedAt)
+	}
+	return w.Flush()
+}
+
+// rotateAPIKey 為金鑰生成新的明文，舊的明文立即失效
+func (a *App) rotateAPIKey(ctx context.Context, args []string) error {
+	fs := a.flagSet("apikeys rotate")
+	asJSON := fs.Bool("json", false, "以 JSON 輸出")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	id, err := parseAPIKeyIDArg(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	backend, _, err := a.open()
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	rotated, err := backend.APIKeys.RotateAPIKey(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return a.printCreatedAPIKey(rotated, *asJSON)
+}
+
+// revokeAPIKey 撤銷 API 金鑰
+func (a *App) revokeAPIKey(ctx context.Context, args []string) error {
+	id, err := parseAPIKeyIDArg(args)
+	if err != nil {
+		return err
+	}
+
+	backend, _, err := a.open()
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	key, err := backend.APIKeys.RevokeAPIKey(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.Stdout, "API 金鑰 %d (%s) 已撤銷\n", key.ID, key.Name)
+	return nil
+}
+
+// printCreatedAPIKey 印出剛創建或輪換的金鑰，並提醒明文只顯示這一次
+func (a *App) printCreatedAPIKey(created model.CreatedAPIKey, asJSON bool) error {
+	if asJSON {
+		return a.printJSON(created)
+	}
+
+	fmt.Fprintf(a.Stdout, "API 金鑰 %d (%s)\n", created.ID, created.Name)
+	fmt.Fprintf(a.Stdout, "  租戶: %s\n", created.Tenant)
+	fmt.Fprintf(a.Stdout, "  範圍: %s\n", strings.Join(created.Scopes, ","))
+	if !created.ExpiresAt.IsZero() {
+		fmt.Fprintf(a.Stdout, "  到期: %s\n", created.ExpiresAt)
+	}
+	fmt.Fprintf(a.Stdout, "  金鑰: %s\n\n", created.Key)
+	fmt.Fprintln(a.Stderr, "請立即保存金鑰，之後無法再次查看")
+	return nil
+}
+
+// parseAPIKeyIDArg 解析唯一的 API 金鑰 ID 參數
+func parseAPIKeyIDArg(args []string) (int64, error) {
+	if len(args) != 1 {
+		return 0, errors.New("請提供一個 API 金鑰 ID")
+	}
+
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("無效的 API 金鑰ID: %s", args[0])
+	}
+
+	return id, nil
+}
@@ -0,0 +1,211 @@
+package productctl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jmoiron/sqlx"
+
+	"main/internal/audit"
+	"main/internal/auth"
+	"main/internal/config"
+	"main/internal/repository"
+	"main/internal/service"
+	"main/internal/tenant"
+	"main/pkg/database"
+)
+
+// 退出碼
+const (
+	ExitOK    = 0 // 成功
+	ExitError = 1 // 命令執行失敗
+	ExitUsage = 2 // 未知的命令或無效的 PRODUCTCTL_TENANT
+)
+
+// TenantEnv 指定命令作用租戶的環境變數
+const TenantEnv = "PRODUCTCTL_TENANT"
+
+const usageText = `productctl - 產品服務管理工具
+
+用法:
+  productctl <命令> [參數]
+
+命令:
+  migrate up                 套用所有尚未執行的遷移
+  migrate down [-steps N]    回滾最近 N 個遷移（默認 1）
+  migrate status             顯示遷移狀態
+  seed [-force]              寫入示範產品數據
+  products list [參數]       列出產品（-page-size, -offset, -sort-by, -sort-dir, -sku-code, -sku-name, -json）
+  products get <id>          顯示單個產品
+  products create [參數]     創建產品（-sku-code, -sku-name, -amount, -expiration）
+  products delete <id>       將產品移到回收站
+  trash list [參數]          列出回收站中的產品（-page-size, -offset, -json）
+  trash restore <id>         還原回收站中的產品
+  trash purge <id>           永久刪除回收站中的產品
+  trash purge -older-than-days N  永久刪除在回收站中超過 N 天的產品
+  import [-dry-run] <檔案>   從 CSV 或 JSON 檔案批量導入產品
+  apikeys create [參數]      創建 API 金鑰並顯示只出現一次的明文（-name, -scopes, -expires-in, -tenant, -json）
+  apikeys list [-json]       列出租戶的 API 金鑰
+  apikeys rotate <id>        為 API 金鑰生成新的明文，舊的明文立即失效
+  apikeys revoke <id>        撤銷 API 金鑰
+  config check               檢查配置與資料庫連線
+
+配置與服務相同：讀取 CONFIG_FILE 或默認路徑的配置文件，再以環境變數覆蓋。
+產品、回收站與 API 金鑰的命令作用於 PRODUCTCTL_TENANT 指定的租戶，未設置時為預設租戶。
+`
+
+// Migrator 遷移命令使用的遷移執行器
+type Migrator interface {
+	Up() ([]database.Migration, error)
+	Down(steps int) ([]database.Migration, error)
+	Status() ([]database.MigrationState, error)
+	// SetRowLevelSecurity 啟用或停用租戶表的資料列安全
+	SetRowLevelSecurity(ctx context.Context, enabled bool) error
+}
+
+// Backend 命令使用的服務與遷移執行器，共用同一個資料庫連線
+type Backend struct {
+	Products service.ProductService
+	APIKeys  service.APIKeyService
+	Migrator Migrator
+	Close    func() error
+}
+
+// App productctl 的命令執行環境；測試以假的配置與服務取代資料庫
+type App struct {
+	Stdout io.Writer
+	Stderr io.Writer
+	Getenv func(string) string
+	// LoadConfig 加載配置
+	LoadConfig func() (*config.AppConfig, error)
+	// Connect 以配置連接資料庫並創建命令使用的服務
+	Connect func(appConfig *config.AppConfig) (*Backend, error)
+}
+
+// NewApp 創建以標準輸出、環境變數與 PostgreSQL 執行的 productctl
+func NewApp() *App {
+	return &App{
+		Stdout:     os.Stdout,
+		Stderr:     os.Stderr,
+		Getenv:     os.Getenv,
+		LoadConfig: config.LoadConfig,
+		Connect:    connectPostgres,
+	}
+}
+
+// Run 執行 args 指定的命令並返回退出碼
+func (a *App) Run(ctx context.Context, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(a.Stderr, usageText)
+		return ExitUsage
+	}
+
+	command, args := args[0], args[1:]
+
+	// 經由命令列的變更在產品歷史中記錄為 productctl，並以系統主體通過服務層的權限檢查
+	ctx = audit.WithActor(ctx, "productctl")
+	ctx = auth.WithPrincipal(ctx, auth.System)
+
+	if id := a.Getenv(TenantEnv); id != "" {
+		if !tenant.Valid(id) {
+			fmt.Fprintf(a.Stderr, "錯誤: 無效的 %s: %q\n", TenantEnv, id)
+			return ExitUsage
+		}
+		ctx = tenant.WithID(ctx, id)
+	}
+
+	var err error
+	switch command {
+	case "migrate":
+		err = a.runMigrate(args)
+	case "seed":
+		err = a.runSeed(ctx, args)
+	case "products":
+		err = a.runProducts(ctx, args)
+	case "trash":
+		err = a.runTrash(ctx, args)
+	case "import":
+		err = a.runImport(ctx, args)
+	case "apikeys":
+		err = a.runAPIKeys(ctx, args)
+	case "config":
+		err = a.runConfig(args)
+	case "help", "-h", "--help":
+		fmt.Fprint(a.Stdout, usageText)
+		return ExitOK
+	default:
+		fmt.Fprintf(a.Stderr, "未知的命令: %s\n\n%s", command, usageText)
+		return ExitUsage
+	}
+
+	if err != nil {
+		fmt.Fprintf(a.Stderr, "錯誤: %v\n", err)
+		return ExitError
+	}
+	return ExitOK
+}
+
+// open 加載配置並連接資料庫
+func (a *App) open() (*Backend, *config.AppConfig, error) {
+	appConfig, err := a.LoadConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("無法加載配置: %w", err)
+	}
+
+	backend, err := a.Connect(appConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return backend, appConfig, nil
+}
+
+// flagSet 創建子命令的參數解析器，錯誤與用法輸出到 Stderr
+func (a *App) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.Stderr)
+	return fs
+}
+
+// printJSON 以縮排 JSON 輸出
+func (a *App) printJSON(v interface{}) error {
+	encoder := json.NewEncoder(a.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// postgresMigrator 以 PostgreSQL 連線執行遷移與資料列安全的設定
+type postgresMigrator struct {
+	*database.Migrator
+	db *sqlx.DB
+}
+
+func (m *postgresMigrator) SetRowLevelSecurity(ctx context.Context, enabled bool) error {
+	return database.SetRowLevelSecurity(ctx, m.db, enabled)
+}
+
+// connectPostgres 連接資料庫並創建服務，資料列安全的設定與服務相同
+func connectPostgres(appConfig *config.AppConfig) (*Backend, error) {
+	db, err := database.NewPostgresDB(&appConfig.Database)
+	if err != nil {
+		return nil, err
+	}
+
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		return nil, errors.Join(err, db.Close())
+	}
+
+	return &Backend{
+		Products: service.NewProductService(repository.NewProductRepository(db,
+			repository.WithRowLevelSecurity(appConfig.Database.RowLevelSecurity))),
+		APIKeys:  service.NewAPIKeyService(repository.NewAPIKeyRepository(db)),
+		Migrator: &postgresMigrator{Migrator: migrator, db: db},
+		Close:    db.Close,
+	}, nil
+}
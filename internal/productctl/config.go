@@ -0,0 +1,55 @@
+package productctl
+
+import (
+	"errors"
+	"fmt"
+)
+
+// runConfig 執行 config 子命令
+func (a *App) runConfig(args []string) error {
+	if len(args) == 0 || args[0] != "check" {
+		return errors.New("請指定 config check")
+	}
+
+	appConfig, err := a.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("無法加載配置: %w", err)
+	}
+
+	fmt.Fprintln(a.Stdout, "配置:")
+	fmt.Fprintf(a.Stdout, "  服務器: 端口=%d, 模式=%s\n", appConfig.Server.Port, appConfig.Server.Mode)
+	fmt.Fprintf(a.Stdout, "  數據庫: %s@%s:%d/%s (sslmode=%s, 自動遷移=%v)\n",
+		appConfig.Database.User, appConfig.Database.Host, appConfig.Database.Port,
+		appConfig.Database.DBName, appConfig.Database.SSLMode, appConfig.Database.AutoMigrate)
+	fmt.Fprintf(a.Stdout, "  日誌: 級別=%s, 格式=%s, 輸出=%s\n",
+		appConfig.Logger.Level, appConfig.Logger.Format, appConfig.Logger.OutputPaths)
+	if appConfig.Pagination.CursorSecret == "" {
+		fmt.Fprintln(a.Stdout, "  警告: 未設置 CURSOR_SECRET，分頁游標在重啟後失效")
+	}
+
+	backend, err := a.Connect(appConfig)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+	fmt.Fprintln(a.Stdout, "資料庫連線: 正常")
+
+	states, err := backend.Migrator.Status()
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+	for _, state := range states {
+		if !state.Applied {
+			pending++
+		}
+	}
+	if pending > 0 {
+		fmt.Fprintf(a.Stdout, "遷移: %d 個尚未套用（執行 productctl migrate up）\n", pending)
+	} else {
+		fmt.Fprintln(a.Stdout, "遷移: 已是最新版本")
+	}
+
+	return nil
+}
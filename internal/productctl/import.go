@@ -0,0 +1,102 @@
+package productctl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	model "main/internal/models"
+	"main/internal/productio"
+)
+
+// runImport 執行 import 子命令，從 CSV、XLSX 或 JSON 檔案依 sku_code 更新或創建產品
+func (a *App) runImport(ctx context.Context, args []string) error {
+	fs := a.flagSet("import")
+	dryRun := fs.Bool("dry-run", false, "只驗證，不寫入資料庫")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("請提供一個要導入的檔案")
+	}
+
+	path := fs.Arg(0)
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("無法打開檔案: %w", err)
+	}
+	defer file.Close()
+
+	var rows []model.ImportRow
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		rows, err = productio.ReadRows(file, productio.FormatCSV, nil)
+	case ".xlsx":
+		rows, err = productio.ReadRows(file, productio.FormatXLSX, nil)
+	case ".json":
+		rows, err = readJSONRows(file)
+	default:
+		return fmt.Errorf("不支援的檔案格式: %s（僅支援 .csv、.xlsx 與 .json）", filepath.Ext(path))
+	}
+	if err != nil {
+		return err
+	}
+
+	// dry-run 只在本地驗證，不需要連接資料庫
+	if *dryRun {
+		invalid := 0
+		for _, row := range rows {
+			if row.Err == nil {
+				row.Err = model.ValidateProduct(row.Product)
+			}
+			if row.Err != nil {
+				invalid++
+				fmt.Fprintf(a.Stderr, "第 %d 行: %v\n", row.Line, row.Err)
+			}
+		}
+		fmt.Fprintf(a.Stdout, "驗證完成: 共 %d 行，有效 %d 行，無效 %d 行（dry-run，未寫入）\n", len(rows), len(rows)-invalid, invalid)
+		return nil
+	}
+
+	backend, _, err := a.open()
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	report, err := backend.Products.ImportProducts(ctx, rows, false)
+	if err != nil {
+		return err
+	}
+
+	for _, rowErr := range report.Errors {
+		fmt.Fprintf(a.Stderr, "第 %d 行: %s\n", rowErr.Line, rowErr.Error)
+	}
+
+	fmt.Fprintf(a.Stdout, "導入完成: 共 %d 行，新增 %d 行，更新 %d 行，失敗 %d 行\n", report.Total, report.Created, report.Updated, report.Failed)
+	if report.Failed > 0 {
+		return fmt.Errorf("%d 行導入失敗", report.Failed)
+	}
+
+	return nil
+}
+
+// readJSONRows 讀取產品 JSON 陣列
+func readJSONRows(r io.Reader) ([]model.ImportRow, error) {
+	var products []model.Product
+	if err := json.NewDecoder(r).Decode(&products); err != nil {
+		return nil, fmt.Errorf("解析 JSON 失敗: %w", err)
+	}
+
+	rows := make([]model.ImportRow, 0, len(products))
+	for i, p := range products {
+		rows = append(rows, model.ImportRow{Line: i + 1, Product: p})
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,93 @@
+package productctl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"text/tabwriter"
+)
+
+// runMigrate 執行 migrate 子命令
+func (a *App) runMigrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New("請指定 migrate up、down 或 status")
+	}
+
+	switch args[0] {
+	case "up":
+		backend, appConfig, err := a.open()
+		if err != nil {
+			return err
+		}
+		defer backend.Close()
+
+		applied, err := backend.Migrator.Up()
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Fprintln(a.Stdout, "資料庫已是最新版本")
+		}
+		for _, m := range applied {
+			fmt.Fprintf(a.Stdout, "已套用 %04d_%s\n", m.Version, m.Name)
+		}
+		// 與服務啟動時的自動遷移相同，依配置啟用或停用資料列安全
+		return backend.Migrator.SetRowLevelSecurity(context.Background(), appConfig.Database.RowLevelSecurity)
+
+	case "down":
+		fs := a.flagSet("migrate down")
+		steps := fs.Int("steps", 1, "回滾的遷移數量")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *steps <= 0 {
+			return errors.New("steps 必須為正整數")
+		}
+
+		backend, _, err := a.open()
+		if err != nil {
+			return err
+		}
+		defer backend.Close()
+
+		rolledBack, err := backend.Migrator.Down(*steps)
+		if err != nil {
+			return err
+		}
+		if len(rolledBack) == 0 {
+			fmt.Fprintln(a.Stdout, "沒有可回滾的遷移")
+			return nil
+		}
+		for _, m := range rolledBack {
+			fmt.Fprintf(a.Stdout, "已回滾 %04d_%s\n", m.Version, m.Name)
+		}
+		return nil
+
+	case "status":
+		backend, _, err := a.open()
+		if err != nil {
+			return err
+		}
+		defer backend.Close()
+
+		states, err := backend.Migrator.Status()
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(a.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, state := range states {
+			status, appliedAt := "pending", ""
+			if state.Applied {
+				status = "applied"
+				appliedAt = state.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", state.Version, state.Name, status, appliedAt)
+		}
+		return w.Flush()
+
+	default:
+		return fmt.Errorf("未知的 migrate 子命令: %s", args[0])
+	}
+}
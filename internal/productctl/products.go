@@ -0,0 +1,185 @@
+package productctl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"text/tabwriter"
+
+	model "main/internal/models"
+)
+
+// runProducts 執行 products 子命令
+func (a *App) runProducts(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("請指定 products list、get、create 或 delete")
+	}
+
+	switch args[0] {
+	case "list":
+		return a.listProducts(ctx, args[1:])
+	case "get":
+		return a.getProduct(ctx, args[1:])
+	case "create":
+		return a.createProduct(ctx, args[1:])
+	case "delete":
+		return a.deleteProduct(ctx, args[1:])
+	default:
+		return fmt.Errorf("未知的 products 子命令: %s", args[0])
+	}
+}
+
+// listProducts 列出產品
+func (a *App) listProducts(ctx context.Context, args []string) error {
+	fs := a.flagSet("products list")
+	pageSize := fs.Int("page-size", model.DefaultPageSize, "每頁筆數")
+	offset := fs.Int("offset", 0, "起始位移")
+	sortBy := fs.String("sort-by", "id", "排序欄位")
+	sortDir := fs.String("sort-dir", model.SortAsc, "排序方向 asc 或 desc")
+	skuCode := fs.String("sku-code", "", "SKU 代碼前綴")
+	skuName := fs.String("sku-name", "", "名稱模糊搜尋")
+	asJSON := fs.Bool("json", false, "以 JSON 輸出")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if !model.IsSortableProductField(*sortBy) {
+		return fmt.Errorf("不支援的排序欄位: %s", *sortBy)
+	}
+	if *sortDir != model.SortAsc && *sortDir != model.SortDesc {
+		return errors.New("sort-dir 只能為 asc 或 desc")
+	}
+
+	backend, _, err := a.open()
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	page, err := backend.Products.GetProducts(ctx, model.ProductQuery{
+		PageSize: *pageSize,
+		Offset:   *offset,
+		SortBy:   *sortBy,
+		SortDir:  *sortDir,
+		SkuCode:  *skuCode,
+		SkuName:  *skuName,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return a.printJSON(page)
+	}
+
+	w := tabwriter.NewWriter(a.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSKU CODE\tSKU NAME\tAMOUNT\tEXPIRATION")
+	for _, p := range page.Items {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\n", p.ID, p.SkuCode, p.SkuName, p.SkuAmount, p.Expiration)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.Stdout, "\n共 %d 筆，顯示 %d-%d\n", page.Total, page.Offset+1, page.Offset+len(page.Items))
+
+	return nil
+}
+
+// getProduct 顯示單個產品
+func (a *App) getProduct(ctx context.Context, args []string) error {
+	id, err := parseIDArg(args)
+	if err != nil {
+		return err
+	}
+
+	backend, _, err := a.open()
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	product, err := backend.Products.GetProduct(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return a.printJSON(product)
+}
+
+// createProduct 創建產品
+func (a *App) createProduct(ctx context.Context, args []string) error {
+	fs := a.flagSet("products create")
+	skuCode := fs.String("sku-code", "", "SKU 代碼")
+	skuName := fs.String("sku-name", "", "產品名稱")
+	amount := fs.Int("amount", 0, "庫存數量")
+	expiration := fs.String("expiration", "", "到期日 YYYY-MM-DD")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	input := model.Product{
+		SkuCode:   *skuCode,
+		SkuName:   *skuName,
+		SkuAmount: *amount,
+	}
+	if *expiration != "" {
+		date, err := model.ParseDate(*expiration)
+		if err != nil {
+			return err
+		}
+		input.Expiration = date
+	}
+	if err := model.ValidateNewProduct(input, model.Today()); err != nil {
+		return err
+	}
+
+	backend, _, err := a.open()
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	product, err := backend.Products.CreateProduct(ctx, input)
+	if err != nil {
+		return err
+	}
+
+	return a.printJSON(product)
+}
+
+// deleteProduct 將產品移到回收站
+func (a *App) deleteProduct(ctx context.Context, args []string) error {
+	id, err := parseIDArg(args)
+	if err != nil {
+		return err
+	}
+
+	backend, _, err := a.open()
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	// 管理工具直接刪除，不做版本檢查
+	if err := backend.Products.DeleteProduct(ctx, id, 0); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.Stdout, "產品 %d 已移到回收站\n", id)
+	return nil
+}
+
+// parseIDArg 解析位置參數中的產品 ID
+func parseIDArg(args []string) (int64, error) {
+	if len(args) != 1 {
+		return 0, errors.New("請提供一個產品 ID")
+	}
+
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("無效的產品ID: %s", args[0])
+	}
+
+	return id, nil
+}
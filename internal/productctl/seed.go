@@ -0,0 +1,57 @@
+package productctl
+
+import (
+	"context"
+	"fmt"
+
+	model "main/internal/models"
+)
+
+// demoProducts 示範產品數據，到期日相對於今天，避免示範數據一寫入就已過期
+func demoProducts(today model.Date) []model.Product {
+	after := func(months int) model.Date {
+		return model.DateOf(today.AddDate(0, months, 0))
+	}
+
+	return []model.Product{
+		{SkuCode: "SKU001", SkuName: "測試產品1", SkuAmount: 100, Expiration: after(6)},
+		{SkuCode: "SKU002", SkuName: "測試產品2", SkuAmount: 50, Expiration: after(1)},
+		{SkuCode: "SKU003", SkuName: "測試產品3", SkuAmount: 200, Expiration: after(12)},
+	}
+}
+
+// runSeed 執行 seed 子命令，默認只在產品表為空時寫入
+func (a *App) runSeed(ctx context.Context, args []string) error {
+	fs := a.flagSet("seed")
+	force := fs.Bool("force", false, "產品表不為空時仍然寫入")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	backend, _, err := a.open()
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	if !*force {
+		page, err := backend.Products.GetProducts(ctx, model.ProductQuery{PageSize: 1})
+		if err != nil {
+			return err
+		}
+		if page.Total > 0 {
+			fmt.Fprintf(a.Stdout, "產品表已有 %d 筆數據，略過（使用 -force 強制寫入）\n", page.Total)
+			return nil
+		}
+	}
+
+	for _, p := range demoProducts(model.Today()) {
+		created, err := backend.Products.CreateProduct(ctx, p)
+		if err != nil {
+			return fmt.Errorf("寫入 %s 失敗: %w", p.SkuCode, err)
+		}
+		fmt.Fprintf(a.Stdout, "已創建產品 %d (%s)\n", created.ID, created.SkuCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package productctl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	model "main/internal/models"
+)
+
+// runTrash 執行 trash 子命令
+func (a *App) runTrash(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("請指定 trash list、restore 或 purge")
+	}
+
+	switch args[0] {
+	case "list":
+		return a.listTrash(ctx, args[1:])
+	case "restore":
+		return a.restoreProduct(ctx, args[1:])
+	case "purge":
+		return a.purgeTrash(ctx, args[1:])
+	default:
+		return fmt.Errorf("未知的 trash 子命令: %s", args[0])
+	}
+}
+
+// listTrash 列出回收站中的產品
+func (a *App) listTrash(ctx context.Context, args []string) error {
+	fs := a.flagSet("trash list")
+	pageSize := fs.Int("page-size", model.DefaultPageSize, "每頁筆數")
+	offset := fs.Int("offset", 0, "起始位移")
+	asJSON := fs.Bool("json", false, "以 JSON 輸出")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	backend, _, err := a.open()
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	page, err := backend.Products.GetDeletedProducts(ctx, model.ProductQuery{PageSize: *pageSize, Offset: *offset})
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return a.printJSON(page)
+	}
+
+	w := tabwriter.NewWriter(a.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSKU CODE\tSKU NAME\tAMOUNT\tDELETED AT")
+	for _, p := range page.Items {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\n", p.ID, p.SkuCode, p.SkuName, p.SkuAmount, p.DeletedAt)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.Stdout, "\n共 %d 筆，顯示 %d-%d\n", page.Total, page.Offset+1, page.Offset+len(page.Items))
+
+	return nil
+}
+
+// restoreProduct 還原回收站中的產品
+func (a *App) restoreProduct(ctx context.Context, args []string) error {
+	id, err := parseIDArg(args)
+	if err != nil {
+		return err
+	}
+
+	backend, _, err := a.open()
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	product, err := backend.Products.RestoreProduct(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.Stdout, "產品 %d (%s) 已還原\n", product.ID, product.SkuCode)
+	return nil
+}
+
+// purgeTrash 永久刪除回收站中的單個產品，或以 -older-than-days 刪除超過保留期的所有產品
+func (a *App) purgeTrash(ctx context.Context, args []string) error {
+	fs := a.flagSet("trash purge")
+	olderThanDays := fs.Int("older-than-days", 0, "永久刪除在回收站中超過指定天數的產品")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *olderThanDays <= 0 && fs.NArg() == 0 {
+		return errors.New("請提供產品 ID 或 -older-than-days")
+	}
+	if *olderThanDays > 0 && fs.NArg() > 0 {
+		return errors.New("產品 ID 與 -older-than-days 不能同時使用")
+	}
+
+	backend, _, err := a.open()
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	if *olderThanDays > 0 {
+		purged, err := backend.Products.PurgeDeletedProducts(ctx, time.Duration(*olderThanDays)*24*time.Hour)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(a.Stdout, "已永久刪除 %d 個產品\n", purged)
+		return nil
+	}
+
+	id, err := parseIDArg(fs.Args())
+	if err != nil {
+		return err
+	}
+	if err := backend.Products.PurgeProduct(ctx, id); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.Stdout, "產品 %d 已永久刪除\n", id)
+	return nil
+}
@@ -0,0 +1,89 @@
+// Package productio 負責產品在 CSV 與 XLSX 檔案間的讀寫
+package productio
+
+import (
+	"errors"
+	"fmt"
+	model "main/internal/models"
+	"path/filepath"
+	"strings"
+)
+
+// 支援的檔案格式
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// ErrUnsupportedFormat 不支援的檔案格式
+var ErrUnsupportedFormat = errors.New("不支援的檔案格式（僅支援 csv 與 xlsx）")
+
+// ExportColumns 匯出檔案的欄位，依序寫入表頭
+var ExportColumns = []string{"id", "sku_code", "sku_name", "sku_amount", "expiration", "reorder_point", "reorder_quantity", "create_at", "update_at", "version"}
+
+// importFields 匯入時可對應的產品欄位
+var importFields = map[string]bool{
+	"sku_code":   true,
+	"sku_name":   true,
+	"sku_amount": true,
+	"expiration": true,
+
+	"reorder_point":    true,
+	"reorder_quantity": true,
+}
+
+// ParseFormat 驗證並正規化格式名稱
+func ParseFormat(format string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case FormatCSV:
+		return FormatCSV, nil
+	case FormatXLSX:
+		return FormatXLSX, nil
+	default:
+		return "", ErrUnsupportedFormat
+	}
+}
+
+// FormatFromFilename 依副檔名推斷格式
+func FormatFromFilename(name string) (string, error) {
+	return ParseFormat(strings.TrimPrefix(filepath.Ext(name), "."))
+}
+
+// ContentType 返回格式對應的 MIME 類型
+func ContentType(format string) string {
+	if format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// ValidateMapping 驗證表頭對應，對應的目標必須是可匯入的產品欄位
+func ValidateMapping(mapping map[string]string) error {
+	targets := map[string]string{}
+	for header, field := range mapping {
+		if !importFields[field] {
+			return fmt.Errorf("表頭 %q 對應到不支援的欄位: %s", header, field)
+		}
+		if other, ok := targets[field]; ok {
+			return fmt.Errorf("表頭 %q 與 %q 對應到同一個欄位: %s", other, header, field)
+		}
+		targets[field] = header
+	}
+	return nil
+}
+
+// exportRecord 將產品轉為與 ExportColumns 對應的一列
+func exportRecord(p model.Product) []string {
+	return []string{
+		fmt.Sprint(p.ID),
+		p.SkuCode,
+		p.SkuName,
+		fmt.Sprint(p.SkuAmount),
+		p.Expiration.String(),
+		fmt.Sprint(p.ReorderPoint),
+		fmt.Sprint(p.ReorderQuantity),
+		p.CreateAt.String(),
+		p.UpdateAt.String(),
+		fmt.Sprint(p.Version),
+	}
+}
@@ -0,0 +1,213 @@
+package productio
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	model "main/internal/models"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ErrMissingSkuCode 表頭中找不到 sku_code 欄位
+var ErrMissingSkuCode = errors.New("表頭缺少 sku_code 欄位")
+
+// ReadRows 讀取帶表頭的 CSV 或 XLSX（第一個工作表），返回每一列的解析結果
+// mapping 將檔案中的表頭名稱對應到產品欄位，未對應的表頭若與欄位同名則直接使用，其餘欄位忽略；
+// 表頭比對不區分大小寫。單列的解析錯誤記錄在 ImportRow.Err，檔案本身無法讀取時返回錯誤
+func ReadRows(r io.Reader, format string, mapping map[string]string) ([]model.ImportRow, error) {
+	if err := ValidateMapping(mapping); err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case FormatCSV:
+		return readCSV(r, mapping)
+	case FormatXLSX:
+		return readXLSX(r, mapping)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+// columnIndex 表頭欄位到產品欄位的索引
+type columnIndex map[string]int
+
+// newColumnIndex 依表頭與對應關係建立索引
+func newColumnIndex(header []string, mapping map[string]string) (columnIndex, error) {
+	normalized := map[string]string{}
+	for name, field := range mapping {
+		normalized[normalizeHeader(name)] = field
+	}
+
+	columns := columnIndex{}
+	for i, name := range header {
+		name = normalizeHeader(name)
+		field, ok := normalized[name]
+		if !ok {
+			if !importFields[name] {
+				continue
+			}
+			field = name
+		}
+		// 同一欄位出現多次時以第一次為準
+		if _, exists := columns[field]; !exists {
+			columns[field] = i
+		}
+	}
+
+	if _, ok := columns["sku_code"]; !ok {
+		return nil, ErrMissingSkuCode
+	}
+	return columns, nil
+}
+
+// normalizeHeader 去除空白與 UTF-8 BOM 並轉為小寫
+func normalizeHeader(name string) string {
+	return strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+}
+
+// parseRecord 將一列資料轉為產品
+func (columns columnIndex) parseRecord(line int, record []string) model.ImportRow {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	row := model.ImportRow{Line: line}
+	row.Product = model.Product{
+		SkuCode: field("sku_code"),
+		SkuName: field("sku_name"),
+	}
+	// 數值欄位未提供時為 0，依序檢查以便錯誤訊息固定指向第一個無效的欄位
+	numbers := []struct {
+		name   string
+		target *int
+	}{
+		{"sku_amount", &row.Product.SkuAmount},
+		{"reorder_point", &row.Product.ReorderPoint},
+		{"reorder_quantity", &row.Product.ReorderQuantity},
+	}
+	for _, number := range numbers {
+		value := field(number.name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			if row.Err == nil {
+				row.Err = fmt.Errorf("%s 必須為整數: %s", number.name, value)
+			}
+			continue
+		}
+		*number.target = n
+	}
+	if expiration := field("expiration"); expiration != "" {
+		var err error
+		row.Product.Expiration, err = model.ParseDate(expiration)
+		if err != nil && row.Err == nil {
+			row.Err = fmt.Errorf("expiration 格式必須為 YYYY-MM-DD: %s", expiration)
+		}
+	}
+
+	return row
+}
+
+// isBlank 判斷整列是否為空
+func isBlank(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func readCSV(r io.Reader, mapping map[string]string) ([]model.ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("讀取 CSV 表頭失敗: %w", err)
+	}
+
+	columns, err := newColumnIndex(header, mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []model.ImportRow{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("CSV 格式錯誤: %w", err)
+		}
+		if isBlank(record) {
+			continue
+		}
+
+		// 欄位內可能含換行，行號以 CSV 解析器記錄的為準
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, columns.parseRecord(line, record))
+	}
+
+	return rows, nil
+}
+
+func readXLSX(r io.Reader, mapping map[string]string) ([]model.ImportRow, error) {
+	file, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("無法讀取 XLSX 檔案: %w", err)
+	}
+	defer file.Close()
+
+	sheets := file.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, errors.New("XLSX 檔案沒有工作表")
+	}
+
+	iter, err := file.Rows(sheets[0])
+	if err != nil {
+		return nil, fmt.Errorf("無法讀取 XLSX 工作表: %w", err)
+	}
+	defer iter.Close()
+
+	var columns columnIndex
+	rows := []model.ImportRow{}
+	for line := 1; iter.Next(); line++ {
+		record, err := iter.Columns()
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行讀取失敗: %w", line, err)
+		}
+
+		if columns == nil {
+			if columns, err = newColumnIndex(record, mapping); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if isBlank(record) {
+			continue
+		}
+
+		rows = append(rows, columns.parseRecord(line, record))
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("無法讀取 XLSX 工作表: %w", err)
+	}
+	if columns == nil {
+		return nil, errors.New("XLSX 工作表缺少表頭")
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,118 @@
+package productio
+
+import (
+	"encoding/csv"
+	"io"
+	model "main/internal/models"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// csvFlushInterval CSV 每寫入多少列刷新一次，讓資料盡早送出而不累積在緩衝區
+const csvFlushInterval = 500
+
+// xlsxSheet 匯出與匯入預設使用的工作表
+const xlsxSheet = "Sheet1"
+
+// Writer 逐筆寫入產品，Close 時完成檔案
+type Writer interface {
+	Write(product model.Product) error
+	Close() error
+}
+
+// NewWriter 依格式創建寫入器並寫入表頭
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatXLSX:
+		return newXLSXWriter(w)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+type csvWriter struct {
+	w    *csv.Writer
+	rows int
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	cw := &csvWriter{w: csv.NewWriter(w)}
+	if err := cw.w.Write(ExportColumns); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+func (cw *csvWriter) Write(product model.Product) error {
+	if err := cw.w.Write(exportRecord(product)); err != nil {
+		return err
+	}
+
+	cw.rows++
+	if cw.rows%csvFlushInterval == 0 {
+		cw.w.Flush()
+		return cw.w.Error()
+	}
+	return nil
+}
+
+func (cw *csvWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// xlsxWriter 使用串流寫入工作表，資料量大時由 excelize 暫存到磁碟
+type xlsxWriter struct {
+	out    io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	row    int
+}
+
+func newXLSXWriter(w io.Writer) (*xlsxWriter, error) {
+	file := excelize.NewFile()
+	stream, err := file.NewStreamWriter(xlsxSheet)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	xw := &xlsxWriter{out: w, file: file, stream: stream, row: 1}
+
+	header := make([]interface{}, len(ExportColumns))
+	for i, name := range ExportColumns {
+		header[i] = name
+	}
+	if err := xw.writeRow(header); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return xw, nil
+}
+
+func (xw *xlsxWriter) Write(p model.Product) error {
+	// 數值欄位保留數字型別，方便在試算表中計算
+	return xw.writeRow([]interface{}{p.ID, p.SkuCode, p.SkuName, p.SkuAmount, p.Expiration.String(), p.ReorderPoint, p.ReorderQuantity, p.CreateAt.String(), p.UpdateAt.String(), p.Version})
+}
+
+func (xw *xlsxWriter) writeRow(values []interface{}) error {
+	cell, err := excelize.CoordinatesToCellName(1, xw.row)
+	if err != nil {
+		return err
+	}
+	xw.row++
+	return xw.stream.SetRow(cell, values)
+}
+
+// Close 完成工作表並將整個檔案寫出；XLSX 是壓縮檔，只能在最後一次寫出
+func (xw *xlsxWriter) Close() error {
+	defer xw.file.Close()
+
+	if err := xw.stream.Flush(); err != nil {
+		return err
+	}
+	return xw.file.Write(xw.out)
+}
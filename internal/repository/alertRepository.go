@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"main/internal/models"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// AlertRepository 定義低庫存事件寄件匣的儲存庫接口
+type AlertRepository interface {
+	// DeliverLowStockEvents 鎖定所有租戶最早記錄的至多 limit 筆低庫存事件，依記錄順序交給 deliver，
+	// 只刪除送出成功的事件；deliver 失敗時停止，該事件與之後的事件留在寄件匣等待下次派送。
+	// 返回送出的數量，deliver 失敗時同時返回其錯誤；多個實例同時派送時各自鎖定不同的事件
+	DeliverLowStockEvents(ctx context.Context, limit int, deliver func(event models.LowStockEvent) error) (int, error)
+}
+
+type PostgresAlertRepository struct {
+	db tenantDB
+}
+
+func NewAlertRepository(db *sqlx.DB, opts ...Option) AlertRepository {
+	return &PostgresAlertRepository{db: newTenantDB(db, opts)}
+}
+
+// DeliverLowStockEvents 在同一交易中以 SKIP LOCKED 鎖定事件、派送並刪除送出的事件；
+// 交易在派送完成前不會提交，派送途中中止時事件仍留在寄件匣，因此每個事件至少送出一次
+func (r *PostgresAlertRepository) DeliverLowStockEvents(ctx context.Context, limit int, deliver func(event models.LowStockEvent) error) (int, error) {
+	var delivered []int64
+	var deliverErr error
+
+	ctx = allTenants(ctx)
+	err := runInTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		delivered, deliverErr = nil, nil
+
+		events := []models.LowStockEvent{}
+		if err := tx.SelectContext(ctx, &events, `
+			SELECT id, tenant_id, product_id, sku_code, sku_name, sku_amount, reorder_point, reorder_quantity, occurred_at
+			FROM low_stock_events
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		`, limit); err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			if deliverErr = deliver(event); deliverErr != nil {
+				break
+			}
+			delivered = append(delivered, event.ID)
+		}
+		if len(delivered) == 0 {
+			return nil
+		}
+
+		_, err := tx.ExecContext(ctx, `DELETE FROM low_stock_events WHERE id = ANY($1)`, pq.Array(delivered))
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(delivered), deliverErr
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"main/internal/models"
+	"main/internal/tenant"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// API 金鑰的錯誤定義
+var (
+	ErrAPIKeyNotFound  = errors.New("API 金鑰未找到")
+	ErrAPIKeyRevoked   = errors.New("API 金鑰已撤銷")
+	ErrDuplicateAPIKey = errors.New("已有同名且未撤銷的 API 金鑰")
+)
+
+// apiKeyColumns API 金鑰的欄位，不包含金鑰雜湊
+const apiKeyColumns = `id, name, prefix, scopes, tenant_id, expires_at, last_used_at, revoked_at, create_at, update_at`
+
+// APIKeyTouchInterval 最後使用時間的更新間隔，避免每個請求都寫入資料庫
+const APIKeyTouchInterval = time.Minute
+
+// APIKeyRepository 定義 API 金鑰的儲存庫接口
+type APIKeyRepository interface {
+	// CreateAPIKey 以金鑰的雜湊創建 API 金鑰
+	CreateAPIKey(ctx context.Context, input models.APIKey, keyHash string) (models.APIKey, error)
+	// ListAPIKeys 依創建順序列出 ctx 租戶的所有金鑰，包括已撤銷的
+	ListAPIKeys(ctx context.Context) ([]models.APIKey, error)
+	// GetAPIKeyByHash 依金鑰的雜湊查詢，包括已過期或已撤銷的金鑰
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (models.APIKey, error)
+	// RotateAPIKey 以新的金鑰取代 ctx 租戶的金鑰，名稱、範圍與到期時間不變，舊金鑰立即失效；
+	// 已撤銷的金鑰返回 ErrAPIKeyRevoked，其他租戶的金鑰視為不存在
+	RotateAPIKey(ctx context.Context, id int64, prefix string, keyHash string, now time.Time) (models.APIKey, error)
+	// RevokeAPIKey 撤銷 ctx 租戶的金鑰，已撤銷的金鑰保留原本的撤銷時間
+	RevokeAPIKey(ctx context.Context, id int64, now time.Time) (models.APIKey, error)
+	// TouchAPIKey 記錄金鑰的最後使用時間，距離上次記錄不到 APIKeyTouchInterval 時不寫入
+	TouchAPIKey(ctx context.Context, id int64, now time.Time) error
+}
+
+type PostgresAPIKeyRepository struct {
+	db *sqlx.DB
+}
+
+func NewAPIKeyRepository(db *sqlx.DB) APIKeyRepository {
+	return &PostgresAPIKeyRepository{db: db}
+}
+
+// CreateAPIKey 創建 API 金鑰，同名的金鑰未撤銷時返回 ErrDuplicateAPIKey
+func (r *PostgresAPIKeyRepository) CreateAPIKey(ctx context.Context, input models.APIKey, keyHash string) (models.APIKey, error) {
+	var key models.APIKey
+	err := r.db.QueryRowxContext(ctx, `
+		INSERT INTO api_keys (name, prefix, key_hash, scopes, expires_at, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+apiKeyColumns,
+		input.Name, input.Prefix, keyHash, input.Scopes, input.ExpiresAt, input.Tenant,
+	).StructScan(&key)
+	if err != nil {
+		return models.APIKey{}, uniqueError(err, ErrDuplicateAPIKey)
+	}
+	return key, nil
+}
+
+// ListAPIKeys 列出 ctx 租戶的 API 金鑰
+func (r *PostgresAPIKeyRepository) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	keys := []models.APIKey{}
+	if err := r.db.SelectContext(ctx, &keys, `SELECT `+apiKeyColumns+` FROM api_keys WHERE tenant_id = $1 ORDER BY id`, tenant.ID(ctx)); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// GetAPIKeyByHash 依金鑰的雜湊查詢 API 金鑰
+func (r *PostgresAPIKeyRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.GetContext(ctx, &key, `SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1`, keyHash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.APIKey{}, ErrAPIKeyNotFound
+		}
+		return models.APIKey{}, err
+	}
+	return key, nil
+}
+
+// RotateAPIKey 更換金鑰的雜湊與開頭，並清除最後使用時間
+func (r *PostgresAPIKeyRepository) RotateAPIKey(ctx context.Context, id int64, prefix string, keyHash string, now time.Time) (models.APIKey, error) {
+	var key models.APIKey
+	err := r.db.QueryRowxContext(ctx, `
+		UPDATE api_keys
+		SET prefix = $2, key_hash = $3, last_used_at = NULL, update_at = $4
+		WHERE id = $1 AND tenant_id = $5 AND revoked_at IS NULL
+		RETURNING `+apiKeyColumns,
+		id, prefix, keyHash, now, tenant.ID(ctx),
+	).StructScan(&key)
+	if errors.Is(err, sql.ErrNoRows) {
+		// 區分不存在與已撤銷
+		var revoked bool
+		if err := r.db.GetContext(ctx, &revoked, `SELECT revoked_at IS NOT NULL FROM api_keys WHERE id = $1 AND tenant_id = $2`, id, tenant.ID(ctx)); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return models.APIKey{}, ErrAPIKeyNotFound
+			}
+			return models.APIKey{}, err
+		}
+		return models.APIKey{}, ErrAPIKeyRevoked
+	}
+	if err != nil {
+		return models.APIKey{}, err
+	}
+	return key, nil
+}
+
+// RevokeAPIKey 撤銷 API 金鑰
+func (r *PostgresAPIKeyRepository) RevokeAPIKey(ctx context.Context, id int64, now time.Time) (models.APIKey, error) {
+	var key models.APIKey
+	err := r.db.QueryRowxContext(ctx, `
+		UPDATE api_keys
+		SET revoked_at = COALESCE(revoked_at, $2), update_at = $2
+		WHERE id = $1 AND tenant_id = $3
+		RETURNING `+apiKeyColumns,
+		id, now, tenant.ID(ctx),
+	).StructScan(&key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.APIKey{}, ErrAPIKeyNotFound
+		}
+		return models.APIKey{}, err
+	}
+	return key, nil
+}
+
+// TouchAPIKey 更新最後使用時間
+func (r *PostgresAPIKeyRepository) TouchAPIKey(ctx context.Context, id int64, now time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE api_keys
+		SET last_used_at = $2
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $3)
+	`, id, now, now.Add(-APIKeyTouchInterval))
+	return err
+}
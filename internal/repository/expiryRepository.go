@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"main/internal/models"
+	"main/internal/tenant"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// expiredStock 返回產品已標記過期而不可動用數量的 SQL 表達式，table 為 products 在查詢中的名稱或別名：
+// 產品本身已過期時為全部庫存，否則為已標記過期的批次數量
+func expiredStock(table string) string {
+	return `CASE WHEN ` + table + `.expired_at IS NOT NULL THEN ` + table + `.sku_amount ELSE (
+			SELECT COALESCE(SUM(quantity), 0) FROM product_lots WHERE product_id = ` + table + `.id AND expired_at IS NOT NULL
+		) END`
+}
+
+// ExpiryRepository 定義即將到期報表與過期清理的儲存庫接口
+type ExpiryRepository interface {
+	// GetExpiringByWarehouse 列出到期日在 from 到 to（含）之間的產品在各倉庫的庫存，依倉庫代碼與到期日排序
+	GetExpiringByWarehouse(ctx context.Context, from, to models.Date) ([]models.ExpiringStock, error)
+	// GetExpiringByLot 列出到期日在 from 到 to（含）之間且仍有庫存的批次，依到期日排序
+	GetExpiringByLot(ctx context.Context, from, to models.Date) ([]models.ExpiringStock, error)
+	// MarkExpired 在同一個交易中把到期日早於 today 的批次與產品標記為過期，返回本次標記的項目；
+	// 已標記的不會重複返回，因此多次執行的結果相同
+	MarkExpired(ctx context.Context, today models.Date, now time.Time) ([]models.ExpiredStock, error)
+}
+
+type PostgresExpiryRepository struct {
+	db tenantDB
+}
+
+func NewExpiryRepository(db *sqlx.DB, opts ...Option) ExpiryRepository {
+	return &PostgresExpiryRepository{db: newTenantDB(db, opts)}
+}
+
+// GetExpiringByWarehouse 產品在同一倉庫多個儲位的庫存合計為一行，已標記過期或在回收站中的產品不列出
+func (r *PostgresExpiryRepository) GetExpiringByWarehouse(ctx context.Context, from, to models.Date) ([]models.ExpiringStock, error) {
+	stocks := []models.ExpiringStock{}
+	if err := r.db.SelectContext(ctx, &stocks, `
+		SELECT w.id AS warehouse_id, w.code AS warehouse_code, w.name AS warehouse_name,
+			p.id AS product_id, p.sku_code, p.sku_name, p.expiration, SUM(s.quantity) AS quantity
+		FROM products p
+		JOIN product_stock s ON s.product_id = p.id
+		JOIN stock_locations l ON l.id = s.location_id
+		JOIN warehouses w ON w.id = l.warehouse_id
+		WHERE p.deleted_at IS NULL AND p.expired_at IS NULL AND p.expiration BETWEEN $1 AND $2 AND s.quantity > 0 AND p.tenant_id = $3
+		GROUP BY w.id, p.id
+		ORDER BY w.code, p.expiration, p.sku_code
+	`, from, to, tenant.ID(ctx)); err != nil {
+		return nil, err
+	}
+	return stocks, nil
+}
+
+// GetExpiringByLot 已標記過期的批次或在回收站中的產品不列出
+func (r *PostgresExpiryRepository) GetExpiringByLot(ctx context.Context, from, to models.Date) ([]models.ExpiringStock, error) {
+	stocks := []models.ExpiringStock{}
+	if err := r.db.SelectContext(ctx, &stocks, `
+		SELECT l.id AS lot_id, l.lot_number, p.id AS product_id, p.sku_code, p.sku_name, l.expiration, l.quantity
+		FROM product_lots l
+		JOIN products p ON p.id = l.product_id
+		WHERE p.deleted_at IS NULL AND l.expired_at IS NULL AND l.quantity > 0 AND l.expiration BETWEEN $1 AND $2 AND p.tenant_id = $3
+		ORDER BY l.expiration, p.sku_code, l.lot_number
+	`, from, to, tenant.ID(ctx)); err != nil {
+		return nil, err
+	}
+	return stocks, nil
+}
+
+// MarkExpired 標記所有租戶的批次與產品；只標記仍有庫存的批次，產品不論庫存都會標記，修改到期日後由下次清理重新判斷
+func (r *PostgresExpiryRepository) MarkExpired(ctx context.Context, today models.Date, now time.Time) ([]models.ExpiredStock, error) {
+	expired := []models.ExpiredStock{}
+
+	ctx = allTenants(ctx)
+	err := runInTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		var lots []models.ExpiredStock
+		if err := tx.SelectContext(ctx, &lots, `
+			UPDATE product_lots l
+			SET expired_at = $2, update_at = $2
+			FROM products p
+			WHERE p.id = l.product_id AND p.deleted_at IS NULL
+				AND l.expired_at IS NULL AND l.quantity > 0 AND l.expiration < $1
+			RETURNING l.product_id, p.sku_code, p.sku_name, l.id AS lot_id, l.lot_number, l.expiration, l.quantity, l.tenant_id
+		`, today, now); err != nil {
+			return err
+		}
+
+		var products []models.ExpiredStock
+		if err := tx.SelectContext(ctx, &products, `
+			UPDATE products
+			SET expired_at = $2, update_at = $2, version = version + 1
+			WHERE deleted_at IS NULL AND expired_at IS NULL AND expiration < $1
+			RETURNING id AS product_id, sku_code, sku_name, expiration, sku_amount AS quantity, tenant_id
+		`, today, now); err != nil {
+			return err
+		}
+
+		expired = append(append(expired, lots...), products...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return expired, nil
+}
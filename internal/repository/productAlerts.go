@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"main/internal/models"
+)
+
+// lowStockCondition 低庫存的條件，與 idx_products_low_stock 的部分索引一致
+const lowStockCondition = " AND reorder_point > 0 AND sku_amount < reorder_point"
+
+// GetLowStock 列出庫存低於補貨點的產品，缺口（補貨點減去庫存）最大的在前，只支援偏移分頁
+func (r *PostgresProductRepository) GetLowStock(ctx context.Context, query models.ProductQuery) ([]models.Product, int, error) {
+	where, args := buildProductFilter(ctx, query, false)
+	where += lowStockCondition
+
+	var total int
+	if err := r.db.GetContext(ctx, &total, "SELECT COUNT(*) FROM products"+where, args...); err != nil {
+		return nil, 0, err
+	}
+
+	argIndex := len(args) + 1
+	args = append(args, query.PageSize, query.Offset)
+
+	products := []models.Product{}
+	if err := r.db.SelectContext(ctx, &products, fmt.Sprintf(`
+		SELECT *
+		FROM products%s
+		ORDER BY reorder_point - sku_amount DESC, id
+		LIMIT $%d OFFSET $%d
+	`, where, argIndex, argIndex+1), args...); err != nil {
+		return nil, 0, err
+	}
+
+	return products, total, nil
+}
@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"main/internal/models"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const (
+	// insertChunkSize 多行 INSERT 每條語句的行數，每行 6 個參數，需低於 PostgreSQL 的 65535 個參數上限
+	insertChunkSize = 1000
+	// copyThreshold 達到此數量時改用 COPY 寫入
+	copyThreshold = 500
+)
+
+// CreateBatch 在單一交易中創建多個產品，返回的產品與輸入順序一致
+// 任一 sku_code 已存在或在批次中重複時返回 ErrDuplicateSku，無法得知是哪一項
+func (r *PostgresProductRepository) CreateBatch(ctx context.Context, inputs []models.Product) ([]models.Product, error) {
+	products := []models.Product{}
+	if len(inputs) == 0 {
+		return products, nil
+	}
+
+	err := r.inTx(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		if len(inputs) >= copyThreshold {
+			products, err = copyProducts(ctx, tx, inputs)
+		} else {
+			products, err = insertProducts(ctx, tx, inputs)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, productWriteError(err)
+	}
+
+	// id 依寫入順序遞增，以此恢復輸入順序
+	sort.Slice(products, func(i, j int) bool {
+		return products[i].ID < products[j].ID
+	})
+
+	return products, nil
+}
+
+// insertProducts 以多行 INSERT 分塊寫入
+func insertProducts(ctx context.Context, tx *sqlx.Tx, inputs []models.Product) ([]models.Product, error) {
+	products := make([]models.Product, 0, len(inputs))
+
+	for start := 0; start < len(inputs); start += insertChunkSize {
+		end := start + insertChunkSize
+		if end > len(inputs) {
+			end = len(inputs)
+		}
+
+		values := make([]string, 0, end-start)
+		args := make([]interface{}, 0, (end-start)*6)
+		for i, input := range inputs[start:end] {
+			n := i * 6
+			values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6))
+			args = append(args, input.SkuCode, input.SkuName, input.SkuAmount, input.Expiration, input.ReorderPoint, input.ReorderQuantity)
+		}
+
+		var chunk []models.Product
+		if err := tx.SelectContext(ctx, &chunk, `
+			INSERT INTO products (sku_code, sku_name, sku_amount, expiration, reorder_point, reorder_quantity)
+			VALUES `+strings.Join(values, ", ")+`
+			RETURNING id, create_at, update_at, sku_code, sku_name, sku_amount, expiration, version, reorder_point, reorder_quantity, expired_at, tenant_id
+		`, args...); err != nil {
+			return nil, err
+		}
+		products = append(products, chunk...)
+	}
+
+	return products, nil
+}
+
+// copyProducts 先以 COPY 寫入臨時表，再一次插入產品表以取得生成的 id
+func copyProducts(ctx context.Context, tx *sqlx.Tx, inputs []models.Product) ([]models.Product, error) {
+	if _, err := tx.ExecContext(ctx, `
+		CREATE TEMP TABLE products_batch ON COMMIT DROP AS
+		SELECT 0 AS ord, sku_code, sku_name, sku_amount, expiration, reorder_point, reorder_quantity
+		FROM products
+		WITH NO DATA
+	`); err != nil {
+		return nil, err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("products_batch", "ord", "sku_code", "sku_name", "sku_amount", "expiration", "reorder_point", "reorder_quantity"))
+	if err != nil {
+		return nil, err
+	}
+
+	for i, input := range inputs {
+		if _, err := stmt.ExecContext(ctx, i, input.SkuCode, input.SkuName, input.SkuAmount, input.Expiration, input.ReorderPoint, input.ReorderQuantity); err != nil {
+			stmt.Close()
+			return nil, err
+		}
+	}
+
+	// 無參數的 Exec 將緩衝的數據送出
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		return nil, err
+	}
+	if err := stmt.Close(); err != nil {
+		return nil, err
+	}
+
+	products := make([]models.Product, 0, len(inputs))
+	if err := tx.SelectContext(ctx, &products, `
+		INSERT INTO products (sku_code, sku_name, sku_amount, expiration, reorder_point, reorder_quantity)
+		SELECT sku_code, sku_name, sku_amount, expiration, reorder_point, reorder_quantity
+		FROM products_batch
+		ORDER BY ord
+		RETURNING id, create_at, update_at, sku_code, sku_name, sku_amount, expiration, version, reorder_point, reorder_quantity, expired_at, tenant_id
+	`); err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
+
+// UpdateBatch 在單一交易中依序更新多個產品
+func (r *PostgresProductRepository) UpdateBatch(ctx context.Context, items []models.ProductBatchUpdate) ([]models.Product, error) {
+	products := make([]models.Product, 0, len(items))
+
+	err := r.inTx(ctx, func(tx *sqlx.Tx) error {
+		for i, item := range items {
+			product, err := updateProduct(ctx, tx, item.ID, item.Version, item.Patch)
+			if err != nil {
+				return &models.BatchItemError{Index: i, Err: err}
+			}
+			products = append(products, product)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
+
+// DeleteBatch 在單一交易中依序刪除多個產品
+func (r *PostgresProductRepository) DeleteBatch(ctx context.Context, refs []models.ProductRef) error {
+	return r.inTx(ctx, func(tx *sqlx.Tx) error {
+		for i, ref := range refs {
+			if err := deleteProduct(ctx, tx, ref.ID, ref.Version); err != nil {
+				return &models.BatchItemError{Index: i, Err: err}
+			}
+		}
+		return nil
+	})
+}
+
+// inTx 在交易中執行 fn，fn 返回錯誤時回滾
+func (r *PostgresProductRepository) inTx(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	return runInTx(ctx, r.db, fn)
+}
+
+// txBeginner 可以開始交易的連接池
+type txBeginner interface {
+	BeginTxx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, error)
+}
+
+// runInTx 在交易中執行 fn，fn 返回錯誤時回滾
+// 所有寫入都經由此函數，交易開始時先設置操作者、請求 ID 與租戶，使觸發器寫入的變更歷史帶有來源，
+// 新行的 tenant_id 預設為交易的租戶
+func runInTx(ctx context.Context, db txBeginner, fn func(tx *sqlx.Tx) error) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := setAuditContext(ctx, tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"main/internal/audit"
+	"main/internal/models"
+	"main/internal/tenant"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// historyRow product_history 的一行，快照以 JSONB 保存
+type historyRow struct {
+	ID        int64            `db:"id"`
+	TenantID  string           `db:"tenant_id"`
+	ProductID int64            `db:"product_id"`
+	Operation string           `db:"operation"`
+	Version   int              `db:"version"`
+	Before    []byte           `db:"before"`
+	After     []byte           `db:"after"`
+	Actor     string           `db:"actor"`
+	RequestID string           `db:"request_id"`
+	ChangedAt models.Timestamp `db:"changed_at"`
+}
+
+// toHistory 解析快照，空的快照對應 nil
+func (row historyRow) toHistory() (models.ProductHistory, error) {
+	history := models.ProductHistory{
+		ID:        row.ID,
+		ProductID: row.ProductID,
+		Operation: row.Operation,
+		Version:   row.Version,
+		Actor:     row.Actor,
+		RequestID: row.RequestID,
+		ChangedAt: row.ChangedAt,
+	}
+
+	var err error
+	if history.Before, err = decodeSnapshot(row.Before); err != nil {
+		return models.ProductHistory{}, err
+	}
+	if history.After, err = decodeSnapshot(row.After); err != nil {
+		return models.ProductHistory{}, err
+	}
+	return history, nil
+}
+
+func decodeSnapshot(data []byte) (*models.Product, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var product models.Product
+	if err := json.Unmarshal(data, &product); err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// GetHistory 依時間由新到舊列出產品的變更歷史，包含已刪除的產品
+func (r *PostgresProductRepository) GetHistory(ctx context.Context, id int64, query models.ProductQuery) ([]models.ProductHistory, int, error) {
+	var total int
+	if err := r.db.GetContext(ctx, &total, `SELECT COUNT(*) FROM product_history WHERE product_id = $1 AND tenant_id = $2`, id, tenant.ID(ctx)); err != nil {
+		return nil, 0, err
+	}
+
+	rows := []historyRow{}
+	if err := r.db.SelectContext(ctx, &rows, `
+		SELECT *
+		FROM product_history
+		WHERE product_id = $1 AND tenant_id = $4
+		ORDER BY changed_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`, id, query.PageSize, query.Offset, tenant.ID(ctx)); err != nil {
+		return nil, 0, err
+	}
+
+	history := make([]models.ProductHistory, 0, len(rows))
+	for _, row := range rows {
+		h, err := row.toHistory()
+		if err != nil {
+			return nil, 0, err
+		}
+		history = append(history, h)
+	}
+
+	return history, total, nil
+}
+
+// GetAsOf 以變更歷史重建產品在 asOf 時的內容，當時尚未創建或已刪除時返回 ErrProductNotFound
+func (r *PostgresProductRepository) GetAsOf(ctx context.Context, id int64, asOf time.Time) (models.Product, error) {
+	var row historyRow
+
+	err := r.db.GetContext(ctx, &row, `
+		SELECT *
+		FROM product_history
+		WHERE product_id = $1 AND changed_at <= $2 AND tenant_id = $3
+		ORDER BY changed_at DESC, id DESC
+		LIMIT 1
+	`, id, asOf, tenant.ID(ctx))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Product{}, ErrProductNotFound
+		}
+		return models.Product{}, err
+	}
+
+	snapshot, err := decodeSnapshot(row.After)
+	if err != nil {
+		return models.Product{}, err
+	}
+	if snapshot == nil || !snapshot.DeletedAt.IsZero() {
+		return models.Product{}, ErrProductNotFound
+	}
+
+	return *snapshot, nil
+}
+
+// setAuditContext 將 context 中的操作者、請求 ID 與租戶設置到交易內，由歷史記錄觸發器、
+// tenant_id 的預設值與資料列安全策略讀取
+func setAuditContext(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, `SELECT set_config('app.actor', $1, true), set_config('app.request_id', $2, true), set_config('app.tenant_id', $3, true)`,
+		audit.Actor(ctx), audit.RequestID(ctx), tenant.ID(ctx))
+	return err
+}
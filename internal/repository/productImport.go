@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"main/internal/models"
+	"main/internal/tenant"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Stream 依過濾與排序條件逐筆讀取所有產品，不會一次載入全部結果
+func (r *PostgresProductRepository) Stream(ctx context.Context, query models.ProductQuery, fn func(models.Product) error) error {
+	where, args := buildProductFilter(ctx, query, false)
+
+	column, ok := productSortColumns[query.SortBy]
+	if !ok {
+		column = "id"
+	}
+
+	return r.db.scoped(ctx, func(q sqlx.ExtContext) error {
+		rows, err := q.QueryxContext(ctx, "SELECT * FROM products"+where+" ORDER BY "+orderByClause(column, query.SortDir == models.SortDesc), args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var product models.Product
+			if err := rows.StructScan(&product); err != nil {
+				return err
+			}
+			if err := fn(product); err != nil {
+				return err
+			}
+		}
+
+		return rows.Err()
+	})
+}
+
+// upsertedProduct UpsertBySku 返回的產品，inserted 表示該行是新插入的
+type upsertedProduct struct {
+	models.Product
+	Inserted bool `db:"inserted"`
+}
+
+// UpsertBySku 以 INSERT ... ON CONFLICT 依租戶內的 sku_code 更新或創建產品
+// 內容與現有產品相同時不寫入也不遞增版本，重複執行的結果一致
+func (r *PostgresProductRepository) UpsertBySku(ctx context.Context, input models.Product) (models.Product, bool, error) {
+	var result upsertedProduct
+
+	err := r.inTx(ctx, func(tx *sqlx.Tx) error {
+		// xmax 為 0 表示該行由本次 INSERT 產生，而非由衝突後的 UPDATE 修改
+		err := tx.QueryRowxContext(ctx, `
+			INSERT INTO products (sku_code, sku_name, sku_amount, expiration, reorder_point, reorder_quantity)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (tenant_id, sku_code) WHERE deleted_at IS NULL DO UPDATE
+			SET sku_name = EXCLUDED.sku_name,
+				sku_amount = EXCLUDED.sku_amount,
+				expiration = EXCLUDED.expiration,
+				expired_at = CASE WHEN products.expiration IS DISTINCT FROM EXCLUDED.expiration THEN NULL ELSE products.expired_at END,
+				reorder_point = EXCLUDED.reorder_point,
+				reorder_quantity = EXCLUDED.reorder_quantity,
+				update_at = $7,
+				version = products.version + 1
+			WHERE (products.sku_name, products.sku_amount, products.expiration, products.reorder_point, products.reorder_quantity)
+				IS DISTINCT FROM (EXCLUDED.sku_name, EXCLUDED.sku_amount, EXCLUDED.expiration, EXCLUDED.reorder_point, EXCLUDED.reorder_quantity)
+			RETURNING id, create_at, update_at, sku_code, sku_name, sku_amount, expiration, version, reorder_point, reorder_quantity, expired_at, tenant_id, (xmax = 0) AS inserted
+		`, input.SkuCode, input.SkuName, input.SkuAmount, input.Expiration, input.ReorderPoint, input.ReorderQuantity, time.Now()).StructScan(&result)
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		// 內容未改變時 DO UPDATE 不會返回任何行，讀取現有的產品
+		result.Product, err = getProductBySku(ctx, tx, input.SkuCode)
+		return err
+	})
+	if err != nil {
+		return models.Product{}, false, productWriteError(err)
+	}
+
+	return result.Product, result.Inserted, nil
+}
+
+// ExistingSkuCodes 返回給定 sku_code 中已存在的部分（不含已軟刪除的產品）
+func (r *PostgresProductRepository) ExistingSkuCodes(ctx context.Context, skuCodes []string) (map[string]bool, error) {
+	existing := map[string]bool{}
+	if len(skuCodes) == 0 {
+		return existing, nil
+	}
+
+	var found []string
+	if err := r.db.SelectContext(ctx, &found, `SELECT sku_code FROM products WHERE sku_code = ANY($1) AND deleted_at IS NULL AND tenant_id = $2`, pq.Array(skuCodes), tenant.ID(ctx)); err != nil {
+		return nil, err
+	}
+
+	for _, code := range found {
+		existing[code] = true
+	}
+	return existing, nil
+}
@@ -1,63 +1,294 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"main/internal/models"
+	"main/internal/tenant"
 	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 // 錯誤定義
 var (
-	ErrProductNotFound = errors.New("產品未找到")
+	ErrProductNotFound   = errors.New("產品未找到")
+	ErrVersionConflict   = errors.New("產品已被其他請求修改")
+	ErrDuplicateSku      = errors.New("產品編號已存在")
+	ErrInsufficientStock = errors.New("庫存不足")
 )
 
+// skuCodeConstraint 未刪除產品的 sku_code 唯一索引的名稱
+const skuCodeConstraint = "products_sku_code_key"
+
+// locationStockConstraint 儲位庫存不能為負數的檢查約束名稱
+const locationStockConstraint = "product_stock_quantity_nonnegative"
+
 // ProductRepository 定義產品儲存庫接口
 type ProductRepository interface {
-	GetAll() ([]models.Product, error)
-	GetByID(id int64) (models.Product, error)
-	Create(input models.Product) (models.Product, error)
-	UpdateNonBlank(id int64, input models.Product) (models.Product, error)
-	Delete(id int64) error
+	GetAll(ctx context.Context, query models.ProductQuery) ([]models.Product, int, error)
+	GetByID(ctx context.Context, id int64) (models.Product, error)
+	GetBySku(ctx context.Context, skuCode string) (models.Product, error)
+	Create(ctx context.Context, input models.Product) (models.Product, error)
+	Update(ctx context.Context, id int64, version int, patch models.ProductPatch) (models.Product, error)
+	// Delete 軟刪除產品，之後所有讀取都不會返回該產品，直到被還原
+	Delete(ctx context.Context, id int64, version int) error
+
+	// GetDeleted 依刪除時間由新到舊列出回收站中的產品，同時返回總數
+	GetDeleted(ctx context.Context, query models.ProductQuery) ([]models.Product, int, error)
+	// Restore 還原回收站中的產品，sku_code 已被其他產品使用時返回 ErrDuplicateSku
+	Restore(ctx context.Context, id int64) (models.Product, error)
+	// Purge 永久刪除回收站中的產品，產品不在回收站時返回 ErrProductNotFound
+	Purge(ctx context.Context, id int64) error
+	// PurgeDeletedBefore 永久刪除 ctx 租戶在 before 之前軟刪除的產品，返回刪除的數量；租戶為 tenant.All 時包含所有租戶
+	PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error)
+
+	// 每次寫入都由觸發器在同一交易中記錄變更歷史
+	// GetHistory 依時間由新到舊列出產品的變更歷史，同時返回總數
+	GetHistory(ctx context.Context, id int64, query models.ProductQuery) ([]models.ProductHistory, int, error)
+	// GetAsOf 重建產品在 asOf 時的內容，當時尚未創建或已刪除時返回 ErrProductNotFound
+	GetAsOf(ctx context.Context, id int64, asOf time.Time) (models.Product, error)
+
+	// GetLocationStock 依產品 ID 返回各產品在儲位的庫存，只包含數量大於 0 的儲位
+	GetLocationStock(ctx context.Context, ids []int64) (map[int64][]models.LocationStock, error)
+	// AdjustStock 以帶正負號的差額原子地調整 sku_amount；產品不存在時返回 ErrProductNotFound，
+	// 結果為負數或預設儲位的庫存不足時返回 ErrInsufficientStock
+	AdjustStock(ctx context.Context, id int64, delta int) (models.Product, error)
+	// GetLowStock 列出庫存低於補貨點的產品，同時返回總數
+	GetLowStock(ctx context.Context, query models.ProductQuery) ([]models.Product, int, error)
+
+	// Stream 依過濾與排序條件逐筆讀取所有產品（忽略分頁），fn 返回錯誤時停止
+	Stream(ctx context.Context, query models.ProductQuery, fn func(models.Product) error) error
+	// UpsertBySku 依 sku_code 更新已存在的產品，不存在時創建，返回是否為新建；內容未改變時不遞增版本
+	UpsertBySku(ctx context.Context, input models.Product) (models.Product, bool, error)
+	// ExistingSkuCodes 返回給定 sku_code 中已存在的部分
+	ExistingSkuCodes(ctx context.Context, skuCodes []string) (map[string]bool, error)
+
+	// 批量操作均在單一交易中執行，任一項失敗時返回 *models.BatchItemError 並回滾
+	CreateBatch(ctx context.Context, inputs []models.Product) ([]models.Product, error)
+	UpdateBatch(ctx context.Context, items []models.ProductBatchUpdate) ([]models.Product, error)
+	DeleteBatch(ctx context.Context, refs []models.ProductRef) error
 }
 
 type PostgresProductRepository struct {
-	db *sqlx.DB
+	db tenantDB
 }
 
-func NewProductRepository(db *sqlx.DB) ProductRepository {
-	return &PostgresProductRepository{db: db}
+func NewProductRepository(db *sqlx.DB, opts ...Option) ProductRepository {
+	return &PostgresProductRepository{db: newTenantDB(db, opts)}
 }
 
-// GetAll 獲取所有產品
-func (r *PostgresProductRepository) GetAll() ([]models.Product, error) {
-	var products []models.Product
+// GetAll 依查詢選項獲取產品列表，同時返回符合過濾條件的總數（不受分頁影響）
+func (r *PostgresProductRepository) GetAll(ctx context.Context, query models.ProductQuery) ([]models.Product, int, error) {
+	where, args := buildProductFilter(ctx, query, false)
+
+	// 先計算符合條件的總數
+	var total int
+	if err := r.db.GetContext(ctx, &total, "SELECT COUNT(*) FROM products"+where, args...); err != nil {
+		return nil, 0, err
+	}
+
+	// 排序欄位只能來自白名單，避免 SQL 注入
+	column, ok := productSortColumns[query.SortBy]
+	if !ok {
+		column = "id"
+	}
+	descending := query.SortDir == models.SortDesc
+
+	// 鍵集分頁：從定位點之後（或往前翻頁時之前）開始讀取
+	argIndex := len(args) + 1
+	if query.Keyset != nil {
+		// 往前翻頁時以反方向查詢，讀取後再反轉結果
+		if query.Keyset.Backward {
+			descending = !descending
+		}
+		operator := ">"
+		if descending {
+			operator = "<"
+		}
+
+		var condition string
+		if column == "id" {
+			condition = fmt.Sprintf("id %s $%d", operator, argIndex)
+			args = append(args, query.Keyset.ID)
+			argIndex++
+		} else {
+			condition = fmt.Sprintf("(%s, id) %s ($%d, $%d)", column, operator, argIndex, argIndex+1)
+			args = append(args, query.Keyset.Value, query.Keyset.ID)
+			argIndex += 2
+		}
+
+		where += " AND " + condition
+	}
+
+	orderBy := orderByClause(column, descending)
+
+	// 鍵集分頁不需要 OFFSET
+	limitClause := fmt.Sprintf("LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
+	if query.Keyset != nil {
+		limitClause = fmt.Sprintf("LIMIT $%d", argIndex)
+		args = append(args, query.PageSize)
+	} else {
+		args = append(args, query.PageSize, query.Offset)
+	}
 
-	err := r.db.Select(&products, `
+	listQuery := fmt.Sprintf(`
+		SELECT *
+		FROM products%s
+		ORDER BY %s
+		%s
+	`, where, orderBy, limitClause)
+
+	products := []models.Product{}
+	if err := r.db.SelectContext(ctx, &products, listQuery, args...); err != nil {
+		return nil, 0, err
+	}
+
+	// 往前翻頁時恢復原本的排序
+	if query.Keyset != nil && query.Keyset.Backward {
+		for i, j := 0, len(products)-1; i < j; i, j = i+1, j-1 {
+			products[i], products[j] = products[j], products[i]
+		}
+	}
+
+	return products, total, nil
+}
+
+// orderByClause 構建 ORDER BY 內容，以 id 作為次要排序確保結果穩定
+func orderByClause(column string, descending bool) string {
+	direction := "ASC"
+	if descending {
+		direction = "DESC"
+	}
+
+	orderBy := fmt.Sprintf("%s %s", column, direction)
+	if column != "id" {
+		orderBy += fmt.Sprintf(", id %s", direction)
+	}
+	return orderBy
+}
+
+// productSortColumns 排序欄位與資料庫欄位的對應
+var productSortColumns = map[string]string{
+	"id":         "id",
+	"sku_code":   "sku_code",
+	"sku_name":   "sku_name",
+	"sku_amount": "sku_amount",
+	"expiration": "expiration",
+	"create_at":  "create_at",
+	"update_at":  "update_at",
+}
+
+// buildProductFilter 根據過濾條件構建 WHERE 子句，所有值都使用參數綁定，並只包含 ctx 租戶的產品；
+// deleted 為 false 時只包含未刪除的產品，為 true 時只包含回收站中的產品
+func buildProductFilter(ctx context.Context, query models.ProductQuery, deleted bool) (string, []interface{}) {
+	conditions := []string{"deleted_at IS NULL"}
+	if deleted {
+		conditions[0] = "deleted_at IS NOT NULL"
+	}
+	args := []interface{}{}
+	argIndex := 1
+
+	if query.SkuCode != "" {
+		conditions = append(conditions, fmt.Sprintf("sku_code LIKE $%d", argIndex))
+		args = append(args, escapeLike(query.SkuCode)+"%")
+		argIndex++
+	}
+
+	if query.SkuName != "" {
+		conditions = append(conditions, fmt.Sprintf("sku_name ILIKE $%d", argIndex))
+		args = append(args, "%"+escapeLike(query.SkuName)+"%")
+		argIndex++
+	}
+
+	if query.MinAmount != nil {
+		conditions = append(conditions, fmt.Sprintf("sku_amount >= $%d", argIndex))
+		args = append(args, *query.MinAmount)
+		argIndex++
+	}
+
+	if query.MaxAmount != nil {
+		conditions = append(conditions, fmt.Sprintf("sku_amount <= $%d", argIndex))
+		args = append(args, *query.MaxAmount)
+		argIndex++
+	}
+
+	if !query.ExpirationFrom.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("expiration >= $%d", argIndex))
+		args = append(args, query.ExpirationFrom)
+		argIndex++
+	}
+
+	if !query.ExpirationTo.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("expiration <= $%d", argIndex))
+		args = append(args, query.ExpirationTo)
+		argIndex++
+	}
+
+	if query.WarehouseID > 0 {
+		conditions = append(conditions, fmt.Sprintf(`EXISTS (
+			SELECT 1 FROM product_stock ps JOIN stock_locations l ON l.id = ps.location_id
+			WHERE ps.product_id = products.id AND l.warehouse_id = $%d AND ps.quantity > 0
+		)`, argIndex))
+		args = append(args, query.WarehouseID)
+		argIndex++
+	}
+
+	conditions = append(conditions, fmt.Sprintf("tenant_id = $%d", argIndex))
+	args = append(args, tenant.ID(ctx))
+
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// escapeLike 轉義 LIKE 模式中的特殊字元
+func escapeLike(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	return replacer.Replace(s)
+}
+
+func (r *PostgresProductRepository) GetByID(ctx context.Context, id int64) (models.Product, error) {
+	var product models.Product
+
+	err := r.db.GetContext(ctx, &product, `
 		SELECT *
 		FROM products
-		ORDER BY id
-	`)
+		WHERE id = $1 AND deleted_at IS NULL AND tenant_id = $2
+	`, id, tenant.ID(ctx))
 
 	if err != nil {
-		return nil, err
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Product{}, ErrProductNotFound
+		}
+		return models.Product{}, err
 	}
 
-	return products, nil
+	return product, nil
+}
+
+// GetBySku 依 sku_code 獲取產品
+func (r *PostgresProductRepository) GetBySku(ctx context.Context, skuCode string) (models.Product, error) {
+	var product models.Product
+	err := r.db.scoped(ctx, func(q sqlx.ExtContext) error {
+		var err error
+		product, err = getProductBySku(ctx, q, skuCode)
+		return err
+	})
+	return product, err
 }
 
-func (r *PostgresProductRepository) GetByID(id int64) (models.Product, error) {
+// getProductBySku 在指定的連接或交易上執行 GetBySku
+func getProductBySku(ctx context.Context, q sqlx.QueryerContext, skuCode string) (models.Product, error) {
 	var product models.Product
 
-	err := r.db.Get(&product, `
+	err := sqlx.GetContext(ctx, q, &product, `
 		SELECT *
 		FROM products
-		WHERE id = $1
-	`, id)
+		WHERE sku_code = $1 AND deleted_at IS NULL AND tenant_id = $2
+	`, skuCode, tenant.ID(ctx))
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -69,85 +300,139 @@ func (r *PostgresProductRepository) GetByID(id int64) (models.Product, error) {
 	return product, nil
 }
 
-// Create 創建新產品
-func (r *PostgresProductRepository) Create(input models.Product) (models.Product, error) {
+// Create 創建新產品，sku_code 已存在時返回 ErrDuplicateSku
+func (r *PostgresProductRepository) Create(ctx context.Context, input models.Product) (models.Product, error) {
 	var product models.Product
 
-	err := r.db.QueryRowx(`
-		INSERT INTO products (sku_code, sku_name, sku_amount, expiration)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, sku_code, sku_name, sku_amount, expiration
-	`, input.SkuCode, input.SkuName, input.SkuAmount, input.Expiration).StructScan(&product)
+	err := r.inTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.QueryRowxContext(ctx, `
+			INSERT INTO products (sku_code, sku_name, sku_amount, expiration, reorder_point, reorder_quantity)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id, create_at, update_at, sku_code, sku_name, sku_amount, expiration, version, reorder_point, reorder_quantity, expired_at, tenant_id
+		`, input.SkuCode, input.SkuName, input.SkuAmount, input.Expiration, input.ReorderPoint, input.ReorderQuantity).StructScan(&product)
+	})
 
 	if err != nil {
-		return models.Product{}, err
+		return models.Product{}, productWriteError(err)
 	}
 
 	return product, nil
 }
 
-// Update 更新產品
-func (r *PostgresProductRepository) UpdateNonBlank(id int64, input models.Product) (models.Product, error) {
+// Update 更新修補中出現的欄位並遞增版本號
+// version 為客戶端讀取時的版本，與資料庫不一致時返回 ErrVersionConflict；為 0 時不檢查版本；
+// 修改後的 sku_code 與其他產品重複時返回 ErrDuplicateSku
+func (r *PostgresProductRepository) Update(ctx context.Context, id int64, version int, patch models.ProductPatch) (models.Product, error) {
+	var product models.Product
+
+	err := r.inTx(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		product, err = updateProduct(ctx, tx, id, version, patch)
+		return err
+	})
+
+	return product, err
+}
+
+// updateProduct 在指定的連接或交易上執行 Update
+func updateProduct(ctx context.Context, q sqlx.ExtContext, id int64, version int, patch models.ProductPatch) (models.Product, error) {
 	// 準備 SQL 查詢部分
 	sets := []string{}
 	args := []interface{}{}
 	argIndex := 1
 
-	// 檢查每個欄位，只添加非空欄位
-	if input.SkuCode != "" {
+	// 只更新修補中出現的欄位，null 已由服務層驗證，此處寫入零值
+	if patch.SkuCode.Set {
 		sets = append(sets, fmt.Sprintf("sku_code = $%d", argIndex))
-		args = append(args, input.SkuCode)
+		args = append(args, patch.SkuCode.Value)
 		argIndex++
 	}
 
-	if input.SkuName != "" {
+	if patch.SkuName.Set {
 		sets = append(sets, fmt.Sprintf("sku_name = $%d", argIndex))
-		args = append(args, input.SkuName)
+		args = append(args, patch.SkuName.Value)
 		argIndex++
 	}
 
-	if input.Expiration != "" {
+	if patch.Expiration.Set {
+		// 沒有到期日以零值表示，寫入 NULL
 		sets = append(sets, fmt.Sprintf("expiration = $%d", argIndex))
-		args = append(args, input.Expiration)
+		// 到期日改變時清除過期標記，由下次過期清理重新判斷
+		sets = append(sets, fmt.Sprintf("expired_at = CASE WHEN expiration IS DISTINCT FROM $%d THEN NULL ELSE expired_at END", argIndex))
+		args = append(args, patch.Expiration.Value)
 		argIndex++
 	}
 
-	sets = append(sets, fmt.Sprintf("sku_amount = $%d", argIndex))
-	args = append(args, input.SkuAmount)
-	argIndex++
+	if patch.SkuAmount.Set {
+		sets = append(sets, fmt.Sprintf("sku_amount = $%d", argIndex))
+		args = append(args, patch.SkuAmount.Value)
+		argIndex++
+	}
+
+	if patch.ReorderPoint.Set {
+		sets = append(sets, fmt.Sprintf("reorder_point = $%d", argIndex))
+		args = append(args, patch.ReorderPoint.Value)
+		argIndex++
+	}
+
+	if patch.ReorderQuantity.Set {
+		sets = append(sets, fmt.Sprintf("reorder_quantity = $%d", argIndex))
+		args = append(args, patch.ReorderQuantity.Value)
+		argIndex++
+	}
 
 	sets = append(sets, fmt.Sprintf("update_at = $%d", argIndex))
 	args = append(args, time.Now())
 	argIndex++
 
+	sets = append(sets, "version = version + 1")
+
 	// 構建完整的 SQL 查詢
 	query := fmt.Sprintf(`
         UPDATE products
         SET %s
-        WHERE id = $%d
-        RETURNING id, update_at, sku_code, sku_name, sku_amount, expiration
-    `, strings.Join(sets, ", "), argIndex)
-
-	// 添加 ID 到參數列表
-	args = append(args, id)
+        WHERE id = $%d AND deleted_at IS NULL AND tenant_id = $%d%s
+        RETURNING id, create_at, update_at, sku_code, sku_name, sku_amount, expiration, version, reorder_point, reorder_quantity, expired_at, tenant_id
+    `, strings.Join(sets, ", "), argIndex, argIndex+1, versionCondition(version, argIndex+2))
+
+	// 添加 ID、租戶與版本到參數列表
+	args = append(args, id, tenant.ID(ctx))
+	if version > 0 {
+		args = append(args, version)
+	}
 
 	// 執行查詢
 	var product models.Product
-	err := r.db.QueryRowx(query, args...).StructScan(&product)
+	err := q.QueryRowxContext(ctx, query, args...).StructScan(&product)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return models.Product{}, ErrProductNotFound
+			return models.Product{}, missingOrConflict(ctx, q, id)
 		}
-		return models.Product{}, err
+		return models.Product{}, productWriteError(err)
 	}
 
 	return product, nil
 }
 
-// Delete 刪除產品
-func (r *PostgresProductRepository) Delete(id int64) error {
-	result, err := r.db.Exec(`DELETE FROM products WHERE id = $1`, id)
+// Delete 軟刪除產品並遞增版本號，version 的語義與 Update 相同
+func (r *PostgresProductRepository) Delete(ctx context.Context, id int64, version int) error {
+	return r.inTx(ctx, func(tx *sqlx.Tx) error {
+		return deleteProduct(ctx, tx, id, version)
+	})
+}
+
+// deleteProduct 在指定的連接或交易上執行 Delete
+func deleteProduct(ctx context.Context, q sqlx.ExtContext, id int64, version int) error {
+	args := []interface{}{id, time.Now(), tenant.ID(ctx)}
+	if version > 0 {
+		args = append(args, version)
+	}
+
+	result, err := q.ExecContext(ctx, `
+		UPDATE products
+		SET deleted_at = $2, version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL AND tenant_id = $3`+versionCondition(version, 4), args...)
 	if err != nil {
 		return err
 	}
@@ -158,8 +443,46 @@ func (r *PostgresProductRepository) Delete(id int64) error {
 	}
 
 	if rowsAffected == 0 {
-		return ErrProductNotFound
+		return missingOrConflict(ctx, q, id)
 	}
 
 	return nil
 }
+
+// productWriteError 將 sku_code 唯一約束衝突轉為 ErrDuplicateSku；
+// 直接減少 sku_amount 而預設儲位的庫存不足時轉為 ErrInsufficientStock，其他錯誤原樣返回
+func productWriteError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	switch {
+	case pqErr.Code == "23505" && pqErr.Constraint == skuCodeConstraint:
+		return ErrDuplicateSku
+	case pqErr.Code == "23514" && pqErr.Constraint == locationStockConstraint:
+		return ErrInsufficientStock
+	}
+	return err
+}
+
+// versionCondition 返回版本檢查的 WHERE 條件，version 為 0 時不檢查
+func versionCondition(version int, argIndex int) string {
+	if version <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" AND version = $%d", argIndex)
+}
+
+// missingOrConflict 在條件更新未影響任何行時，區分產品不存在（含已軟刪除）與版本衝突
+func missingOrConflict(ctx context.Context, q sqlx.QueryerContext, id int64) error {
+	var exists bool
+	if err := sqlx.GetContext(ctx, q, &exists, `SELECT EXISTS(SELECT 1 FROM products WHERE id = $1 AND deleted_at IS NULL AND tenant_id = $2)`, id, tenant.ID(ctx)); err != nil {
+		return err
+	}
+
+	if exists {
+		return ErrVersionConflict
+	}
+	return ErrProductNotFound
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"main/internal/models"
+	"main/internal/tenant"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// AdjustStock 以單一條件更新遞增 sku_amount，並發的調整由行鎖依序套用，不會遺失更新；
+// 減少時與出庫相同，不能動用未到期的預留與已標記過期的庫存。
+// 差額與直接修改 sku_amount 相同，由觸發器記入異動帳的預設儲位與批次
+func (r *PostgresProductRepository) AdjustStock(ctx context.Context, id int64, delta int) (models.Product, error) {
+	var product models.Product
+
+	err := r.inTx(ctx, func(tx *sqlx.Tx) error {
+		guard := `sku_amount + $2 >= 0`
+		if delta < 0 {
+			// 先鎖定產品，讓條件更新在新的快照中看到並發提交的預留
+			if _, err := tx.ExecContext(ctx, `SELECT 1 FROM products WHERE id = $1 AND tenant_id = $2 FOR UPDATE`, id, tenant.ID(ctx)); err != nil {
+				return err
+			}
+			guard = unreservedStockCondition("true")
+		}
+
+		err := tx.QueryRowxContext(ctx, `
+			UPDATE products
+			SET sku_amount = sku_amount + $2, update_at = $3, version = version + 1
+			WHERE id = $1 AND deleted_at IS NULL AND tenant_id = $4 AND `+guard+`
+			RETURNING id, create_at, update_at, sku_code, sku_name, sku_amount, expiration, version, reorder_point, reorder_quantity, expired_at, tenant_id
+		`, id, delta, time.Now(), tenant.ID(ctx)).StructScan(&product)
+		if errors.Is(err, sql.ErrNoRows) {
+			return insufficientOrMissing(ctx, tx, id)
+		}
+		return productWriteError(err)
+	})
+
+	if err != nil {
+		return models.Product{}, err
+	}
+
+	return product, nil
+}
+
+// GetLocationStock 依產品 ID 返回各產品有庫存的儲位，依倉庫與儲位代碼排序
+func (r *PostgresProductRepository) GetLocationStock(ctx context.Context, ids []int64) (map[int64][]models.LocationStock, error) {
+	stock := map[int64][]models.LocationStock{}
+	if len(ids) == 0 {
+		return stock, nil
+	}
+
+	var rows []models.LocationStock
+	if err := r.db.SelectContext(ctx, &rows, `
+		SELECT ps.product_id, ps.location_id, l.code AS location_code, w.id AS warehouse_id, w.code AS warehouse_code, ps.quantity
+		FROM product_stock ps
+		JOIN stock_locations l ON l.id = ps.location_id
+		JOIN warehouses w ON w.id = l.warehouse_id
+		WHERE ps.product_id = ANY($1) AND ps.quantity > 0 AND ps.tenant_id = $2
+		ORDER BY ps.product_id, w.code, l.code
+	`, pq.Array(ids), tenant.ID(ctx)); err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		stock[row.ProductID] = append(stock[row.ProductID], row)
+	}
+	return stock, nil
+}
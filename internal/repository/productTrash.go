@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"main/internal/models"
+	"main/internal/tenant"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// GetDeleted 依刪除時間由新到舊列出回收站中的產品，只支援偏移分頁
+func (r *PostgresProductRepository) GetDeleted(ctx context.Context, query models.ProductQuery) ([]models.Product, int, error) {
+	where, args := buildProductFilter(ctx, query, true)
+
+	var total int
+	if err := r.db.GetContext(ctx, &total, "SELECT COUNT(*) FROM products"+where, args...); err != nil {
+		return nil, 0, err
+	}
+
+	argIndex := len(args) + 1
+	args = append(args, query.PageSize, query.Offset)
+
+	products := []models.Product{}
+	if err := r.db.SelectContext(ctx, &products, fmt.Sprintf(`
+		SELECT *
+		FROM products%s
+		ORDER BY deleted_at DESC, id DESC
+		LIMIT $%d OFFSET $%d
+	`, where, argIndex, argIndex+1), args...); err != nil {
+		return nil, 0, err
+	}
+
+	return products, total, nil
+}
+
+// Restore 還原回收站中的產品並遞增版本號
+func (r *PostgresProductRepository) Restore(ctx context.Context, id int64) (models.Product, error) {
+	var product models.Product
+
+	err := r.inTx(ctx, func(tx *sqlx.Tx) error {
+		return tx.QueryRowxContext(ctx, `
+			UPDATE products
+			SET deleted_at = NULL, update_at = $2, version = version + 1
+			WHERE id = $1 AND deleted_at IS NOT NULL AND tenant_id = $3
+			RETURNING id, create_at, update_at, sku_code, sku_name, sku_amount, expiration, version, reorder_point, reorder_quantity, expired_at, tenant_id
+		`, id, time.Now(), tenant.ID(ctx)).StructScan(&product)
+	})
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Product{}, ErrProductNotFound
+		}
+		return models.Product{}, productWriteError(err)
+	}
+
+	return product, nil
+}
+
+// Purge 永久刪除回收站中的產品，未軟刪除的產品不能直接永久刪除
+func (r *PostgresProductRepository) Purge(ctx context.Context, id int64) error {
+	return r.inTx(ctx, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, `DELETE FROM products WHERE id = $1 AND deleted_at IS NOT NULL AND tenant_id = $2`, id, tenant.ID(ctx))
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return ErrProductNotFound
+		}
+
+		return nil
+	})
+}
+
+// PurgeDeletedBefore 永久刪除 ctx 租戶在 before 之前軟刪除的產品，租戶為 tenant.All 時包含所有租戶
+func (r *PostgresProductRepository) PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	var purged int64
+
+	query := `DELETE FROM products WHERE deleted_at < $1`
+	args := []interface{}{before}
+	if id := tenant.ID(ctx); id != tenant.All {
+		query += ` AND tenant_id = $2`
+		args = append(args, id)
+	}
+
+	err := r.inTx(ctx, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		purged, err = result.RowsAffected()
+		return err
+	})
+
+	return purged, err
+}
@@ -0,0 +1,289 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"main/internal/audit"
+	"main/internal/models"
+	"main/internal/tenant"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// 預留的錯誤定義
+var (
+	ErrReservationNotFound  = errors.New("預留未找到")
+	ErrReservationConflict  = errors.New("reference 已用於數量不同的預留")
+	ErrReservationNotActive = errors.New("預留已提交、釋放或過期")
+	ErrReservationExpired   = errors.New("預留已過期")
+)
+
+// ReservationRepository 定義庫存預留的儲存庫接口；可承諾量為產品庫存扣除未到期的 active 預留
+type ReservationRepository interface {
+	// Reserve 在可承諾量足夠時創建預留，返回的布爾值表示是否新建；
+	// 同一產品已有相同 reference 的預留時，數量相同則返回既有的預留，不同則返回 ErrReservationConflict；
+	// 產品不存在時返回 ErrProductNotFound，可承諾量不足時返回 ErrInsufficientStock
+	Reserve(ctx context.Context, input models.StockReservation) (models.StockReservation, bool, error)
+	// GetReservation 依 ID 獲取預留，不存在時返回 ErrReservationNotFound
+	GetReservation(ctx context.Context, id int64) (models.StockReservation, error)
+	// ListActiveReservations 依到期時間列出產品未到期的 active 預留；產品不存在時返回 ErrProductNotFound
+	ListActiveReservations(ctx context.Context, productID int64) ([]models.StockReservation, error)
+	// GetAvailability 獲取產品的可承諾量；產品不存在時返回 ErrProductNotFound
+	GetAvailability(ctx context.Context, productID int64) (models.StockAvailability, error)
+	// Commit 提交預留並從 locationID 出庫（0 為預設儲位），已提交的預留直接返回；
+	// 已釋放或過期時返回 ErrReservationNotActive，剛好到期時標記為過期並返回 ErrReservationExpired
+	Commit(ctx context.Context, id int64, locationID int64) (models.StockReservation, error)
+	// Release 釋放預留，已釋放或過期的預留直接返回，已提交時返回 ErrReservationNotActive
+	Release(ctx context.Context, id int64) (models.StockReservation, error)
+	// ExpireReservations 將最多 limit 筆在 now 之前到期的 active 預留標記為過期，返回處理的數量
+	ExpireReservations(ctx context.Context, now time.Time, limit int) (int64, error)
+}
+
+// reservationColumns 讀取預留的欄位，尚未提交時 movement_id 以 0 表示
+const reservationColumns = `id, product_id, quantity, reference, status, expires_at, COALESCE(movement_id, 0) AS movement_id,
+			actor, request_id, create_at, update_at`
+
+type PostgresReservationRepository struct {
+	db tenantDB
+}
+
+func NewReservationRepository(db *sqlx.DB, opts ...Option) ReservationRepository {
+	return &PostgresReservationRepository{db: newTenantDB(db, opts)}
+}
+
+// Reserve 先鎖定產品的行，同一產品的預留與出庫依序進行；之後的語句在新的快照中計算已預留的數量
+func (r *PostgresReservationRepository) Reserve(ctx context.Context, input models.StockReservation) (models.StockReservation, bool, error) {
+	var reservation models.StockReservation
+	created := false
+
+	err := runInTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		var amount int
+		if err := tx.GetContext(ctx, &amount, `SELECT sku_amount - `+expiredStock("products")+` FROM products WHERE id = $1 AND deleted_at IS NULL AND tenant_id = $2 FOR UPDATE`, input.ProductID, tenant.ID(ctx)); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrProductNotFound
+			}
+			return err
+		}
+
+		err := tx.GetContext(ctx, &reservation, `
+			SELECT `+reservationColumns+`
+			FROM stock_reservations
+			WHERE product_id = $1 AND reference = $2
+		`, input.ProductID, input.Reference)
+		if err == nil {
+			if reservation.Quantity != input.Quantity {
+				return ErrReservationConflict
+			}
+			return nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		now := time.Now()
+		var reserved int
+		if err := tx.GetContext(ctx, &reserved, `
+			SELECT COALESCE(SUM(quantity), 0) FROM stock_reservations
+			WHERE product_id = $1 AND status = 'active' AND expires_at > $2
+		`, input.ProductID, now); err != nil {
+			return err
+		}
+		if amount-reserved < input.Quantity {
+			return ErrInsufficientStock
+		}
+
+		if err := tx.GetContext(ctx, &reservation, `
+			INSERT INTO stock_reservations (product_id, quantity, reference, expires_at, actor, request_id, create_at, update_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+			RETURNING `+reservationColumns+`
+		`, input.ProductID, input.Quantity, input.Reference, input.ExpiresAt.Time,
+			audit.Actor(ctx), audit.RequestID(ctx), now); err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+
+	if err != nil {
+		return models.StockReservation{}, false, err
+	}
+
+	return reservation, created, nil
+}
+
+// GetReservation 依 ID 獲取預留
+func (r *PostgresReservationRepository) GetReservation(ctx context.Context, id int64) (models.StockReservation, error) {
+	var reservation models.StockReservation
+	if err := r.db.GetContext(ctx, &reservation, `SELECT `+reservationColumns+` FROM stock_reservations WHERE id = $1 AND tenant_id = $2`, id, tenant.ID(ctx)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.StockReservation{}, ErrReservationNotFound
+		}
+		return models.StockReservation{}, err
+	}
+	return reservation, nil
+}
+
+// ListActiveReservations 列出產品未到期的 active 預留，已到期但尚未被清理的預留不列出
+func (r *PostgresReservationRepository) ListActiveReservations(ctx context.Context, productID int64) ([]models.StockReservation, error) {
+	var exists bool
+	if err := r.db.GetContext(ctx, &exists, `SELECT EXISTS(SELECT 1 FROM products WHERE id = $1 AND deleted_at IS NULL AND tenant_id = $2)`, productID, tenant.ID(ctx)); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrProductNotFound
+	}
+
+	reservations := []models.StockReservation{}
+	if err := r.db.SelectContext(ctx, &reservations, `
+		SELECT `+reservationColumns+`
+		FROM stock_reservations
+		WHERE product_id = $1 AND status = 'active' AND expires_at > $2
+		ORDER BY expires_at, id
+	`, productID, time.Now()); err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
+
+// GetAvailability 以單次查詢讀取庫存、未到期的預留與已過期的庫存；直接調整庫存後 available 可能為負數
+func (r *PostgresReservationRepository) GetAvailability(ctx context.Context, productID int64) (models.StockAvailability, error) {
+	var availability models.StockAvailability
+	if err := r.db.GetContext(ctx, &availability, `
+		SELECT p.id AS product_id, p.sku_amount AS on_hand, r.reserved, e.expired, p.sku_amount - r.reserved - e.expired AS available
+		FROM products p
+		CROSS JOIN LATERAL (
+			SELECT COALESCE(SUM(quantity), 0) AS reserved FROM stock_reservations
+			WHERE product_id = p.id AND status = 'active' AND expires_at > $2
+		) r
+		CROSS JOIN LATERAL (SELECT `+expiredStock("p")+` AS expired) e
+		WHERE p.id = $1 AND p.deleted_at IS NULL AND p.tenant_id = $3
+	`, productID, time.Now(), tenant.ID(ctx)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.StockAvailability{}, ErrProductNotFound
+		}
+		return models.StockAvailability{}, err
+	}
+	return availability, nil
+}
+
+// lockReservation 在交易中鎖定預留，讓提交、釋放與過期清理依序處理同一筆預留
+func lockReservation(ctx context.Context, tx *sqlx.Tx, id int64) (models.StockReservation, error) {
+	var reservation models.StockReservation
+	if err := tx.GetContext(ctx, &reservation, `SELECT `+reservationColumns+` FROM stock_reservations WHERE id = $1 AND tenant_id = $2 FOR UPDATE`, id, tenant.ID(ctx)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.StockReservation{}, ErrReservationNotFound
+		}
+		return models.StockReservation{}, err
+	}
+	return reservation, nil
+}
+
+// Commit 先把預留標記為已提交，使其不再佔用可承諾量，再於同一交易中寫入出庫異動
+func (r *PostgresReservationRepository) Commit(ctx context.Context, id int64, locationID int64) (models.StockReservation, error) {
+	var reservation models.StockReservation
+	expired := false
+
+	err := runInTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		var err error
+		reservation, err = lockReservation(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		switch reservation.Status {
+		case models.ReservationCommitted:
+			return nil
+		case models.ReservationReleased, models.ReservationExpired:
+			return ErrReservationNotActive
+		}
+
+		now := time.Now()
+		if !reservation.ExpiresAt.After(now) {
+			// 過期的標記需要提交，因此不以錯誤結束交易
+			expired = true
+			_, err := tx.ExecContext(ctx, `UPDATE stock_reservations SET status = 'expired', update_at = $2 WHERE id = $1`, id, now)
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE stock_reservations SET status = 'committed', update_at = $2 WHERE id = $1`, id, now); err != nil {
+			return err
+		}
+
+		movements, err := applyMovementsTx(ctx, tx, []models.StockMovement{{
+			ProductID:  reservation.ProductID,
+			LocationID: locationID,
+			Type:       models.MovementIssue,
+			Quantity:   -reservation.Quantity,
+			Reason:     models.ReasonSale,
+			Reference:  reservation.Reference,
+		}})
+		if err != nil {
+			return err
+		}
+
+		return tx.GetContext(ctx, &reservation, `
+			UPDATE stock_reservations SET movement_id = $2
+			WHERE id = $1
+			RETURNING `+reservationColumns, id, movements[0].ID)
+	})
+
+	if err != nil {
+		return models.StockReservation{}, err
+	}
+	if expired {
+		return models.StockReservation{}, ErrReservationExpired
+	}
+
+	return reservation, nil
+}
+
+// Release 釋放預留，未到期的預留立即歸還可承諾量
+func (r *PostgresReservationRepository) Release(ctx context.Context, id int64) (models.StockReservation, error) {
+	var reservation models.StockReservation
+
+	err := runInTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		var err error
+		reservation, err = lockReservation(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		switch reservation.Status {
+		case models.ReservationReleased, models.ReservationExpired:
+			return nil
+		case models.ReservationCommitted:
+			return ErrReservationNotActive
+		}
+
+		return tx.GetContext(ctx, &reservation, `
+			UPDATE stock_reservations SET status = 'released', update_at = $2
+			WHERE id = $1
+			RETURNING `+reservationColumns, id, time.Now())
+	})
+
+	if err != nil {
+		return models.StockReservation{}, err
+	}
+
+	return reservation, nil
+}
+
+// ExpireReservations 清理所有租戶的預留，跳過正在被提交或釋放而鎖定的預留，多個清理程序並發執行時也不會互相等待
+func (r *PostgresReservationRepository) ExpireReservations(ctx context.Context, now time.Time, limit int) (int64, error) {
+	result, err := r.db.ExecContext(allTenants(ctx), `
+		UPDATE stock_reservations SET status = 'expired', update_at = $1
+		WHERE id IN (
+			SELECT id FROM stock_reservations
+			WHERE status = 'active' AND expires_at <= $1
+			ORDER BY expires_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+	`, now, limit)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"main/internal/models"
+	"main/internal/tenant"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// 批次的錯誤定義
+var (
+	ErrLotExpirationMismatch = errors.New("批號已存在且到期日不同")
+	ErrMovementNotFound      = errors.New("庫存異動未找到")
+)
+
+// applyLots 在數量都更新後處理異動的批次：先依 FEFO 分配出庫，再放入入庫的批次；
+// 轉移只在同一產品的儲位間進行，批次不分儲位，因此轉出與轉入不影響批次
+func applyLots(ctx context.Context, tx *sqlx.Tx, movements []models.StockMovement, now time.Time) error {
+	for i := range movements {
+		if movements[i].Quantity > 0 || isTransfer(movements[i]) {
+			continue
+		}
+
+		lots, err := allocateLots(ctx, tx, movements[i], now)
+		if err != nil {
+			return err
+		}
+		movements[i].Lots = lots
+	}
+
+	for i := range movements {
+		if movements[i].Quantity < 0 || isTransfer(movements[i]) {
+			continue
+		}
+
+		received, err := receiveLots(ctx, tx, movements[i], movements[i].Lots, now)
+		if err != nil {
+			return err
+		}
+		movements[i].Lots = received
+	}
+	return nil
+}
+
+// isTransfer 判斷異動是否為儲位間轉移的一端
+func isTransfer(movement models.StockMovement) bool {
+	return movement.Type == models.MovementTransferOut || movement.Type == models.MovementTransferIn
+}
+
+// allocateLots 依到期日由早到晚扣減批次，批次不足的部分由未追蹤的庫存支付；已標記過期的批次最先分配，
+// 但一般出庫跳過這些批次，只有過期報廢會扣減。
+// 產品的行已在更新 sku_amount 時鎖定，因此同一產品的分配不會並發進行
+func allocateLots(ctx context.Context, tx *sqlx.Tx, movement models.StockMovement, now time.Time) ([]models.MovementLot, error) {
+	skipExpired := movement.Type == models.MovementIssue && movement.Reason != models.ReasonExpired
+
+	var available []models.ProductLot
+	if err := tx.SelectContext(ctx, &available, `
+		SELECT id, lot_number, quantity, expiration
+		FROM product_lots
+		WHERE product_id = $1 AND quantity > 0 AND NOT ($2 AND expired_at IS NOT NULL)
+		ORDER BY expired_at IS NULL, expiration NULLS LAST, received_at, id
+		FOR UPDATE
+	`, movement.ProductID, skipExpired); err != nil {
+		return nil, err
+	}
+
+	remaining := -movement.Quantity
+	lots := []models.MovementLot{}
+	for _, lot := range available {
+		if remaining == 0 {
+			break
+		}
+
+		taken := min(lot.Quantity, remaining)
+		if _, err := tx.ExecContext(ctx, `UPDATE product_lots SET quantity = quantity - $2, update_at = $3 WHERE id = $1`, lot.ID, taken, now); err != nil {
+			return nil, err
+		}
+
+		allocated := models.MovementLot{LotID: lot.ID, LotNumber: lot.LotNumber, Expiration: lot.Expiration, Quantity: -taken}
+		if err := insertMovementLot(ctx, tx, movement.ID, allocated); err != nil {
+			return nil, err
+		}
+		lots = append(lots, allocated)
+		remaining -= taken
+	}
+
+	return lots, nil
+}
+
+// receiveLots 把入庫的數量加到批次中，批號不存在時建立；既有批次的到期日不同時返回 ErrLotExpirationMismatch
+func receiveLots(ctx context.Context, tx *sqlx.Tx, movement models.StockMovement, lots []models.MovementLot, now time.Time) ([]models.MovementLot, error) {
+	received := make([]models.MovementLot, 0, len(lots))
+	for _, lot := range lots {
+		// 未指定到期日時沿用既有批次的到期日
+		err := tx.QueryRowxContext(ctx, `
+			INSERT INTO product_lots (product_id, lot_number, quantity, expiration, received_at)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (product_id, lot_number) DO UPDATE
+			SET quantity = product_lots.quantity + EXCLUDED.quantity, update_at = $6
+			WHERE EXCLUDED.expiration IS NULL OR product_lots.expiration IS NOT DISTINCT FROM EXCLUDED.expiration
+			RETURNING id, expiration
+		`, movement.ProductID, lot.LotNumber, lot.Quantity, lot.Expiration, models.DateOf(now.UTC()), now).Scan(&lot.LotID, &lot.Expiration)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrLotExpirationMismatch
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if err := insertMovementLot(ctx, tx, movement.ID, lot); err != nil {
+			return nil, err
+		}
+		received = append(received, lot)
+	}
+	return received, nil
+}
+
+// insertMovementLot 記錄異動在批次上的增減
+func insertMovementLot(ctx context.Context, tx *sqlx.Tx, movementID int64, lot models.MovementLot) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO stock_movement_lots (movement_id, lot_id, quantity) VALUES ($1, $2, $3)`, movementID, lot.LotID, lot.Quantity)
+	return err
+}
+
+// GetLots 依 FEFO 順序列出產品仍有庫存的批次，以及不屬於任何批次的庫存；回收站中的產品仍可查詢
+func (r *PostgresStockRepository) GetLots(ctx context.Context, productID int64) (models.ProductLotStock, error) {
+	var amount int
+	if err := r.db.GetContext(ctx, &amount, `SELECT sku_amount FROM products WHERE id = $1 AND tenant_id = $2`, productID, tenant.ID(ctx)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ProductLotStock{}, ErrProductNotFound
+		}
+		return models.ProductLotStock{}, err
+	}
+
+	lots := []models.ProductLot{}
+	if err := r.db.SelectContext(ctx, &lots, `
+		SELECT id, product_id, lot_number, quantity, expiration, received_at, expired_at, create_at, update_at
+		FROM product_lots
+		WHERE product_id = $1 AND quantity > 0
+		ORDER BY expiration NULLS LAST, received_at, id
+	`, productID); err != nil {
+		return models.ProductLotStock{}, err
+	}
+
+	untracked := amount
+	for _, lot := range lots {
+		untracked -= lot.Quantity
+	}
+
+	return models.ProductLotStock{
+		ProductID:         productID,
+		Items:             lots,
+		UntrackedQuantity: untracked,
+	}, nil
+}
+
+// GetMovement 獲取產品的單筆庫存異動及其批次
+func (r *PostgresStockRepository) GetMovement(ctx context.Context, productID int64, movementID int64) (models.StockMovement, error) {
+	var movement models.StockMovement
+	if err := r.db.GetContext(ctx, &movement, `
+		SELECT `+movementColumns+`
+		FROM stock_movements
+		WHERE id = $1 AND product_id = $2 AND tenant_id = $3
+	`, movementID, productID, tenant.ID(ctx)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.StockMovement{}, ErrMovementNotFound
+		}
+		return models.StockMovement{}, err
+	}
+
+	movements := []models.StockMovement{movement}
+	if err := r.attachMovementLots(ctx, movements); err != nil {
+		return models.StockMovement{}, err
+	}
+	return movements[0], nil
+}
+
+// attachMovementLots 以單次查詢為異動附加其增減的批次
+func (r *PostgresStockRepository) attachMovementLots(ctx context.Context, movements []models.StockMovement) error {
+	if len(movements) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(movements))
+	for i, movement := range movements {
+		ids[i] = movement.ID
+	}
+
+	var rows []models.MovementLot
+	if err := r.db.SelectContext(ctx, &rows, `
+		SELECT ml.movement_id, ml.lot_id, l.lot_number, l.expiration, ml.quantity
+		FROM stock_movement_lots ml
+		JOIN product_lots l ON l.id = ml.lot_id
+		WHERE ml.movement_id = ANY($1)
+		ORDER BY ml.movement_id, l.expiration NULLS LAST, l.received_at, l.id
+	`, pq.Array(ids)); err != nil {
+		return err
+	}
+
+	lots := map[int64][]models.MovementLot{}
+	for _, row := range rows {
+		lots[row.MovementID] = append(lots[row.MovementID], row)
+	}
+	for i := range movements {
+		movements[i].Lots = lots[movements[i].ID]
+	}
+	return nil
+}
@@ -0,0 +1,253 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"main/internal/audit"
+	"main/internal/models"
+	"main/internal/tenant"
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// StockRepository 定義庫存異動帳的儲存庫接口
+type StockRepository interface {
+	// ApplyMovements 在單一交易中寫入異動並更新對應產品的 sku_amount、儲位庫存與批次，任一筆失敗時全部回滾；
+	// 產品不存在時返回 ErrProductNotFound，儲位不存在時返回 ErrLocationNotFound，
+	// 產品或儲位的庫存會變成負數時返回 ErrInsufficientStock，批號的到期日不一致時返回 ErrLotExpirationMismatch
+	ApplyMovements(ctx context.Context, movements []models.StockMovement) ([]models.StockMovement, error)
+	// GetMovements 依時間由新到舊列出產品的庫存異動及其批次，同時返回總數；產品不存在時返回 ErrProductNotFound
+	GetMovements(ctx context.Context, productID int64, query models.ProductQuery) ([]models.StockMovement, int, error)
+	// GetMovement 獲取產品的單筆庫存異動及其批次，不存在時返回 ErrMovementNotFound
+	GetMovement(ctx context.Context, productID int64, movementID int64) (models.StockMovement, error)
+	// GetLots 列出產品仍有庫存的批次；產品不存在時返回 ErrProductNotFound
+	GetLots(ctx context.Context, productID int64) (models.ProductLotStock, error)
+}
+
+// movementColumns 讀取異動的欄位，已刪除的儲位以 0 表示
+const movementColumns = `id, product_id, COALESCE(location_id, 0) AS location_id, movement_type, quantity, reason, reference,
+			balance, actor, request_id, created_at`
+
+type PostgresStockRepository struct {
+	db tenantDB
+}
+
+func NewStockRepository(db *sqlx.DB, opts ...Option) StockRepository {
+	return &PostgresStockRepository{db: newTenantDB(db, opts)}
+}
+
+// ApplyMovements 在單一交易中寫入異動
+func (r *PostgresStockRepository) ApplyMovements(ctx context.Context, movements []models.StockMovement) ([]models.StockMovement, error) {
+	var applied []models.StockMovement
+	err := runInTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		var err error
+		applied, err = applyMovementsTx(ctx, tx, movements)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return applied, nil
+}
+
+// applyMovementsTx 在既有交易中寫入異動，依產品與儲位 ID 順序鎖定並更新，避免並發的轉移互相死鎖；
+// 未指定儲位時使用租戶的預設儲位，排序前先換成預設儲位的 ID，返回的異動保持輸入順序
+func applyMovementsTx(ctx context.Context, tx *sqlx.Tx, movements []models.StockMovement) ([]models.StockMovement, error) {
+	// 標記為異動帳寫入，觸發器不再把 sku_amount 的變化補記為 product_edit
+	if _, err := tx.ExecContext(ctx, `SELECT set_config('app.stock_ledger', 'on', true)`); err != nil {
+		return nil, err
+	}
+
+	var defaultLocation int64
+	if err := tx.GetContext(ctx, &defaultLocation, `SELECT tenant_default_location($1)`, tenant.ID(ctx)); err != nil {
+		return nil, err
+	}
+
+	resolved := make([]models.StockMovement, len(movements))
+	order := make([]int, len(movements))
+	for i, movement := range movements {
+		if movement.LocationID == 0 {
+			movement.LocationID = defaultLocation
+		}
+		resolved[i] = movement
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		ma, mb := resolved[order[a]], resolved[order[b]]
+		if ma.ProductID != mb.ProductID {
+			return ma.ProductID < mb.ProductID
+		}
+		return ma.LocationID < mb.LocationID
+	})
+
+	applied := make([]models.StockMovement, len(movements))
+	now := time.Now()
+	for _, i := range order {
+		movement, err := applyMovement(ctx, tx, resolved[i], now)
+		if err != nil {
+			return nil, err
+		}
+		applied[i] = movement
+	}
+
+	if err := applyLots(ctx, tx, applied, now); err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// applyMovement 以條件更新遞增產品與儲位的庫存，結果都不能為負數，再追加異動紀錄；
+// 出庫還不能動用其他請求預留的庫存。轉移只在同一產品的儲位間進行，產品的總庫存不變，
+// 因此轉出只檢查儲位的庫存，不受預留與過期限制；調整同樣不受預留限制
+func applyMovement(ctx context.Context, tx *sqlx.Tx, movement models.StockMovement, now time.Time) (models.StockMovement, error) {
+	var err error
+	if movement.Type == models.MovementIssue {
+		err = applyUnreservedStock(ctx, tx, &movement, now, movement.Reason != models.ReasonExpired)
+	} else {
+		err = tx.GetContext(ctx, &movement.Balance, `
+			UPDATE products
+			SET sku_amount = sku_amount + $2, update_at = $3, version = version + 1
+			WHERE id = $1 AND deleted_at IS NULL AND sku_amount + $2 >= 0 AND tenant_id = $4
+			RETURNING sku_amount
+		`, movement.ProductID, movement.Quantity, now, tenant.ID(ctx))
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.StockMovement{}, insufficientOrMissing(ctx, tx, movement.ProductID)
+	}
+	if err != nil {
+		return models.StockMovement{}, err
+	}
+
+	if err := applyLocationStock(ctx, tx, movement); err != nil {
+		return models.StockMovement{}, err
+	}
+
+	movement.Actor = audit.Actor(ctx)
+	movement.RequestID = audit.RequestID(ctx)
+
+	err = tx.QueryRowxContext(ctx, `
+		INSERT INTO stock_movements (product_id, location_id, movement_type, quantity, reason, reference, balance, actor, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at
+	`, movement.ProductID, movement.LocationID, movement.Type, movement.Quantity, movement.Reason, movement.Reference,
+		movement.Balance, movement.Actor, movement.RequestID).Scan(&movement.ID, &movement.CreatedAt)
+	if err != nil {
+		return models.StockMovement{}, err
+	}
+
+	return movement, nil
+}
+
+// applyUnreservedStock 扣減產品庫存，扣減後仍須足以支付未到期的預留；excludeExpired 時還須保留已標記過期的庫存，
+// 讓一般出庫不會動用過期品。先以獨立的語句鎖定產品，讓之後的更新在新的快照中看到並發提交的預留
+func applyUnreservedStock(ctx context.Context, tx *sqlx.Tx, movement *models.StockMovement, now time.Time, excludeExpired bool) error {
+	if _, err := tx.ExecContext(ctx, `SELECT 1 FROM products WHERE id = $1 AND tenant_id = $2 FOR UPDATE`, movement.ProductID, tenant.ID(ctx)); err != nil {
+		return err
+	}
+
+	return tx.GetContext(ctx, &movement.Balance, `
+		UPDATE products
+		SET sku_amount = sku_amount + $2, update_at = $3, version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL AND tenant_id = $5 AND `+unreservedStockCondition("$4")+`
+		RETURNING sku_amount
+	`, movement.ProductID, movement.Quantity, now, excludeExpired, tenant.ID(ctx))
+}
+
+// unreservedStockCondition 扣減後的庫存仍須足以支付未到期的預留，excludeExpired 為真時還須保留已標記過期的庫存；
+// 語句的 $1 須為產品 ID、$2 為差額、$3 為當前時間，excludeExpired 為布林參數的佔位符或常量
+func unreservedStockCondition(excludeExpired string) string {
+	return `sku_amount + $2 >= (
+			SELECT COALESCE(SUM(quantity), 0) FROM stock_reservations
+			WHERE product_id = $1 AND status = 'active' AND expires_at > $3
+		) + CASE WHEN ` + excludeExpired + ` THEN ` + expiredStock("products") + ` ELSE 0 END`
+}
+
+// applyLocationStock 更新產品在儲位的庫存：增加時不存在的行自動建立，減少時該儲位的庫存必須足夠；
+// 產品已在同一交易中確認屬於 ctx 的租戶，其他租戶的儲位違反 product_stock 的複合外鍵
+func applyLocationStock(ctx context.Context, tx *sqlx.Tx, movement models.StockMovement) error {
+	if movement.Quantity > 0 {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO product_stock (product_id, location_id, quantity)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (product_id, location_id) DO UPDATE SET quantity = product_stock.quantity + EXCLUDED.quantity
+		`, movement.ProductID, movement.LocationID, movement.Quantity)
+		return restrictError(err, ErrLocationNotFound)
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE product_stock
+		SET quantity = quantity + $3
+		WHERE product_id = $1 AND location_id = $2 AND quantity + $3 >= 0
+	`, movement.ProductID, movement.LocationID, movement.Quantity)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected > 0 {
+		return nil
+	}
+
+	var exists bool
+	if err := tx.GetContext(ctx, &exists, `SELECT EXISTS(SELECT 1 FROM stock_locations WHERE id = $1 AND tenant_id = $2)`, movement.LocationID, tenant.ID(ctx)); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrLocationNotFound
+	}
+	return ErrInsufficientStock
+}
+
+// insufficientOrMissing 區分條件更新未影響任何行的原因：產品不存在或庫存不足
+func insufficientOrMissing(ctx context.Context, ext sqlx.ExtContext, id int64) error {
+	var exists bool
+	if err := sqlx.GetContext(ctx, ext, &exists, `SELECT EXISTS(SELECT 1 FROM products WHERE id = $1 AND deleted_at IS NULL AND tenant_id = $2)`, id, tenant.ID(ctx)); err != nil {
+		return err
+	}
+
+	if !exists {
+		return ErrProductNotFound
+	}
+	return ErrInsufficientStock
+}
+
+// GetMovements 依時間由新到舊列出產品的庫存異動，回收站中的產品仍可查詢
+func (r *PostgresStockRepository) GetMovements(ctx context.Context, productID int64, query models.ProductQuery) ([]models.StockMovement, int, error) {
+	var exists bool
+	if err := r.db.GetContext(ctx, &exists, `SELECT EXISTS(SELECT 1 FROM products WHERE id = $1 AND tenant_id = $2)`, productID, tenant.ID(ctx)); err != nil {
+		return nil, 0, err
+	}
+	if !exists {
+		return nil, 0, ErrProductNotFound
+	}
+
+	var total int
+	if err := r.db.GetContext(ctx, &total, `SELECT COUNT(*) FROM stock_movements WHERE product_id = $1`, productID); err != nil {
+		return nil, 0, err
+	}
+
+	movements := []models.StockMovement{}
+	if err := r.db.SelectContext(ctx, &movements, `
+		SELECT `+movementColumns+`
+		FROM stock_movements
+		WHERE product_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`, productID, query.PageSize, query.Offset); err != nil {
+		return nil, 0, err
+	}
+
+	if err := r.attachMovementLots(ctx, movements); err != nil {
+		return nil, 0, err
+	}
+
+	return movements, total, nil
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"main/internal/tenant"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Option 儲存庫的選項
+type Option func(*tenantDB)
+
+// WithRowLevelSecurity 設置資料庫是否啟用了資料列安全（見 database.SetRowLevelSecurity）：
+// 啟用時不在交易中的讀取也改為在設置了租戶的交易中執行，否則 tenant_isolation 策略會拒絕所有的行
+func WithRowLevelSecurity(enabled bool) Option {
+	return func(db *tenantDB) {
+		db.rowLevelSecurity = enabled
+	}
+}
+
+// tenantDB 儲存庫使用的連接池。所有查詢都以 tenant.ID(ctx) 限定租戶；
+// 資料列安全是第二層防護，即使查詢遺漏了租戶條件，資料庫也不會返回其他租戶的行
+type tenantDB struct {
+	db               *sqlx.DB
+	rowLevelSecurity bool
+}
+
+func newTenantDB(db *sqlx.DB, opts []Option) tenantDB {
+	scoped := tenantDB{db: db}
+	for _, opt := range opts {
+		opt(&scoped)
+	}
+	return scoped
+}
+
+// BeginTxx 開始交易，供 runInTx 使用
+func (t tenantDB) BeginTxx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, error) {
+	return t.db.BeginTxx(ctx, opts)
+}
+
+// scoped 以 fn 執行不需要交易的查詢：啟用資料列安全時在設置了租戶的交易中執行，否則直接使用連接池
+func (t tenantDB) scoped(ctx context.Context, fn func(q sqlx.ExtContext) error) error {
+	if !t.rowLevelSecurity {
+		return fn(t.db)
+	}
+	return runInTx(ctx, t.db, func(tx *sqlx.Tx) error {
+		return fn(tx)
+	})
+}
+
+// GetContext 在租戶範圍內讀取單行
+func (t tenantDB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return t.scoped(ctx, func(q sqlx.ExtContext) error {
+		return sqlx.GetContext(ctx, q, dest, query, args...)
+	})
+}
+
+// SelectContext 在租戶範圍內讀取多行
+func (t tenantDB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return t.scoped(ctx, func(q sqlx.ExtContext) error {
+		return sqlx.SelectContext(ctx, q, dest, query, args...)
+	})
+}
+
+// ExecContext 在租戶範圍內執行語句
+func (t tenantDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := t.scoped(ctx, func(q sqlx.ExtContext) error {
+		var err error
+		result, err = q.ExecContext(ctx, query, args...)
+		return err
+	})
+	return result, err
+}
+
+// allTenants 返回跨所有租戶的 context，只用於過期清理這類背景任務；資料列安全策略允許租戶為 * 的交易存取所有的行
+func allTenants(ctx context.Context) context.Context {
+	return tenant.WithID(ctx, tenant.All)
+}
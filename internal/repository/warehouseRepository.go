@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"main/internal/models"
+	"main/internal/tenant"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// 倉庫與儲位的錯誤定義
+var (
+	ErrWarehouseNotFound  = errors.New("倉庫未找到")
+	ErrLocationNotFound   = errors.New("儲位未找到")
+	ErrDuplicateWarehouse = errors.New("倉庫代碼已存在")
+	ErrDuplicateLocation  = errors.New("同一倉庫內的儲位代碼已存在")
+	ErrWarehouseInUse     = errors.New("倉庫內仍有儲位")
+	ErrLocationInUse      = errors.New("儲位仍有庫存")
+	ErrDefaultLocation    = errors.New("預設儲位不能刪除")
+)
+
+// WarehouseRepository 定義倉庫與儲位的儲存庫接口
+type WarehouseRepository interface {
+	ListWarehouses(ctx context.Context) ([]models.Warehouse, error)
+	GetWarehouse(ctx context.Context, id int64) (models.Warehouse, error)
+	CreateWarehouse(ctx context.Context, input models.Warehouse) (models.Warehouse, error)
+	UpdateWarehouse(ctx context.Context, id int64, input models.Warehouse) (models.Warehouse, error)
+	// DeleteWarehouse 刪除沒有儲位的倉庫，仍有儲位時返回 ErrWarehouseInUse
+	DeleteWarehouse(ctx context.Context, id int64) error
+
+	// ListLocations 列出倉庫內的儲位，倉庫不存在時返回 ErrWarehouseNotFound
+	ListLocations(ctx context.Context, warehouseID int64) ([]models.StockLocation, error)
+	GetLocation(ctx context.Context, id int64) (models.StockLocation, error)
+	CreateLocation(ctx context.Context, input models.StockLocation) (models.StockLocation, error)
+	// UpdateLocation 修改儲位的代碼與名稱，儲位不能移到其他倉庫
+	UpdateLocation(ctx context.Context, id int64, input models.StockLocation) (models.StockLocation, error)
+	// DeleteLocation 刪除沒有庫存的儲位，異動紀錄保留但不再關聯儲位
+	DeleteLocation(ctx context.Context, id int64) error
+}
+
+// 倉庫與儲位的寫入以參數明確指定 tenant_id，不依賴交易內設置的租戶；
+// 儲位以複合外鍵引用同一租戶的倉庫，其他租戶的倉庫視為不存在
+type PostgresWarehouseRepository struct {
+	db tenantDB
+}
+
+func NewWarehouseRepository(db *sqlx.DB, opts ...Option) WarehouseRepository {
+	return &PostgresWarehouseRepository{db: newTenantDB(db, opts)}
+}
+
+// ListWarehouses 依代碼排序列出租戶的所有倉庫，預設倉庫在租戶第一次寫入庫存時建立
+func (r *PostgresWarehouseRepository) ListWarehouses(ctx context.Context) ([]models.Warehouse, error) {
+	warehouses := []models.Warehouse{}
+	if err := r.db.SelectContext(ctx, &warehouses, `SELECT * FROM warehouses WHERE tenant_id = $1 ORDER BY code`, tenant.ID(ctx)); err != nil {
+		return nil, err
+	}
+	return warehouses, nil
+}
+
+// GetWarehouse 依 ID 獲取倉庫
+func (r *PostgresWarehouseRepository) GetWarehouse(ctx context.Context, id int64) (models.Warehouse, error) {
+	var warehouse models.Warehouse
+	if err := r.db.GetContext(ctx, &warehouse, `SELECT * FROM warehouses WHERE id = $1 AND tenant_id = $2`, id, tenant.ID(ctx)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Warehouse{}, ErrWarehouseNotFound
+		}
+		return models.Warehouse{}, err
+	}
+	return warehouse, nil
+}
+
+// CreateWarehouse 創建倉庫
+func (r *PostgresWarehouseRepository) CreateWarehouse(ctx context.Context, input models.Warehouse) (models.Warehouse, error) {
+	var warehouse models.Warehouse
+	err := r.db.GetContext(ctx, &warehouse, `
+		INSERT INTO warehouses (code, name, tenant_id)
+		VALUES ($1, $2, $3)
+		RETURNING *
+	`, input.Code, input.Name, tenant.ID(ctx))
+	if err != nil {
+		return models.Warehouse{}, uniqueError(err, ErrDuplicateWarehouse)
+	}
+	return warehouse, nil
+}
+
+// UpdateWarehouse 修改倉庫的代碼與名稱
+func (r *PostgresWarehouseRepository) UpdateWarehouse(ctx context.Context, id int64, input models.Warehouse) (models.Warehouse, error) {
+	var warehouse models.Warehouse
+	err := r.db.GetContext(ctx, &warehouse, `
+		UPDATE warehouses
+		SET code = $2, name = $3, update_at = $4
+		WHERE id = $1 AND tenant_id = $5
+		RETURNING *
+	`, id, input.Code, input.Name, time.Now(), tenant.ID(ctx))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Warehouse{}, ErrWarehouseNotFound
+		}
+		return models.Warehouse{}, uniqueError(err, ErrDuplicateWarehouse)
+	}
+	return warehouse, nil
+}
+
+// DeleteWarehouse 刪除倉庫，儲位以外鍵限制刪除
+func (r *PostgresWarehouseRepository) DeleteWarehouse(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM warehouses WHERE id = $1 AND tenant_id = $2`, id, tenant.ID(ctx))
+	if err != nil {
+		return restrictError(err, ErrWarehouseInUse)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrWarehouseNotFound
+	}
+	return nil
+}
+
+// ListLocations 依代碼排序列出倉庫內的儲位
+func (r *PostgresWarehouseRepository) ListLocations(ctx context.Context, warehouseID int64) ([]models.StockLocation, error) {
+	if _, err := r.GetWarehouse(ctx, warehouseID); err != nil {
+		return nil, err
+	}
+
+	locations := []models.StockLocation{}
+	if err := r.db.SelectContext(ctx, &locations, `SELECT * FROM stock_locations WHERE warehouse_id = $1 AND tenant_id = $2 ORDER BY code`, warehouseID, tenant.ID(ctx)); err != nil {
+		return nil, err
+	}
+	return locations, nil
+}
+
+// GetLocation 依 ID 獲取儲位
+func (r *PostgresWarehouseRepository) GetLocation(ctx context.Context, id int64) (models.StockLocation, error) {
+	var location models.StockLocation
+	if err := r.db.GetContext(ctx, &location, `SELECT * FROM stock_locations WHERE id = $1 AND tenant_id = $2`, id, tenant.ID(ctx)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.StockLocation{}, ErrLocationNotFound
+		}
+		return models.StockLocation{}, err
+	}
+	return location, nil
+}
+
+// CreateLocation 在倉庫內創建儲位，新儲位不會成為預設儲位
+func (r *PostgresWarehouseRepository) CreateLocation(ctx context.Context, input models.StockLocation) (models.StockLocation, error) {
+	var location models.StockLocation
+	err := r.db.GetContext(ctx, &location, `
+		INSERT INTO stock_locations (warehouse_id, code, name, tenant_id)
+		VALUES ($1, $2, $3, $4)
+		RETURNING *
+	`, input.WarehouseID, input.Code, input.Name, tenant.ID(ctx))
+	if err != nil {
+		return models.StockLocation{}, uniqueError(restrictError(err, ErrWarehouseNotFound), ErrDuplicateLocation)
+	}
+	return location, nil
+}
+
+// UpdateLocation 修改儲位的代碼與名稱
+func (r *PostgresWarehouseRepository) UpdateLocation(ctx context.Context, id int64, input models.StockLocation) (models.StockLocation, error) {
+	var location models.StockLocation
+	err := r.db.GetContext(ctx, &location, `
+		UPDATE stock_locations
+		SET code = $2, name = $3, update_at = $4
+		WHERE id = $1 AND tenant_id = $5
+		RETURNING *
+	`, id, input.Code, input.Name, time.Now(), tenant.ID(ctx))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.StockLocation{}, ErrLocationNotFound
+		}
+		return models.StockLocation{}, uniqueError(err, ErrDuplicateLocation)
+	}
+	return location, nil
+}
+
+// DeleteLocation 先清除庫存為 0 的行，仍有庫存的產品以外鍵限制刪除
+func (r *PostgresWarehouseRepository) DeleteLocation(ctx context.Context, id int64) error {
+	return runInTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		var isDefault bool
+		if err := tx.GetContext(ctx, &isDefault, `SELECT is_default FROM stock_locations WHERE id = $1 AND tenant_id = $2 FOR UPDATE`, id, tenant.ID(ctx)); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrLocationNotFound
+			}
+			return err
+		}
+		if isDefault {
+			return ErrDefaultLocation
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM product_stock WHERE location_id = $1 AND quantity = 0`, id); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM stock_locations WHERE id = $1`, id); err != nil {
+			return restrictError(err, ErrLocationInUse)
+		}
+		return nil
+	})
+}
+
+// uniqueError 將違反唯一約束的錯誤轉為 target
+func uniqueError(err error, target error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return target
+	}
+	return err
+}
+
+// restrictError 將違反外鍵限制的錯誤轉為 target
+func restrictError(err error, target error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		return target
+	}
+	return err
+}
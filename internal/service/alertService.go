@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"main/internal/auth"
+	model "main/internal/models"
+	"main/internal/notify"
+	"main/internal/repository"
+	"time"
+)
+
+// AlertService 定義庫存事件派送的服務接口
+type AlertService interface {
+	// RelayLowStock 將至多 limit 筆觸發器記錄的低庫存事件送到通知管道，返回送出的數量；
+	// 送出失敗時返回錯誤，未送出的事件留待下次派送
+	RelayLowStock(ctx context.Context, limit int) (int, error)
+}
+
+// DefaultAlertService 實現默認事件派送服務
+type DefaultAlertService struct {
+	repo     repository.AlertRepository
+	notifier notify.Notifier
+	timeout  time.Duration
+}
+
+// NewAlertService 創建新的事件派送服務，timeout 為單個事件的派送時限
+func NewAlertService(repo repository.AlertRepository, notifier notify.Notifier, timeout time.Duration) AlertService {
+	return &DefaultAlertService{
+		repo:     repo,
+		notifier: notifier,
+		timeout:  timeout,
+	}
+}
+
+// RelayLowStock 直接呼叫通知管道而不經過記憶體中的派送佇列，事件在送出後才從寄件匣刪除；
+// 任一管道失敗時整個事件稍後重送，已成功的管道可能收到重複的事件
+func (s *DefaultAlertService) RelayLowStock(ctx context.Context, limit int) (int, error) {
+	if err := auth.Authorize(ctx, auth.PermStockAdjust); err != nil {
+		return 0, err
+	}
+
+	return s.repo.DeliverLowStockEvents(ctx, limit, func(event model.LowStockEvent) error {
+		deliverCtx, cancel := context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+
+		return s.notifier.NotifyLowStock(deliverCtx, event)
+	})
+}
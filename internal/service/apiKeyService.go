@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"main/internal/auth"
+	model "main/internal/models"
+	"main/internal/repository"
+	"main/internal/tenant"
+)
+
+// APIKeySubjectPrefix API 金鑰驗證後主體的前綴，與 JWT 的 sub 區分
+const APIKeySubjectPrefix = "api-key:"
+
+// APIKeyService 定義 API 金鑰的服務接口
+type APIKeyService interface {
+	// CreateAPIKey 生成並保存新的 API 金鑰，明文金鑰只在返回值中出現一次
+	CreateAPIKey(ctx context.Context, req model.CreateAPIKeyRequest) (model.CreatedAPIKey, error)
+	// ListAPIKeys 列出 ctx 租戶的金鑰
+	ListAPIKeys(ctx context.Context) ([]model.APIKey, error)
+	// RotateAPIKey 為金鑰生成新的明文，舊的明文立即失效
+	RotateAPIKey(ctx context.Context, id int64) (model.CreatedAPIKey, error)
+	// RevokeAPIKey 撤銷金鑰，撤銷後無法再使用或輪換
+	RevokeAPIKey(ctx context.Context, id int64) (model.APIKey, error)
+	// Verify 驗證 X-API-Key 標頭的金鑰，返回 api-key:<租戶>/<名稱> 為主體的身分
+	Verify(ctx context.Context, key string) (auth.Principal, error)
+}
+
+// DefaultAPIKeyService 實現默認 API 金鑰服務
+type DefaultAPIKeyService struct {
+	repo repository.APIKeyRepository
+}
+
+// NewAPIKeyService 創建新的 API 金鑰服務
+func NewAPIKeyService(repo repository.APIKeyRepository) APIKeyService {
+	return &DefaultAPIKeyService{
+		repo: repo,
+	}
+}
+
+// CreateAPIKey 驗證請求後生成金鑰，資料庫只保存雜湊；金鑰屬於 ctx 的租戶，不能為其他租戶創建
+func (s *DefaultAPIKeyService) CreateAPIKey(ctx context.Context, req model.CreateAPIKeyRequest) (model.CreatedAPIKey, error) {
+	if err := auth.Authorize(ctx, auth.PermAPIKeyManage); err != nil {
+		return model.CreatedAPIKey{}, err
+	}
+	if err := req.Validate(time.Now()); err != nil {
+		return model.CreatedAPIKey{}, err
+	}
+	keyTenant := tenant.ID(ctx)
+	if req.Tenant != "" && req.Tenant != keyTenant {
+		return model.CreatedAPIKey{}, &model.ValidationError{Message: fmt.Sprintf("不能為其他租戶創建 API 金鑰: %q，目前的租戶為 %q", req.Tenant, keyTenant)}
+	}
+
+	key, prefix, err := auth.GenerateAPIKey()
+	if err != nil {
+		return model.CreatedAPIKey{}, fmt.Errorf("生成 API 金鑰失敗: %w", err)
+	}
+
+	scopes := req.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	created, err := s.repo.CreateAPIKey(ctx, model.APIKey{
+		Name:      req.Name,
+		Prefix:    prefix,
+		Scopes:    scopes,
+		Tenant:    keyTenant,
+		ExpiresAt: req.ExpiresAt,
+	}, auth.HashAPIKey(key))
+	if err != nil {
+		return model.CreatedAPIKey{}, err
+	}
+
+	return model.CreatedAPIKey{APIKey: created, Key: key}, nil
+}
+
+// ListAPIKeys 列出 ctx 租戶的金鑰，不包含明文或雜湊
+func (s *DefaultAPIKeyService) ListAPIKeys(ctx context.Context) ([]model.APIKey, error) {
+	if err := auth.Authorize(ctx, auth.PermAPIKeyManage); err != nil {
+		return nil, err
+	}
+	return s.repo.ListAPIKeys(ctx)
+}
+
+// RotateAPIKey 生成新的金鑰取代原有的金鑰
+func (s *DefaultAPIKeyService) RotateAPIKey(ctx context.Context, id int64) (model.CreatedAPIKey, error) {
+	if err := auth.Authorize(ctx, auth.PermAPIKeyManage); err != nil {
+		return model.CreatedAPIKey{}, err
+	}
+
+	key, prefix, err := auth.GenerateAPIKey()
+	if err != nil {
+		return model.CreatedAPIKey{}, fmt.Errorf("生成 API 金鑰失敗: %w", err)
+	}
+
+	rotated, err := s.repo.RotateAPIKey(ctx, id, prefix, auth.HashAPIKey(key), time.Now())
+	if err != nil {
+		return model.CreatedAPIKey{}, err
+	}
+
+	return model.CreatedAPIKey{APIKey: rotated, Key: key}, nil
+}
+
+// RevokeAPIKey 撤銷金鑰
+func (s *DefaultAPIKeyService) RevokeAPIKey(ctx context.Context, id int64) (model.APIKey, error) {
+	if err := auth.Authorize(ctx, auth.PermAPIKeyManage); err != nil {
+		return model.APIKey{}, err
+	}
+	return s.repo.RevokeAPIKey(ctx, id, time.Now())
+}
+
+// Verify 不存在或已撤銷的金鑰返回 auth.ErrInvalidAPIKey，過期的返回 auth.ErrAPIKeyExpired；
+// 查詢失敗時返回 auth.ErrUnavailable。驗證成功後記錄最後使用時間，距離上次記錄不到
+// repository.APIKeyTouchInterval 時不寫入資料庫，記錄失敗不影響驗證結果
+func (s *DefaultAPIKeyService) Verify(ctx context.Context, key string) (auth.Principal, error) {
+	if !auth.ValidAPIKeyFormat(key) {
+		return auth.Principal{}, auth.ErrInvalidAPIKey
+	}
+
+	apiKey, err := s.repo.GetAPIKeyByHash(ctx, auth.HashAPIKey(key))
+	if err != nil {
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			return auth.Principal{}, auth.ErrInvalidAPIKey
+		}
+		return auth.Principal{}, fmt.Errorf("%w: %v", auth.ErrUnavailable, err)
+	}
+
+	now := time.Now()
+	if !apiKey.RevokedAt.IsZero() {
+		return auth.Principal{}, auth.ErrInvalidAPIKey
+	}
+	if apiKey.Expired(now) {
+		return auth.Principal{}, auth.ErrAPIKeyExpired
+	}
+
+	if apiKey.LastUsedAt.IsZero() || now.Sub(apiKey.LastUsedAt.Time) >= repository.APIKeyTouchInterval {
+		_ = s.repo.TouchAPIKey(ctx, apiKey.ID, now)
+	}
+
+	return auth.Principal{Subject: APIKeySubjectPrefix + apiKey.Tenant + "/" + apiKey.Name, Scopes: apiKey.Scopes, Tenant: apiKey.Tenant}, nil
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"main/internal/auth"
+	model "main/internal/models"
+	"main/internal/notify"
+	"main/internal/repository"
+	"time"
+)
+
+// ExpiryService 定義即將到期報表與過期清理的服務接口
+type ExpiryService interface {
+	// GetExpiringReport 依 query 的分組方式列出今天起 WithinDays 天內到期的庫存
+	GetExpiringReport(ctx context.Context, query model.ExpiringQuery) (model.ExpiringReport, error)
+	// SweepExpired 把到期日早於 now 當天的批次與產品標記為過期，並為每個標記的項目發出事件
+	SweepExpired(ctx context.Context, now time.Time) (model.ExpirySweepResult, error)
+}
+
+// DefaultExpiryService 實現默認過期服務
+type DefaultExpiryService struct {
+	repo     repository.ExpiryRepository
+	notifier notify.Notifier
+}
+
+// NewExpiryService 創建新的過期服務，notifier 為 nil 時不發出事件
+func NewExpiryService(repo repository.ExpiryRepository, notifier notify.Notifier) ExpiryService {
+	return &DefaultExpiryService{
+		repo:     repo,
+		notifier: notifier,
+	}
+}
+
+// GetExpiringReport 未指定時查詢 30 天內依倉庫分組的報表；日期以 UTC 計算
+func (s *DefaultExpiryService) GetExpiringReport(ctx context.Context, query model.ExpiringQuery) (model.ExpiringReport, error) {
+	if err := auth.Authorize(ctx, auth.PermProductRead); err != nil {
+		return model.ExpiringReport{}, err
+	}
+
+	if query.GroupBy == "" {
+		query.GroupBy = model.ExpiringByWarehouse
+	}
+	if err := query.Validate(); err != nil {
+		return model.ExpiringReport{}, err
+	}
+
+	today := model.DateOf(time.Now().UTC())
+	cutoff := model.Date{Time: today.AddDate(0, 0, query.WithinDays)}
+
+	var stocks []model.ExpiringStock
+	var err error
+	if query.GroupBy == model.ExpiringByLot {
+		stocks, err = s.repo.GetExpiringByLot(ctx, today, cutoff)
+	} else {
+		stocks, err = s.repo.GetExpiringByWarehouse(ctx, today, cutoff)
+	}
+	if err != nil {
+		return model.ExpiringReport{}, err
+	}
+
+	report := model.ExpiringReport{
+		WithinDays: query.WithinDays,
+		Cutoff:     cutoff,
+		GroupBy:    query.GroupBy,
+		Groups:     groupExpiring(stocks, query.GroupBy, today),
+	}
+	for _, group := range report.Groups {
+		report.Quantity += group.Quantity
+	}
+	return report, nil
+}
+
+// groupExpiring 依儲存庫返回的順序把相鄰且屬於同一倉庫或批次的行歸為一組
+func groupExpiring(stocks []model.ExpiringStock, groupBy string, today model.Date) []model.ExpiringGroup {
+	groups := []model.ExpiringGroup{}
+	for _, stock := range stocks {
+		key := model.ExpiringGroup{WarehouseID: stock.WarehouseID, WarehouseCode: stock.WarehouseCode, WarehouseName: stock.WarehouseName}
+		if groupBy == model.ExpiringByLot {
+			key = model.ExpiringGroup{LotID: stock.LotID, LotNumber: stock.LotNumber}
+		}
+
+		last := len(groups) - 1
+		if last < 0 || groups[last].WarehouseID != key.WarehouseID || groups[last].LotID != key.LotID {
+			key.Items = []model.ExpiringItem{}
+			groups = append(groups, key)
+			last++
+		}
+
+		groups[last].Quantity += stock.Quantity
+		groups[last].Items = append(groups[last].Items, model.ExpiringItem{
+			ProductID:  stock.ProductID,
+			SkuCode:    stock.SkuCode,
+			SkuName:    stock.SkuName,
+			Expiration: stock.Expiration,
+			DaysLeft:   int(stock.Expiration.Sub(today.Time).Hours() / 24),
+			Quantity:   stock.Quantity,
+		})
+	}
+	return groups
+}
+
+// SweepExpired 事件的派送失敗不影響已提交的標記，下次清理也不會重新發出
+func (s *DefaultExpiryService) SweepExpired(ctx context.Context, now time.Time) (model.ExpirySweepResult, error) {
+	if err := auth.Authorize(ctx, auth.PermStockAdjust); err != nil {
+		return model.ExpirySweepResult{}, err
+	}
+
+	expired, err := s.repo.MarkExpired(ctx, model.DateOf(now.UTC()), now)
+	if err != nil {
+		return model.ExpirySweepResult{}, err
+	}
+
+	var result model.ExpirySweepResult
+	for _, stock := range expired {
+		if stock.LotID != 0 {
+			result.Lots++
+		} else {
+			result.Products++
+		}
+		result.Quantity += stock.Quantity
+
+		if s.notifier != nil {
+			_ = s.notifier.NotifyExpired(ctx, model.NewExpiredStockEvent(stock, now))
+		}
+	}
+	return result, nil
+}
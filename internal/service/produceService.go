@@ -1,17 +1,55 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"main/internal/auth"
 	model "main/internal/models"
 	"main/internal/repository"
+	"time"
 )
 
 // ProductService 定義產品服務接口
 type ProductService interface {
-	GetProducts() ([]model.Product, error)
-	GetProduct(id int64) (model.Product, error)
-	CreateProduct(input model.Product) (model.Product, error)
-	UpdateProduct(id int64, input model.Product) (model.Product, error)
-	DeleteProduct(id int64) error
+	GetProducts(ctx context.Context, query model.ProductQuery) (model.ProductPage, error)
+	GetProduct(ctx context.Context, id int64) (model.Product, error)
+	GetProductBySku(ctx context.Context, skuCode string) (model.Product, error)
+	CreateProduct(ctx context.Context, input model.Product) (model.Product, error)
+	UpdateProduct(ctx context.Context, id int64, version int, input model.Product) (model.Product, error)
+	MergePatchProduct(ctx context.Context, id int64, version int, patch model.ProductPatch) (model.Product, error)
+	JSONPatchProduct(ctx context.Context, id int64, version int, ops []model.JSONPatchOperation) (model.Product, error)
+	// DeleteProduct 將產品移到回收站
+	DeleteProduct(ctx context.Context, id int64, version int) error
+	// UpsertProductBySku 以輸入完整替換 sku_code 對應的產品，不存在時創建，返回是否為新建；
+	// version 大於 0 時產品必須存在且版本一致
+	UpsertProductBySku(ctx context.Context, skuCode string, version int, input model.Product) (model.Product, bool, error)
+	// AdjustStock 以帶正負號的差額原子地調整庫存，無需先讀取產品
+	AdjustStock(ctx context.Context, id int64, delta int) (model.Product, error)
+
+	// 批量操作：atomic 為 true 時全部成功或全部不生效，失敗時返回 *model.BatchItemError；
+	// 為 false 時逐項處理，每項的錯誤記錄在對應的 BatchOutcome 中
+	BatchCreateProducts(ctx context.Context, inputs []model.Product, atomic bool) ([]model.BatchOutcome, error)
+	BatchUpdateProducts(ctx context.Context, inputs []model.Product, atomic bool) ([]model.BatchOutcome, error)
+	BatchDeleteProducts(ctx context.Context, refs []model.ProductRef, atomic bool) ([]model.BatchOutcome, error)
+
+	// 回收站：列出、還原與永久刪除軟刪除的產品
+	GetDeletedProducts(ctx context.Context, query model.ProductQuery) (model.ProductPage, error)
+	RestoreProduct(ctx context.Context, id int64) (model.Product, error)
+	PurgeProduct(ctx context.Context, id int64) error
+	// PurgeDeletedProducts 永久刪除在回收站中超過 retention 的產品，返回刪除的數量
+	PurgeDeletedProducts(ctx context.Context, retention time.Duration) (int64, error)
+
+	// 變更歷史：GetProductHistory 列出產品的變更紀錄，GetProductAsOf 返回產品在某個時間點的內容
+	GetProductHistory(ctx context.Context, id int64, query model.ProductQuery) (model.ProductHistoryPage, error)
+	GetProductAsOf(ctx context.Context, id int64, asOf time.Time) (model.Product, error)
+
+	// ExportProducts 依過濾與排序條件逐筆輸出所有產品（忽略分頁）
+	ExportProducts(ctx context.Context, query model.ProductQuery, fn func(model.Product) error) error
+	// ImportProducts 驗證匯入的列並依 sku_code 更新或創建產品；dryRun 時只驗證不寫入
+	ImportProducts(ctx context.Context, rows []model.ImportRow, dryRun bool) (model.ImportReport, error)
+
+	// GetLowStockProducts 列出庫存低於補貨點的產品，缺口最大的在前
+	GetLowStockProducts(ctx context.Context, query model.ProductQuery) (model.ProductPage, error)
 }
 
 // DefaultProductService 實現默認產品服務
@@ -19,47 +57,282 @@ type DefaultProductService struct {
 	repo repository.ProductRepository
 }
 
-// NewProductService 創建新的產品服務
+// NewProductService 創建新的產品服務；低庫存事件由資料庫觸發器記錄，不經過服務層
 func NewProductService(repo repository.ProductRepository) ProductService {
 	return &DefaultProductService{
 		repo: repo,
 	}
 }
 
-// GetProducts 獲取所有產品
-func (s *DefaultProductService) GetProducts() ([]model.Product, error) {
-	return s.repo.GetAll()
+// GetProducts 依查詢選項獲取產品分頁
+func (s *DefaultProductService) GetProducts(ctx context.Context, query model.ProductQuery) (model.ProductPage, error) {
+	if err := auth.Authorize(ctx, auth.PermProductRead); err != nil {
+		return model.ProductPage{}, err
+	}
+
+	// 套用預設分頁與排序
+	if query.PageSize <= 0 {
+		query.PageSize = model.DefaultPageSize
+	}
+	if query.PageSize > model.MaxPageSize {
+		query.PageSize = model.MaxPageSize
+	}
+	if query.Offset < 0 {
+		query.Offset = 0
+	}
+	if query.SortBy == "" {
+		query.SortBy = "id"
+	}
+	if query.SortDir == "" {
+		query.SortDir = model.SortAsc
+	}
+
+	if query.Keyset != nil {
+		return s.getProductsByKeyset(ctx, query)
+	}
+
+	products, total, err := s.repo.GetAll(ctx, query)
+	if err != nil {
+		return model.ProductPage{}, err
+	}
+
+	if err := s.attachLocations(ctx, products); err != nil {
+		return model.ProductPage{}, err
+	}
+
+	return model.ProductPage{
+		Items:    products,
+		Total:    total,
+		PageSize: query.PageSize,
+		Offset:   query.Offset,
+		HasNext:  query.Offset+len(products) < total,
+		HasPrev:  query.Offset > 0,
+	}, nil
+}
+
+// getProductsByKeyset 鍵集分頁：多讀取一筆以判斷翻頁方向上是否還有資料
+func (s *DefaultProductService) getProductsByKeyset(ctx context.Context, query model.ProductQuery) (model.ProductPage, error) {
+	pageSize := query.PageSize
+	query.PageSize++
+
+	products, total, err := s.repo.GetAll(ctx, query)
+	if err != nil {
+		return model.ProductPage{}, err
+	}
+
+	hasMore := len(products) > pageSize
+	backward := query.Keyset.Backward
+	if hasMore {
+		if backward {
+			products = products[1:]
+		} else {
+			products = products[:pageSize]
+		}
+	}
+
+	if err := s.attachLocations(ctx, products); err != nil {
+		return model.ProductPage{}, err
+	}
+
+	return model.ProductPage{
+		Items:    products,
+		Total:    total,
+		PageSize: pageSize,
+		HasNext:  hasMore || backward,
+		HasPrev:  hasMore || !backward,
+	}, nil
+}
+
+// GetProduct 獲取特定產品，包含各儲位的庫存
+func (s *DefaultProductService) GetProduct(ctx context.Context, id int64) (model.Product, error) {
+	if err := auth.Authorize(ctx, auth.PermProductRead); err != nil {
+		return model.Product{}, err
+	}
+
+	return s.withLocations(ctx)(s.repo.GetByID(ctx, id))
 }
 
-// GetProduct 獲取特定產品
-func (s *DefaultProductService) GetProduct(id int64) (model.Product, error) {
-	return s.repo.GetByID(id)
+// GetProductBySku 依 sku_code 獲取產品，包含各儲位的庫存
+func (s *DefaultProductService) GetProductBySku(ctx context.Context, skuCode string) (model.Product, error) {
+	if err := auth.Authorize(ctx, auth.PermProductRead); err != nil {
+		return model.Product{}, err
+	}
+
+	return s.withLocations(ctx)(s.repo.GetBySku(ctx, skuCode))
+}
+
+// withLocations 在讀取成功時為單個產品附加各儲位的庫存
+func (s *DefaultProductService) withLocations(ctx context.Context) func(model.Product, error) (model.Product, error) {
+	return func(product model.Product, err error) (model.Product, error) {
+		if err != nil {
+			return model.Product{}, err
+		}
+
+		products := []model.Product{product}
+		if err := s.attachLocations(ctx, products); err != nil {
+			return model.Product{}, err
+		}
+		return products[0], nil
+	}
+}
+
+// attachLocations 以單次查詢為產品列表附加各儲位的庫存
+func (s *DefaultProductService) attachLocations(ctx context.Context, products []model.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(products))
+	for i, product := range products {
+		ids[i] = int64(product.ID)
+	}
+
+	stock, err := s.repo.GetLocationStock(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range products {
+		products[i].Locations = stock[int64(products[i].ID)]
+	}
+	return nil
 }
 
 // CreateProduct 創建新產品
-func (s *DefaultProductService) CreateProduct(input model.Product) (model.Product, error) {
+func (s *DefaultProductService) CreateProduct(ctx context.Context, input model.Product) (model.Product, error) {
+	if err := auth.Authorize(ctx, auth.PermProductWrite); err != nil {
+		return model.Product{}, err
+	}
+
 	// 這裡可以添加業務邏輯，如庫存檢查、價格驗證等
-	return s.repo.Create(input)
+	return s.repo.Create(ctx, input)
 }
 
-// UpdateProduct 更新產品
-func (s *DefaultProductService) UpdateProduct(id int64, input model.Product) (model.Product, error) {
+// UpdateProduct 以輸入完整替換產品，未提供的欄位會被清空，version 為客戶端持有的版本（0 表示不檢查）
+func (s *DefaultProductService) UpdateProduct(ctx context.Context, id int64, version int, input model.Product) (model.Product, error) {
+	if err := auth.Authorize(ctx, auth.PermProductWrite); err != nil {
+		return model.Product{}, err
+	}
+
+	existing, err := s.getForUpdate(ctx, id, version)
+	if err != nil {
+		return model.Product{}, err
+	}
+
+	return s.applyPatch(ctx, id, existing, version, model.ReplacePatch(input))
+}
+
+// MergePatchProduct 只更新修補中出現的欄位（JSON Merge Patch）
+func (s *DefaultProductService) MergePatchProduct(ctx context.Context, id int64, version int, patch model.ProductPatch) (model.Product, error) {
+	if err := auth.Authorize(ctx, auth.PermProductWrite); err != nil {
+		return model.Product{}, err
+	}
+
+	existing, err := s.getForUpdate(ctx, id, version)
+	if err != nil {
+		return model.Product{}, err
+	}
+
+	return s.applyPatch(ctx, id, existing, version, patch)
+}
+
+// JSONPatchProduct 在目前的產品上執行 JSON Patch 操作
+func (s *DefaultProductService) JSONPatchProduct(ctx context.Context, id int64, version int, ops []model.JSONPatchOperation) (model.Product, error) {
+	if err := auth.Authorize(ctx, auth.PermProductWrite); err != nil {
+		return model.Product{}, err
+	}
+
+	existing, err := s.getForUpdate(ctx, id, version)
+	if err != nil {
+		return model.Product{}, err
+	}
+
+	patch, err := model.ApplyJSONPatch(existing, ops)
+	if err != nil {
+		return model.Product{}, err
+	}
+
+	// 操作（特別是 test）是依據讀取到的版本計算的，寫入時必須確認版本未變
+	return s.applyPatch(ctx, id, existing, existing.Version, patch)
+}
+
+// getForUpdate 讀取待更新的產品並檢查客戶端持有的版本
+func (s *DefaultProductService) getForUpdate(ctx context.Context, id int64, version int) (model.Product, error) {
 	// 先檢查產品是否存在
-	_, err := s.repo.GetByID(id)
+	existing, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return model.Product{}, err
 	}
+	if version > 0 && existing.Version != version {
+		return model.Product{}, repository.ErrVersionConflict
+	}
+
+	return existing, nil
+}
+
+// applyPatch 驗證套用修補後的產品並寫入
+func (s *DefaultProductService) applyPatch(ctx context.Context, id int64, existing model.Product, version int, patch model.ProductPatch) (model.Product, error) {
+	merged, err := patch.Apply(existing)
+	if err != nil {
+		return model.Product{}, err
+	}
+	if err := model.ValidateProduct(merged); err != nil {
+		return model.Product{}, err
+	}
+
+	// 沒有任何欄位時不寫入，也不遞增版本
+	if patch.IsEmpty() {
+		return existing, nil
+	}
 
-	return s.repo.UpdateNonBlank(id, input)
+	return s.repo.Update(ctx, id, version, patch)
 }
 
-// DeleteProduct 刪除產品
-func (s *DefaultProductService) DeleteProduct(id int64) error {
+// DeleteProduct 將產品移到回收站（軟刪除），version 為客戶端持有的版本（0 表示不檢查）
+func (s *DefaultProductService) DeleteProduct(ctx context.Context, id int64, version int) error {
+	if err := auth.Authorize(ctx, auth.PermProductDelete); err != nil {
+		return err
+	}
+
 	// 先檢查產品是否存在
-	_, err := s.repo.GetByID(id)
+	existing, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return err
 	}
+	if version > 0 && existing.Version != version {
+		return repository.ErrVersionConflict
+	}
+
+	return s.repo.Delete(ctx, id, version)
+}
+
+// UpsertProductBySku 依 sku_code 更新或創建產品
+func (s *DefaultProductService) UpsertProductBySku(ctx context.Context, skuCode string, version int, input model.Product) (model.Product, bool, error) {
+	if err := auth.Authorize(ctx, auth.PermProductWrite); err != nil {
+		return model.Product{}, false, err
+	}
+
+	input.SkuCode = skuCode
+	if err := model.ValidateProduct(input); err != nil {
+		return model.Product{}, false, err
+	}
+
+	if version <= 0 {
+		return s.repo.UpsertBySku(ctx, input)
+	}
+
+	// 帶版本的請求只能更新已存在的產品，不存在時視為版本不符
+	existing, err := s.repo.GetBySku(ctx, skuCode)
+	if errors.Is(err, repository.ErrProductNotFound) {
+		return model.Product{}, false, repository.ErrVersionConflict
+	}
+	if err != nil {
+		return model.Product{}, false, err
+	}
+	if existing.Version != version {
+		return model.Product{}, false, repository.ErrVersionConflict
+	}
 
-	return s.repo.Delete(id)
+	product, err := s.repo.Update(ctx, int64(existing.ID), version, model.ReplacePatch(input))
+	return product, false, err
 }
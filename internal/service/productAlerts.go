@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+	"main/internal/auth"
+	model "main/internal/models"
+)
+
+// GetLowStockProducts 列出庫存低於補貨點的產品，只支援偏移分頁
+func (s *DefaultProductService) GetLowStockProducts(ctx context.Context, query model.ProductQuery) (model.ProductPage, error) {
+	if err := auth.Authorize(ctx, auth.PermProductRead); err != nil {
+		return model.ProductPage{}, err
+	}
+
+	if query.PageSize <= 0 {
+		query.PageSize = model.DefaultPageSize
+	}
+	if query.PageSize > model.MaxPageSize {
+		query.PageSize = model.MaxPageSize
+	}
+	if query.Offset < 0 {
+		query.Offset = 0
+	}
+
+	products, total, err := s.repo.GetLowStock(ctx, query)
+	if err != nil {
+		return model.ProductPage{}, err
+	}
+
+	return model.ProductPage{
+		Items:    products,
+		Total:    total,
+		PageSize: query.PageSize,
+		Offset:   query.Offset,
+		HasNext:  query.Offset+len(products) < total,
+		HasPrev:  query.Offset > 0,
+	}, nil
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"main/internal/auth"
+	model "main/internal/models"
+)
+
+// errBatchVersionRequired 批量更新或刪除的項目缺少版本號
+var errBatchVersionRequired = &model.ValidationError{Message: "批量更新或刪除時每項都必須提供 id 與 version"}
+
+// BatchCreateProducts 批量創建產品
+func (s *DefaultProductService) BatchCreateProducts(ctx context.Context, inputs []model.Product, atomic bool) ([]model.BatchOutcome, error) {
+	if err := auth.Authorize(ctx, auth.PermProductWrite); err != nil {
+		return nil, err
+	}
+
+	outcomes := make([]model.BatchOutcome, len(inputs))
+
+	// 先驗證所有項目，只寫入有效的項目
+	valid := make([]model.Product, 0, len(inputs))
+	validIndex := make([]int, 0, len(inputs))
+	today := model.Today()
+	for i, input := range inputs {
+		if err := model.ValidateNewProduct(input, today); err != nil {
+			if atomic {
+				return nil, &model.BatchItemError{Index: i, Err: err}
+			}
+			outcomes[i].Err = err
+			continue
+		}
+		valid = append(valid, input)
+		validIndex = append(validIndex, i)
+	}
+
+	created, err := s.repo.CreateBatch(ctx, valid)
+	if err != nil {
+		if atomic || ctx.Err() != nil {
+			return nil, err
+		}
+
+		// 整批寫入失敗時逐項重試，找出是哪些項目導致失敗
+		for _, i := range validIndex {
+			outcomes[i].Product, outcomes[i].Err = s.CreateProduct(ctx, inputs[i])
+		}
+		return outcomes, nil
+	}
+
+	for n, i := range validIndex {
+		outcomes[i].Product = created[n]
+	}
+
+	return outcomes, nil
+}
+
+// BatchUpdateProducts 批量以完整內容替換產品，每項需帶 id 與 version
+func (s *DefaultProductService) BatchUpdateProducts(ctx context.Context, inputs []model.Product, atomic bool) ([]model.BatchOutcome, error) {
+	if err := auth.Authorize(ctx, auth.PermProductWrite); err != nil {
+		return nil, err
+	}
+
+	if !atomic {
+		outcomes := make([]model.BatchOutcome, len(inputs))
+		for i, input := range inputs {
+			if err := validateBatchUpdate(input); err != nil {
+				outcomes[i].Err = err
+				continue
+			}
+			outcomes[i].Product, outcomes[i].Err = s.UpdateProduct(ctx, int64(input.ID), input.Version, input)
+		}
+		return outcomes, nil
+	}
+
+	items := make([]model.ProductBatchUpdate, len(inputs))
+	for i, input := range inputs {
+		if err := validateBatchUpdate(input); err != nil {
+			return nil, &model.BatchItemError{Index: i, Err: err}
+		}
+		items[i] = model.ProductBatchUpdate{ID: int64(input.ID), Version: input.Version, Patch: model.ReplacePatch(input)}
+	}
+
+	updated, err := s.repo.UpdateBatch(ctx, items)
+	if err != nil {
+		return nil, err
+	}
+
+	outcomes := make([]model.BatchOutcome, len(updated))
+	for i, product := range updated {
+		outcomes[i].Product = product
+	}
+
+	return outcomes, nil
+}
+
+// BatchDeleteProducts 批量刪除產品，每項需帶 id 與 version
+func (s *DefaultProductService) BatchDeleteProducts(ctx context.Context, refs []model.ProductRef, atomic bool) ([]model.BatchOutcome, error) {
+	if err := auth.Authorize(ctx, auth.PermProductDelete); err != nil {
+		return nil, err
+	}
+
+	outcomes := make([]model.BatchOutcome, len(refs))
+
+	for i, ref := range refs {
+		if ref.ID <= 0 || ref.Version <= 0 {
+			if atomic {
+				return nil, &model.BatchItemError{Index: i, Err: errBatchVersionRequired}
+			}
+			outcomes[i].Err = errBatchVersionRequired
+			continue
+		}
+		if !atomic {
+			outcomes[i].Err = s.DeleteProduct(ctx, ref.ID, ref.Version)
+		}
+		outcomes[i].Product = model.Product{ID: int(ref.ID), Version: ref.Version}
+	}
+
+	if atomic {
+		if err := s.repo.DeleteBatch(ctx, refs); err != nil {
+			return nil, err
+		}
+	}
+
+	return outcomes, nil
+}
+
+// validateBatchUpdate 驗證批量更新中的單項
+func validateBatchUpdate(input model.Product) error {
+	if input.ID <= 0 || input.Version <= 0 {
+		return errBatchVersionRequired
+	}
+	return model.ValidateProduct(input)
+}
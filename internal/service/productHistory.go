@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"main/internal/auth"
+	model "main/internal/models"
+	"main/internal/repository"
+	"time"
+)
+
+// GetProductHistory 依時間由新到舊列出產品的變更歷史，沒有任何紀錄時返回 ErrProductNotFound
+func (s *DefaultProductService) GetProductHistory(ctx context.Context, id int64, query model.ProductQuery) (model.ProductHistoryPage, error) {
+	if err := auth.Authorize(ctx, auth.PermProductRead); err != nil {
+		return model.ProductHistoryPage{}, err
+	}
+
+	if query.PageSize <= 0 {
+		query.PageSize = model.DefaultPageSize
+	}
+	if query.PageSize > model.MaxPageSize {
+		query.PageSize = model.MaxPageSize
+	}
+	if query.Offset < 0 {
+		query.Offset = 0
+	}
+
+	history, total, err := s.repo.GetHistory(ctx, id, query)
+	if err != nil {
+		return model.ProductHistoryPage{}, err
+	}
+
+	if total == 0 {
+		return model.ProductHistoryPage{}, repository.ErrProductNotFound
+	}
+
+	return model.ProductHistoryPage{
+		Items:    history,
+		Total:    total,
+		PageSize: query.PageSize,
+		Offset:   query.Offset,
+		HasNext:  query.Offset+len(history) < total,
+		HasPrev:  query.Offset > 0,
+	}, nil
+}
+
+// GetProductAsOf 返回產品在 asOf 時的內容
+func (s *DefaultProductService) GetProductAsOf(ctx context.Context, id int64, asOf time.Time) (model.Product, error) {
+	if err := auth.Authorize(ctx, auth.PermProductRead); err != nil {
+		return model.Product{}, err
+	}
+
+	return s.repo.GetAsOf(ctx, id, asOf)
+}
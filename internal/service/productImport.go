@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"main/internal/auth"
+	model "main/internal/models"
+)
+
+// ExportProducts 依過濾與排序條件逐筆輸出所有產品
+func (s *DefaultProductService) ExportProducts(ctx context.Context, query model.ProductQuery, fn func(model.Product) error) error {
+	if err := auth.Authorize(ctx, auth.PermProductRead); err != nil {
+		return err
+	}
+
+	if query.SortBy == "" {
+		query.SortBy = "id"
+	}
+	if query.SortDir == "" {
+		query.SortDir = model.SortAsc
+	}
+
+	return s.repo.Stream(ctx, query, fn)
+}
+
+// ImportProducts 逐列驗證並依 sku_code 更新或創建產品
+// 單列失敗不影響其他列，失敗原因記錄在報告中；資料庫連接錯誤或請求取消時中止並返回錯誤
+func (s *DefaultProductService) ImportProducts(ctx context.Context, rows []model.ImportRow, dryRun bool) (model.ImportReport, error) {
+	if err := auth.Authorize(ctx, auth.PermProductWrite); err != nil {
+		return model.ImportReport{}, err
+	}
+
+	report := model.ImportReport{
+		DryRun: dryRun,
+		Total:  len(rows),
+		Errors: []model.ImportRowError{},
+	}
+
+	fail := func(row model.ImportRow, err error) {
+		report.Failed++
+		report.Errors = append(report.Errors, model.ImportRowError{
+			Line:    row.Line,
+			SkuCode: row.Product.SkuCode,
+			Error:   err.Error(),
+		})
+	}
+
+	// 先驗證所有列，同一個 sku_code 在檔案中只能出現一次
+	valid := make([]model.ImportRow, 0, len(rows))
+	seen := map[string]int{}
+	for _, row := range rows {
+		if row.Err != nil {
+			fail(row, row.Err)
+			continue
+		}
+		if err := model.ValidateProduct(row.Product); err != nil {
+			fail(row, err)
+			continue
+		}
+		if line, ok := seen[row.Product.SkuCode]; ok {
+			fail(row, fmt.Errorf("sku_code 與第 %d 行重複", line))
+			continue
+		}
+		seen[row.Product.SkuCode] = row.Line
+		valid = append(valid, row)
+	}
+
+	if dryRun {
+		codes := make([]string, 0, len(valid))
+		for _, row := range valid {
+			codes = append(codes, row.Product.SkuCode)
+		}
+		existing, err := s.repo.ExistingSkuCodes(ctx, codes)
+		if err != nil {
+			return model.ImportReport{}, err
+		}
+		for _, row := range valid {
+			if existing[row.Product.SkuCode] {
+				report.Updated++
+			} else {
+				report.Created++
+			}
+		}
+		return report, nil
+	}
+
+	for _, row := range valid {
+		_, created, err := s.repo.UpsertBySku(ctx, row.Product)
+		if err != nil {
+			if ctx.Err() != nil {
+				return model.ImportReport{}, err
+			}
+			fail(row, err)
+			continue
+		}
+		if created {
+			report.Created++
+		} else {
+			report.Updated++
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,20 @@
+package service
+
+import (
+	"context"
+	"main/internal/auth"
+	model "main/internal/models"
+)
+
+// AdjustStock 驗證差額後原子地調整庫存
+func (s *DefaultProductService) AdjustStock(ctx context.Context, id int64, delta int) (model.Product, error) {
+	if err := auth.Authorize(ctx, auth.PermStockAdjust); err != nil {
+		return model.Product{}, err
+	}
+
+	if delta == 0 {
+		return model.Product{}, &model.ValidationError{Message: "delta 不能為 0"}
+	}
+
+	return s.repo.AdjustStock(ctx, id, delta)
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"main/internal/auth"
+	model "main/internal/models"
+	"time"
+)
+
+// GetDeletedProducts 列出回收站中的產品，依刪除時間由新到舊排序
+func (s *DefaultProductService) GetDeletedProducts(ctx context.Context, query model.ProductQuery) (model.ProductPage, error) {
+	if err := auth.Authorize(ctx, auth.PermProductRead); err != nil {
+		return model.ProductPage{}, err
+	}
+
+	if query.PageSize <= 0 {
+		query.PageSize = model.DefaultPageSize
+	}
+	if query.PageSize > model.MaxPageSize {
+		query.PageSize = model.MaxPageSize
+	}
+	if query.Offset < 0 {
+		query.Offset = 0
+	}
+
+	products, total, err := s.repo.GetDeleted(ctx, query)
+	if err != nil {
+		return model.ProductPage{}, err
+	}
+
+	return model.ProductPage{
+		Items:    products,
+		Total:    total,
+		PageSize: query.PageSize,
+		Offset:   query.Offset,
+		HasNext:  query.Offset+len(products) < total,
+		HasPrev:  query.Offset > 0,
+	}, nil
+}
+
+// RestoreProduct 將回收站中的產品還原
+func (s *DefaultProductService) RestoreProduct(ctx context.Context, id int64) (model.Product, error) {
+	if err := auth.Authorize(ctx, auth.PermProductDelete); err != nil {
+		return model.Product{}, err
+	}
+
+	return s.repo.Restore(ctx, id)
+}
+
+// PurgeProduct 永久刪除回收站中的產品，只能刪除已軟刪除的產品
+func (s *DefaultProductService) PurgeProduct(ctx context.Context, id int64) error {
+	if err := auth.Authorize(ctx, auth.PermProductPurge); err != nil {
+		return err
+	}
+
+	return s.repo.Purge(ctx, id)
+}
+
+// PurgeDeletedProducts 永久刪除在回收站中超過 retention 的產品
+func (s *DefaultProductService) PurgeDeletedProducts(ctx context.Context, retention time.Duration) (int64, error) {
+	if err := auth.Authorize(ctx, auth.PermProductPurge); err != nil {
+		return 0, err
+	}
+
+	if retention <= 0 {
+		return 0, errors.New("保留期必須大於 0")
+	}
+
+	return s.repo.PurgeDeletedBefore(ctx, time.Now().Add(-retention))
+}
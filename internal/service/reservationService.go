@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"main/internal/auth"
+	model "main/internal/models"
+	"main/internal/repository"
+	"time"
+)
+
+// 預留的有效期限制
+const (
+	DefaultReservationTTL = 15 * time.Minute
+	MaxReservationTTL     = 24 * time.Hour
+
+	// maxReferenceLength 與 stock_reservations.reference 欄位一致
+	maxReferenceLength = 200
+	// expireBatchSize 清理過期預留時每批處理的數量，避免單一交易鎖定過多行
+	expireBatchSize = 500
+)
+
+// ReservationService 定義庫存預留服務接口
+type ReservationService interface {
+	// Reserve 創建預留，返回的布爾值表示是否新建；以相同 reference 重送時返回既有的預留
+	Reserve(ctx context.Context, productID int64, req model.ReservationRequest) (model.StockReservation, bool, error)
+	GetReservation(ctx context.Context, id int64) (model.StockReservation, error)
+	// ListReservations 列出產品未到期的 active 預留
+	ListReservations(ctx context.Context, productID int64) ([]model.StockReservation, error)
+	// GetAvailability 獲取產品的可承諾量
+	GetAvailability(ctx context.Context, productID int64) (model.StockAvailability, error)
+	// Commit 提交預留並出庫，重複提交返回同一結果
+	Commit(ctx context.Context, id int64, req model.CommitReservationRequest) (model.StockReservation, error)
+	// Release 釋放預留，重複釋放返回同一結果
+	Release(ctx context.Context, id int64) (model.StockReservation, error)
+	// ExpireReservations 將 now 之前到期的 active 預留全部標記為過期，返回處理的數量
+	ExpireReservations(ctx context.Context, now time.Time) (int64, error)
+}
+
+// DefaultReservationService 實現默認庫存預留服務
+type DefaultReservationService struct {
+	repo repository.ReservationRepository
+}
+
+// NewReservationService 創建新的庫存預留服務
+func NewReservationService(repo repository.ReservationRepository) ReservationService {
+	return &DefaultReservationService{
+		repo: repo,
+	}
+}
+
+// Reserve 驗證數量、reference 與有效期後創建預留
+func (s *DefaultReservationService) Reserve(ctx context.Context, productID int64, req model.ReservationRequest) (model.StockReservation, bool, error) {
+	if err := auth.Authorize(ctx, auth.PermStockAdjust); err != nil {
+		return model.StockReservation{}, false, err
+	}
+
+	if req.Quantity <= 0 {
+		return model.StockReservation{}, false, &model.ValidationError{Message: "預留數量必須大於 0"}
+	}
+	if req.Reference == "" {
+		return model.StockReservation{}, false, &model.ValidationError{Message: "reference 不能為空"}
+	}
+	if len(req.Reference) > maxReferenceLength {
+		return model.StockReservation{}, false, &model.ValidationError{Message: fmt.Sprintf("reference 不能超過 %d 個字元", maxReferenceLength)}
+	}
+
+	ttl := time.Duration(req.TTLSeconds) * time.Second
+	if req.TTLSeconds == 0 {
+		ttl = DefaultReservationTTL
+	}
+	if ttl <= 0 || ttl > MaxReservationTTL {
+		return model.StockReservation{}, false, &model.ValidationError{Message: fmt.Sprintf("ttl_seconds 必須介於 1 與 %d 之間", int(MaxReservationTTL.Seconds()))}
+	}
+
+	return s.repo.Reserve(ctx, model.StockReservation{
+		ProductID: productID,
+		Quantity:  req.Quantity,
+		Reference: req.Reference,
+		ExpiresAt: model.Timestamp{Time: time.Now().Add(ttl)},
+	})
+}
+
+// GetReservation 依 ID 獲取預留
+func (s *DefaultReservationService) GetReservation(ctx context.Context, id int64) (model.StockReservation, error) {
+	if err := auth.Authorize(ctx, auth.PermProductRead); err != nil {
+		return model.StockReservation{}, err
+	}
+
+	return s.repo.GetReservation(ctx, id)
+}
+
+// ListReservations 列出產品未到期的 active 預留
+func (s *DefaultReservationService) ListReservations(ctx context.Context, productID int64) ([]model.StockReservation, error) {
+	if err := auth.Authorize(ctx, auth.PermProductRead); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListActiveReservations(ctx, productID)
+}
+
+// GetAvailability 獲取產品的可承諾量
+func (s *DefaultReservationService) GetAvailability(ctx context.Context, productID int64) (model.StockAvailability, error) {
+	if err := auth.Authorize(ctx, auth.PermProductRead); err != nil {
+		return model.StockAvailability{}, err
+	}
+
+	return s.repo.GetAvailability(ctx, productID)
+}
+
+// Commit 提交預留並出庫
+func (s *DefaultReservationService) Commit(ctx context.Context, id int64, req model.CommitReservationRequest) (model.StockReservation, error) {
+	if err := auth.Authorize(ctx, auth.PermStockAdjust); err != nil {
+		return model.StockReservation{}, err
+	}
+
+	if req.LocationID < 0 {
+		return model.StockReservation{}, &model.ValidationError{Message: "location_id 必須為正整數"}
+	}
+	return s.repo.Commit(ctx, id, req.LocationID)
+}
+
+// Release 釋放預留
+func (s *DefaultReservationService) Release(ctx context.Context, id int64) (model.StockReservation, error) {
+	if err := auth.Authorize(ctx, auth.PermStockAdjust); err != nil {
+		return model.StockReservation{}, err
+	}
+
+	return s.repo.Release(ctx, id)
+}
+
+// ExpireReservations 分批清理過期的預留，直到某一批未滿為止
+func (s *DefaultReservationService) ExpireReservations(ctx context.Context, now time.Time) (int64, error) {
+	if err := auth.Authorize(ctx, auth.PermStockAdjust); err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for {
+		expired, err := s.repo.ExpireReservations(ctx, now, expireBatchSize)
+		if err != nil {
+			return total, err
+		}
+		total += expired
+
+		if expired < expireBatchSize {
+			return total, nil
+		}
+	}
+}
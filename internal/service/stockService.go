@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"main/internal/auth"
+	model "main/internal/models"
+	"main/internal/repository"
+)
+
+// StockService 定義庫存異動服務接口，所有庫存變化都記錄在異動帳中
+type StockService interface {
+	// Receive 入庫，Quantity 必須為正數，原因預設為 purchase；指定 LotNumber 時放入該批次
+	Receive(ctx context.Context, productID int64, req model.StockRequest) (model.StockMovement, error)
+	// Issue 出庫，Quantity 必須為正數，原因預設為 sale；依 FEFO 扣減批次，庫存不足時返回 ErrInsufficientStock
+	Issue(ctx context.Context, productID int64, req model.StockRequest) (model.StockMovement, error)
+	// Adjust 以帶正負號的差額調整庫存，原因預設為 correction
+	Adjust(ctx context.Context, productID int64, req model.StockRequest) (model.StockMovement, error)
+	// Transfer 將庫存轉到同一產品的其他儲位，返回轉出與轉入兩筆異動；
+	// 兩端的儲位必須不同
+	Transfer(ctx context.Context, productID int64, req model.TransferRequest) ([]model.StockMovement, error)
+	// GetMovements 依時間由新到舊列出產品的庫存異動
+	GetMovements(ctx context.Context, productID int64, query model.ProductQuery) (model.StockMovementPage, error)
+	// GetMovement 獲取單筆異動，包含其增減的批次
+	GetMovement(ctx context.Context, productID int64, movementID int64) (model.StockMovement, error)
+	// GetLots 依 FEFO 順序列出產品的批次庫存
+	GetLots(ctx context.Context, productID int64) (model.ProductLotStock, error)
+}
+
+// DefaultStockService 實現默認庫存異動服務
+type DefaultStockService struct {
+	repo repository.StockRepository
+}
+
+// NewStockService 創建新的庫存異動服務
+func NewStockService(repo repository.StockRepository) StockService {
+	return &DefaultStockService{
+		repo: repo,
+	}
+}
+
+// Receive 入庫
+func (s *DefaultStockService) Receive(ctx context.Context, productID int64, req model.StockRequest) (model.StockMovement, error) {
+	if err := auth.Authorize(ctx, auth.PermStockAdjust); err != nil {
+		return model.StockMovement{}, err
+	}
+
+	if req.Quantity <= 0 {
+		return model.StockMovement{}, &model.ValidationError{Message: "入庫數量必須大於 0"}
+	}
+	if req.LotNumber == "" && !req.Expiration.IsZero() {
+		return model.StockMovement{}, &model.ValidationError{Message: "指定到期日時必須同時指定批號"}
+	}
+	if len(req.LotNumber) > maxLotNumberLength {
+		return model.StockMovement{}, &model.ValidationError{Message: fmt.Sprintf("批號不能超過 %d 個字元", maxLotNumberLength)}
+	}
+
+	return s.applyOne(ctx, productID, model.MovementReceive, req.Quantity, req, model.ReasonPurchase)
+}
+
+// Issue 出庫
+func (s *DefaultStockService) Issue(ctx context.Context, productID int64, req model.StockRequest) (model.StockMovement, error) {
+	if err := auth.Authorize(ctx, auth.PermStockAdjust); err != nil {
+		return model.StockMovement{}, err
+	}
+
+	if req.Quantity <= 0 {
+		return model.StockMovement{}, &model.ValidationError{Message: "出庫數量必須大於 0"}
+	}
+	if err := rejectLot(req); err != nil {
+		return model.StockMovement{}, err
+	}
+
+	return s.applyOne(ctx, productID, model.MovementIssue, -req.Quantity, req, model.ReasonSale)
+}
+
+// Adjust 調整庫存
+func (s *DefaultStockService) Adjust(ctx context.Context, productID int64, req model.StockRequest) (model.StockMovement, error) {
+	if err := auth.Authorize(ctx, auth.PermStockAdjust); err != nil {
+		return model.StockMovement{}, err
+	}
+
+	if req.Quantity == 0 {
+		return model.StockMovement{}, &model.ValidationError{Message: "調整數量不能為 0"}
+	}
+	if err := rejectLot(req); err != nil {
+		return model.StockMovement{}, err
+	}
+
+	return s.applyOne(ctx, productID, model.MovementAdjust, req.Quantity, req, model.ReasonCorrection)
+}
+
+// Transfer 在同一交易中轉出與轉入，任一方失敗時兩筆都不生效；
+// 轉到其他產品會改變 SKU 而不經過任何記錄，因此一律拒絕
+func (s *DefaultStockService) Transfer(ctx context.Context, productID int64, req model.TransferRequest) ([]model.StockMovement, error) {
+	if err := auth.Authorize(ctx, auth.PermStockAdjust); err != nil {
+		return nil, err
+	}
+
+	if req.Quantity <= 0 {
+		return nil, &model.ValidationError{Message: "轉移數量必須大於 0"}
+	}
+	if req.Reason != "" && req.Reason != model.ReasonTransfer {
+		return nil, &model.ValidationError{Message: "轉移的原因只能是 transfer"}
+	}
+	if err := rejectLot(req.StockRequest); err != nil {
+		return nil, err
+	}
+
+	if req.LocationID == req.ToLocationID {
+		return nil, &model.ValidationError{Message: "轉移需要指定不同的 location_id 與 to_location_id"}
+	}
+
+	return s.repo.ApplyMovements(ctx, []model.StockMovement{
+		{ProductID: productID, LocationID: req.LocationID, Type: model.MovementTransferOut, Quantity: -req.Quantity, Reason: model.ReasonTransfer, Reference: req.Reference},
+		{ProductID: productID, LocationID: req.ToLocationID, Type: model.MovementTransferIn, Quantity: req.Quantity, Reason: model.ReasonTransfer, Reference: req.Reference},
+	})
+}
+
+// maxLotNumberLength 批號的最大長度，與 product_lots.lot_number 欄位一致
+const maxLotNumberLength = 100
+
+// rejectLot 只有入庫可以指定批號，出庫與轉移由儲存庫依 FEFO 分配
+func rejectLot(req model.StockRequest) error {
+	if req.LotNumber != "" || !req.Expiration.IsZero() {
+		return &model.ValidationError{Message: "只有入庫可以指定批號與到期日，出庫依到期日先後自動分配批次"}
+	}
+	return nil
+}
+
+// applyOne 驗證原因代碼並寫入單筆異動，未指定原因時使用 defaultReason
+func (s *DefaultStockService) applyOne(ctx context.Context, productID int64, movementType string, quantity int, req model.StockRequest, defaultReason string) (model.StockMovement, error) {
+	reason := req.Reason
+	if reason == "" {
+		reason = defaultReason
+	}
+	if !model.IsStockReason(reason) {
+		return model.StockMovement{}, &model.ValidationError{Message: fmt.Sprintf("無效的原因代碼: %s", reason)}
+	}
+
+	movement := model.StockMovement{
+		ProductID:  productID,
+		LocationID: req.LocationID,
+		Type:       movementType,
+		Quantity:   quantity,
+		Reason:     reason,
+		Reference:  req.Reference,
+	}
+	if req.LotNumber != "" {
+		movement.Lots = []model.MovementLot{{LotNumber: req.LotNumber, Expiration: req.Expiration, Quantity: quantity}}
+	}
+
+	movements, err := s.repo.ApplyMovements(ctx, []model.StockMovement{movement})
+	if err != nil {
+		return model.StockMovement{}, err
+	}
+
+	return movements[0], nil
+}
+
+// GetMovements 列出產品的庫存異動並套用預設分頁
+func (s *DefaultStockService) GetMovements(ctx context.Context, productID int64, query model.ProductQuery) (model.StockMovementPage, error) {
+	if err := auth.Authorize(ctx, auth.PermProductRead); err != nil {
+		return model.StockMovementPage{}, err
+	}
+
+	if query.PageSize <= 0 {
+		query.PageSize = model.DefaultPageSize
+	}
+	if query.PageSize > model.MaxPageSize {
+		query.PageSize = model.MaxPageSize
+	}
+	if query.Offset < 0 {
+		query.Offset = 0
+	}
+
+	movements, total, err := s.repo.GetMovements(ctx, productID, query)
+	if err != nil {
+		return model.StockMovementPage{}, err
+	}
+
+	return model.StockMovementPage{
+		Items:    movements,
+		Total:    total,
+		PageSize: query.PageSize,
+		Offset:   query.Offset,
+		HasNext:  query.Offset+len(movements) < total,
+		HasPrev:  query.Offset > 0,
+	}, nil
+}
+
+// GetMovement 獲取單筆異動
+func (s *DefaultStockService) GetMovement(ctx context.Context, productID int64, movementID int64) (model.StockMovement, error) {
+	if err := auth.Authorize(ctx, auth.PermProductRead); err != nil {
+		return model.StockMovement{}, err
+	}
+
+	return s.repo.GetMovement(ctx, productID, movementID)
+}
+
+// GetLots 列出產品的批次庫存
+func (s *DefaultStockService) GetLots(ctx context.Context, productID int64) (model.ProductLotStock, error) {
+	if err := auth.Authorize(ctx, auth.PermProductRead); err != nil {
+		return model.ProductLotStock{}, err
+	}
+
+	return s.repo.GetLots(ctx, productID)
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"main/internal/auth"
+	model "main/internal/models"
+	"main/internal/repository"
+)
+
+// WarehouseService 定義倉庫與儲位的服務接口
+type WarehouseService interface {
+	ListWarehouses(ctx context.Context) ([]model.Warehouse, error)
+	GetWarehouse(ctx context.Context, id int64) (model.Warehouse, error)
+	CreateWarehouse(ctx context.Context, input model.Warehouse) (model.Warehouse, error)
+	UpdateWarehouse(ctx context.Context, id int64, input model.Warehouse) (model.Warehouse, error)
+	// DeleteWarehouse 刪除倉庫，倉庫內仍有儲位時返回 ErrWarehouseInUse
+	DeleteWarehouse(ctx context.Context, id int64) error
+
+	ListLocations(ctx context.Context, warehouseID int64) ([]model.StockLocation, error)
+	GetLocation(ctx context.Context, id int64) (model.StockLocation, error)
+	// CreateLocation 在 warehouseID 指定的倉庫內創建儲位
+	CreateLocation(ctx context.Context, warehouseID int64, input model.StockLocation) (model.StockLocation, error)
+	UpdateLocation(ctx context.Context, id int64, input model.StockLocation) (model.StockLocation, error)
+	// DeleteLocation 刪除儲位，預設儲位或仍有庫存的儲位不能刪除
+	DeleteLocation(ctx context.Context, id int64) error
+}
+
+// DefaultWarehouseService 實現默認倉庫服務
+type DefaultWarehouseService struct {
+	repo repository.WarehouseRepository
+}
+
+// NewWarehouseService 創建新的倉庫服務
+func NewWarehouseService(repo repository.WarehouseRepository) WarehouseService {
+	return &DefaultWarehouseService{
+		repo: repo,
+	}
+}
+
+// ListWarehouses 列出所有倉庫
+func (s *DefaultWarehouseService) ListWarehouses(ctx context.Context) ([]model.Warehouse, error) {
+	if err := auth.Authorize(ctx, auth.PermProductRead); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListWarehouses(ctx)
+}
+
+// GetWarehouse 獲取特定倉庫
+func (s *DefaultWarehouseService) GetWarehouse(ctx context.Context, id int64) (model.Warehouse, error) {
+	if err := auth.Authorize(ctx, auth.PermProductRead); err != nil {
+		return model.Warehouse{}, err
+	}
+
+	return s.repo.GetWarehouse(ctx, id)
+}
+
+// CreateWarehouse 驗證後創建倉庫
+func (s *DefaultWarehouseService) CreateWarehouse(ctx context.Context, input model.Warehouse) (model.Warehouse, error) {
+	if err := auth.Authorize(ctx, auth.PermWarehouseWrite); err != nil {
+		return model.Warehouse{}, err
+	}
+
+	if err := model.ValidateWarehouse(input); err != nil {
+		return model.Warehouse{}, err
+	}
+	return s.repo.CreateWarehouse(ctx, input)
+}
+
+// UpdateWarehouse 驗證後修改倉庫
+func (s *DefaultWarehouseService) UpdateWarehouse(ctx context.Context, id int64, input model.Warehouse) (model.Warehouse, error) {
+	if err := auth.Authorize(ctx, auth.PermWarehouseWrite); err != nil {
+		return model.Warehouse{}, err
+	}
+
+	if err := model.ValidateWarehouse(input); err != nil {
+		return model.Warehouse{}, err
+	}
+	return s.repo.UpdateWarehouse(ctx, id, input)
+}
+
+// DeleteWarehouse 刪除倉庫
+func (s *DefaultWarehouseService) DeleteWarehouse(ctx context.Context, id int64) error {
+	if err := auth.Authorize(ctx, auth.PermWarehouseWrite); err != nil {
+		return err
+	}
+
+	return s.repo.DeleteWarehouse(ctx, id)
+}
+
+// ListLocations 列出倉庫內的儲位
+func (s *DefaultWarehouseService) ListLocations(ctx context.Context, warehouseID int64) ([]model.StockLocation, error) {
+	if err := auth.Authorize(ctx, auth.PermProductRead); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListLocations(ctx, warehouseID)
+}
+
+// GetLocation 獲取特定儲位
+func (s *DefaultWarehouseService) GetLocation(ctx context.Context, id int64) (model.StockLocation, error) {
+	if err := auth.Authorize(ctx, auth.PermProductRead); err != nil {
+		return model.StockLocation{}, err
+	}
+
+	return s.repo.GetLocation(ctx, id)
+}
+
+// CreateLocation 驗證後在倉庫內創建儲位，新儲位不會成為預設儲位
+func (s *DefaultWarehouseService) CreateLocation(ctx context.Context, warehouseID int64, input model.StockLocation) (model.StockLocation, error) {
+	if err := auth.Authorize(ctx, auth.PermWarehouseWrite); err != nil {
+		return model.StockLocation{}, err
+	}
+
+	if err := model.ValidateStockLocation(input); err != nil {
+		return model.StockLocation{}, err
+	}
+
+	input.WarehouseID = warehouseID
+	input.IsDefault = false
+	return s.repo.CreateLocation(ctx, input)
+}
+
+// UpdateLocation 驗證後修改儲位
+func (s *DefaultWarehouseService) UpdateLocation(ctx context.Context, id int64, input model.StockLocation) (model.StockLocation, error) {
+	if err := auth.Authorize(ctx, auth.PermWarehouseWrite); err != nil {
+		return model.StockLocation{}, err
+	}
+
+	if err := model.ValidateStockLocation(input); err != nil {
+		return model.StockLocation{}, err
+	}
+	return s.repo.UpdateLocation(ctx, id, input)
+}
+
+// DeleteLocation 刪除儲位
+func (s *DefaultWarehouseService) DeleteLocation(ctx context.Context, id int64) error {
+	if err := auth.Authorize(ctx, auth.PermWarehouseWrite); err != nil {
+		return err
+	}
+
+	return s.repo.DeleteLocation(ctx, id)
+}
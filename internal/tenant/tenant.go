@@ -0,0 +1,37 @@
+// Package tenant 在 context 中傳遞租戶，儲存庫依此限定所有查詢的範圍
+package tenant
+
+import (
+	"context"
+	"regexp"
+)
+
+const (
+	// Default 未指定租戶的請求、productctl 與既有數據所屬的租戶
+	Default = "default"
+	// All 跨所有租戶的背景任務（過期清理、回收站清理）使用的標記，不能作為請求的租戶
+	All = "*"
+)
+
+type contextKey struct{}
+
+// idPattern 租戶 ID 只能包含小寫字母、數字、底線與連字號，與資料庫的檢查約束一致
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// Valid 返回 id 是否為合法的租戶 ID
+func Valid(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+// WithID 返回帶有租戶的 context
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// ID 返回 context 中的租戶，沒有時為 Default
+func ID(ctx context.Context) string {
+	if id, ok := ctx.Value(contextKey{}).(string); ok && id != "" {
+		return id
+	}
+	return Default
+}
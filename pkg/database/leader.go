@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// LeaderLock 以 session 層級的 advisory lock 在多個實例間選出一個領導者；
+// 鎖固定在一條專用連線上，持有者的進程或連線中斷時由資料庫自動釋放，其他實例即可接手
+type LeaderLock struct {
+	db   *sqlx.DB
+	key  int64
+	conn *sql.Conn
+}
+
+// NewLeaderLock 創建領導者鎖，key 區分不同用途的鎖
+func NewLeaderLock(db *sqlx.DB, key int64) *LeaderLock {
+	return &LeaderLock{db: db, key: key}
+}
+
+// TryAcquire 嘗試成為領導者，不會等待其他實例釋放；已是領導者時確認連線仍然有效，
+// 連線失效時放棄領導權並返回錯誤，下次呼叫再重新競爭
+func (l *LeaderLock) TryAcquire(ctx context.Context) (bool, error) {
+	if l.conn != nil {
+		if _, err := l.conn.ExecContext(ctx, `SELECT 1`); err != nil {
+			discardConn(l.conn)
+			l.conn = nil
+			return false, fmt.Errorf("領導者連線已失效: %w", err)
+		}
+		return true, nil
+	}
+
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return false, fmt.Errorf("取得資料庫連線失敗: %w", err)
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, l.key).Scan(&acquired); err != nil {
+		conn.Close()
+		return false, fmt.Errorf("取得領導者鎖失敗: %w", err)
+	}
+	if !acquired {
+		conn.Close()
+		return false, nil
+	}
+
+	l.conn = conn
+	return true, nil
+}
+
+// Release 釋放領導權並歸還連線；不是領導者時不做任何事
+func (l *LeaderLock) Release() {
+	if l.conn == nil {
+		return
+	}
+
+	if _, err := l.conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, l.key); err != nil {
+		discardConn(l.conn)
+	} else {
+		l.conn.Close()
+	}
+	l.conn = nil
+}
+
+// discardConn 關閉底層的實體連線而不是放回連線池，session 結束時資料庫會釋放仍持有的鎖
+func discardConn(conn *sql.Conn) {
+	conn.Raw(func(any) error { return driver.ErrBadConn })
+	conn.Close()
+}
@@ -0,0 +1,2 @@
+-- 鍵集分頁使用的複合索引
+CREATE INDEX IF NOT EXISTS idx_products_update_at_id ON products(update_at, id);
@@ -0,0 +1,2 @@
+-- 樂觀並發控制使用的版本號，每次更新遞增
+ALTER TABLE products ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,2 @@
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_sku_code_key;
+CREATE INDEX IF NOT EXISTS idx_products_sku_code ON products(sku_code);
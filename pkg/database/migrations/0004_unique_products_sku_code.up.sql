@@ -0,0 +1,18 @@
+-- 已有重複的 sku_code 時中止遷移並列出（最多 20 個），需先手動合併或修改
+DO $$
+DECLARE
+    duplicates TEXT;
+BEGIN
+    SELECT string_agg(sku_code, ', ' ORDER BY sku_code) INTO duplicates
+    FROM (
+        SELECT sku_code FROM products GROUP BY sku_code HAVING COUNT(*) > 1 ORDER BY sku_code LIMIT 20
+    ) d;
+
+    IF duplicates IS NOT NULL THEN
+        RAISE EXCEPTION '存在重複的 sku_code，請先處理後再執行遷移: %', duplicates;
+    END IF;
+END $$;
+
+-- 唯一約束自帶索引，取代原本的非唯一索引
+DROP INDEX IF EXISTS idx_products_sku_code;
+ALTER TABLE products ADD CONSTRAINT products_sku_code_key UNIQUE (sku_code);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_products_expiration;
+
+ALTER TABLE products
+    ALTER COLUMN create_at TYPE TIMESTAMP USING create_at AT TIME ZONE 'UTC',
+    ALTER COLUMN update_at TYPE TIMESTAMP USING update_at AT TIME ZONE 'UTC';
+
+ALTER TABLE products
+    ALTER COLUMN expiration TYPE VARCHAR(50) USING to_char(expiration, 'YYYY-MM-DD');
@@ -0,0 +1,37 @@
+-- 無法轉換為日期的 expiration 時中止遷移並列出（最多 20 筆），需先手動修正
+DO $$
+DECLARE
+    r RECORD;
+    invalid TEXT[] := '{}';
+BEGIN
+    FOR r IN
+        SELECT id, expiration FROM products
+        WHERE btrim(expiration) <> ''
+        ORDER BY id
+    LOOP
+        BEGIN
+            IF btrim(r.expiration) !~ '^\d{4}-\d{2}-\d{2}$' THEN
+                RAISE invalid_datetime_format;
+            END IF;
+            PERFORM btrim(r.expiration)::DATE;
+        EXCEPTION WHEN invalid_datetime_format OR datetime_field_overflow THEN
+            invalid := invalid || format('id=%s: %s', r.id, r.expiration);
+        END;
+    END LOOP;
+
+    IF cardinality(invalid) > 0 THEN
+        RAISE EXCEPTION '% 筆產品的 expiration 無法轉換為日期（格式須為 YYYY-MM-DD），請先修正: %',
+            cardinality(invalid), array_to_string(invalid[1:20], ', ');
+    END IF;
+END $$;
+
+-- 空字串表示沒有到期日，轉換為 NULL
+ALTER TABLE products
+    ALTER COLUMN expiration TYPE DATE USING NULLIF(btrim(expiration), '')::DATE;
+
+-- 原本的時間戳沒有時區，以 UTC 解讀
+ALTER TABLE products
+    ALTER COLUMN create_at TYPE TIMESTAMPTZ USING create_at AT TIME ZONE 'UTC',
+    ALTER COLUMN update_at TYPE TIMESTAMPTZ USING update_at AT TIME ZONE 'UTC';
+
+CREATE INDEX IF NOT EXISTS idx_products_expiration ON products(expiration);
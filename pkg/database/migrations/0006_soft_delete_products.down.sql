@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_products_deleted_at;
+
+-- 移除欄位後軟刪除的產品會重新出現，且可能與現有產品的 sku_code 重複，回滾時一併永久刪除
+DELETE FROM products WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS products_sku_code_key;
+ALTER TABLE products ADD CONSTRAINT products_sku_code_key UNIQUE (sku_code);
+
+ALTER TABLE products DROP COLUMN IF EXISTS deleted_at;
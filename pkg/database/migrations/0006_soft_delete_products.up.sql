@@ -0,0 +1,8 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+-- 軟刪除的產品保留原 sku_code，唯一性只約束未刪除的產品；索引沿用原約束名稱
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_sku_code_key;
+CREATE UNIQUE INDEX IF NOT EXISTS products_sku_code_key ON products(sku_code) WHERE deleted_at IS NULL;
+
+-- 回收站列表與保留期清理依刪除時間查找
+CREATE INDEX IF NOT EXISTS idx_products_deleted_at ON products(deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS products_history ON products;
+DROP FUNCTION IF EXISTS record_product_history();
+DROP TABLE IF EXISTS product_history;
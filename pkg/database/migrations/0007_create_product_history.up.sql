@@ -0,0 +1,64 @@
+-- 產品的每次變更，before/after 為變更前後的完整快照
+CREATE TABLE IF NOT EXISTS product_history (
+    id BIGSERIAL PRIMARY KEY,
+    product_id INT NOT NULL,
+    operation VARCHAR(20) NOT NULL,
+    version INT NOT NULL,
+    before JSONB,
+    after JSONB,
+    actor VARCHAR(200) NOT NULL DEFAULT '',
+    request_id VARCHAR(200) NOT NULL DEFAULT '',
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_history_product_changed ON product_history(product_id, changed_at, id);
+
+-- 由觸發器在同一交易中寫入歷史，任何寫入路徑都不會遺漏；
+-- 操作者與請求 ID 由儲存庫以 set_config('app.actor' / 'app.request_id', ..., true) 設置在交易內
+CREATE OR REPLACE FUNCTION record_product_history() RETURNS TRIGGER AS $$
+DECLARE
+    op TEXT;
+    snapshot_before JSONB;
+    snapshot_after JSONB;
+    target products%ROWTYPE;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        op := 'create';
+        snapshot_after := to_jsonb(NEW);
+        target := NEW;
+    ELSIF TG_OP = 'UPDATE' THEN
+        IF OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN
+            op := 'delete';
+        ELSIF OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN
+            op := 'restore';
+        ELSE
+            op := 'update';
+        END IF;
+        snapshot_before := to_jsonb(OLD);
+        snapshot_after := to_jsonb(NEW);
+        target := NEW;
+    ELSE
+        op := 'purge';
+        snapshot_before := to_jsonb(OLD);
+        target := OLD;
+    END IF;
+
+    INSERT INTO product_history (product_id, operation, version, before, after, actor, request_id)
+    VALUES (
+        target.id, op, target.version, snapshot_before, snapshot_after,
+        COALESCE(current_setting('app.actor', true), ''),
+        COALESCE(current_setting('app.request_id', true), '')
+    );
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER products_history
+    AFTER INSERT OR UPDATE OR DELETE ON products
+    FOR EACH ROW EXECUTE FUNCTION record_product_history();
+
+-- 既有產品沒有變更紀錄，以目前內容作為基準，時間點為最後更新時間
+INSERT INTO product_history (product_id, operation, version, after, changed_at)
+SELECT id, 'baseline', version, to_jsonb(p), COALESCE(update_at, create_at, CURRENT_TIMESTAMP)
+FROM products p;
@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS products_stock_edit ON products;
+DROP FUNCTION IF EXISTS record_stock_edit();
+
+DROP TABLE IF EXISTS stock_movements;
+DROP FUNCTION IF EXISTS reject_stock_movement_update();
+
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_sku_amount_nonnegative;
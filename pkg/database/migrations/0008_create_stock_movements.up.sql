@@ -0,0 +1,83 @@
+-- 庫存不能為負數；既有的負庫存需先手動修正
+DO $$
+DECLARE
+    negative TEXT;
+BEGIN
+    SELECT string_agg(format('id=%s: %s', id, sku_amount), ', ' ORDER BY id) INTO negative
+    FROM (SELECT id, sku_amount FROM products WHERE sku_amount < 0 ORDER BY id LIMIT 20) p;
+
+    IF negative IS NOT NULL THEN
+        RAISE EXCEPTION '產品的 sku_amount 為負數，請先修正: %', negative;
+    END IF;
+END $$;
+
+ALTER TABLE products ADD CONSTRAINT products_sku_amount_nonnegative CHECK (sku_amount >= 0);
+
+-- 庫存異動帳，只能追加；products.sku_amount 為 quantity 的累計，balance 為異動後的庫存
+CREATE TABLE IF NOT EXISTS stock_movements (
+    id BIGSERIAL PRIMARY KEY,
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    movement_type VARCHAR(20) NOT NULL,
+    quantity INT NOT NULL CHECK (quantity <> 0),
+    reason VARCHAR(50) NOT NULL,
+    reference VARCHAR(200) NOT NULL DEFAULT '',
+    balance INT NOT NULL CHECK (balance >= 0),
+    actor VARCHAR(200) NOT NULL DEFAULT '',
+    request_id VARCHAR(200) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_movements_product_created ON stock_movements(product_id, created_at, id);
+
+CREATE OR REPLACE FUNCTION reject_stock_movement_update() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION '庫存異動不可修改，請以新的調整異動更正';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER stock_movements_append_only
+    BEFORE UPDATE ON stock_movements
+    FOR EACH ROW EXECUTE FUNCTION reject_stock_movement_update();
+
+-- 未經異動帳直接修改 sku_amount（創建、PUT/PATCH、匯入）時補記一筆調整，使異動帳與庫存保持一致；
+-- 異動帳自身的寫入以 set_config('app.stock_ledger', 'on', true) 標記並略過
+CREATE OR REPLACE FUNCTION record_stock_edit() RETURNS TRIGGER AS $$
+DECLARE
+    delta INT;
+BEGIN
+    IF COALESCE(current_setting('app.stock_ledger', true), '') = 'on' THEN
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP = 'INSERT' THEN
+        delta := NEW.sku_amount;
+    ELSE
+        delta := NEW.sku_amount - OLD.sku_amount;
+    END IF;
+
+    IF delta = 0 THEN
+        RETURN NULL;
+    END IF;
+
+    INSERT INTO stock_movements (product_id, movement_type, quantity, reason, balance, actor, request_id)
+    VALUES (
+        NEW.id, 'adjust', delta,
+        CASE WHEN TG_OP = 'INSERT' THEN 'opening_balance' ELSE 'product_edit' END,
+        NEW.sku_amount,
+        COALESCE(current_setting('app.actor', true), ''),
+        COALESCE(current_setting('app.request_id', true), '')
+    );
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER products_stock_edit
+    AFTER INSERT OR UPDATE OF sku_amount ON products
+    FOR EACH ROW EXECUTE FUNCTION record_stock_edit();
+
+-- 既有庫存以期初餘額記入異動帳
+INSERT INTO stock_movements (product_id, movement_type, quantity, reason, balance, created_at)
+SELECT id, 'adjust', sku_amount, 'opening_balance', sku_amount, COALESCE(update_at, create_at, CURRENT_TIMESTAMP)
+FROM products
+WHERE sku_amount <> 0;
@@ -0,0 +1,37 @@
+-- 恢復只記錄異動的觸發函數
+CREATE OR REPLACE FUNCTION record_stock_edit() RETURNS TRIGGER AS $$
+DECLARE
+    delta INT;
+BEGIN
+    IF COALESCE(current_setting('app.stock_ledger', true), '') = 'on' THEN
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP = 'INSERT' THEN
+        delta := NEW.sku_amount;
+    ELSE
+        delta := NEW.sku_amount - OLD.sku_amount;
+    END IF;
+
+    IF delta = 0 THEN
+        RETURN NULL;
+    END IF;
+
+    INSERT INTO stock_movements (product_id, movement_type, quantity, reason, balance, actor, request_id)
+    VALUES (
+        NEW.id, 'adjust', delta,
+        CASE WHEN TG_OP = 'INSERT' THEN 'opening_balance' ELSE 'product_edit' END,
+        NEW.sku_amount,
+        COALESCE(current_setting('app.actor', true), ''),
+        COALESCE(current_setting('app.request_id', true), '')
+    );
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE stock_movements DROP COLUMN IF EXISTS location_id;
+
+DROP TABLE IF EXISTS product_stock;
+DROP TABLE IF EXISTS stock_locations;
+DROP TABLE IF EXISTS warehouses;
@@ -0,0 +1,85 @@
+CREATE TABLE IF NOT EXISTS warehouses (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(50) NOT NULL UNIQUE,
+    name VARCHAR(200) NOT NULL,
+    create_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    update_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 倉庫內的儲位；is_default 的儲位接收未指定儲位的異動，全系統只有一個
+CREATE TABLE IF NOT EXISTS stock_locations (
+    id SERIAL PRIMARY KEY,
+    warehouse_id INT NOT NULL REFERENCES warehouses(id) ON DELETE RESTRICT,
+    code VARCHAR(50) NOT NULL,
+    name VARCHAR(200) NOT NULL,
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    create_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    update_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT stock_locations_warehouse_code_key UNIQUE (warehouse_id, code)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS stock_locations_default_key ON stock_locations(is_default) WHERE is_default;
+
+-- 產品在各儲位的庫存，products.sku_amount 為其合計
+CREATE TABLE IF NOT EXISTS product_stock (
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    location_id INT NOT NULL REFERENCES stock_locations(id) ON DELETE RESTRICT,
+    quantity INT NOT NULL CONSTRAINT product_stock_quantity_nonnegative CHECK (quantity >= 0),
+    PRIMARY KEY (product_id, location_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_stock_location ON product_stock(location_id);
+
+INSERT INTO warehouses (code, name) VALUES ('MAIN', '主倉庫');
+INSERT INTO stock_locations (warehouse_id, code, name, is_default)
+SELECT id, 'DEFAULT', '預設儲位', TRUE FROM warehouses WHERE code = 'MAIN';
+
+-- 既有庫存全部放在預設儲位
+INSERT INTO product_stock (product_id, location_id, quantity)
+SELECT p.id, l.id, p.sku_amount
+FROM products p CROSS JOIN stock_locations l
+WHERE l.is_default AND p.sku_amount > 0;
+
+-- 異動記錄所在的儲位，刪除儲位後保留異動但不再關聯
+ALTER TABLE stock_movements ADD COLUMN location_id INT REFERENCES stock_locations(id) ON DELETE SET NULL;
+UPDATE stock_movements SET location_id = (SELECT id FROM stock_locations WHERE is_default);
+CREATE INDEX IF NOT EXISTS idx_stock_movements_location ON stock_movements(location_id);
+
+-- 直接修改 sku_amount 時，差額記在預設儲位；預設儲位的庫存不足以扣減時違反 product_stock 的檢查約束
+CREATE OR REPLACE FUNCTION record_stock_edit() RETURNS TRIGGER AS $$
+DECLARE
+    delta INT;
+    default_location INT;
+BEGIN
+    IF COALESCE(current_setting('app.stock_ledger', true), '') = 'on' THEN
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP = 'INSERT' THEN
+        delta := NEW.sku_amount;
+    ELSE
+        delta := NEW.sku_amount - OLD.sku_amount;
+    END IF;
+
+    IF delta = 0 THEN
+        RETURN NULL;
+    END IF;
+
+    SELECT id INTO default_location FROM stock_locations WHERE is_default;
+
+    INSERT INTO product_stock (product_id, location_id, quantity)
+    VALUES (NEW.id, default_location, delta)
+    ON CONFLICT (product_id, location_id) DO UPDATE SET quantity = product_stock.quantity + EXCLUDED.quantity;
+
+    INSERT INTO stock_movements (product_id, location_id, movement_type, quantity, reason, balance, actor, request_id)
+    VALUES (
+        NEW.id, default_location, 'adjust', delta,
+        CASE WHEN TG_OP = 'INSERT' THEN 'opening_balance' ELSE 'product_edit' END,
+        NEW.sku_amount,
+        COALESCE(current_setting('app.actor', true), ''),
+        COALESCE(current_setting('app.request_id', true), '')
+    );
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
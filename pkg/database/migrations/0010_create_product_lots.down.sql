@@ -0,0 +1,41 @@
+-- 恢復不處理批次的觸發函數
+CREATE OR REPLACE FUNCTION record_stock_edit() RETURNS TRIGGER AS $$
+DECLARE
+    delta INT;
+    default_location INT;
+BEGIN
+    IF COALESCE(current_setting('app.stock_ledger', true), '') = 'on' THEN
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP = 'INSERT' THEN
+        delta := NEW.sku_amount;
+    ELSE
+        delta := NEW.sku_amount - OLD.sku_amount;
+    END IF;
+
+    IF delta = 0 THEN
+        RETURN NULL;
+    END IF;
+
+    SELECT id INTO default_location FROM stock_locations WHERE is_default;
+
+    INSERT INTO product_stock (product_id, location_id, quantity)
+    VALUES (NEW.id, default_location, delta)
+    ON CONFLICT (product_id, location_id) DO UPDATE SET quantity = product_stock.quantity + EXCLUDED.quantity;
+
+    INSERT INTO stock_movements (product_id, location_id, movement_type, quantity, reason, balance, actor, request_id)
+    VALUES (
+        NEW.id, default_location, 'adjust', delta,
+        CASE WHEN TG_OP = 'INSERT' THEN 'opening_balance' ELSE 'product_edit' END,
+        NEW.sku_amount,
+        COALESCE(current_setting('app.actor', true), ''),
+        COALESCE(current_setting('app.request_id', true), '')
+    );
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TABLE IF EXISTS stock_movement_lots;
+DROP TABLE IF EXISTS product_lots;
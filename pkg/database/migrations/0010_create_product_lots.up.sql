@@ -0,0 +1,105 @@
+-- 產品的批次，每個批次有自己的到期日；產品的庫存可以有一部分不屬於任何批次（未追蹤）
+CREATE TABLE IF NOT EXISTS product_lots (
+    id BIGSERIAL PRIMARY KEY,
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    lot_number VARCHAR(100) NOT NULL,
+    quantity INT NOT NULL CONSTRAINT product_lots_quantity_nonnegative CHECK (quantity >= 0),
+    expiration DATE,
+    received_at DATE NOT NULL DEFAULT CURRENT_DATE,
+    create_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    update_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT product_lots_product_lot_key UNIQUE (product_id, lot_number)
+);
+
+-- 先到期先出（FEFO）的分配順序
+CREATE INDEX IF NOT EXISTS idx_product_lots_fefo ON product_lots(product_id, expiration, received_at, id) WHERE quantity > 0;
+
+-- 每筆異動增減了哪些批次，quantity 的正負號與異動相同
+CREATE TABLE IF NOT EXISTS stock_movement_lots (
+    movement_id BIGINT NOT NULL REFERENCES stock_movements(id) ON DELETE CASCADE,
+    lot_id BIGINT NOT NULL REFERENCES product_lots(id) ON DELETE CASCADE,
+    quantity INT NOT NULL CHECK (quantity <> 0),
+    PRIMARY KEY (movement_id, lot_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_movement_lots_lot ON stock_movement_lots(lot_id);
+
+CREATE TRIGGER stock_movement_lots_append_only
+    BEFORE UPDATE ON stock_movement_lots
+    FOR EACH ROW EXECUTE FUNCTION reject_stock_movement_update();
+
+-- 既有庫存以產品的到期日建立期初批次，不關聯到過去的異動
+INSERT INTO product_lots (product_id, lot_number, quantity, expiration, received_at)
+SELECT id, 'OPENING', sku_amount, expiration, COALESCE(create_at, CURRENT_TIMESTAMP)::DATE
+FROM products
+WHERE sku_amount > 0;
+
+-- 直接修改 sku_amount 時：創建產品的庫存成為期初批次；減少後批次合計超過 sku_amount 時，依 FEFO 扣減超出的部分
+CREATE OR REPLACE FUNCTION record_stock_edit() RETURNS TRIGGER AS $$
+DECLARE
+    delta INT;
+    default_location INT;
+    movement BIGINT;
+    opening BIGINT;
+    excess INT;
+    lot RECORD;
+    taken INT;
+BEGIN
+    IF COALESCE(current_setting('app.stock_ledger', true), '') = 'on' THEN
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP = 'INSERT' THEN
+        delta := NEW.sku_amount;
+    ELSE
+        delta := NEW.sku_amount - OLD.sku_amount;
+    END IF;
+
+    IF delta = 0 THEN
+        RETURN NULL;
+    END IF;
+
+    SELECT id INTO default_location FROM stock_locations WHERE is_default;
+
+    INSERT INTO product_stock (product_id, location_id, quantity)
+    VALUES (NEW.id, default_location, delta)
+    ON CONFLICT (product_id, location_id) DO UPDATE SET quantity = product_stock.quantity + EXCLUDED.quantity;
+
+    INSERT INTO stock_movements (product_id, location_id, movement_type, quantity, reason, balance, actor, request_id)
+    VALUES (
+        NEW.id, default_location, 'adjust', delta,
+        CASE WHEN TG_OP = 'INSERT' THEN 'opening_balance' ELSE 'product_edit' END,
+        NEW.sku_amount,
+        COALESCE(current_setting('app.actor', true), ''),
+        COALESCE(current_setting('app.request_id', true), '')
+    )
+    RETURNING id INTO movement;
+
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO product_lots (product_id, lot_number, quantity, expiration)
+        VALUES (NEW.id, 'OPENING', delta, NEW.expiration)
+        RETURNING id INTO opening;
+
+        INSERT INTO stock_movement_lots (movement_id, lot_id, quantity) VALUES (movement, opening, delta);
+        RETURN NULL;
+    END IF;
+
+    SELECT COALESCE(SUM(quantity), 0) - NEW.sku_amount INTO excess FROM product_lots WHERE product_id = NEW.id;
+
+    FOR lot IN
+        SELECT id, quantity FROM product_lots
+        WHERE product_id = NEW.id AND quantity > 0
+        ORDER BY expiration NULLS LAST, received_at, id
+        FOR UPDATE
+    LOOP
+        EXIT WHEN excess <= 0;
+
+        taken := LEAST(lot.quantity, excess);
+        UPDATE product_lots SET quantity = quantity - taken, update_at = CURRENT_TIMESTAMP WHERE id = lot.id;
+        INSERT INTO stock_movement_lots (movement_id, lot_id, quantity) VALUES (movement, lot.id, -taken);
+        excess := excess - taken;
+    END LOOP;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,20 @@
+-- 庫存預留：active 的預留在到期前從可承諾量中扣除，提交時轉為出庫異動
+CREATE TABLE IF NOT EXISTS stock_reservations (
+    id BIGSERIAL PRIMARY KEY,
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    quantity INT NOT NULL CONSTRAINT stock_reservations_quantity_positive CHECK (quantity > 0),
+    reference VARCHAR(200) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'active'
+        CONSTRAINT stock_reservations_status_check CHECK (status IN ('active', 'committed', 'released', 'expired')),
+    expires_at TIMESTAMPTZ NOT NULL,
+    movement_id BIGINT REFERENCES stock_movements(id) ON DELETE SET NULL,
+    actor VARCHAR(200) NOT NULL DEFAULT '',
+    request_id VARCHAR(200) NOT NULL DEFAULT '',
+    create_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    update_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- reference 為冪等鍵，同一產品重送相同的 reference 返回既有的預留
+    CONSTRAINT stock_reservations_product_reference_key UNIQUE (product_id, reference)
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_reservations_active_product ON stock_reservations(product_id) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_stock_reservations_active_expires ON stock_reservations(expires_at) WHERE status = 'active';
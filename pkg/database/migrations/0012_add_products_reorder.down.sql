@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_products_low_stock;
+
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_reorder_nonnegative;
+ALTER TABLE products DROP COLUMN IF EXISTS reorder_quantity;
+ALTER TABLE products DROP COLUMN IF EXISTS reorder_point;
@@ -0,0 +1,9 @@
+-- 補貨點與建議補貨量，庫存低於補貨點時觸發低庫存事件；補貨點為 0 表示不監控
+ALTER TABLE products ADD COLUMN IF NOT EXISTS reorder_point INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS reorder_quantity INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE products ADD CONSTRAINT products_reorder_nonnegative CHECK (reorder_point >= 0 AND reorder_quantity >= 0);
+
+-- 低庫存報表只讀取低於補貨點的產品
+CREATE INDEX IF NOT EXISTS idx_products_low_stock ON products(id)
+    WHERE deleted_at IS NULL AND reorder_point > 0 AND sku_amount < reorder_point;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_products_unexpired;
+DROP INDEX IF EXISTS idx_product_lots_unexpired;
+
+ALTER TABLE products DROP COLUMN IF EXISTS expired_at;
+ALTER TABLE product_lots DROP COLUMN IF EXISTS expired_at;
@@ -0,0 +1,9 @@
+-- 過期清理標記已過期的批次與產品；標記後的庫存不能以一般出庫動用，只能以 expired 原因報廢
+ALTER TABLE product_lots ADD COLUMN IF NOT EXISTS expired_at TIMESTAMPTZ;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS expired_at TIMESTAMPTZ;
+
+-- 過期清理與即將到期報表只讀取尚未標記的行
+CREATE INDEX IF NOT EXISTS idx_product_lots_unexpired ON product_lots(expiration)
+    WHERE expired_at IS NULL AND quantity > 0;
+CREATE INDEX IF NOT EXISTS idx_products_unexpired ON products(expiration)
+    WHERE expired_at IS NULL AND deleted_at IS NULL;
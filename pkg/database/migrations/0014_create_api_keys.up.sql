@@ -0,0 +1,17 @@
+-- 機器對機器客戶端的 API 金鑰，只保存金鑰的 SHA-256 雜湊；prefix 為金鑰開頭的數個字元，用於辨識
+CREATE TABLE IF NOT EXISTS api_keys (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash CHAR(64) NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+    create_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    update_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT api_keys_key_hash_key UNIQUE (key_hash)
+);
+
+-- 名稱作為驗證後的主體，未撤銷的金鑰名稱不能重複
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_active_name ON api_keys(name) WHERE revoked_at IS NULL;
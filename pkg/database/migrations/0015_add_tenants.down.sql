@@ -0,0 +1,154 @@
+-- 回到單一租戶：其他租戶的數據與 sku_code、倉庫代碼、預設儲位或有效 API 金鑰的名稱衝突時需先手動處理
+DO $$
+DECLARE
+    t TEXT;
+BEGIN
+    FOREACH t IN ARRAY ARRAY['products', 'product_history', 'product_stock', 'product_lots', 'stock_movements',
+        'stock_movement_lots', 'stock_reservations', 'warehouses', 'stock_locations']
+    LOOP
+        EXECUTE format('DROP POLICY IF EXISTS tenant_isolation ON %I', t);
+        EXECUTE format('ALTER TABLE %I NO FORCE ROW LEVEL SECURITY', t);
+        EXECUTE format('ALTER TABLE %I DISABLE ROW LEVEL SECURITY', t);
+    END LOOP;
+END $$;
+
+-- 恢復 0010 的觸發器函數
+CREATE OR REPLACE FUNCTION record_stock_edit() RETURNS TRIGGER AS $$
+DECLARE
+    delta INT;
+    default_location INT;
+    movement BIGINT;
+    opening BIGINT;
+    excess INT;
+    lot RECORD;
+    taken INT;
+BEGIN
+    IF COALESCE(current_setting('app.stock_ledger', true), '') = 'on' THEN
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP = 'INSERT' THEN
+        delta := NEW.sku_amount;
+    ELSE
+        delta := NEW.sku_amount - OLD.sku_amount;
+    END IF;
+
+    IF delta = 0 THEN
+        RETURN NULL;
+    END IF;
+
+    SELECT id INTO default_location FROM stock_locations WHERE is_default;
+
+    INSERT INTO product_stock (product_id, location_id, quantity)
+    VALUES (NEW.id, default_location, delta)
+    ON CONFLICT (product_id, location_id) DO UPDATE SET quantity = product_stock.quantity + EXCLUDED.quantity;
+
+    INSERT INTO stock_movements (product_id, location_id, movement_type, quantity, reason, balance, actor, request_id)
+    VALUES (
+        NEW.id, default_location, 'adjust', delta,
+        CASE WHEN TG_OP = 'INSERT' THEN 'opening_balance' ELSE 'product_edit' END,
+        NEW.sku_amount,
+        COALESCE(current_setting('app.actor', true), ''),
+        COALESCE(current_setting('app.request_id', true), '')
+    )
+    RETURNING id INTO movement;
+
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO product_lots (product_id, lot_number, quantity, expiration)
+        VALUES (NEW.id, 'OPENING', delta, NEW.expiration)
+        RETURNING id INTO opening;
+
+        INSERT INTO stock_movement_lots (movement_id, lot_id, quantity) VALUES (movement, opening, delta);
+        RETURN NULL;
+    END IF;
+
+    SELECT COALESCE(SUM(quantity), 0) - NEW.sku_amount INTO excess FROM product_lots WHERE product_id = NEW.id;
+
+    FOR lot IN
+        SELECT id, quantity FROM product_lots
+        WHERE product_id = NEW.id AND quantity > 0
+        ORDER BY expiration NULLS LAST, received_at, id
+        FOR UPDATE
+    LOOP
+        EXIT WHEN excess <= 0;
+
+        taken := LEAST(lot.quantity, excess);
+        UPDATE product_lots SET quantity = quantity - taken, update_at = CURRENT_TIMESTAMP WHERE id = lot.id;
+        INSERT INTO stock_movement_lots (movement_id, lot_id, quantity) VALUES (movement, lot.id, -taken);
+        excess := excess - taken;
+    END LOOP;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- 恢復 0007 的觸發器函數
+CREATE OR REPLACE FUNCTION record_product_history() RETURNS TRIGGER AS $$
+DECLARE
+    op TEXT;
+    snapshot_before JSONB;
+    snapshot_after JSONB;
+    target products%ROWTYPE;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        op := 'create';
+        snapshot_after := to_jsonb(NEW);
+        target := NEW;
+    ELSIF TG_OP = 'UPDATE' THEN
+        IF OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN
+            op := 'delete';
+        ELSIF OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN
+            op := 'restore';
+        ELSE
+            op := 'update';
+        END IF;
+        snapshot_before := to_jsonb(OLD);
+        snapshot_after := to_jsonb(NEW);
+        target := NEW;
+    ELSE
+        op := 'purge';
+        snapshot_before := to_jsonb(OLD);
+        target := OLD;
+    END IF;
+
+    INSERT INTO product_history (product_id, operation, version, before, after, actor, request_id)
+    VALUES (
+        target.id, op, target.version, snapshot_before, snapshot_after,
+        COALESCE(current_setting('app.actor', true), ''),
+        COALESCE(current_setting('app.request_id', true), '')
+    );
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP FUNCTION IF EXISTS tenant_default_location(TEXT);
+
+DROP INDEX IF EXISTS idx_product_history_product_changed;
+CREATE INDEX IF NOT EXISTS idx_product_history_product_changed ON product_history(product_id, changed_at, id);
+
+DROP INDEX IF EXISTS stock_locations_default_key;
+CREATE UNIQUE INDEX IF NOT EXISTS stock_locations_default_key ON stock_locations(is_default) WHERE is_default;
+
+ALTER TABLE warehouses DROP CONSTRAINT IF EXISTS warehouses_code_key;
+ALTER TABLE warehouses ADD CONSTRAINT warehouses_code_key UNIQUE (code);
+
+DROP INDEX IF EXISTS products_sku_code_key;
+CREATE UNIQUE INDEX IF NOT EXISTS products_sku_code_key ON products(sku_code) WHERE deleted_at IS NULL;
+
+DROP INDEX IF EXISTS idx_api_keys_active_name;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_active_name ON api_keys(name) WHERE revoked_at IS NULL;
+
+-- 刪除欄位時一併刪除以其為基礎的複合外鍵與唯一約束
+ALTER TABLE product_history DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE stock_reservations DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE stock_movement_lots DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE product_lots DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE stock_movements DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE product_stock DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE stock_locations DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE warehouses DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE products DROP COLUMN IF EXISTS tenant_id;
+
+DROP FUNCTION IF EXISTS app_tenant_id();
@@ -0,0 +1,218 @@
+-- 多租戶：產品及其相關表都帶有 tenant_id。儲存庫以 set_config('app.tenant_id', ..., true) 在交易內設置租戶，
+-- 未設置時為 default，既有數據都屬於 default 租戶
+CREATE OR REPLACE FUNCTION app_tenant_id() RETURNS TEXT AS $$
+    SELECT COALESCE(NULLIF(current_setting('app.tenant_id', true), ''), 'default')
+$$ LANGUAGE sql STABLE;
+
+-- 頂層的表以交易的租戶為預設值；租戶 ID 的格式與 tenant.Valid 一致，跨租戶背景任務的 * 不能寫入
+ALTER TABLE products ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT app_tenant_id()
+    CONSTRAINT products_tenant_id_check CHECK (tenant_id ~ '^[a-z0-9][a-z0-9_-]{0,62}$');
+ALTER TABLE warehouses ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT app_tenant_id()
+    CONSTRAINT warehouses_tenant_id_check CHECK (tenant_id ~ '^[a-z0-9][a-z0-9_-]{0,62}$');
+ALTER TABLE api_keys ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default'
+    CONSTRAINT api_keys_tenant_id_check CHECK (tenant_id ~ '^[a-z0-9][a-z0-9_-]{0,62}$');
+
+-- 相關的表與上層的行屬於同一租戶，由複合外鍵保證，引用其他租戶的行時違反外鍵
+ALTER TABLE products ADD CONSTRAINT products_tenant_id_key UNIQUE (tenant_id, id);
+ALTER TABLE warehouses ADD CONSTRAINT warehouses_tenant_id_key UNIQUE (tenant_id, id);
+
+ALTER TABLE stock_locations ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT app_tenant_id();
+ALTER TABLE stock_locations ADD CONSTRAINT stock_locations_tenant_id_key UNIQUE (tenant_id, id);
+ALTER TABLE stock_locations ADD CONSTRAINT stock_locations_tenant_warehouse_fkey
+    FOREIGN KEY (tenant_id, warehouse_id) REFERENCES warehouses(tenant_id, id) ON DELETE RESTRICT;
+
+ALTER TABLE product_stock ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT app_tenant_id();
+ALTER TABLE product_stock ADD CONSTRAINT product_stock_tenant_product_fkey
+    FOREIGN KEY (tenant_id, product_id) REFERENCES products(tenant_id, id) ON DELETE CASCADE;
+ALTER TABLE product_stock ADD CONSTRAINT product_stock_tenant_location_fkey
+    FOREIGN KEY (tenant_id, location_id) REFERENCES stock_locations(tenant_id, id) ON DELETE RESTRICT;
+
+-- 刪除儲位時異動的 location_id 設為 NULL，因此異動的儲位仍只以單欄外鍵關聯，由同一交易中的 product_stock 檢查租戶
+ALTER TABLE stock_movements ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT app_tenant_id();
+ALTER TABLE stock_movements ADD CONSTRAINT stock_movements_tenant_id_key UNIQUE (tenant_id, id);
+ALTER TABLE stock_movements ADD CONSTRAINT stock_movements_tenant_product_fkey
+    FOREIGN KEY (tenant_id, product_id) REFERENCES products(tenant_id, id) ON DELETE CASCADE;
+
+ALTER TABLE product_lots ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT app_tenant_id();
+ALTER TABLE product_lots ADD CONSTRAINT product_lots_tenant_id_key UNIQUE (tenant_id, id);
+ALTER TABLE product_lots ADD CONSTRAINT product_lots_tenant_product_fkey
+    FOREIGN KEY (tenant_id, product_id) REFERENCES products(tenant_id, id) ON DELETE CASCADE;
+
+ALTER TABLE stock_movement_lots ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT app_tenant_id();
+ALTER TABLE stock_movement_lots ADD CONSTRAINT stock_movement_lots_tenant_movement_fkey
+    FOREIGN KEY (tenant_id, movement_id) REFERENCES stock_movements(tenant_id, id) ON DELETE CASCADE;
+ALTER TABLE stock_movement_lots ADD CONSTRAINT stock_movement_lots_tenant_lot_fkey
+    FOREIGN KEY (tenant_id, lot_id) REFERENCES product_lots(tenant_id, id) ON DELETE CASCADE;
+
+ALTER TABLE stock_reservations ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT app_tenant_id();
+ALTER TABLE stock_reservations ADD CONSTRAINT stock_reservations_tenant_product_fkey
+    FOREIGN KEY (tenant_id, product_id) REFERENCES products(tenant_id, id) ON DELETE CASCADE;
+
+-- 產品永久刪除後歷史仍保留，因此沒有外鍵，由觸發器寫入產品的租戶
+ALTER TABLE product_history ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT app_tenant_id();
+
+-- 唯一性只在租戶內：sku_code、倉庫代碼、預設儲位與有效 API 金鑰的名稱；索引沿用原名稱
+DROP INDEX IF EXISTS products_sku_code_key;
+CREATE UNIQUE INDEX IF NOT EXISTS products_sku_code_key ON products(tenant_id, sku_code) WHERE deleted_at IS NULL;
+
+ALTER TABLE warehouses DROP CONSTRAINT IF EXISTS warehouses_code_key;
+ALTER TABLE warehouses ADD CONSTRAINT warehouses_code_key UNIQUE (tenant_id, code);
+
+DROP INDEX IF EXISTS idx_api_keys_active_name;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_active_name ON api_keys(tenant_id, name) WHERE revoked_at IS NULL;
+
+DROP INDEX IF EXISTS stock_locations_default_key;
+CREATE UNIQUE INDEX IF NOT EXISTS stock_locations_default_key ON stock_locations(tenant_id) WHERE is_default;
+
+DROP INDEX IF EXISTS idx_product_history_product_changed;
+CREATE INDEX IF NOT EXISTS idx_product_history_product_changed ON product_history(tenant_id, product_id, changed_at, id);
+
+-- 租戶的預設儲位，第一次使用時在租戶的 MAIN 倉庫中建立
+CREATE OR REPLACE FUNCTION tenant_default_location(tenant TEXT) RETURNS INT AS $$
+DECLARE
+    location INT;
+    warehouse INT;
+BEGIN
+    SELECT id INTO location FROM stock_locations WHERE tenant_id = tenant AND is_default;
+    IF FOUND THEN
+        RETURN location;
+    END IF;
+
+    INSERT INTO warehouses (tenant_id, code, name) VALUES (tenant, 'MAIN', '主倉庫')
+    ON CONFLICT (tenant_id, code) DO NOTHING;
+    SELECT id INTO warehouse FROM warehouses WHERE tenant_id = tenant AND code = 'MAIN';
+
+    -- 並發的請求同時建立時，等待先建立的交易提交後沿用其儲位
+    INSERT INTO stock_locations (tenant_id, warehouse_id, code, name, is_default)
+    VALUES (tenant, warehouse, 'DEFAULT', '預設儲位', TRUE)
+    ON CONFLICT DO NOTHING;
+    SELECT id INTO location FROM stock_locations WHERE tenant_id = tenant AND is_default;
+    RETURN location;
+END;
+$$ LANGUAGE plpgsql;
+
+-- 與 0007 相同，另外記錄產品的租戶
+CREATE OR REPLACE FUNCTION record_product_history() RETURNS TRIGGER AS $$
+DECLARE
+    op TEXT;
+    snapshot_before JSONB;
+    snapshot_after JSONB;
+    target products%ROWTYPE;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        op := 'create';
+        snapshot_after := to_jsonb(NEW);
+        target := NEW;
+    ELSIF TG_OP = 'UPDATE' THEN
+        IF OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN
+            op := 'delete';
+        ELSIF OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN
+            op := 'restore';
+        ELSE
+            op := 'update';
+        END IF;
+        snapshot_before := to_jsonb(OLD);
+        snapshot_after := to_jsonb(NEW);
+        target := NEW;
+    ELSE
+        op := 'purge';
+        snapshot_before := to_jsonb(OLD);
+        target := OLD;
+    END IF;
+
+    INSERT INTO product_history (tenant_id, product_id, operation, version, before, after, actor, request_id)
+    VALUES (
+        target.tenant_id, target.id, op, target.version, snapshot_before, snapshot_after,
+        COALESCE(current_setting('app.actor', true), ''),
+        COALESCE(current_setting('app.request_id', true), '')
+    );
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- 與 0010 相同，差額記在產品所屬租戶的預設儲位，寫入的行都帶有產品的租戶
+CREATE OR REPLACE FUNCTION record_stock_edit() RETURNS TRIGGER AS $$
+DECLARE
+    delta INT;
+    default_location INT;
+    movement BIGINT;
+    opening BIGINT;
+    excess INT;
+    lot RECORD;
+    taken INT;
+BEGIN
+    IF COALESCE(current_setting('app.stock_ledger', true), '') = 'on' THEN
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP = 'INSERT' THEN
+        delta := NEW.sku_amount;
+    ELSE
+        delta := NEW.sku_amount - OLD.sku_amount;
+    END IF;
+
+    IF delta = 0 THEN
+        RETURN NULL;
+    END IF;
+
+    default_location := tenant_default_location(NEW.tenant_id);
+
+    INSERT INTO product_stock (tenant_id, product_id, location_id, quantity)
+    VALUES (NEW.tenant_id, NEW.id, default_location, delta)
+    ON CONFLICT (product_id, location_id) DO UPDATE SET quantity = product_stock.quantity + EXCLUDED.quantity;
+
+    INSERT INTO stock_movements (tenant_id, product_id, location_id, movement_type, quantity, reason, balance, actor, request_id)
+    VALUES (
+        NEW.tenant_id, NEW.id, default_location, 'adjust', delta,
+        CASE WHEN TG_OP = 'INSERT' THEN 'opening_balance' ELSE 'product_edit' END,
+        NEW.sku_amount,
+        COALESCE(current_setting('app.actor', true), ''),
+        COALESCE(current_setting('app.request_id', true), '')
+    )
+    RETURNING id INTO movement;
+
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO product_lots (tenant_id, product_id, lot_number, quantity, expiration)
+        VALUES (NEW.tenant_id, NEW.id, 'OPENING', delta, NEW.expiration)
+        RETURNING id INTO opening;
+
+        INSERT INTO stock_movement_lots (tenant_id, movement_id, lot_id, quantity) VALUES (NEW.tenant_id, movement, opening, delta);
+        RETURN NULL;
+    END IF;
+
+    SELECT COALESCE(SUM(quantity), 0) - NEW.sku_amount INTO excess FROM product_lots WHERE product_id = NEW.id;
+
+    FOR lot IN
+        SELECT id, quantity FROM product_lots
+        WHERE product_id = NEW.id AND quantity > 0
+        ORDER BY expiration NULLS LAST, received_at, id
+        FOR UPDATE
+    LOOP
+        EXIT WHEN excess <= 0;
+
+        taken := LEAST(lot.quantity, excess);
+        UPDATE product_lots SET quantity = quantity - taken, update_at = CURRENT_TIMESTAMP WHERE id = lot.id;
+        INSERT INTO stock_movement_lots (tenant_id, movement_id, lot_id, quantity) VALUES (NEW.tenant_id, movement, lot.id, -taken);
+        excess := excess - taken;
+    END LOOP;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- 資料列安全策略：只能存取交易所設置租戶的行，跨租戶的背景任務以 * 存取所有租戶；未設置租戶時看不到任何行。
+-- 策略在表啟用資料列安全後才生效，由 database.row_level_security 設定控制（見 database.SetRowLevelSecurity）
+DO $$
+DECLARE
+    t TEXT;
+BEGIN
+    FOREACH t IN ARRAY ARRAY['products', 'product_history', 'product_stock', 'product_lots', 'stock_movements',
+        'stock_movement_lots', 'stock_reservations', 'warehouses', 'stock_locations']
+    LOOP
+        EXECUTE format($policy$
+            CREATE POLICY tenant_isolation ON %I
+            USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.tenant_id', true) = '*')
+        $policy$, t);
+    END LOOP;
+END $$;
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS products_low_stock ON products;
+DROP FUNCTION IF EXISTS record_low_stock();
+DROP TABLE IF EXISTS low_stock_events;
@@ -0,0 +1,40 @@
+-- 低庫存事件的寄件匣。所有改變 sku_amount 或補貨點的寫入（產品編輯、原子調整、異動、提交預留、匯入）都經過同一個觸發器，
+-- 以同一個語句的 OLD 與 NEW 判斷是否跨過補貨點；並發的寫入由行鎖依序套用，每次跨過只記錄一筆事件。
+-- 事件在寫入的交易提交後才可見，由背景任務取出後交給通知管道
+CREATE TABLE IF NOT EXISTS low_stock_events (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    product_id INT NOT NULL,
+    sku_code VARCHAR(50) NOT NULL,
+    sku_name VARCHAR(100) NOT NULL,
+    sku_amount INT NOT NULL,
+    reorder_point INT NOT NULL,
+    reorder_quantity INT NOT NULL,
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 寫入後低於補貨點、而寫入前不低於補貨點（或產品剛建立）時記錄事件，已經低於補貨點的產品繼續減少時不重複記錄
+CREATE OR REPLACE FUNCTION record_low_stock() RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.deleted_at IS NOT NULL OR NEW.reorder_point = 0 OR NEW.sku_amount >= NEW.reorder_point THEN
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP = 'UPDATE' AND OLD.deleted_at IS NULL AND OLD.reorder_point > 0 AND OLD.sku_amount < OLD.reorder_point THEN
+        RETURN NULL;
+    END IF;
+
+    INSERT INTO low_stock_events (tenant_id, product_id, sku_code, sku_name, sku_amount, reorder_point, reorder_quantity)
+    VALUES (NEW.tenant_id, NEW.id, NEW.sku_code, NEW.sku_name, NEW.sku_amount, NEW.reorder_point, NEW.reorder_quantity);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER products_low_stock
+    AFTER INSERT OR UPDATE OF sku_amount, reorder_point ON products
+    FOR EACH ROW EXECUTE FUNCTION record_low_stock();
+
+-- 與 0015 的其他租戶表相同的資料列安全策略
+CREATE POLICY tenant_isolation ON low_stock_events
+    USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.tenant_id', true) = '*');
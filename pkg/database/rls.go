@@ -0,0 +1,46 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// tenantTables 帶有 tenant_id 並由遷移 0015 與 0016 建立 tenant_isolation 策略的表
+var tenantTables = []string{
+	"products", "product_history", "product_stock", "product_lots", "stock_movements",
+	"stock_movement_lots", "stock_reservations", "warehouses", "stock_locations", "low_stock_events",
+}
+
+// SetRowLevelSecurity 啟用或停用租戶表的資料列安全，需要表擁有者的權限；
+// 啟用時同時 FORCE，使擁有者的連線也受策略限制。應在遷移完成後呼叫，重複呼叫的結果相同
+func SetRowLevelSecurity(ctx context.Context, db *sqlx.DB, enabled bool) error {
+	statements := []string{"ENABLE ROW LEVEL SECURITY", "FORCE ROW LEVEL SECURITY"}
+	if !enabled {
+		statements = []string{"NO FORCE ROW LEVEL SECURITY", "DISABLE ROW LEVEL SECURITY"}
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range tenantTables {
+		for _, statement := range statements {
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s %s", table, statement)); err != nil {
+				return fmt.Errorf("設置 %s 的資料列安全失敗: %w", table, err)
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// BypassesRowLevelSecurity 檢查目前連線的用戶是否為超級用戶或擁有 BYPASSRLS 屬性，這類用戶不受資料列安全策略限制
+func BypassesRowLevelSecurity(ctx context.Context, db *sqlx.DB) (bool, error) {
+	var bypass bool
+	err := db.GetContext(ctx, &bypass, `SELECT rolsuper OR rolbypassrls FROM pg_roles WHERE rolname = current_user`)
+	return bypass, err
+}
@@ -33,8 +33,10 @@ curl -s http://localhost:8080/api/v1/products/$PRODUCT_ID | jq .
 
 # 更新產品
 echo -e "\n5. 更新產品"
+ETAG=$(curl -s -o /dev/null -D - http://localhost:8080/api/v1/products/$PRODUCT_ID | grep -i '^etag:' | cut -d' ' -f2 | tr -d '\r')
 curl -s -X PUT http://localhost:8080/api/v1/products/$PRODUCT_ID \
   -H "Content-Type: application/json" \
+  -H "If-Match: $ETAG" \
   -d '{
     "sku_code": "TEST001",
     "sku_name": "已更新測試產品",
@@ -48,7 +50,8 @@ curl -s http://localhost:8080/api/v1/products/$PRODUCT_ID | jq .
 
 # 刪除產品
 echo -e "\n7. 刪除產品"
-curl -s -X DELETE http://localhost:8080/api/v1/products/$PRODUCT_ID | jq .
+ETAG=$(curl -s -o /dev/null -D - http://localhost:8080/api/v1/products/$PRODUCT_ID | grep -i '^etag:' | cut -d' ' -f2 | tr -d '\r')
+curl -s -X DELETE http://localhost:8080/api/v1/products/$PRODUCT_ID -H "If-Match: $ETAG" | jq .
 
 # 確認產品已刪除
 echo -e "\n8. 確認產品已刪除"
@@ -0,0 +1,393 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"main/internal/auth"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "test-secret"
+
+var b64 = base64.RawURLEncoding
+
+// signToken 以指定算法簽發令牌，key 為 HS256 的密鑰或 RS256 / ES256 的私鑰
+func signToken(t *testing.T, alg, kid string, key any, claims map[string]any) string {
+	t.Helper()
+
+	header := map[string]any{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	headerJSON, _ := json.Marshal(header)
+	claimsJSON, _ := json.Marshal(claims)
+	input := b64.EncodeToString(headerJSON) + "." + b64.EncodeToString(claimsJSON)
+	digest := sha256.Sum256([]byte(input))
+
+	var signature []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(input))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		sig, err := rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+		signature = sig
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		require.NoError(t, err)
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+
+	return input + "." + b64.EncodeToString(signature)
+}
+
+// validClaims 返回一小時後過期的聲明
+func validClaims() map[string]any {
+	return map[string]any{
+		"sub": "alice",
+		"iss": "https://issuer.example",
+		"aud": "product-api",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+// jwksJSON 將公鑰編碼為 JWKS
+func jwksJSON(keys map[string]crypto.PublicKey) []byte {
+	set := struct {
+		Keys []map[string]string `json:"keys"`
+	}{}
+	for kid, key := range keys {
+		switch k := key.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, map[string]string{
+				"kty": "RSA", "kid": kid, "use": "sig", "alg": "RS256",
+				"n": b64.EncodeToString(k.N.Bytes()),
+				"e": b64.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+			})
+		case *ecdsa.PublicKey:
+			set.Keys = append(set.Keys, map[string]string{
+				"kty": "EC", "kid": kid, "crv": "P-256",
+				"x": b64.EncodeToString(k.X.FillBytes(make([]byte, 32))),
+				"y": b64.EncodeToString(k.Y.FillBytes(make([]byte, 32))),
+			})
+		}
+	}
+	data, _ := json.Marshal(set)
+	return data
+}
+
+func writeJWKSFile(t *testing.T, keys map[string]crypto.PublicKey) string {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, jwksJSON(keys), 0o600))
+	return path
+}
+
+func newHS256Verifier() *auth.JWTVerifier {
+	return auth.NewJWTVerifier(auth.JWTOptions{
+		HS256Secret: []byte(testSecret),
+		Issuer:      "https://issuer.example",
+		Audience:    "product-api",
+		ClockSkew:   30 * time.Second,
+	})
+}
+
+// 測試有效的 HS256 令牌返回主體與 tenant_id 聲明中的租戶
+func TestVerifyHS256(t *testing.T) {
+	claims := validClaims()
+	claims["tenant_id"] = "acme"
+	token := signToken(t, auth.AlgHS256, "", []byte(testSecret), claims)
+
+	principal, err := newHS256Verifier().Verify(context.Background(), token)
+
+	require.NoError(t, err)
+	assert.Equal(t, "alice", principal.Subject)
+	assert.Equal(t, "acme", principal.Tenant)
+}
+
+// 測試簽名錯誤、格式錯誤與 alg 為 none 的令牌被拒絕
+func TestVerifyRejectsBadSignature(t *testing.T) {
+	verifier := newHS256Verifier()
+
+	forged := signToken(t, auth.AlgHS256, "", []byte("other-secret"), validClaims())
+	_, err := verifier.Verify(context.Background(), forged)
+	assert.ErrorIs(t, err, auth.ErrInvalidSignature)
+
+	none := signToken(t, "none", "", nil, validClaims())
+	_, err = verifier.Verify(context.Background(), none)
+	assert.ErrorIs(t, err, auth.ErrUnsupportedAlgorithm)
+
+	_, err = verifier.Verify(context.Background(), "not-a-token")
+	assert.ErrorIs(t, err, auth.ErrInvalidToken)
+}
+
+// 測試未配置 JWKS 時不接受 RS256 令牌，避免以 HS256 密鑰混淆算法
+func TestVerifyRejectsUnconfiguredAlgorithm(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	token := signToken(t, auth.AlgRS256, "", key, validClaims())
+	_, err = newHS256Verifier().Verify(context.Background(), token)
+
+	assert.ErrorIs(t, err, auth.ErrUnsupportedAlgorithm)
+}
+
+// 測試 exp 與 nbf 在容許的時鐘誤差內仍然有效
+func TestVerifyClockSkew(t *testing.T) {
+	verifier := newHS256Verifier()
+	now := time.Now()
+
+	cases := []struct {
+		name  string
+		exp   time.Time
+		nbf   time.Time
+		error error
+	}{
+		{"在誤差內過期", now.Add(-10 * time.Second), time.Time{}, nil},
+		{"超過誤差過期", now.Add(-time.Minute), time.Time{}, auth.ErrTokenExpired},
+		{"在誤差內生效", now.Add(time.Hour), now.Add(10 * time.Second), nil},
+		{"尚未生效", now.Add(time.Hour), now.Add(time.Minute), auth.ErrTokenNotYetValid},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			claims := validClaims()
+			claims["exp"] = tc.exp.Unix()
+			if !tc.nbf.IsZero() {
+				claims["nbf"] = tc.nbf.Unix()
+			}
+
+			_, err := verifier.Verify(context.Background(), signToken(t, auth.AlgHS256, "", []byte(testSecret), claims))
+			if tc.error == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tc.error)
+			}
+		})
+	}
+}
+
+// 測試簽發者、受眾、主體與 exp 的檢查
+func TestVerifyClaims(t *testing.T) {
+	verifier := newHS256Verifier()
+
+	cases := []struct {
+		name   string
+		modify func(map[string]any)
+		error  error
+	}{
+		{"受眾為陣列", func(c map[string]any) { c["aud"] = []string{"other", "product-api"} }, nil},
+		{"簽發者不符", func(c map[string]any) { c["iss"] = "https://evil.example" }, auth.ErrInvalidIssuer},
+		{"受眾不符", func(c map[string]any) { c["aud"] = "other" }, auth.ErrInvalidAudience},
+		{"缺少受眾", func(c map[string]any) { delete(c, "aud") }, auth.ErrInvalidAudience},
+		{"缺少主體", func(c map[string]any) { delete(c, "sub") }, auth.ErrMissingSubject},
+		{"缺少 exp", func(c map[string]any) { delete(c, "exp") }, auth.ErrInvalidToken},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			claims := validClaims()
+			tc.modify(claims)
+
+			_, err := verifier.Verify(context.Background(), signToken(t, auth.AlgHS256, "", []byte(testSecret), claims))
+			if tc.error == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tc.error)
+			}
+		})
+	}
+}
+
+// 測試以 JWKS 文件驗證 RS256 與 ES256 令牌，並依 kid 選擇公鑰
+func TestVerifyJWKSFile(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	keys, err := auth.NewJWKSFile(writeJWKSFile(t, map[string]crypto.PublicKey{
+		"rsa-1": &rsaKey.PublicKey,
+		"ec-1":  &ecKey.PublicKey,
+	}))
+	require.NoError(t, err)
+	verifier := auth.NewJWTVerifier(auth.JWTOptions{Keys: keys})
+
+	principal, err := verifier.Verify(context.Background(), signToken(t, auth.AlgRS256, "rsa-1", rsaKey, validClaims()))
+	require.NoError(t, err)
+	assert.Equal(t, "alice", principal.Subject)
+
+	principal, err = verifier.Verify(context.Background(), signToken(t, auth.AlgES256, "ec-1", ecKey, validClaims()))
+	require.NoError(t, err)
+	assert.Equal(t, "alice", principal.Subject)
+
+	// 未帶 kid 時嘗試所有適用的公鑰
+	_, err = verifier.Verify(context.Background(), signToken(t, auth.AlgES256, "", ecKey, validClaims()))
+	assert.NoError(t, err)
+
+	_, err = verifier.Verify(context.Background(), signToken(t, auth.AlgRS256, "unknown", rsaKey, validClaims()))
+	assert.ErrorIs(t, err, auth.ErrUnknownKey)
+
+	// 以 EC 金鑰的 kid 聲稱 RS256 時不會通過
+	_, err = verifier.Verify(context.Background(), signToken(t, auth.AlgRS256, "ec-1", rsaKey, validClaims()))
+	assert.ErrorIs(t, err, auth.ErrInvalidSignature)
+}
+
+// 測試無效的 JWKS 文件在啟動時返回錯誤
+func TestNewJWKSFileInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"keys": [{"kty": "EC", "crv": "P-256", "x": "AA", "y": "AA"}]}`), 0o600))
+
+	_, err := auth.NewJWKSFile(path)
+	assert.ErrorIs(t, err, auth.ErrKeySetUnavailable)
+
+	_, err = auth.NewJWKSFile(filepath.Join(t.TempDir(), "missing.json"))
+	assert.ErrorIs(t, err, auth.ErrKeySetUnavailable)
+}
+
+// 測試 JWKS URL 在刷新間隔內使用快取，過期後重新獲取輪換的金鑰
+func TestVerifyJWKSURL(t *testing.T) {
+	oldKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	newKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	var current atomic.Value
+	current.Store(jwksJSON(map[string]crypto.PublicKey{"old": &oldKey.PublicKey}))
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write(current.Load().([]byte))
+	}))
+	defer server.Close()
+
+	refresh := 200 * time.Millisecond
+	verifier := auth.NewJWTVerifier(auth.JWTOptions{Keys: auth.NewJWKSURL(server.URL, server.Client(), refresh)})
+
+	for range 3 {
+		_, err := verifier.Verify(context.Background(), signToken(t, auth.AlgES256, "old", oldKey, validClaims()))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), fetches.Load())
+
+	// 金鑰輪換後，刷新間隔過後接受新的金鑰
+	current.Store(jwksJSON(map[string]crypto.PublicKey{"new": &newKey.PublicKey}))
+	time.Sleep(refresh)
+
+	_, err = verifier.Verify(context.Background(), signToken(t, auth.AlgES256, "new", newKey, validClaims()))
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), fetches.Load())
+}
+
+// 測試 JWKS URL 無法獲取時返回 ErrKeySetUnavailable
+func TestVerifyJWKSURLUnavailable(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	verifier := auth.NewJWTVerifier(auth.JWTOptions{Keys: auth.NewJWKSURL(server.URL, server.Client(), time.Minute)})
+	_, err = verifier.Verify(context.Background(), signToken(t, auth.AlgES256, "", key, validClaims()))
+
+	assert.ErrorIs(t, err, auth.ErrKeySetUnavailable)
+}
+
+// 測試快取過期後在背景重新獲取：獲取進行中時並發的驗證以已載入的金鑰立即回應，且只發出一次獲取
+func TestVerifyJWKSURLServesCachedKeysDuringRefresh(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	release := make(chan struct{})
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 第一次之後的獲取等到測試放行才回應
+		if fetches.Add(1) > 1 {
+			<-release
+		}
+		w.Write(jwksJSON(map[string]crypto.PublicKey{"k1": &key.PublicKey}))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	refresh := 100 * time.Millisecond
+	verifier := auth.NewJWTVerifier(auth.JWTOptions{Keys: auth.NewJWKSURL(server.URL, server.Client(), refresh)})
+	token := signToken(t, auth.AlgES256, "k1", key, validClaims())
+
+	_, err = verifier.Verify(context.Background(), token)
+	require.NoError(t, err)
+	time.Sleep(refresh)
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			_, err := verifier.Verify(ctx, token)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	// 背景的獲取仍被擋住，所有驗證都沒有等待它
+	assert.Eventually(t, func() bool { return fetches.Load() == 2 }, time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(2), fetches.Load())
+}
+
+// 測試請求的 ctx 結束時停止等待但不中斷獲取，獲取完成後的請求直接使用載入的金鑰
+func TestVerifyJWKSURLFetchOutlivesRequest(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	release := make(chan struct{})
+	fetched := make(chan struct{})
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		<-release
+		w.Write(jwksJSON(map[string]crypto.PublicKey{"k1": &key.PublicKey}))
+		close(fetched)
+	}))
+	defer server.Close()
+
+	verifier := auth.NewJWTVerifier(auth.JWTOptions{Keys: auth.NewJWKSURL(server.URL, server.Client(), time.Minute)})
+	token := signToken(t, auth.AlgES256, "k1", key, validClaims())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = verifier.Verify(ctx, token)
+	assert.ErrorIs(t, err, auth.ErrKeySetUnavailable)
+
+	close(release)
+	<-fetched
+	require.Eventually(t, func() bool {
+		_, err := verifier.Verify(context.Background(), token)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(1), fetches.Load())
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"context"
+	"main/internal/auth"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 測試每個角色包含前一個角色的權限，未知角色沒有權限
+func TestRolePermissions(t *testing.T) {
+	tests := []struct {
+		role    auth.Role
+		allowed []auth.Permission
+		denied  []auth.Permission
+	}{
+		{auth.RoleViewer, []auth.Permission{auth.PermProductRead}, []auth.Permission{auth.PermProductWrite, auth.PermStockAdjust}},
+		{auth.RoleClerk, []auth.Permission{auth.PermProductRead, auth.PermProductWrite, auth.PermStockAdjust}, []auth.Permission{auth.PermProductDelete}},
+		{auth.RoleManager, []auth.Permission{auth.PermProductDelete, auth.PermWarehouseWrite}, []auth.Permission{auth.PermProductPurge}},
+		{auth.RoleAdmin, []auth.Permission{auth.PermProductRead, auth.PermProductDelete, auth.PermProductPurge}, nil},
+		{"owner", nil, []auth.Permission{auth.PermProductRead}},
+	}
+
+	for _, tt := range tests {
+		principal := auth.Principal{Subject: "alice", Roles: []string{string(tt.role)}}
+		for _, permission := range tt.allowed {
+			assert.True(t, principal.Can(permission), "%s %s", tt.role, permission)
+		}
+		for _, permission := range tt.denied {
+			assert.False(t, principal.Can(permission), "%s %s", tt.role, permission)
+		}
+	}
+}
+
+// 測試與權限同名的範圍直接授予該權限
+func TestScopeGrantsPermission(t *testing.T) {
+	principal := auth.Principal{Subject: "api-key:pos-01", Scopes: []string{"product:read", "stock:adjust"}}
+
+	assert.True(t, principal.Can(auth.PermStockAdjust))
+	assert.False(t, principal.Can(auth.PermProductWrite))
+}
+
+// 測試 Authorize 拒絕權限不足的主體，沒有主體時同樣拒絕
+func TestAuthorize(t *testing.T) {
+	// 沒有主體時拒絕，系統主體擁有所有權限
+	assert.ErrorIs(t, auth.Authorize(context.Background(), auth.PermProductRead), auth.ErrForbidden)
+	assert.NoError(t, auth.Authorize(auth.WithPrincipal(context.Background(), auth.System), auth.PermProductPurge))
+
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "alice", Roles: []string{"viewer"}})
+	assert.NoError(t, auth.Authorize(ctx, auth.PermProductRead))
+
+	err := auth.Authorize(ctx, auth.PermProductDelete)
+	assert.ErrorIs(t, err, auth.ErrForbidden)
+	assert.Contains(t, err.Error(), "product:delete")
+}
+
+// 測試令牌的 roles 可以是字串或陣列
+func TestVerifyRoles(t *testing.T) {
+	for _, roles := range []any{"clerk", []string{"viewer", "clerk"}} {
+		claims := validClaims()
+		claims["roles"] = roles
+		token := signToken(t, auth.AlgHS256, "", []byte(testSecret), claims)
+
+		principal, err := newHS256Verifier().Verify(context.Background(), token)
+
+		require.NoError(t, err)
+		assert.Contains(t, principal.Roles, "clerk")
+		assert.True(t, principal.Can(auth.PermStockAdjust))
+	}
+}
@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"main/internal/auth"
+	"main/internal/controller"
+	"main/internal/middleware"
+	"main/internal/models"
+	"main/internal/repository"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+// 模擬 API 金鑰服務
+type MockAPIKeyService struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyService) CreateAPIKey(ctx context.Context, req models.CreateAPIKeyRequest) (models.CreatedAPIKey, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(models.CreatedAPIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyService) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	args := m.Called(ctx)
+	keys, _ := args.Get(0).([]models.APIKey)
+	return keys, args.Error(1)
+}
+
+func (m *MockAPIKeyService) RotateAPIKey(ctx context.Context, id int64) (models.CreatedAPIKey, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.CreatedAPIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyService) RevokeAPIKey(ctx context.Context, id int64) (models.APIKey, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyService) Verify(ctx context.Context, key string) (auth.Principal, error) {
+	args := m.Called(ctx, key)
+	return args.Get(0).(auth.Principal), args.Error(1)
+}
+
+func setupAPIKeyRouter(mockService *MockAPIKeyService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Unauthenticated())
+
+	logger, _ := zap.NewDevelopment()
+	controller.NewAPIKeyController(mockService, logger).RegisterAdminRoutes(router.Group("/api/v1/admin"))
+
+	return router
+}
+
+// 測試創建 API 金鑰返回明文且不允許快取
+func TestCreateAPIKeyEndpoint(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockAPIKeyService)
+	router := setupAPIKeyRouter(mockService)
+
+	req := models.CreateAPIKeyRequest{Name: "pos-01", Scopes: []string{"product:read"}}
+	created := models.CreatedAPIKey{
+		APIKey: models.APIKey{ID: 1, Name: "pos-01", Prefix: "pk_abcdefgh", Scopes: pq.StringArray{"product:read"}},
+		Key:    "pk_abcdefgh-secret",
+	}
+	mockService.On("CreateAPIKey", mock.Anything, req).Return(created, nil)
+
+	// 執行請求
+	body, _ := json.Marshal(req)
+	httpReq := httptest.NewRequest(http.MethodPost, "/api/v1/admin/api-keys", bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httpReq)
+
+	// 驗證回應
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+
+	var response map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "pk_abcdefgh-secret", response["key"])
+	assert.Equal(t, "pk_abcdefgh", response["prefix"])
+	assert.NotContains(t, response, "key_hash")
+
+	// 驗證模擬服務方法被調用
+	mockService.AssertExpectations(t)
+}
+
+// 測試 API 金鑰管理端點的錯誤對應
+func TestAPIKeyEndpointErrors(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockAPIKeyService)
+	router := setupAPIKeyRouter(mockService)
+
+	mockService.On("CreateAPIKey", mock.Anything, models.CreateAPIKeyRequest{Name: "pos-01"}).
+		Return(models.CreatedAPIKey{}, repository.ErrDuplicateAPIKey)
+	mockService.On("CreateAPIKey", mock.Anything, models.CreateAPIKeyRequest{}).
+		Return(models.CreatedAPIKey{}, &models.ValidationError{Message: "API 金鑰名稱不能為空"})
+	mockService.On("RotateAPIKey", mock.Anything, int64(2)).Return(models.CreatedAPIKey{}, repository.ErrAPIKeyRevoked)
+	mockService.On("RevokeAPIKey", mock.Anything, int64(999)).Return(models.APIKey{}, repository.ErrAPIKeyNotFound)
+	mockService.On("RevokeAPIKey", mock.Anything, int64(1)).Return(models.APIKey{ID: 1}, nil)
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		status int
+		code   string
+	}{
+		{http.MethodPost, "/api/v1/admin/api-keys", `{"name":"pos-01"}`, http.StatusConflict, "DUPLICATE_API_KEY"},
+		{http.MethodPost, "/api/v1/admin/api-keys", `{}`, http.StatusBadRequest, "API_KEY_VALIDATION_ERROR"},
+		{http.MethodPost, "/api/v1/admin/api-keys/2/rotate", "", http.StatusConflict, "API_KEY_REVOKED"},
+		{http.MethodPost, "/api/v1/admin/api-keys/abc/rotate", "", http.StatusBadRequest, "INVALID_API_KEY_ID"},
+		{http.MethodDelete, "/api/v1/admin/api-keys/999", "", http.StatusNotFound, "API_KEY_NOT_FOUND"},
+		{http.MethodDelete, "/api/v1/admin/api-keys/1", "", http.StatusNoContent, ""},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, tt.status, w.Code, tt.path)
+		if tt.code != "" {
+			var response map[string]interface{}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.code, response["error_code"], tt.path)
+		}
+	}
+
+	// 驗證模擬服務方法被調用
+	mockService.AssertExpectations(t)
+}
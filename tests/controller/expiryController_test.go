@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"main/internal/controller"
+	"main/internal/middleware"
+	"main/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+// 模擬過期服務
+type MockExpiryService struct {
+	mock.Mock
+}
+
+func (m *MockExpiryService) GetExpiringReport(ctx context.Context, query models.ExpiringQuery) (models.ExpiringReport, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).(models.ExpiringReport), args.Error(1)
+}
+
+func (m *MockExpiryService) SweepExpired(ctx context.Context, now time.Time) (models.ExpirySweepResult, error) {
+	args := m.Called(ctx, now)
+	return args.Get(0).(models.ExpirySweepResult), args.Error(1)
+}
+
+func setupExpiryRouter(mockService *MockExpiryService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Unauthenticated())
+
+	logger, _ := zap.NewDevelopment()
+	controller.NewExpiryController(mockService, logger).RegisterRoutes(router)
+
+	return router
+}
+
+// 測試即將到期報表解析 within 與 group_by
+func TestGetExpiringReport(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockExpiryService)
+	router := setupExpiryRouter(mockService)
+
+	report := models.ExpiringReport{WithinDays: 14, GroupBy: models.ExpiringByLot, Groups: []models.ExpiringGroup{}}
+	mockService.On("GetExpiringReport", mock.Anything, models.ExpiringQuery{WithinDays: 30}).Return(models.ExpiringReport{WithinDays: 30}, nil)
+	mockService.On("GetExpiringReport", mock.Anything, models.ExpiringQuery{WithinDays: 14, GroupBy: models.ExpiringByLot}).Return(report, nil)
+	mockService.On("GetExpiringReport", mock.Anything, models.ExpiringQuery{WithinDays: 10, GroupBy: "location"}).
+		Return(models.ExpiringReport{}, &models.ValidationError{Message: "group_by 必須為 warehouse 或 lot"})
+	mockService.On("GetExpiringReport", mock.Anything, models.ExpiringQuery{WithinDays: 1}).Return(models.ExpiringReport{}, errors.New("資料庫錯誤"))
+
+	tests := []struct {
+		path   string
+		status int
+		code   string
+	}{
+		{"/api/v1/reports/expiring", http.StatusOK, ""},
+		{"/api/v1/reports/expiring?within=2w&group_by=lot", http.StatusOK, ""},
+		{"/api/v1/reports/expiring?within=10&group_by=location", http.StatusBadRequest, "INVALID_QUERY_PARAMS"},
+		{"/api/v1/reports/expiring?within=30days", http.StatusBadRequest, "INVALID_QUERY_PARAMS"},
+		{"/api/v1/reports/expiring?within=-1d", http.StatusBadRequest, "INVALID_QUERY_PARAMS"},
+		{"/api/v1/reports/expiring?within=1d", http.StatusInternalServerError, "EXPIRING_REPORT_FETCH_ERROR"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, tt.status, w.Code, tt.path)
+		if tt.code != "" {
+			var response map[string]interface{}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.code, response["error_code"], tt.path)
+		}
+	}
+
+	// 驗證回應內容
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/reports/expiring?within=2w&group_by=lot", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var body models.ExpiringReport
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, report, body)
+
+	// 驗證模擬服務方法被調用
+	mockService.AssertExpectations(t)
+}
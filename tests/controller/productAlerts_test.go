@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"main/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// 測試低庫存報表的分頁與過濾參數
+func TestGetLowStockReport(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockProductService)
+	router := setupTestRouter(mockService)
+
+	page := models.ProductPage{
+		Items:    []models.Product{{ID: 2, SkuCode: "SKU002", SkuAmount: 1, ReorderPoint: 10, ReorderQuantity: 40}},
+		Total:    2,
+		PageSize: 1,
+		HasNext:  true,
+	}
+	mockService.On("GetLowStockProducts", mock.Anything, models.ProductQuery{PageSize: 1, SkuCode: "SKU"}).Return(page, nil)
+
+	// 執行請求
+	resp := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/alerts/low-stock?page_size=1&sku_code=SKU", nil)
+	router.ServeHTTP(resp, req)
+
+	// 驗證結果
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var response models.ProductPage
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+	assert.Equal(t, 10, response.Items[0].ReorderPoint)
+	assert.Equal(t, 40, response.Items[0].ReorderQuantity)
+	assert.Equal(t, "/api/v1/alerts/low-stock?offset=1&page_size=1&sku_code=SKU", response.Links.Next)
+
+	// 驗證模擬服務方法被調用
+	mockService.AssertExpectations(t)
+}
+
+// 測試低庫存報表的錯誤回應
+func TestGetLowStockReportErrors(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockProductService)
+	router := setupTestRouter(mockService)
+
+	mockService.On("GetLowStockProducts", mock.Anything, models.ProductQuery{PageSize: 5}).Return(models.ProductPage{}, context.DeadlineExceeded)
+
+	cases := []struct {
+		path   string
+		status int
+		code   string
+	}{
+		{"/api/v1/alerts/low-stock?page_size=abc", http.StatusBadRequest, "INVALID_QUERY_PARAMS"},
+		{"/api/v1/alerts/low-stock?page_size=5", http.StatusGatewayTimeout, "REQUEST_TIMEOUT"},
+	}
+
+	for _, tc := range cases {
+		resp := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, tc.path, nil)
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, tc.status, resp.Code, tc.path)
+
+		var response map[string]interface{}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+		assert.Equal(t, tc.code, response["error_code"], tc.path)
+	}
+}
@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"main/internal/controller"
+	"main/internal/models"
+	"main/internal/repository"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// 測試非原子批量創建部分失敗時返回 207 與逐項結果
+func TestBatchCreateProductsPartial(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockProductService)
+	router := setupTestRouter(mockService)
+
+	items := []models.Product{{SkuCode: "SKU001", SkuAmount: 1}, {SkuAmount: 2}}
+
+	// 設置模擬服務預期行為
+	mockService.On("BatchCreateProducts", mock.Anything, items, false).Return([]models.BatchOutcome{
+		{Product: models.Product{ID: 1, SkuCode: "SKU001", SkuAmount: 1, Version: 1}},
+		{Err: &models.ValidationError{Message: "產品名稱不能為空"}},
+	}, nil)
+
+	// 創建請求
+	jsonBody, _ := json.Marshal(models.ProductBatchRequest{Items: items})
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/products:batchCreate", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	// 執行請求
+	router.ServeHTTP(resp, req)
+
+	// 驗證結果
+	assert.Equal(t, http.StatusMultiStatus, resp.Code)
+
+	var response controller.BatchResponse
+	err := json.Unmarshal(resp.Body.Bytes(), &response)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, response.Succeeded)
+	assert.Equal(t, 1, response.Failed)
+	assert.Equal(t, http.StatusCreated, response.Results[0].Status)
+	assert.Equal(t, "SKU001", response.Results[0].Product.SkuCode)
+	assert.Equal(t, http.StatusBadRequest, response.Results[1].Status)
+	assert.Equal(t, "PRODUCT_VALIDATION_ERROR", response.Results[1].Error.ErrorCode)
+
+	// 驗證模擬服務方法被調用
+	mockService.AssertExpectations(t)
+}
+
+// 測試原子批量更新失敗時以失敗項目的狀態碼回應
+func TestBatchUpdateProductsAtomicConflict(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockProductService)
+	router := setupTestRouter(mockService)
+
+	items := []models.Product{{ID: 1, Version: 1, SkuCode: "SKU001"}, {ID: 2, Version: 1, SkuCode: "SKU002"}}
+
+	// 設置模擬服務預期行為 - 第二項版本衝突
+	mockService.On("BatchUpdateProducts", mock.Anything, items, true).
+		Return(nil, &models.BatchItemError{Index: 1, Err: repository.ErrVersionConflict})
+
+	// 創建請求
+	jsonBody, _ := json.Marshal(models.ProductBatchRequest{Atomic: true, Items: items})
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/products:batchUpdate", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	// 執行請求
+	router.ServeHTTP(resp, req)
+
+	// 驗證結果
+	assert.Equal(t, http.StatusPreconditionFailed, resp.Code)
+
+	var response controller.ErrorResponse
+	err := json.Unmarshal(resp.Body.Bytes(), &response)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "VERSION_CONFLICT", response.ErrorCode)
+	assert.Contains(t, response.ErrorMessage, "第 1 項")
+
+	// 驗證模擬服務方法被調用
+	mockService.AssertExpectations(t)
+}
+
+// 測試批量刪除全部成功時返回 200
+func TestBatchDeleteProducts(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockProductService)
+	router := setupTestRouter(mockService)
+
+	refs := []models.ProductRef{{ID: 1, Version: 2}}
+
+	// 設置模擬服務預期行為
+	mockService.On("BatchDeleteProducts", mock.Anything, refs, true).
+		Return([]models.BatchOutcome{{Product: models.Product{ID: 1, Version: 2}}}, nil)
+
+	// 創建請求
+	jsonBody, _ := json.Marshal(models.ProductBatchDeleteRequest{Atomic: true, Items: refs})
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/products:batchDelete", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	// 執行請求
+	router.ServeHTTP(resp, req)
+
+	// 驗證結果
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var response controller.BatchResponse
+	err := json.Unmarshal(resp.Body.Bytes(), &response)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, response.Succeeded)
+
+	// 驗證模擬服務方法被調用
+	mockService.AssertExpectations(t)
+}
+
+// 測試空批量與未知的自定義方法
+func TestBatchInvalidRequests(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockProductService)
+	router := setupTestRouter(mockService)
+
+	tests := []struct {
+		path string
+		body string
+		code int
+		err  string
+	}{
+		{"/api/v1/products:batchCreate", `{"items": []}`, http.StatusBadRequest, "INVALID_BATCH_SIZE"},
+		{"/api/v1/products:batchDelete", `{"items": 1}`, http.StatusBadRequest, "INVALID_REQUEST_DATA"},
+		{"/api/v1/products:batchMerge", `{"items": []}`, http.StatusNotFound, "NOT_FOUND"},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodPost, tt.path, bytes.NewBufferString(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, tt.code, resp.Code, tt.path)
+
+		var response controller.ErrorResponse
+		assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), &response))
+		assert.Equal(t, tt.err, response.ErrorCode, tt.path)
+	}
+
+	// 確保服務沒有被調用
+	mockService.AssertNotCalled(t, "BatchCreateProducts", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -2,13 +2,20 @@ package tests
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
+	"main/internal/auth"
 	"main/internal/controller"
+	"main/internal/middleware"
 	"main/internal/models"
+	"main/internal/pagination"
 	"main/internal/repository"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -21,46 +28,154 @@ type MockProductService struct {
 	mock.Mock
 }
 
-func (m *MockProductService) GetProducts() ([]models.Product, error) {
-	args := m.Called()
-	return args.Get(0).([]models.Product), args.Error(1)
+func (m *MockProductService) GetProducts(ctx context.Context, query models.ProductQuery) (models.ProductPage, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).(models.ProductPage), args.Error(1)
 }
 
-func (m *MockProductService) GetProduct(id int64) (models.Product, error) {
-	args := m.Called(id)
+func (m *MockProductService) GetProduct(ctx context.Context, id int64) (models.Product, error) {
+	args := m.Called(ctx, id)
 	return args.Get(0).(models.Product), args.Error(1)
 }
 
-func (m *MockProductService) CreateProduct(product models.Product) (models.Product, error) {
-	args := m.Called(product)
+func (m *MockProductService) GetProductBySku(ctx context.Context, skuCode string) (models.Product, error) {
+	args := m.Called(ctx, skuCode)
 	return args.Get(0).(models.Product), args.Error(1)
 }
 
-func (m *MockProductService) UpdateProduct(id int64, product models.Product) (models.Product, error) {
-	args := m.Called(id, product)
+func (m *MockProductService) CreateProduct(ctx context.Context, product models.Product) (models.Product, error) {
+	args := m.Called(ctx, product)
 	return args.Get(0).(models.Product), args.Error(1)
 }
 
-func (m *MockProductService) DeleteProduct(id int64) error {
-	args := m.Called(id)
+func (m *MockProductService) UpdateProduct(ctx context.Context, id int64, version int, product models.Product) (models.Product, error) {
+	args := m.Called(ctx, id, version, product)
+	return args.Get(0).(models.Product), args.Error(1)
+}
+
+func (m *MockProductService) MergePatchProduct(ctx context.Context, id int64, version int, patch models.ProductPatch) (models.Product, error) {
+	args := m.Called(ctx, id, version, patch)
+	return args.Get(0).(models.Product), args.Error(1)
+}
+
+func (m *MockProductService) JSONPatchProduct(ctx context.Context, id int64, version int, ops []models.JSONPatchOperation) (models.Product, error) {
+	args := m.Called(ctx, id, version, ops)
+	return args.Get(0).(models.Product), args.Error(1)
+}
+
+func (m *MockProductService) DeleteProduct(ctx context.Context, id int64, version int) error {
+	args := m.Called(ctx, id, version)
+	return args.Error(0)
+}
+
+func (m *MockProductService) UpsertProductBySku(ctx context.Context, skuCode string, version int, product models.Product) (models.Product, bool, error) {
+	args := m.Called(ctx, skuCode, version, product)
+	return args.Get(0).(models.Product), args.Bool(1), args.Error(2)
+}
+
+func (m *MockProductService) GetDeletedProducts(ctx context.Context, query models.ProductQuery) (models.ProductPage, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).(models.ProductPage), args.Error(1)
+}
+
+func (m *MockProductService) RestoreProduct(ctx context.Context, id int64) (models.Product, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.Product), args.Error(1)
+}
+
+func (m *MockProductService) AdjustStock(ctx context.Context, id int64, delta int) (models.Product, error) {
+	args := m.Called(ctx, id, delta)
+	return args.Get(0).(models.Product), args.Error(1)
+}
+
+func (m *MockProductService) GetLowStockProducts(ctx context.Context, query models.ProductQuery) (models.ProductPage, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).(models.ProductPage), args.Error(1)
+}
+
+func (m *MockProductService) PurgeProduct(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
+func (m *MockProductService) PurgeDeletedProducts(ctx context.Context, retention time.Duration) (int64, error) {
+	args := m.Called(ctx, retention)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockProductService) GetProductHistory(ctx context.Context, id int64, query models.ProductQuery) (models.ProductHistoryPage, error) {
+	args := m.Called(ctx, id, query)
+	return args.Get(0).(models.ProductHistoryPage), args.Error(1)
+}
+
+func (m *MockProductService) GetProductAsOf(ctx context.Context, id int64, asOf time.Time) (models.Product, error) {
+	args := m.Called(ctx, id, asOf)
+	return args.Get(0).(models.Product), args.Error(1)
+}
+
+func (m *MockProductService) BatchCreateProducts(ctx context.Context, inputs []models.Product, atomic bool) ([]models.BatchOutcome, error) {
+	args := m.Called(ctx, inputs, atomic)
+	outcomes, _ := args.Get(0).([]models.BatchOutcome)
+	return outcomes, args.Error(1)
+}
+
+func (m *MockProductService) BatchUpdateProducts(ctx context.Context, inputs []models.Product, atomic bool) ([]models.BatchOutcome, error) {
+	args := m.Called(ctx, inputs, atomic)
+	outcomes, _ := args.Get(0).([]models.BatchOutcome)
+	return outcomes, args.Error(1)
+}
+
+func (m *MockProductService) BatchDeleteProducts(ctx context.Context, refs []models.ProductRef, atomic bool) ([]models.BatchOutcome, error) {
+	args := m.Called(ctx, refs, atomic)
+	outcomes, _ := args.Get(0).([]models.BatchOutcome)
+	return outcomes, args.Error(1)
+}
+
+// ExportProducts 依序以預設的產品調用 fn，再返回預設的錯誤
+func (m *MockProductService) ExportProducts(ctx context.Context, query models.ProductQuery, fn func(models.Product) error) error {
+	args := m.Called(ctx, query)
+	products, _ := args.Get(0).([]models.Product)
+	for _, product := range products {
+		if err := fn(product); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockProductService) ImportProducts(ctx context.Context, rows []models.ImportRow, dryRun bool) (models.ImportReport, error) {
+	args := m.Called(ctx, rows, dryRun)
+	return args.Get(0).(models.ImportReport), args.Error(1)
+}
+
+// 測試用的游標簽名密鑰
+const testCursorSecret = "test-secret"
+
 // 設置測試環境
 func setupTestRouter(mockService *MockProductService) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
+	router.Use(middleware.Unauthenticated())
 
 	// 使用測試用的 zap logger
 	logger, _ := zap.NewDevelopment()
 
 	// 創建控制器並註冊路由
-	controller := controller.NewProducController(mockService, logger)
+	controller := controller.NewProducController(mockService, logger, pagination.NewCursorSigner(testCursorSecret))
 	controller.RegisterRoutes(router)
 
 	return router
 }
 
+// mustTimestamp 解析 RFC 3339 時間作為測試數據
+func mustTimestamp(s string) models.Timestamp {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return models.Timestamp{Time: t}
+}
+
 // 測試健康檢查端點
 func TestHealthCheck(t *testing.T) {
 	// 設置模擬服務和路由
@@ -93,13 +208,17 @@ func TestGetProducts(t *testing.T) {
 	router := setupTestRouter(mockService)
 
 	// 模擬產品數據
-	products := []models.Product{
-		{SkuCode: "SKU001", SkuName: "產品 1", SkuAmount: 10},
-		{SkuCode: "SKU002", SkuName: "產品 2", SkuAmount: 20},
+	page := models.ProductPage{
+		Items: []models.Product{
+			{SkuCode: "SKU001", SkuName: "產品 1", SkuAmount: 10},
+			{SkuCode: "SKU002", SkuName: "產品 2", SkuAmount: 20},
+		},
+		Total:    2,
+		PageSize: models.DefaultPageSize,
 	}
 
 	// 設置模擬服務預期行為
-	mockService.On("GetProducts").Return(products, nil)
+	mockService.On("GetProducts", mock.Anything, models.ProductQuery{}).Return(page, nil)
 
 	// 創建請求
 	req, _ := http.NewRequest(http.MethodGet, "/api/v1/products", nil)
@@ -111,18 +230,216 @@ func TestGetProducts(t *testing.T) {
 	// 驗證結果
 	assert.Equal(t, http.StatusOK, resp.Code)
 
-	var responseProducts []models.Product
-	err := json.Unmarshal(resp.Body.Bytes(), &responseProducts)
+	var responsePage models.ProductPage
+	err := json.Unmarshal(resp.Body.Bytes(), &responsePage)
+
+	assert.Nil(t, err)
+	assert.Len(t, responsePage.Items, 2)
+	assert.Equal(t, 2, responsePage.Total)
+	assert.Equal(t, "SKU001", responsePage.Items[0].SkuCode)
+	assert.Equal(t, "SKU002", responsePage.Items[1].SkuCode)
+	assert.Empty(t, responsePage.Links.Next)
+	assert.Empty(t, responsePage.Links.Prev)
+
+	// 驗證模擬服務方法被調用
+	mockService.AssertExpectations(t)
+}
+
+// 測試帶分頁、排序與過濾參數的產品列表
+func TestGetProductsWithQuery(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockProductService)
+	router := setupTestRouter(mockService)
+
+	minAmount := 10
+	expectedQuery := models.ProductQuery{
+		PageSize:       2,
+		Offset:         2,
+		SortBy:         "sku_name",
+		SortDir:        models.SortDesc,
+		SkuCode:        "SKU",
+		MinAmount:      &minAmount,
+		ExpirationFrom: models.MustParseDate("2025-01-01"),
+	}
+
+	page := models.ProductPage{
+		Items:    []models.Product{{SkuCode: "SKU003"}, {SkuCode: "SKU004"}},
+		Total:    7,
+		PageSize: 2,
+		Offset:   2,
+		HasNext:  true,
+		HasPrev:  true,
+	}
+
+	// 設置模擬服務預期行為
+	mockService.On("GetProducts", mock.Anything, expectedQuery).Return(page, nil)
+
+	// 創建請求
+	req, _ := http.NewRequest(http.MethodGet,
+		"/api/v1/products?page_size=2&offset=2&sort_by=sku_name&sort_dir=DESC&sku_code=SKU&min_amount=10&expiration_from=2025-01-01", nil)
+	resp := httptest.NewRecorder()
+
+	// 執行請求
+	router.ServeHTTP(resp, req)
+
+	// 驗證結果
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var responsePage models.ProductPage
+	err := json.Unmarshal(resp.Body.Bytes(), &responsePage)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 7, responsePage.Total)
+	assert.Contains(t, responsePage.Links.Next, "offset=4")
+	assert.Contains(t, responsePage.Links.Prev, "offset=0")
+
+	// 驗證模擬服務方法被調用
+	mockService.AssertExpectations(t)
+}
+
+// 測試無效的列表查詢參數
+func TestGetProductsInvalidQuery(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockProductService)
+	router := setupTestRouter(mockService)
+
+	invalidQueries := []string{
+		"page_size=0",
+		"page_size=1000",
+		"offset=-1",
+		"sort_by=password",
+		"sort_dir=sideways",
+		"min_amount=abc",
+		"min_amount=10&max_amount=5",
+		"expiration_to=31-12-2025",
+	}
+
+	for _, rawQuery := range invalidQueries {
+		// 創建請求
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/products?"+rawQuery, nil)
+		resp := httptest.NewRecorder()
+
+		// 執行請求
+		router.ServeHTTP(resp, req)
+
+		// 驗證結果
+		assert.Equal(t, http.StatusBadRequest, resp.Code, rawQuery)
+
+		var response controller.ErrorResponse
+		err := json.Unmarshal(resp.Body.Bytes(), &response)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "INVALID_QUERY_PARAMS", response.ErrorCode)
+	}
+
+	// 無效參數不應調用服務
+	mockService.AssertNotCalled(t, "GetProducts", mock.Anything, mock.Anything)
+}
+
+// 測試游標分頁：回應中的游標可用於下一頁請求
+func TestGetProductsCursorPagination(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockProductService)
+	router := setupTestRouter(mockService)
+
+	firstQuery := models.ProductQuery{PageSize: 2, SortBy: "update_at", SortDir: models.SortDesc}
+	firstPage := models.ProductPage{
+		Items: []models.Product{
+			{ID: 9, SkuCode: "SKU009", UpdateAt: mustTimestamp("2025-03-02T10:00:00Z")},
+			{ID: 7, SkuCode: "SKU007", UpdateAt: mustTimestamp("2025-03-01T10:00:00Z")},
+		},
+		Total:    5,
+		PageSize: 2,
+		HasNext:  true,
+	}
+
+	// 設置模擬服務預期行為 - 第一頁
+	mockService.On("GetProducts", mock.Anything, firstQuery).Return(firstPage, nil).Once()
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/products?page_size=2&sort_by=update_at&sort_dir=desc", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var page models.ProductPage
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), &page))
+	assert.NotEmpty(t, page.NextCursor)
+	assert.Empty(t, page.PrevCursor)
 
+	// 游標應編碼最後一筆的排序鍵
+	cursor, err := pagination.NewCursorSigner(testCursorSecret).Decode(page.NextCursor)
 	assert.Nil(t, err)
-	assert.Len(t, responseProducts, 2)
-	assert.Equal(t, "SKU001", responseProducts[0].SkuCode)
-	assert.Equal(t, "SKU002", responseProducts[1].SkuCode)
+	assert.Equal(t, "update_at", cursor.SortBy)
+	assert.Equal(t, models.SortDesc, cursor.SortDir)
+	assert.Equal(t, "2025-03-01T10:00:00Z", cursor.Value)
+	assert.Equal(t, 7, cursor.ID)
+
+	// 設置模擬服務預期行為 - 使用游標取得下一頁
+	secondQuery := models.ProductQuery{
+		PageSize: 2,
+		SortBy:   "update_at",
+		SortDir:  models.SortDesc,
+		Keyset:   &models.ProductKeyset{Value: "2025-03-01T10:00:00Z", ID: 7},
+	}
+	secondPage := models.ProductPage{
+		Items:    []models.Product{{ID: 4, SkuCode: "SKU004", UpdateAt: mustTimestamp("2025-02-01T10:00:00Z")}},
+		Total:    5,
+		PageSize: 2,
+		HasPrev:  true,
+	}
+	mockService.On("GetProducts", mock.Anything, secondQuery).Return(secondPage, nil).Once()
+
+	req, _ = http.NewRequest(http.MethodGet, "/api/v1/products?page_size=2&cursor="+url.QueryEscape(page.NextCursor), nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var next models.ProductPage
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), &next))
+	assert.Empty(t, next.NextCursor)
+	assert.NotEmpty(t, next.PrevCursor)
+	assert.Contains(t, next.Links.Prev, "cursor=")
+	assert.NotContains(t, next.Links.Prev, "offset=")
 
 	// 驗證模擬服務方法被調用
 	mockService.AssertExpectations(t)
 }
 
+// 測試被竄改或不一致的游標
+func TestGetProductsInvalidCursor(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockProductService)
+	router := setupTestRouter(mockService)
+
+	valid := pagination.NewCursorSigner(testCursorSecret).Encode(pagination.Cursor{SortBy: "id", SortDir: models.SortAsc, ID: 3})
+	forged := pagination.NewCursorSigner("another-secret").Encode(pagination.Cursor{SortBy: "id", SortDir: models.SortAsc, ID: 3})
+
+	cases := map[string]string{
+		"cursor=" + forged:                      "INVALID_CURSOR",
+		"cursor=" + valid + "x":                 "INVALID_CURSOR",
+		"cursor=not-a-cursor":                   "INVALID_CURSOR",
+		"cursor=" + valid + "&offset=10":        "INVALID_QUERY_PARAMS",
+		"cursor=" + valid + "&sort_by=sku_code": "INVALID_QUERY_PARAMS",
+	}
+
+	for rawQuery, errorCode := range cases {
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/products?"+rawQuery, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code, rawQuery)
+
+		var response controller.ErrorResponse
+		assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), &response))
+		assert.Equal(t, errorCode, response.ErrorCode, rawQuery)
+	}
+
+	// 無效游標不應調用服務
+	mockService.AssertNotCalled(t, "GetProducts", mock.Anything, mock.Anything)
+}
+
 // 測試獲取單個產品
 func TestGetProduct(t *testing.T) {
 	// 設置模擬服務和路由
@@ -134,10 +451,11 @@ func TestGetProduct(t *testing.T) {
 		SkuCode:   "SKU001",
 		SkuName:   "產品 1",
 		SkuAmount: 10,
+		Version:   2,
 	}
 
 	// 設置模擬服務預期行為
-	mockService.On("GetProduct", int64(1)).Return(product, nil)
+	mockService.On("GetProduct", mock.Anything, int64(1)).Return(product, nil)
 
 	// 創建請求
 	req, _ := http.NewRequest(http.MethodGet, "/api/v1/products/1", nil)
@@ -148,6 +466,7 @@ func TestGetProduct(t *testing.T) {
 
 	// 驗證結果
 	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, `"2"`, resp.Header().Get("ETag"))
 
 	var responseProduct models.Product
 	err := json.Unmarshal(resp.Body.Bytes(), &responseProduct)
@@ -168,7 +487,7 @@ func TestGetProductNotFound(t *testing.T) {
 	router := setupTestRouter(mockService)
 
 	// 設置模擬服務預期行為 - 返回未找到錯誤
-	mockService.On("GetProduct", int64(999)).Return(models.Product{}, repository.ErrProductNotFound)
+	mockService.On("GetProduct", mock.Anything, int64(999)).Return(models.Product{}, repository.ErrProductNotFound)
 
 	// 創建請求
 	req, _ := http.NewRequest(http.MethodGet, "/api/v1/products/999", nil)
@@ -190,6 +509,58 @@ func TestGetProductNotFound(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+// 測試 If-None-Match 與目前版本相符時返回 304
+func TestGetProductNotModified(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockProductService)
+	router := setupTestRouter(mockService)
+
+	// 設置模擬服務預期行為
+	mockService.On("GetProduct", mock.Anything, int64(1)).Return(models.Product{SkuCode: "SKU001", Version: 2}, nil)
+
+	// 創建請求 - 客戶端持有的版本仍是最新
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/products/1", nil)
+	req.Header.Set("If-None-Match", `"1", W/"2"`)
+	resp := httptest.NewRecorder()
+
+	// 執行請求
+	router.ServeHTTP(resp, req)
+
+	// 驗證結果
+	assert.Equal(t, http.StatusNotModified, resp.Code)
+	assert.Equal(t, `"2"`, resp.Header().Get("ETag"))
+	assert.Empty(t, resp.Body.String())
+}
+
+// 測試服務處理超時時返回 504
+func TestGetProductTimeout(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockProductService)
+	router := setupTestRouter(mockService)
+
+	// 設置模擬服務預期行為 - 查詢超過截止時間
+	mockService.On("GetProduct", mock.Anything, int64(1)).Return(models.Product{}, context.DeadlineExceeded)
+
+	// 創建請求
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/products/1", nil)
+	resp := httptest.NewRecorder()
+
+	// 執行請求
+	router.ServeHTTP(resp, req)
+
+	// 驗證結果
+	assert.Equal(t, http.StatusGatewayTimeout, resp.Code)
+
+	var response controller.ErrorResponse
+	err := json.Unmarshal(resp.Body.Bytes(), &response)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "REQUEST_TIMEOUT", response.ErrorCode)
+
+	// 驗證模擬服務方法被調用
+	mockService.AssertExpectations(t)
+}
+
 // 測試創建產品
 func TestCreateProduct(t *testing.T) {
 	// 設置模擬服務和路由
@@ -211,7 +582,7 @@ func TestCreateProduct(t *testing.T) {
 	}
 
 	// 設置模擬服務預期行為
-	mockService.On("CreateProduct", mock.Anything).Return(productOutput, nil)
+	mockService.On("CreateProduct", mock.Anything, mock.Anything).Return(productOutput, nil)
 
 	// 創建請求
 	jsonBody, _ := json.Marshal(productInput)
@@ -269,6 +640,30 @@ func TestCreateInvalidProduct(t *testing.T) {
 	assert.Equal(t, "PRODUCT_VALIDATION_ERROR", response.ErrorCode)
 }
 
+// 測試創建產品時拒絕格式錯誤或已過期的到期日
+func TestCreateProductInvalidExpiration(t *testing.T) {
+	yesterday := time.Now().UTC().AddDate(0, 0, -1).Format(models.DateLayout)
+
+	for _, expiration := range []string{"31-12-2025", "2025-02-30", yesterday} {
+		mockService := new(MockProductService)
+		router := setupTestRouter(mockService)
+
+		body := `{"sku_code": "SKU003", "sku_name": "新產品", "expiration": "` + expiration + `"}`
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/products", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code, expiration)
+
+		var response controller.ErrorResponse
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+		assert.Equal(t, "PRODUCT_VALIDATION_ERROR", response.ErrorCode, expiration)
+		mockService.AssertNotCalled(t, "CreateProduct", mock.Anything, mock.Anything)
+	}
+}
+
 // 測試更新產品
 func TestUpdateProduct(t *testing.T) {
 	// 設置模擬服務和路由
@@ -287,15 +682,17 @@ func TestUpdateProduct(t *testing.T) {
 		SkuCode:   "SKU001",
 		SkuName:   "更新產品名稱",
 		SkuAmount: 25,
+		Version:   3,
 	}
 
 	// 設置模擬服務預期行為
-	mockService.On("UpdateProduct", int64(1), mock.Anything).Return(productOutput, nil)
+	mockService.On("UpdateProduct", mock.Anything, int64(1), 2, mock.Anything).Return(productOutput, nil)
 
 	// 創建請求
 	jsonBody, _ := json.Marshal(productInput)
 	req, _ := http.NewRequest(http.MethodPut, "/api/v1/products/1", bytes.NewBuffer(jsonBody))
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"2"`)
 	resp := httptest.NewRecorder()
 
 	// 執行請求
@@ -303,6 +700,7 @@ func TestUpdateProduct(t *testing.T) {
 
 	// 驗證結果
 	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, `"3"`, resp.Header().Get("ETag"))
 
 	var responseProduct models.Product
 	err := json.Unmarshal(resp.Body.Bytes(), &responseProduct)
@@ -316,6 +714,216 @@ func TestUpdateProduct(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+// 測試更新產品時缺少 If-Match
+func TestUpdateProductWithoutIfMatch(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockProductService)
+	router := setupTestRouter(mockService)
+
+	// 創建請求 - 不帶 If-Match
+	jsonBody, _ := json.Marshal(models.Product{SkuCode: "SKU001", SkuAmount: 25})
+	req, _ := http.NewRequest(http.MethodPut, "/api/v1/products/1", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	// 執行請求
+	router.ServeHTTP(resp, req)
+
+	// 驗證結果
+	assert.Equal(t, http.StatusPreconditionRequired, resp.Code)
+
+	var response controller.ErrorResponse
+	err := json.Unmarshal(resp.Body.Bytes(), &response)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "PRECONDITION_REQUIRED", response.ErrorCode)
+
+	// 確保服務沒有被調用
+	mockService.AssertNotCalled(t, "UpdateProduct", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// 測試以過期的版本更新產品
+func TestUpdateProductVersionConflict(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockProductService)
+	router := setupTestRouter(mockService)
+
+	// 設置模擬服務預期行為 - 產品已被其他請求修改
+	mockService.On("UpdateProduct", mock.Anything, int64(1), 1, mock.Anything).Return(models.Product{}, repository.ErrVersionConflict)
+
+	// 創建請求
+	jsonBody, _ := json.Marshal(models.Product{SkuCode: "SKU001", SkuAmount: 25})
+	req, _ := http.NewRequest(http.MethodPut, "/api/v1/products/1", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"1"`)
+	resp := httptest.NewRecorder()
+
+	// 執行請求
+	router.ServeHTTP(resp, req)
+
+	// 驗證結果
+	assert.Equal(t, http.StatusPreconditionFailed, resp.Code)
+
+	var response controller.ErrorResponse
+	err := json.Unmarshal(resp.Body.Bytes(), &response)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "VERSION_CONFLICT", response.ErrorCode)
+
+	// 驗證模擬服務方法被調用
+	mockService.AssertExpectations(t)
+}
+
+// 測試 If-Match 為弱 ETag 時拒絕請求
+func TestDeleteProductInvalidIfMatch(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockProductService)
+	router := setupTestRouter(mockService)
+
+	// 創建請求
+	req, _ := http.NewRequest(http.MethodDelete, "/api/v1/products/1", nil)
+	req.Header.Set("If-Match", `W/"1"`)
+	resp := httptest.NewRecorder()
+
+	// 執行請求
+	router.ServeHTTP(resp, req)
+
+	// 驗證結果
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	var response controller.ErrorResponse
+	err := json.Unmarshal(resp.Body.Bytes(), &response)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "INVALID_IF_MATCH", response.ErrorCode)
+}
+
+// 測試以 JSON Merge Patch 部分更新產品
+func TestPatchProductMergePatch(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockProductService)
+	router := setupTestRouter(mockService)
+
+	// 只修改名稱並清除到期日
+	body := `{"sku_name": "新名稱", "expiration": null}`
+	expectedPatch := models.ProductPatch{
+		SkuName:    models.Nullable[string]{Set: true, Value: "新名稱"},
+		Expiration: models.Nullable[models.Date]{Set: true, Null: true},
+	}
+
+	// 設置模擬服務預期行為
+	mockService.On("MergePatchProduct", mock.Anything, int64(1), 1, expectedPatch).
+		Return(models.Product{SkuCode: "SKU001", SkuName: "新名稱", SkuAmount: 10, Version: 2}, nil)
+
+	// 創建請求
+	req, _ := http.NewRequest(http.MethodPatch, "/api/v1/products/1", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	req.Header.Set("If-Match", `"1"`)
+	resp := httptest.NewRecorder()
+
+	// 執行請求
+	router.ServeHTTP(resp, req)
+
+	// 驗證結果
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, `"2"`, resp.Header().Get("ETag"))
+
+	var responseProduct models.Product
+	err := json.Unmarshal(resp.Body.Bytes(), &responseProduct)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "新名稱", responseProduct.SkuName)
+	assert.Equal(t, 10, responseProduct.SkuAmount)
+
+	// 驗證模擬服務方法被調用
+	mockService.AssertExpectations(t)
+}
+
+// 測試以 JSON Patch 部分更新產品
+func TestPatchProductJSONPatch(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockProductService)
+	router := setupTestRouter(mockService)
+
+	body := `[{"op": "test", "path": "/sku_amount", "value": 10}, {"op": "replace", "path": "/sku_amount", "value": 0}]`
+	ops, _ := models.DecodeJSONPatch([]byte(body))
+
+	// 設置模擬服務預期行為
+	mockService.On("JSONPatchProduct", mock.Anything, int64(1), 1, ops).
+		Return(models.Product{SkuCode: "SKU001", Version: 2}, nil)
+
+	// 創建請求
+	req, _ := http.NewRequest(http.MethodPatch, "/api/v1/products/1", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json-patch+json")
+	req.Header.Set("If-Match", `"1"`)
+	resp := httptest.NewRecorder()
+
+	// 執行請求
+	router.ServeHTTP(resp, req)
+
+	// 驗證結果
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	// 驗證模擬服務方法被調用
+	mockService.AssertExpectations(t)
+}
+
+// 測試 JSON Patch 的 test 操作不成立時返回 409
+func TestPatchProductTestFailed(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockProductService)
+	router := setupTestRouter(mockService)
+
+	// 設置模擬服務預期行為
+	mockService.On("JSONPatchProduct", mock.Anything, int64(1), 0, mock.Anything).
+		Return(models.Product{}, models.ErrPatchTestFailed)
+
+	// 創建請求
+	body := `[{"op": "test", "path": "/sku_amount", "value": 99}]`
+	req, _ := http.NewRequest(http.MethodPatch, "/api/v1/products/1", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json-patch+json")
+	req.Header.Set("If-Match", "*")
+	resp := httptest.NewRecorder()
+
+	// 執行請求
+	router.ServeHTTP(resp, req)
+
+	// 驗證結果
+	assert.Equal(t, http.StatusConflict, resp.Code)
+
+	var response controller.ErrorResponse
+	err := json.Unmarshal(resp.Body.Bytes(), &response)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "PATCH_TEST_FAILED", response.ErrorCode)
+}
+
+// 測試不支援的修補格式
+func TestPatchProductUnsupportedMediaType(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockProductService)
+	router := setupTestRouter(mockService)
+
+	// 創建請求 - 使用一般的 application/json
+	req, _ := http.NewRequest(http.MethodPatch, "/api/v1/products/1", bytes.NewBufferString(`{"sku_name": "新名稱"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"1"`)
+	resp := httptest.NewRecorder()
+
+	// 執行請求
+	router.ServeHTTP(resp, req)
+
+	// 驗證結果
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.Code)
+	assert.Contains(t, resp.Header().Get("Accept-Patch"), "application/merge-patch+json")
+
+	var response controller.ErrorResponse
+	err := json.Unmarshal(resp.Body.Bytes(), &response)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "UNSUPPORTED_PATCH_TYPE", response.ErrorCode)
+}
+
 // 測試刪除產品
 func TestDeleteProduct(t *testing.T) {
 	// 設置模擬服務和路由
@@ -323,10 +931,11 @@ func TestDeleteProduct(t *testing.T) {
 	router := setupTestRouter(mockService)
 
 	// 設置模擬服務預期行為
-	mockService.On("DeleteProduct", int64(1)).Return(nil)
+	mockService.On("DeleteProduct", mock.Anything, int64(1), 0).Return(nil)
 
 	// 創建請求
 	req, _ := http.NewRequest(http.MethodDelete, "/api/v1/products/1", nil)
+	req.Header.Set("If-Match", "*")
 	resp := httptest.NewRecorder()
 
 	// 執行請求
@@ -345,6 +954,52 @@ func TestDeleteProduct(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+// 測試權限不足時路由返回 403 且不呼叫服務，服務層拒絕時同樣返回 403
+func TestProductPermissionDenied(t *testing.T) {
+	// 設置模擬服務和路由，請求的主體只有 viewer 角色
+	mockService := new(MockProductService)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		principal := auth.Principal{Subject: "alice", Roles: []string{"viewer"}}
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+		c.Next()
+	})
+	logger, _ := zap.NewDevelopment()
+	controller.NewProducController(mockService, logger, pagination.NewCursorSigner(testCursorSecret)).RegisterRoutes(router)
+
+	mockService.On("GetProduct", mock.Anything, int64(1)).
+		Return(models.Product{}, fmt.Errorf("%w: 需要 %s", auth.ErrForbidden, auth.PermProductRead))
+
+	tests := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodDelete, "/api/v1/products/1"},
+		{http.MethodPost, "/api/v1/products"},
+		{http.MethodPost, "/api/v1/products/1/stock:adjust"},
+		{http.MethodPost, "/api/v1/products:batchDelete"},
+		{http.MethodGet, "/api/v1/products/1"},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, tt.path, bytes.NewBufferString(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", "*")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusForbidden, resp.Code, tt.path)
+		var response map[string]string
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+		assert.Equal(t, "PERMISSION_DENIED", response["error_code"], tt.path)
+	}
+
+	// 只有讀取經過服務層
+	mockService.AssertExpectations(t)
+	mockService.AssertNumberOfCalls(t, "GetProduct", 1)
+}
+
 // 測試刪除不存在的產品
 func TestDeleteProductNotFound(t *testing.T) {
 	// 設置模擬服務和路由
@@ -352,10 +1007,11 @@ func TestDeleteProductNotFound(t *testing.T) {
 	router := setupTestRouter(mockService)
 
 	// 設置模擬服務預期行為 - 返回未找到錯誤
-	mockService.On("DeleteProduct", int64(999)).Return(repository.ErrProductNotFound)
+	mockService.On("DeleteProduct", mock.Anything, int64(999), 1).Return(repository.ErrProductNotFound)
 
 	// 創建請求
 	req, _ := http.NewRequest(http.MethodDelete, "/api/v1/products/999", nil)
+	req.Header.Set("If-Match", `"1"`)
 	resp := httptest.NewRecorder()
 
 	// 執行請求
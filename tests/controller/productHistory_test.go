@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"encoding/json"
+	"main/internal/models"
+	"main/internal/repository"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// 測試列出產品的變更歷史
+func TestGetProductHistory(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockProductService)
+	router := setupTestRouter(mockService)
+
+	page := models.ProductHistoryPage{
+		Items: []models.ProductHistory{{
+			ID:        2,
+			ProductID: 1,
+			Operation: models.HistoryUpdate,
+			Version:   2,
+			Before:    &models.Product{ID: 1, SkuCode: "SKU001", SkuAmount: 10, Version: 1},
+			After:     &models.Product{ID: 1, SkuCode: "SKU001", SkuAmount: 5, Version: 2},
+			Actor:     "alice",
+			ChangedAt: mustTimestamp("2025-03-01T10:00:00Z"),
+		}},
+		Total:    2,
+		PageSize: 1,
+		HasNext:  true,
+	}
+	mockService.On("GetProductHistory", mock.Anything, int64(1), models.ProductQuery{PageSize: 1}).Return(page, nil)
+	mockService.On("GetProductHistory", mock.Anything, int64(999), mock.Anything).Return(models.ProductHistoryPage{}, repository.ErrProductNotFound)
+
+	// 執行請求
+	resp := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/products/1/history?page_size=1", nil)
+	router.ServeHTTP(resp, req)
+
+	// 驗證結果
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var response models.ProductHistoryPage
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+	assert.Equal(t, 2, response.Total)
+	assert.Equal(t, "alice", response.Items[0].Actor)
+	assert.Equal(t, 10, response.Items[0].Before.SkuAmount)
+	assert.Equal(t, 5, response.Items[0].After.SkuAmount)
+	assert.Equal(t, "/api/v1/products/1/history?offset=1&page_size=1", response.Links.Next)
+
+	// 沒有變更紀錄的產品
+	resp = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/api/v1/products/999/history", nil)
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+
+	// 驗證模擬服務方法被調用
+	mockService.AssertExpectations(t)
+}
+
+// 測試以 as_of 讀取產品在某個時間點的內容
+func TestGetProductAsOf(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockProductService)
+	router := setupTestRouter(mockService)
+
+	asOf := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	mockService.On("GetProductAsOf", mock.Anything, int64(1), mock.MatchedBy(asOf.Equal)).
+		Return(models.Product{ID: 1, SkuCode: "SKU001", SkuAmount: 5, Version: 2}, nil)
+	mockService.On("GetProductAsOf", mock.Anything, int64(2), mock.Anything).
+		Return(models.Product{}, repository.ErrProductNotFound)
+
+	cases := []struct {
+		path   string
+		status int
+		code   string
+	}{
+		{"/api/v1/products/1?as_of=2025-03-01T20:00:00%2B08:00", http.StatusOK, ""},
+		{"/api/v1/products/2?as_of=2025-03-01T12:00:00Z", http.StatusNotFound, "PRODUCT_NOT_FOUND"},
+		{"/api/v1/products/1?as_of=2025-03-01", http.StatusBadRequest, "INVALID_QUERY_PARAMS"},
+	}
+
+	for _, tc := range cases {
+		resp := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, tc.path, nil)
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, tc.status, resp.Code, tc.path)
+		if tc.code != "" {
+			var response map[string]interface{}
+			assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			assert.Equal(t, tc.code, response["error_code"], tc.path)
+			continue
+		}
+
+		// 歷史內容不設置 ETag
+		assert.Empty(t, resp.Header().Get("ETag"))
+		var product models.Product
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &product))
+		assert.Equal(t, 5, product.SkuAmount)
+	}
+
+	// 驗證模擬服務方法被調用
+	mockService.AssertExpectations(t)
+}
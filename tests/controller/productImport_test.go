@@ -0,0 +1,192 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"main/internal/controller"
+	"main/internal/models"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// 測試以 CSV 匯出產品，過濾條件傳遞給服務
+func TestExportProductsCSV(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockProductService)
+	router := setupTestRouter(mockService)
+
+	// 設置模擬服務預期行為
+	mockService.On("ExportProducts", mock.Anything, models.ProductQuery{SkuCode: "SKU"}).
+		Return([]models.Product{{ID: 1, SkuCode: "SKU001", SkuName: "產品 1", SkuAmount: 10, Version: 1}}, nil)
+
+	// 創建請求
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/products/export?sku_code=SKU", nil)
+	resp := httptest.NewRecorder()
+
+	// 執行請求
+	router.ServeHTTP(resp, req)
+
+	// 驗證結果
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", resp.Header().Get("Content-Type"))
+	assert.Contains(t, resp.Header().Get("Content-Disposition"), `filename="products.csv"`)
+	assert.Equal(t,
+		"id,sku_code,sku_name,sku_amount,expiration,reorder_point,reorder_quantity,create_at,update_at,version\n1,SKU001,產品 1,10,,0,0,,,1\n",
+		resp.Body.String())
+
+	// 驗證模擬服務方法被調用
+	mockService.AssertExpectations(t)
+}
+
+// 測試不支援的匯出格式
+func TestExportProductsInvalidFormat(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockProductService)
+	router := setupTestRouter(mockService)
+
+	// 創建請求
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/products/export?format=pdf", nil)
+	resp := httptest.NewRecorder()
+
+	// 執行請求
+	router.ServeHTTP(resp, req)
+
+	// 驗證結果
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	var response controller.ErrorResponse
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), &response))
+	assert.Equal(t, "INVALID_EXPORT_FORMAT", response.ErrorCode)
+	mockService.AssertNotCalled(t, "ExportProducts", mock.Anything, mock.Anything)
+}
+
+// 測試尚未寫出內容時的錯誤以 JSON 返回
+func TestExportProductsError(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockProductService)
+	router := setupTestRouter(mockService)
+
+	// 設置模擬服務預期行為
+	mockService.On("ExportProducts", mock.Anything, mock.Anything).Return(nil, errors.New("資料庫錯誤"))
+
+	// 創建請求
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/products/export?format=xlsx", nil)
+	resp := httptest.NewRecorder()
+
+	// 執行請求
+	router.ServeHTTP(resp, req)
+
+	// 驗證結果
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.Empty(t, resp.Header().Get("Content-Disposition"))
+
+	var response controller.ErrorResponse
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), &response))
+	assert.Equal(t, "PRODUCT_EXPORT_ERROR", response.ErrorCode)
+}
+
+// newImportRequest 創建帶檔案與表單欄位的 multipart 匯入請求
+func newImportRequest(t *testing.T, filename string, content string, fields map[string]string) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	for k, v := range fields {
+		require.NoError(t, writer.WriteField(k, v))
+	}
+	if filename != "" {
+		part, err := writer.CreateFormFile("file", filename)
+		require.NoError(t, err)
+		_, err = part.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/products/import", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+// 測試匯入部分失敗時返回 207 與逐列錯誤
+func TestImportProductsPartial(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockProductService)
+	router := setupTestRouter(mockService)
+
+	report := models.ImportReport{
+		DryRun:  true,
+		Total:   2,
+		Created: 1,
+		Failed:  1,
+		Errors:  []models.ImportRowError{{Line: 3, SkuCode: "SKU002", Error: "產品庫存不能為負數"}},
+	}
+
+	// 表頭依對應轉為產品欄位
+	expectedRows := []models.ImportRow{
+		{Line: 2, Product: models.Product{SkuCode: "SKU001", SkuAmount: 1}},
+		{Line: 3, Product: models.Product{SkuCode: "SKU002", SkuAmount: -1}},
+	}
+	mockService.On("ImportProducts", mock.Anything, expectedRows, true).Return(report, nil)
+
+	// 創建請求
+	req := newImportRequest(t, "products.csv", "料號,sku_amount\nSKU001,1\nSKU002,-1\n", map[string]string{
+		"mapping": `{"料號": "sku_code"}`,
+		"dry_run": "true",
+	})
+	resp := httptest.NewRecorder()
+
+	// 執行請求
+	router.ServeHTTP(resp, req)
+
+	// 驗證結果
+	assert.Equal(t, http.StatusMultiStatus, resp.Code)
+
+	var response models.ImportReport
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), &response))
+	assert.Equal(t, report, response)
+
+	// 驗證模擬服務方法被調用
+	mockService.AssertExpectations(t)
+}
+
+// 測試匯入請求的檔案、格式與對應驗證
+func TestImportProductsInvalidRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+		fields   map[string]string
+		code     string
+	}{
+		{"缺少檔案", "", "", nil, "INVALID_IMPORT_FILE"},
+		{"不支援的副檔名", "products.txt", "sku_code\n", nil, "INVALID_IMPORT_FORMAT"},
+		{"無效的對應", "products.csv", "sku_code\n", map[string]string{"mapping": `{"a": "id"}`}, "INVALID_IMPORT_MAPPING"},
+		{"缺少 sku_code 表頭", "products.csv", "sku_name\n產品\n", nil, "INVALID_IMPORT_FILE"},
+		{"無效的 dry_run", "products.csv", "sku_code\n", map[string]string{"dry_run": "maybe"}, "INVALID_REQUEST_DATA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 設置模擬服務和路由
+			mockService := new(MockProductService)
+			router := setupTestRouter(mockService)
+
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, newImportRequest(t, tt.filename, tt.content, tt.fields))
+
+			// 驗證結果
+			assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+			var response controller.ErrorResponse
+			assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			assert.Equal(t, tt.code, response.ErrorCode)
+			mockService.AssertNotCalled(t, "ImportProducts", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"main/internal/controller"
+	"main/internal/models"
+	"main/internal/repository"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// 測試依 sku_code 獲取產品
+func TestGetProductBySku(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockProductService)
+	router := setupTestRouter(mockService)
+
+	// 設置模擬服務預期行為
+	mockService.On("GetProductBySku", mock.Anything, "SKU001").Return(models.Product{ID: 1, SkuCode: "SKU001", Version: 4}, nil)
+	mockService.On("GetProductBySku", mock.Anything, "NONE").Return(models.Product{}, repository.ErrProductNotFound)
+
+	// 執行請求
+	resp := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/products/by-sku/SKU001", nil)
+	router.ServeHTTP(resp, req)
+
+	// 驗證結果
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, `"4"`, resp.Header().Get("ETag"))
+
+	resp = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/api/v1/products/by-sku/NONE", nil)
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+
+	// 驗證模擬服務方法被調用
+	mockService.AssertExpectations(t)
+}
+
+// 測試 PUT by-sku 新建時返回 201，更新時返回 200
+func TestUpsertProductBySku(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockProductService)
+	router := setupTestRouter(mockService)
+
+	input := models.Product{SkuName: "產品 1", SkuAmount: 3}
+
+	// 設置模擬服務預期行為
+	mockService.On("UpsertProductBySku", mock.Anything, "SKU001", 0, input).
+		Return(models.Product{ID: 1, SkuCode: "SKU001", Version: 1}, true, nil).Once()
+	mockService.On("UpsertProductBySku", mock.Anything, "SKU001", 0, input).
+		Return(models.Product{ID: 1, SkuCode: "SKU001", Version: 1}, false, nil).Once()
+
+	for _, expected := range []int{http.StatusCreated, http.StatusOK} {
+		jsonBody, _ := json.Marshal(input)
+		req, _ := http.NewRequest(http.MethodPut, "/api/v1/products/by-sku/SKU001", bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		// 驗證結果
+		assert.Equal(t, expected, resp.Code)
+		assert.Equal(t, `"1"`, resp.Header().Get("ETag"))
+	}
+
+	// 驗證模擬服務方法被調用
+	mockService.AssertExpectations(t)
+}
+
+// 測試請求內容的 sku_code 與路徑不一致
+func TestUpsertProductBySkuMismatch(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockProductService)
+	router := setupTestRouter(mockService)
+
+	jsonBody, _ := json.Marshal(models.Product{SkuCode: "SKU002", SkuName: "產品"})
+	req, _ := http.NewRequest(http.MethodPut, "/api/v1/products/by-sku/SKU001", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	// 驗證結果
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	mockService.AssertNotCalled(t, "UpsertProductBySku", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// 測試創建重複 sku_code 的產品返回 409
+func TestCreateProductDuplicateSku(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockProductService)
+	router := setupTestRouter(mockService)
+
+	input := models.Product{SkuCode: "SKU001", SkuName: "產品 1"}
+	mockService.On("CreateProduct", mock.Anything, input).Return(models.Product{}, repository.ErrDuplicateSku)
+
+	jsonBody, _ := json.Marshal(input)
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/products", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	// 驗證結果
+	assert.Equal(t, http.StatusConflict, resp.Code)
+
+	var response controller.ErrorResponse
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), &response))
+	assert.Equal(t, "DUPLICATE_SKU", response.ErrorCode)
+}
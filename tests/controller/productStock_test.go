@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"main/internal/models"
+	"main/internal/repository"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// 測試原子調整庫存的狀態碼、錯誤碼與 ETag
+func TestAdjustStock(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockProductService)
+	router := setupTestRouter(mockService)
+
+	mockService.On("AdjustStock", mock.Anything, int64(1), -3).Return(models.Product{ID: 1, SkuAmount: 7, Version: 4}, nil)
+	mockService.On("AdjustStock", mock.Anything, int64(1), -100).Return(models.Product{}, repository.ErrInsufficientStock)
+	mockService.On("AdjustStock", mock.Anything, int64(999), 1).Return(models.Product{}, repository.ErrProductNotFound)
+	mockService.On("AdjustStock", mock.Anything, int64(1), 0).Return(models.Product{}, &models.ValidationError{Message: "delta 不能為 0"})
+
+	cases := []struct {
+		path   string
+		body   string
+		status int
+		code   string
+	}{
+		{"/api/v1/products/1/stock:adjust", `{"delta": -3}`, http.StatusOK, ""},
+		{"/api/v1/products/1/stock:adjust", `{"delta": -100}`, http.StatusConflict, "INSUFFICIENT_STOCK"},
+		{"/api/v1/products/999/stock:adjust", `{"delta": 1}`, http.StatusNotFound, "PRODUCT_NOT_FOUND"},
+		{"/api/v1/products/1/stock:adjust", `{}`, http.StatusBadRequest, "PRODUCT_VALIDATION_ERROR"},
+		{"/api/v1/products/1/stock:adjust", `{"delta": "1"}`, http.StatusBadRequest, "INVALID_REQUEST_DATA"},
+		{"/api/v1/products/abc/stock:adjust", `{"delta": 1}`, http.StatusBadRequest, "INVALID_PRODUCT_ID"},
+		{"/api/v1/products/1/stock:reset", `{"delta": 1}`, http.StatusNotFound, "NOT_FOUND"},
+	}
+
+	for _, tc := range cases {
+		resp := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, tc.path, bytes.NewBufferString(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, tc.status, resp.Code, tc.path+" "+tc.body)
+
+		if tc.code != "" {
+			var response map[string]interface{}
+			assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			assert.Equal(t, tc.code, response["error_code"], tc.path+" "+tc.body)
+		} else {
+			assert.Equal(t, `"4"`, resp.Header().Get("ETag"))
+		}
+	}
+
+	// 驗證模擬服務方法被調用
+	mockService.AssertExpectations(t)
+}
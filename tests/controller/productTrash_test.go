@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"encoding/json"
+	"main/internal/controller"
+	"main/internal/middleware"
+	"main/internal/models"
+	"main/internal/pagination"
+	"main/internal/repository"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+// 測試列出回收站
+func TestGetTrash(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockProductService)
+	router := setupTestRouter(mockService)
+
+	page := models.ProductPage{
+		Items:    []models.Product{{ID: 3, SkuCode: "SKU003", DeletedAt: mustTimestamp("2025-03-01T10:00:00Z")}},
+		Total:    3,
+		PageSize: 1,
+		Offset:   1,
+		HasNext:  true,
+		HasPrev:  true,
+	}
+	mockService.On("GetDeletedProducts", mock.Anything, models.ProductQuery{PageSize: 1, Offset: 1}).Return(page, nil)
+
+	// 執行請求
+	resp := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/products/trash?page_size=1&offset=1", nil)
+	router.ServeHTTP(resp, req)
+
+	// 驗證結果
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var response models.ProductPage
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+	assert.Equal(t, "2025-03-01T10:00:00Z", response.Items[0].DeletedAt.String())
+	assert.Equal(t, "/api/v1/products/trash?offset=2&page_size=1", response.Links.Next)
+
+	// 驗證模擬服務方法被調用
+	mockService.AssertExpectations(t)
+}
+
+// 測試還原產品的回應
+func TestRestoreProduct(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockProductService)
+	router := setupTestRouter(mockService)
+
+	mockService.On("RestoreProduct", mock.Anything, int64(1)).Return(models.Product{ID: 1, SkuCode: "SKU001", Version: 3}, nil)
+	mockService.On("RestoreProduct", mock.Anything, int64(2)).Return(models.Product{}, repository.ErrProductNotFound)
+	mockService.On("RestoreProduct", mock.Anything, int64(3)).Return(models.Product{}, repository.ErrDuplicateSku)
+
+	cases := []struct {
+		path   string
+		status int
+		code   string
+	}{
+		{"/api/v1/products/1/restore", http.StatusOK, ""},
+		{"/api/v1/products/2/restore", http.StatusNotFound, "PRODUCT_NOT_FOUND"},
+		{"/api/v1/products/3/restore", http.StatusConflict, "DUPLICATE_SKU"},
+	}
+
+	for _, tc := range cases {
+		resp := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, tc.path, nil)
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, tc.status, resp.Code, tc.path)
+		if tc.code != "" {
+			var response controller.ErrorResponse
+			assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			assert.Equal(t, tc.code, response.ErrorCode, tc.path)
+		} else {
+			assert.Equal(t, `"3"`, resp.Header().Get("ETag"))
+		}
+	}
+
+	// 驗證模擬服務方法被調用
+	mockService.AssertExpectations(t)
+}
+
+// 測試永久刪除需要管理員令牌
+func TestPurgeProductRequiresAdmin(t *testing.T) {
+	// 設置模擬服務和帶管理端點的路由
+	mockService := new(MockProductService)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Unauthenticated())
+	logger, _ := zap.NewDevelopment()
+	productController := controller.NewProducController(mockService, logger, pagination.NewCursorSigner(testCursorSecret))
+	productController.RegisterAdminRoutes(router.Group("/api/v1/admin", middleware.AdminToken("secret")))
+
+	mockService.On("PurgeProduct", mock.Anything, int64(1)).Return(nil)
+	mockService.On("PurgeProduct", mock.Anything, int64(2)).Return(repository.ErrProductNotFound)
+
+	purge := func(id string, token string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodDelete, "/api/v1/admin/products/"+id, nil)
+		if token != "" {
+			req.Header.Set(middleware.AdminTokenHeader, token)
+		}
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	// 缺少或錯誤的令牌時不調用服務
+	assert.Equal(t, http.StatusForbidden, purge("1", "").Code)
+	assert.Equal(t, http.StatusForbidden, purge("1", "wrong").Code)
+	mockService.AssertNotCalled(t, "PurgeProduct", mock.Anything, mock.Anything)
+
+	assert.Equal(t, http.StatusNoContent, purge("1", "secret").Code)
+	assert.Equal(t, http.StatusNotFound, purge("2", "secret").Code)
+
+	// 驗證模擬服務方法被調用
+	mockService.AssertExpectations(t)
+}
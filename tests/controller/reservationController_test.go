@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"main/internal/controller"
+	"main/internal/middleware"
+	"main/internal/models"
+	"main/internal/repository"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+// 模擬預留服務
+type MockReservationService struct {
+	mock.Mock
+}
+
+func (m *MockReservationService) Reserve(ctx context.Context, productID int64, req models.ReservationRequest) (models.StockReservation, bool, error) {
+	args := m.Called(ctx, productID, req)
+	return args.Get(0).(models.StockReservation), args.Bool(1), args.Error(2)
+}
+
+func (m *MockReservationService) GetReservation(ctx context.Context, id int64) (models.StockReservation, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.StockReservation), args.Error(1)
+}
+
+func (m *MockReservationService) ListReservations(ctx context.Context, productID int64) ([]models.StockReservation, error) {
+	args := m.Called(ctx, productID)
+	reservations, _ := args.Get(0).([]models.StockReservation)
+	return reservations, args.Error(1)
+}
+
+func (m *MockReservationService) GetAvailability(ctx context.Context, productID int64) (models.StockAvailability, error) {
+	args := m.Called(ctx, productID)
+	return args.Get(0).(models.StockAvailability), args.Error(1)
+}
+
+func (m *MockReservationService) Commit(ctx context.Context, id int64, req models.CommitReservationRequest) (models.StockReservation, error) {
+	args := m.Called(ctx, id, req)
+	return args.Get(0).(models.StockReservation), args.Error(1)
+}
+
+func (m *MockReservationService) Release(ctx context.Context, id int64) (models.StockReservation, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.StockReservation), args.Error(1)
+}
+
+func (m *MockReservationService) ExpireReservations(ctx context.Context, now time.Time) (int64, error) {
+	args := m.Called(ctx, now)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func setupReservationRouter(mockService *MockReservationService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Unauthenticated())
+
+	logger, _ := zap.NewDevelopment()
+	controller.NewReservationController(mockService, logger).RegisterRoutes(router)
+
+	return router
+}
+
+// 測試預留端點的狀態碼與錯誤碼
+func TestReservationEndpoints(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockReservationService)
+	router := setupReservationRouter(mockService)
+
+	mockService.On("Reserve", mock.Anything, int64(1), models.ReservationRequest{Quantity: 2, Reference: "CART-1"}).
+		Return(models.StockReservation{ID: 7, Status: models.ReservationActive}, true, nil)
+	mockService.On("Reserve", mock.Anything, int64(1), models.ReservationRequest{Quantity: 2, Reference: "CART-2"}).
+		Return(models.StockReservation{ID: 8, Status: models.ReservationActive}, false, nil)
+	mockService.On("Reserve", mock.Anything, int64(1), models.ReservationRequest{Quantity: 3, Reference: "CART-2"}).
+		Return(models.StockReservation{}, false, repository.ErrReservationConflict)
+	mockService.On("Reserve", mock.Anything, int64(1), models.ReservationRequest{Quantity: 500, Reference: "CART-3"}).
+		Return(models.StockReservation{}, false, repository.ErrInsufficientStock)
+	mockService.On("Reserve", mock.Anything, int64(1), models.ReservationRequest{Quantity: 1}).
+		Return(models.StockReservation{}, false, &models.ValidationError{Message: "reference 不能為空"})
+	mockService.On("GetAvailability", mock.Anything, int64(999)).
+		Return(models.StockAvailability{}, repository.ErrProductNotFound)
+	mockService.On("GetReservation", mock.Anything, int64(999)).
+		Return(models.StockReservation{}, repository.ErrReservationNotFound)
+	mockService.On("Commit", mock.Anything, int64(7), models.CommitReservationRequest{}).
+		Return(models.StockReservation{ID: 7, Status: models.ReservationCommitted, MovementID: 30}, nil)
+	mockService.On("Commit", mock.Anything, int64(8), models.CommitReservationRequest{LocationID: 5}).
+		Return(models.StockReservation{}, repository.ErrReservationExpired)
+	mockService.On("Release", mock.Anything, int64(7)).
+		Return(models.StockReservation{}, repository.ErrReservationNotActive)
+
+	cases := []struct {
+		method string
+		path   string
+		body   string
+		status int
+		code   string
+	}{
+		{http.MethodPost, "/api/v1/products/1/reservations", `{"quantity": 2, "reference": "CART-1"}`, http.StatusCreated, ""},
+		{http.MethodPost, "/api/v1/products/1/reservations", `{"quantity": 2, "reference": "CART-2"}`, http.StatusOK, ""},
+		{http.MethodPost, "/api/v1/products/1/reservations", `{"quantity": 3, "reference": "CART-2"}`, http.StatusConflict, "RESERVATION_CONFLICT"},
+		{http.MethodPost, "/api/v1/products/1/reservations", `{"quantity": 500, "reference": "CART-3"}`, http.StatusConflict, "INSUFFICIENT_STOCK"},
+		{http.MethodPost, "/api/v1/products/1/reservations", `{"quantity": 1}`, http.StatusBadRequest, "RESERVATION_VALIDATION_ERROR"},
+		{http.MethodPost, "/api/v1/products/abc/reservations", `{}`, http.StatusBadRequest, "INVALID_PRODUCT_ID"},
+		{http.MethodGet, "/api/v1/products/999/availability", "", http.StatusNotFound, "PRODUCT_NOT_FOUND"},
+		{http.MethodGet, "/api/v1/reservations/999", "", http.StatusNotFound, "RESERVATION_NOT_FOUND"},
+		{http.MethodGet, "/api/v1/reservations/abc", "", http.StatusBadRequest, "INVALID_RESERVATION_ID"},
+		{http.MethodPost, "/api/v1/reservations/7/commit", "", http.StatusOK, ""},
+		{http.MethodPost, "/api/v1/reservations/8/commit", `{"location_id": 5}`, http.StatusConflict, "RESERVATION_EXPIRED"},
+		{http.MethodPost, "/api/v1/reservations/7/release", "", http.StatusConflict, "RESERVATION_NOT_ACTIVE"},
+	}
+
+	for _, tc := range cases {
+		resp := httptest.NewRecorder()
+		req, _ := http.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, tc.status, resp.Code, tc.method+" "+tc.path+" "+tc.body)
+
+		if tc.code != "" {
+			var response map[string]interface{}
+			assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			assert.Equal(t, tc.code, response["error_code"], tc.method+" "+tc.path+" "+tc.body)
+		}
+	}
+
+	// 驗證模擬服務方法被調用
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,234 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"main/internal/controller"
+	"main/internal/middleware"
+	"main/internal/models"
+	"main/internal/repository"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+// 模擬庫存異動服務
+type MockStockService struct {
+	mock.Mock
+}
+
+func (m *MockStockService) Receive(ctx context.Context, productID int64, req models.StockRequest) (models.StockMovement, error) {
+	args := m.Called(ctx, productID, req)
+	return args.Get(0).(models.StockMovement), args.Error(1)
+}
+
+func (m *MockStockService) Issue(ctx context.Context, productID int64, req models.StockRequest) (models.StockMovement, error) {
+	args := m.Called(ctx, productID, req)
+	return args.Get(0).(models.StockMovement), args.Error(1)
+}
+
+func (m *MockStockService) Adjust(ctx context.Context, productID int64, req models.StockRequest) (models.StockMovement, error) {
+	args := m.Called(ctx, productID, req)
+	return args.Get(0).(models.StockMovement), args.Error(1)
+}
+
+func (m *MockStockService) Transfer(ctx context.Context, productID int64, req models.TransferRequest) ([]models.StockMovement, error) {
+	args := m.Called(ctx, productID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.StockMovement), args.Error(1)
+}
+
+func (m *MockStockService) GetMovements(ctx context.Context, productID int64, query models.ProductQuery) (models.StockMovementPage, error) {
+	args := m.Called(ctx, productID, query)
+	return args.Get(0).(models.StockMovementPage), args.Error(1)
+}
+
+func (m *MockStockService) GetMovement(ctx context.Context, productID int64, movementID int64) (models.StockMovement, error) {
+	args := m.Called(ctx, productID, movementID)
+	return args.Get(0).(models.StockMovement), args.Error(1)
+}
+
+func (m *MockStockService) GetLots(ctx context.Context, productID int64) (models.ProductLotStock, error) {
+	args := m.Called(ctx, productID)
+	return args.Get(0).(models.ProductLotStock), args.Error(1)
+}
+
+func setupStockRouter(mockService *MockStockService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Unauthenticated())
+
+	logger, _ := zap.NewDevelopment()
+	controller.NewStockController(mockService, logger).RegisterRoutes(router)
+
+	return router
+}
+
+// 測試依 type 登記庫存異動
+func TestPostMovement(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockStockService)
+	router := setupStockRouter(mockService)
+
+	mockService.On("Receive", mock.Anything, int64(1), models.StockRequest{Quantity: 10, Reason: models.ReasonPurchase, Reference: "PO-1"}).
+		Return(models.StockMovement{ID: 1, ProductID: 1, Type: models.MovementReceive, Quantity: 10, Balance: 10}, nil)
+	mockService.On("Issue", mock.Anything, int64(1), models.StockRequest{Quantity: 20}).
+		Return(models.StockMovement{}, repository.ErrInsufficientStock)
+	mockService.On("Adjust", mock.Anything, int64(1), models.StockRequest{Quantity: 0}).
+		Return(models.StockMovement{}, &models.ValidationError{Message: "調整數量不能為 0"})
+	mockService.On("Transfer", mock.Anything, int64(1), models.TransferRequest{StockRequest: models.StockRequest{Quantity: 4}, ToLocationID: 2}).
+		Return([]models.StockMovement{{ID: 2, ProductID: 1, Quantity: -4}, {ID: 3, ProductID: 1, LocationID: 2, Quantity: 4}}, nil)
+	mockService.On("Transfer", mock.Anything, int64(1), models.TransferRequest{StockRequest: models.StockRequest{Quantity: 4, LocationID: 1}, ToLocationID: 99}).
+		Return(nil, repository.ErrLocationNotFound)
+	mockService.On("Receive", mock.Anything, int64(999), mock.Anything).
+		Return(models.StockMovement{}, repository.ErrProductNotFound)
+
+	cases := []struct {
+		path   string
+		body   string
+		status int
+		code   string
+		items  int
+	}{
+		{"/api/v1/products/1/movements", `{"type": "receive", "quantity": 10, "reason": "purchase", "reference": "PO-1"}`, http.StatusCreated, "", 1},
+		{"/api/v1/products/1/movements", `{"type": "issue", "quantity": 20}`, http.StatusConflict, "INSUFFICIENT_STOCK", 0},
+		{"/api/v1/products/1/movements", `{"type": "adjust", "quantity": 0}`, http.StatusBadRequest, "PRODUCT_VALIDATION_ERROR", 0},
+		{"/api/v1/products/1/movements", `{"type": "transfer", "quantity": 4, "to_location_id": 2}`, http.StatusCreated, "", 2},
+		{"/api/v1/products/1/movements", `{"type": "transfer", "quantity": 4, "to_product_id": 1, "to_location_id": 2}`, http.StatusCreated, "", 2},
+		{"/api/v1/products/1/movements", `{"type": "transfer", "quantity": 4, "to_product_id": 2, "to_location_id": 2}`, http.StatusBadRequest, "PRODUCT_VALIDATION_ERROR", 0},
+		{"/api/v1/products/1/movements", `{"type": "transfer", "quantity": 4, "location_id": 1, "to_location_id": 99}`, http.StatusNotFound, "LOCATION_NOT_FOUND", 0},
+		{"/api/v1/products/999/movements", `{"type": "receive", "quantity": 1}`, http.StatusNotFound, "PRODUCT_NOT_FOUND", 0},
+		{"/api/v1/products/1/movements", `{"type": "sell", "quantity": 1}`, http.StatusBadRequest, "INVALID_MOVEMENT_TYPE", 0},
+		{"/api/v1/products/1/movements", `{"quantity": 1}`, http.StatusBadRequest, "INVALID_REQUEST_DATA", 0},
+		{"/api/v1/products/abc/movements", `{"type": "receive", "quantity": 1}`, http.StatusBadRequest, "INVALID_PRODUCT_ID", 0},
+	}
+
+	for _, tc := range cases {
+		resp := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, tc.path, bytes.NewBufferString(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, tc.status, resp.Code, tc.body)
+
+		var response map[string]interface{}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+		if tc.code != "" {
+			assert.Equal(t, tc.code, response["error_code"], tc.body)
+			continue
+		}
+		assert.Len(t, response["items"], tc.items, tc.body)
+	}
+
+	// 驗證模擬服務方法被調用
+	mockService.AssertExpectations(t)
+}
+
+// 測試列出產品的庫存異動
+func TestGetMovements(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockStockService)
+	router := setupStockRouter(mockService)
+
+	page := models.StockMovementPage{
+		Items:    []models.StockMovement{{ID: 2, ProductID: 1, Type: models.MovementIssue, Quantity: -3, Reason: models.ReasonSale, Balance: 7}},
+		Total:    2,
+		PageSize: 1,
+		HasNext:  true,
+	}
+	mockService.On("GetMovements", mock.Anything, int64(1), models.ProductQuery{PageSize: 1}).Return(page, nil)
+
+	// 執行請求
+	resp := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/products/1/movements?page_size=1", nil)
+	router.ServeHTTP(resp, req)
+
+	// 驗證結果
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var response models.StockMovementPage
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+	assert.Equal(t, -3, response.Items[0].Quantity)
+	assert.Equal(t, 7, response.Items[0].Balance)
+	assert.Equal(t, "/api/v1/products/1/movements?offset=1&page_size=1", response.Links.Next)
+
+	// 驗證模擬服務方法被調用
+	mockService.AssertExpectations(t)
+}
+
+// 測試列出批次庫存與查詢出庫消耗的批次
+func TestGetLotsAndMovement(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockStockService)
+	router := setupStockRouter(mockService)
+
+	mockService.On("GetLots", mock.Anything, int64(1)).Return(models.ProductLotStock{
+		ProductID:         1,
+		Items:             []models.ProductLot{{ID: 3, ProductID: 1, LotNumber: "L-2025-01", Quantity: 4, Expiration: models.MustParseDate("2025-06-30")}},
+		UntrackedQuantity: 2,
+	}, nil)
+	mockService.On("GetLots", mock.Anything, int64(999)).Return(models.ProductLotStock{}, repository.ErrProductNotFound)
+	mockService.On("GetMovement", mock.Anything, int64(1), int64(7)).Return(models.StockMovement{
+		ID: 7, ProductID: 1, Type: models.MovementIssue, Quantity: -5,
+		Lots: []models.MovementLot{
+			{LotID: 2, LotNumber: "L-2024-12", Quantity: -1},
+			{LotID: 3, LotNumber: "L-2025-01", Quantity: -4},
+		},
+	}, nil)
+	mockService.On("GetMovement", mock.Anything, int64(1), int64(999)).Return(models.StockMovement{}, repository.ErrMovementNotFound)
+
+	// 批次庫存
+	resp := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/products/1/lots", nil)
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var lots models.ProductLotStock
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &lots))
+	assert.Len(t, lots.Items, 1)
+	assert.Equal(t, "2025-06-30", lots.Items[0].Expiration.String())
+	assert.Equal(t, 2, lots.UntrackedQuantity)
+
+	// 出庫消耗的批次
+	resp = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/api/v1/products/1/movements/7", nil)
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var movement models.StockMovement
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &movement))
+	assert.Len(t, movement.Lots, 2)
+	assert.Equal(t, -4, movement.Lots[1].Quantity)
+
+	cases := []struct {
+		path   string
+		status int
+		code   string
+	}{
+		{"/api/v1/products/999/lots", http.StatusNotFound, "PRODUCT_NOT_FOUND"},
+		{"/api/v1/products/1/movements/999", http.StatusNotFound, "MOVEMENT_NOT_FOUND"},
+		{"/api/v1/products/1/movements/abc", http.StatusBadRequest, "INVALID_MOVEMENT_ID"},
+	}
+
+	for _, tc := range cases {
+		resp := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, tc.path, nil)
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, tc.status, resp.Code, tc.path)
+		var response map[string]interface{}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+		assert.Equal(t, tc.code, response["error_code"], tc.path)
+	}
+
+	// 驗證模擬服務方法被調用
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,155 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"main/internal/controller"
+	"main/internal/middleware"
+	"main/internal/models"
+	"main/internal/repository"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+// 模擬倉庫服務
+type MockWarehouseService struct {
+	mock.Mock
+}
+
+func (m *MockWarehouseService) ListWarehouses(ctx context.Context) ([]models.Warehouse, error) {
+	args := m.Called(ctx)
+	warehouses, _ := args.Get(0).([]models.Warehouse)
+	return warehouses, args.Error(1)
+}
+
+func (m *MockWarehouseService) GetWarehouse(ctx context.Context, id int64) (models.Warehouse, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.Warehouse), args.Error(1)
+}
+
+func (m *MockWarehouseService) CreateWarehouse(ctx context.Context, input models.Warehouse) (models.Warehouse, error) {
+	args := m.Called(ctx, input)
+	return args.Get(0).(models.Warehouse), args.Error(1)
+}
+
+func (m *MockWarehouseService) UpdateWarehouse(ctx context.Context, id int64, input models.Warehouse) (models.Warehouse, error) {
+	args := m.Called(ctx, id, input)
+	return args.Get(0).(models.Warehouse), args.Error(1)
+}
+
+func (m *MockWarehouseService) DeleteWarehouse(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockWarehouseService) ListLocations(ctx context.Context, warehouseID int64) ([]models.StockLocation, error) {
+	args := m.Called(ctx, warehouseID)
+	locations, _ := args.Get(0).([]models.StockLocation)
+	return locations, args.Error(1)
+}
+
+func (m *MockWarehouseService) GetLocation(ctx context.Context, id int64) (models.StockLocation, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.StockLocation), args.Error(1)
+}
+
+func (m *MockWarehouseService) CreateLocation(ctx context.Context, warehouseID int64, input models.StockLocation) (models.StockLocation, error) {
+	args := m.Called(ctx, warehouseID, input)
+	return args.Get(0).(models.StockLocation), args.Error(1)
+}
+
+func (m *MockWarehouseService) UpdateLocation(ctx context.Context, id int64, input models.StockLocation) (models.StockLocation, error) {
+	args := m.Called(ctx, id, input)
+	return args.Get(0).(models.StockLocation), args.Error(1)
+}
+
+func (m *MockWarehouseService) DeleteLocation(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func setupWarehouseRouter(mockService *MockWarehouseService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Unauthenticated())
+
+	logger, _ := zap.NewDevelopment()
+	controller.NewWarehouseController(mockService, logger).RegisterRoutes(router)
+
+	return router
+}
+
+// 測試倉庫與儲位端點的狀態碼與錯誤碼
+func TestWarehouseEndpoints(t *testing.T) {
+	// 設置模擬服務和路由
+	mockService := new(MockWarehouseService)
+	router := setupWarehouseRouter(mockService)
+
+	mockService.On("ListWarehouses", mock.Anything).
+		Return([]models.Warehouse{{ID: 1, Code: "MAIN", Name: "主倉庫"}}, nil)
+	mockService.On("CreateWarehouse", mock.Anything, models.Warehouse{Code: "EAST", Name: "東區倉庫"}).
+		Return(models.Warehouse{ID: 2, Code: "EAST", Name: "東區倉庫"}, nil)
+	mockService.On("CreateWarehouse", mock.Anything, models.Warehouse{Code: "MAIN", Name: "重複"}).
+		Return(models.Warehouse{}, repository.ErrDuplicateWarehouse)
+	mockService.On("CreateWarehouse", mock.Anything, models.Warehouse{Name: "沒有代碼"}).
+		Return(models.Warehouse{}, &models.ValidationError{Message: "倉庫代碼不能為空"})
+	mockService.On("GetWarehouse", mock.Anything, int64(999)).
+		Return(models.Warehouse{}, repository.ErrWarehouseNotFound)
+	mockService.On("DeleteWarehouse", mock.Anything, int64(1)).
+		Return(repository.ErrWarehouseInUse)
+	mockService.On("CreateLocation", mock.Anything, int64(2), models.StockLocation{Code: "A-01", Name: "A 區"}).
+		Return(models.StockLocation{ID: 5, WarehouseID: 2, Code: "A-01", Name: "A 區"}, nil)
+	mockService.On("DeleteLocation", mock.Anything, int64(1)).
+		Return(repository.ErrDefaultLocation)
+	mockService.On("DeleteLocation", mock.Anything, int64(5)).
+		Return(repository.ErrLocationInUse)
+	mockService.On("GetLocation", mock.Anything, int64(999)).
+		Return(models.StockLocation{}, repository.ErrLocationNotFound)
+
+	cases := []struct {
+		method string
+		path   string
+		body   string
+		status int
+		code   string
+	}{
+		{http.MethodGet, "/api/v1/warehouses", "", http.StatusOK, ""},
+		{http.MethodPost, "/api/v1/warehouses", `{"code": "EAST", "name": "東區倉庫"}`, http.StatusCreated, ""},
+		{http.MethodPost, "/api/v1/warehouses", `{"code": "MAIN", "name": "重複"}`, http.StatusConflict, "DUPLICATE_WAREHOUSE"},
+		{http.MethodPost, "/api/v1/warehouses", `{"name": "沒有代碼"}`, http.StatusBadRequest, "WAREHOUSE_VALIDATION_ERROR"},
+		{http.MethodPost, "/api/v1/warehouses", `{"code": `, http.StatusBadRequest, "INVALID_REQUEST_DATA"},
+		{http.MethodGet, "/api/v1/warehouses/999", "", http.StatusNotFound, "WAREHOUSE_NOT_FOUND"},
+		{http.MethodGet, "/api/v1/warehouses/abc", "", http.StatusBadRequest, "INVALID_WAREHOUSE_ID"},
+		{http.MethodDelete, "/api/v1/warehouses/1", "", http.StatusConflict, "WAREHOUSE_IN_USE"},
+		{http.MethodPost, "/api/v1/warehouses/2/locations", `{"code": "A-01", "name": "A 區"}`, http.StatusCreated, ""},
+		{http.MethodDelete, "/api/v1/locations/1", "", http.StatusConflict, "DEFAULT_LOCATION"},
+		{http.MethodDelete, "/api/v1/locations/5", "", http.StatusConflict, "LOCATION_IN_USE"},
+		{http.MethodGet, "/api/v1/locations/999", "", http.StatusNotFound, "LOCATION_NOT_FOUND"},
+		{http.MethodGet, "/api/v1/locations/abc", "", http.StatusBadRequest, "INVALID_LOCATION_ID"},
+	}
+
+	for _, tc := range cases {
+		resp := httptest.NewRecorder()
+		req, _ := http.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, tc.status, resp.Code, tc.method+" "+tc.path)
+
+		if tc.code != "" {
+			var response map[string]interface{}
+			assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			assert.Equal(t, tc.code, response["error_code"], tc.method+" "+tc.path)
+		}
+	}
+
+	// 驗證模擬服務方法被調用
+	mockService.AssertExpectations(t)
+}
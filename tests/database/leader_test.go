@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"main/pkg/database"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// 測試未取得鎖時不是領導者，取得後以同一條連線確認並在釋放時解鎖
+func TestLeaderLock(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	lock := database.NewLeaderLock(db, 42)
+
+	mock.ExpectQuery(`SELECT pg_try_advisory_lock\(\$1\)`).
+		WithArgs(int64(42)).
+		WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(false))
+	mock.ExpectQuery(`SELECT pg_try_advisory_lock\(\$1\)`).
+		WithArgs(int64(42)).
+		WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(true))
+	mock.ExpectExec(`SELECT 1`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).
+		WithArgs(int64(42)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// 其他實例持有鎖
+	ok, err := lock.TryAcquire(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// 取得鎖後再次呼叫只確認連線
+	ok, err = lock.TryAcquire(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = lock.TryAcquire(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	lock.Release()
+	lock.Release()
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試領導者的連線失效時放棄領導權
+func TestLeaderLockLost(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	lock := database.NewLeaderLock(db, 42)
+
+	mock.ExpectQuery(`SELECT pg_try_advisory_lock\(\$1\)`).
+		WithArgs(int64(42)).
+		WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(true))
+	mock.ExpectExec(`SELECT 1`).WillReturnError(errors.New("連線中斷"))
+
+	ok, err := lock.TryAcquire(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = lock.TryAcquire(context.Background())
+	assert.Error(t, err)
+	assert.False(t, ok)
+
+	// 已不是領導者，釋放時不需要解鎖
+	lock.Release()
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
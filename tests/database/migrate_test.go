@@ -0,0 +1,176 @@
+package database
+
+import (
+	"errors"
+	"main/pkg/database"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 創建測試環境
+func setupMockDB(t *testing.T) (*sqlx.DB, sqlmock.Sqlmock) {
+	// 創建 sqlmock
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	// 將普通 sql.DB 轉為 sqlx.DB
+	db := sqlx.NewDb(mockDB, "sqlmock")
+
+	return db, mock
+}
+
+// 設置取得遷移鎖與建立版本表的預期
+func expectLock(mock sqlmock.Sqlmock, appliedVersions ...int64) {
+	mock.ExpectExec(`SELECT pg_advisory_lock\(\$1\)`).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	rows := sqlmock.NewRows([]string{"version", "applied_at"})
+	for _, version := range appliedVersions {
+		rows.AddRow(version, time.Now())
+	}
+	mock.ExpectQuery(`SELECT version, applied_at FROM schema_migrations`).WillReturnRows(rows)
+}
+
+// 設置釋放遷移鎖的預期
+func expectUnlock(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+// 測試載入內嵌遷移檔案
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := database.LoadMigrations()
+
+	// 驗證結果
+	assert.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	assert.Equal(t, int64(1), migrations[0].Version)
+	assert.Equal(t, "create_products", migrations[0].Name)
+
+	// 版本必須遞增且每個版本都有 up 與 down
+	for i, m := range migrations {
+		assert.NotEmpty(t, m.Up, m.Name)
+		assert.NotEmpty(t, m.Down, m.Name)
+		if i > 0 {
+			assert.Greater(t, m.Version, migrations[i-1].Version)
+		}
+	}
+}
+
+// 測試只套用尚未執行的遷移
+func TestMigratorUpAppliesPending(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	migrations, err := database.LoadMigrations()
+	require.NoError(t, err)
+
+	// 除最後一個版本外都已套用
+	applied := []int64{}
+	for _, m := range migrations[:len(migrations)-1] {
+		applied = append(applied, m.Version)
+	}
+	pending := migrations[len(migrations)-1]
+
+	// 設置 SQL 預期 - 遷移腳本與版本記錄在同一個交易中
+	expectLock(mock, applied...)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(pending.Up)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO schema_migrations \(version, name\) VALUES \(\$1, \$2\)`).
+		WithArgs(pending.Version, pending.Name).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectUnlock(mock)
+
+	// 執行遷移
+	migrator, err := database.NewMigrator(db)
+	require.NoError(t, err)
+	result, err := migrator.Up()
+
+	// 驗證結果
+	assert.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, pending.Version, result[0].Version)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試遷移失敗時回滾交易且不記錄版本
+func TestMigratorUpRollsBackOnError(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	migrations, err := database.LoadMigrations()
+	require.NoError(t, err)
+
+	// 設置 SQL 預期 - 第一個遷移執行失敗
+	expectLock(mock)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(migrations[0].Up)).WillReturnError(errors.New("語法錯誤"))
+	mock.ExpectRollback()
+	expectUnlock(mock)
+
+	// 執行遷移
+	migrator, err := database.NewMigrator(db)
+	require.NoError(t, err)
+	result, err := migrator.Up()
+
+	// 驗證結果
+	assert.Error(t, err)
+	assert.Empty(t, result)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試回滾最近一次套用的遷移
+func TestMigratorDown(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	migrations, err := database.LoadMigrations()
+	require.NoError(t, err)
+
+	applied := []int64{}
+	for _, m := range migrations {
+		applied = append(applied, m.Version)
+	}
+	latest := migrations[len(migrations)-1]
+
+	// 設置 SQL 預期
+	expectLock(mock, applied...)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(latest.Down)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM schema_migrations WHERE version = \$1`).
+		WithArgs(latest.Version).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectUnlock(mock)
+
+	// 執行回滾
+	migrator, err := database.NewMigrator(db)
+	require.NoError(t, err)
+	result, err := migrator.Down(1)
+
+	// 驗證結果
+	assert.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, latest.Version, result[0].Version)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
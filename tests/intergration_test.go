@@ -2,20 +2,32 @@ package tests
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"main/internal/auth"
 	"main/internal/config"
 	"main/internal/controller"
+	"main/internal/jobs"
+	applog "main/internal/logger"
+	"main/internal/middleware"
 	"main/internal/models"
+	"main/internal/pagination"
 	"main/internal/repository"
 	"main/internal/service"
+	"main/internal/tenant"
 	"main/pkg/database"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jmoiron/sqlx"
@@ -26,6 +38,9 @@ import (
 	"go.uber.org/zap"
 )
 
+// 測試用的管理令牌
+const testAdminToken = "test-admin"
+
 type IntegrationTestSuite struct {
 	suite.Suite
 	db         *sqlx.DB
@@ -33,6 +48,53 @@ type IntegrationTestSuite struct {
 	controller *controller.ProductController
 	pool       *dockertest.Pool
 	resource   *dockertest.Resource
+	events     *eventRecorder
+	alerts     *jobs.AlertRelay
+	expiry     service.ExpiryService
+	apiKeys    service.APIKeyService
+}
+
+// eventRecorder 同步記錄服務發出的低庫存與過期事件
+type eventRecorder struct {
+	mu       sync.Mutex
+	lowStock []models.LowStockEvent
+	expired  []models.ExpiredStockEvent
+	// failLowStock 不為 nil 時低庫存事件送出失敗，模擬通知管道無法使用
+	failLowStock error
+}
+
+func (r *eventRecorder) NotifyLowStock(ctx context.Context, event models.LowStockEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failLowStock != nil {
+		return r.failLowStock
+	}
+	r.lowStock = append(r.lowStock, event)
+	return nil
+}
+
+func (r *eventRecorder) NotifyExpired(ctx context.Context, event models.ExpiredStockEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.expired = append(r.expired, event)
+	return nil
+}
+
+func (r *eventRecorder) resetLowStock() []models.LowStockEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := r.lowStock
+	r.lowStock = nil
+	r.failLowStock = nil
+	return events
+}
+
+func (r *eventRecorder) resetExpired() []models.ExpiredStockEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := r.expired
+	r.expired = nil
+	return events
 }
 
 // 設置測試套件 - 啟動 Docker PostgreSQL
@@ -89,40 +151,59 @@ func (s *IntegrationTestSuite) SetupSuite() {
 	// 設置應用依賴
 	logger, _ := zap.NewDevelopment()
 	productRepo := repository.NewProductRepository(s.db)
+	s.events = &eventRecorder{}
 	productService := service.NewProductService(productRepo)
-	s.controller = controller.NewProducController(productService, logger)
+	s.alerts = jobs.NewAlertRelay(service.NewAlertService(repository.NewAlertRepository(s.db), s.events, time.Second), logger, time.Second)
+	s.expiry = service.NewExpiryService(repository.NewExpiryRepository(s.db), s.events)
+	s.controller = controller.NewProducController(productService, logger, pagination.NewCursorSigner("test-secret"))
 
 	// 設置路由
 	s.router = gin.New()
+	s.router.Use(applog.LoggerMiddleware(logger))
+	s.router.Use(middleware.Actor())
+	s.router.Use(middleware.Tenant())
+	s.router.Use(middleware.Unauthenticated())
 	s.controller.RegisterRoutes(s.router)
+	controller.NewStockController(service.NewStockService(repository.NewStockRepository(s.db)), logger).RegisterRoutes(s.router)
+	controller.NewWarehouseController(service.NewWarehouseService(repository.NewWarehouseRepository(s.db)), logger).RegisterRoutes(s.router)
+	controller.NewReservationController(service.NewReservationService(repository.NewReservationRepository(s.db)), logger).RegisterRoutes(s.router)
+	controller.NewExpiryController(s.expiry, logger).RegisterRoutes(s.router)
+	s.apiKeys = service.NewAPIKeyService(repository.NewAPIKeyRepository(s.db))
+	admin := s.router.Group("/api/v1/admin", middleware.AdminToken(testAdminToken))
+	s.controller.RegisterAdminRoutes(admin)
+	controller.NewAPIKeyController(s.apiKeys, logger).RegisterAdminRoutes(admin)
 }
 
-// 創建測試表
+// 執行資料庫遷移創建測試表
 func (s *IntegrationTestSuite) createTestTables() {
-	// 創建產品表
-	schema := `
-    CREATE TABLE IF NOT EXISTS products (
-        id SERIAL PRIMARY KEY,
-        sku_code VARCHAR(50) NOT NULL,
-        sku_name VARCHAR(100) NOT NULL,
-        sku_amount INT NOT NULL DEFAULT 0,
-        expiration VARCHAR(50),
-        create_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-        update_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-    );
-    `
-	_, err := s.db.Exec(schema)
+	migrator, err := database.NewMigrator(s.db)
 	if err != nil {
+		log.Fatalf("無法載入資料庫遷移: %s", err)
+	}
+
+	if _, err := migrator.Up(); err != nil {
 		log.Fatalf("無法創建測試表: %s", err)
 	}
 }
 
 // 測試每個方法前清理表數據
 func (s *IntegrationTestSuite) SetupTest() {
-	_, err := s.db.Exec("TRUNCATE TABLE products RESTART IDENTITY")
+	_, err := s.db.Exec("TRUNCATE TABLE products, product_history, stock_movements, product_stock, product_lots, stock_movement_lots, stock_reservations, api_keys, low_stock_events RESTART IDENTITY")
 	if err != nil {
 		log.Fatalf("無法清理測試表: %s", err)
 	}
+	s.events.resetLowStock()
+	s.events.resetExpired()
+
+	// 保留遷移建立的主倉庫與預設儲位，其他租戶的在首次寫入時重新建立
+	_, err = s.db.Exec("DELETE FROM stock_locations WHERE NOT is_default OR tenant_id <> 'default'")
+	if err != nil {
+		log.Fatalf("無法清理測試儲位: %s", err)
+	}
+	_, err = s.db.Exec("DELETE FROM warehouses WHERE id NOT IN (SELECT warehouse_id FROM stock_locations)")
+	if err != nil {
+		log.Fatalf("無法清理測試倉庫: %s", err)
+	}
 }
 
 // 拆解測試套件 - 關閉 Docker 容器
@@ -159,7 +240,7 @@ func (s *IntegrationTestSuite) TestCreateProduct() {
 		SkuCode:    "TEST001",
 		SkuName:    "測試產品",
 		SkuAmount:  100,
-		Expiration: "2025-12-31",
+		Expiration: models.DateOf(time.Now().AddDate(1, 0, 0)),
 	}
 
 	jsonBody, _ := json.Marshal(productInput)
@@ -179,6 +260,9 @@ func (s *IntegrationTestSuite) TestCreateProduct() {
 	assert.Equal(s.T(), "測試產品", response.SkuName)
 
 	assert.Equal(s.T(), 100, response.SkuAmount)
+	assert.Equal(s.T(), productInput.Expiration, response.Expiration)
+	assert.False(s.T(), response.CreateAt.IsZero())
+	assert.Equal(s.T(), time.UTC, response.CreateAt.Location())
 }
 
 // 測試獲取所有產品端點
@@ -194,10 +278,64 @@ func (s *IntegrationTestSuite) TestGetProducts() {
 	// 驗證響應
 	assert.Equal(s.T(), http.StatusOK, w.Code)
 
-	var products []models.Product
-	err := json.Unmarshal(w.Body.Bytes(), &products)
+	var page models.ProductPage
+	err := json.Unmarshal(w.Body.Bytes(), &page)
 	assert.NoError(s.T(), err)
-	assert.Len(s.T(), products, 3)
+	assert.Len(s.T(), page.Items, 3)
+	assert.Equal(s.T(), 3, page.Total)
+}
+
+// 測試產品列表分頁與排序
+func (s *IntegrationTestSuite) TestGetProductsPaginated() {
+	// 先添加一些測試數據
+	s.insertTestProducts(5)
+
+	// 發送請求 - 依庫存降序，每頁兩筆，取第二頁
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/products?page_size=2&offset=2&sort_by=sku_amount&sort_dir=desc", nil)
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+
+	// 驗證響應
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+
+	var page models.ProductPage
+	err := json.Unmarshal(w.Body.Bytes(), &page)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), 5, page.Total)
+	assert.Len(s.T(), page.Items, 2)
+	assert.Equal(s.T(), "TEST002", page.Items[0].SkuCode)
+	assert.Equal(s.T(), "TEST001", page.Items[1].SkuCode)
+	assert.NotEmpty(s.T(), page.Links.Next)
+	assert.NotEmpty(s.T(), page.Links.Prev)
+}
+
+// 測試以游標走訪所有產品頁面
+func (s *IntegrationTestSuite) TestGetProductsCursorPagination() {
+	// 先添加一些測試數據
+	s.insertTestProducts(5)
+
+	seen := []string{}
+	next := "/api/v1/products?page_size=2&sort_by=sku_code&sort_dir=desc"
+	for next != "" {
+		req := httptest.NewRequest(http.MethodGet, next, nil)
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+		assert.Equal(s.T(), http.StatusOK, w.Code)
+
+		var page models.ProductPage
+		err := json.Unmarshal(w.Body.Bytes(), &page)
+		assert.NoError(s.T(), err)
+		for _, p := range page.Items {
+			seen = append(seen, p.SkuCode)
+		}
+
+		if page.NextCursor == "" {
+			break
+		}
+		next = "/api/v1/products?page_size=2&cursor=" + url.QueryEscape(page.NextCursor)
+	}
+
+	assert.Equal(s.T(), []string{"TEST004", "TEST003", "TEST002", "TEST001", "TEST000"}, seen)
 }
 
 // 向測試資料庫插入測試產品
@@ -259,18 +397,20 @@ func (s *IntegrationTestSuite) TestUpdateProduct() {
 		SkuCode:    "TEST000",
 		SkuName:    "已更新的測試產品",
 		SkuAmount:  50,
-		Expiration: "2026-06-30",
+		Expiration: models.MustParseDate("2026-06-30"),
 	}
 
 	jsonBody, _ := json.Marshal(updateInput)
 	req := httptest.NewRequest(http.MethodPut, "/api/v1/products/1", bytes.NewBuffer(jsonBody))
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"1"`)
 	w := httptest.NewRecorder()
 
 	s.router.ServeHTTP(w, req)
 
 	// 驗證響應
 	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Equal(s.T(), `"2"`, w.Header().Get("ETag"))
 
 	var response models.Product
 	err := json.Unmarshal(w.Body.Bytes(), &response)
@@ -278,6 +418,44 @@ func (s *IntegrationTestSuite) TestUpdateProduct() {
 	assert.Equal(s.T(), "TEST000", response.SkuCode)
 	assert.Equal(s.T(), "已更新的測試產品", response.SkuName)
 	assert.Equal(s.T(), 50, response.SkuAmount)
+	assert.Equal(s.T(), 2, response.Version)
+
+	// 以舊版本再次更新應被拒絕
+	req = httptest.NewRequest(http.MethodPut, "/api/v1/products/1", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"1"`)
+	w = httptest.NewRecorder()
+
+	s.router.ServeHTTP(w, req)
+
+	assert.Equal(s.T(), http.StatusPreconditionFailed, w.Code)
+}
+
+// 測試以 Merge Patch 部分更新產品
+func (s *IntegrationTestSuite) TestPatchProduct() {
+	// 添加測試數據
+	s.insertTestProducts(1)
+
+	// 只修改名稱並清除到期日，庫存應保持不變
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/products/1",
+		bytes.NewBufferString(`{"sku_name": "部分更新", "expiration": null}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	req.Header.Set("If-Match", `"1"`)
+	w := httptest.NewRecorder()
+
+	s.router.ServeHTTP(w, req)
+
+	// 驗證響應
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+
+	var response models.Product
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "TEST000", response.SkuCode)
+	assert.Equal(s.T(), "部分更新", response.SkuName)
+	assert.Equal(s.T(), 100, response.SkuAmount)
+	assert.Empty(s.T(), response.Expiration)
+	assert.Equal(s.T(), 2, response.Version)
 }
 
 // 測試刪除產品端點
@@ -287,23 +465,669 @@ func (s *IntegrationTestSuite) TestDeleteProduct() {
 
 	// 發送刪除請求
 	req := httptest.NewRequest(http.MethodDelete, "/api/v1/products/1", nil)
+	req.Header.Set("If-Match", `"1"`)
 	w := httptest.NewRecorder()
 	s.router.ServeHTTP(w, req)
 
 	// 驗證響應
 	assert.Equal(s.T(), http.StatusOK, w.Code)
 
-	// 驗證產品已被刪除
+	// 驗證產品已移到回收站，資料仍保留
 	var count int
-	err := s.db.Get(&count, "SELECT COUNT(*) FROM products WHERE id = 1")
+	err := s.db.Get(&count, "SELECT COUNT(*) FROM products WHERE id = 1 AND deleted_at IS NOT NULL")
 	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), 1, count)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/products/1", nil)
+	w = httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	assert.Equal(s.T(), http.StatusNotFound, w.Code)
+}
+
+// 測試回收站列表、還原與永久刪除
+func (s *IntegrationTestSuite) TestTrashRestoreAndPurge() {
+	s.insertTestProducts(2)
+
+	serve := func(method string, path string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", "*")
+		req.Header.Set(middleware.AdminTokenHeader, testAdminToken)
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(s.T(), http.StatusOK, serve(http.MethodDelete, "/api/v1/products/1", "").Code)
+	assert.Equal(s.T(), http.StatusOK, serve(http.MethodDelete, "/api/v1/products/2", "").Code)
+
+	var page models.ProductPage
+	w := serve(http.MethodGet, "/api/v1/products/trash", "")
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Equal(s.T(), 2, page.Total)
+	assert.False(s.T(), page.Items[0].DeletedAt.IsZero())
+
+	// 刪除後 sku_code 可被新產品使用，此時還原原產品會衝突
+	assert.Equal(s.T(), http.StatusCreated, serve(http.MethodPost, "/api/v1/products", `{"sku_code": "TEST000", "sku_name": "替代產品"}`).Code)
+	w = serve(http.MethodPost, "/api/v1/products/1/restore", "")
+	assert.Equal(s.T(), http.StatusConflict, w.Code)
+
+	w = serve(http.MethodPost, "/api/v1/products/2/restore", "")
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Equal(s.T(), http.StatusOK, serve(http.MethodGet, "/api/v1/products/2", "").Code)
+
+	// 未刪除的產品不能永久刪除
+	assert.Equal(s.T(), http.StatusNotFound, serve(http.MethodDelete, "/api/v1/admin/products/2", "").Code)
+	assert.Equal(s.T(), http.StatusNoContent, serve(http.MethodDelete, "/api/v1/admin/products/1", "").Code)
+
+	var count int
+	assert.NoError(s.T(), s.db.Get(&count, "SELECT COUNT(*) FROM products WHERE id = 1"))
 	assert.Equal(s.T(), 0, count)
 }
 
+// 測試每次變更都記錄歷史，並可讀取過去時間點的內容
+func (s *IntegrationTestSuite) TestProductHistory() {
+	serve := func(method string, path string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", "*")
+		req.Header.Set("X-Request-ID", "req-history")
+		req.Header.Set(middleware.ActorHeader, "alice")
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(s.T(), http.StatusCreated, serve(http.MethodPost, "/api/v1/products", `{"sku_code": "HIST001", "sku_name": "歷史產品", "sku_amount": 10}`).Code)
+
+	// 觸發器使用交易開始的時間，前後留出間隔以便取得兩次變更之間的時間點
+	time.Sleep(10 * time.Millisecond)
+	beforePatch := time.Now()
+	time.Sleep(10 * time.Millisecond)
+
+	assert.Equal(s.T(), http.StatusOK, serve(http.MethodPatch, "/api/v1/products/1", `{"sku_amount": 5}`).Code)
+	assert.Equal(s.T(), http.StatusOK, serve(http.MethodDelete, "/api/v1/products/1", "").Code)
+
+	// 已刪除的產品仍可查詢歷史
+	var page models.ProductHistoryPage
+	w := serve(http.MethodGet, "/api/v1/products/1/history", "")
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Equal(s.T(), 3, page.Total)
+	assert.Equal(s.T(), models.HistoryDelete, page.Items[0].Operation)
+	assert.Equal(s.T(), models.HistoryUpdate, page.Items[1].Operation)
+	assert.Equal(s.T(), 10, page.Items[1].Before.SkuAmount)
+	assert.Equal(s.T(), 5, page.Items[1].After.SkuAmount)
+	assert.Equal(s.T(), models.HistoryCreate, page.Items[2].Operation)
+	assert.Nil(s.T(), page.Items[2].Before)
+	assert.Equal(s.T(), "alice", page.Items[2].Actor)
+	assert.Equal(s.T(), "req-history", page.Items[2].RequestID)
+
+	var product models.Product
+	w = serve(http.MethodGet, "/api/v1/products/1?as_of="+url.QueryEscape(beforePatch.Format(time.RFC3339Nano)), "")
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &product))
+	assert.Equal(s.T(), 10, product.SkuAmount)
+	assert.Equal(s.T(), 1, product.Version)
+
+	// 刪除後與創建前都不存在
+	w = serve(http.MethodGet, "/api/v1/products/1?as_of="+url.QueryEscape(time.Now().Format(time.RFC3339Nano)), "")
+	assert.Equal(s.T(), http.StatusNotFound, w.Code)
+	w = serve(http.MethodGet, "/api/v1/products/1?as_of=2000-01-01T00:00:00Z", "")
+	assert.Equal(s.T(), http.StatusNotFound, w.Code)
+}
+
+// 測試庫存異動帳與 sku_amount 保持一致
+func (s *IntegrationTestSuite) TestStockMovements() {
+	s.insertTestProducts(2)
+
+	serve := func(method string, path string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", "*")
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(s.T(), http.StatusCreated, serve(http.MethodPost, "/api/v1/products/1/movements", `{"type": "receive", "quantity": 5, "reference": "PO-1"}`).Code)
+	assert.Equal(s.T(), http.StatusConflict, serve(http.MethodPost, "/api/v1/products/1/movements", `{"type": "issue", "quantity": 1000}`).Code)
+	assert.Equal(s.T(), http.StatusCreated, serve(http.MethodPost, "/api/v1/products/1/movements", `{"type": "issue", "quantity": 3, "reference": "SO-1"}`).Code)
+
+	// 轉移不能改變產品；轉入的儲位不存在時轉出也不生效
+	assert.Equal(s.T(), http.StatusBadRequest, serve(http.MethodPost, "/api/v1/products/1/movements", `{"type": "transfer", "quantity": 2, "to_product_id": 2, "to_location_id": 1}`).Code)
+	assert.Equal(s.T(), http.StatusNotFound, serve(http.MethodPost, "/api/v1/products/1/movements", `{"type": "transfer", "quantity": 2, "to_location_id": 999}`).Code)
+
+	// 直接修改 sku_amount 補記為 product_edit
+	assert.Equal(s.T(), http.StatusOK, serve(http.MethodPatch, "/api/v1/products/2", `{"sku_amount": 50}`).Code)
+
+	var product models.Product
+	assert.NoError(s.T(), s.db.Get(&product, "SELECT * FROM products WHERE id = 1"))
+	assert.Equal(s.T(), 102, product.SkuAmount)
+
+	var page models.StockMovementPage
+	w := serve(http.MethodGet, "/api/v1/products/1/movements", "")
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Equal(s.T(), 3, page.Total)
+	assert.Equal(s.T(), models.MovementIssue, page.Items[0].Type)
+	assert.Equal(s.T(), 102, page.Items[0].Balance)
+	assert.Equal(s.T(), models.ReasonOpeningBalance, page.Items[2].Reason)
+
+	// 每個產品的異動合計等於 sku_amount
+	var mismatched int
+	assert.NoError(s.T(), s.db.Get(&mismatched, `
+		SELECT COUNT(*) FROM products p
+		WHERE p.sku_amount <> (SELECT COALESCE(SUM(quantity), 0) FROM stock_movements m WHERE m.product_id = p.id)`))
+	assert.Equal(s.T(), 0, mismatched)
+
+	// 異動帳不可修改
+	_, err := s.db.Exec("UPDATE stock_movements SET quantity = 1")
+	assert.Error(s.T(), err)
+}
+
+// 測試儲位間轉移、產品的儲位庫存明細與依倉庫過濾
+func (s *IntegrationTestSuite) TestWarehouseLocations() {
+	s.insertTestProducts(2)
+
+	serve := func(method string, path string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+		return w
+	}
+
+	var warehouse models.Warehouse
+	w := serve(http.MethodPost, "/api/v1/warehouses", `{"code": "EAST", "name": "東區倉庫"}`)
+	assert.Equal(s.T(), http.StatusCreated, w.Code)
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &warehouse))
+	assert.Equal(s.T(), http.StatusConflict, serve(http.MethodPost, "/api/v1/warehouses", `{"code": "EAST", "name": "重複"}`).Code)
+
+	var location models.StockLocation
+	w = serve(http.MethodPost, fmt.Sprintf("/api/v1/warehouses/%d/locations", warehouse.ID), `{"code": "A-01", "name": "A 區一號架"}`)
+	assert.Equal(s.T(), http.StatusCreated, w.Code)
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &location))
+	assert.False(s.T(), location.IsDefault)
+
+	// 從預設儲位轉出 30 到東區倉庫
+	w = serve(http.MethodPost, "/api/v1/products/1/movements", fmt.Sprintf(`{"type": "transfer", "quantity": 30, "to_location_id": %d}`, location.ID))
+	assert.Equal(s.T(), http.StatusCreated, w.Code)
+	// 儲位的庫存不足時拒絕，即使產品的總庫存足夠
+	w = serve(http.MethodPost, "/api/v1/products/1/movements", fmt.Sprintf(`{"type": "issue", "quantity": 40, "location_id": %d}`, location.ID))
+	assert.Equal(s.T(), http.StatusConflict, w.Code)
+	w = serve(http.MethodPost, "/api/v1/products/1/movements", `{"type": "receive", "quantity": 1, "location_id": 999}`)
+	assert.Equal(s.T(), http.StatusNotFound, w.Code)
+
+	// 產品的總庫存不變，明細分佈在兩個儲位
+	var product models.Product
+	w = serve(http.MethodGet, "/api/v1/products/1", "")
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &product))
+	assert.Equal(s.T(), 100, product.SkuAmount)
+	assert.Len(s.T(), product.Locations, 2)
+	assert.Equal(s.T(), "EAST", product.Locations[0].WarehouseCode)
+	assert.Equal(s.T(), 30, product.Locations[0].Quantity)
+	assert.Equal(s.T(), 70, product.Locations[1].Quantity)
+
+	// 只有產品 1 在東區倉庫有庫存
+	var page models.ProductPage
+	w = serve(http.MethodGet, fmt.Sprintf("/api/v1/products?warehouse_id=%d", warehouse.ID), "")
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Equal(s.T(), 1, page.Total)
+
+	// 有儲位的倉庫與有庫存的儲位都不能刪除
+	assert.Equal(s.T(), http.StatusConflict, serve(http.MethodDelete, fmt.Sprintf("/api/v1/warehouses/%d", warehouse.ID), "").Code)
+	assert.Equal(s.T(), http.StatusConflict, serve(http.MethodDelete, fmt.Sprintf("/api/v1/locations/%d", location.ID), "").Code)
+
+	// 產品的庫存全部被預留時仍可在儲位間雙向轉移，但不能出庫
+	var reservation models.StockReservation
+	w = serve(http.MethodPost, "/api/v1/products/1/reservations", `{"quantity": 100, "reference": "ORDER-ALL"}`)
+	assert.Equal(s.T(), http.StatusCreated, w.Code)
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &reservation))
+	w = serve(http.MethodPost, "/api/v1/products/1/movements", fmt.Sprintf(`{"type": "transfer", "quantity": 5, "to_location_id": %d}`, location.ID))
+	assert.Equal(s.T(), http.StatusCreated, w.Code)
+	w = serve(http.MethodPost, "/api/v1/products/1/movements", fmt.Sprintf(`{"type": "transfer", "quantity": 5, "location_id": %d}`, location.ID))
+	assert.Equal(s.T(), http.StatusCreated, w.Code)
+	assert.Equal(s.T(), http.StatusConflict, serve(http.MethodPost, "/api/v1/products/1/movements", `{"type": "issue", "quantity": 1}`).Code)
+	assert.Equal(s.T(), http.StatusOK, serve(http.MethodPost, fmt.Sprintf("/api/v1/reservations/%d/release", reservation.ID), "").Code)
+
+	// 轉回預設儲位後即可刪除，異動紀錄保留
+	w = serve(http.MethodPost, "/api/v1/products/1/movements", fmt.Sprintf(`{"type": "transfer", "quantity": 30, "location_id": %d}`, location.ID))
+	assert.Equal(s.T(), http.StatusCreated, w.Code)
+	assert.Equal(s.T(), http.StatusOK, serve(http.MethodDelete, fmt.Sprintf("/api/v1/locations/%d", location.ID), "").Code)
+	assert.Equal(s.T(), http.StatusOK, serve(http.MethodDelete, fmt.Sprintf("/api/v1/warehouses/%d", warehouse.ID), "").Code)
+
+	// 每個產品的儲位庫存合計等於 sku_amount
+	var mismatched int
+	assert.NoError(s.T(), s.db.Get(&mismatched, `
+		SELECT COUNT(*) FROM products p
+		WHERE p.sku_amount <> (SELECT COALESCE(SUM(quantity), 0) FROM product_stock ps WHERE ps.product_id = p.id)`))
+	assert.Equal(s.T(), 0, mismatched)
+}
+
+// 測試出庫依到期日先後分配批次
+func (s *IntegrationTestSuite) TestProductLots() {
+	s.insertTestProducts(1)
+
+	serve := func(method string, path string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", "*")
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 創建產品時的庫存成為期初批次，到期日為 2025-12-31
+	assert.Equal(s.T(), http.StatusCreated, serve(http.MethodPost, "/api/v1/products/1/movements", `{"type": "receive", "quantity": 10, "lot_number": "A", "expiration": "2025-03-01"}`).Code)
+	assert.Equal(s.T(), http.StatusCreated, serve(http.MethodPost, "/api/v1/products/1/movements", `{"type": "receive", "quantity": 5, "lot_number": "B", "expiration": "2025-06-01"}`).Code)
+	assert.Equal(s.T(), http.StatusConflict, serve(http.MethodPost, "/api/v1/products/1/movements", `{"type": "receive", "quantity": 1, "lot_number": "A", "expiration": "2026-01-01"}`).Code)
+
+	var issued struct {
+		Items []models.StockMovement `json:"items"`
+	}
+	w := serve(http.MethodPost, "/api/v1/products/1/movements", `{"type": "issue", "quantity": 12}`)
+	assert.Equal(s.T(), http.StatusCreated, w.Code)
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &issued))
+	assert.Len(s.T(), issued.Items[0].Lots, 2)
+	assert.Equal(s.T(), "A", issued.Items[0].Lots[0].LotNumber)
+	assert.Equal(s.T(), -10, issued.Items[0].Lots[0].Quantity)
+	assert.Equal(s.T(), -2, issued.Items[0].Lots[1].Quantity)
+
+	var movement models.StockMovement
+	w = serve(http.MethodGet, fmt.Sprintf("/api/v1/products/1/movements/%d", issued.Items[0].ID), "")
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &movement))
+	assert.Equal(s.T(), issued.Items[0].Lots, movement.Lots)
+
+	var lots models.ProductLotStock
+	w = serve(http.MethodGet, "/api/v1/products/1/lots", "")
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &lots))
+	assert.Len(s.T(), lots.Items, 2)
+	assert.Equal(s.T(), "B", lots.Items[0].LotNumber)
+	assert.Equal(s.T(), 3, lots.Items[0].Quantity)
+	assert.Equal(s.T(), 0, lots.UntrackedQuantity)
+
+	// 直接減少 sku_amount 時依 FEFO 扣減超出的批次
+	assert.Equal(s.T(), http.StatusOK, serve(http.MethodPatch, "/api/v1/products/1", `{"sku_amount": 50}`).Code)
+	w = serve(http.MethodGet, "/api/v1/products/1/lots", "")
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &lots))
+	assert.Len(s.T(), lots.Items, 1)
+	assert.Equal(s.T(), "OPENING", lots.Items[0].LotNumber)
+	assert.Equal(s.T(), 50, lots.Items[0].Quantity)
+}
+
+// 測試預留扣減可承諾量、冪等重送、提交與釋放，以及並發預留不會超賣
+func (s *IntegrationTestSuite) TestStockReservations() {
+	s.insertTestProducts(1)
+
+	serve := func(method string, path string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 並發預留 20 次，每次 10 件，產品庫存 100 件只能成功 10 次
+	statuses := make(chan int, 20)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			statuses <- serve(http.MethodPost, "/api/v1/products/1/reservations", fmt.Sprintf(`{"quantity": 10, "reference": "CART-%d"}`, i)).Code
+		}(i)
+	}
+	wg.Wait()
+	close(statuses)
+
+	created := 0
+	for status := range statuses {
+		if status == http.StatusCreated {
+			created++
+		} else {
+			assert.Equal(s.T(), http.StatusConflict, status)
+		}
+	}
+	assert.Equal(s.T(), 10, created)
+
+	var availability models.StockAvailability
+	w := serve(http.MethodGet, "/api/v1/products/1/availability", "")
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &availability))
+	assert.Equal(s.T(), models.StockAvailability{ProductID: 1, OnHand: 100, Reserved: 100, Available: 0}, availability)
+
+	// 預留的庫存不能出庫，也不能以調整扣減
+	assert.Equal(s.T(), http.StatusConflict, serve(http.MethodPost, "/api/v1/products/1/movements", `{"type": "issue", "quantity": 1}`).Code)
+	assert.Equal(s.T(), http.StatusConflict, serve(http.MethodPost, "/api/v1/products/1/stock:adjust", `{"delta": -1}`).Code)
+
+	var reservations struct {
+		Items []models.StockReservation `json:"items"`
+	}
+	w = serve(http.MethodGet, "/api/v1/products/1/reservations", "")
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &reservations))
+	assert.Len(s.T(), reservations.Items, 10)
+
+	// 以相同 reference 重送返回同一筆預留，數量不同時衝突
+	first := reservations.Items[0]
+	var replayed models.StockReservation
+	w = serve(http.MethodPost, "/api/v1/products/1/reservations", fmt.Sprintf(`{"quantity": 10, "reference": %q}`, first.Reference))
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &replayed))
+	assert.Equal(s.T(), first.ID, replayed.ID)
+	assert.Equal(s.T(), http.StatusConflict, serve(http.MethodPost, "/api/v1/products/1/reservations", fmt.Sprintf(`{"quantity": 5, "reference": %q}`, first.Reference)).Code)
+
+	// 提交後出庫，重複提交返回同一筆異動
+	var committed models.StockReservation
+	w = serve(http.MethodPost, fmt.Sprintf("/api/v1/reservations/%d/commit", first.ID), "")
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &committed))
+	assert.Equal(s.T(), models.ReservationCommitted, committed.Status)
+	assert.NotZero(s.T(), committed.MovementID)
+	w = serve(http.MethodPost, fmt.Sprintf("/api/v1/reservations/%d/commit", first.ID), "")
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &replayed))
+	assert.Equal(s.T(), committed.MovementID, replayed.MovementID)
+	assert.Equal(s.T(), http.StatusConflict, serve(http.MethodPost, fmt.Sprintf("/api/v1/reservations/%d/release", first.ID), "").Code)
+
+	// 釋放後可承諾量恢復，已釋放的預留不能提交
+	second := reservations.Items[1]
+	assert.Equal(s.T(), http.StatusOK, serve(http.MethodPost, fmt.Sprintf("/api/v1/reservations/%d/release", second.ID), "").Code)
+	assert.Equal(s.T(), http.StatusOK, serve(http.MethodPost, fmt.Sprintf("/api/v1/reservations/%d/release", second.ID), "").Code)
+	assert.Equal(s.T(), http.StatusConflict, serve(http.MethodPost, fmt.Sprintf("/api/v1/reservations/%d/commit", second.ID), "").Code)
+
+	w = serve(http.MethodGet, "/api/v1/products/1/availability", "")
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &availability))
+	assert.Equal(s.T(), models.StockAvailability{ProductID: 1, OnHand: 90, Reserved: 80, Available: 10}, availability)
+
+	// 清理到期的預留後全部歸還可承諾量
+	expired, err := repository.NewReservationRepository(s.db).ExpireReservations(context.Background(), time.Now().Add(time.Hour), 100)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(8), expired)
+	assert.Equal(s.T(), http.StatusConflict, serve(http.MethodPost, fmt.Sprintf("/api/v1/reservations/%d/commit", reservations.Items[2].ID), "").Code)
+
+	w = serve(http.MethodGet, "/api/v1/products/1/availability", "")
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &availability))
+	assert.Equal(s.T(), 90, availability.Available)
+}
+
+// 測試並發的原子調整不會遺失更新，也不會讓庫存變成負數
+func (s *IntegrationTestSuite) TestAdjustStockConcurrent() {
+	s.insertTestProducts(1)
+
+	adjust := func(delta int) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/products/1/stock:adjust", bytes.NewBufferString(fmt.Sprintf(`{"delta": %d}`, delta)))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// parallel 並發發送 count 次相同的調整，返回各狀態碼的次數
+	parallel := func(count int, delta int) map[int]int {
+		statuses := make(chan int, count)
+		var wg sync.WaitGroup
+		for i := 0; i < count; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				statuses <- adjust(delta)
+			}()
+		}
+		wg.Wait()
+		close(statuses)
+
+		counts := map[int]int{}
+		for status := range statuses {
+			counts[status]++
+		}
+		return counts
+	}
+
+	// 庫存 100，300 個並發的 -1 只有 100 個成功
+	assert.Equal(s.T(), map[int]int{http.StatusOK: 100, http.StatusConflict: 200}, parallel(300, -1))
+
+	var product models.Product
+	assert.NoError(s.T(), s.db.Get(&product, "SELECT * FROM products WHERE id = 1"))
+	assert.Equal(s.T(), 0, product.SkuAmount)
+
+	// 200 個並發的 +3 全部生效
+	assert.Equal(s.T(), map[int]int{http.StatusOK: 200}, parallel(200, 3))
+	assert.NoError(s.T(), s.db.Get(&product, "SELECT * FROM products WHERE id = 1"))
+	assert.Equal(s.T(), 600, product.SkuAmount)
+
+	// 每次成功的調整都記入異動帳，合計等於 sku_amount
+	var movements, total int
+	assert.NoError(s.T(), s.db.QueryRow("SELECT COUNT(*), SUM(quantity) FROM stock_movements WHERE product_id = 1").Scan(&movements, &total))
+	assert.Equal(s.T(), 1+100+200, movements)
+	assert.Equal(s.T(), 600, total)
+}
+
+// 測試庫存降到補貨點以下時發出一次低庫存事件，並出現在低庫存報表中；
+// 原子調整、提交預留與出庫異動都經過同一個觸發器
+func (s *IntegrationTestSuite) TestLowStockAlerts() {
+	s.insertTestProducts(2)
+
+	send := func(method string, path string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if method == http.MethodPatch {
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+			req.Header.Set("If-Match", "*")
+		}
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+		return w
+	}
+	relay := func() []models.LowStockEvent {
+		s.alerts.RunOnce(context.Background())
+		return s.events.resetLowStock()
+	}
+
+	// 產品 1 庫存 100，補貨點設為 90 時尚未低於補貨點
+	assert.Equal(s.T(), http.StatusOK, send(http.MethodPatch, "/api/v1/products/1", `{"reorder_point": 90, "reorder_quantity": 200}`).Code)
+	assert.Empty(s.T(), relay())
+
+	// 100 -> 85 跨過補貨點，85 -> 80 不重複發出；事件在派送前不會送出
+	assert.Equal(s.T(), http.StatusOK, send(http.MethodPost, "/api/v1/products/1/stock:adjust", `{"delta": -15}`).Code)
+	assert.Equal(s.T(), http.StatusOK, send(http.MethodPost, "/api/v1/products/1/stock:adjust", `{"delta": -5}`).Code)
+	assert.Empty(s.T(), s.events.resetLowStock())
+
+	// 通知管道無法使用時事件留在寄件匣，下次派送時重送
+	s.events.mu.Lock()
+	s.events.failLowStock = errors.New("Webhook 無回應")
+	s.events.mu.Unlock()
+	assert.Zero(s.T(), s.alerts.RunOnce(context.Background()))
+	var pending int
+	assert.NoError(s.T(), s.db.Get(&pending, "SELECT COUNT(*) FROM low_stock_events"))
+	assert.Equal(s.T(), 1, pending)
+	s.events.mu.Lock()
+	s.events.failLowStock = nil
+	s.events.mu.Unlock()
+
+	events := relay()
+	if assert.Len(s.T(), events, 1) {
+		assert.Equal(s.T(), 1, events[0].ProductID)
+		assert.Equal(s.T(), 85, events[0].SkuAmount)
+		assert.Equal(s.T(), 200, events[0].ReorderQuantity)
+		assert.Equal(s.T(), "default", events[0].TenantID)
+	}
+	assert.Empty(s.T(), relay())
+
+	// 產品 2 庫存 101、補貨點 60：提交 50 件的預留後跨過補貨點，之後的出庫不重複發出
+	assert.Equal(s.T(), http.StatusOK, send(http.MethodPatch, "/api/v1/products/2", `{"reorder_point": 60}`).Code)
+	var reservation models.StockReservation
+	w := send(http.MethodPost, "/api/v1/products/2/reservations", `{"quantity": 50, "reference": "ORDER-1"}`)
+	assert.Equal(s.T(), http.StatusCreated, w.Code)
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &reservation))
+	assert.Equal(s.T(), http.StatusOK, send(http.MethodPost, fmt.Sprintf("/api/v1/reservations/%d/commit", reservation.ID), "").Code)
+	assert.Equal(s.T(), http.StatusCreated, send(http.MethodPost, "/api/v1/products/2/movements", `{"type": "issue", "quantity": 1}`).Code)
+
+	events = relay()
+	if assert.Len(s.T(), events, 1) {
+		assert.Equal(s.T(), 2, events[0].ProductID)
+		assert.Equal(s.T(), 51, events[0].SkuAmount)
+	}
+
+	// 負數的補貨點被拒絕
+	assert.Equal(s.T(), http.StatusBadRequest, send(http.MethodPatch, "/api/v1/products/2", `{"reorder_point": -1}`).Code)
+
+	w = send(http.MethodGet, "/api/v1/alerts/low-stock", "")
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+
+	var page models.ProductPage
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Equal(s.T(), 2, page.Total)
+	if assert.Len(s.T(), page.Items, 2) {
+		assert.Equal(s.T(), 1, page.Items[0].ID)
+		assert.Equal(s.T(), 80, page.Items[0].SkuAmount)
+		assert.Equal(s.T(), 90, page.Items[0].ReorderPoint)
+	}
+}
+
+// 測試並發的調整只為跨過補貨點的那一次寫入記錄事件
+func (s *IntegrationTestSuite) TestLowStockAlertsConcurrent() {
+	s.insertTestProducts(1)
+	_, err := s.db.Exec(`UPDATE products SET reorder_point = 50 WHERE id = 1`)
+	assert.NoError(s.T(), err)
+
+	// 100 件分 20 次並發扣減 5 件，只有從 50 降到 45 的那一次跨過補貨點
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/products/1/stock:adjust", bytes.NewBufferString(`{"delta": -5}`))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			s.router.ServeHTTP(w, req)
+			assert.Equal(s.T(), http.StatusOK, w.Code)
+		}()
+	}
+	wg.Wait()
+
+	s.alerts.RunOnce(context.Background())
+	events := s.events.resetLowStock()
+	if assert.Len(s.T(), events, 1) {
+		assert.Equal(s.T(), 45, events[0].SkuAmount)
+	}
+}
+
+// 測試過期清理標記過期的批次與產品、過期庫存只能報廢，以及即將到期報表
+func (s *IntegrationTestSuite) TestExpirySweep() {
+	// 產品 1 的到期日 2025-12-31 已過，期初批次同樣已過期
+	s.insertTestProducts(1)
+
+	send := func(method string, path string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if method == http.MethodPatch {
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+			req.Header.Set("If-Match", "*")
+		}
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+		return w
+	}
+
+	now := time.Now()
+	soon := models.Date{Time: models.DateOf(now.UTC()).AddDate(0, 0, 10)}
+	assert.Equal(s.T(), http.StatusCreated, send(http.MethodPost, "/api/v1/products/1/movements",
+		fmt.Sprintf(`{"type": "receive", "quantity": 20, "lot_number": "FRESH", "expiration": "%s"}`, soon)).Code)
+
+	result, err := s.expiry.SweepExpired(auth.WithPrincipal(context.Background(), auth.System), now)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), models.ExpirySweepResult{Lots: 1, Products: 1, Quantity: 220}, result)
+	assert.Len(s.T(), s.events.resetExpired(), 2)
+
+	// 再次清理不會重複標記
+	result, err = s.expiry.SweepExpired(auth.WithPrincipal(context.Background(), auth.System), now)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), models.ExpirySweepResult{}, result)
+	assert.Empty(s.T(), s.events.resetExpired())
+
+	// 產品本身已過期，全部庫存都不可動用，只能以 expired 原因報廢
+	var availability models.StockAvailability
+	w := send(http.MethodGet, "/api/v1/products/1/availability", "")
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &availability))
+	assert.Equal(s.T(), models.StockAvailability{ProductID: 1, OnHand: 120, Expired: 120, Available: 0}, availability)
+	assert.Equal(s.T(), http.StatusConflict, send(http.MethodPost, "/api/v1/products/1/movements", `{"type": "issue", "quantity": 1, "reason": "sale"}`).Code)
+
+	var issued struct {
+		Items []models.StockMovement `json:"items"`
+	}
+	w = send(http.MethodPost, "/api/v1/products/1/movements", `{"type": "issue", "quantity": 100, "reason": "expired"}`)
+	assert.Equal(s.T(), http.StatusCreated, w.Code)
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &issued))
+	if assert.Len(s.T(), issued.Items[0].Lots, 1) {
+		assert.Equal(s.T(), "OPENING", issued.Items[0].Lots[0].LotNumber)
+	}
+
+	// 修改到期日後清除產品的過期標記，剩下的批次可以正常出庫
+	assert.Equal(s.T(), http.StatusOK, send(http.MethodPatch, "/api/v1/products/1", fmt.Sprintf(`{"expiration": "%s"}`, soon)).Code)
+	w = send(http.MethodGet, "/api/v1/products/1/availability", "")
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &availability))
+	assert.Equal(s.T(), 20, availability.Available)
+
+	var report models.ExpiringReport
+	w = send(http.MethodGet, "/api/v1/reports/expiring?within=30d&group_by=lot", "")
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(s.T(), 20, report.Quantity)
+	if assert.Len(s.T(), report.Groups, 1) {
+		assert.Equal(s.T(), "FRESH", report.Groups[0].LotNumber)
+		assert.Equal(s.T(), 10, report.Groups[0].Items[0].DaysLeft)
+	}
+
+	w = send(http.MethodGet, "/api/v1/reports/expiring?within=1w", "")
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Empty(s.T(), report.Groups)
+
+	w = send(http.MethodGet, "/api/v1/reports/expiring", "")
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &report))
+	if assert.Len(s.T(), report.Groups, 1) {
+		assert.Equal(s.T(), "MAIN", report.Groups[0].WarehouseCode)
+		assert.Equal(s.T(), 20, report.Groups[0].Quantity)
+	}
+}
+
+// 測試同一個鎖鍵只有一個實例能成為領導者，釋放後其他實例可以接手
+func (s *IntegrationTestSuite) TestLeaderLock() {
+	ctx := context.Background()
+	first := database.NewLeaderLock(s.db, jobs.ExpirySweeperLockID)
+	second := database.NewLeaderLock(s.db, jobs.ExpirySweeperLockID)
+	defer second.Release()
+
+	ok, err := first.TryAcquire(ctx)
+	assert.NoError(s.T(), err)
+	assert.True(s.T(), ok)
+
+	ok, err = second.TryAcquire(ctx)
+	assert.NoError(s.T(), err)
+	assert.False(s.T(), ok)
+
+	// 領導者再次呼叫時確認仍持有鎖
+	ok, err = first.TryAcquire(ctx)
+	assert.NoError(s.T(), err)
+	assert.True(s.T(), ok)
+
+	first.Release()
+	ok, err = second.TryAcquire(ctx)
+	assert.NoError(s.T(), err)
+	assert.True(s.T(), ok)
+}
+
 // 測試刪除不存在的產品
 func (s *IntegrationTestSuite) TestDeleteProductNotFound() {
 	// 發送刪除請求 - 嘗試刪除不存在的產品
 	req := httptest.NewRequest(http.MethodDelete, "/api/v1/products/999", nil)
+	req.Header.Set("If-Match", "*")
 	w := httptest.NewRecorder()
 	s.router.ServeHTTP(w, req)
 
@@ -316,6 +1140,395 @@ func (s *IntegrationTestSuite) TestDeleteProductNotFound() {
 	assert.Equal(s.T(), "PRODUCT_NOT_FOUND", response.ErrorCode)
 }
 
+// 測試原子批量創建與批量刪除
+func (s *IntegrationTestSuite) TestBatchCreateAndDelete() {
+	items := make([]models.Product, 600)
+	for i := range items {
+		items[i] = models.Product{SkuCode: fmt.Sprintf("BATCH%04d", i), SkuName: "批量產品", SkuAmount: i}
+	}
+
+	// 超過 COPY 門檻，走 COPY 寫入
+	jsonBody, _ := json.Marshal(models.ProductBatchRequest{Atomic: true, Items: items})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/products:batchCreate", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	s.router.ServeHTTP(w, req)
+
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+
+	var created controller.BatchResponse
+	err := json.Unmarshal(w.Body.Bytes(), &created)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), len(items), created.Succeeded)
+	assert.Equal(s.T(), "BATCH0599", created.Results[599].Product.SkuCode)
+
+	// 原子刪除中有一項版本錯誤時，全部不刪除
+	refs := []models.ProductRef{
+		{ID: int64(created.Results[0].Product.ID), Version: 1},
+		{ID: int64(created.Results[1].Product.ID), Version: 9},
+	}
+	jsonBody, _ = json.Marshal(models.ProductBatchDeleteRequest{Atomic: true, Items: refs})
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/products:batchDelete", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+
+	s.router.ServeHTTP(w, req)
+
+	assert.Equal(s.T(), http.StatusPreconditionFailed, w.Code)
+
+	var count int
+	err = s.db.Get(&count, "SELECT COUNT(*) FROM products")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), len(items), count)
+}
+
+// 測試匯出的 CSV 修改後重新匯入，依 sku_code 更新或創建
+func (s *IntegrationTestSuite) TestExportAndImport() {
+	s.insertTestProducts(2)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/products/export?format=csv&sort_by=sku_code", nil)
+	w := httptest.NewRecorder()
+
+	s.router.ServeHTTP(w, req)
+
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Contains(s.T(), w.Body.String(), "TEST000,測試產品 0,100")
+	assert.Contains(s.T(), w.Body.String(), "TEST001,測試產品 1,101")
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("file", "products.csv")
+	part.Write([]byte("sku_code,sku_name,sku_amount\nTEST000,已更新,5\nNEW001,新產品,7\nBAD001,無效,abc\n"))
+	writer.Close()
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/products/import", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w = httptest.NewRecorder()
+
+	s.router.ServeHTTP(w, req)
+
+	assert.Equal(s.T(), http.StatusMultiStatus, w.Code)
+
+	var report models.ImportReport
+	err := json.Unmarshal(w.Body.Bytes(), &report)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), 1, report.Created)
+	assert.Equal(s.T(), 1, report.Updated)
+	assert.Equal(s.T(), 1, report.Failed)
+
+	var product models.Product
+	err = s.db.Get(&product, "SELECT * FROM products WHERE sku_code = 'TEST000'")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "已更新", product.SkuName)
+	assert.Equal(s.T(), 5, product.SkuAmount)
+	assert.Equal(s.T(), 2, product.Version)
+}
+
+// 測試 sku_code 唯一與依產品編號更新或創建
+func (s *IntegrationTestSuite) TestUpsertBySku() {
+	s.insertTestProducts(1)
+
+	jsonBody, _ := json.Marshal(models.Product{SkuCode: "TEST000", SkuName: "重複"})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/products", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	s.router.ServeHTTP(w, req)
+
+	assert.Equal(s.T(), http.StatusConflict, w.Code)
+
+	// 第一次創建，第二次內容相同，版本不變
+	jsonBody, _ = json.Marshal(models.Product{SkuName: "新產品", SkuAmount: 3})
+	for _, expected := range []int{http.StatusCreated, http.StatusOK} {
+		req = httptest.NewRequest(http.MethodPut, "/api/v1/products/by-sku/NEW001", bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		w = httptest.NewRecorder()
+
+		s.router.ServeHTTP(w, req)
+
+		assert.Equal(s.T(), expected, w.Code)
+		assert.Equal(s.T(), `"1"`, w.Header().Get("ETag"))
+	}
+
+	// 內容改變時更新並遞增版本
+	jsonBody, _ = json.Marshal(models.Product{SkuName: "新產品", SkuAmount: 4})
+	req = httptest.NewRequest(http.MethodPut, "/api/v1/products/by-sku/NEW001", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+
+	s.router.ServeHTTP(w, req)
+
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Equal(s.T(), `"2"`, w.Header().Get("ETag"))
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/products/by-sku/NEW001", nil)
+	w = httptest.NewRecorder()
+
+	s.router.ServeHTTP(w, req)
+
+	var product models.Product
+	err := json.Unmarshal(w.Body.Bytes(), &product)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), 4, product.SkuAmount)
+}
+
+// 測試經由管理端點創建、輪換與撤銷 API 金鑰，並以 X-API-Key 存取產品
+func (s *IntegrationTestSuite) TestAPIKeys() {
+	adminRequest := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(middleware.AdminTokenHeader, testAdminToken)
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+		return w
+	}
+	tenantRequest := func(tenantID, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(middleware.AdminTokenHeader, testAdminToken)
+		req.Header.Set(middleware.TenantHeader, tenantID)
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 需要驗證的路由，只接受 API 金鑰
+	router := gin.New()
+	router.Use(middleware.Authenticate(nil, s.apiKeys, "/health"))
+	s.controller.RegisterRoutes(router)
+	getProducts := func(key string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/products", nil)
+		req.Header.Set(middleware.APIKeyHeader, key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	w := adminRequest(http.MethodPost, "/api/v1/admin/api-keys", `{"name":"pos-01","scopes":["product:read"]}`)
+	assert.Equal(s.T(), http.StatusCreated, w.Code)
+	var created models.CreatedAPIKey
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &created))
+	assert.NotEmpty(s.T(), created.Key)
+
+	// 同名的未撤銷金鑰不能重複創建
+	w = adminRequest(http.MethodPost, "/api/v1/admin/api-keys", `{"name":"pos-01"}`)
+	assert.Equal(s.T(), http.StatusConflict, w.Code)
+
+	assert.Equal(s.T(), http.StatusOK, getProducts(created.Key))
+	assert.Equal(s.T(), http.StatusUnauthorized, getProducts(created.Key+"x"))
+
+	// 列表不包含明文，並記錄了最後使用時間
+	w = adminRequest(http.MethodGet, "/api/v1/admin/api-keys", "")
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.NotContains(s.T(), w.Body.String(), created.Key)
+	var list struct {
+		Items []models.APIKey `json:"items"`
+	}
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Len(s.T(), list.Items, 1)
+	assert.False(s.T(), list.Items[0].LastUsedAt.IsZero())
+
+	// 其他租戶看不到、也不能撤銷這把金鑰，不能為其他租戶創建金鑰
+	w = tenantRequest("acme", http.MethodGet, "/api/v1/admin/api-keys", "")
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.JSONEq(s.T(), `{"items": []}`, w.Body.String())
+	w = tenantRequest("acme", http.MethodDelete, fmt.Sprintf("/api/v1/admin/api-keys/%d", created.ID), "")
+	assert.Equal(s.T(), http.StatusNotFound, w.Code)
+	w = adminRequest(http.MethodPost, "/api/v1/admin/api-keys", `{"name":"acme-01","tenant":"acme"}`)
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+
+	// 輪換後舊金鑰立即失效
+	w = adminRequest(http.MethodPost, fmt.Sprintf("/api/v1/admin/api-keys/%d/rotate", created.ID), "")
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	var rotated models.CreatedAPIKey
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &rotated))
+	assert.Equal(s.T(), http.StatusUnauthorized, getProducts(created.Key))
+	assert.Equal(s.T(), http.StatusOK, getProducts(rotated.Key))
+
+	// 撤銷後無法使用或輪換，名稱可以重新使用
+	w = adminRequest(http.MethodDelete, fmt.Sprintf("/api/v1/admin/api-keys/%d", created.ID), "")
+	assert.Equal(s.T(), http.StatusNoContent, w.Code)
+	assert.Equal(s.T(), http.StatusUnauthorized, getProducts(rotated.Key))
+	w = adminRequest(http.MethodPost, fmt.Sprintf("/api/v1/admin/api-keys/%d/rotate", created.ID), "")
+	assert.Equal(s.T(), http.StatusConflict, w.Code)
+	w = adminRequest(http.MethodPost, "/api/v1/admin/api-keys", `{"name":"pos-01"}`)
+	assert.Equal(s.T(), http.StatusCreated, w.Code)
+}
+
+// 測試租戶之間互相隔離：相同的 sku_code 與 API 金鑰名稱可在各租戶使用，其他租戶的產品、庫存與倉庫讀不到也寫不到
+func (s *IntegrationTestSuite) TestTenantIsolation() {
+	serve := func(tenantID, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", "*")
+		req.Header.Set(middleware.AdminTokenHeader, testAdminToken)
+		req.Header.Set(middleware.TenantHeader, tenantID)
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+		return w
+	}
+
+	create := `{"sku_code": "SHARED", "sku_name": "共用編號", "sku_amount": 10, "expiration": "2030-01-01"}`
+	var acme, globex models.Product
+	w := serve("acme", http.MethodPost, "/api/v1/products", create)
+	assert.Equal(s.T(), http.StatusCreated, w.Code)
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &acme))
+	w = serve("globex", http.MethodPost, "/api/v1/products", create)
+	assert.Equal(s.T(), http.StatusCreated, w.Code)
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &globex))
+
+	// sku_code 只在同一租戶內唯一
+	assert.Equal(s.T(), http.StatusConflict, serve("acme", http.MethodPost, "/api/v1/products", create).Code)
+
+	// 其他租戶的產品一律視為不存在
+	acmePath := fmt.Sprintf("/api/v1/products/%d", acme.ID)
+	assert.Equal(s.T(), http.StatusOK, serve("acme", http.MethodGet, acmePath, "").Code)
+	assert.Equal(s.T(), http.StatusNotFound, serve("globex", http.MethodGet, acmePath, "").Code)
+	assert.Equal(s.T(), http.StatusNotFound, serve("globex", http.MethodPatch, acmePath, `{"sku_name": "竄改"}`).Code)
+	assert.Equal(s.T(), http.StatusNotFound, serve("globex", http.MethodPost, acmePath+"/movements", `{"type": "issue", "quantity": 1}`).Code)
+	assert.Equal(s.T(), http.StatusNotFound, serve("globex", http.MethodGet, acmePath+"/movements", "").Code)
+	var acmeLocation int64
+	assert.NoError(s.T(), s.db.Get(&acmeLocation, `SELECT id FROM stock_locations WHERE tenant_id = 'acme' LIMIT 1`))
+	assert.Equal(s.T(), http.StatusNotFound, serve("globex", http.MethodPost, fmt.Sprintf("/api/v1/products/%d/movements", globex.ID),
+		fmt.Sprintf(`{"type": "transfer", "quantity": 1, "to_location_id": %d}`, acmeLocation)).Code)
+	assert.Equal(s.T(), http.StatusNotFound, serve("globex", http.MethodDelete, acmePath, "").Code)
+
+	// 清理回收站只作用於自己的租戶，其他租戶回收站中的產品保留
+	globexPath := fmt.Sprintf("/api/v1/products/%d", globex.ID)
+	assert.Equal(s.T(), http.StatusOK, serve("globex", http.MethodDelete, globexPath, "").Code)
+	productRepo := repository.NewProductRepository(s.db)
+	purged, err := productRepo.PurgeDeletedBefore(tenant.WithID(context.Background(), "acme"), time.Now().Add(time.Minute))
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(0), purged)
+	assert.Equal(s.T(), http.StatusOK, serve("globex", http.MethodPost, globexPath+"/restore", "").Code)
+	assert.Equal(s.T(), http.StatusNotFound, serve("default", http.MethodGet, acmePath, "").Code)
+
+	var page models.ProductPage
+	w = serve("globex", http.MethodGet, "/api/v1/products", "")
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Equal(s.T(), 1, page.Total)
+	assert.Equal(s.T(), globex.ID, page.Items[0].ID)
+
+	// acme 的產品未被修改，每個租戶的開帳都記在自己的預設儲位
+	var product models.Product
+	assert.NoError(s.T(), s.db.Get(&product, "SELECT * FROM products WHERE id = $1", acme.ID))
+	assert.Equal(s.T(), "共用編號", product.SkuName)
+	assert.Equal(s.T(), 10, product.SkuAmount)
+	assert.Equal(s.T(), "acme", product.TenantID)
+	var foreign int
+	assert.NoError(s.T(), s.db.Get(&foreign, `
+		SELECT COUNT(*) FROM product_stock ps JOIN stock_locations l ON l.id = ps.location_id
+		WHERE ps.tenant_id <> l.tenant_id`))
+	assert.Equal(s.T(), 0, foreign)
+
+	// 倉庫代碼同樣依租戶區分，不能在其他租戶的倉庫建立儲位
+	var warehouse models.Warehouse
+	w = serve("acme", http.MethodPost, "/api/v1/warehouses", `{"code": "EAST", "name": "東區倉庫"}`)
+	assert.Equal(s.T(), http.StatusCreated, w.Code)
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &warehouse))
+	assert.Equal(s.T(), http.StatusCreated, serve("globex", http.MethodPost, "/api/v1/warehouses", `{"code": "EAST", "name": "東區倉庫"}`).Code)
+	locations := fmt.Sprintf("/api/v1/warehouses/%d/locations", warehouse.ID)
+	assert.Equal(s.T(), http.StatusNotFound, serve("globex", http.MethodPost, locations, `{"code": "A-01", "name": "A 區"}`).Code)
+	assert.Equal(s.T(), http.StatusNotFound, serve("globex", http.MethodGet, fmt.Sprintf("/api/v1/warehouses/%d", warehouse.ID), "").Code)
+
+	// 未撤銷的 API 金鑰名稱只在同一租戶內唯一，驗證後的主體帶有租戶
+	subjects := map[string]string{}
+	for _, tenantID := range []string{"acme", "globex"} {
+		var created models.CreatedAPIKey
+		w = serve(tenantID, http.MethodPost, "/api/v1/admin/api-keys", `{"name": "pos-01", "scopes": ["product:read"]}`)
+		assert.Equal(s.T(), http.StatusCreated, w.Code)
+		assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &created))
+		principal, err := s.apiKeys.Verify(context.Background(), created.Key)
+		assert.NoError(s.T(), err)
+		subjects[tenantID] = principal.Subject
+	}
+	assert.Equal(s.T(), map[string]string{"acme": "api-key:acme/pos-01", "globex": "api-key:globex/pos-01"}, subjects)
+	assert.Equal(s.T(), http.StatusConflict, serve("acme", http.MethodPost, "/api/v1/admin/api-keys", `{"name": "pos-01"}`).Code)
+
+	assert.Equal(s.T(), http.StatusBadRequest, serve("ACME", http.MethodGet, "/api/v1/products", "").Code)
+}
+
+// 測試啟用資料列安全後，不受豁免的角色即使查詢沒有租戶條件也只看得到自己租戶的行
+func (s *IntegrationTestSuite) TestRowLevelSecurity() {
+	for _, tenantID := range []string{"acme", "globex"} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/products", bytes.NewBufferString(`{"sku_code": "RLS-1", "sku_name": "RLS", "sku_amount": 1}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(middleware.TenantHeader, tenantID)
+		s.router.ServeHTTP(w, req)
+		assert.Equal(s.T(), http.StatusCreated, w.Code)
+	}
+
+	ctx := context.Background()
+	assert.NoError(s.T(), database.SetRowLevelSecurity(ctx, s.db, true))
+	defer func() {
+		assert.NoError(s.T(), database.SetRowLevelSecurity(ctx, s.db, false))
+	}()
+
+	_, err := s.db.Exec(`DO $$ BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'tenant_app') THEN
+			CREATE ROLE tenant_app NOLOGIN;
+		END IF;
+	END $$`)
+	assert.NoError(s.T(), err)
+	_, err = s.db.Exec("GRANT SELECT, UPDATE ON products TO tenant_app")
+	assert.NoError(s.T(), err)
+
+	// 在不受豁免的角色與指定租戶的交易中執行 fn
+	asTenant := func(tenantID string, fn func(tx *sqlx.Tx)) {
+		tx, err := s.db.Beginx()
+		assert.NoError(s.T(), err)
+		defer tx.Rollback()
+
+		_, err = tx.Exec("SET LOCAL ROLE tenant_app")
+		assert.NoError(s.T(), err)
+		_, err = tx.Exec("SELECT set_config('app.tenant_id', $1, true)", tenantID)
+		assert.NoError(s.T(), err)
+		fn(tx)
+	}
+	visible := func(tenantID string) int {
+		var count int
+		asTenant(tenantID, func(tx *sqlx.Tx) {
+			assert.NoError(s.T(), tx.Get(&count, "SELECT COUNT(*) FROM products"))
+		})
+		return count
+	}
+
+	assert.Equal(s.T(), 1, visible("acme"))
+	assert.Equal(s.T(), 0, visible("nobody"))
+	assert.Equal(s.T(), 2, visible("*"))
+
+	// 明確指定其他租戶的更新同樣不會影響任何行
+	asTenant("globex", func(tx *sqlx.Tx) {
+		result, err := tx.Exec("UPDATE products SET sku_name = '竄改' WHERE tenant_id = 'acme'")
+		assert.NoError(s.T(), err)
+		affected, _ := result.RowsAffected()
+		assert.Equal(s.T(), int64(0), affected)
+	})
+}
+
+// 測試遷移可完整回滾後重新套用
+func (s *IntegrationTestSuite) TestMigrationsDownAndUp() {
+	migrator, err := database.NewMigrator(s.db)
+	assert.NoError(s.T(), err)
+
+	states, err := migrator.Status()
+	assert.NoError(s.T(), err)
+	for _, state := range states {
+		assert.True(s.T(), state.Applied, state.Name)
+	}
+
+	rolledBack, err := migrator.Down(len(states))
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), rolledBack, len(states))
+
+	applied, err := migrator.Up()
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), applied, len(states))
+}
+
 // 運行整合測試套件
 func TestIntegrationSuite(t *testing.T) {
 	// 跳過整合測試如果沒有 Docker 環境
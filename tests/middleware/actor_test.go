@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"main/internal/audit"
+	"main/internal/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// 測試 X-Actor 標頭寫入請求的 context
+func TestActor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Actor())
+	router.GET("/actor", func(c *gin.Context) {
+		c.String(http.StatusOK, audit.Actor(c.Request.Context()))
+	})
+
+	cases := []struct {
+		header string
+		actor  string
+	}{
+		{"alice", "alice"},
+		{"", ""},
+	}
+
+	for _, tc := range cases {
+		req, _ := http.NewRequest(http.MethodGet, "/actor", nil)
+		if tc.header != "" {
+			req.Header.Set(middleware.ActorHeader, tc.header)
+		}
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, tc.actor, resp.Body.String())
+	}
+}
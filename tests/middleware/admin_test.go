@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"main/internal/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// 設置測試路由，只有通過驗證的請求會到達處理函數
+func setupAdminRouter(token string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.AdminToken(token))
+	router.GET("/admin", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	return router
+}
+
+func serveAdmin(router *gin.Engine, token string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodGet, "/admin", nil)
+	if token != "" {
+		req.Header.Set(middleware.AdminTokenHeader, token)
+	}
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+// 測試令牌正確時放行
+func TestAdminTokenAccepted(t *testing.T) {
+	resp := serveAdmin(setupAdminRouter("secret"), "secret")
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "ok", resp.Body.String())
+}
+
+// 測試缺少或錯誤的令牌被拒絕
+func TestAdminTokenRejected(t *testing.T) {
+	router := setupAdminRouter("secret")
+
+	for _, token := range []string{"", "wrong", "secret "} {
+		resp := serveAdmin(router, token)
+		assert.Equal(t, http.StatusForbidden, resp.Code, token)
+
+		assert.Equal(t, "ADMIN_REQUIRED", errorCode(t, resp))
+	}
+}
+
+// 測試未配置令牌時停用管理端點
+func TestAdminTokenDisabled(t *testing.T) {
+	resp := serveAdmin(setupAdminRouter(""), "")
+
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+}
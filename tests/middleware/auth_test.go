@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"main/internal/audit"
+	"main/internal/auth"
+	"main/internal/controller"
+	"main/internal/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubVerifier 只接受 valid 憑證，返回 subject 為主體的身分
+type stubVerifier struct {
+	subject string
+	err     error
+}
+
+func (v stubVerifier) Verify(ctx context.Context, token string) (auth.Principal, error) {
+	if v.err != nil {
+		return auth.Principal{}, v.err
+	}
+	if token != "valid" {
+		return auth.Principal{}, auth.ErrInvalidSignature
+	}
+	return auth.Principal{Subject: v.subject}, nil
+}
+
+func setupAuthRouter(bearer, apiKeys middleware.TokenVerifier) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Actor())
+	router.Use(middleware.Authenticate(bearer, apiKeys, "/health"))
+	router.GET("/health", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	router.GET("/whoami", func(c *gin.Context) {
+		ctx := c.Request.Context()
+		c.String(http.StatusOK, fmt.Sprintf("%s|%s", auth.Subject(ctx), audit.Actor(ctx)))
+	})
+	return router
+}
+
+func serveAuth(router *gin.Engine, path, authorization string, apiKey ...string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	if len(apiKey) > 0 {
+		req.Header.Set(middleware.APIKeyHeader, apiKey[0])
+	}
+	req.Header.Set(middleware.ActorHeader, "mallory")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+// errorCode 以控制器的 ErrorResponse 嚴格解析錯誤回應並返回錯誤碼，多出的欄位視為格式不一致
+func errorCode(t *testing.T, resp *httptest.ResponseRecorder) string {
+	return errorResponse(t, resp).ErrorCode
+}
+
+func errorResponse(t *testing.T, resp *httptest.ResponseRecorder) controller.ErrorResponse {
+	var body controller.ErrorResponse
+	decoder := json.NewDecoder(resp.Body)
+	decoder.DisallowUnknownFields()
+	assert.NoError(t, decoder.Decode(&body))
+	return body
+}
+
+// 測試有效令牌的主體寫入 context 並取代 X-Actor
+func TestJWTAuthAccepted(t *testing.T) {
+	resp := serveAuth(setupAuthRouter(stubVerifier{subject: "alice"}, nil), "/whoami", "Bearer valid")
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "alice|alice", resp.Body.String())
+}
+
+// 測試缺少或無效的令牌返回 401
+func TestJWTAuthRejected(t *testing.T) {
+	router := setupAuthRouter(stubVerifier{subject: "alice"}, nil)
+
+	cases := []struct {
+		authorization string
+		code          string
+	}{
+		{"", "UNAUTHORIZED"},
+		{"Basic dXNlcjpwYXNz", "UNAUTHORIZED"},
+		{"Bearer ", "UNAUTHORIZED"},
+		{"Bearer forged", "INVALID_TOKEN"},
+	}
+
+	for _, tc := range cases {
+		resp := serveAuth(router, "/whoami", tc.authorization)
+		assert.Equal(t, http.StatusUnauthorized, resp.Code, tc.authorization)
+		assert.Equal(t, tc.code, errorCode(t, resp), tc.authorization)
+		assert.Contains(t, resp.Header().Get("WWW-Authenticate"), "Bearer")
+	}
+}
+
+// 測試健康檢查不需要令牌
+func TestJWTAuthPublicPath(t *testing.T) {
+	resp := serveAuth(setupAuthRouter(stubVerifier{subject: "alice"}, nil), "/health", "")
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+// 測試無法獲取公鑰時返回 503 而不是 401
+func TestJWTAuthKeySetUnavailable(t *testing.T) {
+	router := setupAuthRouter(stubVerifier{err: fmt.Errorf("%w: 連線失敗", auth.ErrKeySetUnavailable)}, nil)
+
+	resp := serveAuth(router, "/whoami", "Bearer valid")
+
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+	assert.Equal(t, "AUTH_UNAVAILABLE", errorCode(t, resp))
+}
+
+// 測試 API 金鑰優先於 Bearer 令牌，未啟用的憑證類型不被接受
+func TestAuthenticateAPIKey(t *testing.T) {
+	router := setupAuthRouter(stubVerifier{subject: "alice"}, stubVerifier{subject: "api-key:pos-01"})
+
+	resp := serveAuth(router, "/whoami", "", "valid")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "api-key:pos-01|api-key:pos-01", resp.Body.String())
+
+	resp = serveAuth(router, "/whoami", "Bearer valid", "valid")
+	assert.Equal(t, "api-key:pos-01|api-key:pos-01", resp.Body.String())
+
+	resp = serveAuth(router, "/whoami", "Bearer valid", "forged")
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Equal(t, "INVALID_TOKEN", errorCode(t, resp))
+
+	// 只啟用 API 金鑰時不接受 Bearer 令牌
+	router = setupAuthRouter(nil, stubVerifier{subject: "api-key:pos-01"})
+	resp = serveAuth(router, "/whoami", "Bearer valid")
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Equal(t, "UNAUTHORIZED", errorCode(t, resp))
+	assert.Empty(t, resp.Header().Get("WWW-Authenticate"))
+
+	// API 金鑰查詢失敗時返回 503
+	router = setupAuthRouter(nil, stubVerifier{err: fmt.Errorf("%w: 連線中斷", auth.ErrUnavailable)})
+	resp = serveAuth(router, "/whoami", "", "valid")
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+}
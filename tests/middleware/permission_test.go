@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"main/internal/auth"
+	"main/internal/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupPermissionRouter(principal *auth.Principal) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	if principal != nil {
+		router.Use(func(c *gin.Context) {
+			c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), *principal))
+			c.Next()
+		})
+	}
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.DELETE("/products/:id", middleware.RequirePermission(auth.PermProductDelete), ok)
+	router.POST("/:action", middleware.RequireActionPermission("action", map[string]auth.Permission{
+		"products:batchCreate": auth.PermProductWrite,
+		"products:batchDelete": auth.PermProductDelete,
+	}), ok)
+	return router
+}
+
+func servePermission(router *gin.Engine, method, path string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+// 測試權限不足返回 403 PERMISSION_DENIED
+func TestRequirePermission(t *testing.T) {
+	clerk := setupPermissionRouter(&auth.Principal{Subject: "bob", Roles: []string{"clerk"}})
+	resp := servePermission(clerk, http.MethodDelete, "/products/1")
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Equal(t, "PERMISSION_DENIED", errorCode(t, resp))
+
+	manager := setupPermissionRouter(&auth.Principal{Subject: "carol", Roles: []string{"manager"}})
+	assert.Equal(t, http.StatusOK, servePermission(manager, http.MethodDelete, "/products/1").Code)
+
+	// 沒有主體時拒絕，錯誤回應與控制器的格式相同並帶上請求 ID
+	req, _ := http.NewRequest(http.MethodDelete, "/products/1", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	resp = httptest.NewRecorder()
+	setupPermissionRouter(nil).ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	body := errorResponse(t, resp)
+	assert.Equal(t, "PERMISSION_DENIED", body.ErrorCode)
+	assert.Equal(t, "req-1", body.RequestID)
+
+	// 未啟用驗證時以系統主體執行，不受限制
+	unauthenticated := gin.New()
+	unauthenticated.Use(middleware.Unauthenticated())
+	unauthenticated.DELETE("/products/:id", middleware.RequirePermission(auth.PermProductPurge), func(c *gin.Context) { c.Status(http.StatusOK) })
+	assert.Equal(t, http.StatusOK, servePermission(unauthenticated, http.MethodDelete, "/products/1").Code)
+}
+
+// 測試依動作選擇權限，未列出的動作交由處理器
+func TestRequireActionPermission(t *testing.T) {
+	router := setupPermissionRouter(&auth.Principal{Subject: "bob", Roles: []string{"clerk"}})
+
+	assert.Equal(t, http.StatusOK, servePermission(router, http.MethodPost, "/products:batchCreate").Code)
+	assert.Equal(t, http.StatusForbidden, servePermission(router, http.MethodPost, "/products:batchDelete").Code)
+	assert.Equal(t, http.StatusOK, servePermission(router, http.MethodPost, "/products:unknown").Code)
+}
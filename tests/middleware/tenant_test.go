@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"main/internal/auth"
+	"main/internal/middleware"
+	"main/internal/tenant"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupTenantRouter(principal *auth.Principal) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	if principal != nil {
+		router.Use(func(c *gin.Context) {
+			c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), *principal))
+			c.Next()
+		})
+	}
+	router.Use(middleware.Tenant())
+	router.GET("/tenant", func(c *gin.Context) {
+		c.String(http.StatusOK, tenant.ID(c.Request.Context()))
+	})
+	return router
+}
+
+func serveTenant(router *gin.Engine, header string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodGet, "/tenant", nil)
+	if header != "" {
+		req.Header.Set(middleware.TenantHeader, header)
+	}
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+// 測試沒有主體時以 X-Tenant-ID 決定租戶，未指定時為預設租戶，格式不符返回 400
+func TestTenantHeader(t *testing.T) {
+	router := setupTenantRouter(nil)
+
+	resp := serveTenant(router, "acme")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "acme", resp.Body.String())
+
+	resp = serveTenant(router, "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, tenant.Default, resp.Body.String())
+
+	for _, invalid := range []string{"Acme", "acme corp", "*", "-acme"} {
+		resp = serveTenant(router, invalid)
+		assert.Equal(t, http.StatusBadRequest, resp.Code, invalid)
+		assert.Equal(t, "INVALID_TENANT", errorCode(t, resp))
+	}
+}
+
+// 測試主體帶有租戶時以其為準，X-Tenant-ID 不同時返回 403
+func TestTenantFromPrincipal(t *testing.T) {
+	router := setupTenantRouter(&auth.Principal{Subject: "alice", Tenant: "acme"})
+
+	resp := serveTenant(router, "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "acme", resp.Body.String())
+
+	resp = serveTenant(router, "acme")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "acme", resp.Body.String())
+
+	resp = serveTenant(router, "globex")
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Equal(t, "TENANT_MISMATCH", errorCode(t, resp))
+}
+
+// 測試沒有租戶的憑證屬於預設租戶，不能以 X-Tenant-ID 存取其他租戶
+func TestTenantPrincipalWithoutTenant(t *testing.T) {
+	router := setupTenantRouter(&auth.Principal{Subject: "bob", Roles: []string{"admin"}})
+
+	resp := serveTenant(router, "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, tenant.Default, resp.Body.String())
+
+	resp = serveTenant(router, tenant.Default)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, tenant.Default, resp.Body.String())
+
+	resp = serveTenant(router, "globex")
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Equal(t, "TENANT_MISMATCH", errorCode(t, resp))
+}
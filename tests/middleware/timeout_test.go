@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"main/internal/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// 設置測試路由，處理函數回報請求 context 剩餘的時間
+func setupTimeoutRouter(defaultTimeout time.Duration, routeTimeouts map[string]time.Duration) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.RequestTimeout(defaultTimeout, routeTimeouts))
+
+	handler := func(c *gin.Context) {
+		deadline, ok := c.Request.Context().Deadline()
+		if !ok {
+			c.String(http.StatusOK, "none")
+			return
+		}
+		c.String(http.StatusOK, time.Until(deadline).Round(time.Second).String())
+	}
+	router.GET("/fast", handler)
+	router.GET("/slow/:id", handler)
+
+	return router
+}
+
+func serve(router *gin.Engine, path string) string {
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp.Body.String()
+}
+
+// 測試未單獨配置的路由使用默認超時
+func TestRequestTimeoutDefault(t *testing.T) {
+	router := setupTimeoutRouter(5*time.Second, nil)
+
+	assert.Equal(t, "5s", serve(router, "/fast"))
+}
+
+// 測試依路由模板覆蓋超時
+func TestRequestTimeoutPerRoute(t *testing.T) {
+	router := setupTimeoutRouter(5*time.Second, map[string]time.Duration{
+		"GET /slow/:id": 30 * time.Second,
+	})
+
+	assert.Equal(t, "5s", serve(router, "/fast"))
+	assert.Equal(t, "30s", serve(router, "/slow/1"))
+}
+
+// 測試超時為 0 時不設置截止時間
+func TestRequestTimeoutDisabled(t *testing.T) {
+	router := setupTimeoutRouter(0, map[string]time.Duration{
+		"GET /slow/:id": 30 * time.Second,
+	})
+
+	assert.Equal(t, "none", serve(router, "/fast"))
+	assert.Equal(t, "30s", serve(router, "/slow/1"))
+}
+
+// 測試超時後下游可觀察到 context 已取消
+func TestRequestTimeoutCancelsContext(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.RequestTimeout(10*time.Millisecond, nil))
+	router.GET("/wait", func(c *gin.Context) {
+		select {
+		case <-c.Request.Context().Done():
+			c.String(http.StatusGatewayTimeout, c.Request.Context().Err().Error())
+		case <-time.After(time.Second):
+			c.String(http.StatusOK, "done")
+		}
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/wait", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusGatewayTimeout, resp.Code)
+	assert.Equal(t, "context deadline exceeded", resp.Body.String())
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"encoding/json"
+	"main/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 測試日期的 JSON 格式與零值
+func TestDateJSON(t *testing.T) {
+	product := models.Product{SkuCode: "SKU001", Expiration: models.MustParseDate("2025-12-31")}
+	body, err := json.Marshal(product)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"expiration":"2025-12-31"`)
+	assert.NotContains(t, string(body), "create_at")
+
+	var decoded models.Product
+	require.NoError(t, json.Unmarshal(body, &decoded))
+	assert.Equal(t, product.Expiration, decoded.Expiration)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"expiration": null}`), &decoded))
+	assert.True(t, decoded.Expiration.IsZero())
+}
+
+// 測試拒絕格式錯誤或不存在的日期
+func TestDateInvalid(t *testing.T) {
+	for _, body := range []string{`"31-12-2025"`, `"2025-02-30"`, `"2025-12-31T00:00:00Z"`, `20251231`} {
+		var d models.Date
+		err := json.Unmarshal([]byte(body), &d)
+
+		var validationErr *models.ValidationError
+		assert.ErrorAs(t, err, &validationErr, body)
+	}
+}
+
+// 測試時間戳以 UTC 的 RFC 3339 輸出
+func TestTimestampJSON(t *testing.T) {
+	taipei := time.FixedZone("UTC+8", 8*60*60)
+	ts := models.Timestamp{Time: time.Date(2025, 3, 1, 18, 0, 0, 0, taipei)}
+
+	body, err := json.Marshal(ts)
+	require.NoError(t, err)
+	assert.Equal(t, `"2025-03-01T10:00:00Z"`, string(body))
+}
+
+// 測試從資料庫讀取日期與時間戳
+func TestDateScan(t *testing.T) {
+	var d models.Date
+	require.NoError(t, d.Scan(time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "2025-06-30", d.String())
+
+	require.NoError(t, d.Scan(nil))
+	assert.True(t, d.IsZero())
+
+	value, err := d.Value()
+	require.NoError(t, err)
+	assert.Nil(t, value)
+
+	var ts models.Timestamp
+	require.NoError(t, ts.Scan("2025-03-01 10:00:00"))
+	assert.Equal(t, "2025-03-01T10:00:00Z", ts.String())
+}
+
+// 測試新產品的到期日不能早於今天
+func TestValidateNewProduct(t *testing.T) {
+	today := models.MustParseDate("2025-06-15")
+
+	product := models.Product{SkuCode: "SKU001", SkuName: "產品", Expiration: models.MustParseDate("2025-06-14")}
+	assert.Error(t, models.ValidateNewProduct(product, today))
+	assert.NoError(t, models.ValidateProduct(product))
+
+	product.Expiration = today
+	assert.NoError(t, models.ValidateNewProduct(product, today))
+
+	product.Expiration = models.Date{}
+	assert.NoError(t, models.ValidateNewProduct(product, today))
+}
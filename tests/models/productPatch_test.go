@@ -0,0 +1,105 @@
+package models
+
+import (
+	"main/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 測試 Merge Patch 區分未提供、null 與零值
+func TestDecodeMergePatch(t *testing.T) {
+	patch, err := models.DecodeMergePatch([]byte(`{"sku_amount": 0, "expiration": null}`))
+	require.NoError(t, err)
+
+	assert.False(t, patch.SkuCode.Set)
+	assert.False(t, patch.SkuName.Set)
+	assert.Equal(t, models.Nullable[int]{Set: true, Value: 0}, patch.SkuAmount)
+	assert.Equal(t, models.Nullable[models.Date]{Set: true, Null: true}, patch.Expiration)
+}
+
+// 測試 Merge Patch 拒絕非物件與唯讀欄位
+func TestDecodeMergePatchInvalid(t *testing.T) {
+	for _, body := range []string{`[]`, `"x"`, `{"version": 3}`, `{"sku_amount": "10"}`} {
+		_, err := models.DecodeMergePatch([]byte(body))
+		assert.ErrorIs(t, err, models.ErrInvalidPatch, body)
+	}
+}
+
+// 測試套用修補
+func TestProductPatchApply(t *testing.T) {
+	product := models.Product{SkuCode: "SKU001", SkuName: "產品", SkuAmount: 10, Expiration: models.MustParseDate("2025-12-31")}
+
+	patch, err := models.DecodeMergePatch([]byte(`{"sku_name": "新名稱", "expiration": null}`))
+	require.NoError(t, err)
+
+	result, err := patch.Apply(product)
+	require.NoError(t, err)
+	assert.Equal(t, "新名稱", result.SkuName)
+	assert.Equal(t, 10, result.SkuAmount)
+	assert.Empty(t, result.Expiration)
+
+	// 必填欄位不能設為 null
+	patch, err = models.DecodeMergePatch([]byte(`{"sku_code": null}`))
+	require.NoError(t, err)
+
+	_, err = patch.Apply(product)
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+}
+
+// 測試 JSON Patch 只返回有變化的欄位
+func TestApplyJSONPatch(t *testing.T) {
+	product := models.Product{SkuCode: "SKU001", SkuName: "產品", SkuAmount: 10, Expiration: models.MustParseDate("2025-12-31")}
+
+	ops, err := models.DecodeJSONPatch([]byte(`[
+		{"op": "test", "path": "/sku_amount", "value": 10},
+		{"op": "replace", "path": "/sku_amount", "value": 0},
+		{"op": "remove", "path": "/expiration"},
+		{"op": "copy", "from": "/sku_code", "path": "/sku_name"},
+		{"op": "replace", "path": "/sku_code", "value": "SKU001"}
+	]`))
+	require.NoError(t, err)
+
+	patch, err := models.ApplyJSONPatch(product, ops)
+	require.NoError(t, err)
+
+	assert.False(t, patch.SkuCode.Set)
+	assert.Equal(t, models.Nullable[string]{Set: true, Value: "SKU001"}, patch.SkuName)
+	assert.Equal(t, models.Nullable[int]{Set: true, Value: 0}, patch.SkuAmount)
+	assert.Equal(t, models.Nullable[models.Date]{Set: true, Null: true}, patch.Expiration)
+}
+
+// 測試 JSON Patch 的 test 操作不成立
+func TestApplyJSONPatchTestFailed(t *testing.T) {
+	product := models.Product{SkuCode: "SKU001", SkuAmount: 10}
+
+	ops, err := models.DecodeJSONPatch([]byte(`[
+		{"op": "replace", "path": "/sku_name", "value": "新名稱"},
+		{"op": "test", "path": "/sku_amount", "value": 5}
+	]`))
+	require.NoError(t, err)
+
+	_, err = models.ApplyJSONPatch(product, ops)
+	assert.ErrorIs(t, err, models.ErrPatchTestFailed)
+}
+
+// 測試 JSON Patch 拒絕無效的操作
+func TestApplyJSONPatchInvalid(t *testing.T) {
+	product := models.Product{SkuCode: "SKU001", SkuAmount: 10}
+
+	for _, body := range []string{
+		`[{"op": "replace", "path": "/version", "value": 1}]`,
+		`[{"op": "add", "path": "/sku_name/x", "value": "a"}]`,
+		`[{"op": "add", "path": "/sku_name"}]`,
+		`[{"op": "merge", "path": "/sku_name", "value": "a"}]`,
+		`[{"op": "move", "from": "/missing", "path": "/sku_name"}]`,
+	} {
+		ops, err := models.DecodeJSONPatch([]byte(body))
+		require.NoError(t, err)
+
+		_, err = models.ApplyJSONPatch(product, ops)
+		assert.ErrorIs(t, err, models.ErrInvalidPatch, body)
+	}
+}
@@ -0,0 +1,298 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"main/internal/models"
+	"main/internal/notify"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var testEvent = models.LowStockEvent{
+	ProductID:       1,
+	SkuCode:         "SKU001",
+	SkuName:         "產品 1",
+	SkuAmount:       3,
+	ReorderPoint:    10,
+	ReorderQuantity: 50,
+	OccurredAt:      time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+}
+
+var testExpiredEvent = models.ExpiredStockEvent{
+	ProductID:  1,
+	SkuCode:    "SKU001",
+	SkuName:    "產品 1",
+	LotID:      7,
+	LotNumber:  "LOT-A",
+	Expiration: models.MustParseDate("2025-01-01"),
+	Quantity:   12,
+	OccurredAt: time.Date(2025, 1, 2, 0, 5, 0, 0, time.UTC),
+}
+
+// fakeMail 假 SMTP 伺服器收到的郵件
+type fakeMail struct {
+	From string
+	To   []string
+	Data string
+}
+
+// fakeSMTPServer 只實現寄信所需指令的本機 SMTP 伺服器，不支援 STARTTLS 與 AUTH
+type fakeSMTPServer struct {
+	listener net.Listener
+
+	mu    sync.Mutex
+	mails []fakeMail
+}
+
+func startFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := &fakeSMTPServer{listener: listener}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+
+	return server
+}
+
+func (s *fakeSMTPServer) addr() (string, int) {
+	addr := s.listener.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port
+}
+
+func (s *fakeSMTPServer) received() []fakeMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]fakeMail(nil), s.mails...)
+}
+
+func (s *fakeSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	reply := func(line string) {
+		conn.Write([]byte(line + "\r\n"))
+	}
+
+	var mail fakeMail
+	reply("220 localhost fake SMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command := strings.ToUpper(line)
+
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			mail = fakeMail{From: strings.Trim(line[len("MAIL FROM:"):], "<>")}
+			reply("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			mail.To = append(mail.To, strings.Trim(line[len("RCPT TO:"):], "<>"))
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			mail.Data = data.String()
+			s.mu.Lock()
+			s.mails = append(s.mails, mail)
+			s.mu.Unlock()
+			reply("250 OK")
+		case command == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+// 測試透過本機的假 SMTP 伺服器寄出低庫存通知
+func TestSMTPNotifier(t *testing.T) {
+	server := startFakeSMTPServer(t)
+	host, port := server.addr()
+
+	notifier := notify.NewSMTPNotifier(host, port, "", "", "alerts@example.com", []string{"buyer@example.com", "ops@example.com"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, notifier.NotifyLowStock(ctx, testEvent))
+
+	mails := server.received()
+	require.Len(t, mails, 1)
+	assert.Equal(t, "alerts@example.com", mails[0].From)
+	assert.Equal(t, []string{"buyer@example.com", "ops@example.com"}, mails[0].To)
+	assert.Contains(t, mails[0].Data, "To: buyer@example.com, ops@example.com\r\n")
+	assert.Contains(t, mails[0].Data, "Subject: =?UTF-8?q?")
+	assert.Contains(t, mails[0].Data, "產品編號：SKU001\r\n")
+	assert.Contains(t, mails[0].Data, "目前庫存：3\r\n")
+	assert.Contains(t, mails[0].Data, "建議補貨量：50\r\n")
+}
+
+// 測試過期通知郵件包含批號與到期日
+func TestSMTPNotifierExpired(t *testing.T) {
+	server := startFakeSMTPServer(t)
+	host, port := server.addr()
+
+	notifier := notify.NewSMTPNotifier(host, port, "", "", "alerts@example.com", []string{"buyer@example.com"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, notifier.NotifyExpired(ctx, testExpiredEvent))
+
+	mails := server.received()
+	require.Len(t, mails, 1)
+	assert.Contains(t, mails[0].Data, "批號：LOT-A\r\n")
+	assert.Contains(t, mails[0].Data, "到期日：2025-01-01\r\n")
+	assert.Contains(t, mails[0].Data, "數量：12\r\n")
+}
+
+// 測試 SMTP 伺服器無法連接時返回錯誤
+func TestSMTPNotifierUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().(*net.TCPAddr)
+	listener.Close()
+
+	notifier := notify.NewSMTPNotifier(addr.IP.String(), addr.Port, "", "", "alerts@example.com", []string{"buyer@example.com"})
+
+	assert.Error(t, notifier.NotifyLowStock(context.Background(), testEvent))
+}
+
+// 測試 Webhook 以 JSON 送出事件，非 2xx 狀態碼視為失敗
+func TestWebhookNotifier(t *testing.T) {
+	var received map[string]json.RawMessage
+	status := http.StatusNoContent
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	notifier := notify.NewWebhookNotifier(server.URL, server.Client())
+
+	require.NoError(t, notifier.NotifyLowStock(context.Background(), testEvent))
+	assert.JSONEq(t, `"low_stock"`, string(received["type"]))
+
+	var event models.LowStockEvent
+	require.NoError(t, json.Unmarshal(received["event"], &event))
+	assert.Equal(t, testEvent, event)
+
+	require.NoError(t, notifier.NotifyExpired(context.Background(), testExpiredEvent))
+	assert.JSONEq(t, `"expired_stock"`, string(received["type"]))
+
+	var expired models.ExpiredStockEvent
+	require.NoError(t, json.Unmarshal(received["event"], &expired))
+	assert.Equal(t, testExpiredEvent, expired)
+
+	status = http.StatusInternalServerError
+	assert.Error(t, notifier.NotifyLowStock(context.Background(), testEvent))
+}
+
+// recordingNotifier 記錄收到的事件，可設定返回的錯誤
+type recordingNotifier struct {
+	mu     sync.Mutex
+	events []any
+	err    error
+}
+
+func (n *recordingNotifier) NotifyLowStock(ctx context.Context, event models.LowStockEvent) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.events = append(n.events, event)
+	return n.err
+}
+
+func (n *recordingNotifier) NotifyExpired(ctx context.Context, event models.ExpiredStockEvent) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.events = append(n.events, event)
+	return n.err
+}
+
+func (n *recordingNotifier) count() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.events)
+}
+
+// 測試 Multi 通知所有管道並合併錯誤
+func TestMultiNotifier(t *testing.T) {
+	failing := &recordingNotifier{err: errors.New("管道失敗")}
+	ok := &recordingNotifier{}
+
+	err := notify.Multi(failing, ok).NotifyLowStock(context.Background(), testEvent)
+
+	assert.ErrorIs(t, err, failing.err)
+	assert.Equal(t, 1, failing.count())
+	assert.Equal(t, 1, ok.count())
+}
+
+// 測試派送器在佇列已滿時丟棄事件，停止時送出佇列中剩餘的事件
+func TestDispatcher(t *testing.T) {
+	recorder := &recordingNotifier{}
+	dispatcher := notify.NewDispatcher(recorder, zap.NewNop(), 2, time.Second)
+
+	// 尚未啟動時事件留在佇列中
+	assert.NoError(t, dispatcher.NotifyLowStock(context.Background(), testEvent))
+	assert.NoError(t, dispatcher.NotifyLowStock(context.Background(), testEvent))
+	assert.ErrorIs(t, dispatcher.NotifyLowStock(context.Background(), testEvent), notify.ErrQueueFull)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	dispatcher.Run(ctx)
+
+	assert.Equal(t, 2, recorder.count())
+
+	// 啟動後非同步送出新事件
+	ctx, cancel = context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		dispatcher.Run(ctx)
+		close(done)
+	}()
+
+	assert.NoError(t, dispatcher.NotifyLowStock(context.Background(), testEvent))
+	assert.NoError(t, dispatcher.NotifyExpired(context.Background(), testExpiredEvent))
+	assert.Eventually(t, func() bool { return recorder.count() == 4 }, time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+}
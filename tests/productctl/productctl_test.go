@@ -0,0 +1,394 @@
+package productctl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"main/internal/audit"
+	"main/internal/auth"
+	"main/internal/config"
+	"main/internal/models"
+	"main/internal/productctl"
+	"main/internal/repository"
+	"main/internal/service"
+	"main/internal/tenant"
+	"main/pkg/database"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// 模擬產品服務，只實現命令使用的方法
+type MockProductService struct {
+	service.ProductService
+	mock.Mock
+}
+
+func (m *MockProductService) GetProducts(ctx context.Context, query models.ProductQuery) (models.ProductPage, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).(models.ProductPage), args.Error(1)
+}
+
+func (m *MockProductService) GetProduct(ctx context.Context, id int64) (models.Product, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.Product), args.Error(1)
+}
+
+func (m *MockProductService) CreateProduct(ctx context.Context, product models.Product) (models.Product, error) {
+	args := m.Called(ctx, product)
+	return args.Get(0).(models.Product), args.Error(1)
+}
+
+func (m *MockProductService) DeleteProduct(ctx context.Context, id int64, version int) error {
+	args := m.Called(ctx, id, version)
+	return args.Error(0)
+}
+
+func (m *MockProductService) GetDeletedProducts(ctx context.Context, query models.ProductQuery) (models.ProductPage, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).(models.ProductPage), args.Error(1)
+}
+
+func (m *MockProductService) RestoreProduct(ctx context.Context, id int64) (models.Product, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.Product), args.Error(1)
+}
+
+func (m *MockProductService) PurgeProduct(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockProductService) PurgeDeletedProducts(ctx context.Context, retention time.Duration) (int64, error) {
+	args := m.Called(ctx, retention)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockProductService) ImportProducts(ctx context.Context, rows []models.ImportRow, dryRun bool) (models.ImportReport, error) {
+	args := m.Called(ctx, rows, dryRun)
+	return args.Get(0).(models.ImportReport), args.Error(1)
+}
+
+// 模擬 API 金鑰服務
+type MockAPIKeyService struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyService) CreateAPIKey(ctx context.Context, req models.CreateAPIKeyRequest) (models.CreatedAPIKey, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(models.CreatedAPIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyService) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	args := m.Called(ctx)
+	keys, _ := args.Get(0).([]models.APIKey)
+	return keys, args.Error(1)
+}
+
+func (m *MockAPIKeyService) RotateAPIKey(ctx context.Context, id int64) (models.CreatedAPIKey, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.CreatedAPIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyService) RevokeAPIKey(ctx context.Context, id int64) (models.APIKey, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyService) Verify(ctx context.Context, key string) (auth.Principal, error) {
+	args := m.Called(ctx, key)
+	return args.Get(0).(auth.Principal), args.Error(1)
+}
+
+// 模擬遷移執行器
+type MockMigrator struct {
+	mock.Mock
+}
+
+func (m *MockMigrator) Up() ([]database.Migration, error) {
+	args := m.Called()
+	migrations, _ := args.Get(0).([]database.Migration)
+	return migrations, args.Error(1)
+}
+
+func (m *MockMigrator) Down(steps int) ([]database.Migration, error) {
+	args := m.Called(steps)
+	migrations, _ := args.Get(0).([]database.Migration)
+	return migrations, args.Error(1)
+}
+
+func (m *MockMigrator) Status() ([]database.MigrationState, error) {
+	args := m.Called()
+	states, _ := args.Get(0).([]database.MigrationState)
+	return states, args.Error(1)
+}
+
+func (m *MockMigrator) SetRowLevelSecurity(ctx context.Context, enabled bool) error {
+	args := m.Called(ctx, enabled)
+	return args.Error(0)
+}
+
+// harness 以模擬的服務執行 productctl，記錄輸出與是否連接了資料庫
+type harness struct {
+	app        *productctl.App
+	stdout     bytes.Buffer
+	stderr     bytes.Buffer
+	env        map[string]string
+	products   *MockProductService
+	apiKeys    *MockAPIKeyService
+	migrator   *MockMigrator
+	connectErr error
+	connected  bool
+	closed     bool
+}
+
+func newHarness() *harness {
+	h := &harness{
+		env:      map[string]string{},
+		products: new(MockProductService),
+		apiKeys:  new(MockAPIKeyService),
+		migrator: new(MockMigrator),
+	}
+	h.app = &productctl.App{
+		Stdout:     &h.stdout,
+		Stderr:     &h.stderr,
+		Getenv:     func(key string) string { return h.env[key] },
+		LoadConfig: func() (*config.AppConfig, error) { return config.DefaultConfig(), nil },
+		Connect: func(*config.AppConfig) (*productctl.Backend, error) {
+			if h.connectErr != nil {
+				return nil, h.connectErr
+			}
+			h.connected = true
+			return &productctl.Backend{
+				Products: h.products,
+				APIKeys:  h.apiKeys,
+				Migrator: h.migrator,
+				Close: func() error {
+					h.closed = true
+					return nil
+				},
+			}, nil
+		},
+	}
+	return h
+}
+
+// writeFile 在臨時目錄寫入檔案並返回路徑
+func writeFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// 測試命令的分派、參數解析、輸出與退出碼
+func TestRun(t *testing.T) {
+	csvFile := writeFile(t, "products.csv", "sku_code,sku_name,sku_amount,expiration\nSKU001,產品一,10,2099-01-01\n,沒有編號,1,2099-01-01\n")
+	textFile := writeFile(t, "products.txt", "")
+
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		setup   func(h *harness)
+		code    int
+		stdout  string
+		stderr  string
+		connect bool
+	}{
+		// 命令與用法
+		{name: "沒有命令", args: nil, code: productctl.ExitUsage, stderr: "用法"},
+		{name: "說明", args: []string{"help"}, code: productctl.ExitOK, stdout: "用法"},
+		{name: "未知的命令", args: []string{"frobnicate"}, code: productctl.ExitUsage, stderr: "未知的命令: frobnicate"},
+		{name: "無效的租戶", args: []string{"products", "list"}, env: map[string]string{productctl.TenantEnv: "Acme Corp"}, code: productctl.ExitUsage, stderr: "無效的 PRODUCTCTL_TENANT"},
+		{name: "無法加載配置", args: []string{"products", "get", "1"}, setup: func(h *harness) {
+			h.app.LoadConfig = func() (*config.AppConfig, error) { return nil, errors.New("配置文件格式錯誤") }
+		}, code: productctl.ExitError, stderr: "無法加載配置"},
+		{name: "無法連接資料庫", args: []string{"products", "get", "1"}, setup: func(h *harness) {
+			h.connectErr = errors.New("連線被拒")
+		}, code: productctl.ExitError, stderr: "連線被拒"},
+
+		// products
+		{name: "products 缺少子命令", args: []string{"products"}, code: productctl.ExitError, stderr: "請指定 products list、get、create 或 delete"},
+		{name: "products 未知的子命令", args: []string{"products", "frob"}, code: productctl.ExitError, stderr: "未知的 products 子命令: frob"},
+		{name: "products list 參數", args: []string{"products", "list", "-page-size", "5", "-offset", "10", "-sort-by", "sku_code", "-sort-dir", "desc", "-sku-code", "SKU", "-sku-name", "茶", "-json"}, setup: func(h *harness) {
+			h.products.On("GetProducts", mock.Anything, models.ProductQuery{PageSize: 5, Offset: 10, SortBy: "sku_code", SortDir: "desc", SkuCode: "SKU", SkuName: "茶"}).
+				Return(models.ProductPage{Items: []models.Product{{ID: 1, SkuCode: "SKU001"}}, Total: 11, PageSize: 5, Offset: 10}, nil)
+		}, code: productctl.ExitOK, stdout: `"sku_code": "SKU001"`, connect: true},
+		{name: "products list 表格", args: []string{"products", "list"}, setup: func(h *harness) {
+			h.products.On("GetProducts", mock.Anything, models.ProductQuery{PageSize: models.DefaultPageSize, SortBy: "id", SortDir: models.SortAsc}).
+				Return(models.ProductPage{Items: []models.Product{{ID: 1, SkuCode: "SKU001"}}, Total: 1}, nil)
+		}, code: productctl.ExitOK, stdout: "共 1 筆，顯示 1-1", connect: true},
+		{name: "products list 不支援的排序欄位", args: []string{"products", "list", "-sort-by", "color"}, code: productctl.ExitError, stderr: "不支援的排序欄位: color"},
+		{name: "products list 無效的排序方向", args: []string{"products", "list", "-sort-dir", "up"}, code: productctl.ExitError, stderr: "sort-dir 只能為 asc 或 desc"},
+		{name: "products list 無效的參數值", args: []string{"products", "list", "-page-size", "many"}, code: productctl.ExitError, stderr: "invalid value"},
+		{name: "products list 未知的參數", args: []string{"products", "list", "-verbose"}, code: productctl.ExitError, stderr: "flag provided but not defined: -verbose"},
+		{name: "products get", args: []string{"products", "get", "7"}, setup: func(h *harness) {
+			h.products.On("GetProduct", mock.Anything, int64(7)).Return(models.Product{ID: 7, SkuCode: "SKU007"}, nil)
+		}, code: productctl.ExitOK, stdout: `"sku_code": "SKU007"`, connect: true},
+		{name: "products get 不存在", args: []string{"products", "get", "999"}, setup: func(h *harness) {
+			h.products.On("GetProduct", mock.Anything, int64(999)).Return(models.Product{}, repository.ErrProductNotFound)
+		}, code: productctl.ExitError, stderr: "產品未找到", connect: true},
+		{name: "products get 無效的 ID", args: []string{"products", "get", "abc"}, code: productctl.ExitError, stderr: "無效的產品ID: abc"},
+		{name: "products get 缺少 ID", args: []string{"products", "get"}, code: productctl.ExitError, stderr: "請提供一個產品 ID"},
+		{name: "products create", args: []string{"products", "create", "-sku-code", "SKU010", "-sku-name", "新產品", "-amount", "3", "-expiration", "2099-01-01"}, setup: func(h *harness) {
+			h.products.On("CreateProduct", mock.Anything, models.Product{SkuCode: "SKU010", SkuName: "新產品", SkuAmount: 3, Expiration: models.MustParseDate("2099-01-01")}).
+				Return(models.Product{ID: 10, SkuCode: "SKU010"}, nil)
+		}, code: productctl.ExitOK, stdout: `"id": 10`, connect: true},
+		{name: "products create 無效的到期日", args: []string{"products", "create", "-sku-code", "SKU010", "-sku-name", "新產品", "-expiration", "2099-13-01"}, code: productctl.ExitError},
+		{name: "products create 缺少編號", args: []string{"products", "create", "-sku-name", "新產品"}, code: productctl.ExitError},
+		{name: "products delete", args: []string{"products", "delete", "3"}, setup: func(h *harness) {
+			h.products.On("DeleteProduct", mock.Anything, int64(3), 0).Return(nil)
+		}, code: productctl.ExitOK, stdout: "產品 3 已移到回收站", connect: true},
+
+		// trash
+		{name: "trash 缺少子命令", args: []string{"trash"}, code: productctl.ExitError, stderr: "請指定 trash list、restore 或 purge"},
+		{name: "trash list", args: []string{"trash", "list", "-page-size", "2", "-json"}, setup: func(h *harness) {
+			h.products.On("GetDeletedProducts", mock.Anything, models.ProductQuery{PageSize: 2}).Return(models.ProductPage{Items: []models.Product{{ID: 4}}, Total: 1}, nil)
+		}, code: productctl.ExitOK, stdout: `"total": 1`, connect: true},
+		{name: "trash restore", args: []string{"trash", "restore", "4"}, setup: func(h *harness) {
+			h.products.On("RestoreProduct", mock.Anything, int64(4)).Return(models.Product{ID: 4, SkuCode: "SKU004"}, nil)
+		}, code: productctl.ExitOK, stdout: "產品 4 (SKU004) 已還原", connect: true},
+		{name: "trash purge 單個產品", args: []string{"trash", "purge", "4"}, setup: func(h *harness) {
+			h.products.On("PurgeProduct", mock.Anything, int64(4)).Return(nil)
+		}, code: productctl.ExitOK, stdout: "產品 4 已永久刪除", connect: true},
+		{name: "trash purge 保留期", args: []string{"trash", "purge", "-older-than-days", "30"}, setup: func(h *harness) {
+			h.products.On("PurgeDeletedProducts", mock.Anything, 30*24*time.Hour).Return(int64(2), nil)
+		}, code: productctl.ExitOK, stdout: "已永久刪除 2 個產品", connect: true},
+		{name: "trash purge 缺少參數", args: []string{"trash", "purge"}, code: productctl.ExitError, stderr: "請提供產品 ID 或 -older-than-days"},
+		{name: "trash purge 參數衝突", args: []string{"trash", "purge", "-older-than-days", "30", "4"}, code: productctl.ExitError, stderr: "不能同時使用"},
+
+		// import
+		{name: "import 缺少檔案", args: []string{"import"}, code: productctl.ExitError, stderr: "請提供一個要導入的檔案"},
+		{name: "import 檔案不存在", args: []string{"import", filepath.Join(t.TempDir(), "missing.csv")}, code: productctl.ExitError, stderr: "無法打開檔案"},
+		{name: "import 不支援的格式", args: []string{"import", textFile}, code: productctl.ExitError, stderr: "不支援的檔案格式: .txt"},
+		{name: "import dry-run 不連接資料庫", args: []string{"import", "-dry-run", csvFile}, code: productctl.ExitOK, stdout: "共 2 行，有效 1 行，無效 1 行", stderr: "第 3 行"},
+		{name: "import 部分失敗", args: []string{"import", csvFile}, setup: func(h *harness) {
+			h.products.On("ImportProducts", mock.Anything, mock.AnythingOfType("[]models.ImportRow"), false).
+				Return(models.ImportReport{Total: 2, Created: 1, Failed: 1, Errors: []models.ImportRowError{{Line: 3, Error: "SKU 代碼不能為空"}}}, nil)
+		}, code: productctl.ExitError, stdout: "新增 1 行", stderr: "1 行導入失敗", connect: true},
+
+		// seed
+		{name: "seed 產品表不為空", args: []string{"seed"}, setup: func(h *harness) {
+			h.products.On("GetProducts", mock.Anything, models.ProductQuery{PageSize: 1}).Return(models.ProductPage{Total: 5}, nil)
+		}, code: productctl.ExitOK, stdout: "略過", connect: true},
+		{name: "seed -force", args: []string{"seed", "-force"}, setup: func(h *harness) {
+			h.products.On("CreateProduct", mock.Anything, mock.AnythingOfType("models.Product")).Return(models.Product{ID: 1, SkuCode: "SKU001"}, nil).Times(3)
+		}, code: productctl.ExitOK, stdout: "已創建產品 1", connect: true},
+
+		// apikeys
+		{name: "apikeys 缺少子命令", args: []string{"apikeys"}, code: productctl.ExitError, stderr: "請指定 apikeys create、list、rotate 或 revoke"},
+		{name: "apikeys create", args: []string{"apikeys", "create", "-name", "pos-01", "-scopes", "product:read, stock:adjust", "-json"}, setup: func(h *harness) {
+			h.apiKeys.On("CreateAPIKey", mock.Anything, models.CreateAPIKeyRequest{Name: "pos-01", Scopes: []string{"product:read", "stock:adjust"}}).
+				Return(models.CreatedAPIKey{APIKey: models.APIKey{ID: 1, Name: "pos-01"}, Key: "pk_secret"}, nil)
+		}, code: productctl.ExitOK, stdout: `"key": "pk_secret"`, connect: true},
+		{name: "apikeys list", args: []string{"apikeys", "list"}, setup: func(h *harness) {
+			h.apiKeys.On("ListAPIKeys", mock.Anything).Return([]models.APIKey{{ID: 1, Name: "pos-01", RevokedAt: models.Timestamp{Time: time.Now()}}}, nil)
+		}, code: productctl.ExitOK, stdout: "revoked", connect: true},
+		{name: "apikeys rotate", args: []string{"apikeys", "rotate", "1"}, setup: func(h *harness) {
+			h.apiKeys.On("RotateAPIKey", mock.Anything, int64(1)).Return(models.CreatedAPIKey{APIKey: models.APIKey{ID: 1, Name: "pos-01"}, Key: "pk_rotated"}, nil)
+		}, code: productctl.ExitOK, stdout: "金鑰: pk_rotated", stderr: "請立即保存金鑰", connect: true},
+		{name: "apikeys rotate 已撤銷", args: []string{"apikeys", "rotate", "1"}, setup: func(h *harness) {
+			h.apiKeys.On("RotateAPIKey", mock.Anything, int64(1)).Return(models.CreatedAPIKey{}, repository.ErrAPIKeyRevoked)
+		}, code: productctl.ExitError, stderr: "API 金鑰已撤銷", connect: true},
+		{name: "apikeys revoke", args: []string{"apikeys", "revoke", "2"}, setup: func(h *harness) {
+			h.apiKeys.On("RevokeAPIKey", mock.Anything, int64(2)).Return(models.APIKey{ID: 2, Name: "etl"}, nil)
+		}, code: productctl.ExitOK, stdout: "API 金鑰 2 (etl) 已撤銷", connect: true},
+		{name: "apikeys revoke 無效的 ID", args: []string{"apikeys", "revoke", "two"}, code: productctl.ExitError, stderr: "無效的 API 金鑰ID: two"},
+
+		// migrate
+		{name: "migrate 缺少子命令", args: []string{"migrate"}, code: productctl.ExitError, stderr: "請指定 migrate up、down 或 status"},
+		{name: "migrate 未知的子命令", args: []string{"migrate", "sideways"}, code: productctl.ExitError, stderr: "未知的 migrate 子命令: sideways"},
+		{name: "migrate up", args: []string{"migrate", "up"}, setup: func(h *harness) {
+			h.migrator.On("Up").Return([]database.Migration{{Version: 16, Name: "create_low_stock_events"}}, nil)
+			h.migrator.On("SetRowLevelSecurity", mock.Anything, false).Return(nil)
+		}, code: productctl.ExitOK, stdout: "已套用 0016_create_low_stock_events", connect: true},
+		{name: "migrate down", args: []string{"migrate", "down", "-steps", "2"}, setup: func(h *harness) {
+			h.migrator.On("Down", 2).Return(nil, nil)
+		}, code: productctl.ExitOK, stdout: "沒有可回滾的遷移", connect: true},
+		{name: "migrate down 無效的步數", args: []string{"migrate", "down", "-steps", "0"}, code: productctl.ExitError, stderr: "steps 必須為正整數"},
+		{name: "migrate status", args: []string{"migrate", "status"}, setup: func(h *harness) {
+			h.migrator.On("Status").Return([]database.MigrationState{{Migration: database.Migration{Version: 1, Name: "create_products"}}}, nil)
+		}, code: productctl.ExitOK, stdout: "pending", connect: true},
+
+		// config
+		{name: "config 缺少子命令", args: []string{"config"}, code: productctl.ExitError, stderr: "請指定 config check"},
+		{name: "config check", args: []string{"config", "check"}, setup: func(h *harness) {
+			h.migrator.On("Status").Return([]database.MigrationState{{Applied: true}, {}}, nil)
+		}, code: productctl.ExitOK, stdout: "遷移: 1 個尚未套用", connect: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHarness()
+			for key, value := range tt.env {
+				h.env[key] = value
+			}
+			if tt.setup != nil {
+				tt.setup(h)
+			}
+
+			code := h.app.Run(context.Background(), tt.args)
+
+			assert.Equal(t, tt.code, code, "stdout: %s\nstderr: %s", h.stdout.String(), h.stderr.String())
+			assert.Contains(t, h.stdout.String(), tt.stdout)
+			assert.Contains(t, h.stderr.String(), tt.stderr)
+			assert.Equal(t, tt.connect, h.connected, "是否連接資料庫")
+			assert.Equal(t, h.connected, h.closed, "連線在命令結束後關閉")
+			h.products.AssertExpectations(t)
+			h.apiKeys.AssertExpectations(t)
+			h.migrator.AssertExpectations(t)
+		})
+	}
+}
+
+// 測試命令以系統主體與 productctl 操作者執行，租戶取自 PRODUCTCTL_TENANT，未設置時為預設租戶
+func TestRunTenant(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    string
+		tenant string
+	}{
+		{"未設置", "", tenant.Default},
+		{"指定租戶", "acme", "acme"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHarness()
+			h.env[productctl.TenantEnv] = tt.env
+
+			h.products.On("GetProduct", mock.MatchedBy(func(ctx context.Context) bool {
+				principal, ok := auth.FromContext(ctx)
+				return ok && principal.Subject == auth.System.Subject &&
+					audit.Actor(ctx) == "productctl" &&
+					tenant.ID(ctx) == tt.tenant
+			}), int64(1)).Return(models.Product{ID: 1}, nil)
+
+			assert.Equal(t, productctl.ExitOK, h.app.Run(context.Background(), []string{"products", "get", "1"}), h.stderr.String())
+			h.products.AssertExpectations(t)
+		})
+	}
+}
+
+// 測試 apikeys create 的 -tenant 取代 PRODUCTCTL_TENANT
+func TestRunAPIKeyTenant(t *testing.T) {
+	h := newHarness()
+	h.env[productctl.TenantEnv] = "acme"
+
+	h.apiKeys.On("CreateAPIKey", mock.MatchedBy(func(ctx context.Context) bool {
+		return tenant.ID(ctx) == "globex"
+	}), models.CreateAPIKeyRequest{Name: "pos-01", Scopes: []string{}, Tenant: "globex"}).
+		Return(models.CreatedAPIKey{APIKey: models.APIKey{ID: 1, Name: "pos-01", Tenant: "globex"}, Key: "pk_secret"}, nil)
+
+	code := h.app.Run(context.Background(), []string{"apikeys", "create", "-name", "pos-01", "-tenant", "globex"})
+
+	assert.Equal(t, productctl.ExitOK, code, h.stderr.String())
+	assert.Contains(t, h.stdout.String(), "租戶: globex")
+	h.apiKeys.AssertExpectations(t)
+}
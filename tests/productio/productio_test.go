@@ -0,0 +1,121 @@
+package productio
+
+import (
+	"bytes"
+	"errors"
+	"main/internal/models"
+	"main/internal/productio"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 測試讀取 CSV：表頭不分大小寫，無效數量記錄在該列
+func TestReadRowsCSV(t *testing.T) {
+	input := "SKU_Code, sku_name ,sku_amount,expiration,備註\n" +
+		"SKU001,產品 1,10,2025-01-01,忽略\n" +
+		"\n" +
+		"SKU002,\"產品\n2\",abc,,\n" +
+		"SKU003,產品 3,,,\n"
+
+	rows, err := productio.ReadRows(strings.NewReader(input), productio.FormatCSV, nil)
+
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+
+	assert.Equal(t, 2, rows[0].Line)
+	assert.Equal(t, models.Product{SkuCode: "SKU001", SkuName: "產品 1", SkuAmount: 10, Expiration: models.MustParseDate("2025-01-01")}, rows[0].Product)
+	assert.NoError(t, rows[0].Err)
+
+	// 空行被跳過，行號仍對應檔案中的位置
+	assert.Equal(t, 4, rows[1].Line)
+	assert.Equal(t, "產品\n2", rows[1].Product.SkuName)
+	assert.EqualError(t, rows[1].Err, "sku_amount 必須為整數: abc")
+
+	// 欄位內的換行不影響後續行號
+	assert.Equal(t, 6, rows[2].Line)
+	assert.Equal(t, 0, rows[2].Product.SkuAmount)
+}
+
+// 測試以表頭對應讀取非標準欄位名稱
+func TestReadRowsMapping(t *testing.T) {
+	input := "料號,品名,數量\nSKU001,產品 1,5\n"
+	mapping := map[string]string{"料號": "sku_code", "品名": "sku_name", "數量": "sku_amount"}
+
+	rows, err := productio.ReadRows(strings.NewReader(input), productio.FormatCSV, mapping)
+
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, models.Product{SkuCode: "SKU001", SkuName: "產品 1", SkuAmount: 5}, rows[0].Product)
+}
+
+// 測試缺少 sku_code 表頭或對應到未知欄位時返回錯誤
+func TestReadRowsInvalidHeader(t *testing.T) {
+	_, err := productio.ReadRows(strings.NewReader("sku_name\n產品 1\n"), productio.FormatCSV, nil)
+	assert.True(t, errors.Is(err, productio.ErrMissingSkuCode))
+
+	_, err = productio.ReadRows(strings.NewReader("code\nSKU001\n"), productio.FormatCSV, map[string]string{"code": "id"})
+	assert.Error(t, err)
+
+	_, err = productio.ReadRows(strings.NewReader("sku_code\nSKU001\n"), "json", nil)
+	assert.True(t, errors.Is(err, productio.ErrUnsupportedFormat))
+}
+
+// 測試 CSV 匯出的表頭與內容
+func TestWriterCSV(t *testing.T) {
+	var buf bytes.Buffer
+
+	writer, err := productio.NewWriter(&buf, productio.FormatCSV)
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(models.Product{ID: 1, SkuCode: "SKU001", SkuName: "產品, 1", SkuAmount: 10, ReorderPoint: 5, Version: 2}))
+	require.NoError(t, writer.Close())
+
+	assert.Equal(t,
+		"id,sku_code,sku_name,sku_amount,expiration,reorder_point,reorder_quantity,create_at,update_at,version\n"+
+			"1,SKU001,\"產品, 1\",10,,5,0,,,2\n",
+		buf.String())
+}
+
+// 測試匯出的 XLSX 可以再次匯入
+func TestWriterXLSXRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+
+	products := []models.Product{
+		{ID: 1, SkuCode: "SKU001", SkuName: "產品 1", SkuAmount: 10, Expiration: models.MustParseDate("2025-01-01"), ReorderPoint: 5, ReorderQuantity: 20, Version: 1},
+		{ID: 2, SkuCode: "SKU002", SkuName: "產品 2", SkuAmount: 20, Version: 3},
+	}
+
+	writer, err := productio.NewWriter(&buf, productio.FormatXLSX)
+	require.NoError(t, err)
+	for _, p := range products {
+		require.NoError(t, writer.Write(p))
+	}
+	require.NoError(t, writer.Close())
+
+	rows, err := productio.ReadRows(bytes.NewReader(buf.Bytes()), productio.FormatXLSX, nil)
+
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	for i, row := range rows {
+		assert.Equal(t, i+2, row.Line)
+		assert.NoError(t, row.Err)
+		assert.Equal(t, products[i].SkuCode, row.Product.SkuCode)
+		assert.Equal(t, products[i].SkuName, row.Product.SkuName)
+		assert.Equal(t, products[i].SkuAmount, row.Product.SkuAmount)
+		assert.Equal(t, products[i].Expiration, row.Product.Expiration)
+		assert.Equal(t, products[i].ReorderPoint, row.Product.ReorderPoint)
+		assert.Equal(t, products[i].ReorderQuantity, row.Product.ReorderQuantity)
+	}
+}
+
+// 測試依副檔名推斷格式
+func TestFormatFromFilename(t *testing.T) {
+	format, err := productio.FormatFromFilename("products.XLSX")
+	assert.NoError(t, err)
+	assert.Equal(t, productio.FormatXLSX, format)
+
+	_, err = productio.FormatFromFilename("products.json")
+	assert.Error(t, err)
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"main/internal/models"
+	"main/internal/repository"
+	"main/internal/tenant"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var lowStockEventColumns = []string{"id", "tenant_id", "product_id", "sku_code", "sku_name", "sku_amount", "reorder_point", "reorder_quantity", "occurred_at"}
+
+const selectLowStockEvents = `SELECT id, tenant_id, product_id, .* FROM low_stock_events ORDER BY id LIMIT \$1 FOR UPDATE SKIP LOCKED`
+
+// 測試跨所有租戶鎖定最早的事件並依記錄順序派送，送出後才刪除
+func TestDeliverLowStockEvents(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewAlertRepository(db)
+
+	now := time.Now()
+	expectTenantBegin(mock, tenant.All)
+	mock.ExpectQuery(selectLowStockEvents).
+		WithArgs(100).
+		WillReturnRows(sqlmock.NewRows(lowStockEventColumns).
+			AddRow(3, "default", 1, "SKU001", "產品 1", 9, 10, 50, now).
+			AddRow(4, "acme", 7, "SKU007", "產品 7", 0, 5, 20, now))
+	mock.ExpectExec(`DELETE FROM low_stock_events WHERE id = ANY\(\$1\)`).
+		WithArgs(pq.Array([]int64{3, 4})).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	// 調用儲存庫方法
+	var events []models.LowStockEvent
+	delivered, err := repo.DeliverLowStockEvents(tenant.WithID(context.Background(), "acme"), 100, func(event models.LowStockEvent) error {
+		events = append(events, event)
+		return nil
+	})
+
+	// 驗證結果
+	require.NoError(t, err)
+	assert.Equal(t, 2, delivered)
+	assert.Equal(t, []models.LowStockEvent{
+		{ID: 3, TenantID: "default", ProductID: 1, SkuCode: "SKU001", SkuName: "產品 1", SkuAmount: 9, ReorderPoint: 10, ReorderQuantity: 50, OccurredAt: now},
+		{ID: 4, TenantID: "acme", ProductID: 7, SkuCode: "SKU007", SkuName: "產品 7", SkuAmount: 0, ReorderPoint: 5, ReorderQuantity: 20, OccurredAt: now},
+	}, events)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試派送失敗時停止，只刪除失敗之前送出的事件
+func TestDeliverLowStockEventsStopsOnFailure(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewAlertRepository(db)
+
+	now := time.Now()
+	expectTenantBegin(mock, tenant.All)
+	mock.ExpectQuery(selectLowStockEvents).
+		WithArgs(100).
+		WillReturnRows(sqlmock.NewRows(lowStockEventColumns).
+			AddRow(3, "default", 1, "SKU001", "產品 1", 9, 10, 50, now).
+			AddRow(4, "default", 2, "SKU002", "產品 2", 1, 5, 20, now).
+			AddRow(5, "default", 3, "SKU003", "產品 3", 2, 5, 20, now))
+	mock.ExpectExec(`DELETE FROM low_stock_events WHERE id = ANY\(\$1\)`).
+		WithArgs(pq.Array([]int64{3})).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// 調用儲存庫方法
+	unavailable := errors.New("Webhook 無回應")
+	calls := 0
+	delivered, err := repo.DeliverLowStockEvents(context.Background(), 100, func(event models.LowStockEvent) error {
+		calls++
+		if event.ID == 4 {
+			return unavailable
+		}
+		return nil
+	})
+
+	// 驗證結果
+	assert.ErrorIs(t, err, unavailable)
+	assert.Equal(t, 1, delivered)
+	assert.Equal(t, 2, calls)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試第一個事件就送出失敗時不刪除任何事件
+func TestDeliverLowStockEventsNothingDelivered(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewAlertRepository(db)
+
+	expectTenantBegin(mock, tenant.All)
+	mock.ExpectQuery(selectLowStockEvents).
+		WithArgs(100).
+		WillReturnRows(sqlmock.NewRows(lowStockEventColumns).
+			AddRow(3, "default", 1, "SKU001", "產品 1", 9, 10, 50, time.Now()))
+	mock.ExpectCommit()
+
+	// 調用儲存庫方法並驗證結果
+	delivered, err := repo.DeliverLowStockEvents(context.Background(), 100, func(models.LowStockEvent) error {
+		return errors.New("Webhook 無回應")
+	})
+	assert.Error(t, err)
+	assert.Zero(t, delivered)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
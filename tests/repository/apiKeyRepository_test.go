@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"main/internal/models"
+	"main/internal/repository"
+	"main/internal/tenant"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var apiKeyColumns = []string{"id", "name", "prefix", "scopes", "expires_at", "last_used_at", "revoked_at", "create_at", "update_at"}
+
+// 測試創建 API 金鑰只寫入雜湊，同名的未撤銷金鑰返回 ErrDuplicateAPIKey
+func TestCreateAPIKey(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewAPIKeyRepository(db)
+
+	now := time.Now()
+	input := models.APIKey{Name: "pos-01", Prefix: "pk_abcdefgh", Scopes: pq.StringArray{"product:read"}, Tenant: "acme"}
+	insert := `INSERT INTO api_keys \(name, prefix, key_hash, scopes, expires_at, tenant_id\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6\) RETURNING id, name, prefix, scopes, tenant_id`
+	mock.ExpectQuery(insert).
+		WithArgs("pos-01", "pk_abcdefgh", "hash", input.Scopes, nil, "acme").
+		WillReturnRows(sqlmock.NewRows(apiKeyColumns).AddRow(1, "pos-01", "pk_abcdefgh", "{product:read}", nil, nil, nil, now, now))
+	mock.ExpectQuery(insert).
+		WithArgs("pos-01", "pk_abcdefgh", "hash", input.Scopes, nil, "acme").
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "idx_api_keys_active_name"})
+
+	// 調用儲存庫方法
+	key, err := repo.CreateAPIKey(context.Background(), input, "hash")
+
+	// 驗證結果
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), key.ID)
+	assert.Equal(t, pq.StringArray{"product:read"}, key.Scopes)
+	assert.True(t, key.ExpiresAt.IsZero())
+
+	_, err = repo.CreateAPIKey(context.Background(), input, "hash")
+	assert.ErrorIs(t, err, repository.ErrDuplicateAPIKey)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試輪換區分不存在與已撤銷的金鑰
+func TestRotateAPIKey(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewAPIKeyRepository(db)
+
+	now := time.Now()
+	update := `UPDATE api_keys SET prefix = \$2, key_hash = \$3, last_used_at = NULL, update_at = \$4 WHERE id = \$1 AND tenant_id = \$5 AND revoked_at IS NULL`
+	mock.ExpectQuery(update).
+		WithArgs(int64(1), "pk_newprefx", "new-hash", now, "acme").
+		WillReturnRows(sqlmock.NewRows(apiKeyColumns).AddRow(1, "pos-01", "pk_newprefx", "{}", nil, nil, nil, now, now))
+	mock.ExpectQuery(update).
+		WithArgs(int64(2), "pk_newprefx", "new-hash", now, "acme").
+		WillReturnRows(sqlmock.NewRows(apiKeyColumns))
+	mock.ExpectQuery(`SELECT revoked_at IS NOT NULL FROM api_keys WHERE id = \$1 AND tenant_id = \$2`).
+		WithArgs(int64(2), "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(true))
+	mock.ExpectQuery(update).
+		WithArgs(int64(999), "pk_newprefx", "new-hash", now, "acme").
+		WillReturnRows(sqlmock.NewRows(apiKeyColumns))
+	mock.ExpectQuery(`SELECT revoked_at IS NOT NULL FROM api_keys WHERE id = \$1 AND tenant_id = \$2`).
+		WithArgs(int64(999), "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"?column?"}))
+
+	// 調用儲存庫方法並驗證結果
+	ctx := tenant.WithID(context.Background(), "acme")
+	key, err := repo.RotateAPIKey(ctx, 1, "pk_newprefx", "new-hash", now)
+	require.NoError(t, err)
+	assert.Equal(t, "pk_newprefx", key.Prefix)
+
+	_, err = repo.RotateAPIKey(ctx, 2, "pk_newprefx", "new-hash", now)
+	assert.ErrorIs(t, err, repository.ErrAPIKeyRevoked)
+
+	_, err = repo.RotateAPIKey(ctx, 999, "pk_newprefx", "new-hash", now)
+	assert.ErrorIs(t, err, repository.ErrAPIKeyNotFound)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試列出與撤銷只作用於請求租戶的金鑰，其他租戶的金鑰視為不存在
+func TestListAndRevokeAPIKeysByTenant(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewAPIKeyRepository(db)
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT id, name, prefix, scopes, tenant_id, .* FROM api_keys WHERE tenant_id = \$1 ORDER BY id`).
+		WithArgs("acme").
+		WillReturnRows(sqlmock.NewRows(apiKeyColumns).AddRow(3, "pos-01", "pk_abcdefgh", "{}", nil, nil, nil, now, now))
+	revoke := `UPDATE api_keys SET revoked_at = COALESCE\(revoked_at, \$2\), update_at = \$2 WHERE id = \$1 AND tenant_id = \$3`
+	mock.ExpectQuery(revoke).
+		WithArgs(int64(3), now, "acme").
+		WillReturnRows(sqlmock.NewRows(apiKeyColumns).AddRow(3, "pos-01", "pk_abcdefgh", "{}", nil, nil, now, now, now))
+	mock.ExpectQuery(revoke).
+		WithArgs(int64(1), now, "acme").
+		WillReturnRows(sqlmock.NewRows(apiKeyColumns))
+
+	// 調用儲存庫方法並驗證結果
+	ctx := tenant.WithID(context.Background(), "acme")
+	keys, err := repo.ListAPIKeys(ctx)
+	require.NoError(t, err)
+	if assert.Len(t, keys, 1) {
+		assert.Equal(t, int64(3), keys[0].ID)
+	}
+
+	key, err := repo.RevokeAPIKey(ctx, 3, now)
+	require.NoError(t, err)
+	assert.False(t, key.RevokedAt.IsZero())
+
+	_, err = repo.RevokeAPIKey(ctx, 1, now)
+	assert.ErrorIs(t, err, repository.ErrAPIKeyNotFound)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試最後使用時間每分鐘最多寫入一次
+func TestTouchAPIKey(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewAPIKeyRepository(db)
+
+	now := time.Now()
+	mock.ExpectExec(`UPDATE api_keys SET last_used_at = \$2 WHERE id = \$1 AND \(last_used_at IS NULL OR last_used_at < \$3\)`).
+		WithArgs(int64(1), now, now.Add(-time.Minute)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// 調用儲存庫方法並驗證結果
+	assert.NoError(t, repo.TouchAPIKey(context.Background(), 1, now))
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
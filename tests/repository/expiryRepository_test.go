@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"main/internal/models"
+	"main/internal/repository"
+	"main/internal/tenant"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 測試在同一個交易中標記過期的批次與產品，批次在前
+func TestMarkExpired(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewExpiryRepository(db)
+
+	today := models.MustParseDate("2025-04-01")
+	now := time.Date(2025, 4, 1, 0, 5, 0, 0, time.UTC)
+
+	expectBegin(mock)
+	mock.ExpectQuery(`UPDATE product_lots l SET expired_at = \$2, update_at = \$2 FROM products p WHERE p.id = l.product_id AND p.deleted_at IS NULL AND l.expired_at IS NULL AND l.quantity > 0 AND l.expiration < \$1 RETURNING`).
+		WithArgs(today, now).
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "sku_code", "sku_name", "lot_id", "lot_number", "expiration", "quantity"}).
+			AddRow(1, "SKU001", "產品 1", 8, "L1", "2025-03-31", 4))
+	mock.ExpectQuery(`UPDATE products SET expired_at = \$2, update_at = \$2, version = version \+ 1 WHERE deleted_at IS NULL AND expired_at IS NULL AND expiration < \$1 RETURNING`).
+		WithArgs(today, now).
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "sku_code", "sku_name", "expiration", "quantity"}).
+			AddRow(2, "SKU002", "產品 2", "2025-03-15", 10))
+	mock.ExpectCommit()
+
+	// 調用儲存庫方法
+	expired, err := repo.MarkExpired(context.Background(), today, now)
+
+	// 驗證結果
+	require.NoError(t, err)
+	assert.Equal(t, []models.ExpiredStock{
+		{ProductID: 1, SkuCode: "SKU001", SkuName: "產品 1", LotID: 8, LotNumber: "L1", Expiration: models.MustParseDate("2025-03-31"), Quantity: 4},
+		{ProductID: 2, SkuCode: "SKU002", SkuName: "產品 2", Expiration: models.MustParseDate("2025-03-15"), Quantity: 10},
+	}, expired)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試依倉庫與依批次查詢到期日範圍內的庫存
+func TestGetExpiring(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewExpiryRepository(db)
+
+	from := models.MustParseDate("2025-04-01")
+	to := models.MustParseDate("2025-05-01")
+
+	mock.ExpectQuery(`FROM products p JOIN product_stock s ON s.product_id = p.id JOIN stock_locations l ON l.id = s.location_id JOIN warehouses w ON w.id = l.warehouse_id WHERE p.deleted_at IS NULL AND p.expired_at IS NULL AND p.expiration BETWEEN \$1 AND \$2 AND s.quantity > 0 AND p.tenant_id = \$3 GROUP BY w.id, p.id`).
+		WithArgs(from, to, "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"warehouse_id", "warehouse_code", "warehouse_name", "product_id", "sku_code", "sku_name", "expiration", "quantity"}).
+			AddRow(1, "MAIN", "主倉庫", 3, "SKU003", "產品 3", "2025-04-10", 6))
+	mock.ExpectQuery(`FROM product_lots l JOIN products p ON p.id = l.product_id WHERE p.deleted_at IS NULL AND l.expired_at IS NULL AND l.quantity > 0 AND l.expiration BETWEEN \$1 AND \$2 AND p.tenant_id = \$3`).
+		WithArgs(from, to, "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"lot_id", "lot_number", "product_id", "sku_code", "sku_name", "expiration", "quantity"}))
+
+	// 調用儲存庫方法並驗證結果
+	ctx := tenant.WithID(context.Background(), "acme")
+	stocks, err := repo.GetExpiringByWarehouse(ctx, from, to)
+	require.NoError(t, err)
+	assert.Equal(t, []models.ExpiringStock{
+		{WarehouseID: 1, WarehouseCode: "MAIN", WarehouseName: "主倉庫", ProductID: 3, SkuCode: "SKU003", SkuName: "產品 3", Expiration: models.MustParseDate("2025-04-10"), Quantity: 6},
+	}, stocks)
+
+	stocks, err = repo.GetExpiringByLot(ctx, from, to)
+	require.NoError(t, err)
+	assert.Empty(t, stocks)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
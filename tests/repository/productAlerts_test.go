@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"main/internal/models"
+	"main/internal/repository"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// 測試低庫存報表只包含低於補貨點的產品，缺口最大的在前
+func TestGetLowStock(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewProductRepository(db)
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM products WHERE deleted_at IS NULL AND sku_code LIKE \$1 AND tenant_id = \$2 AND reorder_point > 0 AND sku_amount < reorder_point$`).
+		WithArgs("SKU%", "default").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectQuery(`SELECT \* FROM products WHERE deleted_at IS NULL AND sku_code LIKE \$1 AND tenant_id = \$2 AND reorder_point > 0 AND sku_amount < reorder_point ORDER BY reorder_point - sku_amount DESC, id LIMIT \$3 OFFSET \$4`).
+		WithArgs("SKU%", "default", 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "sku_code", "sku_amount", "reorder_point", "reorder_quantity"}).
+			AddRow(2, "SKU002", 0, 10, 40).
+			AddRow(1, "SKU001", 3, 5, 20))
+
+	// 調用儲存庫方法
+	products, total, err := repo.GetLowStock(context.Background(), models.ProductQuery{PageSize: 10, SkuCode: "SKU"})
+
+	// 驗證結果
+	assert.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Len(t, products, 2)
+	assert.Equal(t, 10, products[0].ReorderPoint)
+	assert.Equal(t, 40, products[0].ReorderQuantity)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
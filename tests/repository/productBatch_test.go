@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"main/internal/models"
+	"main/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 測試小批量以多行 INSERT 創建並保持輸入順序
+func TestCreateBatch(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewProductRepository(db)
+
+	inputs := []models.Product{
+		{SkuCode: "SKU001", SkuName: "產品 1", SkuAmount: 1, Expiration: models.MustParseDate("2025-01-01")},
+		{SkuCode: "SKU002", SkuName: "產品 2", SkuAmount: 2},
+	}
+
+	// 返回順序不保證，儲存庫需依 id 排序
+	rows := sqlmock.NewRows([]string{"id", "sku_code", "sku_name", "sku_amount", "expiration", "version"}).
+		AddRow(11, "SKU002", "產品 2", 2, nil, 1).
+		AddRow(10, "SKU001", "產品 1", 1, "2025-01-01", 1)
+
+	expectBegin(mock)
+	mock.ExpectQuery(`INSERT INTO products \(sku_code, sku_name, sku_amount, expiration, reorder_point, reorder_quantity\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6\), \(\$7, \$8, \$9, \$10, \$11, \$12\) RETURNING`).
+		WithArgs("SKU001", "產品 1", 1, "2025-01-01", 0, 0, "SKU002", "產品 2", 2, nil, 0, 0).
+		WillReturnRows(rows)
+	mock.ExpectCommit()
+
+	// 調用儲存庫方法
+	products, err := repo.CreateBatch(context.Background(), inputs)
+
+	// 驗證結果
+	assert.NoError(t, err)
+	require.Len(t, products, 2)
+	assert.Equal(t, "SKU001", products[0].SkuCode)
+	assert.Equal(t, "SKU002", products[1].SkuCode)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試大批量改用 COPY 寫入臨時表
+func TestCreateBatchCopy(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewProductRepository(db)
+
+	const count = 500
+	inputs := make([]models.Product, count)
+	rows := sqlmock.NewRows([]string{"id", "sku_code", "sku_name", "sku_amount", "expiration", "version"})
+	for i := range inputs {
+		inputs[i] = models.Product{SkuCode: fmt.Sprintf("SKU%04d", i), SkuName: "產品", SkuAmount: i}
+		rows.AddRow(i+1, inputs[i].SkuCode, "產品", i, nil, 1)
+	}
+
+	expectBegin(mock)
+	mock.ExpectExec(`CREATE TEMP TABLE products_batch ON COMMIT DROP`).WillReturnResult(sqlmock.NewResult(0, 0))
+	copyStmt := mock.ExpectPrepare(`COPY "products_batch" \("ord", "sku_code", "sku_name", "sku_amount", "expiration", "reorder_point", "reorder_quantity"\) FROM STDIN`)
+	for i, input := range inputs {
+		copyStmt.ExpectExec().
+			WithArgs(i, input.SkuCode, input.SkuName, input.SkuAmount, input.Expiration, input.ReorderPoint, input.ReorderQuantity).
+			WillReturnResult(driver.ResultNoRows)
+	}
+	copyStmt.ExpectExec().WithoutArgs().WillReturnResult(sqlmock.NewResult(0, count))
+	mock.ExpectQuery(`INSERT INTO products \(sku_code, sku_name, sku_amount, expiration, reorder_point, reorder_quantity\) SELECT .* FROM products_batch ORDER BY ord`).
+		WillReturnRows(rows)
+	mock.ExpectCommit()
+
+	// 調用儲存庫方法
+	products, err := repo.CreateBatch(context.Background(), inputs)
+
+	// 驗證結果
+	assert.NoError(t, err)
+	assert.Len(t, products, count)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試批量更新中任一項版本衝突時回滾整個交易
+func TestUpdateBatchRollback(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewProductRepository(db)
+
+	items := []models.ProductBatchUpdate{
+		{ID: 1, Version: 1, Patch: models.ProductPatch{SkuAmount: models.Nullable[int]{Set: true, Value: 5}}},
+		{ID: 2, Version: 3, Patch: models.ProductPatch{SkuAmount: models.Nullable[int]{Set: true, Value: 6}}},
+	}
+
+	expectBegin(mock)
+	mock.ExpectQuery(`UPDATE products SET sku_amount = \$1, update_at = \$2, version = version \+ 1 WHERE id = \$3 AND deleted_at IS NULL AND tenant_id = \$4 AND version = \$5`).
+		WithArgs(5, sqlmock.AnyArg(), int64(1), "default", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "update_at", "sku_code", "sku_name", "sku_amount", "expiration", "version"}).
+			AddRow(1, time.Now(), "SKU001", "產品 1", 5, nil, 2))
+	mock.ExpectQuery(`UPDATE products SET sku_amount = \$1, update_at = \$2, version = version \+ 1 WHERE id = \$3 AND deleted_at IS NULL AND tenant_id = \$4 AND version = \$5`).
+		WithArgs(6, sqlmock.AnyArg(), int64(2), "default", 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM products WHERE id = \$1 AND deleted_at IS NULL AND tenant_id = \$2\)`).
+		WithArgs(int64(2), "default").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	// 調用儲存庫方法
+	products, err := repo.UpdateBatch(context.Background(), items)
+
+	// 驗證結果
+	assert.Nil(t, products)
+	var itemErr *models.BatchItemError
+	require.ErrorAs(t, err, &itemErr)
+	assert.Equal(t, 1, itemErr.Index)
+	assert.ErrorIs(t, err, repository.ErrVersionConflict)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試批量刪除在單一交易中執行
+func TestDeleteBatch(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewProductRepository(db)
+
+	expectBegin(mock)
+	mock.ExpectExec(`UPDATE products SET deleted_at = \$2, version = version \+ 1 WHERE id = \$1 AND deleted_at IS NULL AND tenant_id = \$3 AND version = \$4`).
+		WithArgs(int64(1), sqlmock.AnyArg(), "default", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE products SET deleted_at = \$2, version = version \+ 1 WHERE id = \$1 AND deleted_at IS NULL AND tenant_id = \$3 AND version = \$4`).
+		WithArgs(int64(2), sqlmock.AnyArg(), "default", 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// 調用儲存庫方法
+	err := repo.DeleteBatch(context.Background(), []models.ProductRef{{ID: 1, Version: 1}, {ID: 2, Version: 4}})
+
+	// 驗證結果
+	assert.NoError(t, err)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
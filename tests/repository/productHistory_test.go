@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"main/internal/audit"
+	"main/internal/models"
+	"main/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var historyColumns = []string{"id", "product_id", "operation", "version", "before", "after", "actor", "request_id", "changed_at"}
+
+// 測試列出變更歷史並解析快照
+func TestGetHistory(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewProductRepository(db)
+
+	changedAt := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM product_history WHERE product_id = \$1 AND tenant_id = \$2`).
+		WithArgs(int64(1), "default").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectQuery(`SELECT \* FROM product_history WHERE product_id = \$1 AND tenant_id = \$4 ORDER BY changed_at DESC, id DESC LIMIT \$2 OFFSET \$3`).
+		WithArgs(int64(1), 10, 0, "default").
+		WillReturnRows(sqlmock.NewRows(historyColumns).
+			AddRow(2, 1, models.HistoryUpdate, 2,
+				[]byte(`{"id": 1, "sku_code": "SKU001", "sku_amount": 10, "version": 1}`),
+				[]byte(`{"id": 1, "sku_code": "SKU001", "sku_amount": 5, "expiration": "2025-06-30", "update_at": "2025-03-01T10:00:00.123456+00:00", "deleted_at": null, "version": 2}`),
+				"alice", "req-2", changedAt).
+			AddRow(1, 1, models.HistoryCreate, 1, nil,
+				[]byte(`{"id": 1, "sku_code": "SKU001", "sku_amount": 10, "version": 1}`),
+				"", "", changedAt.Add(-time.Hour)))
+
+	// 調用儲存庫方法
+	history, total, err := repo.GetHistory(context.Background(), 1, models.ProductQuery{PageSize: 10})
+
+	// 驗證結果
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	require.Len(t, history, 2)
+	assert.Equal(t, models.HistoryUpdate, history[0].Operation)
+	assert.Equal(t, "alice", history[0].Actor)
+	assert.Equal(t, 10, history[0].Before.SkuAmount)
+	assert.Equal(t, 5, history[0].After.SkuAmount)
+	assert.Equal(t, "2025-06-30", history[0].After.Expiration.String())
+	assert.Equal(t, 123456000, history[0].After.UpdateAt.Nanosecond())
+	assert.Nil(t, history[1].Before)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試以變更歷史重建某個時間點的產品
+func TestGetAsOf(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewProductRepository(db)
+
+	asOf := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	query := `SELECT \* FROM product_history WHERE product_id = \$1 AND changed_at <= \$2 AND tenant_id = \$3 ORDER BY changed_at DESC, id DESC LIMIT 1`
+
+	mock.ExpectQuery(query).
+		WithArgs(int64(1), asOf, "default").
+		WillReturnRows(sqlmock.NewRows(historyColumns).
+			AddRow(2, 1, models.HistoryUpdate, 2, nil, []byte(`{"id": 1, "sku_code": "SKU001", "sku_amount": 5, "version": 2}`), "", "", asOf))
+	// 當時已軟刪除
+	mock.ExpectQuery(query).
+		WithArgs(int64(2), asOf, "default").
+		WillReturnRows(sqlmock.NewRows(historyColumns).
+			AddRow(5, 2, models.HistoryDelete, 3, nil, []byte(`{"id": 2, "deleted_at": "2025-03-01T11:00:00+00:00", "version": 3}`), "", "", asOf))
+	// 當時尚未創建
+	mock.ExpectQuery(query).
+		WithArgs(int64(3), asOf, "default").
+		WillReturnRows(sqlmock.NewRows(historyColumns))
+
+	// 調用儲存庫方法
+	product, err := repo.GetAsOf(context.Background(), 1, asOf)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, product.SkuAmount)
+	assert.Equal(t, 2, product.Version)
+
+	_, err = repo.GetAsOf(context.Background(), 2, asOf)
+	assert.ErrorIs(t, err, repository.ErrProductNotFound)
+
+	_, err = repo.GetAsOf(context.Background(), 3, asOf)
+	assert.ErrorIs(t, err, repository.ErrProductNotFound)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試寫入時將 context 中的操作者與請求 ID 設置到交易內
+func TestWriteSetsAuditContext(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewProductRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT set_config\('app.actor', \$1, true\), set_config\('app.request_id', \$2, true\), set_config\('app.tenant_id', \$3, true\)`).
+		WithArgs("alice", "req-1", "default").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE products SET deleted_at = \$2`).
+		WithArgs(1, sqlmock.AnyArg(), "default").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	ctx := audit.WithRequestID(audit.WithActor(context.Background(), "alice"), "req-1")
+
+	// 調用儲存庫方法
+	assert.NoError(t, repo.Delete(ctx, 1, 0))
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"main/internal/models"
+	"main/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 測試逐筆讀取符合條件的產品，不帶分頁
+func TestStream(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewProductRepository(db)
+
+	rows := sqlmock.NewRows([]string{"id", "sku_code", "sku_name", "sku_amount", "expiration", "version"}).
+		AddRow(2, "SKU002", "產品 2", 20, nil, 1).
+		AddRow(1, "SKU001", "產品 1", 10, nil, 1)
+
+	mock.ExpectQuery(`SELECT \* FROM products WHERE deleted_at IS NULL AND sku_code LIKE \$1 AND tenant_id = \$2 ORDER BY sku_code DESC, id DESC$`).
+		WithArgs("SKU%", "default").
+		WillReturnRows(rows)
+
+	// 調用儲存庫方法
+	var codes []string
+	err := repo.Stream(context.Background(), models.ProductQuery{SkuCode: "SKU", SortBy: "sku_code", SortDir: models.SortDesc, PageSize: 1},
+		func(p models.Product) error {
+			codes = append(codes, p.SkuCode)
+			return nil
+		})
+
+	// 驗證結果
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"SKU002", "SKU001"}, codes)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試 sku_code 不存在時插入，存在時更新，以 xmax 判斷是否為新建
+func TestUpsertBySku(t *testing.T) {
+	for _, inserted := range []bool{true, false} {
+		// 設置模擬數據庫
+		db, mock := setupMockDB(t)
+
+		// 創建儲存庫
+		repo := repository.NewProductRepository(db)
+
+		input := models.Product{SkuCode: "SKU001", SkuName: "新名稱", SkuAmount: 5}
+		rows := sqlmock.NewRows([]string{"id", "create_at", "update_at", "sku_code", "sku_name", "sku_amount", "expiration", "version", "inserted"}).
+			AddRow(1, time.Now().Format(time.RFC3339), time.Now().Format(time.RFC3339), "SKU001", "新名稱", 5, nil, 2, inserted)
+
+		expectBegin(mock)
+		mock.ExpectQuery(`INSERT INTO products \(sku_code, sku_name, sku_amount, expiration, reorder_point, reorder_quantity\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6\) ON CONFLICT \(tenant_id, sku_code\) WHERE deleted_at IS NULL DO UPDATE`).
+			WithArgs("SKU001", "新名稱", 5, nil, 0, 0, sqlmock.AnyArg()).
+			WillReturnRows(rows)
+		mock.ExpectCommit()
+
+		// 調用儲存庫方法
+		product, created, err := repo.UpsertBySku(context.Background(), input)
+
+		// 驗證結果
+		assert.NoError(t, err)
+		assert.Equal(t, inserted, created)
+		assert.Equal(t, 2, product.Version)
+
+		// 確保所有預期都被滿足
+		assert.NoError(t, mock.ExpectationsWereMet())
+		db.Close()
+	}
+}
+
+// 測試內容未改變時不寫入，返回現有的產品
+func TestUpsertBySkuUnchanged(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewProductRepository(db)
+
+	input := models.Product{SkuCode: "SKU001", SkuName: "產品 1", SkuAmount: 5}
+
+	expectBegin(mock)
+	mock.ExpectQuery(`ON CONFLICT \(tenant_id, sku_code\) WHERE deleted_at IS NULL DO UPDATE`).
+		WithArgs("SKU001", "產品 1", 5, nil, 0, 0, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "version", "inserted"}))
+	mock.ExpectQuery(`SELECT \* FROM products WHERE sku_code = \$1 AND deleted_at IS NULL AND tenant_id = \$2`).
+		WithArgs("SKU001", "default").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "sku_code", "sku_name", "sku_amount", "expiration", "version"}).
+			AddRow(1, "SKU001", "產品 1", 5, nil, 3))
+	mock.ExpectCommit()
+
+	// 調用儲存庫方法
+	product, created, err := repo.UpsertBySku(context.Background(), input)
+
+	// 驗證結果
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, 3, product.Version)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試查詢已存在的 sku_code
+func TestExistingSkuCodes(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewProductRepository(db)
+
+	mock.ExpectQuery(`SELECT sku_code FROM products WHERE sku_code = ANY\(\$1\) AND deleted_at IS NULL AND tenant_id = \$2`).
+		WithArgs(pq.Array([]string{"SKU001", "SKU002"}), "default").
+		WillReturnRows(sqlmock.NewRows([]string{"sku_code"}).AddRow("SKU002"))
+
+	// 調用儲存庫方法
+	existing, err := repo.ExistingSkuCodes(context.Background(), []string{"SKU001", "SKU002"})
+
+	// 驗證結果
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"SKU002": true}, existing)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
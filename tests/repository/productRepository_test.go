@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"main/internal/models"
 	"main/internal/repository"
 	"testing"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -24,6 +26,13 @@ func setupMockDB(t *testing.T) (*sqlx.DB, sqlmock.Sqlmock) {
 	return db, mock
 }
 
+// expectBegin 預期開始交易，並在交易內設置供變更歷史使用的操作者與請求 ID
+func expectBegin(mock sqlmock.Sqlmock) {
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT set_config\('app.actor', \$1, true\), set_config\('app.request_id', \$2, true\)`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
 // 測試獲取所有產品
 func TestGetAll(t *testing.T) {
 	// 設置模擬數據庫
@@ -39,13 +48,19 @@ func TestGetAll(t *testing.T) {
 		AddRow(2, "SKU002", "產品 2", 20, "2024-12-31", time.Now().Format("2006-01-02 15:04:05"), time.Now().Format("2006-01-02 15:04:05"))
 
 	// 設置 SQL 查詢預期
-	mock.ExpectQuery("SELECT (.+) FROM products").WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM products WHERE deleted_at IS NULL AND tenant_id = \$1$`).
+		WithArgs("default").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectQuery(`SELECT \* FROM products WHERE deleted_at IS NULL AND tenant_id = \$1 ORDER BY id ASC LIMIT \$2 OFFSET \$3`).
+		WithArgs("default", 20, 0).
+		WillReturnRows(rows)
 
 	// 調用儲存庫方法
-	products, err := repo.GetAll()
+	products, total, err := repo.GetAll(context.Background(), models.ProductQuery{PageSize: 20, SortBy: "id", SortDir: models.SortAsc})
 
 	// 驗證結果
 	assert.NoError(t, err)
+	assert.Equal(t, 2, total)
 	assert.Len(t, products, 2)
 	assert.Equal(t, "SKU001", products[0].SkuCode)
 	assert.Equal(t, "SKU002", products[1].SkuCode)
@@ -54,6 +69,127 @@ func TestGetAll(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+// 測試帶過濾與排序條件的產品列表
+func TestGetAllWithFilters(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewProductRepository(db)
+
+	minAmount, maxAmount := 5, 50
+	query := models.ProductQuery{
+		PageSize:     10,
+		Offset:       10,
+		SortBy:       "expiration",
+		SortDir:      models.SortDesc,
+		SkuCode:      "SKU_1",
+		SkuName:      "牛奶",
+		MinAmount:    &minAmount,
+		MaxAmount:    &maxAmount,
+		ExpirationTo: models.MustParseDate("2025-12-31"),
+	}
+
+	where := `WHERE deleted_at IS NULL AND sku_code LIKE \$1 AND sku_name ILIKE \$2 AND sku_amount >= \$3 AND sku_amount <= \$4 AND expiration <= \$5 AND tenant_id = \$6`
+
+	// 設置 SQL 查詢預期 - 過濾值必須以參數綁定，LIKE 特殊字元需轉義
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM products `+where).
+		WithArgs(`SKU\_1%`, "%牛奶%", 5, 50, "2025-12-31", "default").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(11))
+	mock.ExpectQuery(`SELECT \* FROM products `+where+` ORDER BY expiration DESC, id DESC LIMIT \$7 OFFSET \$8`).
+		WithArgs(`SKU\_1%`, "%牛奶%", 5, 50, "2025-12-31", "default", 10, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "sku_code", "sku_name", "sku_amount", "expiration", "create_at", "update_at"}).
+			AddRow(11, "SKU_1", "鮮牛奶", 20, "2025-06-30", time.Now(), time.Now()))
+
+	// 調用儲存庫方法
+	products, total, err := repo.GetAll(context.Background(), query)
+
+	// 驗證結果
+	assert.NoError(t, err)
+	assert.Equal(t, 11, total)
+	assert.Len(t, products, 1)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試鍵集分頁查詢
+func TestGetAllWithKeyset(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewProductRepository(db)
+
+	query := models.ProductQuery{
+		PageSize: 3,
+		SortBy:   "update_at",
+		SortDir:  models.SortDesc,
+		SkuName:  "牛奶",
+		Keyset:   &models.ProductKeyset{Value: "2025-03-01T10:00:00Z", ID: 7},
+	}
+
+	// 設置 SQL 查詢預期 - 總數不受游標影響，列表以 (update_at, id) 比較且不使用 OFFSET
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM products WHERE deleted_at IS NULL AND sku_name ILIKE \$1 AND tenant_id = \$2$`).
+		WithArgs("%牛奶%", "default").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+	mock.ExpectQuery(`SELECT \* FROM products WHERE deleted_at IS NULL AND sku_name ILIKE \$1 AND tenant_id = \$2 AND \(update_at, id\) < \(\$3, \$4\) ORDER BY update_at DESC, id DESC LIMIT \$5$`).
+		WithArgs("%牛奶%", "default", "2025-03-01T10:00:00Z", 7, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "sku_code", "sku_name", "sku_amount", "expiration", "create_at", "update_at"}).
+			AddRow(5, "SKU005", "鮮牛奶", 20, "2025-06-30", time.Now(), time.Now()))
+
+	// 調用儲存庫方法
+	products, total, err := repo.GetAll(context.Background(), query)
+
+	// 驗證結果
+	assert.NoError(t, err)
+	assert.Equal(t, 4, total)
+	assert.Len(t, products, 1)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試鍵集分頁往前翻頁 - 反向查詢後恢復原排序
+func TestGetAllWithKeysetBackward(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewProductRepository(db)
+
+	query := models.ProductQuery{
+		PageSize: 2,
+		SortBy:   "id",
+		SortDir:  models.SortAsc,
+		Keyset:   &models.ProductKeyset{ID: 5, Backward: true},
+	}
+
+	// 設置 SQL 查詢預期
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM products WHERE deleted_at IS NULL AND tenant_id = \$1$`).
+		WithArgs("default").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(9))
+	mock.ExpectQuery(`SELECT \* FROM products WHERE deleted_at IS NULL AND tenant_id = \$1 AND id < \$2 ORDER BY id DESC LIMIT \$3$`).
+		WithArgs("default", 5, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "sku_code", "sku_name", "sku_amount", "expiration", "create_at", "update_at"}).
+			AddRow(4, "SKU004", "產品 4", 10, "2025-06-30", time.Now(), time.Now()).
+			AddRow(3, "SKU003", "產品 3", 10, "2025-06-30", time.Now(), time.Now()))
+
+	// 調用儲存庫方法
+	products, _, err := repo.GetAll(context.Background(), query)
+
+	// 驗證結果
+	assert.NoError(t, err)
+	assert.Equal(t, "SKU003", products[0].SkuCode)
+	assert.Equal(t, "SKU004", products[1].SkuCode)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 // 測試獲取單個產品
 func TestGetByID(t *testing.T) {
 	// 設置模擬數據庫
@@ -68,12 +204,12 @@ func TestGetByID(t *testing.T) {
 		AddRow(1, "SKU001", "產品 1", 10, "2023-12-31", time.Now().Format("2006-01-02 15:04:05"), time.Now().Format("2006-01-02 15:04:05"))
 
 	// 設置 SQL 查詢預期
-	mock.ExpectQuery("SELECT (.+) FROM products WHERE id = \\$1").
-		WithArgs(1).
+	mock.ExpectQuery("SELECT (.+) FROM products WHERE id = \\$1 AND deleted_at IS NULL AND tenant_id = \\$2").
+		WithArgs(1, "default").
 		WillReturnRows(row)
 
 	// 調用儲存庫方法
-	product, err := repo.GetByID(1)
+	product, err := repo.GetByID(context.Background(), 1)
 
 	// 驗證結果
 	assert.NoError(t, err)
@@ -95,12 +231,12 @@ func TestGetByIDNotFound(t *testing.T) {
 	repo := repository.NewProductRepository(db)
 
 	// 設置 SQL 查詢預期 - 返回空結果
-	mock.ExpectQuery("SELECT (.+) FROM products WHERE id = \\$1").
-		WithArgs(999).
+	mock.ExpectQuery("SELECT (.+) FROM products WHERE id = \\$1 AND deleted_at IS NULL AND tenant_id = \\$2").
+		WithArgs(999, "default").
 		WillReturnRows(sqlmock.NewRows([]string{"id", "sku_code", "sku_name", "sku_amount", "expiration", "create_at", "update_at"}))
 
 	// 調用儲存庫方法
-	_, err := repo.GetByID(999)
+	_, err := repo.GetByID(context.Background(), 999)
 
 	// 驗證結果
 	assert.Error(t, err)
@@ -124,33 +260,94 @@ func TestCreate(t *testing.T) {
 		SkuCode:    "SKU003",
 		SkuName:    "新產品",
 		SkuAmount:  15,
-		Expiration: "2025-01-01",
+		Expiration: models.MustParseDate("2025-01-01"),
+
+		ReorderPoint:    5,
+		ReorderQuantity: 30,
 	}
 
 	// 模擬數據庫返回的行
-	rows := sqlmock.NewRows([]string{"id", "sku_code", "sku_name", "sku_amount", "expiration"}).
-		AddRow(3, "SKU003", "新產品", 15, "2025-01-01")
+	rows := sqlmock.NewRows([]string{"id", "sku_code", "sku_name", "sku_amount", "expiration", "version", "reorder_point", "reorder_quantity"}).
+		AddRow(3, "SKU003", "新產品", 15, "2025-01-01", 1, 5, 30)
 
 	// 設置 SQL 插入預期
+	expectBegin(mock)
 	mock.ExpectQuery("INSERT INTO products").
-		WithArgs(productInput.SkuCode, productInput.SkuName, productInput.SkuAmount, productInput.Expiration).
+		WithArgs(productInput.SkuCode, productInput.SkuName, productInput.SkuAmount, productInput.Expiration, productInput.ReorderPoint, productInput.ReorderQuantity).
 		WillReturnRows(rows)
+	mock.ExpectCommit()
 
 	// 調用儲存庫方法
-	product, err := repo.Create(productInput)
+	product, err := repo.Create(context.Background(), productInput)
 
 	// 驗證結果
 	assert.NoError(t, err)
 	assert.Equal(t, "SKU003", product.SkuCode)
 	assert.Equal(t, "新產品", product.SkuName)
 	assert.Equal(t, 15, product.SkuAmount)
+	assert.Equal(t, 1, product.Version)
+	assert.Equal(t, 5, product.ReorderPoint)
+	assert.Equal(t, 30, product.ReorderQuantity)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試 sku_code 已存在時返回 ErrDuplicateSku
+func TestCreateDuplicateSku(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewProductRepository(db)
+
+	// 設置 SQL 插入預期 - 違反唯一約束
+	expectBegin(mock)
+	mock.ExpectQuery("INSERT INTO products").
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "products_sku_code_key"})
+	mock.ExpectRollback()
+
+	// 調用儲存庫方法
+	_, err := repo.Create(context.Background(), models.Product{SkuCode: "SKU001"})
+
+	// 驗證結果
+	assert.ErrorIs(t, err, repository.ErrDuplicateSku)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試依 sku_code 獲取產品
+func TestGetBySku(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewProductRepository(db)
+
+	mock.ExpectQuery("SELECT (.+) FROM products WHERE sku_code = \\$1 AND deleted_at IS NULL AND tenant_id = \\$2").
+		WithArgs("SKU001", "default").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "sku_code", "version"}).AddRow(1, "SKU001", 2))
+	mock.ExpectQuery("SELECT (.+) FROM products WHERE sku_code = \\$1 AND deleted_at IS NULL AND tenant_id = \\$2").
+		WithArgs("NONE", "default").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "sku_code", "version"}))
+
+	// 調用儲存庫方法
+	product, err := repo.GetBySku(context.Background(), "SKU001")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, product.ID)
+
+	_, err = repo.GetBySku(context.Background(), "NONE")
+	assert.Equal(t, repository.ErrProductNotFound, err)
 
 	// 確保所有預期都被滿足
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 // 測試更新產品
-func TestUpdateNonBlank(t *testing.T) {
+func TestUpdate(t *testing.T) {
 	// 設置模擬數據庫
 	db, mock := setupMockDB(t)
 	defer db.Close()
@@ -163,26 +360,231 @@ func TestUpdateNonBlank(t *testing.T) {
 		SkuCode:    "SKU001",
 		SkuName:    "更新產品名稱",
 		SkuAmount:  25,
-		Expiration: "2024-06-30",
+		Expiration: models.MustParseDate("2024-06-30"),
 	}
 
 	// 模擬數據庫返回的行
-	rows := sqlmock.NewRows([]string{"id", "update_at", "sku_code", "sku_name", "sku_amount", "expiration"}).
-		AddRow(1, time.Now(), "SKU001", "更新產品名稱", 25, "2024-06-30")
+	rows := sqlmock.NewRows([]string{"id", "update_at", "sku_code", "sku_name", "sku_amount", "expiration", "version"}).
+		AddRow(1, time.Now(), "SKU001", "更新產品名稱", 25, "2024-06-30", 3)
 
 	// 設置 SQL 更新預期 - 使用更精確的匹配
-	mock.ExpectQuery(`UPDATE products SET sku_code = \$1, sku_name = \$2, expiration = \$3, sku_amount = \$4, update_at = \$5 WHERE id = \$6 RETURNING id, update_at, sku_code, sku_name, sku_amount, expiration`).
-		WithArgs(productInput.SkuCode, productInput.SkuName, productInput.Expiration, productInput.SkuAmount, sqlmock.AnyArg(), int64(1)).
+	expectBegin(mock)
+	mock.ExpectQuery(`UPDATE products SET sku_code = \$1, sku_name = \$2, expiration = \$3, expired_at = CASE WHEN expiration IS DISTINCT FROM \$3 THEN NULL ELSE expired_at END, sku_amount = \$4, reorder_point = \$5, reorder_quantity = \$6, update_at = \$7, version = version \+ 1 WHERE id = \$8 AND deleted_at IS NULL AND tenant_id = \$9 AND version = \$10 RETURNING id, create_at, update_at, sku_code, sku_name, sku_amount, expiration, version, reorder_point, reorder_quantity, expired_at, tenant_id`).
+		WithArgs(productInput.SkuCode, productInput.SkuName, productInput.Expiration, productInput.SkuAmount, 0, 0, sqlmock.AnyArg(), int64(1), "default", 2).
 		WillReturnRows(rows)
+	mock.ExpectCommit()
 
 	// 調用儲存庫方法
-	product, err := repo.UpdateNonBlank(1, productInput)
+	product, err := repo.Update(context.Background(), 1, 2, models.ReplacePatch(productInput))
 
 	// 驗證結果
 	assert.NoError(t, err)
 	assert.Equal(t, "SKU001", product.SkuCode)
 	assert.Equal(t, "更新產品名稱", product.SkuName)
 	assert.Equal(t, 25, product.SkuAmount)
+	assert.Equal(t, 3, product.Version)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試部分更新只寫入出現的欄位
+func TestUpdatePartial(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewProductRepository(db)
+
+	// 只更新名稱並清除到期日，不應觸及庫存
+	patch, err := models.DecodeMergePatch([]byte(`{"sku_name": "新名稱", "expiration": null}`))
+	require.NoError(t, err)
+
+	rows := sqlmock.NewRows([]string{"id", "update_at", "sku_code", "sku_name", "sku_amount", "expiration", "version"}).
+		AddRow(1, time.Now(), "SKU001", "新名稱", 10, nil, 2)
+
+	expectBegin(mock)
+	mock.ExpectQuery(`UPDATE products SET sku_name = \$1, expiration = \$2, expired_at = CASE WHEN expiration IS DISTINCT FROM \$2 THEN NULL ELSE expired_at END, update_at = \$3, version = version \+ 1 WHERE id = \$4 AND deleted_at IS NULL AND tenant_id = \$5 RETURNING`).
+		WithArgs("新名稱", nil, sqlmock.AnyArg(), int64(1), "default").
+		WillReturnRows(rows)
+	mock.ExpectCommit()
+
+	// 調用儲存庫方法
+	product, err := repo.Update(context.Background(), 1, 0, patch)
+
+	// 驗證結果
+	assert.NoError(t, err)
+	assert.Equal(t, "新名稱", product.SkuName)
+	assert.Equal(t, 10, product.SkuAmount)
+	assert.Empty(t, product.Expiration)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試以過期版本更新產品
+func TestUpdateVersionConflict(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewProductRepository(db)
+
+	// 版本不符時更新不影響任何行，但產品仍存在
+	expectBegin(mock)
+	mock.ExpectQuery(`UPDATE products SET .* WHERE id = \$3 AND deleted_at IS NULL AND tenant_id = \$4 AND version = \$5`).
+		WithArgs(25, sqlmock.AnyArg(), int64(1), "default", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM products WHERE id = \$1 AND deleted_at IS NULL AND tenant_id = \$2\)`).
+		WithArgs(int64(1), "default").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	// 調用儲存庫方法
+	patch := models.ProductPatch{SkuAmount: models.Nullable[int]{Set: true, Value: 25}}
+	_, err := repo.Update(context.Background(), 1, 2, patch)
+
+	// 驗證結果
+	assert.Equal(t, repository.ErrVersionConflict, err)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試直接減少庫存而預設儲位的庫存不足時返回 ErrInsufficientStock
+func TestUpdateInsufficientLocationStock(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewProductRepository(db)
+
+	// 觸發器扣減預設儲位時違反檢查約束
+	expectBegin(mock)
+	mock.ExpectQuery(`UPDATE products SET .* WHERE id = \$3 AND deleted_at IS NULL`).
+		WillReturnError(&pq.Error{Code: "23514", Constraint: "product_stock_quantity_nonnegative"})
+	mock.ExpectRollback()
+
+	// 調用儲存庫方法
+	patch := models.ProductPatch{SkuAmount: models.Nullable[int]{Set: true, Value: 5}}
+	_, err := repo.Update(context.Background(), 1, 0, patch)
+
+	// 驗證結果
+	assert.ErrorIs(t, err, repository.ErrInsufficientStock)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// adjustUnreserved 減少庫存時的條件更新：扣減後仍須足以支付未到期的預留與已標記過期的庫存
+const adjustUnreserved = `UPDATE products SET sku_amount = sku_amount \+ \$2, update_at = \$3, version = version \+ 1 WHERE id = \$1 AND deleted_at IS NULL AND tenant_id = \$4 AND sku_amount \+ \$2 >= \( SELECT COALESCE\(SUM\(quantity\), 0\) FROM stock_reservations WHERE product_id = \$1 AND status = 'active' AND expires_at > \$3 \) \+ CASE WHEN true THEN CASE WHEN products.expired_at IS NOT NULL THEN products.sku_amount ELSE .* END ELSE 0 END RETURNING`
+
+// expectAdjustLock 預期減少庫存前鎖定產品
+func expectAdjustLock(mock sqlmock.Sqlmock, id int64) {
+	mock.ExpectExec(`SELECT 1 FROM products WHERE id = \$1 AND tenant_id = \$2 FOR UPDATE`).
+		WithArgs(id, "default").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+// 測試原子調整庫存：成功、結果為負數、產品不存在與預設儲位不足
+func TestAdjustStock(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewProductRepository(db)
+
+	now := time.Now()
+	increase := `UPDATE products SET sku_amount = sku_amount \+ \$2, update_at = \$3, version = version \+ 1 WHERE id = \$1 AND deleted_at IS NULL AND tenant_id = \$4 AND sku_amount \+ \$2 >= 0 RETURNING`
+	exists := `SELECT EXISTS\(SELECT 1 FROM products WHERE id = \$1 AND deleted_at IS NULL AND tenant_id = \$2\)`
+
+	expectBegin(mock)
+	expectAdjustLock(mock, 1)
+	mock.ExpectQuery(adjustUnreserved).
+		WithArgs(int64(1), -3, sqlmock.AnyArg(), "default").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "create_at", "update_at", "sku_code", "sku_name", "sku_amount", "expiration", "version"}).
+			AddRow(1, now, now, "SKU001", "產品1", 7, nil, 4))
+	mock.ExpectCommit()
+
+	expectBegin(mock)
+	expectAdjustLock(mock, 1)
+	mock.ExpectQuery(adjustUnreserved).WithArgs(int64(1), -100, sqlmock.AnyArg(), "default").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(exists).WithArgs(int64(1), "default").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	// 增加庫存不受預留限制
+	expectBegin(mock)
+	mock.ExpectQuery(increase).WithArgs(int64(999), 1, sqlmock.AnyArg(), "default").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(exists).WithArgs(int64(999), "default").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectRollback()
+
+	// 觸發器扣減預設儲位時違反檢查約束
+	expectBegin(mock)
+	expectAdjustLock(mock, 2)
+	mock.ExpectQuery(adjustUnreserved).
+		WithArgs(int64(2), -5, sqlmock.AnyArg(), "default").
+		WillReturnError(&pq.Error{Code: "23514", Constraint: "product_stock_quantity_nonnegative"})
+	mock.ExpectRollback()
+
+	// 調用儲存庫方法並驗證結果
+	product, err := repo.AdjustStock(context.Background(), 1, -3)
+	require.NoError(t, err)
+	assert.Equal(t, 7, product.SkuAmount)
+	assert.Equal(t, 4, product.Version)
+
+	_, err = repo.AdjustStock(context.Background(), 1, -100)
+	assert.ErrorIs(t, err, repository.ErrInsufficientStock)
+
+	_, err = repo.AdjustStock(context.Background(), 999, 1)
+	assert.ErrorIs(t, err, repository.ErrProductNotFound)
+
+	_, err = repo.AdjustStock(context.Background(), 2, -5)
+	assert.ErrorIs(t, err, repository.ErrInsufficientStock)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試產品有未到期的預留時，調整不能扣減被預留的庫存：庫存 10、預留 8，減少 5 時條件更新不影響任何行
+func TestAdjustStockWithActiveReservation(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewProductRepository(db)
+
+	now := time.Now()
+	expectBegin(mock)
+	expectAdjustLock(mock, 1)
+	mock.ExpectQuery(adjustUnreserved).
+		WithArgs(int64(1), -5, sqlmock.AnyArg(), "default").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM products WHERE id = \$1 AND deleted_at IS NULL AND tenant_id = \$2\)`).
+		WithArgs(int64(1), "default").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	// 減少 2 後仍足以支付預留
+	expectBegin(mock)
+	expectAdjustLock(mock, 1)
+	mock.ExpectQuery(adjustUnreserved).
+		WithArgs(int64(1), -2, sqlmock.AnyArg(), "default").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "create_at", "update_at", "sku_code", "sku_name", "sku_amount", "expiration", "version"}).
+			AddRow(1, now, now, "SKU001", "產品1", 8, nil, 2))
+	mock.ExpectCommit()
+
+	// 調用儲存庫方法並驗證結果
+	_, err := repo.AdjustStock(context.Background(), 1, -5)
+	assert.ErrorIs(t, err, repository.ErrInsufficientStock)
+
+	product, err := repo.AdjustStock(context.Background(), 1, -2)
+	require.NoError(t, err)
+	assert.Equal(t, 8, product.SkuAmount)
 
 	// 確保所有預期都被滿足
 	assert.NoError(t, mock.ExpectationsWereMet())
@@ -198,12 +600,14 @@ func TestDelete(t *testing.T) {
 	repo := repository.NewProductRepository(db)
 
 	// 設置 SQL 刪除預期
-	mock.ExpectExec("DELETE FROM products WHERE id = \\$1").
-		WithArgs(1).
+	expectBegin(mock)
+	mock.ExpectExec(`UPDATE products SET deleted_at = \$2, version = version \+ 1 WHERE id = \$1 AND deleted_at IS NULL AND tenant_id = \$3$`).
+		WithArgs(1, sqlmock.AnyArg(), "default").
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	// 調用儲存庫方法
-	err := repo.Delete(1)
+	err := repo.Delete(context.Background(), 1, 0)
 
 	// 驗證結果
 	assert.NoError(t, err)
@@ -222,12 +626,17 @@ func TestDeleteNotFound(t *testing.T) {
 	repo := repository.NewProductRepository(db)
 
 	// 設置 SQL 刪除預期 - 返回沒有影響的行
-	mock.ExpectExec("DELETE FROM products WHERE id = \\$1").
-		WithArgs(999).
+	expectBegin(mock)
+	mock.ExpectExec(`UPDATE products SET deleted_at = \$2`).
+		WithArgs(999, sqlmock.AnyArg(), "default").
 		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM products WHERE id = \$1 AND deleted_at IS NULL AND tenant_id = \$2\)`).
+		WithArgs(999, "default").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectRollback()
 
 	// 調用儲存庫方法
-	err := repo.Delete(999)
+	err := repo.Delete(context.Background(), 999, 0)
 
 	// 驗證結果
 	assert.Error(t, err)
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"main/internal/models"
+	"main/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+// 測試列出回收站只包含軟刪除的產品，依刪除時間排序
+func TestGetDeleted(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewProductRepository(db)
+
+	deletedAt := time.Now()
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM products WHERE deleted_at IS NOT NULL AND sku_code LIKE \$1 AND tenant_id = \$2$`).
+		WithArgs("SKU%", "default").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(`SELECT \* FROM products WHERE deleted_at IS NOT NULL AND sku_code LIKE \$1 AND tenant_id = \$2 ORDER BY deleted_at DESC, id DESC LIMIT \$3 OFFSET \$4`).
+		WithArgs("SKU%", "default", 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "sku_code", "deleted_at", "version"}).
+			AddRow(3, "SKU003", deletedAt, 2))
+
+	// 調用儲存庫方法
+	products, total, err := repo.GetDeleted(context.Background(), models.ProductQuery{PageSize: 10, SkuCode: "SKU"})
+
+	// 驗證結果
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Len(t, products, 1)
+	assert.False(t, products[0].DeletedAt.IsZero())
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試還原回收站中的產品
+func TestRestore(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewProductRepository(db)
+
+	expectBegin(mock)
+	mock.ExpectQuery(`UPDATE products SET deleted_at = NULL, update_at = \$2, version = version \+ 1 WHERE id = \$1 AND deleted_at IS NOT NULL AND tenant_id = \$3 RETURNING`).
+		WithArgs(1, sqlmock.AnyArg(), "default").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "sku_code", "version"}).AddRow(1, "SKU001", 3))
+	mock.ExpectCommit()
+	expectBegin(mock)
+	mock.ExpectQuery(`UPDATE products SET deleted_at = NULL`).
+		WithArgs(2, sqlmock.AnyArg(), "default").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+	expectBegin(mock)
+	mock.ExpectQuery(`UPDATE products SET deleted_at = NULL`).
+		WithArgs(3, sqlmock.AnyArg(), "default").
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "products_sku_code_key"})
+	mock.ExpectRollback()
+
+	// 調用儲存庫方法
+	product, err := repo.Restore(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, product.Version)
+
+	// 不在回收站中
+	_, err = repo.Restore(context.Background(), 2)
+	assert.ErrorIs(t, err, repository.ErrProductNotFound)
+
+	// sku_code 已被新產品使用
+	_, err = repo.Restore(context.Background(), 3)
+	assert.ErrorIs(t, err, repository.ErrDuplicateSku)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試只能永久刪除回收站中的產品
+func TestPurge(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewProductRepository(db)
+
+	expectBegin(mock)
+	mock.ExpectExec(`DELETE FROM products WHERE id = \$1 AND deleted_at IS NOT NULL AND tenant_id = \$2`).
+		WithArgs(1, "default").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectBegin(mock)
+	mock.ExpectExec(`DELETE FROM products WHERE id = \$1 AND deleted_at IS NOT NULL AND tenant_id = \$2`).
+		WithArgs(2, "default").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	// 調用儲存庫方法
+	assert.NoError(t, repo.Purge(context.Background(), 1))
+	assert.ErrorIs(t, repo.Purge(context.Background(), 2), repository.ErrProductNotFound)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試依刪除時間永久刪除超過保留期的產品
+func TestPurgeDeletedBefore(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewProductRepository(db)
+
+	before := time.Now().Add(-30 * 24 * time.Hour)
+	expectBegin(mock)
+	mock.ExpectExec(`DELETE FROM products WHERE deleted_at < \$1 AND tenant_id = \$2$`).
+		WithArgs(before, "default").
+		WillReturnResult(sqlmock.NewResult(0, 5))
+	mock.ExpectCommit()
+
+	// 調用儲存庫方法
+	purged, err := repo.PurgeDeletedBefore(context.Background(), before)
+
+	// 驗證結果
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), purged)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
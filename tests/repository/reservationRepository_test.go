@@ -0,0 +1,301 @@
+package repository
+
+import (
+	"context"
+	"main/internal/models"
+	"main/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reservationRows 預留查詢返回的欄位
+var reservationRows = []string{"id", "product_id", "quantity", "reference", "status", "expires_at", "movement_id", "actor", "request_id", "create_at", "update_at"}
+
+// 測試創建預留：新建、以相同 reference 重送、數量不同與可承諾量不足
+func TestReserve(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewReservationRepository(db)
+
+	now := time.Now()
+	expiresAt := now.Add(15 * time.Minute)
+	// 已標記過期的庫存不能預留
+	lock := `SELECT sku_amount - CASE WHEN products.expired_at IS NOT NULL THEN products.sku_amount ELSE .* END FROM products WHERE id = \$1 AND deleted_at IS NULL AND tenant_id = \$2 FOR UPDATE`
+	existing := `FROM stock_reservations WHERE product_id = \$1 AND reference = \$2`
+	reserved := `SELECT COALESCE\(SUM\(quantity\), 0\) FROM stock_reservations WHERE product_id = \$1 AND status = 'active' AND expires_at > \$2`
+
+	// 新建
+	expectBegin(mock)
+	mock.ExpectQuery(lock).WithArgs(int64(1), "default").WillReturnRows(sqlmock.NewRows([]string{"sku_amount"}).AddRow(10))
+	mock.ExpectQuery(existing).WithArgs(int64(1), "CART-1").WillReturnRows(sqlmock.NewRows(reservationRows))
+	mock.ExpectQuery(reserved).WithArgs(int64(1), sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(6))
+	mock.ExpectQuery(`INSERT INTO stock_reservations`).
+		WithArgs(int64(1), 4, "CART-1", expiresAt, "", "", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(reservationRows).AddRow(7, 1, 4, "CART-1", "active", expiresAt, 0, "", "", now, now))
+	mock.ExpectCommit()
+
+	// 重送
+	expectBegin(mock)
+	mock.ExpectQuery(lock).WithArgs(int64(1), "default").WillReturnRows(sqlmock.NewRows([]string{"sku_amount"}).AddRow(10))
+	mock.ExpectQuery(existing).WithArgs(int64(1), "CART-1").
+		WillReturnRows(sqlmock.NewRows(reservationRows).AddRow(7, 1, 4, "CART-1", "active", expiresAt, 0, "", "", now, now))
+	mock.ExpectCommit()
+
+	// 數量不同
+	expectBegin(mock)
+	mock.ExpectQuery(lock).WithArgs(int64(1), "default").WillReturnRows(sqlmock.NewRows([]string{"sku_amount"}).AddRow(10))
+	mock.ExpectQuery(existing).WithArgs(int64(1), "CART-1").
+		WillReturnRows(sqlmock.NewRows(reservationRows).AddRow(7, 1, 4, "CART-1", "active", expiresAt, 0, "", "", now, now))
+	mock.ExpectRollback()
+
+	// 可承諾量不足
+	expectBegin(mock)
+	mock.ExpectQuery(lock).WithArgs(int64(1), "default").WillReturnRows(sqlmock.NewRows([]string{"sku_amount"}).AddRow(10))
+	mock.ExpectQuery(existing).WithArgs(int64(1), "CART-2").WillReturnRows(sqlmock.NewRows(reservationRows))
+	mock.ExpectQuery(reserved).WithArgs(int64(1), sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(10))
+	mock.ExpectRollback()
+
+	// 產品不存在
+	expectBegin(mock)
+	mock.ExpectQuery(lock).WithArgs(int64(999), "default").WillReturnRows(sqlmock.NewRows([]string{"sku_amount"}))
+	mock.ExpectRollback()
+
+	input := models.StockReservation{ProductID: 1, Quantity: 4, Reference: "CART-1", ExpiresAt: models.Timestamp{Time: expiresAt}}
+
+	// 調用儲存庫方法並驗證結果
+	reservation, created, err := repo.Reserve(context.Background(), input)
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, int64(7), reservation.ID)
+
+	reservation, created, err = repo.Reserve(context.Background(), input)
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, int64(7), reservation.ID)
+
+	_, _, err = repo.Reserve(context.Background(), models.StockReservation{ProductID: 1, Quantity: 5, Reference: "CART-1", ExpiresAt: input.ExpiresAt})
+	assert.ErrorIs(t, err, repository.ErrReservationConflict)
+
+	_, _, err = repo.Reserve(context.Background(), models.StockReservation{ProductID: 1, Quantity: 1, Reference: "CART-2", ExpiresAt: input.ExpiresAt})
+	assert.ErrorIs(t, err, repository.ErrInsufficientStock)
+
+	_, _, err = repo.Reserve(context.Background(), models.StockReservation{ProductID: 999, Quantity: 1, Reference: "CART-3", ExpiresAt: input.ExpiresAt})
+	assert.ErrorIs(t, err, repository.ErrProductNotFound)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試提交預留：先標記為已提交再出庫，最後記錄出庫異動
+func TestCommitReservation(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewReservationRepository(db)
+
+	now := time.Now()
+	expiresAt := now.Add(time.Minute)
+	lock := `FROM stock_reservations WHERE id = \$1 AND tenant_id = \$2 FOR UPDATE`
+
+	expectBegin(mock)
+	mock.ExpectQuery(lock).WithArgs(int64(7), "default").
+		WillReturnRows(sqlmock.NewRows(reservationRows).AddRow(7, 1, 4, "CART-1", "active", expiresAt, 0, "", "", now, now))
+	mock.ExpectExec(`UPDATE stock_reservations SET status = 'committed'`).
+		WithArgs(int64(7), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`SELECT set_config\('app.stock_ledger', 'on', true\)`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT tenant_default_location\(\$1\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectProductLock(mock, 1)
+	mock.ExpectQuery(`UPDATE products SET sku_amount = sku_amount \+ \$2`).
+		WithArgs(int64(1), -4, sqlmock.AnyArg(), true, "default").
+		WillReturnRows(sqlmock.NewRows([]string{"sku_amount"}).AddRow(6))
+	mock.ExpectExec(`UPDATE product_stock SET quantity = quantity \+ \$3`).
+		WithArgs(int64(1), int64(1), -4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO stock_movements`).
+		WithArgs(int64(1), int64(1), models.MovementIssue, -4, models.ReasonSale, "CART-1", 6, "", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(30, now))
+	mock.ExpectQuery(`SELECT id, lot_number, quantity, expiration FROM product_lots`).
+		WithArgs(int64(1), true).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "lot_number", "quantity", "expiration"}))
+	mock.ExpectQuery(`UPDATE stock_reservations SET movement_id = \$2`).
+		WithArgs(int64(7), int64(30)).
+		WillReturnRows(sqlmock.NewRows(reservationRows).AddRow(7, 1, 4, "CART-1", "committed", expiresAt, 30, "", "", now, now))
+	mock.ExpectCommit()
+
+	// 調用儲存庫方法
+	reservation, err := repo.Commit(context.Background(), 7, 0)
+
+	// 驗證結果
+	require.NoError(t, err)
+	assert.Equal(t, models.ReservationCommitted, reservation.Status)
+	assert.Equal(t, int64(30), reservation.MovementID)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試提交非 active 或已到期的預留
+func TestCommitReservationNotActive(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewReservationRepository(db)
+
+	now := time.Now()
+	lock := `FROM stock_reservations WHERE id = \$1 AND tenant_id = \$2 FOR UPDATE`
+
+	// 已提交的預留直接返回
+	expectBegin(mock)
+	mock.ExpectQuery(lock).WithArgs(int64(1), "default").
+		WillReturnRows(sqlmock.NewRows(reservationRows).AddRow(1, 1, 4, "CART-1", "committed", now, 30, "", "", now, now))
+	mock.ExpectCommit()
+
+	// 已釋放
+	expectBegin(mock)
+	mock.ExpectQuery(lock).WithArgs(int64(2), "default").
+		WillReturnRows(sqlmock.NewRows(reservationRows).AddRow(2, 1, 4, "CART-2", "released", now, 0, "", "", now, now))
+	mock.ExpectRollback()
+
+	// 到期但尚未被清理：標記為過期並提交
+	expectBegin(mock)
+	mock.ExpectQuery(lock).WithArgs(int64(3), "default").
+		WillReturnRows(sqlmock.NewRows(reservationRows).AddRow(3, 1, 4, "CART-3", "active", now.Add(-time.Second), 0, "", "", now, now))
+	mock.ExpectExec(`UPDATE stock_reservations SET status = 'expired'`).
+		WithArgs(int64(3), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// 不存在
+	expectBegin(mock)
+	mock.ExpectQuery(lock).WithArgs(int64(999), "default").WillReturnRows(sqlmock.NewRows(reservationRows))
+	mock.ExpectRollback()
+
+	// 調用儲存庫方法並驗證結果
+	reservation, err := repo.Commit(context.Background(), 1, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(30), reservation.MovementID)
+
+	_, err = repo.Commit(context.Background(), 2, 0)
+	assert.ErrorIs(t, err, repository.ErrReservationNotActive)
+
+	_, err = repo.Commit(context.Background(), 3, 0)
+	assert.ErrorIs(t, err, repository.ErrReservationExpired)
+
+	_, err = repo.Commit(context.Background(), 999, 0)
+	assert.ErrorIs(t, err, repository.ErrReservationNotFound)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試釋放預留：重複釋放直接返回，已提交的預留不能釋放
+func TestReleaseReservation(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewReservationRepository(db)
+
+	now := time.Now()
+	lock := `FROM stock_reservations WHERE id = \$1 AND tenant_id = \$2 FOR UPDATE`
+
+	expectBegin(mock)
+	mock.ExpectQuery(lock).WithArgs(int64(1), "default").
+		WillReturnRows(sqlmock.NewRows(reservationRows).AddRow(1, 1, 4, "CART-1", "active", now.Add(time.Minute), 0, "", "", now, now))
+	mock.ExpectQuery(`UPDATE stock_reservations SET status = 'released'`).
+		WithArgs(int64(1), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(reservationRows).AddRow(1, 1, 4, "CART-1", "released", now.Add(time.Minute), 0, "", "", now, now))
+	mock.ExpectCommit()
+
+	expectBegin(mock)
+	mock.ExpectQuery(lock).WithArgs(int64(1), "default").
+		WillReturnRows(sqlmock.NewRows(reservationRows).AddRow(1, 1, 4, "CART-1", "released", now.Add(time.Minute), 0, "", "", now, now))
+	mock.ExpectCommit()
+
+	expectBegin(mock)
+	mock.ExpectQuery(lock).WithArgs(int64(2), "default").
+		WillReturnRows(sqlmock.NewRows(reservationRows).AddRow(2, 1, 4, "CART-2", "committed", now, 30, "", "", now, now))
+	mock.ExpectRollback()
+
+	// 調用儲存庫方法並驗證結果
+	reservation, err := repo.Release(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReservationReleased, reservation.Status)
+
+	reservation, err = repo.Release(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReservationReleased, reservation.Status)
+
+	_, err = repo.Release(context.Background(), 2)
+	assert.ErrorIs(t, err, repository.ErrReservationNotActive)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試過期清理跳過已鎖定的預留
+func TestExpireReservations(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewReservationRepository(db)
+
+	now := time.Now()
+	mock.ExpectExec(`UPDATE stock_reservations SET status = 'expired', update_at = \$1 WHERE id IN \( SELECT id FROM stock_reservations WHERE status = 'active' AND expires_at <= \$1 ORDER BY expires_at LIMIT \$2 FOR UPDATE SKIP LOCKED \)`).
+		WithArgs(now, 500).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	// 調用儲存庫方法
+	expired, err := repo.ExpireReservations(context.Background(), now, 500)
+
+	// 驗證結果
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), expired)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試可承諾量扣除未到期的預留
+func TestGetAvailability(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewReservationRepository(db)
+
+	query := `SELECT p.id AS product_id, p.sku_amount AS on_hand, r.reserved, e.expired, p.sku_amount - r.reserved - e.expired AS available FROM products p`
+	mock.ExpectQuery(query).WithArgs(int64(1), sqlmock.AnyArg(), "default").
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "on_hand", "reserved", "expired", "available"}).AddRow(1, 10, 4, 2, 4))
+	mock.ExpectQuery(query).WithArgs(int64(999), sqlmock.AnyArg(), "default").
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "on_hand", "reserved", "expired", "available"}))
+
+	// 調用儲存庫方法並驗證結果
+	availability, err := repo.GetAvailability(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.StockAvailability{ProductID: 1, OnHand: 10, Reserved: 4, Expired: 2, Available: 4}, availability)
+
+	_, err = repo.GetAvailability(context.Background(), 999)
+	assert.ErrorIs(t, err, repository.ErrProductNotFound)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"main/internal/models"
+	"main/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 測試出庫超過批次合計時，其餘由未追蹤的庫存支付
+func TestApplyMovementsAllocatesUntracked(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewStockRepository(db)
+
+	expectStockLedger(mock)
+	expectProductLock(mock, 1)
+	mock.ExpectQuery(`UPDATE products SET sku_amount = sku_amount \+ \$2`).
+		WithArgs(int64(1), -6, sqlmock.AnyArg(), true, "default").
+		WillReturnRows(sqlmock.NewRows([]string{"sku_amount"}).AddRow(4))
+	mock.ExpectExec(`UPDATE product_stock SET quantity = quantity \+ \$3`).
+		WithArgs(int64(1), int64(1), -6).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO stock_movements`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(40, time.Now()))
+	mock.ExpectQuery(`SELECT id, lot_number, quantity, expiration FROM product_lots`).
+		WithArgs(int64(1), true).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "lot_number", "quantity", "expiration"}).AddRow(8, "L1", 2, nil))
+	mock.ExpectExec(`UPDATE product_lots SET quantity = quantity - \$2`).WithArgs(int64(8), 2, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO stock_movement_lots`).WithArgs(int64(40), int64(8), -2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// 調用儲存庫方法
+	movements, err := repo.ApplyMovements(context.Background(), []models.StockMovement{
+		{ProductID: 1, Type: models.MovementIssue, Quantity: -6, Reason: models.ReasonSale},
+	})
+
+	// 驗證結果
+	require.NoError(t, err)
+	require.Len(t, movements[0].Lots, 1)
+	assert.Equal(t, -2, movements[0].Lots[0].Quantity)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試入庫到既有批號但到期日不同時回滾
+func TestApplyMovementsLotExpirationMismatch(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewStockRepository(db)
+
+	expiration := models.MustParseDate("2025-06-30")
+
+	expectStockLedger(mock)
+	mock.ExpectQuery(`UPDATE products SET sku_amount = sku_amount \+ \$2`).
+		WithArgs(int64(1), 5, sqlmock.AnyArg(), "default").
+		WillReturnRows(sqlmock.NewRows([]string{"sku_amount"}).AddRow(15))
+	mock.ExpectExec(`INSERT INTO product_stock`).
+		WithArgs(int64(1), int64(1), 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO stock_movements`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(41, time.Now()))
+	// 到期日不同時 DO UPDATE 的條件不成立，不返回任何行
+	mock.ExpectQuery(`INSERT INTO product_lots .* ON CONFLICT \(product_id, lot_number\) DO UPDATE`).
+		WithArgs(int64(1), "L1", 5, expiration, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "expiration"}))
+	mock.ExpectRollback()
+
+	// 調用儲存庫方法
+	_, err := repo.ApplyMovements(context.Background(), []models.StockMovement{{
+		ProductID: 1, Type: models.MovementReceive, Quantity: 5, Reason: models.ReasonPurchase,
+		Lots: []models.MovementLot{{LotNumber: "L1", Expiration: expiration, Quantity: 5}},
+	}})
+
+	// 驗證結果
+	assert.ErrorIs(t, err, repository.ErrLotExpirationMismatch)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試同一產品在儲位間轉移不影響批次
+func TestApplyMovementsLocationTransferKeepsLots(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewStockRepository(db)
+
+	update := `UPDATE products SET sku_amount = sku_amount \+ \$2`
+
+	expectStockLedger(mock)
+	mock.ExpectQuery(update).WithArgs(int64(1), -3, sqlmock.AnyArg(), "default").WillReturnRows(sqlmock.NewRows([]string{"sku_amount"}).AddRow(7))
+	mock.ExpectExec(`UPDATE product_stock`).WithArgs(int64(1), int64(1), -3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO stock_movements`).WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(50, time.Now()))
+	mock.ExpectQuery(update).WithArgs(int64(1), 3, sqlmock.AnyArg(), "default").WillReturnRows(sqlmock.NewRows([]string{"sku_amount"}).AddRow(10))
+	mock.ExpectExec(`INSERT INTO product_stock`).WithArgs(int64(1), int64(5), 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO stock_movements`).WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(51, time.Now()))
+	mock.ExpectCommit()
+
+	// 調用儲存庫方法
+	_, err := repo.ApplyMovements(context.Background(), []models.StockMovement{
+		{ProductID: 1, Type: models.MovementTransferOut, Quantity: -3, Reason: models.ReasonTransfer},
+		{ProductID: 1, LocationID: 5, Type: models.MovementTransferIn, Quantity: 3, Reason: models.ReasonTransfer},
+	})
+
+	// 驗證結果
+	assert.NoError(t, err)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試列出批次庫存與未追蹤的數量
+func TestGetLots(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewStockRepository(db)
+
+	mock.ExpectQuery(`SELECT sku_amount FROM products WHERE id = \$1 AND tenant_id = \$2`).
+		WithArgs(int64(1), "default").
+		WillReturnRows(sqlmock.NewRows([]string{"sku_amount"}).AddRow(10))
+	mock.ExpectQuery(`FROM product_lots WHERE product_id = \$1 AND quantity > 0 ORDER BY expiration NULLS LAST, received_at, id`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "lot_number", "quantity", "expiration", "received_at", "create_at", "update_at"}).
+			AddRow(2, 1, "L-EARLY", 3, "2025-03-01", "2025-01-10", time.Now(), time.Now()).
+			AddRow(1, 1, "L-LATE", 5, "2025-09-01", "2025-01-05", time.Now(), time.Now()))
+	mock.ExpectQuery(`SELECT sku_amount FROM products WHERE id = \$1 AND tenant_id = \$2`).
+		WithArgs(int64(999), "default").
+		WillReturnRows(sqlmock.NewRows([]string{"sku_amount"}))
+
+	// 調用儲存庫方法
+	lots, err := repo.GetLots(context.Background(), 1)
+
+	// 驗證結果
+	require.NoError(t, err)
+	require.Len(t, lots.Items, 2)
+	assert.Equal(t, "L-EARLY", lots.Items[0].LotNumber)
+	assert.Equal(t, 2, lots.UntrackedQuantity)
+
+	_, err = repo.GetLots(context.Background(), 999)
+	assert.ErrorIs(t, err, repository.ErrProductNotFound)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,284 @@
+package repository
+
+import (
+	"context"
+	"main/internal/models"
+	"main/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// expectStockLedger 預期開始交易、標記為異動帳寫入並讀取預設儲位（ID 為 1）
+func expectStockLedger(mock sqlmock.Sqlmock) {
+	expectBegin(mock)
+	mock.ExpectExec(`SELECT set_config\('app.stock_ledger', 'on', true\)`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT tenant_default_location\(\$1\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+}
+
+// expectProductLock 預期出庫先鎖定產品，之後的條件更新才能看到並發提交的預留
+func expectProductLock(mock sqlmock.Sqlmock, id int64) {
+	mock.ExpectExec(`SELECT 1 FROM products WHERE id = \$1 AND tenant_id = \$2 FOR UPDATE`).
+		WithArgs(id, "default").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+// 測試異動依產品 ID 順序更新產品與儲位庫存，返回的異動保持輸入順序
+func TestApplyMovements(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewStockRepository(db)
+
+	createdAt := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	update := `UPDATE products SET sku_amount = sku_amount \+ \$2, update_at = \$3, version = version \+ 1 WHERE id = \$1 AND deleted_at IS NULL AND sku_amount \+ \$2 >= 0 AND tenant_id = \$4 RETURNING sku_amount`
+	insert := `INSERT INTO stock_movements \(product_id, location_id, movement_type, quantity, reason, reference, balance, actor, request_id\)`
+
+	expectStockLedger(mock)
+	mock.ExpectQuery(update).
+		WithArgs(int64(1), 5, sqlmock.AnyArg(), "default").
+		WillReturnRows(sqlmock.NewRows([]string{"sku_amount"}).AddRow(15))
+	mock.ExpectExec(`INSERT INTO product_stock \(product_id, location_id, quantity\) VALUES \(\$1, \$2, \$3\) ON CONFLICT \(product_id, location_id\) DO UPDATE`).
+		WithArgs(int64(1), int64(4), 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(insert).
+		WithArgs(int64(1), int64(4), models.MovementReceive, 5, models.ReasonPurchase, "RP-1", 15, "", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(21, createdAt))
+	mock.ExpectQuery(update).
+		WithArgs(int64(2), -5, sqlmock.AnyArg(), "default").
+		WillReturnRows(sqlmock.NewRows([]string{"sku_amount"}).AddRow(0))
+	mock.ExpectExec(`UPDATE product_stock SET quantity = quantity \+ \$3 WHERE product_id = \$1 AND location_id = \$2 AND quantity \+ \$3 >= 0`).
+		WithArgs(int64(2), int64(1), -5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(insert).
+		WithArgs(int64(2), int64(1), models.MovementAdjust, -5, models.ReasonCorrection, "RP-1", 0, "", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(22, createdAt))
+
+	// 減少的調整依到期日先後扣減批次，未指定批號的入庫不屬於任何批次
+	mock.ExpectQuery(`SELECT id, lot_number, quantity, expiration FROM product_lots WHERE product_id = \$1 AND quantity > 0 AND NOT \(\$2 AND expired_at IS NOT NULL\) ORDER BY expired_at IS NULL, expiration NULLS LAST, received_at, id FOR UPDATE`).
+		WithArgs(int64(2), false).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "lot_number", "quantity", "expiration"}).
+			AddRow(8, "L1", 3, "2025-05-01").
+			AddRow(9, "L2", 10, "2025-07-01"))
+	mock.ExpectExec(`UPDATE product_lots SET quantity = quantity - \$2`).WithArgs(int64(8), 3, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO stock_movement_lots`).WithArgs(int64(22), int64(8), -3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE product_lots SET quantity = quantity - \$2`).WithArgs(int64(9), 2, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO stock_movement_lots`).WithArgs(int64(22), int64(9), -2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// 調用儲存庫方法
+	movements, err := repo.ApplyMovements(context.Background(), []models.StockMovement{
+		{ProductID: 2, Type: models.MovementAdjust, Quantity: -5, Reason: models.ReasonCorrection, Reference: "RP-1"},
+		{ProductID: 1, LocationID: 4, Type: models.MovementReceive, Quantity: 5, Reason: models.ReasonPurchase, Reference: "RP-1"},
+	})
+
+	// 驗證結果
+	require.NoError(t, err)
+	require.Len(t, movements, 2)
+	assert.Equal(t, int64(22), movements[0].ID)
+	assert.Equal(t, 0, movements[0].Balance)
+	assert.Equal(t, int64(1), movements[0].LocationID)
+	assert.Equal(t, int64(21), movements[1].ID)
+	assert.Equal(t, 15, movements[1].Balance)
+	assert.Equal(t, "2025-03-01T10:00:00Z", movements[1].CreatedAt.String())
+	require.Len(t, movements[0].Lots, 2)
+	assert.Equal(t, -3, movements[0].Lots[0].Quantity)
+	assert.Empty(t, movements[1].Lots)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試產品的庫存全部被預留時仍可在儲位間轉移：轉出只檢查儲位的庫存，不受預留限制；
+// 未指定的儲位先換成預設儲位 5 再排序，因此先轉入儲位 2 再從儲位 5 轉出
+func TestApplyMovementsTransferReservedStock(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewStockRepository(db)
+
+	update := `UPDATE products SET sku_amount = sku_amount \+ \$2, update_at = \$3, version = version \+ 1 WHERE id = \$1 AND deleted_at IS NULL AND sku_amount \+ \$2 >= 0 AND tenant_id = \$4 RETURNING sku_amount`
+
+	expectBegin(mock)
+	mock.ExpectExec(`SELECT set_config\('app.stock_ledger', 'on', true\)`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT tenant_default_location\(\$1\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectQuery(update).
+		WithArgs(int64(1), 4, sqlmock.AnyArg(), "default").
+		WillReturnRows(sqlmock.NewRows([]string{"sku_amount"}).AddRow(14))
+	mock.ExpectExec(`INSERT INTO product_stock`).
+		WithArgs(int64(1), int64(2), 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO stock_movements`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(61, time.Now()))
+	mock.ExpectQuery(update).
+		WithArgs(int64(1), -4, sqlmock.AnyArg(), "default").
+		WillReturnRows(sqlmock.NewRows([]string{"sku_amount"}).AddRow(10))
+	mock.ExpectExec(`UPDATE product_stock SET quantity = quantity \+ \$3`).
+		WithArgs(int64(1), int64(5), -4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO stock_movements`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(62, time.Now()))
+	mock.ExpectCommit()
+
+	// 調用儲存庫方法
+	movements, err := repo.ApplyMovements(context.Background(), []models.StockMovement{
+		{ProductID: 1, Type: models.MovementTransferOut, Quantity: -4, Reason: models.ReasonTransfer},
+		{ProductID: 1, LocationID: 2, Type: models.MovementTransferIn, Quantity: 4, Reason: models.ReasonTransfer},
+	})
+
+	// 驗證結果
+	require.NoError(t, err)
+	require.Len(t, movements, 2)
+	assert.Equal(t, int64(62), movements[0].ID)
+	assert.Equal(t, int64(5), movements[0].LocationID)
+	assert.Equal(t, int64(61), movements[1].ID)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試庫存會變成負數或產品不存在時回滾
+func TestApplyMovementsRejected(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewStockRepository(db)
+
+	cases := []struct {
+		exists bool
+		err    error
+	}{
+		{true, repository.ErrInsufficientStock},
+		{false, repository.ErrProductNotFound},
+	}
+
+	for _, tc := range cases {
+		expectStockLedger(mock)
+		expectProductLock(mock, 1)
+		mock.ExpectQuery(`UPDATE products SET sku_amount = sku_amount \+ \$2`).
+			WithArgs(int64(1), -20, sqlmock.AnyArg(), true, "default").
+			WillReturnRows(sqlmock.NewRows([]string{"sku_amount"}))
+		mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM products WHERE id = \$1 AND deleted_at IS NULL AND tenant_id = \$2\)`).
+			WithArgs(int64(1), "default").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(tc.exists))
+		mock.ExpectRollback()
+
+		// 調用儲存庫方法
+		_, err := repo.ApplyMovements(context.Background(), []models.StockMovement{
+			{ProductID: 1, Type: models.MovementIssue, Quantity: -20, Reason: models.ReasonSale},
+		})
+
+		// 驗證結果
+		assert.ErrorIs(t, err, tc.err)
+	}
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試儲位庫存不足或儲位不存在時回滾
+func TestApplyMovementsLocationRejected(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewStockRepository(db)
+
+	cases := []struct {
+		exists bool
+		err    error
+	}{
+		{true, repository.ErrInsufficientStock},
+		{false, repository.ErrLocationNotFound},
+	}
+
+	for _, tc := range cases {
+		expectStockLedger(mock)
+		expectProductLock(mock, 1)
+		mock.ExpectQuery(`UPDATE products SET sku_amount = sku_amount \+ \$2`).
+			WithArgs(int64(1), -3, sqlmock.AnyArg(), true, "default").
+			WillReturnRows(sqlmock.NewRows([]string{"sku_amount"}).AddRow(7))
+		mock.ExpectExec(`UPDATE product_stock SET quantity = quantity \+ \$3`).
+			WithArgs(int64(1), int64(9), -3).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM stock_locations WHERE id = \$1 AND tenant_id = \$2\)`).
+			WithArgs(int64(9), "default").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(tc.exists))
+		mock.ExpectRollback()
+
+		// 調用儲存庫方法：產品總庫存足夠，但儲位 9 的庫存不足
+		_, err := repo.ApplyMovements(context.Background(), []models.StockMovement{
+			{ProductID: 1, LocationID: 9, Type: models.MovementIssue, Quantity: -3, Reason: models.ReasonSale},
+		})
+
+		// 驗證結果
+		assert.ErrorIs(t, err, tc.err)
+	}
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試列出產品的庫存異動
+func TestGetMovements(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewStockRepository(db)
+
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM products WHERE id = \$1 AND tenant_id = \$2\)`).
+		WithArgs(int64(1), "default").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM stock_movements WHERE product_id = \$1`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectQuery(`SELECT id, product_id, COALESCE\(location_id, 0\) AS location_id, .* FROM stock_movements WHERE product_id = \$1 ORDER BY created_at DESC, id DESC LIMIT \$2 OFFSET \$3`).
+		WithArgs(int64(1), 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "location_id", "movement_type", "quantity", "reason", "reference", "balance", "actor", "request_id", "created_at"}).
+			AddRow(2, 1, 1, models.MovementIssue, -3, models.ReasonSale, "SO-1", 7, "alice", "req-2", time.Now()).
+			AddRow(1, 1, 1, models.MovementAdjust, 10, models.ReasonOpeningBalance, "", 10, "", "", time.Now()))
+	mock.ExpectQuery(`SELECT ml.movement_id, ml.lot_id, l.lot_number, l.expiration, ml.quantity FROM stock_movement_lots ml`).
+		WithArgs(pq.Array([]int64{2, 1})).
+		WillReturnRows(sqlmock.NewRows([]string{"movement_id", "lot_id", "lot_number", "expiration", "quantity"}).
+			AddRow(2, 5, "L1", "2025-05-01", -3))
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM products WHERE id = \$1 AND tenant_id = \$2\)`).
+		WithArgs(int64(999), "default").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	// 調用儲存庫方法
+	movements, total, err := repo.GetMovements(context.Background(), 1, models.ProductQuery{PageSize: 10})
+
+	// 驗證結果
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	require.Len(t, movements, 2)
+	assert.Equal(t, models.MovementIssue, movements[0].Type)
+	assert.Equal(t, -3, movements[0].Quantity)
+	assert.Equal(t, 7, movements[0].Balance)
+	require.Len(t, movements[0].Lots, 1)
+	assert.Equal(t, "L1", movements[0].Lots[0].LotNumber)
+	assert.Empty(t, movements[1].Lots)
+
+	_, _, err = repo.GetMovements(context.Background(), 999, models.ProductQuery{PageSize: 10})
+	assert.ErrorIs(t, err, repository.ErrProductNotFound)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
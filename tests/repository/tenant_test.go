@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"main/internal/models"
+	"main/internal/repository"
+	"main/internal/tenant"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// expectTenantBegin 預期開始交易，並在交易內設置指定的租戶
+func expectTenantBegin(mock sqlmock.Sqlmock, id string) {
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT set_config\('app.actor', \$1, true\), set_config\('app.request_id', \$2, true\), set_config\('app.tenant_id', \$3, true\)`).
+		WithArgs("", "", id).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+// 測試其他租戶的產品讀不到也寫不到：所有查詢都以 ctx 的租戶為條件，未命中時返回產品未找到
+func TestProductTenantIsolation(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewProductRepository(db)
+	ctx := tenant.WithID(context.Background(), "acme")
+
+	// 產品 1 屬於 default 租戶，以 acme 讀取時沒有結果
+	mock.ExpectQuery(`SELECT \* FROM products WHERE id = \$1 AND deleted_at IS NULL AND tenant_id = \$2`).
+		WithArgs(int64(1), "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	// 更新與刪除同樣只作用於 acme 的行
+	expectTenantBegin(mock, "acme")
+	mock.ExpectQuery(`UPDATE products SET sku_name = \$1, update_at = \$2, version = version \+ 1 WHERE id = \$3 AND deleted_at IS NULL AND tenant_id = \$4 RETURNING`).
+		WithArgs("改名", sqlmock.AnyArg(), int64(1), "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM products WHERE id = \$1 AND deleted_at IS NULL AND tenant_id = \$2\)`).
+		WithArgs(int64(1), "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectRollback()
+
+	expectTenantBegin(mock, "acme")
+	mock.ExpectExec(`UPDATE products SET deleted_at = \$2, version = version \+ 1 WHERE id = \$1 AND deleted_at IS NULL AND tenant_id = \$3$`).
+		WithArgs(int64(1), sqlmock.AnyArg(), "acme").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM products WHERE id = \$1 AND deleted_at IS NULL AND tenant_id = \$2\)`).
+		WithArgs(int64(1), "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectRollback()
+
+	// 列表只計算與返回 acme 的產品
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM products WHERE deleted_at IS NULL AND tenant_id = \$1$`).
+		WithArgs("acme").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(`SELECT \* FROM products WHERE deleted_at IS NULL AND tenant_id = \$1 ORDER BY id ASC LIMIT \$2 OFFSET \$3`).
+		WithArgs("acme", 20, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	// 調用儲存庫方法並驗證結果
+	_, err := repo.GetByID(ctx, 1)
+	assert.ErrorIs(t, err, repository.ErrProductNotFound)
+
+	patch := models.ProductPatch{SkuName: models.Nullable[string]{Set: true, Value: "改名"}}
+	_, err = repo.Update(ctx, 1, 0, patch)
+	assert.ErrorIs(t, err, repository.ErrProductNotFound)
+
+	assert.ErrorIs(t, repo.Delete(ctx, 1, 0), repository.ErrProductNotFound)
+
+	products, total, err := repo.GetAll(ctx, models.ProductQuery{PageSize: 20, SortBy: "id"})
+	require.NoError(t, err)
+	assert.Empty(t, products)
+	assert.Equal(t, 0, total)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試啟用資料列安全時，不在交易中的讀取也在設置了租戶的交易中執行
+func TestProductRowLevelSecurity(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewProductRepository(db, repository.WithRowLevelSecurity(true))
+	ctx := tenant.WithID(context.Background(), "acme")
+
+	expectTenantBegin(mock, "acme")
+	mock.ExpectQuery(`SELECT \* FROM products WHERE id = \$1 AND deleted_at IS NULL AND tenant_id = \$2`).
+		WithArgs(int64(1), "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "sku_code"}).AddRow(1, "SKU001"))
+	mock.ExpectCommit()
+
+	// 調用儲存庫方法
+	product, err := repo.GetByID(ctx, 1)
+
+	// 驗證結果
+	require.NoError(t, err)
+	assert.Equal(t, "SKU001", product.SkuCode)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試清理回收站只刪除 ctx 租戶的產品，其他租戶的回收站保留；只有租戶為 tenant.All 時跨所有租戶
+func TestPurgeDeletedBeforeTenants(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewProductRepository(db)
+
+	before := time.Now()
+	expectTenantBegin(mock, "acme")
+	mock.ExpectExec(`DELETE FROM products WHERE deleted_at < \$1 AND tenant_id = \$2$`).
+		WithArgs(before, "acme").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	expectTenantBegin(mock, tenant.All)
+	mock.ExpectExec(`DELETE FROM products WHERE deleted_at < \$1$`).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	// 調用儲存庫方法並驗證結果
+	purged, err := repo.PurgeDeletedBefore(tenant.WithID(context.Background(), "acme"), before)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	purged, err = repo.PurgeDeletedBefore(tenant.WithID(context.Background(), tenant.All), before)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), purged)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"main/internal/models"
+	"main/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 測試創建倉庫與代碼重複
+func TestCreateWarehouse(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewWarehouseRepository(db)
+
+	now := time.Now()
+	insert := `INSERT INTO warehouses \(code, name, tenant_id\) VALUES \(\$1, \$2, \$3\) RETURNING \*`
+	mock.ExpectQuery(insert).
+		WithArgs("EAST", "東區倉庫", "default").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code", "name", "create_at", "update_at"}).AddRow(2, "EAST", "東區倉庫", now, now))
+	mock.ExpectQuery(insert).
+		WithArgs("EAST", "東區倉庫", "default").
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "warehouses_code_key"})
+
+	// 調用儲存庫方法
+	warehouse, err := repo.CreateWarehouse(context.Background(), models.Warehouse{Code: "EAST", Name: "東區倉庫"})
+
+	// 驗證結果
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), warehouse.ID)
+
+	_, err = repo.CreateWarehouse(context.Background(), models.Warehouse{Code: "EAST", Name: "東區倉庫"})
+	assert.ErrorIs(t, err, repository.ErrDuplicateWarehouse)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試仍有儲位的倉庫不能刪除
+func TestDeleteWarehouse(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewWarehouseRepository(db)
+
+	mock.ExpectExec(`DELETE FROM warehouses WHERE id = \$1 AND tenant_id = \$2`).
+		WithArgs(int64(1), "default").
+		WillReturnError(&pq.Error{Code: "23503"})
+	mock.ExpectExec(`DELETE FROM warehouses WHERE id = \$1 AND tenant_id = \$2`).
+		WithArgs(int64(999), "default").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// 調用儲存庫方法並驗證結果
+	assert.ErrorIs(t, repo.DeleteWarehouse(context.Background(), 1), repository.ErrWarehouseInUse)
+	assert.ErrorIs(t, repo.DeleteWarehouse(context.Background(), 999), repository.ErrWarehouseNotFound)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試在不存在的倉庫創建儲位
+func TestCreateLocationWarehouseNotFound(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewWarehouseRepository(db)
+
+	mock.ExpectQuery(`INSERT INTO stock_locations \(warehouse_id, code, name, tenant_id\)`).
+		WithArgs(int64(999), "A-01", "A 區", "default").
+		WillReturnError(&pq.Error{Code: "23503"})
+
+	// 調用儲存庫方法
+	_, err := repo.CreateLocation(context.Background(), models.StockLocation{WarehouseID: 999, Code: "A-01", Name: "A 區"})
+
+	// 驗證結果
+	assert.ErrorIs(t, err, repository.ErrWarehouseNotFound)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試刪除儲位：預設儲位與仍有庫存的儲位不能刪除
+func TestDeleteLocation(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewWarehouseRepository(db)
+
+	lock := `SELECT is_default FROM stock_locations WHERE id = \$1 AND tenant_id = \$2 FOR UPDATE`
+	clear := `DELETE FROM product_stock WHERE location_id = \$1 AND quantity = 0`
+	remove := `DELETE FROM stock_locations WHERE id = \$1`
+
+	// 預設儲位
+	expectBegin(mock)
+	mock.ExpectQuery(lock).WithArgs(int64(1), "default").WillReturnRows(sqlmock.NewRows([]string{"is_default"}).AddRow(true))
+	mock.ExpectRollback()
+
+	// 仍有庫存
+	expectBegin(mock)
+	mock.ExpectQuery(lock).WithArgs(int64(2), "default").WillReturnRows(sqlmock.NewRows([]string{"is_default"}).AddRow(false))
+	mock.ExpectExec(clear).WithArgs(int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(remove).WithArgs(int64(2)).WillReturnError(&pq.Error{Code: "23503"})
+	mock.ExpectRollback()
+
+	// 不存在
+	expectBegin(mock)
+	mock.ExpectQuery(lock).WithArgs(int64(999), "default").WillReturnRows(sqlmock.NewRows([]string{"is_default"}))
+	mock.ExpectRollback()
+
+	// 沒有庫存，刪除成功
+	expectBegin(mock)
+	mock.ExpectQuery(lock).WithArgs(int64(3), "default").WillReturnRows(sqlmock.NewRows([]string{"is_default"}).AddRow(false))
+	mock.ExpectExec(clear).WithArgs(int64(3)).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(remove).WithArgs(int64(3)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// 調用儲存庫方法並驗證結果
+	assert.ErrorIs(t, repo.DeleteLocation(context.Background(), 1), repository.ErrDefaultLocation)
+	assert.ErrorIs(t, repo.DeleteLocation(context.Background(), 2), repository.ErrLocationInUse)
+	assert.ErrorIs(t, repo.DeleteLocation(context.Background(), 999), repository.ErrLocationNotFound)
+	assert.NoError(t, repo.DeleteLocation(context.Background(), 3))
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// 測試讀取產品在各儲位的庫存
+func TestGetLocationStock(t *testing.T) {
+	// 設置模擬數據庫
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	// 創建儲存庫
+	repo := repository.NewProductRepository(db)
+
+	mock.ExpectQuery(`FROM product_stock ps JOIN stock_locations l ON l.id = ps.location_id JOIN warehouses w ON w.id = l.warehouse_id WHERE ps.product_id = ANY\(\$1\) AND ps.quantity > 0 AND ps.tenant_id = \$2`).
+		WithArgs(pq.Array([]int64{1, 2}), "default").
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "location_id", "location_code", "warehouse_id", "warehouse_code", "quantity"}).
+			AddRow(1, 2, "A-01", 2, "EAST", 30).
+			AddRow(1, 1, "DEFAULT", 1, "MAIN", 70))
+
+	// 調用儲存庫方法
+	stock, err := repo.GetLocationStock(context.Background(), []int64{1, 2})
+
+	// 驗證結果
+	require.NoError(t, err)
+	require.Len(t, stock[1], 2)
+	assert.Equal(t, "EAST", stock[1][0].WarehouseCode)
+	assert.Equal(t, 30, stock[1][0].Quantity)
+	assert.Empty(t, stock[2])
+
+	// 沒有產品時不查詢
+	stock, err = repo.GetLocationStock(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.Empty(t, stock)
+
+	// 確保所有預期都被滿足
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
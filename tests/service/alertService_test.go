@@ -0,0 +1,147 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"main/internal/auth"
+	"main/internal/models"
+	"main/internal/service"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// 模擬通知管道
+type MockNotifier struct {
+	mock.Mock
+}
+
+func (m *MockNotifier) NotifyLowStock(ctx context.Context, event models.LowStockEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+func (m *MockNotifier) NotifyExpired(ctx context.Context, event models.ExpiredStockEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+// 模擬事件寄件匣儲存庫，依序把 events 交給 deliver，遇到錯誤時停止
+type MockAlertRepository struct {
+	mock.Mock
+}
+
+func (m *MockAlertRepository) DeliverLowStockEvents(ctx context.Context, limit int, deliver func(event models.LowStockEvent) error) (int, error) {
+	args := m.Called(ctx, limit)
+	if err := args.Error(1); err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, event := range args.Get(0).([]models.LowStockEvent) {
+		if err := deliver(event); err != nil {
+			return delivered, err
+		}
+		delivered++
+	}
+	return delivered, nil
+}
+
+// 測試事件依序送到通知管道，並帶有派送時限
+func TestRelayLowStock(t *testing.T) {
+	// 創建模擬儲存庫與通知管道
+	mockRepo := new(MockAlertRepository)
+	notifier := new(MockNotifier)
+
+	// 創建事件派送服務
+	service := service.NewAlertService(mockRepo, notifier, time.Second)
+
+	events := []models.LowStockEvent{
+		{ID: 1, ProductID: 1, SkuCode: "SKU001", SkuAmount: 9, ReorderPoint: 10, TenantID: "default"},
+		{ID: 2, ProductID: 7, SkuCode: "SKU007", SkuAmount: 0, ReorderPoint: 5, TenantID: "acme"},
+	}
+	hasDeadline := mock.MatchedBy(func(ctx context.Context) bool {
+		_, ok := ctx.Deadline()
+		return ok
+	})
+	mockRepo.On("DeliverLowStockEvents", mock.Anything, 100).Return(events, nil)
+	notifier.On("NotifyLowStock", hasDeadline, events[0]).Return(nil).Once()
+	notifier.On("NotifyLowStock", hasDeadline, events[1]).Return(nil).Once()
+
+	// 調用服務方法
+	relayed, err := service.RelayLowStock(systemContext(), 100)
+
+	// 驗證結果
+	require.NoError(t, err)
+	assert.Equal(t, 2, relayed)
+	notifier.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+}
+
+// 測試通知失敗時停止並返回錯誤，失敗的事件不計入送出數量
+func TestRelayLowStockNotifyError(t *testing.T) {
+	// 創建模擬儲存庫與通知管道
+	mockRepo := new(MockAlertRepository)
+	notifier := new(MockNotifier)
+
+	// 創建事件派送服務
+	service := service.NewAlertService(mockRepo, notifier, time.Second)
+
+	events := []models.LowStockEvent{
+		{ID: 1, ProductID: 1, SkuCode: "SKU001", TenantID: "default"},
+		{ID: 2, ProductID: 2, SkuCode: "SKU002", TenantID: "default"},
+		{ID: 3, ProductID: 3, SkuCode: "SKU003", TenantID: "default"},
+	}
+	mockRepo.On("DeliverLowStockEvents", mock.Anything, 100).Return(events, nil)
+	notifier.On("NotifyLowStock", mock.Anything, events[0]).Return(nil).Once()
+	notifier.On("NotifyLowStock", mock.Anything, events[1]).Return(errors.New("Webhook 無回應")).Once()
+
+	// 調用服務方法
+	relayed, err := service.RelayLowStock(systemContext(), 100)
+
+	// 驗證結果
+	assert.Error(t, err)
+	assert.Equal(t, 1, relayed)
+	notifier.AssertExpectations(t)
+	notifier.AssertNotCalled(t, "NotifyLowStock", mock.Anything, events[2])
+}
+
+// 測試讀取寄件匣失敗時返回錯誤，不呼叫通知管道
+func TestRelayLowStockClaimError(t *testing.T) {
+	// 創建模擬儲存庫與通知管道
+	mockRepo := new(MockAlertRepository)
+	notifier := new(MockNotifier)
+
+	// 創建事件派送服務
+	service := service.NewAlertService(mockRepo, notifier, time.Second)
+
+	mockRepo.On("DeliverLowStockEvents", mock.Anything, 100).Return(nil, errors.New("連線中斷"))
+
+	// 調用服務方法
+	relayed, err := service.RelayLowStock(systemContext(), 100)
+
+	// 驗證結果
+	assert.Error(t, err)
+	assert.Zero(t, relayed)
+	notifier.AssertNotCalled(t, "NotifyLowStock", mock.Anything, mock.Anything)
+}
+
+// 測試只有 viewer 角色的主體不能派送事件
+func TestRelayLowStockForbidden(t *testing.T) {
+	// 創建模擬儲存庫與通知管道
+	mockRepo := new(MockAlertRepository)
+	notifier := new(MockNotifier)
+
+	// 創建事件派送服務
+	service := service.NewAlertService(mockRepo, notifier, time.Second)
+
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "alice", Roles: []string{"viewer"}})
+
+	// 調用服務方法並驗證結果
+	_, err := service.RelayLowStock(ctx, 100)
+	assert.ErrorIs(t, err, auth.ErrForbidden)
+	mockRepo.AssertNotCalled(t, "DeliverLowStockEvents", mock.Anything, mock.Anything)
+}
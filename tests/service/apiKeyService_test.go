@@ -0,0 +1,260 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"main/internal/auth"
+	"main/internal/models"
+	"main/internal/repository"
+	"main/internal/service"
+	"main/internal/tenant"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// 模擬 API 金鑰儲存庫
+type MockAPIKeyRepository struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyRepository) CreateAPIKey(ctx context.Context, input models.APIKey, keyHash string) (models.APIKey, error) {
+	args := m.Called(ctx, input, keyHash)
+	return args.Get(0).(models.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	args := m.Called(ctx)
+	keys, _ := args.Get(0).([]models.APIKey)
+	return keys, args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (models.APIKey, error) {
+	args := m.Called(ctx, keyHash)
+	return args.Get(0).(models.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) RotateAPIKey(ctx context.Context, id int64, prefix string, keyHash string, now time.Time) (models.APIKey, error) {
+	args := m.Called(ctx, id, prefix, keyHash, now)
+	return args.Get(0).(models.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) RevokeAPIKey(ctx context.Context, id int64, now time.Time) (models.APIKey, error) {
+	args := m.Called(ctx, id, now)
+	return args.Get(0).(models.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) TouchAPIKey(ctx context.Context, id int64, now time.Time) error {
+	args := m.Called(ctx, id, now)
+	return args.Error(0)
+}
+
+// 測試創建金鑰時只保存雜湊，明文只出現在返回值中
+func TestCreateAPIKey(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockAPIKeyRepository)
+
+	// 創建 API 金鑰服務
+	service := service.NewAPIKeyService(mockRepo)
+
+	var savedHash string
+	mockRepo.On("CreateAPIKey", mock.Anything, mock.MatchedBy(func(k models.APIKey) bool {
+		return k.Name == "pos-01" && len(k.Prefix) == 11 && len(k.Scopes) == 1 && k.Tenant == "default"
+	}), mock.AnythingOfType("string")).
+		Run(func(args mock.Arguments) { savedHash = args.String(2) }).
+		Return(models.APIKey{ID: 1, Name: "pos-01", Scopes: pq.StringArray{"product:read"}}, nil)
+
+	// 調用服務方法
+	created, err := service.CreateAPIKey(systemContext(), models.CreateAPIKeyRequest{Name: "pos-01", Scopes: []string{"product:read"}})
+
+	// 驗證結果
+	require.NoError(t, err)
+	assert.True(t, auth.ValidAPIKeyFormat(created.Key))
+	assert.Equal(t, auth.HashAPIKey(created.Key), savedHash)
+	assert.NotContains(t, savedHash, created.Key)
+
+	// 驗證模擬儲存庫方法被調用
+	mockRepo.AssertExpectations(t)
+}
+
+// 測試金鑰屬於請求的租戶，不能為其他租戶創建
+func TestCreateAPIKeyTenant(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockAPIKeyRepository)
+
+	// 創建 API 金鑰服務
+	service := service.NewAPIKeyService(mockRepo)
+
+	mockRepo.On("CreateAPIKey", mock.Anything, mock.MatchedBy(func(k models.APIKey) bool {
+		return k.Tenant == "acme"
+	}), mock.AnythingOfType("string")).
+		Return(models.APIKey{ID: 1, Name: "pos-01", Tenant: "acme"}, nil).Twice()
+
+	ctx := tenant.WithID(systemContext(), "acme")
+
+	// 未指定或指定相同的租戶時屬於請求的租戶
+	for _, keyTenant := range []string{"", "acme"} {
+		created, err := service.CreateAPIKey(ctx, models.CreateAPIKeyRequest{Name: "pos-01", Tenant: keyTenant})
+		require.NoError(t, err)
+		assert.Equal(t, "acme", created.Tenant)
+	}
+
+	// 指定其他租戶時拒絕
+	_, err := service.CreateAPIKey(ctx, models.CreateAPIKeyRequest{Name: "pos-01", Tenant: "default"})
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+
+	// 驗證模擬儲存庫方法被調用
+	mockRepo.AssertExpectations(t)
+}
+
+// 測試沒有 apikey:manage 權限的主體不能管理金鑰
+func TestAPIKeyManagementForbidden(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockAPIKeyRepository)
+
+	// 創建 API 金鑰服務
+	service := service.NewAPIKeyService(mockRepo)
+
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "alice", Roles: []string{"manager"}})
+
+	_, err := service.CreateAPIKey(ctx, models.CreateAPIKeyRequest{Name: "pos-01"})
+	assert.ErrorIs(t, err, auth.ErrForbidden)
+	_, err = service.ListAPIKeys(ctx)
+	assert.ErrorIs(t, err, auth.ErrForbidden)
+	_, err = service.RotateAPIKey(ctx, 1)
+	assert.ErrorIs(t, err, auth.ErrForbidden)
+	_, err = service.RevokeAPIKey(ctx, 1)
+	assert.ErrorIs(t, err, auth.ErrForbidden)
+
+	// 沒有主體的呼叫同樣拒絕
+	_, err = service.ListAPIKeys(context.Background())
+	assert.ErrorIs(t, err, auth.ErrForbidden)
+
+	// 驗證模擬儲存庫未被調用
+	assert.Empty(t, mockRepo.Calls)
+}
+
+// 測試無效的名稱、範圍、到期時間或租戶不寫入
+func TestCreateAPIKeyValidation(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockAPIKeyRepository)
+
+	// 創建 API 金鑰服務
+	service := service.NewAPIKeyService(mockRepo)
+
+	requests := []models.CreateAPIKeyRequest{
+		{Scopes: []string{"product:read"}},
+		{Name: "etl", Scopes: []string{"product read"}},
+		{Name: "etl", ExpiresAt: models.Timestamp{Time: time.Now().Add(-time.Hour)}},
+		{Name: "etl", Tenant: "Acme Corp"},
+	}
+	for _, req := range requests {
+		_, err := service.CreateAPIKey(systemContext(), req)
+
+		var validationErr *models.ValidationError
+		assert.ErrorAs(t, err, &validationErr, req)
+	}
+
+	// 驗證模擬儲存庫未被調用
+	mockRepo.AssertNotCalled(t, "CreateAPIKey", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// 測試驗證有效、不存在、已撤銷、已過期的金鑰與查詢失敗
+func TestVerifyAPIKey(t *testing.T) {
+	key, _, err := auth.GenerateAPIKey()
+	require.NoError(t, err)
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		stored models.APIKey
+		err    error
+		want   error
+	}{
+		{"有效", models.APIKey{ID: 1, Name: "pos-01", Scopes: pq.StringArray{"product:read"}, Tenant: "acme"}, nil, nil},
+		{"不存在", models.APIKey{}, repository.ErrAPIKeyNotFound, auth.ErrInvalidAPIKey},
+		{"已撤銷", models.APIKey{ID: 1, RevokedAt: models.Timestamp{Time: now}}, nil, auth.ErrInvalidAPIKey},
+		{"已過期", models.APIKey{ID: 1, ExpiresAt: models.Timestamp{Time: now.Add(-time.Second)}}, nil, auth.ErrAPIKeyExpired},
+		{"查詢失敗", models.APIKey{}, errors.New("連線中斷"), auth.ErrUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 創建模擬儲存庫與服務
+			mockRepo := new(MockAPIKeyRepository)
+			service := service.NewAPIKeyService(mockRepo)
+
+			mockRepo.On("GetAPIKeyByHash", mock.Anything, auth.HashAPIKey(key)).Return(tt.stored, tt.err)
+			mockRepo.On("TouchAPIKey", mock.Anything, int64(1), mock.AnythingOfType("time.Time")).Return(nil)
+
+			principal, err := service.Verify(systemContext(), key)
+
+			if tt.want != nil {
+				assert.ErrorIs(t, err, tt.want)
+				mockRepo.AssertNotCalled(t, "TouchAPIKey", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "api-key:acme/pos-01", principal.Subject)
+			assert.Equal(t, []string{"product:read"}, principal.Scopes)
+			assert.Equal(t, "acme", principal.Tenant)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+// 測試最後使用時間距今不到一分鐘時不寫入資料庫
+func TestVerifyAPIKeyTouchThrottled(t *testing.T) {
+	key, _, err := auth.GenerateAPIKey()
+	require.NoError(t, err)
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		lastUsed models.Timestamp
+		touched  bool
+	}{
+		{"從未使用", models.Timestamp{}, true},
+		{"兩分鐘前", models.Timestamp{Time: now.Add(-2 * time.Minute)}, true},
+		{"剛使用過", models.Timestamp{Time: now.Add(-10 * time.Second)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 創建模擬儲存庫與服務
+			mockRepo := new(MockAPIKeyRepository)
+			service := service.NewAPIKeyService(mockRepo)
+
+			mockRepo.On("GetAPIKeyByHash", mock.Anything, auth.HashAPIKey(key)).Return(models.APIKey{ID: 1, Name: "pos-01", LastUsedAt: tt.lastUsed}, nil)
+			mockRepo.On("TouchAPIKey", mock.Anything, int64(1), mock.AnythingOfType("time.Time")).Return(nil)
+
+			_, err := service.Verify(context.Background(), key)
+
+			require.NoError(t, err)
+			if tt.touched {
+				mockRepo.AssertCalled(t, "TouchAPIKey", mock.Anything, int64(1), mock.AnythingOfType("time.Time"))
+			} else {
+				mockRepo.AssertNotCalled(t, "TouchAPIKey", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+// 測試格式不符的金鑰不查詢資料庫
+func TestVerifyAPIKeyMalformed(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockAPIKeyRepository)
+
+	// 創建 API 金鑰服務
+	service := service.NewAPIKeyService(mockRepo)
+
+	_, err := service.Verify(systemContext(), "not-a-key")
+
+	assert.ErrorIs(t, err, auth.ErrInvalidAPIKey)
+	mockRepo.AssertNotCalled(t, "GetAPIKeyByHash", mock.Anything, mock.Anything)
+}
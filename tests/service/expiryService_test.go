@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"main/internal/models"
+	"main/internal/service"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// 模擬過期儲存庫
+type MockExpiryRepository struct {
+	mock.Mock
+}
+
+func (m *MockExpiryRepository) GetExpiringByWarehouse(ctx context.Context, from, to models.Date) ([]models.ExpiringStock, error) {
+	args := m.Called(ctx, from, to)
+	stocks, _ := args.Get(0).([]models.ExpiringStock)
+	return stocks, args.Error(1)
+}
+
+func (m *MockExpiryRepository) GetExpiringByLot(ctx context.Context, from, to models.Date) ([]models.ExpiringStock, error) {
+	args := m.Called(ctx, from, to)
+	stocks, _ := args.Get(0).([]models.ExpiringStock)
+	return stocks, args.Error(1)
+}
+
+func (m *MockExpiryRepository) MarkExpired(ctx context.Context, today models.Date, now time.Time) ([]models.ExpiredStock, error) {
+	args := m.Called(ctx, today, now)
+	expired, _ := args.Get(0).([]models.ExpiredStock)
+	return expired, args.Error(1)
+}
+
+// 測試報表預設依倉庫分組，相鄰的同一倉庫合為一組並計算剩餘天數
+func TestGetExpiringReportByWarehouse(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockExpiryRepository)
+
+	// 創建過期服務
+	service := service.NewExpiryService(mockRepo, nil)
+
+	today := models.DateOf(time.Now().UTC())
+	cutoff := models.Date{Time: today.AddDate(0, 0, 30)}
+	in3 := models.Date{Time: today.AddDate(0, 0, 3)}
+
+	mockRepo.On("GetExpiringByWarehouse", mock.Anything, today, cutoff).Return([]models.ExpiringStock{
+		{WarehouseID: 1, WarehouseCode: "MAIN", ProductID: 1, SkuCode: "SKU001", Expiration: today, Quantity: 4},
+		{WarehouseID: 1, WarehouseCode: "MAIN", ProductID: 2, SkuCode: "SKU002", Expiration: in3, Quantity: 6},
+		{WarehouseID: 2, WarehouseCode: "EAST", ProductID: 1, SkuCode: "SKU001", Expiration: today, Quantity: 1},
+	}, nil)
+
+	// 調用服務方法
+	report, err := service.GetExpiringReport(systemContext(), models.ExpiringQuery{WithinDays: 30})
+
+	// 驗證結果
+	require.NoError(t, err)
+	assert.Equal(t, models.ExpiringByWarehouse, report.GroupBy)
+	assert.Equal(t, cutoff, report.Cutoff)
+	assert.Equal(t, 11, report.Quantity)
+	require.Len(t, report.Groups, 2)
+	assert.Equal(t, "MAIN", report.Groups[0].WarehouseCode)
+	assert.Equal(t, 10, report.Groups[0].Quantity)
+	require.Len(t, report.Groups[0].Items, 2)
+	assert.Equal(t, 0, report.Groups[0].Items[0].DaysLeft)
+	assert.Equal(t, 3, report.Groups[0].Items[1].DaysLeft)
+	assert.Equal(t, "EAST", report.Groups[1].WarehouseCode)
+
+	// 驗證模擬儲存庫方法被調用
+	mockRepo.AssertExpectations(t)
+}
+
+// 測試依批次分組與無效的查詢條件
+func TestGetExpiringReportByLot(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockExpiryRepository)
+
+	// 創建過期服務
+	service := service.NewExpiryService(mockRepo, nil)
+
+	today := models.DateOf(time.Now().UTC())
+	mockRepo.On("GetExpiringByLot", mock.Anything, today, models.Date{Time: today.AddDate(0, 0, 7)}).Return([]models.ExpiringStock{
+		{LotID: 8, LotNumber: "L1", ProductID: 1, Expiration: today, Quantity: 2},
+		{LotID: 9, LotNumber: "L2", ProductID: 1, Expiration: today, Quantity: 5},
+	}, nil)
+
+	// 調用服務方法
+	report, err := service.GetExpiringReport(systemContext(), models.ExpiringQuery{WithinDays: 7, GroupBy: models.ExpiringByLot})
+
+	// 驗證結果
+	require.NoError(t, err)
+	require.Len(t, report.Groups, 2)
+	assert.Equal(t, "L1", report.Groups[0].LotNumber)
+	assert.Empty(t, report.Groups[0].WarehouseCode)
+
+	var validationErr *models.ValidationError
+	_, err = service.GetExpiringReport(systemContext(), models.ExpiringQuery{WithinDays: 7, GroupBy: "location"})
+	assert.ErrorAs(t, err, &validationErr)
+	_, err = service.GetExpiringReport(systemContext(), models.ExpiringQuery{WithinDays: 400})
+	assert.ErrorAs(t, err, &validationErr)
+
+	// 驗證模擬儲存庫方法被調用
+	mockRepo.AssertExpectations(t)
+}
+
+// 測試清理為每個標記的批次與產品發出事件並返回摘要
+func TestSweepExpired(t *testing.T) {
+	// 創建模擬儲存庫與通知管道
+	mockRepo := new(MockExpiryRepository)
+	notifier := new(MockNotifier)
+
+	// 創建過期服務
+	service := service.NewExpiryService(mockRepo, notifier)
+
+	now := time.Date(2025, 4, 1, 0, 5, 0, 0, time.UTC)
+	mockRepo.On("MarkExpired", mock.Anything, models.MustParseDate("2025-04-01"), now).Return([]models.ExpiredStock{
+		{ProductID: 1, LotID: 8, LotNumber: "L1", Quantity: 4},
+		{ProductID: 1, LotID: 9, LotNumber: "L2", Quantity: 3},
+		{ProductID: 2, Quantity: 10},
+	}, nil)
+	notifier.On("NotifyExpired", mock.Anything, mock.MatchedBy(func(event models.ExpiredStockEvent) bool {
+		return event.OccurredAt.Equal(now)
+	})).Return(errors.New("佇列已滿"))
+
+	// 調用服務方法
+	result, err := service.SweepExpired(systemContext(), now)
+
+	// 驗證通知失敗不影響結果
+	require.NoError(t, err)
+	assert.Equal(t, models.ExpirySweepResult{Lots: 2, Products: 1, Quantity: 17}, result)
+	notifier.AssertNumberOfCalls(t, "NotifyExpired", 3)
+
+	// 驗證模擬儲存庫方法被調用
+	mockRepo.AssertExpectations(t)
+}
@@ -1,46 +1,145 @@
 package tests
 
 import (
+	"context"
 	"errors"
+	"main/internal/auth"
 	"main/internal/models"
 	"main/internal/repository"
 	"main/internal/service"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
+// systemContext 返回以系統主體執行的 context，服務層的權限檢查需要通過驗證的主體
+func systemContext() context.Context {
+	return auth.WithPrincipal(context.Background(), auth.System)
+}
+
 // 模擬儲存庫
 type MockProductRepository struct {
 	mock.Mock
 }
 
-func (m *MockProductRepository) GetAll() ([]models.Product, error) {
-	args := m.Called()
-	return args.Get(0).([]models.Product), args.Error(1)
+func (m *MockProductRepository) GetAll(ctx context.Context, query models.ProductQuery) ([]models.Product, int, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).([]models.Product), args.Int(1), args.Error(2)
+}
+
+func (m *MockProductRepository) GetByID(ctx context.Context, id int64) (models.Product, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.Product), args.Error(1)
+}
+
+func (m *MockProductRepository) GetBySku(ctx context.Context, skuCode string) (models.Product, error) {
+	args := m.Called(ctx, skuCode)
+	return args.Get(0).(models.Product), args.Error(1)
 }
 
-func (m *MockProductRepository) GetByID(id int64) (models.Product, error) {
-	args := m.Called(id)
+func (m *MockProductRepository) Create(ctx context.Context, product models.Product) (models.Product, error) {
+	args := m.Called(ctx, product)
 	return args.Get(0).(models.Product), args.Error(1)
 }
 
-func (m *MockProductRepository) Create(product models.Product) (models.Product, error) {
-	args := m.Called(product)
+func (m *MockProductRepository) Update(ctx context.Context, id int64, version int, patch models.ProductPatch) (models.Product, error) {
+	args := m.Called(ctx, id, version, patch)
 	return args.Get(0).(models.Product), args.Error(1)
 }
 
-func (m *MockProductRepository) UpdateNonBlank(id int64, product models.Product) (models.Product, error) {
-	args := m.Called(id, product)
+func (m *MockProductRepository) Delete(ctx context.Context, id int64, version int) error {
+	args := m.Called(ctx, id, version)
+	return args.Error(0)
+}
+
+func (m *MockProductRepository) GetDeleted(ctx context.Context, query models.ProductQuery) ([]models.Product, int, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).([]models.Product), args.Int(1), args.Error(2)
+}
+
+func (m *MockProductRepository) Restore(ctx context.Context, id int64) (models.Product, error) {
+	args := m.Called(ctx, id)
 	return args.Get(0).(models.Product), args.Error(1)
 }
 
-func (m *MockProductRepository) Delete(id int64) error {
-	args := m.Called(id)
+func (m *MockProductRepository) Purge(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
+func (m *MockProductRepository) PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockProductRepository) GetHistory(ctx context.Context, id int64, query models.ProductQuery) ([]models.ProductHistory, int, error) {
+	args := m.Called(ctx, id, query)
+	return args.Get(0).([]models.ProductHistory), args.Int(1), args.Error(2)
+}
+
+func (m *MockProductRepository) GetAsOf(ctx context.Context, id int64, asOf time.Time) (models.Product, error) {
+	args := m.Called(ctx, id, asOf)
+	return args.Get(0).(models.Product), args.Error(1)
+}
+
+func (m *MockProductRepository) GetLocationStock(ctx context.Context, ids []int64) (map[int64][]models.LocationStock, error) {
+	args := m.Called(ctx, ids)
+	stock, _ := args.Get(0).(map[int64][]models.LocationStock)
+	return stock, args.Error(1)
+}
+
+func (m *MockProductRepository) AdjustStock(ctx context.Context, id int64, delta int) (models.Product, error) {
+	args := m.Called(ctx, id, delta)
+	return args.Get(0).(models.Product), args.Error(1)
+}
+
+func (m *MockProductRepository) GetLowStock(ctx context.Context, query models.ProductQuery) ([]models.Product, int, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).([]models.Product), args.Int(1), args.Error(2)
+}
+
+func (m *MockProductRepository) CreateBatch(ctx context.Context, inputs []models.Product) ([]models.Product, error) {
+	args := m.Called(ctx, inputs)
+	products, _ := args.Get(0).([]models.Product)
+	return products, args.Error(1)
+}
+
+func (m *MockProductRepository) UpdateBatch(ctx context.Context, items []models.ProductBatchUpdate) ([]models.Product, error) {
+	args := m.Called(ctx, items)
+	products, _ := args.Get(0).([]models.Product)
+	return products, args.Error(1)
+}
+
+func (m *MockProductRepository) DeleteBatch(ctx context.Context, refs []models.ProductRef) error {
+	args := m.Called(ctx, refs)
+	return args.Error(0)
+}
+
+// Stream 依序以預設的產品調用 fn，再返回預設的錯誤
+func (m *MockProductRepository) Stream(ctx context.Context, query models.ProductQuery, fn func(models.Product) error) error {
+	args := m.Called(ctx, query)
+	products, _ := args.Get(0).([]models.Product)
+	for _, product := range products {
+		if err := fn(product); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockProductRepository) UpsertBySku(ctx context.Context, input models.Product) (models.Product, bool, error) {
+	args := m.Called(ctx, input)
+	return args.Get(0).(models.Product), args.Bool(1), args.Error(2)
+}
+
+func (m *MockProductRepository) ExistingSkuCodes(ctx context.Context, skuCodes []string) (map[string]bool, error) {
+	args := m.Called(ctx, skuCodes)
+	existing, _ := args.Get(0).(map[string]bool)
+	return existing, args.Error(1)
+}
+
 // 測試獲取所有產品
 func TestGetProducts(t *testing.T) {
 	// 創建模擬儲存庫
@@ -51,19 +150,138 @@ func TestGetProducts(t *testing.T) {
 
 	// 模擬產品數據
 	expectedProducts := []models.Product{
-		{SkuCode: "SKU001", SkuName: "產品 1", SkuAmount: 10},
-		{SkuCode: "SKU002", SkuName: "產品 2", SkuAmount: 20},
+		{ID: 1, SkuCode: "SKU001", SkuName: "產品 1", SkuAmount: 10},
+		{ID: 2, SkuCode: "SKU002", SkuName: "產品 2", SkuAmount: 20},
+	}
+	locations := []models.LocationStock{{ProductID: 1, LocationID: 1, LocationCode: "DEFAULT", WarehouseID: 1, WarehouseCode: "MAIN", Quantity: 10}}
+
+	// 未指定的分頁與排序應套用預設值
+	expectedQuery := models.ProductQuery{
+		PageSize: models.DefaultPageSize,
+		SortBy:   "id",
+		SortDir:  models.SortAsc,
+	}
+
+	// 設置模擬儲存庫預期行為
+	mockRepo.On("GetAll", mock.Anything, expectedQuery).Return(expectedProducts, 2, nil)
+	// 整頁的儲位庫存以單次查詢讀取
+	mockRepo.On("GetLocationStock", mock.Anything, []int64{1, 2}).Return(map[int64][]models.LocationStock{1: locations}, nil)
+
+	// 調用服務方法
+	page, err := service.GetProducts(systemContext(), models.ProductQuery{})
+
+	// 驗證結果
+	assert.Nil(t, err)
+	assert.Len(t, page.Items, 2)
+	assert.Equal(t, locations, page.Items[0].Locations)
+	assert.Empty(t, page.Items[1].Locations)
+	assert.Equal(t, 2, page.Total)
+	assert.Equal(t, models.DefaultPageSize, page.PageSize)
+
+	// 驗證模擬儲存庫方法被調用
+	mockRepo.AssertExpectations(t)
+}
+
+// 測試獲取產品列表 - 分頁大小超過上限
+func TestGetProductsClampsPageSize(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockProductRepository)
+
+	// 創建產品服務
+	service := service.NewProductService(mockRepo)
+
+	expectedQuery := models.ProductQuery{
+		PageSize: models.MaxPageSize,
+		Offset:   40,
+		SortBy:   "sku_name",
+		SortDir:  models.SortDesc,
 	}
 
 	// 設置模擬儲存庫預期行為
-	mockRepo.On("GetAll").Return(expectedProducts, nil)
+	mockRepo.On("GetAll", mock.Anything, expectedQuery).Return([]models.Product{}, 0, nil)
+
+	// 調用服務方法
+	page, err := service.GetProducts(systemContext(), models.ProductQuery{
+		PageSize: 1000,
+		Offset:   40,
+		SortBy:   "sku_name",
+		SortDir:  models.SortDesc,
+	})
+
+	// 驗證結果
+	assert.Nil(t, err)
+	assert.Equal(t, models.MaxPageSize, page.PageSize)
+	assert.Equal(t, 40, page.Offset)
+
+	// 驗證模擬儲存庫方法被調用
+	mockRepo.AssertExpectations(t)
+}
+
+// 測試鍵集分頁 - 多讀取的一筆用於判斷是否有下一頁
+func TestGetProductsByKeyset(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockProductRepository)
+
+	// 創建產品服務
+	service := service.NewProductService(mockRepo)
+
+	keyset := &models.ProductKeyset{Value: "SKU002", ID: 2}
+
+	// 儲存庫應被要求多讀取一筆
+	expectedQuery := models.ProductQuery{
+		PageSize: 3,
+		SortBy:   "sku_code",
+		SortDir:  models.SortAsc,
+		Keyset:   keyset,
+	}
+	mockRepo.On("GetAll", mock.Anything, expectedQuery).Return([]models.Product{
+		{ID: 3, SkuCode: "SKU003"},
+		{ID: 4, SkuCode: "SKU004"},
+		{ID: 5, SkuCode: "SKU005"},
+	}, 10, nil)
+	// 多讀取的一筆不需要讀取儲位庫存
+	mockRepo.On("GetLocationStock", mock.Anything, []int64{3, 4}).Return(map[int64][]models.LocationStock{}, nil)
 
 	// 調用服務方法
-	products, err := service.GetProducts()
+	page, err := service.GetProducts(systemContext(), models.ProductQuery{PageSize: 2, SortBy: "sku_code", Keyset: keyset})
 
 	// 驗證結果
 	assert.Nil(t, err)
-	assert.Equal(t, expectedProducts, products)
+	assert.Len(t, page.Items, 2)
+	assert.Equal(t, "SKU004", page.Items[1].SkuCode)
+	assert.Equal(t, 2, page.PageSize)
+	assert.True(t, page.HasNext)
+	assert.True(t, page.HasPrev)
+
+	// 驗證模擬儲存庫方法被調用
+	mockRepo.AssertExpectations(t)
+}
+
+// 測試鍵集分頁 - 往前翻到第一頁
+func TestGetProductsByKeysetBackward(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockProductRepository)
+
+	// 創建產品服務
+	service := service.NewProductService(mockRepo)
+
+	keyset := &models.ProductKeyset{ID: 3, Backward: true}
+
+	// 設置模擬儲存庫預期行為 - 只剩兩筆，表示已到第一頁
+	mockRepo.On("GetAll", mock.Anything, mock.Anything).Return([]models.Product{
+		{ID: 1, SkuCode: "SKU001"},
+		{ID: 2, SkuCode: "SKU002"},
+	}, 10, nil)
+	mockRepo.On("GetLocationStock", mock.Anything, []int64{1, 2}).Return(map[int64][]models.LocationStock{}, nil)
+
+	// 調用服務方法
+	page, err := service.GetProducts(systemContext(), models.ProductQuery{PageSize: 2, Keyset: keyset})
+
+	// 驗證結果
+	assert.Nil(t, err)
+	assert.Len(t, page.Items, 2)
+	assert.True(t, page.HasNext)
+	assert.False(t, page.HasPrev)
 
 	// 驗證模擬儲存庫方法被調用
 	mockRepo.AssertExpectations(t)
@@ -79,14 +297,14 @@ func TestGetProductsError(t *testing.T) {
 
 	// 設置模擬儲存庫預期行為 - 返回錯誤
 	expectedError := errors.New("資料庫連接錯誤")
-	mockRepo.On("GetAll").Return([]models.Product{}, expectedError)
+	mockRepo.On("GetAll", mock.Anything, mock.Anything).Return([]models.Product{}, 0, expectedError)
 
 	// 調用服務方法
-	products, err := service.GetProducts()
+	page, err := service.GetProducts(systemContext(), models.ProductQuery{})
 
 	// 驗證結果
 	assert.Equal(t, expectedError, err)
-	assert.Empty(t, products)
+	assert.Empty(t, page.Items)
 
 	// 驗證模擬儲存庫方法被調用
 	mockRepo.AssertExpectations(t)
@@ -102,18 +320,25 @@ func TestGetProduct(t *testing.T) {
 
 	// 模擬產品數據
 	expectedProduct := models.Product{
+		ID:        1,
 		SkuCode:   "SKU001",
 		SkuName:   "產品 1",
 		SkuAmount: 10,
 	}
+	locations := []models.LocationStock{
+		{ProductID: 1, LocationID: 1, LocationCode: "DEFAULT", WarehouseID: 1, WarehouseCode: "MAIN", Quantity: 6},
+		{ProductID: 1, LocationID: 2, LocationCode: "A-01", WarehouseID: 1, WarehouseCode: "MAIN", Quantity: 4},
+	}
 
 	// 設置模擬儲存庫預期行為
-	mockRepo.On("GetByID", int64(1)).Return(expectedProduct, nil)
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(expectedProduct, nil)
+	mockRepo.On("GetLocationStock", mock.Anything, []int64{1}).Return(map[int64][]models.LocationStock{1: locations}, nil)
 
 	// 調用服務方法
-	product, err := service.GetProduct(1)
+	product, err := service.GetProduct(systemContext(), 1)
 
 	// 驗證結果
+	expectedProduct.Locations = locations
 	assert.Nil(t, err)
 	assert.Equal(t, expectedProduct, product)
 
@@ -130,10 +355,10 @@ func TestGetProductNotFound(t *testing.T) {
 	service := service.NewProductService(mockRepo)
 
 	// 設置模擬儲存庫預期行為 - 返回未找到錯誤
-	mockRepo.On("GetByID", int64(999)).Return(models.Product{}, repository.ErrProductNotFound)
+	mockRepo.On("GetByID", mock.Anything, int64(999)).Return(models.Product{}, repository.ErrProductNotFound)
 
 	// 調用服務方法
-	product, err := service.GetProduct(999)
+	product, err := service.GetProduct(systemContext(), 999)
 
 	// 驗證結果
 	assert.Equal(t, repository.ErrProductNotFound, err)
@@ -165,10 +390,10 @@ func TestCreateProduct(t *testing.T) {
 	}
 
 	// 設置模擬儲存庫預期行為
-	mockRepo.On("Create", productInput).Return(expectedProduct, nil)
+	mockRepo.On("Create", mock.Anything, productInput).Return(expectedProduct, nil)
 
 	// 調用服務方法
-	product, err := service.CreateProduct(productInput)
+	product, err := service.CreateProduct(systemContext(), productInput)
 
 	// 驗證結果
 	assert.Nil(t, err)
@@ -195,10 +420,10 @@ func TestCreateProductError(t *testing.T) {
 
 	// 設置模擬儲存庫預期行為 - 返回錯誤
 	expectedError := errors.New("資料庫錯誤")
-	mockRepo.On("Create", productInput).Return(models.Product{}, expectedError)
+	mockRepo.On("Create", mock.Anything, productInput).Return(models.Product{}, expectedError)
 
 	// 調用服務方法
-	product, err := service.CreateProduct(productInput)
+	product, err := service.CreateProduct(systemContext(), productInput)
 
 	// 驗證結果
 	assert.Equal(t, expectedError, err)
@@ -221,6 +446,7 @@ func TestUpdateProduct(t *testing.T) {
 		SkuCode:   "SKU001",
 		SkuName:   "原產品",
 		SkuAmount: 10,
+		Version:   2,
 	}
 
 	// 更新後的產品
@@ -238,11 +464,11 @@ func TestUpdateProduct(t *testing.T) {
 	}
 
 	// 設置模擬儲存庫預期行為
-	mockRepo.On("GetByID", int64(1)).Return(existingProduct, nil)
-	mockRepo.On("UpdateNonBlank", int64(1), updateInput).Return(updatedProduct, nil)
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(existingProduct, nil)
+	mockRepo.On("Update", mock.Anything, int64(1), 2, models.ReplacePatch(updateInput)).Return(updatedProduct, nil)
 
 	// 調用服務方法
-	product, err := service.UpdateProduct(1, updateInput)
+	product, err := service.UpdateProduct(systemContext(), 1, 2, updateInput)
 
 	// 驗證結果
 	assert.Nil(t, err)
@@ -268,10 +494,10 @@ func TestUpdateProductNotFound(t *testing.T) {
 	}
 
 	// 設置模擬儲存庫預期行為 - 返回未找到錯誤
-	mockRepo.On("GetByID", int64(999)).Return(models.Product{}, repository.ErrProductNotFound)
+	mockRepo.On("GetByID", mock.Anything, int64(999)).Return(models.Product{}, repository.ErrProductNotFound)
 
 	// 調用服務方法
-	product, err := service.UpdateProduct(999, updateInput)
+	product, err := service.UpdateProduct(systemContext(), 999, 1, updateInput)
 
 	// 驗證結果
 	assert.Equal(t, repository.ErrProductNotFound, err)
@@ -280,8 +506,137 @@ func TestUpdateProductNotFound(t *testing.T) {
 	// 驗證模擬儲存庫方法被調用
 	mockRepo.AssertExpectations(t)
 
-	// 確保 UpdateNonBlank 沒有被調用
-	mockRepo.AssertNotCalled(t, "UpdateNonBlank")
+	// 確保 Update 沒有被調用
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// 測試以過期版本更新產品
+func TestUpdateProductVersionConflict(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockProductRepository)
+
+	// 創建產品服務
+	service := service.NewProductService(mockRepo)
+
+	// 資料庫中的版本已經被其他請求更新
+	existingProduct := models.Product{
+		SkuCode:   "SKU001",
+		SkuName:   "原產品",
+		SkuAmount: 10,
+		Version:   3,
+	}
+
+	// 設置模擬儲存庫預期行為
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(existingProduct, nil)
+
+	// 調用服務方法
+	product, err := service.UpdateProduct(systemContext(), 1, 2, models.Product{SkuCode: "SKU001", SkuAmount: 5})
+
+	// 驗證結果
+	assert.Equal(t, repository.ErrVersionConflict, err)
+	assert.Empty(t, product)
+
+	// 確保 Update 沒有被調用
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// 測試 Merge Patch 只更新出現的欄位
+func TestMergePatchProduct(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockProductRepository)
+
+	// 創建產品服務
+	service := service.NewProductService(mockRepo)
+
+	existingProduct := models.Product{
+		SkuCode:    "SKU001",
+		SkuName:    "原產品",
+		SkuAmount:  10,
+		Expiration: models.MustParseDate("2025-12-31"),
+		Version:    1,
+	}
+
+	// 只修改名稱並清除到期日，庫存保持不變
+	patch, err := models.DecodeMergePatch([]byte(`{"sku_name": "新名稱", "expiration": null}`))
+	assert.NoError(t, err)
+
+	updatedProduct := existingProduct
+	updatedProduct.SkuName = "新名稱"
+	updatedProduct.Expiration = models.Date{}
+	updatedProduct.Version = 2
+
+	// 設置模擬儲存庫預期行為
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(existingProduct, nil)
+	mockRepo.On("Update", mock.Anything, int64(1), 1, patch).Return(updatedProduct, nil)
+
+	// 調用服務方法
+	product, err := service.MergePatchProduct(systemContext(), 1, 1, patch)
+
+	// 驗證結果
+	assert.NoError(t, err)
+	assert.Equal(t, updatedProduct, product)
+
+	// 驗證模擬儲存庫方法被調用
+	mockRepo.AssertExpectations(t)
+}
+
+// 測試 Merge Patch 將必填欄位設為 null
+func TestMergePatchProductNullRequiredField(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockProductRepository)
+
+	// 創建產品服務
+	service := service.NewProductService(mockRepo)
+
+	patch, err := models.DecodeMergePatch([]byte(`{"sku_amount": null}`))
+	assert.NoError(t, err)
+
+	// 設置模擬儲存庫預期行為
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(models.Product{SkuCode: "SKU001", Version: 1}, nil)
+
+	// 調用服務方法
+	_, err = service.MergePatchProduct(systemContext(), 1, 1, patch)
+
+	// 驗證結果
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+
+	// 確保 Update 沒有被調用
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// 測試 JSON Patch 以讀取到的版本寫入
+func TestJSONPatchProduct(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockProductRepository)
+
+	// 創建產品服務
+	service := service.NewProductService(mockRepo)
+
+	existingProduct := models.Product{SkuCode: "SKU001", SkuName: "原產品", SkuAmount: 10, Version: 4}
+
+	ops, err := models.DecodeJSONPatch([]byte(`[
+		{"op": "test", "path": "/sku_amount", "value": 10},
+		{"op": "replace", "path": "/sku_amount", "value": 0}
+	]`))
+	assert.NoError(t, err)
+
+	expectedPatch := models.ProductPatch{SkuAmount: models.Nullable[int]{Set: true, Value: 0}}
+	updatedProduct := models.Product{SkuCode: "SKU001", SkuName: "原產品", SkuAmount: 0, Version: 5}
+
+	// 設置模擬儲存庫預期行為 - If-Match 為 * 時仍以讀取到的版本 4 寫入
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(existingProduct, nil)
+	mockRepo.On("Update", mock.Anything, int64(1), 4, expectedPatch).Return(updatedProduct, nil)
+
+	// 調用服務方法
+	product, err := service.JSONPatchProduct(systemContext(), 1, 0, ops)
+
+	// 驗證結果
+	assert.NoError(t, err)
+	assert.Equal(t, updatedProduct, product)
+
+	// 驗證模擬儲存庫方法被調用
+	mockRepo.AssertExpectations(t)
 }
 
 // 測試刪除產品
@@ -300,11 +655,11 @@ func TestDeleteProduct(t *testing.T) {
 	}
 
 	// 設置模擬儲存庫預期行為
-	mockRepo.On("GetByID", int64(1)).Return(existingProduct, nil)
-	mockRepo.On("Delete", int64(1)).Return(nil)
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(existingProduct, nil)
+	mockRepo.On("Delete", mock.Anything, int64(1), 0).Return(nil)
 
 	// 調用服務方法
-	err := service.DeleteProduct(1)
+	err := service.DeleteProduct(systemContext(), 1, 0)
 
 	// 驗證結果
 	assert.Nil(t, err)
@@ -322,10 +677,10 @@ func TestDeleteProductNotFound(t *testing.T) {
 	service := service.NewProductService(mockRepo)
 
 	// 設置模擬儲存庫預期行為 - 返回未找到錯誤
-	mockRepo.On("GetByID", int64(999)).Return(models.Product{}, repository.ErrProductNotFound)
+	mockRepo.On("GetByID", mock.Anything, int64(999)).Return(models.Product{}, repository.ErrProductNotFound)
 
 	// 調用服務方法
-	err := service.DeleteProduct(999)
+	err := service.DeleteProduct(systemContext(), 999, 1)
 
 	// 驗證結果
 	assert.Equal(t, repository.ErrProductNotFound, err)
@@ -336,3 +691,66 @@ func TestDeleteProductNotFound(t *testing.T) {
 	// 確保 Delete 沒有被調用
 	mockRepo.AssertNotCalled(t, "Delete")
 }
+
+// 測試服務層拒絕權限不足的主體，不呼叫儲存庫
+func TestDeleteProductForbidden(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockProductRepository)
+
+	// 創建產品服務
+	service := service.NewProductService(mockRepo)
+
+	// 只有 viewer 角色的主體
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "alice", Roles: []string{"viewer"}})
+
+	// 調用服務方法
+	err := service.DeleteProduct(ctx, 1, 1)
+
+	// 驗證結果
+	assert.ErrorIs(t, err, auth.ErrForbidden)
+	mockRepo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// 測試 context 中沒有主體時服務層拒絕呼叫，不呼叫儲存庫
+func TestServiceWithoutPrincipalForbidden(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockProductRepository)
+
+	// 創建產品服務
+	service := service.NewProductService(mockRepo)
+
+	// 調用服務方法
+	_, err := service.GetProducts(context.Background(), models.ProductQuery{PageSize: models.DefaultPageSize})
+	assert.ErrorIs(t, err, auth.ErrForbidden)
+
+	_, err = service.GetProduct(context.Background(), 1)
+	assert.ErrorIs(t, err, auth.ErrForbidden)
+
+	// 驗證結果
+	mockRepo.AssertNotCalled(t, "GetAll", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+}
+
+// 測試調整庫存的差額為 0 時不寫入
+func TestAdjustStockZeroDelta(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockProductRepository)
+
+	// 創建產品服務
+	service := service.NewProductService(mockRepo)
+
+	mockRepo.On("AdjustStock", mock.Anything, int64(1), 5).Return(models.Product{ID: 1, SkuAmount: 15}, nil)
+
+	// 調用服務方法
+	_, err := service.AdjustStock(systemContext(), 1, 0)
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+
+	product, err := service.AdjustStock(systemContext(), 1, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, 15, product.SkuAmount)
+
+	// 驗證模擬儲存庫方法只被調用一次
+	mockRepo.AssertNumberOfCalls(t, "AdjustStock", 1)
+}
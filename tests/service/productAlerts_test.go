@@ -0,0 +1,34 @@
+package tests
+
+import (
+	"main/internal/models"
+	"main/internal/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// 測試低庫存報表套用預設分頁
+func TestGetLowStockProducts(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockProductRepository)
+
+	// 創建產品服務
+	service := service.NewProductService(mockRepo)
+
+	products := []models.Product{{ID: 2, SkuAmount: 1, ReorderPoint: 10}, {ID: 1, SkuAmount: 4, ReorderPoint: 5}}
+	mockRepo.On("GetLowStock", mock.Anything, models.ProductQuery{PageSize: models.DefaultPageSize}).Return(products, 3, nil)
+
+	// 調用服務方法
+	page, err := service.GetLowStockProducts(systemContext(), models.ProductQuery{})
+
+	// 驗證結果
+	assert.NoError(t, err)
+	assert.Equal(t, products, page.Items)
+	assert.Equal(t, 3, page.Total)
+	assert.True(t, page.HasNext)
+
+	// 驗證模擬儲存庫方法被調用
+	mockRepo.AssertExpectations(t)
+}
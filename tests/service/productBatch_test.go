@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"errors"
+	"main/internal/models"
+	"main/internal/repository"
+	"main/internal/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// 測試非原子批量創建：無效項目單獨失敗，其餘一次寫入
+func TestBatchCreateProductsPartial(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockProductRepository)
+
+	// 創建產品服務
+	service := service.NewProductService(mockRepo)
+
+	inputs := []models.Product{
+		{SkuCode: "SKU001", SkuAmount: 1},
+		{SkuCode: "", SkuAmount: 2},
+		{SkuCode: "SKU003", SkuAmount: 3},
+	}
+
+	// 設置模擬儲存庫預期行為 - 只寫入有效的項目
+	mockRepo.On("CreateBatch", mock.Anything, []models.Product{inputs[0], inputs[2]}).
+		Return([]models.Product{{ID: 1, SkuCode: "SKU001"}, {ID: 2, SkuCode: "SKU003"}}, nil)
+
+	// 調用服務方法
+	outcomes, err := service.BatchCreateProducts(systemContext(), inputs, false)
+
+	// 驗證結果
+	assert.NoError(t, err)
+	assert.Len(t, outcomes, 3)
+	assert.Equal(t, 1, outcomes[0].Product.ID)
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, outcomes[1].Err, &validationErr)
+	assert.Equal(t, 2, outcomes[2].Product.ID)
+
+	// 驗證模擬儲存庫方法被調用
+	mockRepo.AssertExpectations(t)
+}
+
+// 測試非原子批量創建：整批寫入失敗時逐項重試
+func TestBatchCreateProductsFallback(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockProductRepository)
+
+	// 創建產品服務
+	service := service.NewProductService(mockRepo)
+
+	inputs := []models.Product{{SkuCode: "SKU001"}, {SkuCode: "SKU002"}}
+	writeErr := errors.New("寫入失敗")
+
+	// 設置模擬儲存庫預期行為
+	mockRepo.On("CreateBatch", mock.Anything, inputs).Return(nil, writeErr)
+	mockRepo.On("Create", mock.Anything, inputs[0]).Return(models.Product{ID: 1, SkuCode: "SKU001"}, nil)
+	mockRepo.On("Create", mock.Anything, inputs[1]).Return(models.Product{}, writeErr)
+
+	// 調用服務方法
+	outcomes, err := service.BatchCreateProducts(systemContext(), inputs, false)
+
+	// 驗證結果
+	assert.NoError(t, err)
+	assert.NoError(t, outcomes[0].Err)
+	assert.Equal(t, writeErr, outcomes[1].Err)
+
+	// 驗證模擬儲存庫方法被調用
+	mockRepo.AssertExpectations(t)
+}
+
+// 測試原子批量創建：任一項無效時不寫入
+func TestBatchCreateProductsAtomicInvalid(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockProductRepository)
+
+	// 創建產品服務
+	service := service.NewProductService(mockRepo)
+
+	inputs := []models.Product{{SkuCode: "SKU001"}, {SkuCode: "SKU002", SkuAmount: -1}}
+
+	// 調用服務方法
+	outcomes, err := service.BatchCreateProducts(systemContext(), inputs, true)
+
+	// 驗證結果
+	assert.Nil(t, outcomes)
+	var itemErr *models.BatchItemError
+	assert.ErrorAs(t, err, &itemErr)
+	assert.Equal(t, 1, itemErr.Index)
+
+	// 確保沒有寫入
+	mockRepo.AssertNotCalled(t, "CreateBatch", mock.Anything, mock.Anything)
+}
+
+// 測試原子批量更新在單一交易中執行
+func TestBatchUpdateProductsAtomic(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockProductRepository)
+
+	// 創建產品服務
+	service := service.NewProductService(mockRepo)
+
+	inputs := []models.Product{
+		{ID: 1, Version: 2, SkuCode: "SKU001", SkuAmount: 5},
+		{ID: 2, Version: 1, SkuCode: "SKU002", SkuAmount: 6},
+	}
+	conflict := &models.BatchItemError{Index: 1, Err: repository.ErrVersionConflict}
+
+	// 設置模擬儲存庫預期行為 - 第二項版本衝突，整批回滾
+	mockRepo.On("UpdateBatch", mock.Anything, []models.ProductBatchUpdate{
+		{ID: 1, Version: 2, Patch: models.ReplacePatch(inputs[0])},
+		{ID: 2, Version: 1, Patch: models.ReplacePatch(inputs[1])},
+	}).Return(nil, conflict)
+
+	// 調用服務方法
+	outcomes, err := service.BatchUpdateProducts(systemContext(), inputs, true)
+
+	// 驗證結果
+	assert.Nil(t, outcomes)
+	assert.ErrorIs(t, err, repository.ErrVersionConflict)
+
+	// 驗證模擬儲存庫方法被調用
+	mockRepo.AssertExpectations(t)
+}
+
+// 測試非原子批量刪除逐項返回結果
+func TestBatchDeleteProductsPartial(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockProductRepository)
+
+	// 創建產品服務
+	service := service.NewProductService(mockRepo)
+
+	refs := []models.ProductRef{{ID: 1, Version: 1}, {ID: 2}, {ID: 999, Version: 1}}
+
+	// 設置模擬儲存庫預期行為
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(models.Product{ID: 1, Version: 1}, nil)
+	mockRepo.On("Delete", mock.Anything, int64(1), 1).Return(nil)
+	mockRepo.On("GetByID", mock.Anything, int64(999)).Return(models.Product{}, repository.ErrProductNotFound)
+
+	// 調用服務方法
+	outcomes, err := service.BatchDeleteProducts(systemContext(), refs, false)
+
+	// 驗證結果
+	assert.NoError(t, err)
+	assert.NoError(t, outcomes[0].Err)
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, outcomes[1].Err, &validationErr)
+	assert.Equal(t, repository.ErrProductNotFound, outcomes[2].Err)
+
+	// 驗證模擬儲存庫方法被調用
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"main/internal/models"
+	"main/internal/repository"
+	"main/internal/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// 測試列出變更歷史時套用預設分頁
+func TestGetProductHistory(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockProductRepository)
+
+	// 創建產品服務
+	service := service.NewProductService(mockRepo)
+
+	history := []models.ProductHistory{{ID: 2, ProductID: 1, Operation: models.HistoryUpdate}}
+	mockRepo.On("GetHistory", mock.Anything, int64(1), models.ProductQuery{PageSize: models.DefaultPageSize}).Return(history, 3, nil)
+
+	// 調用服務方法
+	page, err := service.GetProductHistory(systemContext(), 1, models.ProductQuery{})
+
+	// 驗證結果
+	assert.NoError(t, err)
+	assert.Equal(t, history, page.Items)
+	assert.Equal(t, 3, page.Total)
+	assert.True(t, page.HasNext)
+	assert.False(t, page.HasPrev)
+
+	// 驗證模擬儲存庫方法被調用
+	mockRepo.AssertExpectations(t)
+}
+
+// 測試沒有任何變更紀錄的產品視為不存在
+func TestGetProductHistoryNotFound(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockProductRepository)
+
+	// 創建產品服務
+	service := service.NewProductService(mockRepo)
+
+	mockRepo.On("GetHistory", mock.Anything, int64(999), mock.Anything).Return([]models.ProductHistory{}, 0, nil)
+
+	// 調用服務方法
+	_, err := service.GetProductHistory(systemContext(), 999, models.ProductQuery{})
+
+	// 驗證結果
+	assert.ErrorIs(t, err, repository.ErrProductNotFound)
+
+	// 驗證模擬儲存庫方法被調用
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"errors"
+	"main/internal/models"
+	"main/internal/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// 測試匯出時套用預設排序
+func TestExportProductsDefaultSort(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockProductRepository)
+
+	// 創建產品服務
+	service := service.NewProductService(mockRepo)
+
+	expectedQuery := models.ProductQuery{SkuName: "產品", SortBy: "id", SortDir: models.SortAsc}
+	mockRepo.On("Stream", mock.Anything, expectedQuery).
+		Return([]models.Product{{ID: 1}, {ID: 2}}, nil)
+
+	// 調用服務方法
+	var ids []int
+	err := service.ExportProducts(systemContext(), models.ProductQuery{SkuName: "產品"}, func(p models.Product) error {
+		ids = append(ids, p.ID)
+		return nil
+	})
+
+	// 驗證結果
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, ids)
+	mockRepo.AssertExpectations(t)
+}
+
+// 測試匯入：無效、重複的列記錄錯誤，其餘依 sku_code 更新或創建
+func TestImportProducts(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockProductRepository)
+
+	// 創建產品服務
+	service := service.NewProductService(mockRepo)
+
+	rows := []models.ImportRow{
+		{Line: 2, Product: models.Product{SkuCode: "SKU001", SkuAmount: 1}},
+		{Line: 3, Product: models.Product{SkuCode: "SKU002", SkuAmount: -1}},
+		{Line: 4, Product: models.Product{SkuCode: "SKU003"}, Err: errors.New("sku_amount 必須為整數: x")},
+		{Line: 5, Product: models.Product{SkuCode: "SKU001", SkuAmount: 2}},
+		{Line: 6, Product: models.Product{SkuCode: "SKU006", SkuAmount: 6}},
+	}
+
+	// 設置模擬儲存庫預期行為
+	mockRepo.On("UpsertBySku", mock.Anything, rows[0].Product).Return(models.Product{ID: 1}, false, nil)
+	mockRepo.On("UpsertBySku", mock.Anything, rows[4].Product).Return(models.Product{ID: 6}, true, nil)
+
+	// 調用服務方法
+	report, err := service.ImportProducts(systemContext(), rows, false)
+
+	// 驗證結果
+	assert.NoError(t, err)
+	assert.Equal(t, 5, report.Total)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 1, report.Updated)
+	assert.Equal(t, 3, report.Failed)
+	assert.Equal(t, []models.ImportRowError{
+		{Line: 3, SkuCode: "SKU002", Error: "產品庫存不能為負數"},
+		{Line: 4, SkuCode: "SKU003", Error: "sku_amount 必須為整數: x"},
+		{Line: 5, SkuCode: "SKU001", Error: "sku_code 與第 2 行重複"},
+	}, report.Errors)
+	mockRepo.AssertExpectations(t)
+}
+
+// 測試 dry-run 只統計將新增與更新的數量，不寫入
+func TestImportProductsDryRun(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockProductRepository)
+
+	// 創建產品服務
+	service := service.NewProductService(mockRepo)
+
+	rows := []models.ImportRow{
+		{Line: 2, Product: models.Product{SkuCode: "SKU001"}},
+		{Line: 3, Product: models.Product{SkuCode: "SKU002"}},
+	}
+
+	// 設置模擬儲存庫預期行為
+	mockRepo.On("ExistingSkuCodes", mock.Anything, []string{"SKU001", "SKU002"}).
+		Return(map[string]bool{"SKU002": true}, nil)
+
+	// 調用服務方法
+	report, err := service.ImportProducts(systemContext(), rows, true)
+
+	// 驗證結果
+	assert.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 1, report.Updated)
+	assert.Equal(t, 0, report.Failed)
+	mockRepo.AssertNotCalled(t, "UpsertBySku", mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
+// 測試單列寫入失敗不影響其他列
+func TestImportProductsRowWriteError(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockProductRepository)
+
+	// 創建產品服務
+	service := service.NewProductService(mockRepo)
+
+	rows := []models.ImportRow{
+		{Line: 2, Product: models.Product{SkuCode: "SKU001"}},
+		{Line: 3, Product: models.Product{SkuCode: "SKU002"}},
+	}
+
+	// 設置模擬儲存庫預期行為
+	mockRepo.On("UpsertBySku", mock.Anything, rows[0].Product).Return(models.Product{}, false, errors.New("寫入失敗"))
+	mockRepo.On("UpsertBySku", mock.Anything, rows[1].Product).Return(models.Product{ID: 2}, true, nil)
+
+	// 調用服務方法
+	report, err := service.ImportProducts(systemContext(), rows, false)
+
+	// 驗證結果
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, 2, report.Errors[0].Line)
+	mockRepo.AssertExpectations(t)
+}
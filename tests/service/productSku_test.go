@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"main/internal/models"
+	"main/internal/repository"
+	"main/internal/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// 測試不帶版本時以路徑的 sku_code 更新或創建
+func TestUpsertProductBySku(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockProductRepository)
+
+	// 創建產品服務
+	service := service.NewProductService(mockRepo)
+
+	expected := models.Product{SkuCode: "SKU001", SkuName: "產品 1", SkuAmount: 3}
+	mockRepo.On("UpsertBySku", mock.Anything, expected).Return(models.Product{ID: 1, SkuCode: "SKU001", Version: 1}, true, nil)
+
+	// 調用服務方法
+	product, created, err := service.UpsertProductBySku(systemContext(), "SKU001", 0, models.Product{SkuName: "產品 1", SkuAmount: 3})
+
+	// 驗證結果
+	assert.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, 1, product.ID)
+	mockRepo.AssertExpectations(t)
+}
+
+// 測試帶版本時只更新已存在且版本一致的產品
+func TestUpsertProductBySkuWithVersion(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockProductRepository)
+
+	// 創建產品服務
+	service := service.NewProductService(mockRepo)
+
+	input := models.Product{SkuName: "新名稱", SkuAmount: 3}
+	mockRepo.On("GetBySku", mock.Anything, "SKU001").Return(models.Product{ID: 1, SkuCode: "SKU001", Version: 2}, nil)
+	mockRepo.On("GetBySku", mock.Anything, "NONE").Return(models.Product{}, repository.ErrProductNotFound)
+	mockRepo.On("Update", mock.Anything, int64(1), 2, mock.Anything).Return(models.Product{ID: 1, Version: 3}, nil)
+
+	// 版本一致時更新
+	product, created, err := service.UpsertProductBySku(systemContext(), "SKU001", 2, input)
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, 3, product.Version)
+
+	// 版本不一致
+	_, _, err = service.UpsertProductBySku(systemContext(), "SKU001", 1, input)
+	assert.ErrorIs(t, err, repository.ErrVersionConflict)
+
+	// 產品不存在時不會創建
+	_, _, err = service.UpsertProductBySku(systemContext(), "NONE", 1, input)
+	assert.ErrorIs(t, err, repository.ErrVersionConflict)
+
+	mockRepo.AssertNumberOfCalls(t, "Update", 1)
+	mockRepo.AssertNotCalled(t, "UpsertBySku", mock.Anything, mock.Anything)
+}
+
+// 測試無效的內容不寫入
+func TestUpsertProductBySkuValidation(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockProductRepository)
+
+	// 創建產品服務
+	service := service.NewProductService(mockRepo)
+
+	// 調用服務方法
+	_, _, err := service.UpsertProductBySku(systemContext(), "SKU001", 0, models.Product{SkuAmount: -1})
+
+	// 驗證結果
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	mockRepo.AssertNotCalled(t, "UpsertBySku", mock.Anything, mock.Anything)
+}
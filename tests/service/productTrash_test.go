@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"main/internal/models"
+	"main/internal/repository"
+	"main/internal/service"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// 測試列出回收站時套用預設分頁
+func TestGetDeletedProducts(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockProductRepository)
+
+	// 創建產品服務
+	service := service.NewProductService(mockRepo)
+
+	deleted := []models.Product{{ID: 3, SkuCode: "SKU003"}}
+	mockRepo.On("GetDeleted", mock.Anything, models.ProductQuery{PageSize: models.DefaultPageSize}).Return(deleted, 1, nil)
+
+	// 調用服務方法
+	page, err := service.GetDeletedProducts(systemContext(), models.ProductQuery{})
+
+	// 驗證結果
+	assert.NoError(t, err)
+	assert.Equal(t, deleted, page.Items)
+	assert.Equal(t, 1, page.Total)
+	assert.False(t, page.HasNext)
+
+	// 驗證模擬儲存庫方法被調用
+	mockRepo.AssertExpectations(t)
+}
+
+// 測試還原時 sku_code 已被使用
+func TestRestoreProductDuplicateSku(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockProductRepository)
+
+	// 創建產品服務
+	service := service.NewProductService(mockRepo)
+
+	mockRepo.On("Restore", mock.Anything, int64(1)).Return(models.Product{}, repository.ErrDuplicateSku)
+
+	// 調用服務方法
+	_, err := service.RestoreProduct(systemContext(), 1)
+
+	// 驗證結果
+	assert.ErrorIs(t, err, repository.ErrDuplicateSku)
+
+	// 驗證模擬儲存庫方法被調用
+	mockRepo.AssertExpectations(t)
+}
+
+// 測試依保留期計算永久刪除的時間點
+func TestPurgeDeletedProducts(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockProductRepository)
+
+	// 創建產品服務
+	service := service.NewProductService(mockRepo)
+
+	retention := 30 * 24 * time.Hour
+	expected := time.Now().Add(-retention)
+	mockRepo.On("PurgeDeletedBefore", mock.Anything, mock.MatchedBy(func(before time.Time) bool {
+		return before.Sub(expected).Abs() < time.Minute
+	})).Return(int64(4), nil)
+
+	// 調用服務方法
+	purged, err := service.PurgeDeletedProducts(systemContext(), retention)
+
+	// 驗證結果
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), purged)
+
+	// 保留期必須為正數，避免刪除剛移到回收站的產品
+	_, err = service.PurgeDeletedProducts(systemContext(), 0)
+	assert.Error(t, err)
+
+	// 驗證模擬儲存庫方法被調用
+	mockRepo.AssertExpectations(t)
+}
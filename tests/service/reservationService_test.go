@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"context"
+	"main/internal/models"
+	"main/internal/service"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// 模擬預留儲存庫
+type MockReservationRepository struct {
+	mock.Mock
+}
+
+func (m *MockReservationRepository) Reserve(ctx context.Context, input models.StockReservation) (models.StockReservation, bool, error) {
+	args := m.Called(ctx, input)
+	return args.Get(0).(models.StockReservation), args.Bool(1), args.Error(2)
+}
+
+func (m *MockReservationRepository) GetReservation(ctx context.Context, id int64) (models.StockReservation, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.StockReservation), args.Error(1)
+}
+
+func (m *MockReservationRepository) ListActiveReservations(ctx context.Context, productID int64) ([]models.StockReservation, error) {
+	args := m.Called(ctx, productID)
+	reservations, _ := args.Get(0).([]models.StockReservation)
+	return reservations, args.Error(1)
+}
+
+func (m *MockReservationRepository) GetAvailability(ctx context.Context, productID int64) (models.StockAvailability, error) {
+	args := m.Called(ctx, productID)
+	return args.Get(0).(models.StockAvailability), args.Error(1)
+}
+
+func (m *MockReservationRepository) Commit(ctx context.Context, id int64, locationID int64) (models.StockReservation, error) {
+	args := m.Called(ctx, id, locationID)
+	return args.Get(0).(models.StockReservation), args.Error(1)
+}
+
+func (m *MockReservationRepository) Release(ctx context.Context, id int64) (models.StockReservation, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.StockReservation), args.Error(1)
+}
+
+func (m *MockReservationRepository) ExpireReservations(ctx context.Context, now time.Time, limit int) (int64, error) {
+	args := m.Called(ctx, now, limit)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// 測試預留的欄位驗證失敗時不寫入
+func TestReserveValidation(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockReservationRepository)
+
+	// 創建預留服務
+	service := service.NewReservationService(mockRepo)
+
+	cases := []models.ReservationRequest{
+		{Quantity: 0, Reference: "CART-1"},
+		{Quantity: 1},
+		{Quantity: 1, Reference: "CART-1", TTLSeconds: -1},
+		{Quantity: 1, Reference: "CART-1", TTLSeconds: 24*60*60 + 1},
+	}
+
+	for _, req := range cases {
+		_, _, err := service.Reserve(systemContext(), 1, req)
+
+		var validationErr *models.ValidationError
+		assert.ErrorAs(t, err, &validationErr, req)
+	}
+
+	// 驗證沒有寫入
+	mockRepo.AssertNotCalled(t, "Reserve", mock.Anything, mock.Anything)
+}
+
+// 測試未指定 ttl_seconds 時使用預設的有效期
+func TestReserveDefaultTTL(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockReservationRepository)
+
+	// 創建預留服務
+	service := service.NewReservationService(mockRepo)
+
+	before := time.Now()
+	mockRepo.On("Reserve", mock.Anything, mock.MatchedBy(func(input models.StockReservation) bool {
+		ttl := input.ExpiresAt.Sub(before)
+		return input.ProductID == 1 && input.Quantity == 2 && input.Reference == "CART-1" &&
+			ttl >= 15*time.Minute && ttl < 16*time.Minute
+	})).Return(models.StockReservation{ID: 7}, true, nil)
+
+	// 調用服務方法
+	reservation, created, err := service.Reserve(systemContext(), 1, models.ReservationRequest{Quantity: 2, Reference: "CART-1"})
+
+	// 驗證結果
+	assert.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, int64(7), reservation.ID)
+
+	// 驗證模擬儲存庫方法被調用
+	mockRepo.AssertExpectations(t)
+}
+
+// 測試分批清理過期預留，直到某一批未滿
+func TestExpireReservationsBatches(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockReservationRepository)
+
+	// 創建預留服務
+	service := service.NewReservationService(mockRepo)
+
+	now := time.Now()
+	mockRepo.On("ExpireReservations", mock.Anything, now, 500).Return(int64(500), nil).Twice()
+	mockRepo.On("ExpireReservations", mock.Anything, now, 500).Return(int64(12), nil).Once()
+
+	// 調用服務方法
+	expired, err := service.ExpireReservations(systemContext(), now)
+
+	// 驗證結果
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1012), expired)
+
+	// 驗證模擬儲存庫方法被調用
+	mockRepo.AssertNumberOfCalls(t, "ExpireReservations", 3)
+}
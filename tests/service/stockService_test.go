@@ -0,0 +1,208 @@
+package tests
+
+import (
+	"context"
+	"main/internal/models"
+	"main/internal/repository"
+	"main/internal/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// 模擬庫存異動儲存庫
+type MockStockRepository struct {
+	mock.Mock
+}
+
+func (m *MockStockRepository) ApplyMovements(ctx context.Context, movements []models.StockMovement) ([]models.StockMovement, error) {
+	args := m.Called(ctx, movements)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.StockMovement), args.Error(1)
+}
+
+func (m *MockStockRepository) GetMovements(ctx context.Context, productID int64, query models.ProductQuery) ([]models.StockMovement, int, error) {
+	args := m.Called(ctx, productID, query)
+	return args.Get(0).([]models.StockMovement), args.Int(1), args.Error(2)
+}
+
+func (m *MockStockRepository) GetMovement(ctx context.Context, productID int64, movementID int64) (models.StockMovement, error) {
+	args := m.Called(ctx, productID, movementID)
+	return args.Get(0).(models.StockMovement), args.Error(1)
+}
+
+func (m *MockStockRepository) GetLots(ctx context.Context, productID int64) (models.ProductLotStock, error) {
+	args := m.Called(ctx, productID)
+	return args.Get(0).(models.ProductLotStock), args.Error(1)
+}
+
+// 測試入庫、出庫與調整的數量正負號與預設原因
+func TestStockMovementSigns(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockStockRepository)
+
+	// 創建庫存服務
+	service := service.NewStockService(mockRepo)
+
+	expected := []models.StockMovement{
+		{ProductID: 1, Type: models.MovementReceive, Quantity: 10, Reason: models.ReasonPurchase, Reference: "PO-1"},
+		{ProductID: 1, Type: models.MovementIssue, Quantity: -3, Reason: models.ReasonSale},
+		{ProductID: 1, Type: models.MovementAdjust, Quantity: -2, Reason: models.ReasonDamage},
+	}
+	for _, m := range expected {
+		mockRepo.On("ApplyMovements", mock.Anything, []models.StockMovement{m}).Return([]models.StockMovement{m}, nil).Once()
+	}
+
+	// 調用服務方法
+	_, err := service.Receive(systemContext(), 1, models.StockRequest{Quantity: 10, Reference: "PO-1"})
+	assert.NoError(t, err)
+	_, err = service.Issue(systemContext(), 1, models.StockRequest{Quantity: 3})
+	assert.NoError(t, err)
+	_, err = service.Adjust(systemContext(), 1, models.StockRequest{Quantity: -2, Reason: models.ReasonDamage})
+	assert.NoError(t, err)
+
+	// 驗證模擬儲存庫方法被調用
+	mockRepo.AssertExpectations(t)
+}
+
+// 測試無效的數量與原因不會寫入
+func TestStockMovementValidation(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockStockRepository)
+
+	// 創建庫存服務
+	service := service.NewStockService(mockRepo)
+
+	var validationErr *models.ValidationError
+
+	_, err := service.Receive(systemContext(), 1, models.StockRequest{Quantity: 0})
+	assert.ErrorAs(t, err, &validationErr)
+	_, err = service.Issue(systemContext(), 1, models.StockRequest{Quantity: -1})
+	assert.ErrorAs(t, err, &validationErr)
+	_, err = service.Adjust(systemContext(), 1, models.StockRequest{Quantity: 0})
+	assert.ErrorAs(t, err, &validationErr)
+	_, err = service.Receive(systemContext(), 1, models.StockRequest{Quantity: 1, Reason: "gift"})
+	assert.ErrorAs(t, err, &validationErr)
+	// 系統保留的原因不能由客戶端指定
+	_, err = service.Adjust(systemContext(), 1, models.StockRequest{Quantity: 1, Reason: models.ReasonProductEdit})
+	assert.ErrorAs(t, err, &validationErr)
+	// 同一產品在同一儲位內轉移沒有意義
+	_, err = service.Transfer(systemContext(), 1, models.TransferRequest{StockRequest: models.StockRequest{Quantity: 1}})
+	assert.ErrorAs(t, err, &validationErr)
+	_, err = service.Transfer(systemContext(), 1, models.TransferRequest{StockRequest: models.StockRequest{Quantity: 1, LocationID: 3}, ToLocationID: 3})
+	assert.ErrorAs(t, err, &validationErr)
+	_, err = service.Transfer(systemContext(), 1, models.TransferRequest{StockRequest: models.StockRequest{Quantity: 0}, ToLocationID: 5})
+	assert.ErrorAs(t, err, &validationErr)
+
+	// 驗證沒有寫入
+	mockRepo.AssertNotCalled(t, "ApplyMovements", mock.Anything, mock.Anything)
+}
+
+// 測試入庫時指定批號，其他異動不能指定批號
+func TestReceiveLot(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockStockRepository)
+
+	// 創建庫存服務
+	service := service.NewStockService(mockRepo)
+
+	expiration := models.MustParseDate("2025-06-30")
+	expected := models.StockMovement{
+		ProductID: 1, Type: models.MovementReceive, Quantity: 12, Reason: models.ReasonPurchase,
+		Lots: []models.MovementLot{{LotNumber: "L-2025-01", Expiration: expiration, Quantity: 12}},
+	}
+	mockRepo.On("ApplyMovements", mock.Anything, []models.StockMovement{expected}).Return([]models.StockMovement{expected}, nil)
+
+	// 調用服務方法
+	movement, err := service.Receive(systemContext(), 1, models.StockRequest{Quantity: 12, LotNumber: "L-2025-01", Expiration: expiration})
+
+	// 驗證結果
+	assert.NoError(t, err)
+	assert.Len(t, movement.Lots, 1)
+
+	var validationErr *models.ValidationError
+	_, err = service.Receive(systemContext(), 1, models.StockRequest{Quantity: 1, Expiration: expiration})
+	assert.ErrorAs(t, err, &validationErr)
+	_, err = service.Issue(systemContext(), 1, models.StockRequest{Quantity: 1, LotNumber: "L-2025-01"})
+	assert.ErrorAs(t, err, &validationErr)
+	_, err = service.Transfer(systemContext(), 1, models.TransferRequest{StockRequest: models.StockRequest{Quantity: 1, LotNumber: "L-2025-01"}, ToLocationID: 5})
+	assert.ErrorAs(t, err, &validationErr)
+
+	// 驗證模擬儲存庫方法只被調用一次
+	mockRepo.AssertNumberOfCalls(t, "ApplyMovements", 1)
+}
+
+// 測試轉移在同一次寫入中產生轉出與轉入
+func TestTransfer(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockStockRepository)
+
+	// 創建庫存服務
+	service := service.NewStockService(mockRepo)
+
+	movements := []models.StockMovement{
+		{ProductID: 1, LocationID: 2, Type: models.MovementTransferOut, Quantity: -4, Reason: models.ReasonTransfer, Reference: "RP-1"},
+		{ProductID: 1, LocationID: 3, Type: models.MovementTransferIn, Quantity: 4, Reason: models.ReasonTransfer, Reference: "RP-1"},
+	}
+	mockRepo.On("ApplyMovements", mock.Anything, movements).Return(nil, repository.ErrInsufficientStock)
+
+	// 調用服務方法
+	_, err := service.Transfer(systemContext(), 1, models.TransferRequest{StockRequest: models.StockRequest{Quantity: 4, Reference: "RP-1", LocationID: 2}, ToLocationID: 3})
+
+	// 驗證結果
+	assert.ErrorIs(t, err, repository.ErrInsufficientStock)
+
+	// 驗證模擬儲存庫方法被調用
+	mockRepo.AssertExpectations(t)
+}
+
+// 測試同一產品在儲位間轉移
+func TestTransferBetweenLocations(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockStockRepository)
+
+	// 創建庫存服務
+	service := service.NewStockService(mockRepo)
+
+	movements := []models.StockMovement{
+		{ProductID: 1, LocationID: 0, Type: models.MovementTransferOut, Quantity: -3, Reason: models.ReasonTransfer},
+		{ProductID: 1, LocationID: 5, Type: models.MovementTransferIn, Quantity: 3, Reason: models.ReasonTransfer},
+	}
+	mockRepo.On("ApplyMovements", mock.Anything, movements).Return(movements, nil)
+
+	// 調用服務方法：未指定 location_id 時從預設儲位轉出
+	result, err := service.Transfer(systemContext(), 1, models.TransferRequest{StockRequest: models.StockRequest{Quantity: 3}, ToLocationID: 5})
+
+	// 驗證結果
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+
+	// 驗證模擬儲存庫方法被調用
+	mockRepo.AssertExpectations(t)
+}
+
+// 測試列出異動時套用預設分頁
+func TestGetMovements(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockStockRepository)
+
+	// 創建庫存服務
+	service := service.NewStockService(mockRepo)
+
+	movements := []models.StockMovement{{ID: 1, ProductID: 1}}
+	mockRepo.On("GetMovements", mock.Anything, int64(1), models.ProductQuery{PageSize: models.DefaultPageSize}).Return(movements, 1, nil)
+
+	// 調用服務方法
+	page, err := service.GetMovements(systemContext(), 1, models.ProductQuery{})
+
+	// 驗證結果
+	assert.NoError(t, err)
+	assert.Equal(t, movements, page.Items)
+	assert.False(t, page.HasNext)
+
+	// 驗證模擬儲存庫方法被調用
+	mockRepo.AssertExpectations(t)
+}
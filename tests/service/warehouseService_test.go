@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"context"
+	"main/internal/models"
+	"main/internal/repository"
+	"main/internal/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// 模擬倉庫儲存庫
+type MockWarehouseRepository struct {
+	mock.Mock
+}
+
+func (m *MockWarehouseRepository) ListWarehouses(ctx context.Context) ([]models.Warehouse, error) {
+	args := m.Called(ctx)
+	warehouses, _ := args.Get(0).([]models.Warehouse)
+	return warehouses, args.Error(1)
+}
+
+func (m *MockWarehouseRepository) GetWarehouse(ctx context.Context, id int64) (models.Warehouse, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.Warehouse), args.Error(1)
+}
+
+func (m *MockWarehouseRepository) CreateWarehouse(ctx context.Context, input models.Warehouse) (models.Warehouse, error) {
+	args := m.Called(ctx, input)
+	return args.Get(0).(models.Warehouse), args.Error(1)
+}
+
+func (m *MockWarehouseRepository) UpdateWarehouse(ctx context.Context, id int64, input models.Warehouse) (models.Warehouse, error) {
+	args := m.Called(ctx, id, input)
+	return args.Get(0).(models.Warehouse), args.Error(1)
+}
+
+func (m *MockWarehouseRepository) DeleteWarehouse(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockWarehouseRepository) ListLocations(ctx context.Context, warehouseID int64) ([]models.StockLocation, error) {
+	args := m.Called(ctx, warehouseID)
+	locations, _ := args.Get(0).([]models.StockLocation)
+	return locations, args.Error(1)
+}
+
+func (m *MockWarehouseRepository) GetLocation(ctx context.Context, id int64) (models.StockLocation, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.StockLocation), args.Error(1)
+}
+
+func (m *MockWarehouseRepository) CreateLocation(ctx context.Context, input models.StockLocation) (models.StockLocation, error) {
+	args := m.Called(ctx, input)
+	return args.Get(0).(models.StockLocation), args.Error(1)
+}
+
+func (m *MockWarehouseRepository) UpdateLocation(ctx context.Context, id int64, input models.StockLocation) (models.StockLocation, error) {
+	args := m.Called(ctx, id, input)
+	return args.Get(0).(models.StockLocation), args.Error(1)
+}
+
+func (m *MockWarehouseRepository) DeleteLocation(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+// 測試倉庫與儲位的欄位驗證失敗時不寫入
+func TestWarehouseValidation(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockWarehouseRepository)
+
+	// 創建倉庫服務
+	service := service.NewWarehouseService(mockRepo)
+
+	var validationErr *models.ValidationError
+	_, err := service.CreateWarehouse(systemContext(), models.Warehouse{Name: "東區倉庫"})
+	assert.ErrorAs(t, err, &validationErr)
+	_, err = service.UpdateWarehouse(systemContext(), 1, models.Warehouse{Code: "EAST"})
+	assert.ErrorAs(t, err, &validationErr)
+	_, err = service.CreateLocation(systemContext(), 1, models.StockLocation{Name: "A 區"})
+	assert.ErrorAs(t, err, &validationErr)
+
+	// 驗證沒有寫入
+	mockRepo.AssertNotCalled(t, "CreateWarehouse", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "UpdateWarehouse", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "CreateLocation", mock.Anything, mock.Anything)
+}
+
+// 測試創建儲位時使用路徑中的倉庫，且客戶端不能建立預設儲位
+func TestCreateLocation(t *testing.T) {
+	// 創建模擬儲存庫
+	mockRepo := new(MockWarehouseRepository)
+
+	// 創建倉庫服務
+	service := service.NewWarehouseService(mockRepo)
+
+	expected := models.StockLocation{WarehouseID: 2, Code: "A-01", Name: "A 區"}
+	mockRepo.On("CreateLocation", mock.Anything, expected).Return(models.StockLocation{ID: 5, WarehouseID: 2, Code: "A-01", Name: "A 區"}, nil)
+	mockRepo.On("CreateLocation", mock.Anything, mock.Anything).Return(models.StockLocation{}, repository.ErrWarehouseNotFound)
+
+	// 調用服務方法
+	location, err := service.CreateLocation(systemContext(), 2, models.StockLocation{WarehouseID: 9, Code: "A-01", Name: "A 區", IsDefault: true})
+
+	// 驗證結果
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), location.ID)
+
+	// 驗證模擬儲存庫方法被調用
+	mockRepo.AssertNumberOfCalls(t, "CreateLocation", 1)
+}